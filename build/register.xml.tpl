@@ -6,7 +6,7 @@
     <container-port>8081</container-port>
     <container-config-directory>/home/app/wecube-plugins-qcloud/conf</container-config-directory>
     <container-log-directory>/home/app/wecube-plugins-qcloud/log</container-log-directory>
-    <container-start-param>-v /etc/localtime:/etc/localtime -v /home/app/wecube-plugins-qcloud/logs:/home/app/wecube-plugins-qcloud/logs</container-start-param>
+    <container-start-param>-v /etc/localtime:/etc/localtime -v /home/app/wecube-plugins-qcloud/logs:/home/app/wecube-plugins-qcloud/logs -v /home/app/wecube-plugins-qcloud/data:/home/app/wecube-plugins-qcloud/data</container-start-param>
     <plugin id="vpc" name="Vpc Management" >
         <interface name="create" path="/v1/qcloud/vpc/create">
             <input-parameters>
@@ -15,6 +15,7 @@
                 <parameter datatype="string">name</parameter>
                 <parameter datatype="string">cidr_block</parameter>
                 <parameter datatype="string">id</parameter>
+                <parameter datatype="string">idempotency_key</parameter>
             </input-parameters>
             <output-parameters>
                 <parameter datatype="string">guid</parameter>
@@ -31,6 +32,80 @@
                 <parameter datatype="string">guid</parameter>
             </output-parameters>
         </interface>
+        <interface name="describe" path="/v1/qcloud/vpc/describe">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">name</parameter>
+                <parameter datatype="string">cidr_block</parameter>
+                <parameter datatype="string">dns_servers</parameter>
+                <parameter datatype="string">domain_name</parameter>
+                <parameter datatype="string">secondary_cidr_blocks</parameter>
+                <parameter datatype="string">ipv6_cidr_block</parameter>
+                <parameter datatype="string">created_time</parameter>
+                <parameter datatype="string">topology</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="modify" path="/v1/qcloud/vpc/modify">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">name</parameter>
+                <parameter datatype="string">dns_servers</parameter>
+                <parameter datatype="string">domain_name</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">name</parameter>
+                <parameter datatype="string">dns_servers</parameter>
+                <parameter datatype="string">domain_name</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="add-cidrs" path="/v1/qcloud/vpc/add-cidrs">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">secondary_cidr_blocks</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">secondary_cidr_blocks</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="remove-cidrs" path="/v1/qcloud/vpc/remove-cidrs">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">secondary_cidr_blocks</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">secondary_cidr_blocks</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="assign-ipv6" path="/v1/qcloud/vpc/assign-ipv6">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">ipv6_cidr_block</parameter>
+            </output-parameters>
+        </interface>
     </plugin>
     <plugin id="peering-connection" name="Peer Connection Management">
         <interface name="create" path="/v1/qcloud/peering-connection/create">
@@ -70,6 +145,7 @@
                 <parameter datatype="string">name</parameter>
                 <parameter datatype="string">description</parameter>
                 <parameter datatype="string">id</parameter>
+                <parameter datatype="string">idempotency_key</parameter>
             </input-parameters>
             <output-parameters>
                 <parameter datatype="string">guid</parameter>
@@ -156,6 +232,62 @@
                 <parameter datatype="string">guid</parameter>
             </output-parameters>
         </interface>
+        <interface name="describe" path="/v1/qcloud/route-table/describe">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">name</parameter>
+                <parameter datatype="string">vpc_id</parameter>
+                <parameter datatype="string">main</parameter>
+                <parameter datatype="string">subnet_ids</parameter>
+                <parameter datatype="string">routes</parameter>
+                <parameter datatype="string">created_time</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="rename" path="/v1/qcloud/route-table/rename">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">name</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">request_id</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">name</parameter>
+                <parameter datatype="string">vpc_id</parameter>
+                <parameter datatype="string">main</parameter>
+                <parameter datatype="string">subnet_ids</parameter>
+                <parameter datatype="string">routes</parameter>
+                <parameter datatype="string">created_time</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="replace-routes" path="/v1/qcloud/route-table/replace-routes">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">routes</parameter>
+                <parameter datatype="string">delete_all_routes</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">request_id</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">name</parameter>
+                <parameter datatype="string">vpc_id</parameter>
+                <parameter datatype="string">main</parameter>
+                <parameter datatype="string">subnet_ids</parameter>
+                <parameter datatype="string">routes</parameter>
+                <parameter datatype="string">created_time</parameter>
+            </output-parameters>
+        </interface>
     </plugin>
     <plugin id="subnet" name="Subnet Management">
         <interface name="create" path="/v1/qcloud/subnet/create">
@@ -164,12 +296,17 @@
                 <parameter datatype="string">provider_params</parameter>
                 <parameter datatype="string">name</parameter>
                 <parameter datatype="string">cidr_block</parameter>
+                <parameter datatype="number">prefix_length</parameter>
+                <parameter datatype="string">ipv6_cidr_block</parameter>
                 <parameter datatype="string">vpc_id</parameter>
                 <parameter datatype="string">id</parameter>
+                <parameter datatype="string">idempotency_key</parameter>
             </input-parameters>
             <output-parameters>
                 <parameter datatype="string">guid</parameter>
                 <parameter datatype="string">id</parameter>
+                <parameter datatype="string">cidr_block</parameter>
+                <parameter datatype="string">ipv6_cidr_block</parameter>
             </output-parameters>
         </interface>
          <interface name="create" path="/v1/qcloud/subnet/create-with-routetable">
@@ -178,12 +315,17 @@
                 <parameter datatype="string">provider_params</parameter>
                 <parameter datatype="string">name</parameter>
                 <parameter datatype="string">cidr_block</parameter>
+                <parameter datatype="number">prefix_length</parameter>
+                <parameter datatype="string">ipv6_cidr_block</parameter>
                 <parameter datatype="string">vpc_id</parameter>
                 <parameter datatype="string">id</parameter>
+                <parameter datatype="string">idempotency_key</parameter>
             </input-parameters>
             <output-parameters>
                 <parameter datatype="string">guid</parameter>
                 <parameter datatype="string">id</parameter>
+                <parameter datatype="string">cidr_block</parameter>
+                <parameter datatype="string">ipv6_cidr_block</parameter>
                 <parameter datatype="string">route_table_id</parameter>
             </output-parameters>
         </interface>
@@ -208,6 +350,20 @@
                 <parameter datatype="string">guid</parameter>
             </output-parameters>
         </interface>
+        <interface name="assign-ipv6" path="/v1/qcloud/subnet/assign-ipv6">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">vpc_id</parameter>
+                <parameter datatype="string">ipv6_cidr_block</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">ipv6_cidr_block</parameter>
+            </output-parameters>
+        </interface>
     </plugin>
     <plugin id="vm" name="Virtual Machine Management">
         <interface name="create" path="/v1/qcloud/vm/create">
@@ -225,6 +381,22 @@
                 <parameter datatype="number">instance_charge_period</parameter>
                 <parameter datatype="string">instance_private_ip</parameter>
                  <parameter datatype="string">id</parameter>
+                <parameter datatype="string">idempotency_key</parameter>
+                <parameter datatype="string">system_disk_type</parameter>
+                <parameter datatype="string">data_disks</parameter>
+                <parameter datatype="string">security_group_ids</parameter>
+                <parameter datatype="string">key_ids</parameter>
+                <parameter datatype="string">host_name</parameter>
+                <parameter datatype="string">user_data</parameter>
+                <parameter datatype="number">project_id</parameter>
+                <parameter datatype="string">tags</parameter>
+                <parameter datatype="string">internet_charge_type</parameter>
+                <parameter datatype="number">internet_max_bandwidth_out</parameter>
+                <parameter datatype="string">renew_flag</parameter>
+                <parameter datatype="string">zone</parameter>
+                <parameter datatype="string">zones</parameter>
+                <parameter datatype="string">placement_group_id</parameter>
+                <parameter datatype="string">zone_fallback</parameter>
             </input-parameters>
             <output-parameters>
                 <parameter datatype="string">guid</parameter>
@@ -251,9 +423,15 @@
                 <parameter datatype="string">guid</parameter>
                 <parameter datatype="string">provider_params</parameter>
                 <parameter datatype="string">id</parameter>
+                <parameter datatype="string">wait</parameter>
             </input-parameters>
             <output-parameters>
                 <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">cpu</parameter>
+                <parameter datatype="string">memory</parameter>
+                <parameter datatype="string">instance_state</parameter>
+                <parameter datatype="string">instance_private_ip</parameter>
             </output-parameters>
         </interface>
         <interface name="stop" path="/v1/qcloud/vm/stop">
@@ -261,9 +439,330 @@
                 <parameter datatype="string">guid</parameter>
                 <parameter datatype="string">provider_params</parameter>
                 <parameter datatype="string">id</parameter>
+                <parameter datatype="string">wait</parameter>
+                <parameter datatype="string">stop_type</parameter>
+                <parameter datatype="string">stopped_mode</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">cpu</parameter>
+                <parameter datatype="string">memory</parameter>
+                <parameter datatype="string">instance_state</parameter>
+                <parameter datatype="string">instance_private_ip</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="reboot" path="/v1/qcloud/vm/reboot">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">wait</parameter>
+                <parameter datatype="string">stop_type</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">cpu</parameter>
+                <parameter datatype="string">memory</parameter>
+                <parameter datatype="string">instance_state</parameter>
+                <parameter datatype="string">instance_private_ip</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="resize" path="/v1/qcloud/vm/resize">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">instance_type</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">cpu</parameter>
+                <parameter datatype="string">memory</parameter>
+                <parameter datatype="string">instance_state</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="reinstall" path="/v1/qcloud/vm/reinstall">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">seed</parameter>
+                <parameter datatype="string">image_id</parameter>
+                <parameter datatype="string">key_ids</parameter>
+                <parameter datatype="string">host_name</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">cpu</parameter>
+                <parameter datatype="string">memory</parameter>
+                <parameter datatype="string">password</parameter>
+                <parameter datatype="string">instance_state</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="reset-password" path="/v1/qcloud/vm/reset-password">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">seed</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">cpu</parameter>
+                <parameter datatype="string">memory</parameter>
+                <parameter datatype="string">password</parameter>
+                <parameter datatype="string">instance_state</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="describe" path="/v1/qcloud/vm/describe">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">instance_private_ip</parameter>
+                <parameter datatype="string">instance_name</parameter>
+                <parameter datatype="string">tags</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">instance_name</parameter>
+                <parameter datatype="string">instance_type</parameter>
+                <parameter datatype="string">instance_state</parameter>
+                <parameter datatype="string">cpu</parameter>
+                <parameter datatype="string">memory</parameter>
+                <parameter datatype="string">zone</parameter>
+                <parameter datatype="string">vpc_id</parameter>
+                <parameter datatype="string">subnet_id</parameter>
+                <parameter datatype="string">image_id</parameter>
+                <parameter datatype="string">system_disk_type</parameter>
+                <parameter datatype="string">system_disk_size</parameter>
+                <parameter datatype="string">data_disks</parameter>
+                <parameter datatype="string">security_group_ids</parameter>
+                <parameter datatype="string">instance_private_ip</parameter>
+                <parameter datatype="string">instance_public_ip</parameter>
+                <parameter datatype="string">instance_charge_type</parameter>
+                <parameter datatype="string">expired_time</parameter>
+                <parameter datatype="string">created_time</parameter>
+                <parameter datatype="string">tags</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="renew" path="/v1/qcloud/vm/renew">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="number">instance_charge_period</parameter>
+                <parameter datatype="string">renew_flag</parameter>
+                <parameter datatype="string">idempotency_key</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">instance_state</parameter>
+                <parameter datatype="string">instance_charge_type</parameter>
+                <parameter datatype="string">expired_time</parameter>
+                <parameter datatype="string">renew_flag</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="convert-to-prepaid" path="/v1/qcloud/vm/convert-to-prepaid">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="number">instance_charge_period</parameter>
+                <parameter datatype="string">renew_flag</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">instance_state</parameter>
+                <parameter datatype="string">instance_charge_type</parameter>
+                <parameter datatype="string">expired_time</parameter>
+                <parameter datatype="string">renew_flag</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="modify-renew-flag" path="/v1/qcloud/vm/modify-renew-flag">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">renew_flag</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">instance_state</parameter>
+                <parameter datatype="string">instance_charge_type</parameter>
+                <parameter datatype="string">expired_time</parameter>
+                <parameter datatype="string">renew_flag</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="expiry-report" path="/v1/qcloud/vm/expiry-report">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="number">expire_within_days</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">instance_name</parameter>
+                <parameter datatype="string">instance_state</parameter>
+                <parameter datatype="string">instance_charge_type</parameter>
+                <parameter datatype="string">expired_time</parameter>
+                <parameter datatype="string">renew_flag</parameter>
+                <parameter datatype="string">expire_in_days</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="check-quota" path="/v1/qcloud/vm/check-quota">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">instance_type</parameter>
+                <parameter datatype="string">instance_charge_type</parameter>
+                <parameter datatype="string">zones</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">zone</parameter>
+                <parameter datatype="string">instance_type</parameter>
+                <parameter datatype="string">instance_charge_type</parameter>
+                <parameter datatype="string">instance_family</parameter>
+                <parameter datatype="string">cpu</parameter>
+                <parameter datatype="string">memory</parameter>
+                <parameter datatype="string">status</parameter>
+                <parameter datatype="string">sold_out</parameter>
+                <parameter datatype="string">unit_price</parameter>
+                <parameter datatype="string">original_price</parameter>
+                <parameter datatype="string">discount_price</parameter>
+                <parameter datatype="string">charge_unit</parameter>
+            </output-parameters>
+        </interface>
+    </plugin>
+    <plugin id="image" name="Image Management">
+        <interface name="create" path="/v1/qcloud/image/create">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">image_name</parameter>
+                <parameter datatype="string">image_description</parameter>
+                <parameter datatype="string">instance_id</parameter>
+                <parameter datatype="string">force_poweroff</parameter>
+                <parameter datatype="string">data_disk_ids</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">image_state</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="copy" path="/v1/qcloud/image/copy">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">destination_regions</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">destination_image_ids</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="share" path="/v1/qcloud/image/share">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">account_ids</parameter>
+                <parameter datatype="string">permission</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="terminate" path="/v1/qcloud/image/terminate">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="describe" path="/v1/qcloud/image/describe">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">image_name</parameter>
+                <parameter datatype="string">image_type</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">image_name</parameter>
+                <parameter datatype="string">image_description</parameter>
+                <parameter datatype="string">image_state</parameter>
+                <parameter datatype="string">image_type</parameter>
+                <parameter datatype="string">image_size</parameter>
+                <parameter datatype="string">os_name</parameter>
+                <parameter datatype="string">platform</parameter>
+                <parameter datatype="string">architecture</parameter>
+                <parameter datatype="string">created_time</parameter>
+            </output-parameters>
+        </interface>
+    </plugin>
+    <plugin id="placement-group" name="Placement Group Management">
+        <interface name="create" path="/v1/qcloud/placement-group/create">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">name</parameter>
+                <parameter datatype="string">type</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="terminate" path="/v1/qcloud/placement-group/terminate">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="describe" path="/v1/qcloud/placement-group/describe">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">name</parameter>
             </input-parameters>
             <output-parameters>
                 <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">name</parameter>
+                <parameter datatype="string">type</parameter>
+                <parameter datatype="string">cvm_quota_total</parameter>
+                <parameter datatype="string">current_num</parameter>
+                <parameter datatype="string">instance_ids</parameter>
+                <parameter datatype="string">created_time</parameter>
             </output-parameters>
         </interface>
     </plugin>
@@ -279,10 +778,23 @@
                 <parameter datatype="string">disk_charge_type</parameter>
                 <parameter datatype="string">disk_charge_period</parameter>
                 <parameter datatype="string">instance_id</parameter>
+                <parameter datatype="string">delete_with_instance</parameter>
+                <parameter datatype="string">snapshot_id</parameter>
+                <parameter datatype="number">disk_count</parameter>
+                <parameter datatype="string">encrypt</parameter>
+                <parameter datatype="number">throughput_performance</parameter>
+                <parameter datatype="number">project_id</parameter>
+                <parameter datatype="string">tags</parameter>
+                <parameter datatype="string">instance_ids</parameter>
+                <parameter datatype="string">idempotency_key</parameter>
             </input-parameters>
             <output-parameters>
                 <parameter datatype="string">guid</parameter>
                 <parameter datatype="string">id</parameter>
+                <parameter datatype="string">disk_state</parameter>
+                <parameter datatype="string">disk_size</parameter>
+                <parameter datatype="string">instance_id</parameter>
+                <parameter datatype="string">device_name_hint</parameter>
             </output-parameters>
         </interface>
         <interface name="terminate" path="/v1/qcloud/storage/terminate">
@@ -295,6 +807,222 @@
                 <parameter datatype="string">guid</parameter>
             </output-parameters>
         </interface>
+        <interface name="attach" path="/v1/qcloud/storage/attach">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">instance_id</parameter>
+                <parameter datatype="string">delete_with_instance</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">disk_state</parameter>
+                <parameter datatype="string">disk_size</parameter>
+                <parameter datatype="string">instance_id</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="detach" path="/v1/qcloud/storage/detach">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">disk_state</parameter>
+                <parameter datatype="string">disk_size</parameter>
+                <parameter datatype="string">instance_id</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="resize" path="/v1/qcloud/storage/resize">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="number">disk_size</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">disk_state</parameter>
+                <parameter datatype="string">disk_size</parameter>
+                <parameter datatype="string">instance_id</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="find-orphans" path="/v1/qcloud/storage/find-orphans">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">regions</parameter>
+                <parameter datatype="number">delete_older_than_days</parameter>
+                <parameter datatype="string">include_prepaid</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">region</parameter>
+                <parameter datatype="string">disk_name</parameter>
+                <parameter datatype="string">disk_type</parameter>
+                <parameter datatype="string">disk_size</parameter>
+                <parameter datatype="string">disk_charge_type</parameter>
+                <parameter datatype="string">created_time</parameter>
+                <parameter datatype="string">unattached_days</parameter>
+                <parameter datatype="string">last_instance_id</parameter>
+                <parameter datatype="string">deleted</parameter>
+            </output-parameters>
+        </interface>
+    </plugin>
+    <plugin id="snapshot" name="Snapshot Management">
+        <interface name="create" path="/v1/qcloud/snapshot/create">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">disk_id</parameter>
+                <parameter datatype="string">name</parameter>
+                <parameter datatype="string">idempotency_key</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">name</parameter>
+                <parameter datatype="string">disk_id</parameter>
+                <parameter datatype="string">disk_size</parameter>
+                <parameter datatype="string">disk_usage</parameter>
+                <parameter datatype="string">state</parameter>
+                <parameter datatype="string">created_time</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="terminate" path="/v1/qcloud/snapshot/terminate">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="describe" path="/v1/qcloud/snapshot/describe">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">disk_id</parameter>
+                <parameter datatype="string">name</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">name</parameter>
+                <parameter datatype="string">disk_id</parameter>
+                <parameter datatype="string">disk_size</parameter>
+                <parameter datatype="string">disk_usage</parameter>
+                <parameter datatype="string">state</parameter>
+                <parameter datatype="string">created_time</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="rollback" path="/v1/qcloud/snapshot/rollback">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">disk_id</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">name</parameter>
+                <parameter datatype="string">disk_id</parameter>
+                <parameter datatype="string">disk_size</parameter>
+                <parameter datatype="string">disk_usage</parameter>
+                <parameter datatype="string">state</parameter>
+                <parameter datatype="string">created_time</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="create-policy" path="/v1/qcloud/snapshot/create-policy">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">name</parameter>
+                <parameter datatype="string">day_of_week</parameter>
+                <parameter datatype="string">hour</parameter>
+                <parameter datatype="number">retention_days</parameter>
+                <parameter datatype="string">is_activated</parameter>
+                <parameter datatype="string">idempotency_key</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">name</parameter>
+                <parameter datatype="string">state</parameter>
+                <parameter datatype="string">is_activated</parameter>
+                <parameter datatype="string">day_of_week</parameter>
+                <parameter datatype="string">hour</parameter>
+                <parameter datatype="string">retention_days</parameter>
+                <parameter datatype="string">disk_ids</parameter>
+                <parameter datatype="string">next_trigger_time</parameter>
+                <parameter datatype="string">created_time</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="terminate-policy" path="/v1/qcloud/snapshot/terminate-policy">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="bind-policy" path="/v1/qcloud/snapshot/bind-policy">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">disk_ids</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">name</parameter>
+                <parameter datatype="string">state</parameter>
+                <parameter datatype="string">is_activated</parameter>
+                <parameter datatype="string">day_of_week</parameter>
+                <parameter datatype="string">hour</parameter>
+                <parameter datatype="string">retention_days</parameter>
+                <parameter datatype="string">disk_ids</parameter>
+                <parameter datatype="string">next_trigger_time</parameter>
+                <parameter datatype="string">created_time</parameter>
+            </output-parameters>
+        </interface>
+        <interface name="unbind-policy" path="/v1/qcloud/snapshot/unbind-policy">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">disk_ids</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">id</parameter>
+                <parameter datatype="string">name</parameter>
+                <parameter datatype="string">state</parameter>
+                <parameter datatype="string">is_activated</parameter>
+                <parameter datatype="string">day_of_week</parameter>
+                <parameter datatype="string">hour</parameter>
+                <parameter datatype="string">retention_days</parameter>
+                <parameter datatype="string">disk_ids</parameter>
+                <parameter datatype="string">next_trigger_time</parameter>
+                <parameter datatype="string">created_time</parameter>
+            </output-parameters>
+        </interface>
     </plugin>
     <plugin id="nat-gateway" name="Nat Gateway Management">
         <interface name="create" path="/v1/qcloud/nat-gateway/create">
@@ -308,6 +1036,7 @@
                 <parameter datatype="number">bandwidth</parameter>
                 <parameter datatype="string">assigned_eip_set</parameter>
                 <parameter datatype="number">auto_alloc_eip_num</parameter>
+                <parameter datatype="string">idempotency_key</parameter>
             </input-parameters>
             <output-parameters>
                 <parameter datatype="string">guid</parameter>
@@ -344,6 +1073,7 @@
                 <parameter datatype="string">id</parameter>
                 <parameter datatype="string">character_set</parameter>
                 <parameter datatype="string">lower_case_table_names</parameter>
+                <parameter datatype="string">idempotency_key</parameter>
             </input-parameters>
             <output-parameters>
                 <parameter datatype="string">guid</parameter>
@@ -393,6 +1123,7 @@
                 <parameter datatype="string">character_set</parameter>
                 <parameter datatype="string">lower_case_table_names</parameter>
                 <parameter datatype="string">id</parameter>
+                <parameter datatype="string">idempotency_key</parameter>
             </input-parameters>
             <output-parameters>
                 <parameter datatype="string">guid</parameter>
@@ -416,6 +1147,7 @@
                 <parameter datatype="string">gateway_type</parameter>
                 <parameter datatype="string">gateway_id</parameter>
                 <parameter datatype="string">desc</parameter>
+                <parameter datatype="string">overwrite</parameter>
             </input-parameters>
             <output-parameters>
                 <parameter datatype="string">guid</parameter>
@@ -449,6 +1181,7 @@
                 <parameter datatype="string">vpc_id</parameter>
                 <parameter datatype="string">subnet_id</parameter>
                 <parameter datatype="string">id</parameter>
+                <parameter datatype="string">idempotency_key</parameter>
             </input-parameters>
             <output-parameters>
                 <parameter datatype="string">guid</parameter>
@@ -456,4 +1189,23 @@
             </output-parameters>
         </interface>
     </plugin>
+    <plugin id="ipam" name="IP Address Management">
+        <interface name="check" path="/v1/qcloud/ipam/check">
+            <input-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">provider_params</parameter>
+                <parameter datatype="string">regions</parameter>
+            </input-parameters>
+            <output-parameters>
+                <parameter datatype="string">guid</parameter>
+                <parameter datatype="string">region</parameter>
+                <parameter datatype="string">vpc_id</parameter>
+                <parameter datatype="string">cidr_block</parameter>
+                <parameter datatype="string">peer_region</parameter>
+                <parameter datatype="string">peer_vpc_id</parameter>
+                <parameter datatype="string">peer_cidr_block</parameter>
+                <parameter datatype="string">overlap_cidr_block</parameter>
+            </output-parameters>
+        </interface>
+    </plugin>
 </package>
@@ -15,6 +15,8 @@ type AppConfig struct {
 	HttpPort        string
 	CMDBLink        string
 	CMDBUserAuthKey string
+
+	IdempotencyStoreDir string
 }
 
 type AppConfigMgr struct {
@@ -45,6 +47,8 @@ func InitConfig(file string) {
 		return
 	}
 
+	GobalAppConfig.IdempotencyStoreDir = conf.GetIStringDefault("idempotency_store_dir", "./data/idempotency")
+
 	AppConfMgr.Config.Store(GobalAppConfig)
 }
 
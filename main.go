@@ -1,10 +1,13 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	_ "github.com/WeBankPartners/wecube-plugins-qcloud/plugins/bussiness_plugins/security_group"
+	"io/ioutil"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/WeBankPartners/wecube-plugins-qcloud/conf"
@@ -52,11 +55,21 @@ func initLogger() {
 
 func initConfig() {
 	conf.InitConfig(CONF_FILE_PATH)
+	plugins.InitIdempotencyStore(conf.GobalAppConfig.IdempotencyStoreDir)
 }
 
 func initRouter() {
-	//path should be defined as "/[version]/[provider]/[plugin]/[action]"
-	http.HandleFunc("/v1/qcloud/", routeDispatcher)
+	//path should be defined as "/[version]/[provider]/[plugin]/[action]", version and provider are checked by plugins.Process
+	http.HandleFunc("/", routeDispatcher)
+	http.HandleFunc("/catalogue", catalogueHandler)
+}
+
+func catalogueHandler(w http.ResponseWriter, r *http.Request) {
+	write(w, &plugins.PluginResponse{
+		ResultCode: "0",
+		ResultMsg:  "success",
+		Results:    plugins.GetCatalogue(),
+	})
 }
 
 func routeDispatcher(w http.ResponseWriter, r *http.Request) {
@@ -85,7 +98,28 @@ func parsePluginRequest(r *http.Request) *plugins.PluginRequest {
 		pluginInput.Name = pathStrings[3]
 		pluginInput.Action = pathStrings[4]
 	}
-	pluginInput.Parameters = r.Body
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		logrus.Errorf("read http request body meet error (%v)", err)
+	}
+	pluginInput.DryRun = isDryRunRequest(r, body)
+	pluginInput.Parameters = bytes.NewReader(body)
 	logrus.Infof("parsed request = %v", pluginInput)
 	return &pluginInput
 }
+
+//dry run can be asked by query param "?dry_run=true" or by "dry_run":true in the request body
+func isDryRunRequest(r *http.Request, body []byte) bool {
+	if dryRun, err := strconv.ParseBool(r.URL.Query().Get("dry_run")); err == nil && dryRun {
+		return true
+	}
+
+	flag := struct {
+		DryRun bool `json:"dry_run"`
+	}{}
+	if err := json.Unmarshal(body, &flag); err != nil {
+		return false
+	}
+	return flag.DryRun
+}
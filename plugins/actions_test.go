@@ -0,0 +1,3699 @@
+package plugins
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+//mockActionCase runs one action of a plugin with mock clients, or previews it with dryRun,
+//wantCalls is the apis called in order and wantOutput is checked against the first output,
+//retry runs the action again with the same idempotency store after the case is checked,
+//it takes the action and input of the case when its action is empty
+type mockActionCase struct {
+	name       string
+	action     string
+	dryRun     bool
+	input      map[string]interface{}
+	responses  map[string][]mockResponse
+	wantErr    string
+	wantCalls  []string
+	wantOutput map[string]string
+	slow       bool
+	retry      *mockActionCase
+}
+
+func runMockActionCases(t *testing.T, plugin string, cases []mockActionCase) {
+	for _, c := range cases {
+		c := c
+		name := c.action + "/" + c.name
+		if c.dryRun {
+			name = c.action + "/dry run " + c.name
+		}
+		t.Run(name, func(t *testing.T) {
+			if c.slow && testing.Short() {
+				t.Skip("the action sleeps between qcloud calls")
+			}
+			clients, cleanup := setupMockClients(t, c.responses)
+			defer cleanup()
+
+			runMockAction(t, clients, plugin, &c)
+			for retry := c.retry; retry != nil; retry = retry.retry {
+				if retry.action == "" {
+					retry.action, retry.input = c.action, c.input
+				}
+				clients.responses, clients.calls = map[string][]mockResponse{}, nil
+				for api, apiResponses := range retry.responses {
+					clients.responses[api] = append([]mockResponse{}, apiResponses...)
+				}
+				runMockAction(t, clients, plugin, retry)
+			}
+		})
+	}
+}
+
+func runMockAction(t *testing.T, clients *mockClients, plugin string, c *mockActionCase) {
+	c.input["provider_params"] = MOCK_PROVIDER_PARAMS
+	body, _ := json.Marshal(map[string]interface{}{"inputs": []map[string]interface{}{c.input}})
+	response, err := Process(&PluginRequest{
+		Version:      DEFAULT_API_VERSION,
+		ProviderName: DEFAULT_PROVIDER_NAME,
+		Name:         plugin,
+		Action:       c.action,
+		Parameters:   bytes.NewReader(body),
+		DryRun:       c.dryRun,
+	})
+	if c.wantErr == "" && err != nil {
+		t.Fatalf("%s-%s meet err=%v", plugin, c.action, err)
+	}
+	if c.wantErr != "" && (err == nil || !strings.Contains(err.Error(), c.wantErr)) {
+		t.Fatalf("%s-%s err=%v,want %s", plugin, c.action, err, c.wantErr)
+	}
+	if !reflect.DeepEqual(clients.calls, c.wantCalls) {
+		t.Fatalf("calls=%v,want %v", clients.calls, c.wantCalls)
+	}
+	if len(c.wantOutput) == 0 {
+		return
+	}
+
+	var results struct {
+		Outputs []map[string]interface{} `json:"outputs"`
+	}
+	resultBytes, _ := json.Marshal(response.Results)
+	if err = json.Unmarshal(resultBytes, &results); err != nil || len(results.Outputs) == 0 {
+		t.Fatalf("results %s have no outputs, err=%v", resultBytes, err)
+	}
+	for key, want := range c.wantOutput {
+		if got := fmt.Sprint(results.Outputs[0][key]); got != want {
+			t.Fatalf("output %s=%s,want %s", key, got, want)
+		}
+	}
+}
+
+func TestVpcActions(t *testing.T) {
+	runMockActionCases(t, "vpc", []mockActionCase{
+		{
+			name:   "success",
+			action: "create",
+			input:  map[string]interface{}{"guid": "guid", "name": "vpc", "cidr_block": "10.0.0.0/16"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs": {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.1.0.0/16"}]}`)},
+				"vpc.CreateVpc":    {mockOk(`{"Vpc":{"VpcId":"vpc-new"}}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeVpcs", "vpc.CreateVpc"},
+			wantOutput: map[string]string{"guid": "guid", "id": "vpc-new"},
+		},
+		{
+			name:      "cidr overlaps",
+			action:    "create",
+			input:     map[string]interface{}{"guid": "guid", "name": "vpc", "cidr_block": "10.0.0.0/16"},
+			responses: map[string][]mockResponse{"vpc.DescribeVpcs": {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"172.16.0.0/16","AssistantCidrSet":[{"CidrBlock":"10.0.128.0/17"}]}]}`)}},
+			wantErr:   "cidr_block(10.0.0.0/16) overlaps with 10.0.128.0/17 of vpc[vpc-1]",
+			wantCalls: []string{"vpc.DescribeVpcs"},
+		},
+		{
+			name:       "already exists",
+			action:     "create",
+			input:      map[string]interface{}{"guid": "guid", "id": "vpc-exist", "name": "vpc", "cidr_block": "10.0.0.0/16"},
+			responses:  map[string][]mockResponse{"vpc.DescribeVpcs": {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-exist"}]}`)}},
+			wantCalls:  []string{"vpc.DescribeVpcs"},
+			wantOutput: map[string]string{"id": "vpc-exist"},
+		},
+		{
+			name:   "not found",
+			action: "create",
+			input:  map[string]interface{}{"guid": "guid", "id": "vpc-gone", "name": "vpc", "cidr_block": "10.0.0.0/16"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs": {mockOk(`{"TotalCount":0,"VpcSet":[]}`), mockOk(`{"TotalCount":0,"VpcSet":[]}`)},
+				"vpc.CreateVpc":    {mockOk(`{"Vpc":{"VpcId":"vpc-new"}}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeVpcs", "vpc.DescribeVpcs", "vpc.CreateVpc"},
+			wantOutput: map[string]string{"id": "vpc-new"},
+		},
+		{
+			name:   "error",
+			action: "create",
+			input:  map[string]interface{}{"guid": "guid", "name": "vpc", "cidr_block": "10.0.0.0/16"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs": {mockOk(`{"TotalCount":0,"VpcSet":[]}`)},
+				"vpc.CreateVpc":    {mockError("LimitExceeded")},
+			},
+			wantErr:   "LimitExceeded",
+			wantCalls: []string{"vpc.DescribeVpcs", "vpc.CreateVpc"},
+		},
+		{
+			name:       "success",
+			action:     "terminate",
+			input:      map[string]interface{}{"guid": "guid", "id": "vpc-1"},
+			responses:  map[string][]mockResponse{"vpc.DeleteVpc": {mockOk(`{}`)}},
+			wantCalls:  []string{"vpc.DeleteVpc"},
+			wantOutput: map[string]string{"id": "vpc-1"},
+		},
+		{
+			name:      "not found",
+			action:    "terminate",
+			input:     map[string]interface{}{"guid": "guid", "id": "vpc-gone"},
+			responses: map[string][]mockResponse{"vpc.DeleteVpc": {mockError("ResourceNotFound")}},
+			wantErr:   "ResourceNotFound",
+			wantCalls: []string{"vpc.DeleteVpc"},
+		},
+		{
+			name:      "error",
+			action:    "terminate",
+			input:     map[string]interface{}{"guid": "guid", "id": "vpc-1"},
+			responses: map[string][]mockResponse{"vpc.DeleteVpc": {mockError("ResourceInUse")}},
+			wantErr:   "ResourceInUse",
+			wantCalls: []string{"vpc.DeleteVpc"},
+		},
+		{
+			name:   "success",
+			action: "describe",
+			input:  map[string]interface{}{"guid": "guid", "id": "vpc-1"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs":                  {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","VpcName":"vpc","CidrBlock":"10.0.0.0/16","DnsServerSet":["183.60.83.19","183.60.82.98"]}]}`)},
+				"vpc.DescribeSubnets":               {mockOk(`{"TotalCount":1,"SubnetSet":[{"SubnetId":"subnet-1","VpcId":"vpc-1"}]}`)},
+				"vpc.DescribeRouteTables":           {mockOk(`{"TotalCount":1,"RouteTableSet":[{"RouteTableId":"rtb-1","VpcId":"vpc-1","RouteSet":[]}]}`)},
+				"vpc.DescribeNatGateways":           {mockOk(`{"TotalCount":0,"NatGatewaySet":[]}`)},
+				"vpc.DescribeVpcPeeringConnections": {mockOk(`{"TotalCount":1,"PeerConnectionSet":[{"PeeringConnectionId":"pcx-1","SourceVpcId":"vpc-1","PeerVpcId":"vpc-2"}]}`)},
+				"vpc.DescribeNetworkInterfaces":     {mockOk(`{"TotalCount":2,"NetworkInterfaceSet":[{"NetworkInterfaceId":"eni-1","GroupSet":["sg-1"]},{"NetworkInterfaceId":"eni-2","GroupSet":["sg-1"]}]}`)},
+				"vpc.DescribeSecurityGroups":        {mockOk(`{"TotalCount":1,"SecurityGroupSet":[{"SecurityGroupId":"sg-1"}]}`)},
+			},
+			wantCalls: []string{"vpc.DescribeVpcs", "vpc.DescribeSubnets", "vpc.DescribeRouteTables", "vpc.DescribeNatGateways",
+				"vpc.DescribeVpcPeeringConnections", "vpc.DescribeNetworkInterfaces", "vpc.DescribeSecurityGroups"},
+			wantOutput: map[string]string{"id": "vpc-1", "name": "vpc", "dns_servers": "183.60.83.19,183.60.82.98"},
+		},
+		{
+			name:      "not found",
+			action:    "describe",
+			input:     map[string]interface{}{"guid": "guid", "id": "vpc-gone"},
+			responses: map[string][]mockResponse{"vpc.DescribeVpcs": {mockOk(`{"TotalCount":0,"VpcSet":[]}`)}},
+			wantErr:   "vpc[vpc-gone] not found",
+			wantCalls: []string{"vpc.DescribeVpcs"},
+		},
+		{
+			name:   "error",
+			action: "describe",
+			input:  map[string]interface{}{"guid": "guid", "id": "vpc-1"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs":                  {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1"}]}`)},
+				"vpc.DescribeSubnets":               {mockOk(`{"TotalCount":0,"SubnetSet":[]}`)},
+				"vpc.DescribeRouteTables":           {mockOk(`{"TotalCount":0,"RouteTableSet":[]}`)},
+				"vpc.DescribeNatGateways":           {mockOk(`{"TotalCount":0,"NatGatewaySet":[]}`)},
+				"vpc.DescribeVpcPeeringConnections": {mockError("UnsupportedOperation")},
+			},
+			wantErr:   "UnsupportedOperation",
+			wantCalls: []string{"vpc.DescribeVpcs", "vpc.DescribeSubnets", "vpc.DescribeRouteTables", "vpc.DescribeNatGateways", "vpc.DescribeVpcPeeringConnections"},
+		},
+		{
+			name:   "success",
+			action: "modify",
+			input:  map[string]interface{}{"guid": "guid", "id": "vpc-1", "name": "vpc-new", "dns_servers": "10.0.0.2", "domain_name": "example.com"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs": {
+					mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","VpcName":"vpc","DnsServerSet":["10.0.0.2"]}]}`),
+					mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","VpcName":"vpc-new","DnsServerSet":["10.0.0.2"],"DomainName":"example.com"}]}`),
+				},
+				"vpc.ModifyVpcAttribute": {mockOk(`{}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeVpcs", "vpc.ModifyVpcAttribute", "vpc.DescribeVpcs"},
+			wantOutput: map[string]string{"name": "vpc-new", "domain_name": "example.com", "request_id": MOCK_REQUEST_ID},
+		},
+		{
+			name:       "unchanged",
+			action:     "modify",
+			input:      map[string]interface{}{"guid": "guid", "id": "vpc-1", "name": "vpc", "dns_servers": "10.0.0.2,10.0.0.3"},
+			responses:  map[string][]mockResponse{"vpc.DescribeVpcs": {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","VpcName":"vpc","DnsServerSet":["10.0.0.2","10.0.0.3"]}]}`)}},
+			wantCalls:  []string{"vpc.DescribeVpcs"},
+			wantOutput: map[string]string{"name": "vpc", "dns_servers": "10.0.0.2,10.0.0.3"},
+		},
+		{
+			name:   "error",
+			action: "modify",
+			input:  map[string]interface{}{"guid": "guid", "id": "vpc-1", "name": "vpc-new"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs":       {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","VpcName":"vpc"}]}`)},
+				"vpc.ModifyVpcAttribute": {mockError("InvalidParameterValue")},
+			},
+			wantErr:   "InvalidParameterValue",
+			wantCalls: []string{"vpc.DescribeVpcs", "vpc.ModifyVpcAttribute"},
+		},
+		{
+			name:    "nothing to modify",
+			action:  "modify",
+			input:   map[string]interface{}{"guid": "guid", "id": "vpc-1"},
+			wantErr: "input name, dns_servers and domain_name are all empty",
+		},
+		{
+			name:    "invalid dns server",
+			action:  "modify",
+			input:   map[string]interface{}{"guid": "guid", "id": "vpc-1", "dns_servers": "10.0.0.2,dns"},
+			wantErr: "invalid dns server(dns)",
+		},
+		{
+			name:    "too many dns servers",
+			action:  "modify",
+			input:   map[string]interface{}{"guid": "guid", "id": "vpc-1", "dns_servers": "10.0.0.2,10.0.0.3,10.0.0.4,10.0.0.5,10.0.0.6"},
+			wantErr: "has more than 4 servers",
+		},
+		{
+			name:   "success",
+			action: "add-cidrs",
+			input:  map[string]interface{}{"guid": "guid", "id": "vpc-1", "secondary_cidr_blocks": "10.1.0.0/16"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs": {
+					mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16"}]}`),
+					mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16","AssistantCidrSet":[{"VpcId":"vpc-1","CidrBlock":"10.1.0.0/16","AssistantType":0}]}]}`),
+				},
+				"vpc.CreateAssistantCidr": {mockOk(`{"AssistantCidrSet":[{"VpcId":"vpc-1","CidrBlock":"10.1.0.0/16","AssistantType":0}]}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeVpcs", "vpc.CreateAssistantCidr", "vpc.DescribeVpcs"},
+			wantOutput: map[string]string{"id": "vpc-1", "secondary_cidr_blocks": "10.1.0.0/16"},
+		},
+		{
+			name:       "already added",
+			action:     "add-cidrs",
+			input:      map[string]interface{}{"guid": "guid", "id": "vpc-1", "secondary_cidr_blocks": "10.1.0.0/16"},
+			responses:  map[string][]mockResponse{"vpc.DescribeVpcs": {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16","AssistantCidrSet":[{"VpcId":"vpc-1","CidrBlock":"10.1.0.0/16","AssistantType":0}]}]}`)}},
+			wantCalls:  []string{"vpc.DescribeVpcs"},
+			wantOutput: map[string]string{"secondary_cidr_blocks": "10.1.0.0/16"},
+		},
+		{
+			name:      "overlaps primary",
+			action:    "add-cidrs",
+			input:     map[string]interface{}{"guid": "guid", "id": "vpc-1", "secondary_cidr_blocks": "10.0.128.0/17"},
+			responses: map[string][]mockResponse{"vpc.DescribeVpcs": {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16"}]}`)}},
+			wantErr:   "overlaps with 10.0.0.0/16 of vpc[vpc-1]",
+			wantCalls: []string{"vpc.DescribeVpcs"},
+		},
+		{
+			name:    "overlapping inputs",
+			action:  "add-cidrs",
+			input:   map[string]interface{}{"guid": "guid", "id": "vpc-1", "secondary_cidr_blocks": "10.1.0.0/16,10.1.1.0/24"},
+			wantErr: "secondary cidr block(10.1.1.0/24) overlaps with 10.1.0.0/16",
+		},
+		{
+			name:    "invalid cidr",
+			action:  "add-cidrs",
+			input:   map[string]interface{}{"guid": "guid", "id": "vpc-1", "secondary_cidr_blocks": "10.1.0.0"},
+			wantErr: "invalid secondary cidr block(10.1.0.0)",
+		},
+		{
+			name:   "success",
+			action: "remove-cidrs",
+			input:  map[string]interface{}{"guid": "guid", "id": "vpc-1", "secondary_cidr_blocks": "10.1.0.0/16"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs": {
+					mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16","AssistantCidrSet":[{"VpcId":"vpc-1","CidrBlock":"10.1.0.0/16","AssistantType":0}]}]}`),
+					mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16"}]}`),
+				},
+				"vpc.DescribeSubnets":     {mockOk(`{"TotalCount":1,"SubnetSet":[{"SubnetId":"subnet-1","CidrBlock":"10.0.1.0/24"}]}`)},
+				"vpc.DeleteAssistantCidr": {mockOk(`{}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeVpcs", "vpc.DescribeSubnets", "vpc.DeleteAssistantCidr", "vpc.DescribeVpcs"},
+			wantOutput: map[string]string{"id": "vpc-1"},
+		},
+		{
+			name:   "still has subnets",
+			action: "remove-cidrs",
+			input:  map[string]interface{}{"guid": "guid", "id": "vpc-1", "secondary_cidr_blocks": "10.1.0.0/16"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs":    {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16","AssistantCidrSet":[{"VpcId":"vpc-1","CidrBlock":"10.1.0.0/16","AssistantType":0}]}]}`)},
+				"vpc.DescribeSubnets": {mockOk(`{"TotalCount":1,"SubnetSet":[{"SubnetId":"subnet-1","CidrBlock":"10.1.1.0/24"}]}`)},
+			},
+			wantErr:   "secondary cidr block(10.1.0.0/16) still has subnet[subnet-1]",
+			wantCalls: []string{"vpc.DescribeVpcs", "vpc.DescribeSubnets"},
+		},
+		{
+			name:      "primary cidr",
+			action:    "remove-cidrs",
+			input:     map[string]interface{}{"guid": "guid", "id": "vpc-1", "secondary_cidr_blocks": "10.0.0.0/16"},
+			responses: map[string][]mockResponse{"vpc.DescribeVpcs": {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16"}]}`)}},
+			wantErr:   "cidr block(10.0.0.0/16) is the primary cidr block of vpc[vpc-1]",
+			wantCalls: []string{"vpc.DescribeVpcs"},
+		},
+		{
+			name:   "success",
+			action: "assign-ipv6",
+			input:  map[string]interface{}{"guid": "guid", "id": "vpc-1"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs":        {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16"}]}`)},
+				"vpc.AssignIpv6CidrBlock": {mockOk(`{"Ipv6CidrBlock":"2402:4e00:1000:100::/56"}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeVpcs", "vpc.AssignIpv6CidrBlock"},
+			wantOutput: map[string]string{"ipv6_cidr_block": "2402:4e00:1000:100::/56"},
+		},
+		{
+			name:       "already assigned",
+			action:     "assign-ipv6",
+			input:      map[string]interface{}{"guid": "guid", "id": "vpc-1"},
+			responses:  map[string][]mockResponse{"vpc.DescribeVpcs": {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16","Ipv6CidrBlock":"2402:4e00:1000:100::/56"}]}`)}},
+			wantCalls:  []string{"vpc.DescribeVpcs"},
+			wantOutput: map[string]string{"ipv6_cidr_block": "2402:4e00:1000:100::/56"},
+		},
+		{
+			name:      "not found",
+			action:    "assign-ipv6",
+			input:     map[string]interface{}{"guid": "guid", "id": "vpc-gone"},
+			responses: map[string][]mockResponse{"vpc.DescribeVpcs": {mockOk(`{"TotalCount":0,"VpcSet":[]}`)}},
+			wantErr:   "vpc[vpc-gone] not found",
+			wantCalls: []string{"vpc.DescribeVpcs"},
+		},
+	})
+}
+
+func TestSubnetActions(t *testing.T) {
+	runMockActionCases(t, "subnet", []mockActionCase{
+		{
+			name:   "success",
+			action: "create",
+			input:  map[string]interface{}{"guid": "guid", "name": "subnet", "cidr_block": "10.0.1.0/24", "vpc_id": "vpc-1"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs": {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16"}]}`)},
+				"vpc.CreateSubnet": {mockOk(`{"Subnet":{"SubnetId":"subnet-new"}}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeVpcs", "vpc.CreateSubnet"},
+			wantOutput: map[string]string{"id": "subnet-new"},
+		},
+		{
+			name:   "already exists",
+			action: "create",
+			input:  map[string]interface{}{"guid": "guid", "id": "subnet-exist", "name": "subnet", "cidr_block": "10.0.1.0/24", "vpc_id": "vpc-1"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs":    {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16"}]}`)},
+				"vpc.DescribeSubnets": {mockOk(`{"TotalCount":1,"SubnetSet":[{"SubnetId":"subnet-exist"}]}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeVpcs", "vpc.DescribeSubnets"},
+			wantOutput: map[string]string{"id": "subnet-exist"},
+		},
+		{
+			name:   "not found",
+			action: "create",
+			input:  map[string]interface{}{"guid": "guid", "id": "subnet-gone", "name": "subnet", "cidr_block": "10.0.1.0/24", "vpc_id": "vpc-1"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs":    {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16"}]}`)},
+				"vpc.DescribeSubnets": {mockOk(`{"TotalCount":0,"SubnetSet":[]}`)},
+				"vpc.CreateSubnet":    {mockOk(`{"Subnet":{"SubnetId":"subnet-new"}}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeVpcs", "vpc.DescribeSubnets", "vpc.CreateSubnet"},
+			wantOutput: map[string]string{"id": "subnet-new"},
+		},
+		{
+			name:   "error",
+			action: "create",
+			input:  map[string]interface{}{"guid": "guid", "name": "subnet", "cidr_block": "10.0.1.0/24", "vpc_id": "vpc-1"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs": {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16"}]}`)},
+				"vpc.CreateSubnet": {mockError("InvalidParameterValue.SubnetConflict")},
+			},
+			wantErr:   "InvalidParameterValue.SubnetConflict",
+			wantCalls: []string{"vpc.DescribeVpcs", "vpc.CreateSubnet"},
+		},
+		{
+			name:       "success",
+			action:     "terminate",
+			input:      map[string]interface{}{"guid": "guid", "id": "subnet-1"},
+			responses:  map[string][]mockResponse{"vpc.DeleteSubnet": {mockOk(`{}`)}},
+			wantCalls:  []string{"vpc.DeleteSubnet"},
+			wantOutput: map[string]string{"id": "subnet-1"},
+		},
+		{
+			name:      "not found",
+			action:    "terminate",
+			input:     map[string]interface{}{"guid": "guid", "id": "subnet-gone"},
+			responses: map[string][]mockResponse{"vpc.DeleteSubnet": {mockError("ResourceNotFound")}},
+			wantErr:   "ResourceNotFound",
+			wantCalls: []string{"vpc.DeleteSubnet"},
+		},
+		{
+			name:   "success",
+			action: "create-with-routetable",
+			input:  map[string]interface{}{"guid": "guid", "name": "subnet", "cidr_block": "10.0.1.0/24", "vpc_id": "vpc-1"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs":                 {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16"}]}`)},
+				"vpc.CreateSubnet":                 {mockOk(`{"Subnet":{"SubnetId":"subnet-new"}}`)},
+				"vpc.CreateRouteTable":             {mockOk(`{"RouteTable":{"RouteTableId":"rtb-new"}}`)},
+				"vpc.ReplaceRouteTableAssociation": {mockOk(`{}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeVpcs", "vpc.CreateSubnet", "vpc.CreateRouteTable", "vpc.ReplaceRouteTableAssociation"},
+			wantOutput: map[string]string{"id": "subnet-new", "route_table_id": "rtb-new"},
+		},
+		{
+			name:   "already exists",
+			action: "create-with-routetable",
+			input:  map[string]interface{}{"guid": "guid", "id": "subnet-exist", "route_table_id": "rtb-exist", "name": "subnet", "cidr_block": "10.0.1.0/24", "vpc_id": "vpc-1"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs":                 {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16"}]}`)},
+				"vpc.DescribeSubnets":              {mockOk(`{"TotalCount":1,"SubnetSet":[{"SubnetId":"subnet-exist"}]}`)},
+				"vpc.DescribeRouteTables":          {mockOk(`{"TotalCount":1,"RouteTableSet":[{"RouteTableId":"rtb-exist"}]}`)},
+				"vpc.ReplaceRouteTableAssociation": {mockOk(`{}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeVpcs", "vpc.DescribeSubnets", "vpc.DescribeRouteTables", "vpc.ReplaceRouteTableAssociation"},
+			wantOutput: map[string]string{"id": "subnet-exist", "route_table_id": "rtb-exist"},
+		},
+		{
+			name:   "error rolls back",
+			action: "create-with-routetable",
+			input:  map[string]interface{}{"guid": "guid", "name": "subnet", "cidr_block": "10.0.1.0/24", "vpc_id": "vpc-1"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs":                 {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16"}]}`)},
+				"vpc.CreateSubnet":                 {mockOk(`{"Subnet":{"SubnetId":"subnet-new"}}`)},
+				"vpc.CreateRouteTable":             {mockOk(`{"RouteTable":{"RouteTableId":"rtb-new"}}`)},
+				"vpc.ReplaceRouteTableAssociation": {mockError("InternalError")},
+				"vpc.DeleteSubnet":                 {mockOk(`{}`)},
+				"vpc.DeleteRouteTable":             {mockOk(`{}`)},
+			},
+			wantErr:   "InternalError",
+			wantCalls: []string{"vpc.DescribeVpcs", "vpc.CreateSubnet", "vpc.CreateRouteTable", "vpc.ReplaceRouteTableAssociation", "vpc.DeleteSubnet", "vpc.DeleteRouteTable"},
+		},
+		{
+			name:   "success",
+			action: "terminate-with-routetable",
+			input:  map[string]interface{}{"guid": "guid", "id": "subnet-1", "route_table_id": "rtb-1"},
+			responses: map[string][]mockResponse{
+				"vpc.DeleteSubnet":     {mockOk(`{}`)},
+				"vpc.DeleteRouteTable": {mockOk(`{}`)},
+			},
+			wantCalls:  []string{"vpc.DeleteSubnet", "vpc.DeleteRouteTable"},
+			wantOutput: map[string]string{"id": "subnet-1"},
+		},
+		{
+			name:   "not found",
+			action: "terminate-with-routetable",
+			input:  map[string]interface{}{"guid": "guid", "id": "subnet-gone", "route_table_id": "rtb-1"},
+			responses: map[string][]mockResponse{
+				"vpc.DeleteSubnet":     {mockError("ResourceNotFound")},
+				"vpc.DeleteRouteTable": {mockOk(`{}`)},
+			},
+			wantErr:   "ResourceNotFound",
+			wantCalls: []string{"vpc.DeleteSubnet", "vpc.DeleteRouteTable"},
+		},
+		{
+			name:   "in secondary cidr with ipv6",
+			action: "create",
+			input:  map[string]interface{}{"guid": "guid", "name": "subnet", "cidr_block": "10.1.1.0/24", "ipv6_cidr_block": "2402:4e00:1000:101::/64", "vpc_id": "vpc-1"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs":              {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16","AssistantCidrSet":[{"VpcId":"vpc-1","CidrBlock":"10.1.0.0/16","AssistantType":0}],"Ipv6CidrBlock":"2402:4e00:1000:100::/56"}]}`)},
+				"vpc.CreateSubnet":              {mockOk(`{"Subnet":{"SubnetId":"subnet-new"}}`)},
+				"vpc.AssignIpv6SubnetCidrBlock": {mockOk(`{}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeVpcs", "vpc.CreateSubnet", "vpc.AssignIpv6SubnetCidrBlock"},
+			wantOutput: map[string]string{"id": "subnet-new", "ipv6_cidr_block": "2402:4e00:1000:101::/64"},
+		},
+		{
+			name:   "ipv6 error rolls back",
+			action: "create",
+			input:  map[string]interface{}{"guid": "guid", "name": "subnet", "cidr_block": "10.0.1.0/24", "ipv6_cidr_block": "2402:4e00:1000:101::/64", "vpc_id": "vpc-1"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs":              {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16","AssistantCidrSet":[{"VpcId":"vpc-1","CidrBlock":"10.1.0.0/16","AssistantType":0}],"Ipv6CidrBlock":"2402:4e00:1000:100::/56"}]}`)},
+				"vpc.CreateSubnet":              {mockOk(`{"Subnet":{"SubnetId":"subnet-new"}}`)},
+				"vpc.AssignIpv6SubnetCidrBlock": {mockError("InvalidParameterValue.SubnetRange")},
+				"vpc.DeleteSubnet":              {mockOk(`{}`)},
+			},
+			wantErr:   "InvalidParameterValue.SubnetRange",
+			wantCalls: []string{"vpc.DescribeVpcs", "vpc.CreateSubnet", "vpc.AssignIpv6SubnetCidrBlock", "vpc.DeleteSubnet"},
+		},
+		{
+			name:      "out of vpc cidrs",
+			action:    "create",
+			input:     map[string]interface{}{"guid": "guid", "name": "subnet", "cidr_block": "10.2.1.0/24", "vpc_id": "vpc-1"},
+			responses: map[string][]mockResponse{"vpc.DescribeVpcs": {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16","AssistantCidrSet":[{"VpcId":"vpc-1","CidrBlock":"10.1.0.0/16","AssistantType":0}],"Ipv6CidrBlock":"2402:4e00:1000:100::/56"}]}`)}},
+			wantErr:   "cidr_block(10.2.1.0/24) is not in the cidr blocks[10.0.0.0/16,10.1.0.0/16] of vpc[vpc-1]",
+			wantCalls: []string{"vpc.DescribeVpcs"},
+		},
+		{
+			name:      "vpc without ipv6",
+			action:    "create",
+			input:     map[string]interface{}{"guid": "guid", "name": "subnet", "cidr_block": "10.0.1.0/24", "ipv6_cidr_block": "2402:4e00:1000:101::/64", "vpc_id": "vpc-1"},
+			responses: map[string][]mockResponse{"vpc.DescribeVpcs": {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16"}]}`)}},
+			wantErr:   "vpc[vpc-1] has no ipv6 cidr block",
+			wantCalls: []string{"vpc.DescribeVpcs"},
+		},
+		{
+			name:    "invalid ipv6",
+			action:  "create",
+			input:   map[string]interface{}{"guid": "guid", "name": "subnet", "cidr_block": "10.0.1.0/24", "ipv6_cidr_block": "2402:4e00:1000:100::/56", "vpc_id": "vpc-1"},
+			wantErr: "invalid ipv6_cidr_block [2402:4e00:1000:100::/56]",
+		},
+		{
+			name:   "success",
+			action: "assign-ipv6",
+			input:  map[string]interface{}{"guid": "guid", "id": "subnet-1", "vpc_id": "vpc-1", "ipv6_cidr_block": "2402:4e00:1000:101::/64"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs":              {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16","AssistantCidrSet":[{"VpcId":"vpc-1","CidrBlock":"10.1.0.0/16","AssistantType":0}],"Ipv6CidrBlock":"2402:4e00:1000:100::/56"}]}`)},
+				"vpc.DescribeSubnets":           {mockOk(`{"TotalCount":1,"SubnetSet":[{"SubnetId":"subnet-1","VpcId":"vpc-1"}]}`)},
+				"vpc.AssignIpv6SubnetCidrBlock": {mockOk(`{}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeVpcs", "vpc.DescribeSubnets", "vpc.AssignIpv6SubnetCidrBlock"},
+			wantOutput: map[string]string{"id": "subnet-1", "ipv6_cidr_block": "2402:4e00:1000:101::/64"},
+		},
+		{
+			name:   "already assigned",
+			action: "assign-ipv6",
+			input:  map[string]interface{}{"guid": "guid", "id": "subnet-1", "vpc_id": "vpc-1", "ipv6_cidr_block": "2402:4e00:1000:101::/64"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs":    {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16","AssistantCidrSet":[{"VpcId":"vpc-1","CidrBlock":"10.1.0.0/16","AssistantType":0}],"Ipv6CidrBlock":"2402:4e00:1000:100::/56"}]}`)},
+				"vpc.DescribeSubnets": {mockOk(`{"TotalCount":1,"SubnetSet":[{"SubnetId":"subnet-1","VpcId":"vpc-1","Ipv6CidrBlock":"2402:4e00:1000:101::/64"}]}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeVpcs", "vpc.DescribeSubnets"},
+			wantOutput: map[string]string{"id": "subnet-1", "ipv6_cidr_block": "2402:4e00:1000:101::/64"},
+		},
+		{
+			name:   "other ipv6 assigned",
+			action: "assign-ipv6",
+			input:  map[string]interface{}{"guid": "guid", "id": "subnet-1", "vpc_id": "vpc-1", "ipv6_cidr_block": "2402:4e00:1000:101::/64"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs":    {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16","AssistantCidrSet":[{"VpcId":"vpc-1","CidrBlock":"10.1.0.0/16","AssistantType":0}],"Ipv6CidrBlock":"2402:4e00:1000:100::/56"}]}`)},
+				"vpc.DescribeSubnets": {mockOk(`{"TotalCount":1,"SubnetSet":[{"SubnetId":"subnet-1","VpcId":"vpc-1","Ipv6CidrBlock":"2402:4e00:1000:102::/64"}]}`)},
+			},
+			wantErr:   "subnet[subnet-1] already has ipv6 cidr block(2402:4e00:1000:102::/64)",
+			wantCalls: []string{"vpc.DescribeVpcs", "vpc.DescribeSubnets"},
+		},
+		{
+			name:   "prefix length",
+			action: "create",
+			input:  map[string]interface{}{"guid": "guid", "name": "subnet", "prefix_length": 24, "vpc_id": "vpc-1"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs":    {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16"}]}`), mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16"}]}`)},
+				"vpc.DescribeSubnets": {mockOk(`{"TotalCount":2,"SubnetSet":[{"SubnetId":"subnet-1","CidrBlock":"10.0.0.0/24"},{"SubnetId":"subnet-2","CidrBlock":"10.0.2.0/23"}]}`)},
+				"vpc.CreateSubnet":    {mockOk(`{"Subnet":{"SubnetId":"subnet-new"}}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeVpcs", "vpc.DescribeVpcs", "vpc.DescribeSubnets", "vpc.CreateSubnet"},
+			wantOutput: map[string]string{"id": "subnet-new", "cidr_block": "10.0.1.0/24"},
+		},
+		{
+			name:   "no free block",
+			action: "create",
+			input:  map[string]interface{}{"guid": "guid", "name": "subnet", "prefix_length": 17, "vpc_id": "vpc-1"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs":    {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16"}]}`), mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16"}]}`)},
+				"vpc.DescribeSubnets": {mockOk(`{"TotalCount":2,"SubnetSet":[{"SubnetId":"subnet-1","CidrBlock":"10.0.64.0/24"},{"SubnetId":"subnet-2","CidrBlock":"10.0.128.0/24"}]}`)},
+			},
+			wantErr:   "no free /17 cidr block left in the cidr blocks[10.0.0.0/16]",
+			wantCalls: []string{"vpc.DescribeVpcs", "vpc.DescribeVpcs", "vpc.DescribeSubnets"},
+		},
+		{
+			name:    "invalid prefix length",
+			action:  "create",
+			input:   map[string]interface{}{"guid": "guid", "name": "subnet", "prefix_length": 29, "vpc_id": "vpc-1"},
+			wantErr: "invalid prefix_length [29], it must be in [16, 28]",
+		},
+		{
+			name:    "no cidr",
+			action:  "create",
+			input:   map[string]interface{}{"guid": "guid", "name": "subnet", "vpc_id": "vpc-1"},
+			wantErr: "input cidr_block and prefix_length are both empty",
+		},
+	})
+}
+
+func TestRouteTableActions(t *testing.T) {
+	runMockActionCases(t, "route-table", []mockActionCase{
+		{
+			name:       "success",
+			action:     "create",
+			input:      map[string]interface{}{"guid": "guid", "name": "route-table", "vpc_id": "vpc-1"},
+			responses:  map[string][]mockResponse{"vpc.CreateRouteTable": {mockOk(`{"RouteTable":{"RouteTableId":"rtb-new"}}`)}},
+			wantCalls:  []string{"vpc.CreateRouteTable"},
+			wantOutput: map[string]string{"id": "rtb-new"},
+		},
+		{
+			name:       "already exists",
+			action:     "create",
+			input:      map[string]interface{}{"guid": "guid", "id": "rtb-exist", "name": "route-table", "vpc_id": "vpc-1"},
+			responses:  map[string][]mockResponse{"vpc.DescribeRouteTables": {mockOk(`{"TotalCount":1,"RouteTableSet":[{"RouteTableId":"rtb-exist"}]}`)}},
+			wantCalls:  []string{"vpc.DescribeRouteTables"},
+			wantOutput: map[string]string{"id": "rtb-exist"},
+		},
+		{
+			name:   "not found",
+			action: "create",
+			input:  map[string]interface{}{"guid": "guid", "id": "rtb-gone", "name": "route-table", "vpc_id": "vpc-1"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeRouteTables": {mockOk(`{"TotalCount":0,"RouteTableSet":[]}`)},
+				"vpc.CreateRouteTable":    {mockOk(`{"RouteTable":{"RouteTableId":"rtb-new"}}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeRouteTables", "vpc.CreateRouteTable"},
+			wantOutput: map[string]string{"id": "rtb-new"},
+		},
+		{
+			name:      "error",
+			action:    "create",
+			input:     map[string]interface{}{"guid": "guid", "name": "route-table", "vpc_id": "vpc-1"},
+			responses: map[string][]mockResponse{"vpc.CreateRouteTable": {mockError("LimitExceeded")}},
+			wantErr:   "LimitExceeded",
+			wantCalls: []string{"vpc.CreateRouteTable"},
+		},
+		{
+			name:   "success",
+			action: "terminate",
+			input:  map[string]interface{}{"guid": "guid", "id": "rtb-1"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeRouteTables": {mockOk(`{"TotalCount":1,"RouteTableSet":[{"RouteTableId":"rtb-1","AssociationSet":[]}]}`)},
+				"vpc.DeleteRouteTable":    {mockOk(`{}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeRouteTables", "vpc.DeleteRouteTable"},
+			wantOutput: map[string]string{"id": "rtb-1"},
+		},
+		{
+			name:      "not found",
+			action:    "terminate",
+			input:     map[string]interface{}{"guid": "guid", "id": "rtb-gone"},
+			responses: map[string][]mockResponse{"vpc.DescribeRouteTables": {mockOk(`{"TotalCount":0,"RouteTableSet":[]}`)}},
+			wantErr:   "routeTable(rtb-gone) not exist",
+			wantCalls: []string{"vpc.DescribeRouteTables"},
+		},
+		{
+			name:   "still associated",
+			action: "terminate",
+			input:  map[string]interface{}{"guid": "guid", "id": "rtb-1"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeRouteTables": {mockOk(`{"TotalCount":1,"RouteTableSet":[{"RouteTableId":"rtb-1","AssociationSet":[{"SubnetId":"subnet-1"}]}]}`)},
+			},
+			wantErr:   "routetable still associated with 1 subnet",
+			wantCalls: []string{"vpc.DescribeRouteTables"},
+		},
+		{
+			name:       "success",
+			action:     "associate-subnet",
+			input:      map[string]interface{}{"guid": "guid", "subnet_id": "subnet-1", "route_table_id": "rtb-1"},
+			responses:  map[string][]mockResponse{"vpc.ReplaceRouteTableAssociation": {mockOk(`{}`)}},
+			wantCalls:  []string{"vpc.ReplaceRouteTableAssociation"},
+			wantOutput: map[string]string{"guid": "guid"},
+		},
+		{
+			name:      "not found",
+			action:    "associate-subnet",
+			input:     map[string]interface{}{"guid": "guid", "subnet_id": "subnet-gone", "route_table_id": "rtb-1"},
+			responses: map[string][]mockResponse{"vpc.ReplaceRouteTableAssociation": {mockError("ResourceNotFound")}},
+			wantErr:   "ResourceNotFound",
+			wantCalls: []string{"vpc.ReplaceRouteTableAssociation"},
+		},
+		{
+			name:   "success",
+			action: "describe",
+			input:  map[string]interface{}{"guid": "guid", "id": "rtb-1"},
+			responses: map[string][]mockResponse{"vpc.DescribeRouteTables": {mockOk(`{"TotalCount":1,"RouteTableSet":[{"RouteTableId":"rtb-1","RouteTableName":"rtb",` +
+				`"VpcId":"vpc-1","Main":false,"AssociationSet":[{"SubnetId":"subnet-1"},{"SubnetId":"subnet-2"}],"RouteSet":[` +
+				`{"RouteId":1,"DestinationCidrBlock":"10.0.0.0/16","GatewayType":"LOCAL","GatewayId":"Local","RouteType":"NETD","Enabled":true},` +
+				`{"RouteId":2,"DestinationCidrBlock":"192.168.0.0/24","GatewayType":"NORMAL_CVM","GatewayId":"10.0.1.10","RouteType":"USER","Enabled":true},` +
+				`{"RouteId":3,"DestinationCidrBlock":"172.16.0.0/16","GatewayType":"PEERCONNECTION","GatewayId":"pcx-1","RouteType":"USER","Enabled":false}]}]}`)}},
+			wantCalls: []string{"vpc.DescribeRouteTables"},
+			wantOutput: map[string]string{"name": "rtb", "vpc_id": "vpc-1", "main": "false", "subnet_ids": "subnet-1,subnet-2",
+				"routes": "192.168.0.0/24,NORMAL_CVM,10.0.1.10;172.16.0.0/16,PEERCONNECTION,pcx-1,disabled"},
+		},
+		{
+			name:      "not found",
+			action:    "describe",
+			input:     map[string]interface{}{"guid": "guid", "id": "rtb-gone"},
+			responses: map[string][]mockResponse{"vpc.DescribeRouteTables": {mockOk(`{"TotalCount":0,"RouteTableSet":[]}`)}},
+			wantErr:   "route table[rtb-gone] not found",
+			wantCalls: []string{"vpc.DescribeRouteTables"},
+		},
+		{
+			name:   "success",
+			action: "rename",
+			input:  map[string]interface{}{"guid": "guid", "id": "rtb-1", "name": "rtb-new"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeRouteTables":       {mockOk(`{"TotalCount":1,"RouteTableSet":[{"RouteTableId":"rtb-1","RouteTableName":"rtb"}]}`)},
+				"vpc.ModifyRouteTableAttribute": {mockOk(`{}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeRouteTables", "vpc.ModifyRouteTableAttribute"},
+			wantOutput: map[string]string{"name": "rtb-new", "request_id": MOCK_REQUEST_ID},
+		},
+		{
+			name:       "unchanged",
+			action:     "rename",
+			input:      map[string]interface{}{"guid": "guid", "id": "rtb-1", "name": "rtb"},
+			responses:  map[string][]mockResponse{"vpc.DescribeRouteTables": {mockOk(`{"TotalCount":1,"RouteTableSet":[{"RouteTableId":"rtb-1","RouteTableName":"rtb"}]}`)}},
+			wantCalls:  []string{"vpc.DescribeRouteTables"},
+			wantOutput: map[string]string{"name": "rtb"},
+		},
+		{
+			name:    "empty name",
+			action:  "rename",
+			input:   map[string]interface{}{"guid": "guid", "id": "rtb-1"},
+			wantErr: "input name is empty",
+		},
+		{
+			name:   "success",
+			action: "replace-routes",
+			input: map[string]interface{}{"guid": "guid", "id": "rtb-1",
+				"routes": "192.168.0.0/24,NORMAL_CVM,10.0.1.11;172.16.0.0/16,PEERCONNECTION,pcx-1;10.1.0.0/16,NAT,nat-1,disabled"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeRouteTables": {
+					mockOk(`{"TotalCount":1,"RouteTableSet":[{"RouteTableId":"rtb-1","RouteSet":[` +
+						`{"RouteId":2,"DestinationCidrBlock":"192.168.0.0/24","GatewayType":"NORMAL_CVM","GatewayId":"10.0.1.10","RouteType":"USER","Enabled":true},` +
+						`{"RouteId":3,"DestinationCidrBlock":"172.16.0.0/16","GatewayType":"PEERCONNECTION","GatewayId":"pcx-1","RouteType":"USER","Enabled":false},` +
+						`{"RouteId":6,"DestinationCidrBlock":"10.9.0.0/16","GatewayType":"NAT","GatewayId":"nat-9","RouteType":"USER","Enabled":true}]}]}`),
+					mockOk(`{"TotalCount":1,"RouteTableSet":[{"RouteTableId":"rtb-1","RouteSet":[` +
+						`{"RouteId":2,"DestinationCidrBlock":"192.168.0.0/24","GatewayType":"NORMAL_CVM","GatewayId":"10.0.1.11","RouteType":"USER","Enabled":true},` +
+						`{"RouteId":3,"DestinationCidrBlock":"172.16.0.0/16","GatewayType":"PEERCONNECTION","GatewayId":"pcx-1","RouteType":"USER","Enabled":true},` +
+						`{"RouteId":5,"DestinationCidrBlock":"10.1.0.0/16","GatewayType":"NAT","GatewayId":"nat-1","RouteType":"USER","Enabled":false}]}]}`),
+				},
+				"vpc.ReplaceRoutes": {mockOk(`{}`)},
+				"vpc.CreateRoutes": {mockOk(`{"TotalCount":1,"RouteTableSet":[{"RouteTableId":"rtb-1","RouteSet":[` +
+					`{"RouteId":5,"DestinationCidrBlock":"10.1.0.0/16"}]}]}`)},
+				"vpc.EnableRoutes":  {mockOk(`{}`)},
+				"vpc.DisableRoutes": {mockOk(`{}`)},
+				"vpc.DeleteRoutes":  {mockOk(`{}`)},
+			},
+			wantCalls: []string{"vpc.DescribeRouteTables", "vpc.ReplaceRoutes", "vpc.CreateRoutes", "vpc.EnableRoutes", "vpc.DisableRoutes", "vpc.DeleteRoutes", "vpc.DescribeRouteTables"},
+			wantOutput: map[string]string{"request_id": MOCK_REQUEST_ID,
+				"routes": "192.168.0.0/24,NORMAL_CVM,10.0.1.11;172.16.0.0/16,PEERCONNECTION,pcx-1;10.1.0.0/16,NAT,nat-1,disabled"},
+		},
+		{
+			name:   "partly changed",
+			action: "replace-routes",
+			input:  map[string]interface{}{"guid": "guid", "id": "rtb-1", "routes": "192.168.0.0/24,NORMAL_CVM,10.0.1.11;10.1.0.0/16,NAT,nat-1"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeRouteTables": {mockOk(`{"TotalCount":1,"RouteTableSet":[{"RouteTableId":"rtb-1","RouteSet":[` +
+					`{"RouteId":2,"DestinationCidrBlock":"192.168.0.0/24","GatewayType":"NORMAL_CVM","GatewayId":"10.0.1.10","RouteType":"USER","Enabled":true}]}]}`)},
+				"vpc.ReplaceRoutes": {mockOk(`{}`)},
+				"vpc.CreateRoutes":  {mockError("LimitExceeded")},
+			},
+			wantErr: "route table[rtb-1] is partly changed, replace 1 routes[192.168.0.0/24,NORMAL_CVM,10.0.1.10->NORMAL_CVM,10.0.1.11] applied " +
+				"but create 1 routes[10.1.0.0/16,NAT,nat-1] failed",
+			wantCalls: []string{"vpc.DescribeRouteTables", "vpc.ReplaceRoutes", "vpc.CreateRoutes"},
+		},
+		{
+			name:   "unchanged",
+			action: "replace-routes",
+			input:  map[string]interface{}{"guid": "guid", "id": "rtb-1", "routes": "192.168.0.0/24,normal_cvm,10.0.1.10"},
+			responses: map[string][]mockResponse{"vpc.DescribeRouteTables": {mockOk(`{"TotalCount":1,"RouteTableSet":[{"RouteTableId":"rtb-1","RouteSet":[` +
+				`{"RouteId":2,"DestinationCidrBlock":"192.168.0.0/24","GatewayType":"NORMAL_CVM","GatewayId":"10.0.1.10","RouteType":"USER","Enabled":true}]}]}`)}},
+			wantCalls:  []string{"vpc.DescribeRouteTables"},
+			wantOutput: map[string]string{"routes": "192.168.0.0/24,NORMAL_CVM,10.0.1.10"},
+		},
+		{
+			name:   "error",
+			action: "replace-routes",
+			input:  map[string]interface{}{"guid": "guid", "id": "rtb-1", "routes": "192.168.0.0/24,NORMAL_CVM,10.0.1.10"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeRouteTables": {mockOk(`{"TotalCount":1,"RouteTableSet":[{"RouteTableId":"rtb-1","RouteSet":[]}]}`)},
+				"vpc.CreateRoutes":        {mockError("InvalidParameterValue")},
+			},
+			wantErr:   "InvalidParameterValue",
+			wantCalls: []string{"vpc.DescribeRouteTables", "vpc.CreateRoutes"},
+		},
+		{
+			name:    "invalid routes",
+			action:  "replace-routes",
+			input:   map[string]interface{}{"guid": "guid", "id": "rtb-1", "routes": "192.168.0.0/24,NORMAL_CVM"},
+			wantErr: "invalid route(192.168.0.0/24,NORMAL_CVM)",
+		},
+		{
+			name:    "empty routes",
+			action:  "replace-routes",
+			input:   map[string]interface{}{"guid": "guid", "id": "rtb-1", "routes": " ; "},
+			wantErr: "set delete_all_routes to true to delete all the user routes",
+		},
+		{
+			name:    "routes with delete all",
+			action:  "replace-routes",
+			input:   map[string]interface{}{"guid": "guid", "id": "rtb-1", "routes": "192.168.0.0/24,NORMAL_CVM,10.0.1.10", "delete_all_routes": "true"},
+			wantErr: "can't be used together with delete_all_routes",
+		},
+		{
+			name:   "delete all",
+			action: "replace-routes",
+			input:  map[string]interface{}{"guid": "guid", "id": "rtb-1", "delete_all_routes": "true"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeRouteTables": {
+					mockOk(`{"TotalCount":1,"RouteTableSet":[{"RouteTableId":"rtb-1","RouteSet":[` +
+						`{"RouteId":2,"DestinationCidrBlock":"192.168.0.0/24","GatewayType":"NORMAL_CVM","GatewayId":"10.0.1.10","RouteType":"USER","Enabled":true}]}]}`),
+					mockOk(`{"TotalCount":1,"RouteTableSet":[{"RouteTableId":"rtb-1","RouteSet":[]}]}`),
+				},
+				"vpc.DeleteRoutes": {mockOk(`{}`)},
+			},
+			wantCalls: []string{"vpc.DescribeRouteTables", "vpc.DeleteRoutes", "vpc.DescribeRouteTables"},
+		},
+	})
+}
+
+func TestRoutePolicyActions(t *testing.T) {
+	input := func() map[string]interface{} {
+		return map[string]interface{}{
+			"guid": "guid", "route_table_id": "rtb-1", "dest_cidr": "192.168.0.0/24",
+			"gateway_type": "normal_cvm", "gateway_id": "10.0.1.10",
+		}
+	}
+	noConflict := mockOk(`{"RouteConflictSet":[{"RouteTableId":"rtb-1","DestinationCidrBlock":"192.168.0.0/24","ConflictSet":[]}]}`)
+	describeRouteTable := mockOk(`{"TotalCount":1,"RouteTableSet":[{"RouteTableId":"rtb-1","VpcId":"vpc-1"}]}`)
+	describeVpc := mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16"}]}`)
+
+	runMockActionCases(t, "route-policy", []mockActionCase{
+		{
+			name:   "success",
+			action: "create",
+			input:  input(),
+			responses: map[string][]mockResponse{
+				"vpc.DescribeRouteConflicts": {noConflict},
+				"vpc.DescribeRouteTables":    {describeRouteTable},
+				"vpc.DescribeVpcs":           {describeVpc},
+				"vpc.CreateRoutes":           {mockOk(`{"TotalCount":1,"RouteTableSet":[{"RouteTableId":"rtb-1","RouteSet":[{"RouteId":10}]}]}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeRouteConflicts", "vpc.DescribeRouteTables", "vpc.DescribeVpcs", "vpc.CreateRoutes"},
+			wantOutput: map[string]string{"id": "10"},
+		},
+		{
+			name:   "dest cidr overlaps vpc",
+			action: "create",
+			input:  input(),
+			responses: map[string][]mockResponse{
+				"vpc.DescribeRouteConflicts": {noConflict},
+				"vpc.DescribeRouteTables":    {describeRouteTable},
+				"vpc.DescribeVpcs":           {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16","AssistantCidrSet":[{"CidrBlock":"192.168.0.0/16"}]}]}`)},
+			},
+			wantErr:   "dest_cidr(192.168.0.0/24) is inside 192.168.0.0/16 of vpc[vpc-1]",
+			wantCalls: []string{"vpc.DescribeRouteConflicts", "vpc.DescribeRouteTables", "vpc.DescribeVpcs"},
+		},
+		{
+			name:   "default route contains vpc",
+			action: "create",
+			input: map[string]interface{}{
+				"guid": "guid", "route_table_id": "rtb-1", "dest_cidr": "0.0.0.0/0",
+				"gateway_type": "nat", "gateway_id": "nat-1",
+			},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeRouteConflicts": {mockOk(`{"RouteConflictSet":[{"RouteTableId":"rtb-1","DestinationCidrBlock":"0.0.0.0/0","ConflictSet":[]}]}`)},
+				"vpc.DescribeRouteTables":    {describeRouteTable},
+				"vpc.DescribeVpcs":           {describeVpc},
+				"vpc.CreateRoutes":           {mockOk(`{"TotalCount":1,"RouteTableSet":[{"RouteTableId":"rtb-1","RouteSet":[{"RouteId":11}]}]}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeRouteConflicts", "vpc.DescribeRouteTables", "vpc.DescribeVpcs", "vpc.CreateRoutes"},
+			wantOutput: map[string]string{"id": "11"},
+		},
+		{
+			name:    "invalid dest cidr",
+			action:  "create",
+			input:   map[string]interface{}{"guid": "guid", "route_table_id": "rtb-1", "dest_cidr": "192.168.0.0", "gateway_type": "normal_cvm", "gateway_id": "10.0.1.10"},
+			wantErr: "invalid dest_cidr(192.168.0.0)",
+		},
+		{
+			name:   "already exists",
+			action: "create",
+			input:  input(),
+			responses: map[string][]mockResponse{
+				"vpc.DescribeRouteConflicts": {mockOk(`{"RouteConflictSet":[{"RouteTableId":"rtb-1","DestinationCidrBlock":"192.168.0.0/24",` +
+					`"ConflictSet":[{"RouteId":10,"DestinationCidrBlock":"192.168.0.0/24","GatewayType":"NORMAL_CVM","GatewayId":"10.0.1.10","RouteType":"USER"}]}]}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeRouteConflicts"},
+			wantOutput: map[string]string{"id": "10"},
+		},
+		{
+			name:   "already exists disabled",
+			action: "create",
+			input:  input(),
+			responses: map[string][]mockResponse{
+				"vpc.DescribeRouteConflicts": {mockOk(`{"RouteConflictSet":[{"RouteTableId":"rtb-1","DestinationCidrBlock":"192.168.0.0/24",` +
+					`"ConflictSet":[{"RouteId":10,"DestinationCidrBlock":"192.168.0.0/24","GatewayType":"NORMAL_CVM","GatewayId":"10.0.1.10","RouteType":"USER","Enabled":false}]}]}`)},
+				"vpc.EnableRoutes": {mockOk(`{"RequestId":"enable-request"}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeRouteConflicts", "vpc.EnableRoutes"},
+			wantOutput: map[string]string{"id": "10", "request_id": "enable-request"},
+		},
+		{
+			name:   "already exists disabled",
+			action: "create",
+			dryRun: true,
+			input:  input(),
+			responses: map[string][]mockResponse{"vpc.DescribeRouteConflicts": {mockOk(`{"RouteConflictSet":[{"RouteTableId":"rtb-1","DestinationCidrBlock":"192.168.0.0/24",` +
+				`"ConflictSet":[{"RouteId":10,"DestinationCidrBlock":"192.168.0.0/24","GatewayType":"NORMAL_CVM","GatewayId":"10.0.1.10","RouteType":"USER","Enabled":false}]}]}`)}},
+			wantCalls:  []string{"vpc.DescribeRouteConflicts"},
+			wantOutput: map[string]string{"id": "10", "operation": "modify", "detail": "enable route 192.168.0.0/24,NORMAL_CVM,10.0.1.10,disabled in route table[rtb-1]"},
+		},
+		{
+			name:   "conflict",
+			action: "create",
+			input:  input(),
+			responses: map[string][]mockResponse{
+				"vpc.DescribeRouteConflicts": {mockOk(`{"RouteConflictSet":[{"RouteTableId":"rtb-1","DestinationCidrBlock":"192.168.0.0/24",` +
+					`"ConflictSet":[{"RouteId":10,"DestinationCidrBlock":"192.168.0.0/24","GatewayType":"NORMAL_CVM","GatewayId":"10.0.1.11","RouteType":"USER"}]}]}`)},
+			},
+			wantErr:   "route conflict,conflictSet=192.168.0.0/24,NORMAL_CVM,10.0.1.11(10)",
+			wantCalls: []string{"vpc.DescribeRouteConflicts"},
+		},
+		{
+			name:   "overwrite",
+			action: "create",
+			input: map[string]interface{}{"guid": "guid", "route_table_id": "rtb-1", "dest_cidr": "192.168.0.0/24",
+				"gateway_type": "normal_cvm", "gateway_id": "10.0.1.10", "overwrite": "true"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeRouteConflicts": {mockOk(`{"RouteConflictSet":[{"RouteTableId":"rtb-1","DestinationCidrBlock":"192.168.0.0/24",` +
+					`"ConflictSet":[{"RouteId":10,"DestinationCidrBlock":"192.168.0.0/24","GatewayType":"NORMAL_CVM","GatewayId":"10.0.1.11","RouteType":"USER"}]}]}`)},
+				"vpc.DescribeRouteTables": {describeRouteTable},
+				"vpc.DescribeVpcs":        {describeVpc},
+				"vpc.ReplaceRoutes":       {mockOk(`{}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeRouteConflicts", "vpc.DescribeRouteTables", "vpc.DescribeVpcs", "vpc.ReplaceRoutes"},
+			wantOutput: map[string]string{"id": "10", "request_id": MOCK_REQUEST_ID},
+		},
+		{
+			name:   "overwrite system route",
+			action: "create",
+			input: map[string]interface{}{"guid": "guid", "route_table_id": "rtb-1", "dest_cidr": "192.168.0.0/24",
+				"gateway_type": "normal_cvm", "gateway_id": "10.0.1.10", "overwrite": "true"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeRouteConflicts": {mockOk(`{"RouteConflictSet":[{"RouteTableId":"rtb-1","DestinationCidrBlock":"192.168.0.0/24",` +
+					`"ConflictSet":[{"RouteId":10,"DestinationCidrBlock":"192.168.0.0/24","GatewayType":"CCN","GatewayId":"ccn-1","RouteType":"CCN"}]}]}`)},
+			},
+			wantErr:   "route conflict,conflictSet=192.168.0.0/24,CCN,ccn-1(10)",
+			wantCalls: []string{"vpc.DescribeRouteConflicts"},
+		},
+		{
+			name:   "invalid overwrite",
+			action: "create",
+			input: map[string]interface{}{"guid": "guid", "route_table_id": "rtb-1", "dest_cidr": "192.168.0.0/24",
+				"gateway_type": "normal_cvm", "gateway_id": "10.0.1.10", "overwrite": "yes please"},
+			wantErr: "invalid overwrite(yes please)",
+		},
+		{
+			name:   "error",
+			action: "create",
+			input:  input(),
+			responses: map[string][]mockResponse{
+				"vpc.DescribeRouteConflicts": {noConflict},
+				"vpc.DescribeRouteTables":    {describeRouteTable},
+				"vpc.DescribeVpcs":           {describeVpc},
+				"vpc.CreateRoutes":           {mockError("LimitExceeded")},
+			},
+			wantErr:   "LimitExceeded",
+			wantCalls: []string{"vpc.DescribeRouteConflicts", "vpc.DescribeRouteTables", "vpc.DescribeVpcs", "vpc.CreateRoutes"},
+		},
+		{
+			name:       "success",
+			action:     "terminate",
+			input:      map[string]interface{}{"guid": "guid", "id": "10", "route_table_id": "rtb-1"},
+			responses:  map[string][]mockResponse{"vpc.DeleteRoutes": {mockOk(`{}`)}},
+			wantCalls:  []string{"vpc.DeleteRoutes"},
+			wantOutput: map[string]string{"guid": "guid"},
+		},
+		{
+			name:      "not found",
+			action:    "terminate",
+			input:     map[string]interface{}{"guid": "guid", "id": "11", "route_table_id": "rtb-1"},
+			responses: map[string][]mockResponse{"vpc.DeleteRoutes": {mockError("ResourceNotFound")}},
+			wantErr:   "ResourceNotFound",
+			wantCalls: []string{"vpc.DeleteRoutes"},
+		},
+	})
+}
+
+func TestSecurityGroupActions(t *testing.T) {
+	policyInput := func(id string) map[string]interface{} {
+		return map[string]interface{}{
+			"guid": "guid", "id": id, "name": "security-group", "policy_type": "Ingress",
+			"policy_cidr_block": "0.0.0.0/0", "policy_protocol": "TCP", "policy_port": "22", "policy_action": "ACCEPT",
+		}
+	}
+	found := mockOk(`{"TotalCount":1,"SecurityGroupSet":[{"SecurityGroupId":"sg-1"}]}`)
+	notFound := mockOk(`{"TotalCount":0,"SecurityGroupSet":[]}`)
+
+	runMockActionCases(t, "security-group", []mockActionCase{
+		{
+			name:       "success",
+			action:     "create",
+			input:      map[string]interface{}{"guid": "guid", "name": "security-group", "description": "test"},
+			responses:  map[string][]mockResponse{"vpc.CreateSecurityGroup": {mockOk(`{"SecurityGroup":{"SecurityGroupId":"sg-new"}}`)}},
+			wantCalls:  []string{"vpc.CreateSecurityGroup"},
+			wantOutput: map[string]string{"id": "sg-new"},
+		},
+		{
+			name:       "already exists",
+			action:     "create",
+			input:      map[string]interface{}{"guid": "guid", "id": "sg-1", "name": "security-group", "description": "test"},
+			responses:  map[string][]mockResponse{"vpc.DescribeSecurityGroups": {found}},
+			wantCalls:  []string{"vpc.DescribeSecurityGroups"},
+			wantOutput: map[string]string{"id": "sg-1"},
+		},
+		{
+			name:   "not found",
+			action: "create",
+			input:  map[string]interface{}{"guid": "guid", "id": "sg-gone", "name": "security-group", "description": "test"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeSecurityGroups": {notFound},
+				"vpc.CreateSecurityGroup":    {mockOk(`{"SecurityGroup":{"SecurityGroupId":"sg-new"}}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeSecurityGroups", "vpc.CreateSecurityGroup"},
+			wantOutput: map[string]string{"id": "sg-new"},
+		},
+		{
+			name:      "error",
+			action:    "create",
+			input:     map[string]interface{}{"guid": "guid", "name": "security-group", "description": "test"},
+			responses: map[string][]mockResponse{"vpc.CreateSecurityGroup": {mockError("LimitExceeded")}},
+			wantErr:   "LimitExceeded",
+			wantCalls: []string{"vpc.CreateSecurityGroup"},
+		},
+		{
+			name:       "success",
+			action:     "terminate",
+			input:      map[string]interface{}{"guid": "guid", "id": "sg-1"},
+			responses:  map[string][]mockResponse{"vpc.DeleteSecurityGroup": {mockOk(`{}`)}},
+			wantCalls:  []string{"vpc.DeleteSecurityGroup"},
+			wantOutput: map[string]string{"id": "sg-1"},
+		},
+		{
+			name:      "not found",
+			action:    "terminate",
+			input:     map[string]interface{}{"guid": "guid", "id": "sg-gone"},
+			responses: map[string][]mockResponse{"vpc.DeleteSecurityGroup": {mockError("ResourceNotFound")}},
+			wantErr:   "ResourceNotFound",
+			wantCalls: []string{"vpc.DeleteSecurityGroup"},
+		},
+		{
+			name:   "success",
+			action: "create-policies",
+			input:  policyInput("sg-1"),
+			responses: map[string][]mockResponse{
+				"vpc.DescribeSecurityGroups":      {found},
+				"vpc.CreateSecurityGroupPolicies": {mockOk(`{}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeSecurityGroups", "vpc.CreateSecurityGroupPolicies"},
+			wantOutput: map[string]string{"id": "sg-1"},
+		},
+		{
+			name:      "not found",
+			action:    "create-policies",
+			input:     policyInput("sg-gone"),
+			responses: map[string][]mockResponse{"vpc.DescribeSecurityGroups": {notFound}},
+			wantErr:   "security group(sg-gone) not found",
+			wantCalls: []string{"vpc.DescribeSecurityGroups"},
+		},
+		{
+			name:   "error",
+			action: "create-policies",
+			input:  policyInput("sg-1"),
+			responses: map[string][]mockResponse{
+				"vpc.DescribeSecurityGroups":      {found},
+				"vpc.CreateSecurityGroupPolicies": {mockError("LimitExceeded")},
+			},
+			wantErr:   "LimitExceeded",
+			wantCalls: []string{"vpc.DescribeSecurityGroups", "vpc.CreateSecurityGroupPolicies"},
+		},
+		{
+			name:   "success",
+			action: "delete-policies",
+			input:  policyInput("sg-1"),
+			responses: map[string][]mockResponse{
+				"vpc.DescribeSecurityGroups":      {found},
+				"vpc.DeleteSecurityGroupPolicies": {mockOk(`{}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeSecurityGroups", "vpc.DeleteSecurityGroupPolicies"},
+			wantOutput: map[string]string{"id": "sg-1"},
+		},
+		{
+			name:      "not found",
+			action:    "delete-policies",
+			input:     policyInput("sg-gone"),
+			responses: map[string][]mockResponse{"vpc.DescribeSecurityGroups": {notFound}},
+			wantErr:   "security group(sg-gone) not found",
+			wantCalls: []string{"vpc.DescribeSecurityGroups"},
+		},
+	})
+}
+
+//bindnat and unbindnat call the legacy vpc sdk which has no mock client, only their dry runs are mocked
+func TestEIPActions(t *testing.T) {
+	runMockActionCases(t, "eip", []mockActionCase{
+		{
+			name:   "success",
+			action: "create",
+			input:  map[string]interface{}{"guid": "guid"},
+			responses: map[string][]mockResponse{
+				"vpc.AllocateAddresses": {mockOk(`{"AddressSet":["eip-new"]}`)},
+				"vpc.DescribeAddresses": {mockOk(`{"TotalCount":1,"AddressSet":[{"AddressId":"eip-new","AddressIp":"1.1.1.1","AddressStatus":"UNBIND"}]}`)},
+			},
+			wantCalls:  []string{"vpc.AllocateAddresses", "vpc.DescribeAddresses"},
+			wantOutput: map[string]string{"eips": "[map[eip:1.1.1.1 id:eip-new]]"},
+		},
+		{
+			name:   "not found",
+			action: "create",
+			input:  map[string]interface{}{"guid": "guid"},
+			responses: map[string][]mockResponse{
+				"vpc.AllocateAddresses": {mockOk(`{"AddressSet":["eip-new"]}`)},
+				"vpc.DescribeAddresses": {mockOk(`{"TotalCount":0,"AddressSet":[]}`)},
+			},
+			wantErr:   "after create eip can't get eip info",
+			wantCalls: []string{"vpc.AllocateAddresses", "vpc.DescribeAddresses"},
+		},
+		{
+			name:   "retry after wait error",
+			action: "create",
+			input:  map[string]interface{}{"guid": "guid"},
+			responses: map[string][]mockResponse{
+				"vpc.AllocateAddresses": {mockOk(`{"AddressSet":["eip-new"]}`)},
+				"vpc.DescribeAddresses": {mockError("InternalError")},
+			},
+			wantErr:   "InternalError",
+			wantCalls: []string{"vpc.AllocateAddresses", "vpc.DescribeAddresses"},
+			retry: &mockActionCase{
+				responses:  map[string][]mockResponse{"vpc.DescribeAddresses": {mockOk(`{"TotalCount":1,"AddressSet":[{"AddressId":"eip-new","AddressIp":"1.1.1.1","AddressStatus":"UNBIND"}]}`)}},
+				wantCalls:  []string{"vpc.DescribeAddresses"},
+				wantOutput: map[string]string{"eips": "[map[eip:1.1.1.1 id:eip-new]]"},
+			},
+		},
+		{
+			name:      "error",
+			action:    "create",
+			input:     map[string]interface{}{"guid": "guid"},
+			responses: map[string][]mockResponse{"vpc.AllocateAddresses": {mockError("AddressQuotaLimitExceeded")}},
+			wantErr:   "AddressQuotaLimitExceeded",
+			wantCalls: []string{"vpc.AllocateAddresses"},
+		},
+		{
+			name:       "success",
+			action:     "terminate",
+			input:      map[string]interface{}{"guid": "guid", "id": "eip-1"},
+			responses:  map[string][]mockResponse{"vpc.ReleaseAddresses": {mockOk(`{}`)}},
+			wantCalls:  []string{"vpc.ReleaseAddresses"},
+			wantOutput: map[string]string{"guid": "guid"},
+		},
+		{
+			name:      "not found",
+			action:    "terminate",
+			input:     map[string]interface{}{"guid": "guid", "id": "eip-gone"},
+			responses: map[string][]mockResponse{"vpc.ReleaseAddresses": {mockError("InvalidAddressId.NotFound")}},
+			wantErr:   "InvalidAddressId.NotFound",
+			wantCalls: []string{"vpc.ReleaseAddresses"},
+		},
+		{
+			name:       "success",
+			action:     "attach",
+			input:      map[string]interface{}{"guid": "guid", "id": "eip-1", "instance_id": "ins-1"},
+			responses:  map[string][]mockResponse{"vpc.AssociateAddress": {mockOk(`{}`)}},
+			wantCalls:  []string{"vpc.AssociateAddress"},
+			wantOutput: map[string]string{"guid": "guid"},
+		},
+		{
+			name:      "already exists",
+			action:    "attach",
+			input:     map[string]interface{}{"guid": "guid", "id": "eip-1", "instance_id": "ins-1"},
+			responses: map[string][]mockResponse{"vpc.AssociateAddress": {mockError("InvalidAddressIdStatus.NotPermit")}},
+			wantErr:   "InvalidAddressIdStatus.NotPermit",
+			wantCalls: []string{"vpc.AssociateAddress"},
+		},
+		{
+			name:       "success",
+			action:     "detach",
+			input:      map[string]interface{}{"guid": "guid", "id": "eip-1"},
+			responses:  map[string][]mockResponse{"vpc.DisassociateAddress": {mockOk(`{}`)}},
+			wantCalls:  []string{"vpc.DisassociateAddress"},
+			wantOutput: map[string]string{"guid": "guid"},
+		},
+		{
+			name:      "not found",
+			action:    "detach",
+			input:     map[string]interface{}{"guid": "guid", "id": "eip-gone"},
+			responses: map[string][]mockResponse{"vpc.DisassociateAddress": {mockError("InvalidAddressId.NotFound")}},
+			wantErr:   "InvalidAddressId.NotFound",
+			wantCalls: []string{"vpc.DisassociateAddress"},
+		},
+		{
+			name:       "nat gateway not found",
+			action:     "bindnat",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "eip-1", "eip": "1.1.1.1", "vpc_id": "vpc-1", "nat_id": "nat-gone"},
+			responses:  map[string][]mockResponse{"vpc.DescribeNatGateways": {mockOk(`{"TotalCount":0,"NatGatewaySet":[]}`)}},
+			wantCalls:  []string{"vpc.DescribeNatGateways"},
+			wantOutput: map[string]string{"operation": DRY_RUN_OPERATION_NONE, "conflicts": "[nat gateway[nat-gone] not found]"},
+		},
+	})
+}
+
+func TestNatGatewayActions(t *testing.T) {
+	natGateway := func(state string) mockResponse {
+		return mockOk(`{"TotalCount":1,"NatGatewaySet":[{"NatGatewayId":"nat-1","State":"` + state + `",` +
+			`"PublicIpAddressSet":[{"AddressId":"eip-1","PublicIpAddress":"1.1.1.1"}]}]}`)
+	}
+	notFound := mockOk(`{"TotalCount":0,"NatGatewaySet":[]}`)
+	createInput := map[string]interface{}{"guid": "guid", "name": "nat", "vpc_id": "vpc-1", "auto_alloc_eip_num": 1}
+
+	runMockActionCases(t, "nat-gateway", []mockActionCase{
+		{
+			name:   "success",
+			action: "create",
+			input:  createInput,
+			responses: map[string][]mockResponse{
+				"vpc.CreateNatGateway":    {mockOk(`{"NatGatewaySet":[{"NatGatewayId":"nat-1","State":"PENDING"}]}`)},
+				"vpc.DescribeNatGateways": {natGateway("PENDING"), natGateway("AVAILABLE")},
+			},
+			wantCalls:  []string{"vpc.CreateNatGateway", "vpc.DescribeNatGateways", "vpc.DescribeNatGateways"},
+			wantOutput: map[string]string{"id": "nat-1", "eip": "1.1.1.1", "eip_id": "eip-1", "request_id": MOCK_REQUEST_ID},
+		},
+		{
+			name:       "already exists",
+			action:     "create",
+			input:      map[string]interface{}{"guid": "guid", "name": "nat", "vpc_id": "vpc-1", "id": "nat-1"},
+			responses:  map[string][]mockResponse{"vpc.DescribeNatGateways": {natGateway("AVAILABLE")}},
+			wantCalls:  []string{"vpc.DescribeNatGateways"},
+			wantOutput: map[string]string{"id": "nat-1", "eip": "1.1.1.1"},
+		},
+		{
+			name:   "failed",
+			action: "create",
+			input:  createInput,
+			responses: map[string][]mockResponse{
+				"vpc.CreateNatGateway":    {mockOk(`{"NatGatewaySet":[{"NatGatewayId":"nat-1","State":"PENDING"}]}`)},
+				"vpc.DescribeNatGateways": {natGateway("FAILED")},
+			},
+			wantErr:   "nat gateway[nat-1] is FAILED after created",
+			wantCalls: []string{"vpc.CreateNatGateway", "vpc.DescribeNatGateways"},
+		},
+		{
+			name:   "retry after wait error",
+			action: "create",
+			input:  createInput,
+			responses: map[string][]mockResponse{
+				"vpc.CreateNatGateway":    {mockOk(`{"NatGatewaySet":[{"NatGatewayId":"nat-1","State":"PENDING"}]}`)},
+				"vpc.DescribeNatGateways": {mockError("InternalError")},
+			},
+			wantErr:   "InternalError",
+			wantCalls: []string{"vpc.CreateNatGateway", "vpc.DescribeNatGateways"},
+			retry: &mockActionCase{
+				responses:  map[string][]mockResponse{"vpc.DescribeNatGateways": {natGateway("AVAILABLE")}},
+				wantCalls:  []string{"vpc.DescribeNatGateways"},
+				wantOutput: map[string]string{"id": "nat-1", "eip": "1.1.1.1", "request_id": MOCK_REQUEST_ID},
+			},
+		},
+		{
+			name:      "error",
+			action:    "create",
+			input:     createInput,
+			responses: map[string][]mockResponse{"vpc.CreateNatGateway": {mockError("LimitExceeded.NatGatewayPerVpcLimitExceeded")}},
+			wantErr:   "LimitExceeded.NatGatewayPerVpcLimitExceeded",
+			wantCalls: []string{"vpc.CreateNatGateway"},
+		},
+		{
+			name:   "success",
+			action: "terminate",
+			input:  map[string]interface{}{"guid": "guid", "id": "nat-1"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeNatGateways": {natGateway("AVAILABLE"), natGateway("DELETING"), notFound},
+				"vpc.DeleteNatGateway":    {mockOk(`{}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeNatGateways", "vpc.DeleteNatGateway", "vpc.DescribeNatGateways", "vpc.DescribeNatGateways"},
+			wantOutput: map[string]string{"id": "nat-1", "request_id": MOCK_REQUEST_ID},
+		},
+		{
+			name:       "not found",
+			action:     "terminate",
+			input:      map[string]interface{}{"guid": "guid", "id": "nat-gone"},
+			responses:  map[string][]mockResponse{"vpc.DescribeNatGateways": {notFound}},
+			wantCalls:  []string{"vpc.DescribeNatGateways"},
+			wantOutput: map[string]string{"id": "nat-gone"},
+		},
+		{
+			name:   "error",
+			action: "terminate",
+			input:  map[string]interface{}{"guid": "guid", "id": "nat-1"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeNatGateways": {natGateway("AVAILABLE")},
+				"vpc.DeleteNatGateway":    {mockError("UnsupportedOperation.NatGatewayRulePipExists")},
+			},
+			wantErr:   "UnsupportedOperation.NatGatewayRulePipExists",
+			wantCalls: []string{"vpc.DescribeNatGateways", "vpc.DeleteNatGateway"},
+		},
+		{
+			name:       "exists",
+			action:     "terminate",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "nat-1"},
+			responses:  map[string][]mockResponse{"vpc.DescribeNatGateways": {natGateway("AVAILABLE")}},
+			wantCalls:  []string{"vpc.DescribeNatGateways"},
+			wantOutput: map[string]string{"operation": DRY_RUN_OPERATION_DELETE, "exist": "true"},
+		},
+	})
+}
+
+func TestPeeringConnectionActions(t *testing.T) {
+	peeringConnection := func(state string) mockResponse {
+		return mockOk(`{"TotalCount":1,"PeerConnectionSet":[{"PeeringConnectionId":"pcx-1","State":"` + state + `"}]}`)
+	}
+	notFound := mockOk(`{"TotalCount":0,"PeerConnectionSet":[]}`)
+	vpcs := func(peerCidrBlock string) []mockResponse {
+		return []mockResponse{
+			mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16"}]}`),
+			mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-2","CidrBlock":"` + peerCidrBlock + `"}]}`),
+		}
+	}
+	createInput := map[string]interface{}{"guid": "guid", "name": "pcx", "vpc_id": "vpc-1", "peer_vpc_id": "vpc-2", "peer_uin": "100", "bandwidth": "10"}
+	terminateInput := map[string]interface{}{"guid": "guid", "id": "pcx-1", "peer_provider_params": MOCK_PROVIDER_PARAMS}
+
+	runMockActionCases(t, "peering-connection", []mockActionCase{
+		{
+			name:   "success",
+			action: "create",
+			input:  createInput,
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs":                  vpcs("10.1.0.0/16"),
+				"vpc.CreateVpcPeeringConnection":    {mockOk(`{"PeeringConnectionId":"pcx-1"}`)},
+				"vpc.DescribeVpcPeeringConnections": {notFound, peeringConnection("PENDING")},
+			},
+			wantCalls: []string{"vpc.DescribeVpcs", "vpc.DescribeVpcs", "vpc.CreateVpcPeeringConnection",
+				"vpc.DescribeVpcPeeringConnections", "vpc.DescribeVpcPeeringConnections"},
+			wantOutput: map[string]string{"id": "pcx-1", "request_id": MOCK_REQUEST_ID},
+		},
+		{
+			name:       "already exists",
+			action:     "create",
+			input:      map[string]interface{}{"guid": "guid", "name": "pcx", "vpc_id": "vpc-1", "peer_vpc_id": "vpc-2", "id": "pcx-1"},
+			responses:  map[string][]mockResponse{"vpc.DescribeVpcPeeringConnections": {peeringConnection("ACTIVE")}},
+			wantCalls:  []string{"vpc.DescribeVpcPeeringConnections"},
+			wantOutput: map[string]string{"id": "pcx-1"},
+		},
+		{
+			name:   "rejected",
+			action: "create",
+			input:  createInput,
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs":                  vpcs("10.1.0.0/16"),
+				"vpc.CreateVpcPeeringConnection":    {mockOk(`{"PeeringConnectionId":"pcx-1"}`)},
+				"vpc.DescribeVpcPeeringConnections": {peeringConnection("REJECTED")},
+			},
+			wantErr:   "peering connection[pcx-1] is REJECTED after created",
+			wantCalls: []string{"vpc.DescribeVpcs", "vpc.DescribeVpcs", "vpc.CreateVpcPeeringConnection", "vpc.DescribeVpcPeeringConnections"},
+		},
+		{
+			name:      "cidr overlapped",
+			action:    "create",
+			input:     createInput,
+			responses: map[string][]mockResponse{"vpc.DescribeVpcs": vpcs("10.0.1.0/24")},
+			wantErr:   "overlaps with 10.0.1.0/24 of peer vpc[vpc-2]",
+			wantCalls: []string{"vpc.DescribeVpcs", "vpc.DescribeVpcs"},
+		},
+		{
+			name:   "error",
+			action: "create",
+			input:  createInput,
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs":               vpcs("10.1.0.0/16"),
+				"vpc.CreateVpcPeeringConnection": {mockError("LimitExceeded")},
+			},
+			wantErr:   "LimitExceeded",
+			wantCalls: []string{"vpc.DescribeVpcs", "vpc.DescribeVpcs", "vpc.CreateVpcPeeringConnection"},
+		},
+		{
+			name:   "success",
+			action: "terminate",
+			input:  terminateInput,
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcPeeringConnections": {peeringConnection("ACTIVE"), peeringConnection("DELETED")},
+				"vpc.DeleteVpcPeeringConnection":    {mockOk(`{}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeVpcPeeringConnections", "vpc.DeleteVpcPeeringConnection", "vpc.DescribeVpcPeeringConnections"},
+			wantOutput: map[string]string{"id": "pcx-1", "request_id": MOCK_REQUEST_ID},
+		},
+		{
+			name:       "not found",
+			action:     "terminate",
+			input:      terminateInput,
+			responses:  map[string][]mockResponse{"vpc.DescribeVpcPeeringConnections": {notFound}},
+			wantCalls:  []string{"vpc.DescribeVpcPeeringConnections"},
+			wantOutput: map[string]string{"id": "pcx-1"},
+		},
+		{
+			name:   "error",
+			action: "terminate",
+			input:  terminateInput,
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcPeeringConnections": {peeringConnection("ACTIVE")},
+				"vpc.DeleteVpcPeeringConnection":    {mockError("UnsupportedOperation")},
+			},
+			wantErr:   "UnsupportedOperation",
+			wantCalls: []string{"vpc.DescribeVpcPeeringConnections", "vpc.DeleteVpcPeeringConnection"},
+		},
+		{
+			name:       "not found",
+			action:     "terminate",
+			dryRun:     true,
+			input:      terminateInput,
+			responses:  map[string][]mockResponse{"vpc.DescribeVpcPeeringConnections": {notFound}},
+			wantCalls:  []string{"vpc.DescribeVpcPeeringConnections"},
+			wantOutput: map[string]string{"operation": DRY_RUN_OPERATION_NONE, "exist": "false"},
+		},
+	})
+}
+
+func TestElasticNicActions(t *testing.T) {
+	createInput := func(id string) map[string]interface{} {
+		return map[string]interface{}{"guid": "guid", "id": id, "name": "eni", "vpc_id": "vpc-1", "subnet_id": "subnet-1", "security_group_id": []string{"sg-1"}}
+	}
+	eni := `{"NetworkInterfaceId":"%s","State":"%s","PrivateIpAddressSet":[{"PrivateIpAddress":"10.0.1.5"}],"GroupSet":["sg-1"]}`
+
+	runMockActionCases(t, "elastic-nic", []mockActionCase{
+		{
+			name:       "success",
+			action:     "create",
+			input:      createInput(""),
+			responses:  map[string][]mockResponse{"vpc.CreateNetworkInterface": {mockOk(`{"NetworkInterface":` + fmt.Sprintf(eni, "eni-new", "PENDING") + `}`)}},
+			wantCalls:  []string{"vpc.CreateNetworkInterface"},
+			wantOutput: map[string]string{"id": "eni-new", "private_ip": "10.0.1.5", "attach_group_list": "[sg-1]"},
+		},
+		{
+			name:       "already exists",
+			action:     "create",
+			input:      createInput("eni-exist"),
+			responses:  map[string][]mockResponse{"vpc.DescribeNetworkInterfaces": {mockOk(`{"TotalCount":1,"NetworkInterfaceSet":[` + fmt.Sprintf(eni, "eni-exist", "AVAILABLE") + `]}`)}},
+			wantCalls:  []string{"vpc.DescribeNetworkInterfaces"},
+			wantOutput: map[string]string{"id": "eni-exist", "private_ip": "10.0.1.5"},
+		},
+		{
+			name:   "not found",
+			action: "create",
+			input:  createInput("eni-gone"),
+			responses: map[string][]mockResponse{
+				"vpc.DescribeNetworkInterfaces": {mockOk(`{"TotalCount":0,"NetworkInterfaceSet":[]}`)},
+				"vpc.CreateNetworkInterface":    {mockOk(`{"NetworkInterface":` + fmt.Sprintf(eni, "eni-new", "PENDING") + `}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeNetworkInterfaces", "vpc.CreateNetworkInterface"},
+			wantOutput: map[string]string{"id": "eni-new"},
+		},
+		{
+			name:      "error",
+			action:    "create",
+			input:     createInput(""),
+			responses: map[string][]mockResponse{"vpc.CreateNetworkInterface": {mockError("LimitExceeded")}},
+			wantErr:   "LimitExceeded",
+			wantCalls: []string{"vpc.CreateNetworkInterface"},
+		},
+		{
+			name:   "success",
+			action: "terminate",
+			input:  map[string]interface{}{"guid": "guid", "id": "eni-1"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeNetworkInterfaces": {mockOk(`{"TotalCount":1,"NetworkInterfaceSet":[` + fmt.Sprintf(eni, "eni-1", "AVAILABLE") + `]}`)},
+				"vpc.DeleteNetworkInterface":    {mockOk(`{}`)},
+			},
+			wantCalls:  []string{"vpc.DescribeNetworkInterfaces", "vpc.DeleteNetworkInterface"},
+			wantOutput: map[string]string{"guid": "guid"},
+		},
+		{
+			name:      "not found",
+			action:    "terminate",
+			input:     map[string]interface{}{"guid": "guid", "id": "eni-gone"},
+			responses: map[string][]mockResponse{"vpc.DescribeNetworkInterfaces": {mockOk(`{"TotalCount":0,"NetworkInterfaceSet":[]}`)}},
+			wantErr:   "don't find elastic nic eni-gone",
+			wantCalls: []string{"vpc.DescribeNetworkInterfaces"},
+		},
+		{
+			name:      "still attached",
+			action:    "terminate",
+			input:     map[string]interface{}{"guid": "guid", "id": "eni-1"},
+			responses: map[string][]mockResponse{"vpc.DescribeNetworkInterfaces": {mockOk(`{"TotalCount":1,"NetworkInterfaceSet":[` + fmt.Sprintf(eni, "eni-1", "ATTACHING") + `]}`)}},
+			wantErr:   "status is ATTACHING",
+			wantCalls: []string{"vpc.DescribeNetworkInterfaces"},
+		},
+		{
+			name:       "success",
+			action:     "attach",
+			input:      map[string]interface{}{"guid": "guid", "id": "eni-1", "instance_id": "ins-1"},
+			responses:  map[string][]mockResponse{"vpc.AttachNetworkInterface": {mockOk(`{}`)}},
+			wantCalls:  []string{"vpc.AttachNetworkInterface"},
+			wantOutput: map[string]string{"guid": "guid"},
+		},
+		{
+			name:      "already exists",
+			action:    "attach",
+			input:     map[string]interface{}{"guid": "guid", "id": "eni-1", "instance_id": "ins-1"},
+			responses: map[string][]mockResponse{"vpc.AttachNetworkInterface": {mockError("UnsupportedOperation")}},
+			wantErr:   "UnsupportedOperation",
+			wantCalls: []string{"vpc.AttachNetworkInterface"},
+		},
+		{
+			name:       "success",
+			action:     "detach",
+			input:      map[string]interface{}{"guid": "guid", "id": "eni-1", "instance_id": "ins-1"},
+			responses:  map[string][]mockResponse{"vpc.DetachNetworkInterface": {mockOk(`{}`)}},
+			wantCalls:  []string{"vpc.DetachNetworkInterface"},
+			wantOutput: map[string]string{"guid": "guid"},
+		},
+		{
+			name:      "not found",
+			action:    "detach",
+			input:     map[string]interface{}{"guid": "guid", "id": "eni-gone", "instance_id": "ins-1"},
+			responses: map[string][]mockResponse{"vpc.DetachNetworkInterface": {mockError("ResourceNotFound")}},
+			wantErr:   "ResourceNotFound",
+			wantCalls: []string{"vpc.DetachNetworkInterface"},
+		},
+	})
+}
+
+func TestVmActions(t *testing.T) {
+	createInput := func(id string) map[string]interface{} {
+		return map[string]interface{}{
+			"guid": "guid", "seed": "seed", "id": id, "vpc_id": "vpc-1", "subnet_id": "subnet-1", "instance_name": "vm",
+			"instance_type": "S2.MEDIUM4", "image_id": "img-1", "system_disk_size": 50, "instance_charge_type": "POSTPAID_BY_HOUR",
+		}
+	}
+	instance := `{"TotalCount":1,"InstanceSet":[{"InstanceId":"%s","InstanceState":"RUNNING","CPU":2,"Memory":4,"PrivateIpAddresses":["10.0.1.6"]}]}`
+	instanceInState := func(state string) string {
+		return strings.Replace(fmt.Sprintf(instance, "ins-1"), "RUNNING", state, 1)
+	}
+	quota := `{"InstanceTypeQuotaSet":[
+		{"Zone":"ap-guangzhou-3","InstanceType":"S2.MEDIUM4","InstanceChargeType":"POSTPAID_BY_HOUR","Status":"%s"},
+		{"Zone":"ap-guangzhou-4","InstanceType":"S2.MEDIUM4","InstanceChargeType":"POSTPAID_BY_HOUR","Status":"SELL"}]}`
+	image := `{"TotalCount":1,"ImageSet":[{"ImageId":"img-1","ImageState":"NORMAL"}]}`
+
+	runMockActionCases(t, "vm", []mockActionCase{
+		{
+			name:   "success",
+			action: "create",
+			input:  createInput(""),
+			responses: map[string][]mockResponse{
+				"cvm.DescribeZoneInstanceConfigInfos": {mockOk(fmt.Sprintf(quota, "SELL"))},
+				"cvm.DescribeImages":                  {mockOk(image)},
+				"cvm.RunInstances":                    {mockOk(`{"InstanceIdSet":["ins-new"]}`)},
+				"cvm.DescribeInstances":               {mockOk(fmt.Sprintf(instance, "ins-new"))},
+			},
+			wantCalls:  []string{"cvm.DescribeZoneInstanceConfigInfos", "cvm.DescribeImages", "cvm.RunInstances", "cvm.DescribeInstances", "cvm.DescribeInstances"},
+			wantOutput: map[string]string{"id": "ins-new", "cpu": "2", "memory": "4", "instance_private_ip": "10.0.1.6"},
+			slow:       true,
+		},
+		{
+			name:   "fall back to another zone",
+			action: "create",
+			input: func() map[string]interface{} {
+				input := createInput("")
+				input["zone_fallback"] = "true"
+				return input
+			}(),
+			responses: map[string][]mockResponse{
+				"cvm.DescribeZoneInstanceConfigInfos": {mockOk(fmt.Sprintf(quota, "SOLD_OUT"))},
+				"cvm.DescribeImages":                  {mockOk(image)},
+				"vpc.DescribeSubnets":                 {mockOk(`{"TotalCount":1,"SubnetSet":[{"SubnetId":"subnet-4","Zone":"ap-guangzhou-4"}]}`)},
+				"cvm.RunInstances":                    {mockOk(`{"InstanceIdSet":["ins-new"]}`)},
+				"cvm.DescribeInstances":               {mockOk(fmt.Sprintf(instance, "ins-new"))},
+			},
+			wantCalls: []string{
+				"cvm.DescribeZoneInstanceConfigInfos", "cvm.DescribeImages", "cvm.DescribeZoneInstanceConfigInfos", "vpc.DescribeSubnets",
+				"cvm.RunInstances", "cvm.DescribeInstances", "cvm.DescribeInstances",
+			},
+			wantOutput: map[string]string{"id": "ins-new"},
+			slow:       true,
+		},
+		{
+			name:      "sold out",
+			action:    "create",
+			input:     createInput(""),
+			responses: map[string][]mockResponse{"cvm.DescribeZoneInstanceConfigInfos": {mockOk(fmt.Sprintf(quota, "SOLD_OUT"))}},
+			wantErr:   "instance type(S2.MEDIUM4) charged by POSTPAID_BY_HOUR is not on sale in zone(ap-guangzhou-3)",
+			wantCalls: []string{"cvm.DescribeZoneInstanceConfigInfos"},
+		},
+		{
+			name:   "image not found",
+			action: "create",
+			input:  createInput(""),
+			responses: map[string][]mockResponse{
+				"cvm.DescribeZoneInstanceConfigInfos": {mockOk(fmt.Sprintf(quota, "SELL"))},
+				"cvm.DescribeImages":                  {mockOk(`{"TotalCount":0,"ImageSet":[]}`)},
+			},
+			wantErr:   "image(img-1) not found in region(ap-guangzhou)",
+			wantCalls: []string{"cvm.DescribeZoneInstanceConfigInfos", "cvm.DescribeImages"},
+		},
+		{
+			name:   "invalid zone fallback",
+			action: "create",
+			input: func() map[string]interface{} {
+				input := createInput("")
+				input["zone_fallback"] = "maybe"
+				return input
+			}(),
+			wantErr: "invalid zone_fallback(maybe)",
+		},
+		{
+			name:       "already exists",
+			action:     "create",
+			input:      createInput("ins-exist"),
+			responses:  map[string][]mockResponse{"cvm.DescribeInstances": {mockOk(fmt.Sprintf(instance, "ins-exist"))}},
+			wantCalls:  []string{"cvm.DescribeInstances"},
+			wantOutput: map[string]string{"id": "ins-exist", "instance_state": "RUNNING"},
+		},
+		{
+			name:   "error",
+			action: "create",
+			input:  createInput(""),
+			responses: map[string][]mockResponse{
+				"cvm.DescribeZoneInstanceConfigInfos": {mockOk(fmt.Sprintf(quota, "SELL"))},
+				"cvm.DescribeImages":                  {mockOk(image)},
+				"cvm.RunInstances":                    {mockError("ResourceInsufficient.SpecifiedInstanceType")},
+			},
+			wantErr:   "ResourceInsufficient.SpecifiedInstanceType",
+			wantCalls: []string{"cvm.DescribeZoneInstanceConfigInfos", "cvm.DescribeImages", "cvm.RunInstances"},
+		},
+		{
+			name:       "success",
+			action:     "check-quota",
+			input:      map[string]interface{}{"guid": "guid", "instance_type": "S2.MEDIUM4", "zones": "ap-guangzhou-3"},
+			responses:  map[string][]mockResponse{"cvm.DescribeZoneInstanceConfigInfos": {mockOk(`{"InstanceTypeQuotaSet":[{"Zone":"ap-guangzhou-3","InstanceType":"S2.MEDIUM4","InstanceChargeType":"POSTPAID_BY_HOUR","Cpu":2,"Memory":4,"Status":"SOLD_OUT","Price":{"UnitPrice":0.4,"ChargeUnit":"HOUR"}}],"RequestId":"quota-request"}`)}},
+			wantCalls:  []string{"cvm.DescribeZoneInstanceConfigInfos"},
+			wantOutput: map[string]string{"guid": "guid", "request_id": "quota-request", "zone": "ap-guangzhou-3", "cpu": "2", "sold_out": "true", "unit_price": "0.4", "charge_unit": "HOUR"},
+		},
+		{
+			name:   "prepaid",
+			action: "check-quota",
+			input:  map[string]interface{}{"guid": "guid", "instance_type": "S2.MEDIUM4", "instance_charge_type": "PREPAID"},
+			responses: map[string][]mockResponse{"cvm.DescribeZoneInstanceConfigInfos": {mockOk(`{"InstanceTypeQuotaSet":[{"Zone":"ap-guangzhou-3","InstanceType":"S2.MEDIUM4",` +
+				`"InstanceChargeType":"PREPAID","Cpu":2,"Memory":4,"Status":"SELL","Price":{"OriginalPrice":200,"DiscountPrice":160}}]}`)}},
+			wantCalls:  []string{"cvm.DescribeZoneInstanceConfigInfos"},
+			wantOutput: map[string]string{"instance_charge_type": "PREPAID", "original_price": "200", "discount_price": "160", "unit_price": "<nil>"},
+		},
+		{
+			name:   "success",
+			action: "terminate",
+			input:  map[string]interface{}{"guid": "guid", "id": "ins-1"},
+			responses: map[string][]mockResponse{
+				"cvm.TerminateInstances": {mockOk(`{}`)},
+				"cvm.DescribeInstances":  {mockOk(`{"TotalCount":0,"InstanceSet":[]}`)},
+			},
+			wantCalls:  []string{"cvm.TerminateInstances", "cvm.DescribeInstances"},
+			wantOutput: map[string]string{"id": "ins-1"},
+			slow:       true,
+		},
+		{
+			name:      "not found",
+			action:    "terminate",
+			input:     map[string]interface{}{"guid": "guid", "id": "ins-gone"},
+			responses: map[string][]mockResponse{"cvm.TerminateInstances": {mockError("InvalidInstanceId.NotFound")}},
+			wantErr:   "InvalidInstanceId.NotFound",
+			wantCalls: []string{"cvm.TerminateInstances"},
+		},
+		{
+			name:   "success",
+			action: "start",
+			input:  map[string]interface{}{"guid": "guid", "id": "ins-1"},
+			responses: map[string][]mockResponse{
+				"cvm.StartInstances":    {mockOk(`{"RequestId":"start-request"}`)},
+				"cvm.DescribeInstances": {mockOk(instanceInState("STARTING"))},
+			},
+			wantCalls:  []string{"cvm.StartInstances", "cvm.DescribeInstances"},
+			wantOutput: map[string]string{"id": "ins-1", "request_id": "start-request", "instance_state": "STARTING"},
+		},
+		{
+			name:      "not found",
+			action:    "start",
+			input:     map[string]interface{}{"guid": "guid", "id": "ins-gone"},
+			responses: map[string][]mockResponse{"cvm.StartInstances": {mockError("InvalidInstanceId.NotFound")}},
+			wantErr:   "InvalidInstanceId.NotFound",
+			wantCalls: []string{"cvm.StartInstances"},
+		},
+		{
+			name:    "invalid wait",
+			action:  "start",
+			input:   map[string]interface{}{"guid": "guid", "id": "ins-1", "wait": "yes please"},
+			wantErr: "invalid wait(yes please)",
+		},
+		{
+			name:   "success",
+			action: "stop",
+			input:  map[string]interface{}{"guid": "guid", "id": "ins-1"},
+			responses: map[string][]mockResponse{
+				"cvm.StopInstances":     {mockOk(`{}`)},
+				"cvm.DescribeInstances": {mockOk(instanceInState("STOPPING"))},
+			},
+			wantCalls:  []string{"cvm.StopInstances", "cvm.DescribeInstances"},
+			wantOutput: map[string]string{"id": "ins-1", "instance_state": "STOPPING"},
+		},
+		{
+			name:   "wait",
+			action: "stop",
+			input:  map[string]interface{}{"guid": "guid", "id": "ins-1", "wait": "true", "stop_type": "SOFT_FIRST", "stopped_mode": "STOP_CHARGING"},
+			responses: map[string][]mockResponse{
+				"cvm.StopInstances":     {mockOk(`{}`)},
+				"cvm.DescribeInstances": {mockOk(instanceInState("STOPPED"))},
+			},
+			wantCalls:  []string{"cvm.StopInstances", "cvm.DescribeInstances", "cvm.DescribeInstances"},
+			wantOutput: map[string]string{"id": "ins-1", "instance_state": "STOPPED"},
+			slow:       true,
+		},
+		{
+			name:      "error",
+			action:    "stop",
+			input:     map[string]interface{}{"guid": "guid", "id": "ins-1"},
+			responses: map[string][]mockResponse{"cvm.StopInstances": {mockError("UnsupportedOperation.InstanceStateStopped")}},
+			wantErr:   "UnsupportedOperation.InstanceStateStopped",
+			wantCalls: []string{"cvm.StopInstances"},
+		},
+		{
+			name:    "invalid stop type",
+			action:  "stop",
+			input:   map[string]interface{}{"guid": "guid", "id": "ins-1", "stop_type": "GENTLE"},
+			wantErr: "GENTLE is not valid value",
+		},
+		{
+			name:    "invalid stopped mode",
+			action:  "stop",
+			input:   map[string]interface{}{"guid": "guid", "id": "ins-1", "stopped_mode": "FREE"},
+			wantErr: "FREE is not valid value",
+		},
+		{
+			name:   "success",
+			action: "reboot",
+			input:  map[string]interface{}{"guid": "guid", "id": "ins-1", "stop_type": "HARD"},
+			responses: map[string][]mockResponse{
+				"cvm.RebootInstances":   {mockOk(`{}`)},
+				"cvm.DescribeInstances": {mockOk(instanceInState("REBOOTING"))},
+			},
+			wantCalls:  []string{"cvm.RebootInstances", "cvm.DescribeInstances"},
+			wantOutput: map[string]string{"id": "ins-1", "instance_state": "REBOOTING"},
+		},
+		{
+			name:   "wait",
+			action: "reboot",
+			input:  map[string]interface{}{"guid": "guid", "id": "ins-1", "wait": "true"},
+			responses: map[string][]mockResponse{
+				"cvm.RebootInstances": {mockOk(`{"RequestId":"req-reboot"}`)},
+				"cvm.DescribeInstances": {
+					mockOk(`{"TotalCount":1,"InstanceSet":[{"InstanceId":"ins-1","InstanceState":"RUNNING","LatestOperationState":"OPERATING","LatestOperationRequestId":"req-reboot"}]}`),
+					mockOk(`{"TotalCount":1,"InstanceSet":[{"InstanceId":"ins-1","InstanceState":"RUNNING","LatestOperationState":"SUCCESS","LatestOperationRequestId":"req-reboot"}]}`),
+					mockOk(instanceInState("RUNNING")),
+				},
+			},
+			wantCalls:  []string{"cvm.RebootInstances", "cvm.DescribeInstances", "cvm.DescribeInstances", "cvm.DescribeInstances", "cvm.DescribeInstances"},
+			wantOutput: map[string]string{"id": "ins-1", "instance_state": "RUNNING", "request_id": "req-reboot"},
+		},
+		{
+			name:      "error",
+			action:    "reboot",
+			input:     map[string]interface{}{"guid": "guid", "id": "ins-1"},
+			responses: map[string][]mockResponse{"cvm.RebootInstances": {mockError("UnsupportedOperation.InstanceStateStopped")}},
+			wantErr:   "UnsupportedOperation.InstanceStateStopped",
+			wantCalls: []string{"cvm.RebootInstances"},
+		},
+		{
+			name:       "running",
+			action:     "reboot",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "ins-1", "stop_type": "HARD"},
+			responses:  map[string][]mockResponse{"cvm.DescribeInstances": {mockOk(instanceInState("RUNNING"))}},
+			wantCalls:  []string{"cvm.DescribeInstances"},
+			wantOutput: map[string]string{"id": "ins-1", "operation": "modify", "detail": "reboot instance with stop type[HARD]"},
+		},
+		{
+			name:       "stopped",
+			action:     "reboot",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "ins-1"},
+			responses:  map[string][]mockResponse{"cvm.DescribeInstances": {mockOk(instanceInState("STOPPED"))}},
+			wantCalls:  []string{"cvm.DescribeInstances"},
+			wantOutput: map[string]string{"operation": "none", "conflicts": "[instance is in state[STOPPED], should be RUNNING]"},
+		},
+	})
+}
+
+func TestStorageActions(t *testing.T) {
+	createInput := func(id string) map[string]interface{} {
+		return map[string]interface{}{
+			"guid": "guid", "id": id, "disk_type": "CLOUD_PREMIUM", "disk_size": 50, "disk_name": "disk",
+			"disk_charge_type": "POSTPAID_BY_HOUR", "instance_id": "ins-1",
+		}
+	}
+	disk := func(id string, state string, instanceId string, size int) mockResponse {
+		return mockOk(fmt.Sprintf(`{"TotalCount":1,"DiskSet":[{"DiskId":"%s","DiskState":"%s","InstanceId":"%s","DiskSize":%d}]}`, id, state, instanceId, size))
+	}
+	noDisk := mockOk(`{"TotalCount":0,"DiskSet":[]}`)
+	//disks are split between the two instances in order
+	disks := func(state string, firstInstanceId string, secondInstanceId string, ids ...string) mockResponse {
+		items := []string{}
+		for i, id := range ids {
+			instanceId := firstInstanceId
+			if i >= len(ids)/2 {
+				instanceId = secondInstanceId
+			}
+			items = append(items, fmt.Sprintf(`{"DiskId":"%s","DiskState":"%s","InstanceId":"%s","DiskSize":50}`, id, state, instanceId))
+		}
+		return mockOk(fmt.Sprintf(`{"TotalCount":%d,"DiskSet":[%s]}`, len(ids), strings.Join(items, ",")))
+	}
+	daysAgo := func(days int) string {
+		return time.Now().AddDate(0, 0, -days).Add(-time.Hour).In(storageTimeLocation).Format(STORAGE_TIME_LAYOUT)
+	}
+	//the orphans are disk-<age> created age days ago and last attached to ins-old
+	chargedOrphans := func(chargeType string, ages ...int) mockResponse {
+		items := []string{}
+		for _, age := range ages {
+			items = append(items, fmt.Sprintf(`{"DiskId":"disk-%d","DiskState":"UNATTACHED","DiskSize":50,"DiskChargeType":"%s","CreateTime":"%s","LastAttachInsId":"ins-old"}`, age, chargeType, daysAgo(age)))
+		}
+		return mockOk(fmt.Sprintf(`{"TotalCount":%d,"DiskSet":[%s]}`, len(ages), strings.Join(items, ",")))
+	}
+	orphans := func(ages ...int) mockResponse {
+		return chargedOrphans("POSTPAID_BY_HOUR", ages...)
+	}
+	noDetachLog := mockOk(`{"DiskOperationLogSet":[]}`)
+	//disk-40 was detached from ins-old days ago
+	detachLog := func(days int) mockResponse {
+		return mockOk(fmt.Sprintf(`{"DiskOperationLogSet":[{"DiskId":"disk-40","Operation":"CBS_OPERATION_ATTACH","OperationState":"SUCCESS","StartTime":"%s"},`+
+			`{"DiskId":"disk-40","Operation":"CBS_OPERATION_DETACH","OperationState":"SUCCESS","StartTime":"%s"},`+
+			`{"DiskId":"disk-40","Operation":"CBS_OPERATION_DETACH","OperationState":"FAILED","StartTime":"%s"}]}`, daysAgo(39), daysAgo(days), daysAgo(1)))
+	}
+	instance := func(id string, diskIds ...string) mockResponse {
+		dataDisks := []string{}
+		for _, diskId := range diskIds {
+			dataDisks = append(dataDisks, fmt.Sprintf(`{"DiskId":"%s"}`, diskId))
+		}
+		return mockOk(fmt.Sprintf(`{"TotalCount":1,"InstanceSet":[{"InstanceId":"%s","DataDisks":[%s]}]}`, id, strings.Join(dataDisks, ",")))
+	}
+
+	runMockActionCases(t, "storage", []mockActionCase{
+		{
+			name:   "success",
+			action: "create",
+			input:  createInput(""),
+			responses: map[string][]mockResponse{
+				"cbs.CreateDisks":       {mockOk(`{"DiskIdSet":["disk-new"]}`)},
+				"cbs.DescribeDisks":     {disk("disk-new", "UNATTACHED", "", 50), disk("disk-new", "ATTACHED", "ins-1", 50)},
+				"cbs.AttachDisks":       {mockOk(`{}`)},
+				"cvm.DescribeInstances": {instance("ins-1", "disk-old", "disk-new")},
+			},
+			wantCalls:  []string{"cbs.CreateDisks", "cbs.DescribeDisks", "cbs.AttachDisks", "cbs.DescribeDisks", "cvm.DescribeInstances"},
+			wantOutput: map[string]string{"id": "disk-new", "disk_state": "ATTACHED", "instance_id": "ins-1", "device_name_hint": "/dev/vdc"},
+			slow:       true,
+		},
+		{
+			name:   "without instance",
+			action: "create",
+			input: map[string]interface{}{
+				"guid": "guid", "disk_type": "CLOUD_PREMIUM", "disk_size": 50, "disk_name": "disk", "disk_charge_type": "POSTPAID_BY_HOUR",
+			},
+			responses: map[string][]mockResponse{
+				"cbs.CreateDisks":   {mockOk(`{"DiskIdSet":["disk-new"]}`)},
+				"cbs.DescribeDisks": {disk("disk-new", "UNATTACHED", "", 50)},
+			},
+			wantCalls:  []string{"cbs.CreateDisks", "cbs.DescribeDisks"},
+			wantOutput: map[string]string{"id": "disk-new", "disk_state": "UNATTACHED"},
+			slow:       true,
+		},
+		{
+			name:   "batch on instances",
+			action: "create",
+			input: map[string]interface{}{
+				"guid": "guid", "disk_type": "CLOUD_PREMIUM", "disk_size": 50, "disk_name": "disk", "disk_charge_type": "POSTPAID_BY_HOUR",
+				"disk_count": 2, "instance_ids": "ins-1,ins-2", "encrypt": "true", "throughput_performance": 100, "tags": "app=web",
+			},
+			responses: map[string][]mockResponse{
+				"cbs.CreateDisks":       {mockOk(`{"DiskIdSet":["disk-1","disk-2","disk-3","disk-4"]}`)},
+				"cbs.DescribeDisks":     {disks("UNATTACHED", "", "", "disk-1", "disk-2", "disk-3", "disk-4"), disks("ATTACHED", "ins-1", "ins-2", "disk-1", "disk-2", "disk-3", "disk-4")},
+				"cbs.AttachDisks":       {mockOk(`{}`)},
+				"cvm.DescribeInstances": {instance("ins-1", "disk-1", "disk-2"), instance("ins-2", "disk-3", "disk-4")},
+			},
+			wantCalls: []string{"cbs.CreateDisks", "cbs.DescribeDisks", "cbs.AttachDisks", "cbs.AttachDisks", "cbs.DescribeDisks",
+				"cvm.DescribeInstances", "cvm.DescribeInstances"},
+			wantOutput: map[string]string{"id": "disk-1", "instance_id": "ins-1", "device_name_hint": "/dev/vdb"},
+			slow:       true,
+		},
+		{
+			name:   "already exists",
+			action: "create",
+			input:  createInput("disk-exist"),
+			responses: map[string][]mockResponse{
+				"cbs.DescribeDisks":     {disk("disk-exist", "ATTACHED", "ins-1", 50)},
+				"cvm.DescribeInstances": {instance("ins-1", "disk-exist")},
+			},
+			wantCalls:  []string{"cbs.DescribeDisks", "cvm.DescribeInstances"},
+			wantOutput: map[string]string{"id": "disk-exist", "disk_state": "ATTACHED", "device_name_hint": "/dev/vdb"},
+		},
+		{
+			name:   "exists on another instance",
+			action: "create",
+			input:  createInput("disk-exist"),
+			responses: map[string][]mockResponse{
+				"cbs.DescribeDisks": {disk("disk-exist", "ATTACHED", "ins-2", 50)},
+			},
+			wantErr:   "storage[disk-exist] is attached to another instance[ins-2]",
+			wantCalls: []string{"cbs.DescribeDisks"},
+		},
+		{
+			name:      "error",
+			action:    "create",
+			input:     createInput(""),
+			responses: map[string][]mockResponse{"cbs.CreateDisks": {mockError("InvalidParameter")}},
+			wantErr:   "InvalidParameter",
+			wantCalls: []string{"cbs.CreateDisks"},
+		},
+		{
+			name:   "attach failed",
+			action: "create",
+			input:  createInput(""),
+			responses: map[string][]mockResponse{
+				"cbs.CreateDisks":   {mockOk(`{"DiskIdSet":["disk-new"]}`)},
+				"cbs.DescribeDisks": {disk("disk-new", "UNATTACHED", "", 50)},
+				"cbs.AttachDisks":   {mockError("InvalidParameterValue")},
+			},
+			wantErr:   "InvalidParameterValue",
+			wantCalls: []string{"cbs.CreateDisks", "cbs.DescribeDisks", "cbs.AttachDisks"},
+			slow:      true,
+		},
+		{
+			name:    "too many disks",
+			action:  "create",
+			input:   map[string]interface{}{"guid": "guid", "disk_count": 20, "instance_ids": "ins-1,ins-2,ins-3"},
+			wantErr: "disk_count(20) on 3 instances exceeds 50 disks",
+		},
+		{
+			name:    "duplicated instances",
+			action:  "create",
+			input:   map[string]interface{}{"guid": "guid", "instance_id": "ins-1", "instance_ids": "ins-2,ins-1"},
+			wantErr: "instance(ins-1) is listed more than once",
+		},
+		{
+			name:    "invalid encrypt",
+			action:  "create",
+			input:   map[string]interface{}{"guid": "guid", "encrypt": "maybe"},
+			wantErr: "invalid encrypt(maybe)",
+		},
+		{
+			name:    "invalid delete with instance",
+			action:  "create",
+			input:   map[string]interface{}{"guid": "guid", "instance_id": "ins-1", "delete_with_instance": "maybe"},
+			wantErr: "invalid delete_with_instance(maybe)",
+		},
+		{
+			name:   "success",
+			action: "terminate",
+			input:  map[string]interface{}{"guid": "guid", "id": "disk-1"},
+			responses: map[string][]mockResponse{
+				"cbs.DescribeDisks":  {disk("disk-1", "ATTACHED", "ins-1", 50), disk("disk-1", "UNATTACHED", "", 50), noDisk},
+				"cbs.DetachDisks":    {mockOk(`{}`)},
+				"cbs.TerminateDisks": {mockOk(`{}`)},
+			},
+			wantCalls:  []string{"cbs.DescribeDisks", "cbs.DetachDisks", "cbs.DescribeDisks", "cbs.TerminateDisks", "cbs.DescribeDisks"},
+			wantOutput: map[string]string{"id": "disk-1"},
+			slow:       true,
+		},
+		{
+			name:       "already terminated",
+			action:     "terminate",
+			input:      map[string]interface{}{"guid": "guid", "id": "disk-gone"},
+			responses:  map[string][]mockResponse{"cbs.DescribeDisks": {noDisk}},
+			wantCalls:  []string{"cbs.DescribeDisks"},
+			wantOutput: map[string]string{"id": "disk-gone"},
+		},
+		{
+			name:   "detach failed",
+			action: "terminate",
+			input:  map[string]interface{}{"guid": "guid", "id": "disk-1"},
+			responses: map[string][]mockResponse{
+				"cbs.DescribeDisks": {disk("disk-1", "ATTACHED", "ins-1", 50)},
+				"cbs.DetachDisks":   {mockError("InvalidDisk.NotSupported")},
+			},
+			wantErr:   "InvalidDisk.NotSupported",
+			wantCalls: []string{"cbs.DescribeDisks", "cbs.DetachDisks"},
+		},
+		{
+			name:   "terminate failed",
+			action: "terminate",
+			input:  map[string]interface{}{"guid": "guid", "id": "disk-1"},
+			responses: map[string][]mockResponse{
+				"cbs.DescribeDisks":  {disk("disk-1", "UNATTACHED", "", 50)},
+				"cbs.TerminateDisks": {mockError("InvalidDisk.Busy")},
+			},
+			wantErr:   "InvalidDisk.Busy",
+			wantCalls: []string{"cbs.DescribeDisks", "cbs.TerminateDisks"},
+		},
+		{
+			name:   "success",
+			action: "attach",
+			input:  map[string]interface{}{"guid": "guid", "id": "disk-1", "instance_id": "ins-1", "delete_with_instance": "false"},
+			responses: map[string][]mockResponse{
+				"cbs.DescribeDisks": {disk("disk-1", "UNATTACHED", "", 50), disk("disk-1", "ATTACHED", "ins-1", 50)},
+				"cbs.AttachDisks":   {mockOk(`{}`)},
+			},
+			wantCalls:  []string{"cbs.DescribeDisks", "cbs.AttachDisks", "cbs.DescribeDisks"},
+			wantOutput: map[string]string{"id": "disk-1", "disk_state": "ATTACHED", "instance_id": "ins-1"},
+			slow:       true,
+		},
+		{
+			name:      "attached to another instance",
+			action:    "attach",
+			input:     map[string]interface{}{"guid": "guid", "id": "disk-1", "instance_id": "ins-1"},
+			responses: map[string][]mockResponse{"cbs.DescribeDisks": {disk("disk-1", "ATTACHED", "ins-2", 50)}},
+			wantErr:   "storage[disk-1] is attached to another instance[ins-2]",
+			wantCalls: []string{"cbs.DescribeDisks"},
+		},
+		{
+			name:      "not found",
+			action:    "attach",
+			input:     map[string]interface{}{"guid": "guid", "id": "disk-gone", "instance_id": "ins-1"},
+			responses: map[string][]mockResponse{"cbs.DescribeDisks": {noDisk}},
+			wantErr:   "storage[disk-gone] not found",
+			wantCalls: []string{"cbs.DescribeDisks"},
+		},
+		{
+			name:    "empty instance id",
+			action:  "attach",
+			input:   map[string]interface{}{"guid": "guid", "id": "disk-1"},
+			wantErr: "input instance_id is empty",
+		},
+		{
+			name:   "success",
+			action: "detach",
+			input:  map[string]interface{}{"guid": "guid", "id": "disk-1"},
+			responses: map[string][]mockResponse{
+				"cbs.DescribeDisks": {disk("disk-1", "ATTACHED", "ins-1", 50), disk("disk-1", "DETACHING", "", 50), disk("disk-1", "UNATTACHED", "", 50)},
+				"cbs.DetachDisks":   {mockOk(`{}`)},
+			},
+			wantCalls:  []string{"cbs.DescribeDisks", "cbs.DetachDisks", "cbs.DescribeDisks", "cbs.DescribeDisks"},
+			wantOutput: map[string]string{"id": "disk-1", "disk_state": "UNATTACHED"},
+			slow:       true,
+		},
+		{
+			name:       "already detached",
+			action:     "detach",
+			input:      map[string]interface{}{"guid": "guid", "id": "disk-1"},
+			responses:  map[string][]mockResponse{"cbs.DescribeDisks": {disk("disk-1", "UNATTACHED", "", 50)}},
+			wantCalls:  []string{"cbs.DescribeDisks"},
+			wantOutput: map[string]string{"id": "disk-1", "disk_state": "UNATTACHED"},
+		},
+		{
+			name:   "success",
+			action: "resize",
+			input:  map[string]interface{}{"guid": "guid", "id": "disk-1", "disk_size": 100},
+			responses: map[string][]mockResponse{
+				"cbs.DescribeDisks": {disk("disk-1", "ATTACHED", "ins-1", 50), disk("disk-1", "EXPANDING", "ins-1", 100), disk("disk-1", "ATTACHED", "ins-1", 100)},
+				"cbs.ResizeDisk":    {mockOk(`{}`)},
+			},
+			wantCalls:  []string{"cbs.DescribeDisks", "cbs.ResizeDisk", "cbs.DescribeDisks", "cbs.DescribeDisks"},
+			wantOutput: map[string]string{"id": "disk-1", "disk_size": "100", "disk_state": "ATTACHED"},
+			slow:       true,
+		},
+		{
+			name:       "already in size",
+			action:     "resize",
+			input:      map[string]interface{}{"guid": "guid", "id": "disk-1", "disk_size": 100},
+			responses:  map[string][]mockResponse{"cbs.DescribeDisks": {disk("disk-1", "ATTACHED", "ins-1", 100)}},
+			wantCalls:  []string{"cbs.DescribeDisks"},
+			wantOutput: map[string]string{"id": "disk-1", "disk_size": "100"},
+		},
+		{
+			name:      "shrink",
+			action:    "resize",
+			input:     map[string]interface{}{"guid": "guid", "id": "disk-1", "disk_size": 20},
+			responses: map[string][]mockResponse{"cbs.DescribeDisks": {disk("disk-1", "ATTACHED", "ins-1", 50)}},
+			wantErr:   "storage[disk-1] can't be shrunk from 50GB to 20GB",
+			wantCalls: []string{"cbs.DescribeDisks"},
+		},
+		{
+			name:   "resize failed",
+			action: "resize",
+			input:  map[string]interface{}{"guid": "guid", "id": "disk-1", "disk_size": 100},
+			responses: map[string][]mockResponse{
+				"cbs.DescribeDisks": {disk("disk-1", "ATTACHED", "ins-1", 50)},
+				"cbs.ResizeDisk":    {mockError("InvalidDisk.Busy")},
+			},
+			wantErr:   "InvalidDisk.Busy",
+			wantCalls: []string{"cbs.DescribeDisks", "cbs.ResizeDisk"},
+		},
+		{
+			name:    "empty disk size",
+			action:  "resize",
+			input:   map[string]interface{}{"guid": "guid", "id": "disk-1"},
+			wantErr: "input disk_size is empty",
+		},
+		{
+			name:       "unattached",
+			action:     "attach",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "disk-1", "instance_id": "ins-1", "delete_with_instance": "true"},
+			responses:  map[string][]mockResponse{"cbs.DescribeDisks": {disk("disk-1", "UNATTACHED", "", 50)}},
+			wantCalls:  []string{"cbs.DescribeDisks"},
+			wantOutput: map[string]string{"id": "disk-1", "exist": "true", "operation": "modify", "detail": "attach disk to instance[ins-1] with delete_with_instance=true"},
+		},
+		{
+			name:       "already attached",
+			action:     "attach",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "disk-1", "instance_id": "ins-1"},
+			responses:  map[string][]mockResponse{"cbs.DescribeDisks": {disk("disk-1", "ATTACHED", "ins-1", 50)}},
+			wantCalls:  []string{"cbs.DescribeDisks"},
+			wantOutput: map[string]string{"operation": "none", "detail": "disk already attached to instance[ins-1]"},
+		},
+		{
+			name:       "attached to another instance",
+			action:     "attach",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "disk-1", "instance_id": "ins-1"},
+			responses:  map[string][]mockResponse{"cbs.DescribeDisks": {disk("disk-1", "ATTACHED", "ins-2", 50)}},
+			wantCalls:  []string{"cbs.DescribeDisks"},
+			wantOutput: map[string]string{"operation": "none", "conflicts": "[storage is attached to another instance[ins-2]]"},
+		},
+		{
+			name:       "not found",
+			action:     "attach",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "disk-gone", "instance_id": "ins-1"},
+			responses:  map[string][]mockResponse{"cbs.DescribeDisks": {noDisk}},
+			wantCalls:  []string{"cbs.DescribeDisks"},
+			wantOutput: map[string]string{"exist": "false", "operation": "none", "conflicts": "[storage[disk-gone] not found]"},
+		},
+		{
+			name:       "attached",
+			action:     "detach",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "disk-1"},
+			responses:  map[string][]mockResponse{"cbs.DescribeDisks": {disk("disk-1", "ATTACHED", "ins-1", 50)}},
+			wantCalls:  []string{"cbs.DescribeDisks"},
+			wantOutput: map[string]string{"operation": "modify", "detail": "detach disk from instance[ins-1]"},
+		},
+		{
+			name:       "already detached",
+			action:     "detach",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "disk-1"},
+			responses:  map[string][]mockResponse{"cbs.DescribeDisks": {disk("disk-1", "UNATTACHED", "", 50)}},
+			wantCalls:  []string{"cbs.DescribeDisks"},
+			wantOutput: map[string]string{"operation": "none", "detail": "disk already detached"},
+		},
+		{
+			name:       "expand",
+			action:     "resize",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "disk-1", "disk_size": 100},
+			responses:  map[string][]mockResponse{"cbs.DescribeDisks": {disk("disk-1", "ATTACHED", "ins-1", 50)}},
+			wantCalls:  []string{"cbs.DescribeDisks"},
+			wantOutput: map[string]string{"operation": "modify", "detail": "expand disk from 50GB to 100GB"},
+		},
+		{
+			name:       "already in size",
+			action:     "resize",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "disk-1", "disk_size": 100},
+			responses:  map[string][]mockResponse{"cbs.DescribeDisks": {disk("disk-1", "ATTACHED", "ins-1", 100)}},
+			wantCalls:  []string{"cbs.DescribeDisks"},
+			wantOutput: map[string]string{"operation": "none", "detail": "disk already 100GB"},
+		},
+		{
+			name:       "shrink",
+			action:     "resize",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "disk-1", "disk_size": 20},
+			responses:  map[string][]mockResponse{"cbs.DescribeDisks": {disk("disk-1", "ATTACHED", "ins-1", 50)}},
+			wantCalls:  []string{"cbs.DescribeDisks"},
+			wantOutput: map[string]string{"operation": "none", "conflicts": "[storage can't be shrunk from 50GB to 20GB]"},
+		},
+		{
+			name:       "report",
+			action:     "find-orphans",
+			input:      map[string]interface{}{"guid": "guid"},
+			responses:  map[string][]mockResponse{"cbs.DescribeDisks": {orphans(3, 40)}, "cbs.DescribeDiskOperationLogs": {noDetachLog}},
+			wantCalls:  []string{"cbs.DescribeDisks", "cbs.DescribeDiskOperationLogs"},
+			wantOutput: map[string]string{"id": "disk-40", "region": "ap-guangzhou", "unattached_days": "40", "last_instance_id": "ins-old", "disk_charge_type": "POSTPAID_BY_HOUR", "deleted": "false"},
+		},
+		{
+			name:       "all regions",
+			action:     "find-orphans",
+			input:      map[string]interface{}{"guid": "guid", "regions": "ap-shanghai,ap-beijing"},
+			responses:  map[string][]mockResponse{"cbs.DescribeDisks": {orphans(), orphans(5)}, "cbs.DescribeDiskOperationLogs": {noDetachLog}},
+			wantCalls:  []string{"cbs.DescribeDisks", "cbs.DescribeDisks", "cbs.DescribeDiskOperationLogs"},
+			wantOutput: map[string]string{"id": "disk-5", "region": "ap-beijing"},
+		},
+		{
+			name:   "delete old orphans",
+			action: "find-orphans",
+			input:  map[string]interface{}{"guid": "guid", "delete_older_than_days": 30},
+			responses: map[string][]mockResponse{
+				"cbs.DescribeDisks":             {orphans(3, 40), noDisk},
+				"cbs.DescribeDiskOperationLogs": {noDetachLog},
+				"cbs.TerminateDisks":            {mockOk(`{}`)},
+			},
+			wantCalls:  []string{"cbs.DescribeDisks", "cbs.DescribeDiskOperationLogs", "cbs.TerminateDisks", "cbs.DescribeDisks"},
+			wantOutput: map[string]string{"id": "disk-40", "deleted": "true"},
+			slow:       true,
+		},
+		{
+			name:       "nothing old enough",
+			action:     "find-orphans",
+			input:      map[string]interface{}{"guid": "guid", "delete_older_than_days": 60},
+			responses:  map[string][]mockResponse{"cbs.DescribeDisks": {orphans(3, 40)}, "cbs.DescribeDiskOperationLogs": {noDetachLog}},
+			wantCalls:  []string{"cbs.DescribeDisks", "cbs.DescribeDiskOperationLogs"},
+			wantOutput: map[string]string{"id": "disk-40", "deleted": "false"},
+		},
+		{
+			name:       "old disk detached recently",
+			action:     "find-orphans",
+			input:      map[string]interface{}{"guid": "guid", "delete_older_than_days": 30},
+			responses:  map[string][]mockResponse{"cbs.DescribeDisks": {orphans(3, 40)}, "cbs.DescribeDiskOperationLogs": {detachLog(5)}},
+			wantCalls:  []string{"cbs.DescribeDisks", "cbs.DescribeDiskOperationLogs"},
+			wantOutput: map[string]string{"id": "disk-40", "unattached_days": "5", "deleted": "false"},
+		},
+		{
+			name:   "old disk detached long ago",
+			action: "find-orphans",
+			input:  map[string]interface{}{"guid": "guid", "delete_older_than_days": 30},
+			responses: map[string][]mockResponse{
+				"cbs.DescribeDisks":             {orphans(3, 40), noDisk},
+				"cbs.DescribeDiskOperationLogs": {detachLog(35)},
+				"cbs.TerminateDisks":            {mockOk(`{}`)},
+			},
+			wantCalls:  []string{"cbs.DescribeDisks", "cbs.DescribeDiskOperationLogs", "cbs.TerminateDisks", "cbs.DescribeDisks"},
+			wantOutput: map[string]string{"id": "disk-40", "unattached_days": "35", "deleted": "true"},
+			slow:       true,
+		},
+		{
+			name:       "prepaid kept",
+			action:     "find-orphans",
+			input:      map[string]interface{}{"guid": "guid", "delete_older_than_days": 30},
+			responses:  map[string][]mockResponse{"cbs.DescribeDisks": {chargedOrphans("PREPAID", 40)}, "cbs.DescribeDiskOperationLogs": {noDetachLog}},
+			wantCalls:  []string{"cbs.DescribeDisks", "cbs.DescribeDiskOperationLogs"},
+			wantOutput: map[string]string{"id": "disk-40", "disk_charge_type": "PREPAID", "deleted": "false"},
+		},
+		{
+			name:   "prepaid included",
+			action: "find-orphans",
+			input:  map[string]interface{}{"guid": "guid", "delete_older_than_days": 30, "include_prepaid": "true"},
+			responses: map[string][]mockResponse{
+				"cbs.DescribeDisks":             {chargedOrphans("PREPAID", 40), noDisk},
+				"cbs.DescribeDiskOperationLogs": {noDetachLog},
+				"cbs.TerminateDisks":            {mockOk(`{}`)},
+			},
+			wantCalls:  []string{"cbs.DescribeDisks", "cbs.DescribeDiskOperationLogs", "cbs.TerminateDisks", "cbs.DescribeDisks"},
+			wantOutput: map[string]string{"id": "disk-40", "disk_charge_type": "PREPAID", "deleted": "true"},
+			slow:       true,
+		},
+		{
+			name:      "describe logs failed",
+			action:    "find-orphans",
+			input:     map[string]interface{}{"guid": "guid"},
+			responses: map[string][]mockResponse{"cbs.DescribeDisks": {orphans(40)}, "cbs.DescribeDiskOperationLogs": {mockError("AuthFailure")}},
+			wantErr:   "AuthFailure",
+			wantCalls: []string{"cbs.DescribeDisks", "cbs.DescribeDiskOperationLogs"},
+		},
+		{
+			name:    "invalid include prepaid",
+			action:  "find-orphans",
+			input:   map[string]interface{}{"guid": "guid", "include_prepaid": "maybe"},
+			wantErr: "invalid include_prepaid(maybe)",
+		},
+		{
+			name:      "describe failed",
+			action:    "find-orphans",
+			input:     map[string]interface{}{"guid": "guid"},
+			responses: map[string][]mockResponse{"cbs.DescribeDisks": {mockError("AuthFailure")}},
+			wantErr:   "AuthFailure",
+			wantCalls: []string{"cbs.DescribeDisks"},
+		},
+		{
+			name:    "invalid days",
+			action:  "find-orphans",
+			input:   map[string]interface{}{"guid": "guid", "delete_older_than_days": -1},
+			wantErr: "invalid delete_older_than_days(-1)",
+		},
+	})
+}
+
+func TestMysqlVmActions(t *testing.T) {
+	createInput := func(id string) map[string]interface{} {
+		return map[string]interface{}{
+			"guid": "guid", "seed": "seed", "id": id, "engine_version": "5.7", "memory": 1000, "volume": 50,
+			"vpc_id": "vpc-1", "subnet_id": "subnet-1", "name": "mysql", "count": 1, "charge_type": "POSTPAID_BY_HOUR",
+		}
+	}
+	instance := mockOk(`{"TotalCount":1,"Items":[{"InstanceId":"cdb-1","Status":1,"InitFlag":1,"Vip":"10.0.1.7","Vport":3306}]}`)
+
+	runMockActionCases(t, "mysql-vm", []mockActionCase{
+		{
+			name:   "success",
+			action: "create",
+			input:  createInput(""),
+			responses: map[string][]mockResponse{
+				"cdb.CreateDBInstanceHour": {mockOk(`{"InstanceIds":["cdb-1"]}`)},
+				"cdb.DescribeDBInstances":  {instance},
+				"cdb.InitDBInstances":      {mockOk(`{"AsyncRequestIds":["async-1"]}`)},
+			},
+			wantCalls:  []string{"cdb.CreateDBInstanceHour", "cdb.DescribeDBInstances", "cdb.InitDBInstances", "cdb.DescribeDBInstances"},
+			wantOutput: map[string]string{"id": "cdb-1", "private_ip": "10.0.1.7", "private_port": "3306", "user_name": "root"},
+		},
+		{
+			name:       "already exists",
+			action:     "create",
+			input:      createInput("cdb-1"),
+			responses:  map[string][]mockResponse{"cdb.DescribeDBInstances": {instance}},
+			wantCalls:  []string{"cdb.DescribeDBInstances"},
+			wantOutput: map[string]string{"id": "cdb-1", "private_ip": "10.0.1.7"},
+		},
+		{
+			name:      "error",
+			action:    "create",
+			input:     createInput(""),
+			responses: map[string][]mockResponse{"cdb.CreateDBInstanceHour": {mockError("InvalidParameter")}},
+			wantErr:   "InvalidParameter",
+			wantCalls: []string{"cdb.CreateDBInstanceHour"},
+		},
+		{
+			name:   "retry after wait error",
+			action: "create",
+			input:  createInput(""),
+			responses: map[string][]mockResponse{
+				"cdb.CreateDBInstanceHour": {mockOk(`{"InstanceIds":["cdb-1"]}`)},
+				"cdb.DescribeDBInstances":  {mockError("InternalError")},
+			},
+			wantErr:   "InternalError",
+			wantCalls: []string{"cdb.CreateDBInstanceHour", "cdb.DescribeDBInstances"},
+			retry: &mockActionCase{
+				responses: map[string][]mockResponse{
+					"cdb.DescribeDBInstances": {instance},
+					"cdb.InitDBInstances":     {mockOk(`{"AsyncRequestIds":["async-1"]}`)},
+				},
+				wantCalls:  []string{"cdb.DescribeDBInstances", "cdb.InitDBInstances", "cdb.DescribeDBInstances"},
+				wantOutput: map[string]string{"id": "cdb-1", "private_ip": "10.0.1.7", "private_port": "3306", "request_id": MOCK_REQUEST_ID},
+			},
+		},
+		{
+			name:   "success",
+			action: "terminate",
+			input:  map[string]interface{}{"guid": "guid", "id": "cdb-1"},
+			responses: map[string][]mockResponse{
+				"cdb.IsolateDBInstance":   {mockOk(`{"AsyncRequestId":"async-1"}`)},
+				"cdb.DescribeDBInstances": {mockOk(`{"TotalCount":1,"Items":[{"InstanceId":"cdb-1","Status":5}]}`)},
+			},
+			wantCalls:  []string{"cdb.IsolateDBInstance", "cdb.DescribeDBInstances"},
+			wantOutput: map[string]string{"id": "cdb-1"},
+		},
+		{
+			name:      "not found",
+			action:    "terminate",
+			input:     map[string]interface{}{"guid": "guid", "id": "cdb-gone"},
+			responses: map[string][]mockResponse{"cdb.IsolateDBInstance": {mockError("InvalidParameter.InstanceNotFound")}},
+			wantErr:   "InvalidParameter.InstanceNotFound",
+			wantCalls: []string{"cdb.IsolateDBInstance"},
+		},
+		{
+			name:   "success",
+			action: "restart",
+			input:  map[string]interface{}{"guid": "guid", "id": "cdb-1"},
+			responses: map[string][]mockResponse{
+				"cdb.RestartDBInstances":       {mockOk(`{"AsyncRequestId":"async-1"}`)},
+				"cdb.DescribeAsyncRequestInfo": {mockOk(`{"Status":"SUCCESS"}`)},
+			},
+			wantCalls: []string{"cdb.RestartDBInstances", "cdb.DescribeAsyncRequestInfo"},
+		},
+		{
+			name:      "not found",
+			action:    "restart",
+			input:     map[string]interface{}{"guid": "guid", "id": "cdb-gone"},
+			responses: map[string][]mockResponse{"cdb.RestartDBInstances": {mockError("InvalidParameter.InstanceNotFound")}},
+			wantErr:   "InvalidParameter.InstanceNotFound",
+			wantCalls: []string{"cdb.RestartDBInstances"},
+		},
+		{
+			name:   "error",
+			action: "restart",
+			input:  map[string]interface{}{"guid": "guid", "id": "cdb-1"},
+			responses: map[string][]mockResponse{
+				"cdb.RestartDBInstances":       {mockOk(`{"AsyncRequestId":"async-1"}`)},
+				"cdb.DescribeAsyncRequestInfo": {mockOk(`{"Status":"FAILED"}`)},
+			},
+			wantErr:   "waitForAsyncTaskToFinish failed",
+			wantCalls: []string{"cdb.RestartDBInstances", "cdb.DescribeAsyncRequestInfo"},
+		},
+	})
+}
+
+func TestRedisActions(t *testing.T) {
+	createInput := func(id string) map[string]interface{} {
+		return map[string]interface{}{
+			"guid": "guid", "id": id, "type_id": 2, "mem_size": 1024, "goods_num": 1, "period": 1,
+			"password": "Abcd1234", "vpc_id": "vpc-1", "subnet_id": "subnet-1",
+		}
+	}
+	zones := mockOk(`{"TotalCount":1,"ZoneSet":[{"Zone":"ap-guangzhou-3","ZoneId":"100003","ZoneState":"AVAILABLE"}]}`)
+
+	runMockActionCases(t, "redis", []mockActionCase{
+		{
+			name:   "success",
+			action: "create",
+			input:  createInput(""),
+			responses: map[string][]mockResponse{
+				"cvm.DescribeZones":                {zones},
+				"redis.CreateInstances":            {mockOk(`{"DealId":"deal-1"}`)},
+				"redis.DescribeInstanceDealDetail": {mockOk(`{"DealDetails":[{"DealId":"deal-1","Status":4,"InstanceIds":["crs-new"]}]}`)},
+			},
+			wantCalls:  []string{"cvm.DescribeZones", "redis.CreateInstances", "redis.DescribeInstanceDealDetail"},
+			wantOutput: map[string]string{"id": "crs-new", "deal_id": "deal-1"},
+		},
+		{
+			name:       "already exists",
+			action:     "create",
+			input:      createInput("crs-exist"),
+			responses:  map[string][]mockResponse{"redis.DescribeInstances": {mockOk(`{"TotalCount":1,"InstanceSet":[{"InstanceId":"crs-exist"}]}`)}},
+			wantCalls:  []string{"redis.DescribeInstances"},
+			wantOutput: map[string]string{"id": "crs-exist"},
+		},
+		{
+			name:   "not found",
+			action: "create",
+			input:  createInput(""),
+			responses: map[string][]mockResponse{
+				"cvm.DescribeZones": {mockOk(`{"TotalCount":1,"ZoneSet":[{"Zone":"ap-guangzhou-3","ZoneId":"100003","ZoneState":"UNAVAILABLE"}]}`)},
+			},
+			wantErr:   "not found available zone info",
+			wantCalls: []string{"cvm.DescribeZones"},
+		},
+		{
+			name:   "error",
+			action: "create",
+			input:  createInput(""),
+			responses: map[string][]mockResponse{
+				"cvm.DescribeZones":     {zones},
+				"redis.CreateInstances": {mockError("InvalidParameter.PermissionDenied")},
+			},
+			wantErr:   "InvalidParameter.PermissionDenied",
+			wantCalls: []string{"cvm.DescribeZones", "redis.CreateInstances"},
+		},
+		{
+			name:   "retry after wait error",
+			action: "create",
+			input:  createInput(""),
+			responses: map[string][]mockResponse{
+				"cvm.DescribeZones":                {zones},
+				"redis.CreateInstances":            {mockOk(`{"DealId":"deal-1"}`)},
+				"redis.DescribeInstanceDealDetail": {mockError("InternalError")},
+			},
+			wantErr:   "InternalError",
+			wantCalls: []string{"cvm.DescribeZones", "redis.CreateInstances", "redis.DescribeInstanceDealDetail"},
+			retry: &mockActionCase{
+				responses:  map[string][]mockResponse{"redis.DescribeInstanceDealDetail": {mockOk(`{"DealDetails":[{"DealId":"deal-1","Status":4,"InstanceIds":["crs-new"]}]}`)}},
+				wantCalls:  []string{"redis.DescribeInstanceDealDetail"},
+				wantOutput: map[string]string{"id": "crs-new", "deal_id": "deal-1"},
+			},
+		},
+	})
+}
+
+func TestMariadbActions(t *testing.T) {
+	createInput := func(id string) map[string]interface{} {
+		return map[string]interface{}{
+			"guid": "guid", "seed": "seed", "id": id, "zones": "ap-guangzhou-3", "vpc_id": "vpc-1", "subnet_id": "subnet-1",
+			"node_count": 2, "memory_size": 2, "storage_size": 10, "charge_period": 1, "db_version": "10.1.9",
+		}
+	}
+	instance := `{"TotalCount":1,"Instances":[{"InstanceId":"tdsql-1","Status":%d,"Vip":"10.0.1.8","Vport":3306}]}`
+
+	runMockActionCases(t, "mariadb", []mockActionCase{
+		{
+			name:   "success",
+			action: "create",
+			input:  createInput(""),
+			responses: map[string][]mockResponse{
+				"mariadb.CreateDBInstance": {mockOk(`{"DealName":"deal-1"}`)},
+				"mariadb.DescribeOrders":   {mockOk(`{"TotalCount":1,"Deals":[{"DealName":"deal-1","InstanceIds":["tdsql-1"]}]}`)},
+				"mariadb.DescribeDBInstances": {
+					mockOk(fmt.Sprintf(instance, MARIADB_WAIT_INIT_STATUS)),
+					mockOk(fmt.Sprintf(instance, MARIADB_RUNNING_STATUS)),
+				},
+				"mariadb.InitDBInstances":        {mockOk(`{"FlowId":1,"InstanceIds":["tdsql-1"]}`)},
+				"mariadb.DescribeFlow":           {mockOk(`{"Status":0}`)},
+				"mariadb.CreateAccount":          {mockOk(`{}`)},
+				"mariadb.GrantAccountPrivileges": {mockOk(`{}`)},
+			},
+			wantCalls: []string{
+				"mariadb.CreateDBInstance", "mariadb.DescribeOrders", "mariadb.DescribeDBInstances", "mariadb.InitDBInstances",
+				"mariadb.DescribeFlow", "mariadb.DescribeDBInstances", "mariadb.CreateAccount", "mariadb.GrantAccountPrivileges",
+			},
+			wantOutput: map[string]string{"id": "tdsql-1", "private_ip": "10.0.1.8", "private_port": "3306"},
+		},
+		{
+			name:       "already exists",
+			action:     "create",
+			input:      createInput("tdsql-1"),
+			responses:  map[string][]mockResponse{"mariadb.DescribeDBInstances": {mockOk(fmt.Sprintf(instance, MARIADB_RUNNING_STATUS))}},
+			wantCalls:  []string{"mariadb.DescribeDBInstances"},
+			wantOutput: map[string]string{"id": "tdsql-1"},
+		},
+		{
+			name:   "not found",
+			action: "create",
+			input:  createInput(""),
+			responses: map[string][]mockResponse{
+				"mariadb.CreateDBInstance": {mockOk(`{"DealName":"deal-1"}`)},
+				"mariadb.DescribeOrders":   {mockOk(`{"TotalCount":0,"Deals":[]}`)},
+			},
+			wantErr:   "descirbeOrder totalcount!=1",
+			wantCalls: []string{"mariadb.CreateDBInstance", "mariadb.DescribeOrders"},
+		},
+		{
+			name:      "error",
+			action:    "create",
+			input:     createInput(""),
+			responses: map[string][]mockResponse{"mariadb.CreateDBInstance": {mockError("InvalidParameter")}},
+			wantErr:   "InvalidParameter",
+			wantCalls: []string{"mariadb.CreateDBInstance"},
+		},
+		{
+			name:   "retry after grant error",
+			action: "create",
+			input:  createInput(""),
+			responses: map[string][]mockResponse{
+				"mariadb.CreateDBInstance": {mockOk(`{"DealName":"deal-1"}`)},
+				"mariadb.DescribeOrders":   {mockOk(`{"TotalCount":1,"Deals":[{"DealName":"deal-1","InstanceIds":["tdsql-1"]}]}`)},
+				"mariadb.DescribeDBInstances": {
+					mockOk(fmt.Sprintf(instance, MARIADB_WAIT_INIT_STATUS)),
+					mockOk(fmt.Sprintf(instance, MARIADB_RUNNING_STATUS)),
+				},
+				"mariadb.InitDBInstances":        {mockOk(`{"FlowId":1,"InstanceIds":["tdsql-1"]}`)},
+				"mariadb.DescribeFlow":           {mockOk(`{"Status":0}`)},
+				"mariadb.CreateAccount":          {mockOk(`{}`)},
+				"mariadb.GrantAccountPrivileges": {mockError("InternalError")},
+			},
+			wantErr: "InternalError",
+			wantCalls: []string{
+				"mariadb.CreateDBInstance", "mariadb.DescribeOrders", "mariadb.DescribeDBInstances", "mariadb.InitDBInstances",
+				"mariadb.DescribeFlow", "mariadb.DescribeDBInstances", "mariadb.CreateAccount", "mariadb.GrantAccountPrivileges",
+			},
+			retry: &mockActionCase{
+				responses: map[string][]mockResponse{
+					"mariadb.DescribeDBInstances":    {mockOk(fmt.Sprintf(instance, MARIADB_RUNNING_STATUS))},
+					"mariadb.GrantAccountPrivileges": {mockOk(`{}`)},
+				},
+				wantCalls:  []string{"mariadb.DescribeDBInstances", "mariadb.GrantAccountPrivileges"},
+				wantOutput: map[string]string{"id": "tdsql-1", "private_ip": "10.0.1.8", "request_id": MOCK_REQUEST_ID},
+			},
+		},
+	})
+}
+
+func TestVmChangeActions(t *testing.T) {
+	instance := func(instanceType string, imageId string, state string) mockResponse {
+		return mockOk(fmt.Sprintf(`{"TotalCount":1,"InstanceSet":[{"InstanceId":"ins-1","InstanceType":"%s","ImageId":"%s",`+
+			`"InstanceState":"%s","CPU":2,"Memory":4,"PrivateIpAddresses":["10.0.1.6"]}]}`, instanceType, imageId, state))
+	}
+	notFound := mockOk(`{"TotalCount":0,"InstanceSet":[]}`)
+	operation := func(requestId string, state string) mockResponse {
+		return mockOk(fmt.Sprintf(`{"TotalCount":1,"InstanceSet":[{"InstanceId":"ins-1","LatestOperation":"ResetInstancesPassword",`+
+			`"LatestOperationState":"%s","LatestOperationRequestId":"%s"}]}`, state, requestId))
+	}
+
+	runMockActionCases(t, "vm", []mockActionCase{
+		{
+			name:   "success",
+			action: "resize",
+			input:  map[string]interface{}{"guid": "guid", "id": "ins-1", "instance_type": "S2.LARGE8"},
+			responses: map[string][]mockResponse{
+				"cvm.DescribeInstances": {
+					instance("S2.MEDIUM4", "img-1", "RUNNING"), instance("S2.MEDIUM4", "img-1", "STOPPED"),
+					instance("S2.LARGE8", "img-1", "STOPPED"), instance("S2.LARGE8", "img-1", "RUNNING"),
+				},
+				"cvm.StopInstances":      {mockOk(`{}`)},
+				"cvm.ResetInstancesType": {mockOk(`{}`)},
+				"cvm.StartInstances":     {mockOk(`{}`)},
+			},
+			wantCalls: []string{
+				"cvm.DescribeInstances", "cvm.StopInstances", "cvm.DescribeInstances", "cvm.ResetInstancesType",
+				"cvm.DescribeInstances", "cvm.StartInstances", "cvm.DescribeInstances", "cvm.DescribeInstances",
+			},
+			wantOutput: map[string]string{"id": "ins-1", "instance_state": "RUNNING"},
+			slow:       true,
+		},
+		{
+			name:       "already resized",
+			action:     "resize",
+			input:      map[string]interface{}{"guid": "guid", "id": "ins-1", "instance_type": "S2.LARGE8"},
+			responses:  map[string][]mockResponse{"cvm.DescribeInstances": {instance("S2.LARGE8", "img-1", "RUNNING")}},
+			wantCalls:  []string{"cvm.DescribeInstances", "cvm.DescribeInstances"},
+			wantOutput: map[string]string{"id": "ins-1", "cpu": "2"},
+		},
+		{
+			name:      "not found",
+			action:    "resize",
+			input:     map[string]interface{}{"guid": "guid", "id": "ins-gone", "instance_type": "S2.LARGE8"},
+			responses: map[string][]mockResponse{"cvm.DescribeInstances": {notFound}},
+			wantErr:   VM_NOT_FOUND_ERROR.Error(),
+			wantCalls: []string{"cvm.DescribeInstances"},
+		},
+		{
+			name:      "pending",
+			action:    "resize",
+			input:     map[string]interface{}{"guid": "guid", "id": "ins-1", "instance_type": "S2.LARGE8"},
+			responses: map[string][]mockResponse{"cvm.DescribeInstances": {instance("S2.MEDIUM4", "img-1", "PENDING")}},
+			wantErr:   "vm[ins-1] is in state[PENDING]",
+			wantCalls: []string{"cvm.DescribeInstances"},
+		},
+		{
+			name:   "error",
+			action: "resize",
+			input:  map[string]interface{}{"guid": "guid", "id": "ins-1", "instance_type": "S2.LARGE8"},
+			responses: map[string][]mockResponse{
+				"cvm.DescribeInstances":  {instance("S2.MEDIUM4", "img-1", "STOPPED")},
+				"cvm.ResetInstancesType": {mockError("LimitExceeded.InstanceTypeBandwidth")},
+			},
+			wantErr:   "LimitExceeded.InstanceTypeBandwidth",
+			wantCalls: []string{"cvm.DescribeInstances", "cvm.ResetInstancesType"},
+		},
+		{
+			name:    "missing instance type",
+			action:  "resize",
+			input:   map[string]interface{}{"guid": "guid", "id": "ins-1"},
+			wantErr: "input instance_type is empty",
+		},
+		{
+			name:   "success",
+			action: "reinstall",
+			input:  map[string]interface{}{"guid": "guid", "seed": "seed", "id": "ins-1", "image_id": "img-2"},
+			responses: map[string][]mockResponse{
+				"cvm.ResetInstance": {mockOk(`{"RequestId":"req-reinstall"}`)},
+				"cvm.DescribeInstances": {
+					operation("req-old", "SUCCESS"), operation("req-reinstall", "OPERATING"), operation("req-reinstall", "SUCCESS"),
+					instance("S2.MEDIUM4", "img-2", "RUNNING"),
+				},
+			},
+			wantCalls:  []string{"cvm.ResetInstance", "cvm.DescribeInstances", "cvm.DescribeInstances", "cvm.DescribeInstances", "cvm.DescribeInstances"},
+			wantOutput: map[string]string{"id": "ins-1", "instance_state": "RUNNING"},
+		},
+		{
+			name:   "failed",
+			action: "reinstall",
+			input:  map[string]interface{}{"guid": "guid", "seed": "seed", "id": "ins-1", "image_id": "img-2"},
+			responses: map[string][]mockResponse{
+				"cvm.ResetInstance":     {mockOk(`{"RequestId":"req-reinstall"}`)},
+				"cvm.DescribeInstances": {operation("req-reinstall", "FAILED")},
+			},
+			wantErr:   "of request[req-reinstall] failed",
+			wantCalls: []string{"cvm.ResetInstance", "cvm.DescribeInstances"},
+		},
+		{
+			name:      "not found",
+			action:    "reinstall",
+			input:     map[string]interface{}{"guid": "guid", "seed": "seed", "id": "ins-gone", "image_id": "img-2"},
+			responses: map[string][]mockResponse{"cvm.ResetInstance": {mockError("InvalidInstanceId.NotFound")}},
+			wantErr:   "InvalidInstanceId.NotFound",
+			wantCalls: []string{"cvm.ResetInstance"},
+		},
+		{
+			name:    "missing image",
+			action:  "reinstall",
+			input:   map[string]interface{}{"guid": "guid", "id": "ins-1"},
+			wantErr: "input image_id is empty",
+		},
+		{
+			name:   "success",
+			action: "reset-password",
+			input:  map[string]interface{}{"guid": "guid", "seed": "seed", "id": "ins-1"},
+			responses: map[string][]mockResponse{
+				"cvm.DescribeInstances": {
+					instance("S2.MEDIUM4", "img-1", "STOPPED"), operation("req-old", "SUCCESS"), operation("req-reset", "OPERATING"),
+					operation("req-reset", "SUCCESS"), instance("S2.MEDIUM4", "img-1", "STOPPED"),
+				},
+				"cvm.ResetInstancesPassword": {mockOk(`{"RequestId":"req-reset"}`)},
+			},
+			wantCalls: []string{
+				"cvm.DescribeInstances", "cvm.ResetInstancesPassword", "cvm.DescribeInstances", "cvm.DescribeInstances",
+				"cvm.DescribeInstances", "cvm.DescribeInstances",
+			},
+			wantOutput: map[string]string{"id": "ins-1", "instance_state": "STOPPED"},
+		},
+		{
+			name:   "running",
+			action: "reset-password",
+			input:  map[string]interface{}{"guid": "guid", "seed": "seed", "id": "ins-1"},
+			responses: map[string][]mockResponse{
+				"cvm.DescribeInstances": {
+					instance("S2.MEDIUM4", "img-1", "RUNNING"), instance("S2.MEDIUM4", "img-1", "STOPPED"),
+					operation("req-reset", "SUCCESS"), instance("S2.MEDIUM4", "img-1", "RUNNING"),
+				},
+				"cvm.StopInstances":          {mockOk(`{}`)},
+				"cvm.ResetInstancesPassword": {mockOk(`{"RequestId":"req-reset"}`)},
+				"cvm.StartInstances":         {mockOk(`{}`)},
+			},
+			wantCalls: []string{
+				"cvm.DescribeInstances", "cvm.StopInstances", "cvm.DescribeInstances", "cvm.ResetInstancesPassword",
+				"cvm.DescribeInstances", "cvm.StartInstances", "cvm.DescribeInstances", "cvm.DescribeInstances",
+			},
+			wantOutput: map[string]string{"id": "ins-1", "instance_state": "RUNNING"},
+		},
+		{
+			name:   "reset failed",
+			action: "reset-password",
+			input:  map[string]interface{}{"guid": "guid", "seed": "seed", "id": "ins-1"},
+			responses: map[string][]mockResponse{
+				"cvm.DescribeInstances":      {instance("S2.MEDIUM4", "img-1", "STOPPED"), operation("req-reset", "FAILED")},
+				"cvm.ResetInstancesPassword": {mockOk(`{"RequestId":"req-reset"}`)},
+			},
+			wantErr:   "vm[ins-1] ResetInstancesPassword of request[req-reset] failed",
+			wantCalls: []string{"cvm.DescribeInstances", "cvm.ResetInstancesPassword", "cvm.DescribeInstances"},
+		},
+		{
+			name:      "not found",
+			action:    "reset-password",
+			input:     map[string]interface{}{"guid": "guid", "seed": "seed", "id": "ins-gone"},
+			responses: map[string][]mockResponse{"cvm.DescribeInstances": {notFound}},
+			wantErr:   VM_NOT_FOUND_ERROR.Error(),
+			wantCalls: []string{"cvm.DescribeInstances"},
+		},
+		{
+			name:   "error",
+			action: "reset-password",
+			input:  map[string]interface{}{"guid": "guid", "seed": "seed", "id": "ins-1"},
+			responses: map[string][]mockResponse{
+				"cvm.DescribeInstances":      {instance("S2.MEDIUM4", "img-1", "STOPPED")},
+				"cvm.ResetInstancesPassword": {mockError("InvalidPassword")},
+			},
+			wantErr:   "InvalidPassword",
+			wantCalls: []string{"cvm.DescribeInstances", "cvm.ResetInstancesPassword"},
+		},
+		{
+			name:       "resize running",
+			action:     "resize",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "ins-1", "instance_type": "S2.LARGE8"},
+			responses:  map[string][]mockResponse{"cvm.DescribeInstances": {instance("S2.MEDIUM4", "img-1", "RUNNING")}},
+			wantCalls:  []string{"cvm.DescribeInstances"},
+			wantOutput: map[string]string{"operation": "modify", "detail": "change instance type from [S2.MEDIUM4] to [S2.LARGE8], the instance is stopped before and started again after"},
+		},
+		{
+			name:       "already resized",
+			action:     "resize",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "ins-1", "instance_type": "S2.LARGE8"},
+			responses:  map[string][]mockResponse{"cvm.DescribeInstances": {instance("S2.LARGE8", "img-1", "STOPPED")}},
+			wantCalls:  []string{"cvm.DescribeInstances"},
+			wantOutput: map[string]string{"operation": "none", "exist": "true"},
+		},
+		{
+			name:       "resize pending",
+			action:     "resize",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "ins-1", "instance_type": "S2.LARGE8"},
+			responses:  map[string][]mockResponse{"cvm.DescribeInstances": {instance("S2.MEDIUM4", "img-1", "PENDING")}},
+			wantCalls:  []string{"cvm.DescribeInstances"},
+			wantOutput: map[string]string{"operation": "none", "conflicts": "[instance is in state[PENDING], should be RUNNING or STOPPED]"},
+		},
+		{
+			name:       "resize not found",
+			action:     "resize",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "ins-gone", "instance_type": "S2.LARGE8"},
+			responses:  map[string][]mockResponse{"cvm.DescribeInstances": {notFound}},
+			wantCalls:  []string{"cvm.DescribeInstances"},
+			wantOutput: map[string]string{"operation": "none", "exist": "false", "conflicts": "[instance[ins-gone] not found]"},
+		},
+		{
+			name:   "reinstall",
+			action: "reinstall",
+			dryRun: true,
+			input:  map[string]interface{}{"guid": "guid", "seed": "seed", "id": "ins-1", "image_id": "centos"},
+			responses: map[string][]mockResponse{
+				"cvm.DescribeInstances": {instance("S2.MEDIUM4", "img-1", "RUNNING")},
+				"cvm.DescribeImages":    {mockOk(`{"TotalCount":1,"ImageSet":[{"ImageId":"img-centos","ImageName":"centos"}]}`)},
+			},
+			wantCalls:  []string{"cvm.DescribeInstances", "cvm.DescribeImages"},
+			wantOutput: map[string]string{"operation": "modify", "detail": "reinstall instance from image[img-1] to [img-centos] with a new password, the system disk is wiped and the instance is started"},
+		},
+		{
+			name:       "reset password stopped",
+			action:     "reset-password",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "seed": "seed", "id": "ins-1"},
+			responses:  map[string][]mockResponse{"cvm.DescribeInstances": {instance("S2.MEDIUM4", "img-1", "STOPPED")}},
+			wantCalls:  []string{"cvm.DescribeInstances"},
+			wantOutput: map[string]string{"operation": "modify", "detail": "reset the password of instance to a new one"},
+		},
+	})
+}
+
+func TestVmDescribeActions(t *testing.T) {
+	instance := `{"InstanceId":"%s","InstanceName":"vm","InstanceType":"S2.MEDIUM4","InstanceState":"RUNNING","CPU":2,"Memory":4,` +
+		`"Placement":{"Zone":"ap-guangzhou-3"},"VirtualPrivateCloud":{"VpcId":"vpc-1","SubnetId":"subnet-1"},"ImageId":"img-1",` +
+		`"SystemDisk":{"DiskType":"CLOUD_PREMIUM","DiskSize":50},"DataDisks":[{"DiskType":"CLOUD_SSD","DiskSize":100},{"DiskType":"CLOUD_PREMIUM","DiskSize":200}],` +
+		`"PrivateIpAddresses":["10.0.1.6"],"PublicIpAddresses":["203.0.113.6"],"SecurityGroupIds":["sg-1","sg-2"],"InstanceChargeType":"PREPAID",` +
+		`"ExpiredTime":"2020-01-01T00:00:00Z","CreatedTime":"2019-01-01T00:00:00Z","Tags":[{"Key":"env","Value":"prod"},{"Key":"team","Value":"ops"}]}`
+
+	runMockActionCases(t, "vm", []mockActionCase{
+		{
+			name:   "pages",
+			action: "describe",
+			input:  map[string]interface{}{"guid": "guid", "tags": "env=prod"},
+			responses: map[string][]mockResponse{
+				"cvm.DescribeInstances": {
+					mockOk(`{"TotalCount":3,"InstanceSet":[` + fmt.Sprintf(instance, "ins-1") + `,` + fmt.Sprintf(instance, "ins-2") + `]}`),
+					mockOk(`{"TotalCount":3,"InstanceSet":[` + fmt.Sprintf(instance, "ins-3") + `]}`),
+				},
+			},
+			wantCalls: []string{"cvm.DescribeInstances", "cvm.DescribeInstances"},
+			wantOutput: map[string]string{
+				"guid": "guid", "id": "ins-1", "instance_name": "vm", "instance_type": "S2.MEDIUM4", "zone": "ap-guangzhou-3",
+				"vpc_id": "vpc-1", "subnet_id": "subnet-1", "image_id": "img-1", "system_disk_type": "CLOUD_PREMIUM",
+				"system_disk_size": "50", "data_disks": "CLOUD_SSD:100,CLOUD_PREMIUM:200", "security_group_ids": "sg-1,sg-2",
+				"instance_private_ip": "10.0.1.6", "instance_public_ip": "203.0.113.6", "instance_charge_type": "PREPAID",
+				"expired_time": "2020-01-01T00:00:00Z", "created_time": "2019-01-01T00:00:00Z", "tags": "env=prod;team=ops",
+			},
+		},
+		{
+			name:      "not found",
+			action:    "describe",
+			input:     map[string]interface{}{"guid": "guid", "id": "ins-gone"},
+			responses: map[string][]mockResponse{"cvm.DescribeInstances": {mockOk(`{"TotalCount":0,"InstanceSet":[]}`)}},
+			wantCalls: []string{"cvm.DescribeInstances"},
+		},
+		{
+			name:      "error",
+			action:    "describe",
+			input:     map[string]interface{}{"guid": "guid", "instance_private_ip": "10.0.1.6"},
+			responses: map[string][]mockResponse{"cvm.DescribeInstances": {mockError("InvalidFilter")}},
+			wantErr:   "InvalidFilter",
+			wantCalls: []string{"cvm.DescribeInstances"},
+		},
+		{
+			name:    "id with filters",
+			action:  "describe",
+			input:   map[string]interface{}{"guid": "guid", "id": "ins-1", "instance_name": "vm"},
+			wantErr: "input id can't be used together with",
+		},
+	})
+}
+
+func TestVmPrepaidActions(t *testing.T) {
+	instance := func(chargeType string, renewFlag string) string {
+		return fmt.Sprintf(`{"TotalCount":1,"InstanceSet":[{"InstanceId":"ins-1","InstanceState":"RUNNING","InstanceChargeType":"%s",`+
+			`"RenewFlag":"%s","ExpiredTime":"2020-03-01T00:00:00Z"}]}`, chargeType, renewFlag)
+	}
+
+	runMockActionCases(t, "vm", []mockActionCase{
+		{
+			name:   "success",
+			action: "renew",
+			input:  map[string]interface{}{"guid": "guid", "id": "ins-1", "instance_charge_period": 3},
+			responses: map[string][]mockResponse{
+				"cvm.DescribeInstances": {mockOk(instance("PREPAID", "NOTIFY_AND_AUTO_RENEW"))},
+				"cvm.RenewInstances":    {mockOk(`{"RequestId":"renew-request"}`)},
+			},
+			wantCalls: []string{"cvm.DescribeInstances", "cvm.RenewInstances", "cvm.DescribeInstances"},
+			wantOutput: map[string]string{
+				"id": "ins-1", "request_id": "renew-request", "instance_charge_type": "PREPAID",
+				"expired_time": "2020-03-01T00:00:00Z", "renew_flag": "NOTIFY_AND_AUTO_RENEW",
+			},
+		},
+		{
+			name:      "postpaid",
+			action:    "renew",
+			input:     map[string]interface{}{"guid": "guid", "id": "ins-1", "instance_charge_period": 3},
+			responses: map[string][]mockResponse{"cvm.DescribeInstances": {mockOk(instance("POSTPAID_BY_HOUR", ""))}},
+			wantErr:   "only PREPAID vms can be renewed",
+			wantCalls: []string{"cvm.DescribeInstances"},
+		},
+		{
+			name:       "renew",
+			action:     "renew",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "ins-1", "instance_charge_period": 3},
+			responses:  map[string][]mockResponse{"cvm.DescribeInstances": {mockOk(instance("PREPAID", "NOTIFY_AND_AUTO_RENEW"))}},
+			wantCalls:  []string{"cvm.DescribeInstances"},
+			wantOutput: map[string]string{"operation": "modify", "detail": "renew instance expiring at 2020-03-01T00:00:00Z for 3 months"},
+		},
+		{
+			name:       "renew postpaid",
+			action:     "renew",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "ins-1", "instance_charge_period": 3},
+			responses:  map[string][]mockResponse{"cvm.DescribeInstances": {mockOk(instance("POSTPAID_BY_HOUR", ""))}},
+			wantCalls:  []string{"cvm.DescribeInstances"},
+			wantOutput: map[string]string{"operation": "none", "conflicts": "[instance is charged by POSTPAID_BY_HOUR, only PREPAID vms can be renewed]"},
+		},
+		{
+			name:    "invalid period",
+			action:  "renew",
+			input:   map[string]interface{}{"guid": "guid", "id": "ins-1"},
+			wantErr: "invalid instance_charge_period(0)",
+		},
+		{
+			name:       "already prepaid",
+			action:     "convert-to-prepaid",
+			input:      map[string]interface{}{"guid": "guid", "id": "ins-1", "instance_charge_period": 1},
+			responses:  map[string][]mockResponse{"cvm.DescribeInstances": {mockOk(instance("PREPAID", "NOTIFY_AND_AUTO_RENEW"))}},
+			wantCalls:  []string{"cvm.DescribeInstances"},
+			wantOutput: map[string]string{"id": "ins-1", "instance_charge_type": "PREPAID"},
+		},
+		{
+			name:   "success",
+			action: "convert-to-prepaid",
+			input:  map[string]interface{}{"guid": "guid", "id": "ins-1", "instance_charge_period": 1, "renew_flag": "NOTIFY_AND_MANUAL_RENEW"},
+			responses: map[string][]mockResponse{
+				"cvm.DescribeInstances": {
+					mockOk(instance("POSTPAID_BY_HOUR", "")),
+					mockOk(instance("PREPAID", "NOTIFY_AND_MANUAL_RENEW")),
+				},
+				"cvm.ModifyInstancesChargeType": {mockOk(`{}`)},
+			},
+			wantCalls:  []string{"cvm.DescribeInstances", "cvm.ModifyInstancesChargeType", "cvm.DescribeInstances", "cvm.DescribeInstances"},
+			wantOutput: map[string]string{"id": "ins-1", "instance_charge_type": "PREPAID", "renew_flag": "NOTIFY_AND_MANUAL_RENEW"},
+			slow:       true,
+		},
+		{
+			name:       "convert",
+			action:     "convert-to-prepaid",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "ins-1", "instance_charge_period": 1},
+			responses:  map[string][]mockResponse{"cvm.DescribeInstances": {mockOk(instance("POSTPAID_BY_HOUR", ""))}},
+			wantCalls:  []string{"cvm.DescribeInstances"},
+			wantOutput: map[string]string{"operation": "modify", "detail": "convert instance from POSTPAID_BY_HOUR to PREPAID for 1 months with renew flag[NOTIFY_AND_AUTO_RENEW]"},
+		},
+		{
+			name:       "already prepaid",
+			action:     "convert-to-prepaid",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "ins-1", "instance_charge_period": 1},
+			responses:  map[string][]mockResponse{"cvm.DescribeInstances": {mockOk(instance("PREPAID", "NOTIFY_AND_AUTO_RENEW"))}},
+			wantCalls:  []string{"cvm.DescribeInstances"},
+			wantOutput: map[string]string{"operation": "none", "detail": "instance already PREPAID"},
+		},
+		{
+			name:    "invalid renew flag",
+			action:  "convert-to-prepaid",
+			input:   map[string]interface{}{"guid": "guid", "id": "ins-1", "instance_charge_period": 1, "renew_flag": "ALWAYS"},
+			wantErr: "ALWAYS is not valid value",
+		},
+		{
+			name:   "success",
+			action: "modify-renew-flag",
+			input:  map[string]interface{}{"guid": "guid", "id": "ins-1", "renew_flag": "NOTIFY_AND_MANUAL_RENEW"},
+			responses: map[string][]mockResponse{
+				"cvm.DescribeInstances": {
+					mockOk(instance("PREPAID", "NOTIFY_AND_AUTO_RENEW")),
+					mockOk(instance("PREPAID", "NOTIFY_AND_MANUAL_RENEW")),
+				},
+				"cvm.ModifyInstancesRenewFlag": {mockOk(`{}`)},
+			},
+			wantCalls:  []string{"cvm.DescribeInstances", "cvm.ModifyInstancesRenewFlag", "cvm.DescribeInstances"},
+			wantOutput: map[string]string{"id": "ins-1", "renew_flag": "NOTIFY_AND_MANUAL_RENEW"},
+		},
+		{
+			name:       "unchanged",
+			action:     "modify-renew-flag",
+			input:      map[string]interface{}{"guid": "guid", "id": "ins-1", "renew_flag": "NOTIFY_AND_AUTO_RENEW"},
+			responses:  map[string][]mockResponse{"cvm.DescribeInstances": {mockOk(instance("PREPAID", "NOTIFY_AND_AUTO_RENEW"))}},
+			wantCalls:  []string{"cvm.DescribeInstances"},
+			wantOutput: map[string]string{"id": "ins-1", "renew_flag": "NOTIFY_AND_AUTO_RENEW"},
+		},
+		{
+			name:       "modify renew flag",
+			action:     "modify-renew-flag",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "ins-1", "renew_flag": "NOTIFY_AND_MANUAL_RENEW"},
+			responses:  map[string][]mockResponse{"cvm.DescribeInstances": {mockOk(instance("PREPAID", "NOTIFY_AND_AUTO_RENEW"))}},
+			wantCalls:  []string{"cvm.DescribeInstances"},
+			wantOutput: map[string]string{"operation": "modify", "detail": "change renew flag from [NOTIFY_AND_AUTO_RENEW] to [NOTIFY_AND_MANUAL_RENEW]"},
+		},
+		{
+			name:       "renew flag unchanged",
+			action:     "modify-renew-flag",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "ins-1", "renew_flag": "NOTIFY_AND_AUTO_RENEW"},
+			responses:  map[string][]mockResponse{"cvm.DescribeInstances": {mockOk(instance("PREPAID", "NOTIFY_AND_AUTO_RENEW"))}},
+			wantCalls:  []string{"cvm.DescribeInstances"},
+			wantOutput: map[string]string{"operation": "none"},
+		},
+		{
+			name:    "empty renew flag",
+			action:  "modify-renew-flag",
+			input:   map[string]interface{}{"guid": "guid", "id": "ins-1"},
+			wantErr: "input renew_flag is empty",
+		},
+		{
+			name:   "expired",
+			action: "expiry-report",
+			input:  map[string]interface{}{"guid": "guid", "expire_within_days": 30},
+			responses: map[string][]mockResponse{
+				"cvm.DescribeInstances": {mockOk(instance("PREPAID", "NOTIFY_AND_MANUAL_RENEW"))},
+			},
+			wantCalls:  []string{"cvm.DescribeInstances"},
+			wantOutput: map[string]string{"guid": "guid", "id": "ins-1", "expired_time": "2020-03-01T00:00:00Z", "renew_flag": "NOTIFY_AND_MANUAL_RENEW"},
+		},
+		{
+			name:    "invalid days",
+			action:  "expiry-report",
+			input:   map[string]interface{}{"guid": "guid"},
+			wantErr: "invalid expire_within_days(0)",
+		},
+	})
+}
+
+func TestImageActions(t *testing.T) {
+	image := `{"TotalCount":1,"ImageSet":[{"ImageId":"img-1","ImageName":"golden","ImageState":"%s","ImageType":"PRIVATE_IMAGE","ImageSize":50}]}`
+	noImage := `{"TotalCount":0,"ImageSet":[]}`
+
+	runMockActionCases(t, "image", []mockActionCase{
+		{
+			name:   "success",
+			action: "create",
+			input:  map[string]interface{}{"guid": "guid", "image_name": "golden", "instance_id": "ins-1", "force_poweroff": "true"},
+			responses: map[string][]mockResponse{
+				"cvm.DescribeImages": {mockOk(noImage), mockOk(fmt.Sprintf(image, "CREATING")), mockOk(fmt.Sprintf(image, "NORMAL"))},
+				"cvm.CreateImage":    {mockOk(`{"RequestId":"create-request"}`)},
+			},
+			wantCalls:  []string{"cvm.DescribeImages", "cvm.CreateImage", "cvm.DescribeImages", "cvm.DescribeImages"},
+			wantOutput: map[string]string{"guid": "guid", "id": "img-1", "request_id": "create-request", "image_state": "NORMAL", "image_size": "50"},
+			slow:       true,
+		},
+		{
+			name:   "failed",
+			action: "create",
+			input:  map[string]interface{}{"guid": "guid", "image_name": "golden", "instance_id": "ins-1"},
+			responses: map[string][]mockResponse{
+				"cvm.DescribeImages": {mockOk(fmt.Sprintf(image, "CREATEFAILED"))},
+			},
+			wantErr:   "image[img-1] is in state[CREATEFAILED]",
+			wantCalls: []string{"cvm.DescribeImages", "cvm.DescribeImages"},
+			slow:      true,
+		},
+		{
+			name:    "empty name",
+			action:  "create",
+			input:   map[string]interface{}{"guid": "guid", "instance_id": "ins-1"},
+			wantErr: "input image_name is empty",
+		},
+		{
+			name:    "empty regions",
+			action:  "copy",
+			input:   map[string]interface{}{"guid": "guid", "id": "img-1"},
+			wantErr: "input destination_regions is empty",
+		},
+		{
+			name:      "source not found",
+			action:    "copy",
+			input:     map[string]interface{}{"guid": "guid", "id": "img-gone", "destination_regions": "ap-shanghai"},
+			responses: map[string][]mockResponse{"cvm.DescribeImages": {mockOk(noImage)}},
+			wantErr:   "image[img-gone] not found",
+			wantCalls: []string{"cvm.DescribeImages"},
+		},
+		{
+			name:       "success",
+			action:     "share",
+			input:      map[string]interface{}{"guid": "guid", "id": "img-1", "account_ids": "100001,100002"},
+			responses:  map[string][]mockResponse{"cvm.ModifyImageSharePermission": {mockOk(`{}`)}},
+			wantCalls:  []string{"cvm.ModifyImageSharePermission"},
+			wantOutput: map[string]string{"guid": "guid", "id": "img-1"},
+		},
+		{
+			name:    "invalid permission",
+			action:  "share",
+			input:   map[string]interface{}{"guid": "guid", "id": "img-1", "account_ids": "100001", "permission": "READ"},
+			wantErr: "READ is not valid value",
+		},
+		{
+			name:   "success",
+			action: "terminate",
+			input:  map[string]interface{}{"guid": "guid", "id": "img-1"},
+			responses: map[string][]mockResponse{
+				"cvm.DescribeImages": {mockOk(fmt.Sprintf(image, "NORMAL"))},
+				"cvm.DeleteImages":   {mockOk(`{"RequestId":"delete-request"}`)},
+			},
+			wantCalls:  []string{"cvm.DescribeImages", "cvm.DeleteImages"},
+			wantOutput: map[string]string{"id": "img-1", "request_id": "delete-request"},
+		},
+		{
+			name:       "already deleted",
+			action:     "terminate",
+			input:      map[string]interface{}{"guid": "guid", "id": "img-gone"},
+			responses:  map[string][]mockResponse{"cvm.DescribeImages": {mockOk(noImage)}},
+			wantCalls:  []string{"cvm.DescribeImages"},
+			wantOutput: map[string]string{"id": "img-gone"},
+		},
+		{
+			name:       "success",
+			action:     "describe",
+			input:      map[string]interface{}{"guid": "guid", "image_name": "golden"},
+			responses:  map[string][]mockResponse{"cvm.DescribeImages": {mockOk(fmt.Sprintf(image, "NORMAL"))}},
+			wantCalls:  []string{"cvm.DescribeImages"},
+			wantOutput: map[string]string{"guid": "guid", "id": "img-1", "image_name": "golden", "image_type": "PRIVATE_IMAGE"},
+		},
+		{
+			name:    "id with filters",
+			action:  "describe",
+			input:   map[string]interface{}{"guid": "guid", "id": "img-1", "image_name": "golden"},
+			wantErr: "input id can't be used together with image_name or image_type",
+		},
+		{
+			name:   "create",
+			action: "create",
+			dryRun: true,
+			input:  map[string]interface{}{"guid": "guid", "image_name": "golden", "instance_id": "ins-1", "data_disk_ids": "disk-1"},
+			responses: map[string][]mockResponse{
+				"cvm.DescribeImages":    {mockOk(noImage)},
+				"cvm.DescribeInstances": {mockOk(`{"TotalCount":1,"InstanceSet":[{"InstanceId":"ins-1","InstanceState":"RUNNING"}]}`)},
+			},
+			wantCalls:  []string{"cvm.DescribeImages", "cvm.DescribeInstances"},
+			wantOutput: map[string]string{"operation": "create", "detail": "create image[golden] from instance[ins-1] with data disks[disk-1]"},
+		},
+		{
+			name:   "create without instance",
+			action: "create",
+			dryRun: true,
+			input:  map[string]interface{}{"guid": "guid", "image_name": "golden", "instance_id": "ins-gone"},
+			responses: map[string][]mockResponse{
+				"cvm.DescribeImages":    {mockOk(noImage)},
+				"cvm.DescribeInstances": {mockOk(`{"TotalCount":0,"InstanceSet":[]}`)},
+			},
+			wantCalls:  []string{"cvm.DescribeImages", "cvm.DescribeInstances"},
+			wantOutput: map[string]string{"operation": "create", "conflicts": "[instance[ins-gone] not found]"},
+		},
+		{
+			name:       "create existed",
+			action:     "create",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "image_name": "golden", "instance_id": "ins-1"},
+			responses:  map[string][]mockResponse{"cvm.DescribeImages": {mockOk(fmt.Sprintf(image, "NORMAL"))}},
+			wantCalls:  []string{"cvm.DescribeImages"},
+			wantOutput: map[string]string{"id": "img-1", "operation": "none", "exist": "true"},
+		},
+		{
+			name:   "copy",
+			action: "copy",
+			dryRun: true,
+			input:  map[string]interface{}{"guid": "guid", "id": "img-1", "destination_regions": "ap-shanghai,ap-beijing"},
+			responses: map[string][]mockResponse{
+				"cvm.DescribeImages": {mockOk(fmt.Sprintf(image, "NORMAL")), mockOk(fmt.Sprintf(image, "NORMAL")), mockOk(noImage)},
+			},
+			wantCalls:  []string{"cvm.DescribeImages", "cvm.DescribeImages", "cvm.DescribeImages"},
+			wantOutput: map[string]string{"operation": "create", "detail": "copy image named golden to regions[ap-beijing]"},
+		},
+		{
+			name:       "copy missing image",
+			action:     "copy",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "img-gone", "destination_regions": "ap-shanghai"},
+			responses:  map[string][]mockResponse{"cvm.DescribeImages": {mockOk(noImage)}},
+			wantCalls:  []string{"cvm.DescribeImages"},
+			wantOutput: map[string]string{"operation": "none", "conflicts": "[image[img-gone] not found]"},
+		},
+		{
+			name:   "share",
+			action: "share",
+			dryRun: true,
+			input:  map[string]interface{}{"guid": "guid", "id": "img-1", "account_ids": "100001,100002"},
+			responses: map[string][]mockResponse{
+				"cvm.DescribeImages":               {mockOk(fmt.Sprintf(image, "NORMAL"))},
+				"cvm.DescribeImageSharePermission": {mockOk(`{"SharePermissionSet":[{"AccountId":"100001"}]}`)},
+			},
+			wantCalls:  []string{"cvm.DescribeImages", "cvm.DescribeImageSharePermission"},
+			wantOutput: map[string]string{"operation": "modify", "detail": "share image with accounts[100002]"},
+		},
+		{
+			name:   "cancel not shared",
+			action: "share",
+			dryRun: true,
+			input:  map[string]interface{}{"guid": "guid", "id": "img-1", "account_ids": "100002", "permission": "CANCEL"},
+			responses: map[string][]mockResponse{
+				"cvm.DescribeImages":               {mockOk(fmt.Sprintf(image, "NORMAL"))},
+				"cvm.DescribeImageSharePermission": {mockOk(`{"SharePermissionSet":[{"AccountId":"100001"}]}`)},
+			},
+			wantCalls:  []string{"cvm.DescribeImages", "cvm.DescribeImageSharePermission"},
+			wantOutput: map[string]string{"operation": "none", "detail": "image not shared with accounts[100002]"},
+		},
+		{
+			name:       "terminate",
+			action:     "terminate",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "img-1"},
+			responses:  map[string][]mockResponse{"cvm.DescribeImages": {mockOk(fmt.Sprintf(image, "NORMAL"))}},
+			wantCalls:  []string{"cvm.DescribeImages"},
+			wantOutput: map[string]string{"operation": "delete", "detail": "delete image named golden in state[NORMAL]"},
+		},
+		{
+			name:       "terminate deleted",
+			action:     "terminate",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "img-gone"},
+			responses:  map[string][]mockResponse{"cvm.DescribeImages": {mockOk(noImage)}},
+			wantCalls:  []string{"cvm.DescribeImages"},
+			wantOutput: map[string]string{"operation": "none", "exist": "false"},
+		},
+	})
+}
+
+func TestPlacementGroupActions(t *testing.T) {
+	group := `{"TotalCount":1,"DisasterRecoverGroupSet":[{"DisasterRecoverGroupId":"ps-1","Name":"ha","Type":"HOST","CvmQuotaTotal":50,"CurrentNum":%d,"InstanceIds":%s}]}`
+	noGroup := `{"TotalCount":0,"DisasterRecoverGroupSet":[]}`
+
+	runMockActionCases(t, "placement-group", []mockActionCase{
+		{
+			name:   "success",
+			action: "create",
+			input:  map[string]interface{}{"guid": "guid", "name": "ha", "type": "HOST"},
+			responses: map[string][]mockResponse{
+				"cvm.CreateDisasterRecoverGroup": {mockOk(`{"DisasterRecoverGroupId":"ps-1","Name":"ha","Type":"HOST","CvmQuotaTotal":50,"CurrentNum":0,"RequestId":"create-request"}`)},
+			},
+			wantCalls:  []string{"cvm.CreateDisasterRecoverGroup"},
+			wantOutput: map[string]string{"guid": "guid", "id": "ps-1", "request_id": "create-request", "type": "HOST", "cvm_quota_total": "50", "current_num": "0"},
+		},
+		{
+			name:       "exists",
+			action:     "create",
+			input:      map[string]interface{}{"guid": "guid", "id": "ps-1", "name": "ha", "type": "HOST"},
+			responses:  map[string][]mockResponse{"cvm.DescribeDisasterRecoverGroups": {mockOk(fmt.Sprintf(group, 2, `["ins-1","ins-2"]`))}},
+			wantCalls:  []string{"cvm.DescribeDisasterRecoverGroups"},
+			wantOutput: map[string]string{"id": "ps-1", "current_num": "2", "instance_ids": "ins-1,ins-2"},
+		},
+		{
+			name:    "invalid type",
+			action:  "create",
+			input:   map[string]interface{}{"guid": "guid", "name": "ha", "type": "ZONE"},
+			wantErr: "ZONE is not valid value",
+		},
+		{
+			name:       "create",
+			action:     "create",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "name": "ha", "type": "HOST"},
+			wantOutput: map[string]string{"operation": "create", "detail": "create placement group[ha] of type[HOST]"},
+		},
+		{
+			name:       "create exists",
+			action:     "create",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "ps-1", "name": "ha", "type": "HOST"},
+			responses:  map[string][]mockResponse{"cvm.DescribeDisasterRecoverGroups": {mockOk(fmt.Sprintf(group, 0, `[]`))}},
+			wantCalls:  []string{"cvm.DescribeDisasterRecoverGroups"},
+			wantOutput: map[string]string{"id": "ps-1", "operation": "none", "exist": "true"},
+		},
+		{
+			name:       "terminate",
+			action:     "terminate",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "ps-1"},
+			responses:  map[string][]mockResponse{"cvm.DescribeDisasterRecoverGroups": {mockOk(fmt.Sprintf(group, 0, `[]`))}},
+			wantCalls:  []string{"cvm.DescribeDisasterRecoverGroups"},
+			wantOutput: map[string]string{"operation": "delete", "detail": "delete placement group[ha] of type[HOST]"},
+		},
+		{
+			name:       "terminate with vms",
+			action:     "terminate",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "ps-1"},
+			responses:  map[string][]mockResponse{"cvm.DescribeDisasterRecoverGroups": {mockOk(fmt.Sprintf(group, 1, `["ins-1"]`))}},
+			wantCalls:  []string{"cvm.DescribeDisasterRecoverGroups"},
+			wantOutput: map[string]string{"operation": "delete", "conflicts": "[placement group still has 1 vms]"},
+		},
+		{
+			name:   "success",
+			action: "terminate",
+			input:  map[string]interface{}{"guid": "guid", "id": "ps-1"},
+			responses: map[string][]mockResponse{
+				"cvm.DescribeDisasterRecoverGroups": {mockOk(fmt.Sprintf(group, 0, `[]`))},
+				"cvm.DeleteDisasterRecoverGroups":   {mockOk(`{"RequestId":"delete-request"}`)},
+			},
+			wantCalls:  []string{"cvm.DescribeDisasterRecoverGroups", "cvm.DeleteDisasterRecoverGroups"},
+			wantOutput: map[string]string{"id": "ps-1", "request_id": "delete-request"},
+		},
+		{
+			name:      "with vms",
+			action:    "terminate",
+			input:     map[string]interface{}{"guid": "guid", "id": "ps-1"},
+			responses: map[string][]mockResponse{"cvm.DescribeDisasterRecoverGroups": {mockOk(fmt.Sprintf(group, 1, `["ins-1"]`))}},
+			wantErr:   "placement group[ps-1] still has 1 vms",
+			wantCalls: []string{"cvm.DescribeDisasterRecoverGroups"},
+		},
+		{
+			name:       "already deleted",
+			action:     "terminate",
+			input:      map[string]interface{}{"guid": "guid", "id": "ps-gone"},
+			responses:  map[string][]mockResponse{"cvm.DescribeDisasterRecoverGroups": {mockOk(noGroup)}},
+			wantCalls:  []string{"cvm.DescribeDisasterRecoverGroups"},
+			wantOutput: map[string]string{"id": "ps-gone"},
+		},
+		{
+			name:       "success",
+			action:     "describe",
+			input:      map[string]interface{}{"guid": "guid", "name": "ha"},
+			responses:  map[string][]mockResponse{"cvm.DescribeDisasterRecoverGroups": {mockOk(fmt.Sprintf(group, 1, `["ins-1"]`))}},
+			wantCalls:  []string{"cvm.DescribeDisasterRecoverGroups"},
+			wantOutput: map[string]string{"guid": "guid", "id": "ps-1", "name": "ha", "instance_ids": "ins-1"},
+		},
+		{
+			name:    "id with name",
+			action:  "describe",
+			input:   map[string]interface{}{"guid": "guid", "id": "ps-1", "name": "ha"},
+			wantErr: "input id can't be used together with name",
+		},
+	})
+}
+
+func TestSnapshotActions(t *testing.T) {
+	snapshot := `{"TotalCount":1,"SnapshotSet":[{"SnapshotId":"snap-1","SnapshotName":"backup","DiskId":"disk-1","DiskSize":50,"SnapshotState":"%s"}]}`
+	noSnapshot := `{"TotalCount":0,"SnapshotSet":[]}`
+	policy := `{"TotalCount":1,"AutoSnapshotPolicySet":[{"AutoSnapshotPolicyId":"asp-1","AutoSnapshotPolicyName":"daily","IsActivated":true,"RetentionDays":7,"Policy":[{"DayOfWeek":[1,3,5],"Hour":[2]}],"DiskIdSet":%s}]}`
+	rollbackDisk := func(rollbacking bool, percent int) mockResponse {
+		return mockOk(fmt.Sprintf(`{"TotalCount":1,"DiskSet":[{"DiskId":"disk-1","Rollbacking":%v,"RollbackPercent":%d}]}`, rollbacking, percent))
+	}
+	//a rollback never seen running is waited for until the start timeout passes
+	quickRollbackCalls := []string{"cbs.DescribeSnapshots", "cbs.ApplySnapshot"}
+	for i := 0; i <= SNAPSHOT_ROLLBACK_START_TIMEOUT/WAIT_POLL_SECONDS; i++ {
+		quickRollbackCalls = append(quickRollbackCalls, "cbs.DescribeDisks")
+	}
+	quickRollbackCalls = append(quickRollbackCalls, "cbs.DescribeSnapshots")
+	noPolicy := `{"TotalCount":0,"AutoSnapshotPolicySet":[]}`
+
+	runMockActionCases(t, "snapshot", []mockActionCase{
+		{
+			name:   "success",
+			action: "create",
+			input:  map[string]interface{}{"guid": "guid", "disk_id": "disk-1", "name": "backup"},
+			responses: map[string][]mockResponse{
+				"cbs.CreateSnapshot":    {mockOk(`{"SnapshotId":"snap-1","RequestId":"create-request"}`)},
+				"cbs.DescribeSnapshots": {mockOk(fmt.Sprintf(snapshot, "CREATING")), mockOk(fmt.Sprintf(snapshot, "NORMAL"))},
+			},
+			wantCalls:  []string{"cbs.CreateSnapshot", "cbs.DescribeSnapshots", "cbs.DescribeSnapshots"},
+			wantOutput: map[string]string{"id": "snap-1", "request_id": "create-request", "disk_id": "disk-1", "disk_size": "50", "state": "NORMAL"},
+			slow:       true,
+		},
+		{
+			name:      "error",
+			action:    "create",
+			input:     map[string]interface{}{"guid": "guid", "disk_id": "disk-gone"},
+			responses: map[string][]mockResponse{"cbs.CreateSnapshot": {mockError("InvalidDiskId.NotFound")}},
+			wantErr:   "InvalidDiskId.NotFound",
+			wantCalls: []string{"cbs.CreateSnapshot"},
+		},
+		{
+			name:    "empty disk id",
+			action:  "create",
+			input:   map[string]interface{}{"guid": "guid", "name": "backup"},
+			wantErr: "input disk_id is empty",
+		},
+		{
+			name:   "success",
+			action: "terminate",
+			input:  map[string]interface{}{"guid": "guid", "id": "snap-1"},
+			responses: map[string][]mockResponse{
+				"cbs.DescribeSnapshots": {mockOk(fmt.Sprintf(snapshot, "NORMAL")), mockOk(noSnapshot)},
+				"cbs.DeleteSnapshots":   {mockOk(`{"RequestId":"delete-request"}`)},
+			},
+			wantCalls:  []string{"cbs.DescribeSnapshots", "cbs.DeleteSnapshots", "cbs.DescribeSnapshots"},
+			wantOutput: map[string]string{"id": "snap-1", "request_id": "delete-request"},
+			slow:       true,
+		},
+		{
+			name:       "already deleted",
+			action:     "terminate",
+			input:      map[string]interface{}{"guid": "guid", "id": "snap-gone"},
+			responses:  map[string][]mockResponse{"cbs.DescribeSnapshots": {mockOk(noSnapshot)}},
+			wantCalls:  []string{"cbs.DescribeSnapshots"},
+			wantOutput: map[string]string{"id": "snap-gone"},
+		},
+		{
+			name:       "success",
+			action:     "describe",
+			input:      map[string]interface{}{"guid": "guid", "disk_id": "disk-1"},
+			responses:  map[string][]mockResponse{"cbs.DescribeSnapshots": {mockOk(fmt.Sprintf(snapshot, "NORMAL"))}},
+			wantCalls:  []string{"cbs.DescribeSnapshots"},
+			wantOutput: map[string]string{"guid": "guid", "id": "snap-1", "name": "backup", "disk_id": "disk-1"},
+		},
+		{
+			name:    "id with disk id",
+			action:  "describe",
+			input:   map[string]interface{}{"guid": "guid", "id": "snap-1", "disk_id": "disk-1"},
+			wantErr: "input id can't be used together with disk_id or name",
+		},
+		{
+			name:   "success",
+			action: "rollback",
+			input:  map[string]interface{}{"guid": "guid", "id": "snap-1", "disk_id": "disk-1"},
+			responses: map[string][]mockResponse{
+				"cbs.DescribeSnapshots": {mockOk(fmt.Sprintf(snapshot, "NORMAL"))},
+				"cbs.ApplySnapshot":     {mockOk(`{"RequestId":"apply-request"}`)},
+				"cbs.DescribeDisks":     {rollbackDisk(true, 40), rollbackDisk(false, 100)},
+			},
+			wantCalls:  []string{"cbs.DescribeSnapshots", "cbs.ApplySnapshot", "cbs.DescribeDisks", "cbs.DescribeDisks", "cbs.DescribeSnapshots"},
+			wantOutput: map[string]string{"id": "snap-1", "disk_id": "disk-1", "request_id": "apply-request"},
+			slow:       true,
+		},
+		{
+			name:   "started late",
+			action: "rollback",
+			input:  map[string]interface{}{"guid": "guid", "id": "snap-1", "disk_id": "disk-1"},
+			responses: map[string][]mockResponse{
+				"cbs.DescribeSnapshots": {mockOk(fmt.Sprintf(snapshot, "NORMAL"))},
+				"cbs.ApplySnapshot":     {mockOk(`{"RequestId":"apply-request"}`)},
+				"cbs.DescribeDisks":     {rollbackDisk(false, 100), rollbackDisk(true, 0), rollbackDisk(false, 100)},
+			},
+			wantCalls:  []string{"cbs.DescribeSnapshots", "cbs.ApplySnapshot", "cbs.DescribeDisks", "cbs.DescribeDisks", "cbs.DescribeDisks", "cbs.DescribeSnapshots"},
+			wantOutput: map[string]string{"id": "snap-1", "request_id": "apply-request"},
+			slow:       true,
+		},
+		{
+			name:   "quick rollback",
+			action: "rollback",
+			input:  map[string]interface{}{"guid": "guid", "id": "snap-1", "disk_id": "disk-1"},
+			responses: map[string][]mockResponse{
+				"cbs.DescribeSnapshots": {mockOk(fmt.Sprintf(snapshot, "NORMAL"))},
+				"cbs.ApplySnapshot":     {mockOk(`{"RequestId":"apply-request"}`)},
+				"cbs.DescribeDisks":     {rollbackDisk(false, 100)},
+			},
+			wantCalls:  quickRollbackCalls,
+			wantOutput: map[string]string{"id": "snap-1", "request_id": "apply-request"},
+			slow:       true,
+		},
+		{
+			name:      "snapshot creating",
+			action:    "rollback",
+			input:     map[string]interface{}{"guid": "guid", "id": "snap-1", "disk_id": "disk-1"},
+			responses: map[string][]mockResponse{"cbs.DescribeSnapshots": {mockOk(fmt.Sprintf(snapshot, "CREATING"))}},
+			wantErr:   "snapshot[snap-1] is CREATING",
+			wantCalls: []string{"cbs.DescribeSnapshots"},
+		},
+		{
+			name:      "other disk",
+			action:    "rollback",
+			input:     map[string]interface{}{"guid": "guid", "id": "snap-1", "disk_id": "disk-2"},
+			responses: map[string][]mockResponse{"cbs.DescribeSnapshots": {mockOk(fmt.Sprintf(snapshot, "NORMAL"))}},
+			wantErr:   "snapshot[snap-1] is taken from disk[disk-1], can't roll back disk[disk-2]",
+			wantCalls: []string{"cbs.DescribeSnapshots"},
+		},
+		{
+			name:    "empty disk id",
+			action:  "rollback",
+			input:   map[string]interface{}{"guid": "guid", "id": "snap-1"},
+			wantErr: "input disk_id is empty",
+		},
+		{
+			name:   "success",
+			action: "create-policy",
+			input:  map[string]interface{}{"guid": "guid", "name": "daily", "day_of_week": "1,3,5", "hour": "2", "retention_days": 7},
+			responses: map[string][]mockResponse{
+				"cbs.CreateAutoSnapshotPolicy":     {mockOk(`{"AutoSnapshotPolicyId":"asp-1","RequestId":"create-request"}`)},
+				"cbs.DescribeAutoSnapshotPolicies": {mockOk(fmt.Sprintf(policy, `[]`))},
+			},
+			wantCalls:  []string{"cbs.CreateAutoSnapshotPolicy", "cbs.DescribeAutoSnapshotPolicies"},
+			wantOutput: map[string]string{"id": "asp-1", "request_id": "create-request", "day_of_week": "1,3,5", "hour": "2", "retention_days": "7", "is_activated": "true"},
+		},
+		{
+			name:    "invalid day of week",
+			action:  "create-policy",
+			input:   map[string]interface{}{"guid": "guid", "name": "daily", "day_of_week": "1,7", "hour": "2"},
+			wantErr: "invalid day_of_week(1,7)",
+		},
+		{
+			name:    "empty hour",
+			action:  "create-policy",
+			input:   map[string]interface{}{"guid": "guid", "name": "daily", "day_of_week": "1"},
+			wantErr: "input hour is empty",
+		},
+		{
+			name:   "success",
+			action: "bind-policy",
+			input:  map[string]interface{}{"guid": "guid", "id": "asp-1", "disk_ids": "disk-1,disk-2"},
+			responses: map[string][]mockResponse{
+				"cbs.DescribeAutoSnapshotPolicies": {mockOk(fmt.Sprintf(policy, `["disk-1"]`)), mockOk(fmt.Sprintf(policy, `["disk-1","disk-2"]`))},
+				"cbs.BindAutoSnapshotPolicy":       {mockOk(`{"RequestId":"bind-request"}`)},
+			},
+			wantCalls:  []string{"cbs.DescribeAutoSnapshotPolicies", "cbs.BindAutoSnapshotPolicy", "cbs.DescribeAutoSnapshotPolicies"},
+			wantOutput: map[string]string{"id": "asp-1", "request_id": "bind-request", "disk_ids": "disk-1,disk-2"},
+		},
+		{
+			name:       "already unbound",
+			action:     "unbind-policy",
+			input:      map[string]interface{}{"guid": "guid", "id": "asp-1", "disk_ids": "disk-2"},
+			responses:  map[string][]mockResponse{"cbs.DescribeAutoSnapshotPolicies": {mockOk(fmt.Sprintf(policy, `["disk-1"]`))}},
+			wantCalls:  []string{"cbs.DescribeAutoSnapshotPolicies"},
+			wantOutput: map[string]string{"id": "asp-1", "disk_ids": "disk-1"},
+		},
+		{
+			name:      "policy not found",
+			action:    "unbind-policy",
+			input:     map[string]interface{}{"guid": "guid", "id": "asp-gone", "disk_ids": "disk-1"},
+			responses: map[string][]mockResponse{"cbs.DescribeAutoSnapshotPolicies": {mockOk(noPolicy)}},
+			wantErr:   "snapshot policy[asp-gone] not found",
+			wantCalls: []string{"cbs.DescribeAutoSnapshotPolicies"},
+		},
+		{
+			name:    "empty disk ids",
+			action:  "bind-policy",
+			input:   map[string]interface{}{"guid": "guid", "id": "asp-1"},
+			wantErr: "input disk_ids is empty",
+		},
+		{
+			name:   "success",
+			action: "terminate-policy",
+			input:  map[string]interface{}{"guid": "guid", "id": "asp-1"},
+			responses: map[string][]mockResponse{
+				"cbs.DescribeAutoSnapshotPolicies": {mockOk(fmt.Sprintf(policy, `[]`))},
+				"cbs.DeleteAutoSnapshotPolicies":   {mockOk(`{"RequestId":"delete-request"}`)},
+			},
+			wantCalls:  []string{"cbs.DescribeAutoSnapshotPolicies", "cbs.DeleteAutoSnapshotPolicies"},
+			wantOutput: map[string]string{"id": "asp-1", "request_id": "delete-request"},
+		},
+		{
+			name:   "new",
+			action: "create",
+			dryRun: true,
+			input:  map[string]interface{}{"guid": "guid", "disk_id": "disk-1", "name": "backup"},
+			responses: map[string][]mockResponse{
+				"cbs.DescribeDisks": {mockOk(`{"TotalCount":1,"DiskSet":[{"DiskId":"disk-1"}]}`)},
+			},
+			wantCalls:  []string{"cbs.DescribeDisks"},
+			wantOutput: map[string]string{"exist": "false", "operation": "create", "detail": "create snapshot named backup of disk[disk-1]"},
+		},
+		{
+			name:       "existing",
+			action:     "create",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "snap-1", "disk_id": "disk-1", "name": "backup"},
+			responses:  map[string][]mockResponse{"cbs.DescribeSnapshots": {mockOk(fmt.Sprintf(snapshot, "NORMAL"))}},
+			wantCalls:  []string{"cbs.DescribeSnapshots"},
+			wantOutput: map[string]string{"id": "snap-1", "exist": "true", "operation": "none"},
+		},
+		{
+			name:       "disk not found",
+			action:     "create",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "disk_id": "disk-gone"},
+			responses:  map[string][]mockResponse{"cbs.DescribeDisks": {mockOk(`{"TotalCount":0,"DiskSet":[]}`)}},
+			wantCalls:  []string{"cbs.DescribeDisks"},
+			wantOutput: map[string]string{"operation": "create", "conflicts": "[storage[disk-gone] not found]"},
+		},
+		{
+			name:       "existing",
+			action:     "terminate",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "snap-1"},
+			responses:  map[string][]mockResponse{"cbs.DescribeSnapshots": {mockOk(fmt.Sprintf(snapshot, "NORMAL"))}},
+			wantCalls:  []string{"cbs.DescribeSnapshots"},
+			wantOutput: map[string]string{"exist": "true", "operation": "delete", "detail": "delete snapshot named backup of disk[disk-1]"},
+		},
+		{
+			name:       "already deleted",
+			action:     "terminate",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "snap-gone"},
+			responses:  map[string][]mockResponse{"cbs.DescribeSnapshots": {mockOk(noSnapshot)}},
+			wantCalls:  []string{"cbs.DescribeSnapshots"},
+			wantOutput: map[string]string{"exist": "false", "operation": "none"},
+		},
+		{
+			name:       "normal",
+			action:     "rollback",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "snap-1", "disk_id": "disk-1"},
+			responses:  map[string][]mockResponse{"cbs.DescribeSnapshots": {mockOk(fmt.Sprintf(snapshot, "NORMAL"))}},
+			wantCalls:  []string{"cbs.DescribeSnapshots"},
+			wantOutput: map[string]string{"operation": "modify", "detail": "roll back disk[disk-1] to the snapshot, the data written after it is lost"},
+		},
+		{
+			name:       "creating of another disk",
+			action:     "rollback",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "snap-1", "disk_id": "disk-2"},
+			responses:  map[string][]mockResponse{"cbs.DescribeSnapshots": {mockOk(fmt.Sprintf(snapshot, "CREATING"))}},
+			wantCalls:  []string{"cbs.DescribeSnapshots"},
+			wantOutput: map[string]string{"operation": "modify", "conflicts": "[snapshot is CREATING snapshot is taken from disk[disk-1]]"},
+		},
+		{
+			name:       "not found",
+			action:     "rollback",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "snap-gone", "disk_id": "disk-1"},
+			responses:  map[string][]mockResponse{"cbs.DescribeSnapshots": {mockOk(noSnapshot)}},
+			wantCalls:  []string{"cbs.DescribeSnapshots"},
+			wantOutput: map[string]string{"exist": "false", "operation": "none", "conflicts": "[snapshot[snap-gone] not found]"},
+		},
+		{
+			name:       "new",
+			action:     "create-policy",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "name": "daily", "day_of_week": "1,3,5", "hour": "2"},
+			wantOutput: map[string]string{"exist": "false", "operation": "create", "detail": "create snapshot policy named daily taking snapshots at hours[2] of days of week[1,3,5]"},
+		},
+		{
+			name:       "existing",
+			action:     "create-policy",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "asp-1", "name": "daily", "day_of_week": "1", "hour": "2"},
+			responses:  map[string][]mockResponse{"cbs.DescribeAutoSnapshotPolicies": {mockOk(fmt.Sprintf(policy, `[]`))}},
+			wantCalls:  []string{"cbs.DescribeAutoSnapshotPolicies"},
+			wantOutput: map[string]string{"exist": "true", "operation": "none"},
+		},
+		{
+			name:       "existing",
+			action:     "terminate-policy",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "asp-1"},
+			responses:  map[string][]mockResponse{"cbs.DescribeAutoSnapshotPolicies": {mockOk(fmt.Sprintf(policy, `["disk-1","disk-2"]`))}},
+			wantCalls:  []string{"cbs.DescribeAutoSnapshotPolicies"},
+			wantOutput: map[string]string{"exist": "true", "operation": "delete", "detail": "delete snapshot policy bound to 2 disks"},
+		},
+		{
+			name:       "partly bound",
+			action:     "bind-policy",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "asp-1", "disk_ids": "disk-1,disk-2"},
+			responses:  map[string][]mockResponse{"cbs.DescribeAutoSnapshotPolicies": {mockOk(fmt.Sprintf(policy, `["disk-1"]`))}},
+			wantCalls:  []string{"cbs.DescribeAutoSnapshotPolicies"},
+			wantOutput: map[string]string{"operation": "modify", "detail": "bind disks[disk-2] to snapshot policy"},
+		},
+		{
+			name:       "bound",
+			action:     "unbind-policy",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "asp-1", "disk_ids": "disk-1,disk-2"},
+			responses:  map[string][]mockResponse{"cbs.DescribeAutoSnapshotPolicies": {mockOk(fmt.Sprintf(policy, `["disk-1"]`))}},
+			wantCalls:  []string{"cbs.DescribeAutoSnapshotPolicies"},
+			wantOutput: map[string]string{"operation": "modify", "detail": "unbind disks[disk-1] from snapshot policy"},
+		},
+		{
+			name:       "already unbound",
+			action:     "unbind-policy",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "asp-1", "disk_ids": "disk-2"},
+			responses:  map[string][]mockResponse{"cbs.DescribeAutoSnapshotPolicies": {mockOk(fmt.Sprintf(policy, `["disk-1"]`))}},
+			wantCalls:  []string{"cbs.DescribeAutoSnapshotPolicies"},
+			wantOutput: map[string]string{"operation": "none", "detail": "disks[disk-2] are already changed"},
+		},
+		{
+			name:       "policy not found",
+			action:     "bind-policy",
+			dryRun:     true,
+			input:      map[string]interface{}{"guid": "guid", "id": "asp-gone", "disk_ids": "disk-1"},
+			responses:  map[string][]mockResponse{"cbs.DescribeAutoSnapshotPolicies": {mockOk(noPolicy)}},
+			wantCalls:  []string{"cbs.DescribeAutoSnapshotPolicies"},
+			wantOutput: map[string]string{"exist": "false", "conflicts": "[snapshot policy[asp-gone] not found]"},
+		},
+	})
+}
+
+func TestIpamActions(t *testing.T) {
+	runMockActionCases(t, "ipam", []mockActionCase{
+		{
+			name:   "overlaps",
+			action: "check",
+			input:  map[string]interface{}{"guid": "guid", "regions": "ap-guangzhou,ap-shanghai"},
+			responses: map[string][]mockResponse{
+				"vpc.DescribeVpcs": {
+					mockOk(`{"TotalCount":2,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16"},{"VpcId":"vpc-2","CidrBlock":"172.16.0.0/16"}]}`),
+					mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-3","CidrBlock":"192.168.0.0/16","AssistantCidrSet":[{"CidrBlock":"10.0.8.0/21"}]}]}`),
+				},
+			},
+			wantCalls: []string{"vpc.DescribeVpcs", "vpc.DescribeVpcs"},
+			wantOutput: map[string]string{"region": "ap-guangzhou", "vpc_id": "vpc-1", "cidr_block": "10.0.0.0/16",
+				"peer_region": "ap-shanghai", "peer_vpc_id": "vpc-3", "peer_cidr_block": "10.0.8.0/21", "overlap_cidr_block": "10.0.8.0/21"},
+		},
+		{
+			name:      "error",
+			action:    "check",
+			input:     map[string]interface{}{"guid": "guid"},
+			responses: map[string][]mockResponse{"vpc.DescribeVpcs": {mockError("UnauthorizedOperation")}},
+			wantErr:   "UnauthorizedOperation",
+			wantCalls: []string{"vpc.DescribeVpcs"},
+		},
+	})
+}
@@ -33,7 +33,11 @@ func createClbClient(providerParams string) (client *clb.Client, err error) {
 	clientProfile := profile.NewClientProfile()
 	clientProfile.HttpProfile.Endpoint = "clb.tencentcloudapi.com"
 
-	return clb.NewClient(credential, paramsMap["Region"], clientProfile)
+	client, err = clb.NewClient(credential, paramsMap["Region"], clientProfile)
+	if err == nil {
+		plugins.WithQcloudHttpTransport(&client.Client)
+	}
+	return
 }
 
 func (resourceType *ClbResourceType) IsSupportEgressPolicy() bool {
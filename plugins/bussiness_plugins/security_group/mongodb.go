@@ -28,7 +28,11 @@ func createMongodbClient(providerParams string) (client *mongodb.Client, err err
 	clientProfile := profile.NewClientProfile()
 	clientProfile.HttpProfile.Endpoint = "mongodb.tencentcloudapi.com"
 
-	return mongodb.NewClient(credential, paramsMap["Region"], clientProfile)
+	client, err = mongodb.NewClient(credential, paramsMap["Region"], clientProfile)
+	if err == nil {
+		plugins.WithQcloudHttpTransport(&client.Client)
+	}
+	return
 }
 
 func (resourceType *MongodbResourceType) QueryInstancesById(providerParams string, instanceIds []string) (map[string]ResourceInstance, error) {
@@ -33,7 +33,11 @@ func createRedisClient(providerParams string) (client *redis.Client, err error)
 	clientProfile := profile.NewClientProfile()
 	clientProfile.HttpProfile.Endpoint = "redis.tencentcloudapi.com"
 
-	return redis.NewClient(credential, paramsMap["Region"], clientProfile)
+	client, err = redis.NewClient(credential, paramsMap["Region"], clientProfile)
+	if err == nil {
+		plugins.WithQcloudHttpTransport(&client.Client)
+	}
+	return
 }
 
 func redisQueryInstances(providerParams string, searchKeys []string, searchKeyType string) (map[string]ResourceInstance, error) {
@@ -434,6 +434,158 @@ func (action *ApplySecurityPolicyAction) Do(input interface{}) (interface{}, err
 	return result, err
 }
 
+type ApplyPlan struct {
+	Ip                     string   `json:"ip"`
+	Type                   string   `json:"type"`
+	Id                     string   `json:"id"`
+	Region                 string   `json:"region"`
+	PoliciesTotal          int      `json:"policies_total"`
+	FreePoliciesTotal      int      `json:"free_policies_total"`
+	ExistSecurityGroups    []string `json:"exist_security_groups,omitempty"`
+	NewSecurityGroupsTotal int      `json:"new_security_groups_total"`
+	NewSecurityGroups      []string `json:"new_security_groups,omitempty"`
+	ErrorMsg               string   `json:"err_msg,omitempty"`
+	UndoReason             string   `json:"undo_reason,omitempty"`
+}
+
+type ApplySecurityPoliciesDryRunResult struct {
+	TimeTaken     string      `json:"time_taken"`
+	IngressPlans  []ApplyPlan `json:"ingress"`
+	EgressPlans   []ApplyPlan `json:"egress"`
+	FailedTotal   int         `json:"failed_total"`
+	NewGroupTotal int         `json:"new_security_groups_total"`
+}
+
+//DryRun tells how many policies each instance will add and how many security groups will be created,
+//nothing will be created in qcloud
+func (action *ApplySecurityPolicyAction) DryRun(input interface{}) (interface{}, error) {
+	req, _ := input.(ApplySecurityPoliciesRequest)
+	result := ApplySecurityPoliciesDryRunResult{}
+	start := time.Now()
+
+	result.IngressPlans = planPolicies(req.IngressPolicies, INGRESS_RULE)
+	result.EgressPlans = planPolicies(req.EgressPolicies, EGRESS_RULE)
+	for _, plans := range [][]ApplyPlan{result.IngressPlans, result.EgressPlans} {
+		for _, plan := range plans {
+			if plan.ErrorMsg != "" {
+				result.FailedTotal++
+			}
+			result.NewGroupTotal += plan.NewSecurityGroupsTotal
+		}
+	}
+
+	result.TimeTaken = fmt.Sprintf("%v", time.Since(start))
+	return result, nil
+}
+
+func planPolicies(policies []SecurityPolicy, direction string) []ApplyPlan {
+	plans := []ApplyPlan{}
+	instanceMap := make(map[string][]*SecurityPolicy)
+	instanceIps := []string{}
+
+	for i, _ := range policies {
+		policyType := policies[i].Type
+		if strings.HasPrefix(policyType, "clb-cvm") {
+			policyType = "cvm"
+		}
+
+		if policies[i].SupportSecurityGroupApi == true {
+			key := policies[i].Ip
+			if _, found := instanceMap[key]; !found {
+				instanceIps = append(instanceIps, key)
+			}
+			instanceMap[key] = append(instanceMap[key], &policies[i])
+		} else {
+			plans = append(plans, ApplyPlan{
+				Ip:            policies[i].Ip,
+				Type:          policyType,
+				Id:            policies[i].Id,
+				Region:        policies[i].Region,
+				PoliciesTotal: 1,
+				UndoReason:    fmt.Sprintf("instanceType(%s) do not support security group api", policyType),
+			})
+		}
+	}
+
+	for _, ip := range instanceIps {
+		policies := instanceMap[ip]
+		plan := planInstancePolicies(policies, direction)
+		plans = append(plans, plan)
+	}
+	return plans
+}
+
+func planInstancePolicies(policies []*SecurityPolicy, direction string) ApplyPlan {
+	plan := ApplyPlan{
+		Ip:            policies[0].Ip,
+		Type:          policies[0].Type,
+		Id:            policies[0].Id,
+		Region:        policies[0].Region,
+		PoliciesTotal: len(policies),
+	}
+	if strings.HasPrefix(plan.Type, "clb-cvm") {
+		plan.Type = "cvm"
+	}
+
+	resType, err := getResouceTypeByName(plan.Type)
+	if err != nil {
+		plan.ErrorMsg = err.Error()
+		return plan
+	}
+
+	providerParams, err := getProviderParams(plan.Region)
+	if err != nil {
+		plan.ErrorMsg = err.Error()
+		return plan
+	}
+
+	instances, err := resType.QueryInstancesById(providerParams, []string{plan.Id})
+	if err != nil {
+		plan.ErrorMsg = err.Error()
+		return plan
+	}
+	if len(instances) == 0 {
+		plan.ErrorMsg = fmt.Sprintf("can't found instanceId(%s)", plan.Id)
+		return plan
+	}
+
+	existSecurityGroups, err := instances[plan.Id].QuerySecurityGroups(providerParams)
+	if err != nil {
+		plan.ErrorMsg = err.Error()
+		return plan
+	}
+
+	securityGroupsNames, err := getSecurityGroupNames(providerParams, existSecurityGroups)
+	if err != nil {
+		plan.ErrorMsg = err.Error()
+		return plan
+	}
+
+	createdSecurityGroups, autoCreatedStartIndex, err := getAutoCreatedSecurityGroups(plan.Ip, securityGroupsNames, existSecurityGroups)
+	if err != nil {
+		plan.ErrorMsg = err.Error()
+		return plan
+	}
+	plan.ExistSecurityGroups = createdSecurityGroups
+
+	for _, securityGroup := range createdSecurityGroups {
+		freeNum, err := getSecurityGroupFreePolicyNum(providerParams, securityGroup, direction)
+		if err != nil {
+			plan.ErrorMsg = err.Error()
+			return plan
+		}
+		plan.FreePoliciesTotal += freeNum
+	}
+
+	if plan.FreePoliciesTotal < plan.PoliciesTotal {
+		plan.NewSecurityGroupsTotal = (plan.PoliciesTotal - plan.FreePoliciesTotal + MAX_SEUCRITY_RULE_NUM - 1) / MAX_SEUCRITY_RULE_NUM
+		for i := 0; i < plan.NewSecurityGroupsTotal; i++ {
+			plan.NewSecurityGroups = append(plan.NewSecurityGroups, fmt.Sprintf("%s-auto-%d", plan.Ip, autoCreatedStartIndex+i))
+		}
+	}
+	return plan
+}
+
 func fillSecuityPoliciesWithErrMsg(policies []*SecurityPolicy, err error) {
 	for _, policy := range policies {
 		policy.ErrorMsg = err.Error()
@@ -0,0 +1,193 @@
+package plugins
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"sort"
+)
+
+//the most cidrs splitCidr returns, so that a short prefix can't blow up the memory
+const CIDR_MAX_SPLIT_COUNT = 65536
+
+//cidrRange is the addresses from first to last, bits is 32 for ipv4 and 128 for ipv6
+type cidrRange struct {
+	first *big.Int
+	last  *big.Int
+	bits  int
+}
+
+func parseCidrRange(cidr string) (cidrRange, error) {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return cidrRange{}, fmt.Errorf("invalid cidr(%s)", cidr)
+	}
+	ones, bits := ipNet.Mask.Size()
+	first := new(big.Int).SetBytes(ipNet.IP)
+	last := new(big.Int).Add(first, new(big.Int).Lsh(big.NewInt(1), uint(bits-ones)))
+	return cidrRange{first: first, last: last.Sub(last, big.NewInt(1)), bits: bits}, nil
+}
+
+func (r cidrRange) contains(other cidrRange) bool {
+	return r.bits == other.bits && r.first.Cmp(other.first) <= 0 && other.last.Cmp(r.last) <= 0
+}
+
+func (r cidrRange) overlaps(other cidrRange) bool {
+	return r.bits == other.bits && r.first.Cmp(other.last) <= 0 && other.first.Cmp(r.last) <= 0
+}
+
+func formatCidr(first *big.Int, ones int, bits int) string {
+	ip := make(net.IP, bits/8)
+	bytes := first.Bytes()
+	copy(ip[len(ip)-len(bytes):], bytes)
+	return fmt.Sprintf("%s/%d", ip.String(), ones)
+}
+
+//toCidrs returns the fewest cidrs which cover exactly the range, in ascending order
+func (r cidrRange) toCidrs() []string {
+	one := big.NewInt(1)
+	cidrs := []string{}
+	for first := new(big.Int).Set(r.first); first.Cmp(r.last) <= 0; {
+		//the largest block which starts at first and doesn't go beyond last
+		hostBits := 0
+		for ; hostBits < r.bits; hostBits++ {
+			size := new(big.Int).Lsh(one, uint(hostBits+1))
+			if new(big.Int).Mod(first, size).Sign() != 0 {
+				break
+			}
+			if end := new(big.Int).Add(first, size); end.Sub(end, one).Cmp(r.last) > 0 {
+				break
+			}
+		}
+		cidrs = append(cidrs, formatCidr(first, r.bits-hostBits, r.bits))
+		first.Add(first, new(big.Int).Lsh(one, uint(hostBits)))
+	}
+	return cidrs
+}
+
+//isCidrContains is true if the child cidr is inside the parent cidr
+func isCidrContains(parent string, child string) bool {
+	parentRange, err := parseCidrRange(parent)
+	if err != nil {
+		return false
+	}
+	childRange, err := parseCidrRange(child)
+	if err != nil {
+		return false
+	}
+	return parentRange.contains(childRange)
+}
+
+func isCidrOverlap(cidr1 string, cidr2 string) bool {
+	range1, err := parseCidrRange(cidr1)
+	if err != nil {
+		return false
+	}
+	range2, err := parseCidrRange(cidr2)
+	if err != nil {
+		return false
+	}
+	return range1.overlaps(range2)
+}
+
+//parseSortedCidrRanges returns the ranges of the cidrs ordered by ip version and first address
+func parseSortedCidrRanges(cidrs []string) ([]cidrRange, error) {
+	ranges := []cidrRange{}
+	for _, cidr := range cidrs {
+		r, err := parseCidrRange(cidr)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, r)
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].bits != ranges[j].bits {
+			return ranges[i].bits < ranges[j].bits
+		}
+		return ranges[i].first.Cmp(ranges[j].first) < 0
+	})
+	return ranges, nil
+}
+
+//subtractCidrs returns the fewest cidrs which cover the cidr without the excluded ones, in ascending order
+func subtractCidrs(cidr string, excluded []string) ([]string, error) {
+	r, err := parseCidrRange(cidr)
+	if err != nil {
+		return nil, err
+	}
+	excludedRanges, err := parseSortedCidrRanges(excluded)
+	if err != nil {
+		return nil, err
+	}
+
+	one := big.NewInt(1)
+	cidrs := []string{}
+	next := new(big.Int).Set(r.first)
+	for _, excludedRange := range excludedRanges {
+		if !r.overlaps(excludedRange) {
+			continue
+		}
+		if excludedRange.first.Cmp(next) > 0 {
+			gap := cidrRange{first: next, last: new(big.Int).Sub(excludedRange.first, one), bits: r.bits}
+			cidrs = append(cidrs, gap.toCidrs()...)
+		}
+		if afterExcluded := new(big.Int).Add(excludedRange.last, one); afterExcluded.Cmp(next) > 0 {
+			next = afterExcluded
+		}
+	}
+	if next.Cmp(r.last) <= 0 {
+		cidrs = append(cidrs, cidrRange{first: next, last: r.last, bits: r.bits}.toCidrs()...)
+	}
+	return cidrs, nil
+}
+
+//splitCidr returns the cidrs of the prefix length which the cidr is made of, in ascending order
+func splitCidr(cidr string, prefixLength int) ([]string, error) {
+	r, err := parseCidrRange(cidr)
+	if err != nil {
+		return nil, err
+	}
+	ones := r.bits - (new(big.Int).Sub(r.last, r.first).BitLen())
+	if prefixLength < ones || prefixLength > r.bits {
+		return nil, fmt.Errorf("can't split cidr(%s) into /%d cidrs", cidr, prefixLength)
+	}
+	if prefixLength-ones > 16 || 1<<uint(prefixLength-ones) > CIDR_MAX_SPLIT_COUNT {
+		return nil, fmt.Errorf("splitting cidr(%s) into /%d cidrs makes more than %d cidrs", cidr, prefixLength, CIDR_MAX_SPLIT_COUNT)
+	}
+
+	size := new(big.Int).Lsh(big.NewInt(1), uint(r.bits-prefixLength))
+	cidrs := []string{}
+	for first := new(big.Int).Set(r.first); first.Cmp(r.last) <= 0; first.Add(first, size) {
+		cidrs = append(cidrs, formatCidr(first, prefixLength, r.bits))
+	}
+	return cidrs, nil
+}
+
+//summarizeCidrs merges the overlapping and adjacent cidrs into the fewest cidrs, ipv4 ones first
+func summarizeCidrs(cidrs []string) ([]string, error) {
+	ranges, err := parseSortedCidrRanges(cidrs)
+	if err != nil {
+		return nil, err
+	}
+
+	one := big.NewInt(1)
+	merged := []cidrRange{}
+	for _, r := range ranges {
+		if len(merged) > 0 {
+			last := &merged[len(merged)-1]
+			if last.bits == r.bits && r.first.Cmp(new(big.Int).Add(last.last, one)) <= 0 {
+				if r.last.Cmp(last.last) > 0 {
+					last.last = r.last
+				}
+				continue
+			}
+		}
+		merged = append(merged, cidrRange{first: r.first, last: new(big.Int).Set(r.last), bits: r.bits})
+	}
+
+	summarized := []string{}
+	for _, r := range merged {
+		summarized = append(summarized, r.toCidrs()...)
+	}
+	return summarized, nil
+}
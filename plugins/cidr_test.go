@@ -0,0 +1,89 @@
+package plugins
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestIsCidrContains(t *testing.T) {
+	cases := []struct {
+		parent   string
+		child    string
+		contains bool
+	}{
+		{"10.0.0.0/16", "10.0.1.0/24", true},
+		{"10.0.0.0/16", "10.0.0.0/16", true},
+		{"10.0.1.0/24", "10.0.0.0/16", false},
+		{"10.0.0.0/16", "10.1.0.0/24", false},
+		{"2402:4e00:1000:100::/56", "2402:4e00:1000:101::/64", true},
+		{"0.0.0.0/0", "::/64", false},
+		{"invalid", "10.0.1.0/24", false},
+	}
+	for _, c := range cases {
+		if contains := isCidrContains(c.parent, c.child); contains != c.contains {
+			t.Errorf("isCidrContains(%s,%s)=%v,want %v", c.parent, c.child, contains, c.contains)
+		}
+	}
+}
+
+func TestSubtractCidrs(t *testing.T) {
+	cases := []struct {
+		cidr     string
+		excluded []string
+		want     string
+	}{
+		{"10.0.0.0/24", nil, "[10.0.0.0/24]"},
+		{"10.0.0.0/24", []string{"10.0.0.0/24"}, "[]"},
+		{"10.0.0.0/24", []string{"10.0.0.0/8"}, "[]"},
+		{"10.0.0.0/24", []string{"10.0.0.0/26"}, "[10.0.0.64/26 10.0.0.128/25]"},
+		{"10.0.0.0/24", []string{"10.0.0.128/25", "10.0.0.16/28"}, "[10.0.0.0/28 10.0.0.32/27 10.0.0.64/26]"},
+		{"10.0.0.0/24", []string{"10.0.0.64/26", "10.0.0.64/27", "192.168.0.0/16"}, "[10.0.0.0/26 10.0.0.128/25]"},
+		{"2402:4e00:1000:100::/56", []string{"2402:4e00:1000:100::/57"}, "[2402:4e00:1000:180::/57]"},
+	}
+	for _, c := range cases {
+		got, err := subtractCidrs(c.cidr, c.excluded)
+		if err != nil || fmt.Sprint(got) != c.want {
+			t.Errorf("subtractCidrs(%s,%v)=%v,%v,want %s", c.cidr, c.excluded, got, err, c.want)
+		}
+	}
+
+	if _, err := subtractCidrs("10.0.0.0/24", []string{"10.0.0.0"}); err == nil || !strings.Contains(err.Error(), "invalid cidr(10.0.0.0)") {
+		t.Errorf("subtract invalid cidr err=%v", err)
+	}
+}
+
+func TestSplitCidr(t *testing.T) {
+	got, err := splitCidr("10.0.0.0/24", 26)
+	if err != nil || fmt.Sprint(got) != "[10.0.0.0/26 10.0.0.64/26 10.0.0.128/26 10.0.0.192/26]" {
+		t.Errorf("splitCidr(10.0.0.0/24,26)=%v,%v", got, err)
+	}
+	if got, err = splitCidr("10.0.0.0/24", 24); err != nil || fmt.Sprint(got) != "[10.0.0.0/24]" {
+		t.Errorf("splitCidr(10.0.0.0/24,24)=%v,%v", got, err)
+	}
+	if _, err = splitCidr("10.0.0.0/24", 16); err == nil {
+		t.Errorf("split into a shorter prefix should fail")
+	}
+	if _, err = splitCidr("10.0.0.0/8", 28); err == nil || !strings.Contains(err.Error(), "makes more than") {
+		t.Errorf("split into too many cidrs err=%v", err)
+	}
+}
+
+func TestSummarizeCidrs(t *testing.T) {
+	cases := []struct {
+		cidrs []string
+		want  string
+	}{
+		{[]string{"10.0.0.0/25", "10.0.0.128/25"}, "[10.0.0.0/24]"},
+		{[]string{"10.0.1.0/24", "10.0.0.0/16", "10.0.2.0/24"}, "[10.0.0.0/16]"},
+		{[]string{"10.0.1.0/24", "10.0.2.0/24"}, "[10.0.1.0/24 10.0.2.0/24]"},
+		{[]string{"10.0.1.0/24", "10.0.2.0/24", "10.0.3.0/24"}, "[10.0.1.0/24 10.0.2.0/23]"},
+		{[]string{"2402:4e00::/64", "10.0.0.0/24", "2402:4e00:0:1::/64"}, "[10.0.0.0/24 2402:4e00::/63]"},
+	}
+	for _, c := range cases {
+		got, err := summarizeCidrs(c.cidrs)
+		if err != nil || fmt.Sprint(got) != c.want {
+			t.Errorf("summarizeCidrs(%v)=%v,%v,want %s", c.cidrs, got, err, c.want)
+		}
+	}
+}
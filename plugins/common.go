@@ -5,14 +5,33 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
 	"reflect"
 	"strings"
+	"time"
+
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
 )
 
 const (
 	CHARGE_TYPE_PREPAID = "PREPAID"
+
+	//WAIT_POLL_SECONDS is how many seconds of a wait timeout each poll counts for
+	WAIT_POLL_SECONDS = 5
 )
 
+//waitPollInterval is the sleep between two polls of the wait loops, tests shorten it
+var waitPollInterval = WAIT_POLL_SECONDS * time.Second
+
+//QcloudHttpTransport is used by all the qcloud sdk clients when it is set, tests point it to a fake qcloud
+var QcloudHttpTransport http.RoundTripper
+
+func WithQcloudHttpTransport(client *common.Client) {
+	if QcloudHttpTransport != nil {
+		client.WithHttpTransport(QcloudHttpTransport)
+	}
+}
+
 type Filter struct {
 	Name   string
 	Values []string
@@ -44,6 +63,23 @@ func isValidValue(inputValue string, validValues []string) error {
 	return fmt.Errorf("%s is not valid value in(%++v)", inputValue, validValues)
 }
 
+func isStringInList(value string, list []string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+//stringValue returns "" for the fields missing in qcloud responses
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
 func GetRegionFromProviderParams(providerParams string) (string, error) {
 	paramMap, err := GetMapFromProviderParams(providerParams)
 	if err != nil {
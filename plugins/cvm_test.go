@@ -7,7 +7,6 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
-	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
 )
 
 const ENV_SECRET_ID = "SECRET_ID"
@@ -16,6 +15,9 @@ const ENV_SECRET_KEY = "SECRET_KEY"
 func TestQueryCvmInstance1(t *testing.T) {
 	secretId := os.Getenv(ENV_SECRET_ID)
 	secretKey := os.Getenv(ENV_SECRET_KEY)
+	if secretId == "" {
+		t.Skip("need qcloud secret in env")
+	}
 	providerParams := "Region=ap-guangzhou;AvailableZone=ap-guanghzou-4;SecretID=" + secretId + ";SecretKey=" + secretKey
 	filter := Filter{
 		Name:   "instanceId",
@@ -25,13 +27,16 @@ func TestQueryCvmInstance1(t *testing.T) {
 	if err != nil {
 		logrus.Errorf("TestQueryCvmInstance1 cvm DescribeInstances meet err=%v", err)
 	}
-	fmt.Printf("TestQueryCvmInstance1 cvm DescribeInstances InstanceSet[0].InstanceId[%v]\n", *response.(*cvm.DescribeInstancesResponse).Response.InstanceSet[0].InstanceId)
-	fmt.Printf("TestQueryCvmInstance1 cvm DescribeInstances InstanceSet[0].PrivateIpAddresses[%v]\n", common.StringValues(response.(*cvm.DescribeInstancesResponse).Response.InstanceSet[0].PrivateIpAddresses))
+	fmt.Printf("TestQueryCvmInstance1 cvm DescribeInstances InstanceSet[0].InstanceId[%v]\n", *response[0].InstanceId)
+	fmt.Printf("TestQueryCvmInstance1 cvm DescribeInstances InstanceSet[0].PrivateIpAddresses[%v]\n", common.StringValues(response[0].PrivateIpAddresses))
 }
 
 func TestQueryCvmInstance2(t *testing.T) {
 	secretId := os.Getenv(ENV_SECRET_ID)
 	secretKey := os.Getenv(ENV_SECRET_KEY)
+	if secretId == "" {
+		t.Skip("need qcloud secret in env")
+	}
 	providerParams := "Region=ap-guangzhou;AvailableZone=ap-guanghzou-4;SecretID=" + secretId + ";SecretKey=" + secretKey
 	filter := Filter{
 		Name:   "privateIpAddress",
@@ -41,13 +46,16 @@ func TestQueryCvmInstance2(t *testing.T) {
 	if err != nil {
 		logrus.Errorf("TestQueryCvmInstance2 cvm DescribeInstances meet err=%v", err)
 	}
-	fmt.Printf("TestQueryCvmInstance2 cvm DescribeInstances InstanceSet[0].InstanceId[%v]\n", *response.(*cvm.DescribeInstancesResponse).Response.InstanceSet[0].InstanceId)
-	fmt.Printf("TestQueryCvmInstance2 cvm DescribeInstances InstanceSet[0].PrivateIpAddresses[%v]\n", common.StringValues(response.(*cvm.DescribeInstancesResponse).Response.InstanceSet[0].PrivateIpAddresses))
+	fmt.Printf("TestQueryCvmInstance2 cvm DescribeInstances InstanceSet[0].InstanceId[%v]\n", *response[0].InstanceId)
+	fmt.Printf("TestQueryCvmInstance2 cvm DescribeInstances InstanceSet[0].PrivateIpAddresses[%v]\n", common.StringValues(response[0].PrivateIpAddresses))
 }
 
 func TestBindCvmInstanceSecurityGroups(t *testing.T) {
 	secretId := os.Getenv(ENV_SECRET_ID)
 	secretKey := os.Getenv(ENV_SECRET_KEY)
+	if secretId == "" {
+		t.Skip("need qcloud secret in env")
+	}
 	providerParams := "Region=ap-guangzhou;AvailableZone=ap-guanghzou-4;SecretID=" + secretId + ";SecretKey=" + secretKey
 	instanceId := "ins-f1mg286i"
 	securityGroups := []string{"sg-3jh0itt3", "sg-61gur97r", "sg-919hc72d", "sg-f9xgfrxj"}
@@ -60,6 +68,9 @@ func TestBindCvmInstanceSecurityGroups(t *testing.T) {
 func TestQueryCvmInstance3(t *testing.T) {
 	secretId := os.Getenv(ENV_SECRET_ID)
 	secretKey := os.Getenv(ENV_SECRET_KEY)
+	if secretId == "" {
+		t.Skip("need qcloud secret in env")
+	}
 	providerParams := "Region=ap-guangzhou;AvailableZone=ap-guanghzou-4;SecretID=" + secretId + ";SecretKey=" + secretKey
 	filter := Filter{
 		Name:   "instanceId",
@@ -69,7 +80,7 @@ func TestQueryCvmInstance3(t *testing.T) {
 	if err != nil {
 		logrus.Errorf("TestQueryCvmInstance3 cvm DescribeInstances meet err=%v", err)
 	}
-	fmt.Printf("TestQueryCvmInstance3 cvm DescribeInstances InstanceSet[0].InstanceId[%v]\n", *response.(*cvm.DescribeInstancesResponse).Response.InstanceSet[0].InstanceId)
-	fmt.Printf("TestQueryCvmInstance3 cvm DescribeInstances InstanceSet[0].PrivateIpAddresses[%v]\n", common.StringValues(response.(*cvm.DescribeInstancesResponse).Response.InstanceSet[0].PrivateIpAddresses))
-	fmt.Printf("TestQueryCvmInstance3 cvm DescribeInstances InstanceSet[0].SecurityGroupIds[%v]\n", common.StringValues(response.(*cvm.DescribeInstancesResponse).Response.InstanceSet[0].SecurityGroupIds))
+	fmt.Printf("TestQueryCvmInstance3 cvm DescribeInstances InstanceSet[0].InstanceId[%v]\n", *response[0].InstanceId)
+	fmt.Printf("TestQueryCvmInstance3 cvm DescribeInstances InstanceSet[0].PrivateIpAddresses[%v]\n", common.StringValues(response[0].PrivateIpAddresses))
+	fmt.Printf("TestQueryCvmInstance3 cvm DescribeInstances InstanceSet[0].SecurityGroupIds[%v]\n", common.StringValues(response[0].SecurityGroupIds))
 }
@@ -0,0 +1,53 @@
+package plugins
+
+const (
+	DRY_RUN_OPERATION_CREATE = "create"
+	DRY_RUN_OPERATION_DELETE = "delete"
+	DRY_RUN_OPERATION_MODIFY = "modify"
+	DRY_RUN_OPERATION_NONE   = "none"
+)
+
+//DryRunAction is implemented by the actions which can preview what Do would change,
+//DryRun may only call read-only describe apis of qcloud
+type DryRunAction interface {
+	DryRun(param interface{}) (interface{}, error)
+}
+
+type DryRunOutputs struct {
+	Outputs []DryRunOutput `json:"outputs,omitempty"`
+}
+
+type DryRunOutput struct {
+	Guid      string   `json:"guid,omitempty"`
+	Id        string   `json:"id,omitempty"`
+	Exist     bool     `json:"exist"`
+	Operation string   `json:"operation,omitempty"`
+	Detail    string   `json:"detail,omitempty"`
+	Conflicts []string `json:"conflicts,omitempty"`
+}
+
+func newDryRunOutput(guid string, id string, exist bool, operation string, detail string) DryRunOutput {
+	return DryRunOutput{
+		Guid:      guid,
+		Id:        id,
+		Exist:     exist,
+		Operation: operation,
+		Detail:    detail,
+	}
+}
+
+//the resource will be created only if it does not exist
+func newCreateDryRunOutput(guid string, id string, exist bool, detail string) DryRunOutput {
+	if exist {
+		return newDryRunOutput(guid, id, true, DRY_RUN_OPERATION_NONE, "resource already exists")
+	}
+	return newDryRunOutput(guid, id, false, DRY_RUN_OPERATION_CREATE, detail)
+}
+
+//the resource will be deleted only if it exists
+func newDeleteDryRunOutput(guid string, id string, exist bool, detail string) DryRunOutput {
+	if !exist {
+		return newDryRunOutput(guid, id, false, DRY_RUN_OPERATION_NONE, "resource not found")
+	}
+	return newDryRunOutput(guid, id, true, DRY_RUN_OPERATION_DELETE, detail)
+}
@@ -0,0 +1,110 @@
+package plugins
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type dryRunTestPlugin struct {
+	actions map[string]Action
+}
+
+func (plugin *dryRunTestPlugin) GetActionByName(actionName string) (Action, error) {
+	action, found := plugin.actions[actionName]
+	if !found {
+		return nil, fmt.Errorf("action[%s] not found", actionName)
+	}
+	return action, nil
+}
+
+type dryRunTestAction struct {
+	doCalled     bool
+	dryRunCalled bool
+}
+
+func (action *dryRunTestAction) ReadParam(param interface{}) (interface{}, error) {
+	return param, nil
+}
+
+func (action *dryRunTestAction) CheckParam(input interface{}) error {
+	return nil
+}
+
+func (action *dryRunTestAction) Do(input interface{}) (interface{}, error) {
+	action.doCalled = true
+	return nil, nil
+}
+
+type dryRunSupportedTestAction struct {
+	dryRunTestAction
+}
+
+func (action *dryRunSupportedTestAction) DryRun(input interface{}) (interface{}, error) {
+	action.dryRunCalled = true
+	return &DryRunOutputs{Outputs: []DryRunOutput{newCreateDryRunOutput("guid", "", false, "create")}}, nil
+}
+
+func TestProcessDryRun(t *testing.T) {
+	supported := &dryRunSupportedTestAction{}
+	unsupported := &dryRunTestAction{}
+	RegisterPlugin("dry-run-test", &dryRunTestPlugin{
+		actions: map[string]Action{
+			"supported":   supported,
+			"unsupported": unsupported,
+		},
+	})
+
+	response, err := Process(&PluginRequest{Version: DEFAULT_API_VERSION, ProviderName: DEFAULT_PROVIDER_NAME, Name: "dry-run-test", Action: "supported", DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run meet err=%v", err)
+	}
+	if !supported.dryRunCalled || supported.doCalled {
+		t.Fatalf("dry run should only call DryRun, dryRunCalled=%v,doCalled=%v", supported.dryRunCalled, supported.doCalled)
+	}
+	outputs := response.Results.(*DryRunOutputs)
+	if outputs.Outputs[0].Operation != DRY_RUN_OPERATION_CREATE {
+		t.Fatalf("operation=%s,want %s", outputs.Outputs[0].Operation, DRY_RUN_OPERATION_CREATE)
+	}
+
+	response, err = Process(&PluginRequest{Version: DEFAULT_API_VERSION, ProviderName: DEFAULT_PROVIDER_NAME, Name: "dry-run-test", Action: "unsupported", DryRun: true})
+	if err == nil || !strings.Contains(err.Error(), "do not support dry run") {
+		t.Fatalf("dry run on unsupported action should fail, err=%v", err)
+	}
+	if unsupported.doCalled || response.ResultCode != "1" {
+		t.Fatalf("unsupported action should not be done, doCalled=%v,resultCode=%s", unsupported.doCalled, response.ResultCode)
+	}
+}
+
+func TestNewDryRunOutput(t *testing.T) {
+	if output := newCreateDryRunOutput("guid", "id", true, "create"); output.Operation != DRY_RUN_OPERATION_NONE || !output.Exist {
+		t.Fatalf("existed resource should not be created, output=%++v", output)
+	}
+	if output := newCreateDryRunOutput("guid", "", false, "create"); output.Operation != DRY_RUN_OPERATION_CREATE {
+		t.Fatalf("resource not existed should be created, output=%++v", output)
+	}
+	if output := newDeleteDryRunOutput("guid", "id", false, "delete"); output.Operation != DRY_RUN_OPERATION_NONE {
+		t.Fatalf("resource not existed should not be deleted, output=%++v", output)
+	}
+	if output := newDeleteDryRunOutput("guid", "id", true, "delete"); output.Operation != DRY_RUN_OPERATION_DELETE {
+		t.Fatalf("existed resource should be deleted, output=%++v", output)
+	}
+}
+
+func TestIsCidrOverlap(t *testing.T) {
+	cases := []struct {
+		cidr1   string
+		cidr2   string
+		overlap bool
+	}{
+		{"10.0.0.0/16", "10.0.1.0/24", true},
+		{"10.0.1.0/24", "10.0.0.0/16", true},
+		{"10.0.1.0/24", "10.0.2.0/24", false},
+		{"invalid", "10.0.2.0/24", false},
+	}
+	for _, c := range cases {
+		if overlap := isCidrOverlap(c.cidr1, c.cidr2); overlap != c.overlap {
+			t.Errorf("isCidrOverlap(%s,%s)=%v,want %v", c.cidr1, c.cidr2, overlap, c.overlap)
+		}
+	}
+}
@@ -8,7 +8,6 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
-	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
 	vpc "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/vpc/v20170312"
 	vpcb "github.com/zqfan/tencentcloud-sdk-go/services/vpc/unversioned"
 )
@@ -24,13 +23,16 @@ func init() {
 	EIPActions["unbindnat"] = new(EIPUnBindNatAction)
 }
 
-func CreateEIPClient(region, secretId, secretKey string) (client *vpc.Client, err error) {
-	credential := common.NewCredential(secretId, secretKey)
-
-	clientProfile := profile.NewClientProfile()
-	clientProfile.HttpProfile.Endpoint = "vpc.tencentcloudapi.com"
+func newVpcClient(region, secretId, secretKey string) (*vpcb.Client, error) {
+	return vpcb.NewClientWithSecretId(
+		secretId,
+		secretKey,
+		region,
+	)
+}
 
-	return vpc.NewClient(credential, region, clientProfile)
+func CreateEIPClient(region, secretId, secretKey string) (VpcAPI, error) {
+	return QcloudClientFactory.NewVpcClient(region, secretId, secretKey)
 }
 
 type EIPInputs struct {
@@ -46,6 +48,7 @@ type EIPInput struct {
 	NatId          string `json:"nat_id,omitempty"`
 	Eip            string `json:"eip,omitempty"`
 	Id             string `json:"id,omitempty"`
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
 }
 
 type EIPOutputs struct {
@@ -97,37 +100,44 @@ func (action *EIPCreateAction) CheckParam(input interface{}) error {
 	return nil
 }
 
-func (action *EIPCreateAction) createEIP(eip *EIPInput) (*EIPOutput, error) {
+func (action *EIPCreateAction) createEIP(eip *EIPInput, idempotencyKey string) (*EIPOutput, error) {
 	paramsMap, _ := GetMapFromProviderParams(eip.ProviderParams)
 	client, err := CreateEIPClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
 	if err != nil {
 		return nil, err
 	}
 
-	var count int64
-	request := vpc.NewAllocateAddressesRequest()
-	if eip.AddressCount == "" {
-		count = 1
-	} else {
-		c, _ := strconv.Atoi(eip.AddressCount)
-		count = int64(c)
-	}
-	request.AddressCount = &count
-	response, err := client.AllocateAddresses(request)
+	//the eips allocated by the last try of the key are only waited for
+	progress, err := loadIdempotencyCreatedIds(idempotencyKey)
 	if err != nil {
-		return nil, fmt.Errorf("failed to CreateEIP, error=%s", err)
+		return nil, err
+	}
+	if progress == nil {
+		var count int64
+		request := vpc.NewAllocateAddressesRequest()
+		if eip.AddressCount == "" {
+			count = 1
+		} else {
+			c, _ := strconv.Atoi(eip.AddressCount)
+			count = int64(c)
+		}
+		request.AddressCount = &count
+		response, err := client.AllocateAddresses(request)
+		if err != nil {
+			return nil, fmt.Errorf("failed to CreateEIP, error=%s", err)
+		}
+		if len(response.Response.AddressSet) == 0 {
+			return nil, fmt.Errorf("allocate eip meet error, the return eip is zero")
+		}
+		progress = &idempotencyCreateProgress{Ids: common.StringValues(response.Response.AddressSet), RequestId: *response.Response.RequestId}
+		saveIdempotencyCreatedIds(idempotencyKey, progress.RequestId, progress.Ids...)
 	}
 
 	req := vpc.NewDescribeAddressesRequest()
 	output := EIPOutput{}
 	output.Guid = eip.Guid
-	output.RequestId = *response.Response.RequestId
-	if len(response.Response.AddressSet) == 0 {
-		return nil, fmt.Errorf("allocate eip meet error, the return eip is zero")
-	}
-	for i := 0; i < len(response.Response.AddressSet); i++ {
-		req.AddressIds = append(req.AddressIds, response.Response.AddressSet[i])
-	}
+	output.RequestId = progress.RequestId
+	req.AddressIds = common.StringPtrs(progress.Ids)
 	//query eips info get eip ip
 	for {
 		queryEIPResponse, err := client.DescribeAddresses(req)
@@ -163,18 +173,59 @@ func (action *EIPCreateAction) Do(input interface{}) (interface{}, error) {
 	eips, _ := input.(EIPInputs)
 	outputs := EIPOutputs{}
 	for _, subnet := range eips.Inputs {
-		output, err := action.createEIP(&subnet)
+		output := EIPOutput{}
+		idempotencyKey := getIdempotencyKey("eip-create", subnet.Guid, subnet.IdempotencyKey, subnet)
+		err := runWithIdempotencyKey(idempotencyKey, &output, func() (interface{}, error) {
+			return action.createEIP(&subnet, idempotencyKey)
+		})
 		if err != nil {
 			return nil, err
 		}
 
-		outputs.Outputs = append(outputs.Outputs, *output)
+		outputs.Outputs = append(outputs.Outputs, output)
 	}
 
 	logrus.Infof("all eip = %v are created", eips)
 	return &outputs, nil
 }
 
+func queryEIPAddress(client VpcAPI, id string) (*vpc.Address, bool, error) {
+	request := vpc.NewDescribeAddressesRequest()
+	request.AddressIds = []*string{&id}
+	response, err := client.DescribeAddresses(request)
+	if err != nil {
+		return nil, false, fmt.Errorf("query eip info meet error : %s", err)
+	}
+	if len(response.Response.AddressSet) == 0 {
+		return nil, false, nil
+	}
+	return response.Response.AddressSet[0], true, nil
+}
+
+func (action *EIPCreateAction) DryRun(input interface{}) (interface{}, error) {
+	eips, _ := input.(EIPInputs)
+	outputs := DryRunOutputs{}
+	for _, eip := range eips.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(eip.ProviderParams)
+		client, err := CreateEIPClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+		if err != nil {
+			return nil, err
+		}
+
+		addressCount := eip.AddressCount
+		if addressCount == "" {
+			addressCount = "1"
+		}
+		output := newCreateDryRunOutput(eip.Guid, eip.Id, false, fmt.Sprintf("allocate %s eip", addressCount))
+		if err = queryEIPInfo(client, &eip); err != nil {
+			output.Conflicts = append(output.Conflicts, err.Error())
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+
+	return &outputs, nil
+}
+
 type EIPTerminateAction struct {
 }
 
@@ -229,7 +280,27 @@ func (action *EIPTerminateAction) Do(input interface{}) (interface{}, error) {
 	return &outputs, nil
 }
 
-func queryEIPInfo(client *vpc.Client, eip *EIPInput) error {
+func (action *EIPTerminateAction) DryRun(input interface{}) (interface{}, error) {
+	eips, _ := input.(EIPInputs)
+	outputs := DryRunOutputs{}
+	for _, eip := range eips.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(eip.ProviderParams)
+		client, err := CreateEIPClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+		if err != nil {
+			return nil, err
+		}
+
+		_, exist, err := queryEIPAddress(client, eip.Id)
+		if err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, newDeleteDryRunOutput(eip.Guid, eip.Id, exist, "release eip"))
+	}
+
+	return &outputs, nil
+}
+
+func queryEIPInfo(client VpcAPI, eip *EIPInput) error {
 	request := vpc.NewDescribeAddressQuotaRequest()
 	response, err := client.DescribeAddressQuota(request)
 	if err != nil {
@@ -321,6 +392,49 @@ func (action *EIPAttachAction) Do(input interface{}) (interface{}, error) {
 	return &outputs, nil
 }
 
+//dryRunEIPAssociation reports whether the eip need to be associated or disassociated with the instance
+func dryRunEIPAssociation(eips EIPInputs, associate bool) (*DryRunOutputs, error) {
+	outputs := DryRunOutputs{}
+	for _, eip := range eips.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(eip.ProviderParams)
+		client, err := CreateEIPClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+		if err != nil {
+			return nil, err
+		}
+
+		address, exist, err := queryEIPAddress(client, eip.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		output := newDryRunOutput(eip.Guid, eip.Id, exist, DRY_RUN_OPERATION_NONE, "")
+		switch {
+		case !exist:
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("eip[%s] not found", eip.Id))
+		case associate && address.InstanceId != nil && *address.InstanceId == eip.InstanceId:
+			output.Detail = fmt.Sprintf("eip already associated with instance[%s]", eip.InstanceId)
+		case associate && address.InstanceId != nil && *address.InstanceId != "":
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("eip already associated with instance[%s]", *address.InstanceId))
+		case associate:
+			output.Operation = DRY_RUN_OPERATION_MODIFY
+			output.Detail = fmt.Sprintf("associate eip with instance[%s]", eip.InstanceId)
+		case address.InstanceId == nil || *address.InstanceId == "":
+			output.Detail = "eip is not associated with any instance"
+		default:
+			output.Operation = DRY_RUN_OPERATION_MODIFY
+			output.Detail = fmt.Sprintf("disassociate eip from instance[%s]", *address.InstanceId)
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+
+	return &outputs, nil
+}
+
+func (action *EIPAttachAction) DryRun(input interface{}) (interface{}, error) {
+	eips, _ := input.(EIPInputs)
+	return dryRunEIPAssociation(eips, true)
+}
+
 type EIPDetachAction struct {
 }
 
@@ -379,6 +493,11 @@ func (action *EIPDetachAction) Do(input interface{}) (interface{}, error) {
 	return &outputs, nil
 }
 
+func (action *EIPDetachAction) DryRun(input interface{}) (interface{}, error) {
+	eips, _ := input.(EIPInputs)
+	return dryRunEIPAssociation(eips, false)
+}
+
 type EIPBindNatAction struct {
 }
 
@@ -440,7 +559,7 @@ func (action *EIPBindNatAction) bindNatGateway(eip *EIPInput) (*EIPOutput, error
 		if *taskResp.Data.Status == 1 {
 			return nil, fmt.Errorf("terminateNatGateway execute failed, err = %v", *taskResp.Data.Output.ErrorMsg)
 		}
-		time.Sleep(5 * time.Second)
+		time.Sleep(waitPollInterval)
 		count++
 		if count >= 20 {
 			return nil, fmt.Errorf("terminateNatGateway query result timeout")
@@ -467,6 +586,35 @@ func (action *EIPBindNatAction) Do(input interface{}) (interface{}, error) {
 	return &outputs, nil
 }
 
+//dryRunEIPNatBinding only checks the nat gateway exists, legacy qcloud API can't tell which eips are bound
+func dryRunEIPNatBinding(eips EIPInputs, detail string) (*DryRunOutputs, error) {
+	outputs := DryRunOutputs{}
+	for _, eip := range eips.Inputs {
+		client, err := createNatGatewayClient(eip.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		_, exist, err := queryNatGatewayById(client, eip.NatId)
+		if err != nil {
+			return nil, err
+		}
+		output := newDryRunOutput(eip.Guid, eip.Id, exist, DRY_RUN_OPERATION_MODIFY, fmt.Sprintf(detail, eip.Eip, eip.NatId))
+		if !exist {
+			output.Operation = DRY_RUN_OPERATION_NONE
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("nat gateway[%s] not found", eip.NatId))
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+
+	return &outputs, nil
+}
+
+func (action *EIPBindNatAction) DryRun(input interface{}) (interface{}, error) {
+	eips, _ := input.(EIPInputs)
+	return dryRunEIPNatBinding(eips, "bind eip[%s] to nat gateway[%s]")
+}
+
 type EIPUnBindNatAction struct {
 }
 
@@ -529,7 +677,7 @@ func (action *EIPUnBindNatAction) unbindNatGateway(eip *EIPInput) (*EIPOutput, e
 		if *taskResp.Data.Status == 1 {
 			return nil, fmt.Errorf("eip unbind nat gateway execute failed, err = %v", *taskResp.Data.Output.ErrorMsg)
 		}
-		time.Sleep(5 * time.Second)
+		time.Sleep(waitPollInterval)
 		count++
 		if count >= 20 {
 			return nil, fmt.Errorf("eip unbind nat gateway query result timeout")
@@ -555,3 +703,8 @@ func (action *EIPUnBindNatAction) Do(input interface{}) (interface{}, error) {
 
 	return &outputs, nil
 }
+
+func (action *EIPUnBindNatAction) DryRun(input interface{}) (interface{}, error) {
+	eips, _ := input.(EIPInputs)
+	return dryRunEIPNatBinding(eips, "unbind eip[%s] from nat gateway[%s]")
+}
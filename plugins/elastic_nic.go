@@ -5,8 +5,6 @@ import (
 	"fmt"
 
 	"github.com/sirupsen/logrus"
-	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
-	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
 	vpc "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/vpc/v20170312"
 )
 
@@ -19,13 +17,8 @@ func init() {
 	ElasticNicActions["detach"] = new(ElasticNicDetachAction)
 }
 
-func CreateElasticNicClient(region, secretId, secretKey string) (client *vpc.Client, err error) {
-	credential := common.NewCredential(secretId, secretKey)
-
-	clientProfile := profile.NewClientProfile()
-	clientProfile.HttpProfile.Endpoint = "vpc.tencentcloudapi.com"
-
-	return vpc.NewClient(credential, region, clientProfile)
+func CreateElasticNicClient(region, secretId, secretKey string) (VpcAPI, error) {
+	return QcloudClientFactory.NewVpcClient(region, secretId, secretKey)
 }
 
 type ElasticNicInputs struct {
@@ -43,6 +36,7 @@ type ElasticNicInput struct {
 	SubnetId           string   `json:"subnet_id,omitempty"`
 	InstanceId         string   `json:"instance_id,omitempty"`
 	Id                 string   `json:"id,omitempty"`
+	IdempotencyKey     string   `json:"idempotency_key,omitempty"`
 }
 
 type ElasticNicOutputs struct {
@@ -103,11 +97,14 @@ func (action *ElasticNicCreateAction) CheckParam(input interface{}) error {
 	return nil
 }
 
-func (action *ElasticNicCreateAction) createElasticNic(ElasticNicInput *ElasticNicInput) (*ElasticNicOutput, error) {
+func (action *ElasticNicCreateAction) createElasticNic(ElasticNicInput *ElasticNicInput, idempotencyKey string) (*ElasticNicOutput, error) {
 	paramsMap, err := GetMapFromProviderParams(ElasticNicInput.ProviderParams)
 	client, _ := CreateElasticNicClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
 
 	//check resource exist
+	if ElasticNicInput.Id, err = getIdempotencyCreatedId(idempotencyKey, ElasticNicInput.Id); err != nil {
+		return nil, err
+	}
 	if ElasticNicInput.Id != "" {
 		queryElasticNiResponse, flag, err := queryElasticNicInfo(client, ElasticNicInput)
 		if err != nil && flag == false {
@@ -132,6 +129,7 @@ func (action *ElasticNicCreateAction) createElasticNic(ElasticNicInput *ElasticN
 		logrus.Errorf("failed to create elastic nic, error=%s", err)
 		return nil, err
 	}
+	saveIdempotencyCreatedIds(idempotencyKey, *response.Response.RequestId, *response.Response.NetworkInterface.NetworkInterfaceId)
 	output := ElasticNicOutput{}
 	output.RequestId = *response.Response.RequestId
 	output.Guid = ElasticNicInput.Guid
@@ -152,17 +150,43 @@ func (action *ElasticNicCreateAction) Do(input interface{}) (interface{}, error)
 	elasticNics, _ := input.(ElasticNicInputs)
 	outputs := ElasticNicOutputs{}
 	for _, elasticNic := range elasticNics.Inputs {
-		ElasticNicOutput, err := action.createElasticNic(&elasticNic)
+		ElasticNicOutput := ElasticNicOutput{}
+		idempotencyKey := getIdempotencyKey("elastic-nic-create", elasticNic.Guid, elasticNic.IdempotencyKey, elasticNic)
+		err := runWithIdempotencyKey(idempotencyKey, &ElasticNicOutput, func() (interface{}, error) {
+			return action.createElasticNic(&elasticNic, idempotencyKey)
+		})
 		if err != nil {
 			return nil, err
 		}
-		outputs.Outputs = append(outputs.Outputs, *ElasticNicOutput)
+		outputs.Outputs = append(outputs.Outputs, ElasticNicOutput)
 	}
 
 	logrus.Infof("all elasticNics = %v are created", elasticNics)
 	return &outputs, nil
 }
 
+func (action *ElasticNicCreateAction) DryRun(input interface{}) (interface{}, error) {
+	elasticNics, _ := input.(ElasticNicInputs)
+	outputs := DryRunOutputs{}
+	for _, elasticNic := range elasticNics.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(elasticNic.ProviderParams)
+		client, _ := CreateElasticNicClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+
+		exist := false
+		if elasticNic.Id != "" {
+			_, flag, err := queryElasticNicInfo(client, &elasticNic)
+			if err != nil {
+				return nil, err
+			}
+			exist = flag
+		}
+		detail := fmt.Sprintf("create elastic nic name=%s in vpc[%s] subnet[%s]", elasticNic.Name, elasticNic.VpcId, elasticNic.SubnetId)
+		outputs.Outputs = append(outputs.Outputs, newCreateDryRunOutput(elasticNic.Guid, elasticNic.Id, exist, detail))
+	}
+
+	return &outputs, nil
+}
+
 type ElasticNicTerminateAction struct {
 }
 
@@ -227,7 +251,30 @@ func (action *ElasticNicTerminateAction) Do(input interface{}) (interface{}, err
 	return &outputs, nil
 }
 
-func queryElasticNicInfo(client *vpc.Client, input *ElasticNicInput) (*ElasticNicOutput, bool, error) {
+func (action *ElasticNicTerminateAction) DryRun(input interface{}) (interface{}, error) {
+	elasticNics, _ := input.(ElasticNicInputs)
+	outputs := DryRunOutputs{}
+	for _, elasticNic := range elasticNics.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(elasticNic.ProviderParams)
+		client, _ := CreateElasticNicClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+
+		_, exist, err := queryElasticNicInfo(client, &elasticNic)
+		if err != nil {
+			return nil, err
+		}
+		output := newDeleteDryRunOutput(elasticNic.Guid, elasticNic.Id, exist, "delete elastic nic")
+		if exist {
+			if err = ensureElasticNicDetach(client, &elasticNic); err != nil {
+				output.Conflicts = append(output.Conflicts, err.Error())
+			}
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+
+	return &outputs, nil
+}
+
+func queryElasticNicInfo(client VpcAPI, input *ElasticNicInput) (*ElasticNicOutput, bool, error) {
 	output := ElasticNicOutput{}
 
 	request := vpc.NewDescribeNetworkInterfacesRequest()
@@ -330,6 +377,57 @@ func (action *ElasticNicAttachAction) Do(input interface{}) (interface{}, error)
 	return &outputs, nil
 }
 
+//dryRunElasticNicAttachment reports whether the elastic nic need to be attached to or detached from the instance
+func dryRunElasticNicAttachment(elasticNics ElasticNicInputs, attach bool) (*DryRunOutputs, error) {
+	outputs := DryRunOutputs{}
+	for _, elasticNic := range elasticNics.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(elasticNic.ProviderParams)
+		client, _ := CreateElasticNicClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+
+		request := vpc.NewDescribeNetworkInterfacesRequest()
+		request.NetworkInterfaceIds = append(request.NetworkInterfaceIds, &elasticNic.Id)
+		response, err := client.DescribeNetworkInterfaces(request)
+		if err != nil {
+			return nil, err
+		}
+
+		exist := len(response.Response.NetworkInterfaceSet) == 1
+		output := newDryRunOutput(elasticNic.Guid, elasticNic.Id, exist, DRY_RUN_OPERATION_NONE, "")
+		if !exist {
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("elastic nic[%s] not found", elasticNic.Id))
+			outputs.Outputs = append(outputs.Outputs, output)
+			continue
+		}
+
+		attachedInstanceId := ""
+		if attachment := response.Response.NetworkInterfaceSet[0].Attachment; attachment != nil && attachment.InstanceId != nil {
+			attachedInstanceId = *attachment.InstanceId
+		}
+		switch {
+		case attach && attachedInstanceId == elasticNic.InstanceId:
+			output.Detail = fmt.Sprintf("elastic nic already attached to instance[%s]", elasticNic.InstanceId)
+		case attach && attachedInstanceId != "":
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("elastic nic already attached to instance[%s]", attachedInstanceId))
+		case attach:
+			output.Operation = DRY_RUN_OPERATION_MODIFY
+			output.Detail = fmt.Sprintf("attach elastic nic to instance[%s]", elasticNic.InstanceId)
+		case attachedInstanceId != elasticNic.InstanceId:
+			output.Detail = fmt.Sprintf("elastic nic is not attached to instance[%s]", elasticNic.InstanceId)
+		default:
+			output.Operation = DRY_RUN_OPERATION_MODIFY
+			output.Detail = fmt.Sprintf("detach elastic nic from instance[%s]", elasticNic.InstanceId)
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+
+	return &outputs, nil
+}
+
+func (action *ElasticNicAttachAction) DryRun(input interface{}) (interface{}, error) {
+	elasticNics, _ := input.(ElasticNicInputs)
+	return dryRunElasticNicAttachment(elasticNics, true)
+}
+
 type ElasticNicDetachAction struct {
 }
 
@@ -397,7 +495,12 @@ func (action *ElasticNicDetachAction) Do(input interface{}) (interface{}, error)
 	return &outputs, nil
 }
 
-func ensureElasticNicDetach(client *vpc.Client, input *ElasticNicInput) error {
+func (action *ElasticNicDetachAction) DryRun(input interface{}) (interface{}, error) {
+	elasticNics, _ := input.(ElasticNicInputs)
+	return dryRunElasticNicAttachment(elasticNics, false)
+}
+
+func ensureElasticNicDetach(client VpcAPI, input *ElasticNicInput) error {
 	request := vpc.NewDescribeNetworkInterfacesRequest()
 	request.NetworkInterfaceIds = append(request.NetworkInterfaceIds, &input.Id)
 	response, err := client.DescribeNetworkInterfaces(request)
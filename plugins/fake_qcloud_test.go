@@ -0,0 +1,1247 @@
+package plugins
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WeBankPartners/wecube-plugins-qcloud/plugins/utils"
+	"github.com/WeBankPartners/wecube-plugins-qcloud/test_fixtures/fakeqcloud"
+)
+
+const (
+	FAKE_QCLOUD_REGION = "ap-guangzhou"
+	FAKE_QCLOUD_ZONE   = "ap-guangzhou-3"
+)
+
+//setupFakeQcloud points all the sdk clients to an in-memory fake qcloud
+func setupFakeQcloud(t *testing.T) (*fakeqcloud.Server, func()) {
+	cleanupIdempotencyStore := setupIdempotencyStore(t)
+	cleanupWaitPollInterval := setupWaitPollInterval()
+	server := fakeqcloud.NewServer()
+	server.AsyncSteps = 0
+	QcloudHttpTransport = server
+	return server, func() {
+		QcloudHttpTransport = nil
+		cleanupIdempotencyStore()
+		cleanupWaitPollInterval()
+	}
+}
+
+func newFakeQcloudRequest(name string, action string, inputs ...map[string]interface{}) *PluginRequest {
+	for _, input := range inputs {
+		input["provider_params"] = fakeqcloud.ProviderParams(FAKE_QCLOUD_REGION, FAKE_QCLOUD_ZONE)
+	}
+	body, _ := json.Marshal(map[string]interface{}{"inputs": inputs})
+	return &PluginRequest{
+		Version:      DEFAULT_API_VERSION,
+		ProviderName: DEFAULT_PROVIDER_NAME,
+		Name:         name,
+		Action:       action,
+		Parameters:   bytes.NewReader(body),
+	}
+}
+
+func runFakeQcloud(name string, action string, inputs ...map[string]interface{}) (*PluginResponse, error) {
+	return Process(newFakeQcloudRequest(name, action, inputs...))
+}
+
+//dryRunFakeQcloud previews the plugin action with the inputs and returns the dry run outputs
+func dryRunFakeQcloud(t *testing.T, name string, action string, inputs ...map[string]interface{}) []DryRunOutput {
+	request := newFakeQcloudRequest(name, action, inputs...)
+	request.DryRun = true
+	response, err := Process(request)
+	if err != nil {
+		t.Fatalf("dry run %s-%s meet err=%v", name, action, err)
+	}
+	return response.Results.(*DryRunOutputs).Outputs
+}
+
+//decryptFakeVmPassword decrypts the password output of the vm created with the seed
+func decryptFakeVmPassword(t *testing.T, output map[string]interface{}, seed string) string {
+	md5sum := utils.Md5Encode(output["guid"].(string) + seed)
+	password, err := utils.AesDecode(md5sum[0:16], output["password"].(string))
+	if err != nil {
+		t.Fatalf("decrypt password of vm[%v] meet err=%v", output["id"], err)
+	}
+	return password
+}
+
+//processFakeQcloudError runs the plugin action which should fail and returns the error message
+func processFakeQcloudError(t *testing.T, name string, action string, inputs ...map[string]interface{}) string {
+	if _, err := runFakeQcloud(name, action, inputs...); err != nil {
+		return err.Error()
+	}
+	t.Fatalf("%s-%s should fail", name, action)
+	return ""
+}
+
+//processFakeQcloud runs the plugin action with the inputs and returns the outputs
+func processFakeQcloud(t *testing.T, name string, action string, inputs ...map[string]interface{}) []map[string]interface{} {
+	response, err := runFakeQcloud(name, action, inputs...)
+	if err != nil {
+		t.Fatalf("%s-%s meet err=%v", name, action, err)
+	}
+
+	var results struct {
+		Outputs []map[string]interface{} `json:"outputs"`
+	}
+	resultBytes, _ := json.Marshal(response.Results)
+	if err = json.Unmarshal(resultBytes, &results); err != nil {
+		t.Fatalf("%s-%s unmarshal results meet err=%v", name, action, err)
+	}
+	return results.Outputs
+}
+
+func outputId(t *testing.T, outputs []map[string]interface{}) string {
+	if len(outputs) != 1 {
+		t.Fatalf("got %d outputs,want 1", len(outputs))
+	}
+	id, _ := outputs[0]["id"].(string)
+	if id == "" {
+		t.Fatalf("output %v has no id", outputs[0])
+	}
+	return id
+}
+
+func createFakeSubnet(t *testing.T) (string, string) {
+	vpcId := outputId(t, processFakeQcloud(t, "vpc", "create", map[string]interface{}{
+		"guid": "vpc-guid", "name": "vpc-test", "cidr_block": "10.0.0.0/16",
+	}))
+	subnetId := outputId(t, processFakeQcloud(t, "subnet", "create", map[string]interface{}{
+		"guid": "subnet-guid", "name": "subnet-test", "cidr_block": "10.0.1.0/24", "vpc_id": vpcId,
+	}))
+	return vpcId, subnetId
+}
+
+func TestFakeQcloudNetwork(t *testing.T) {
+	server, cleanup := setupFakeQcloud(t)
+	defer cleanup()
+
+	vpcId, subnetId := createFakeSubnet(t)
+	routeTableId := outputId(t, processFakeQcloud(t, "route-table", "create", map[string]interface{}{
+		"guid": "route-table-guid", "name": "route-table-test", "vpc_id": vpcId,
+	}))
+	processFakeQcloud(t, "route-table", "associate-subnet", map[string]interface{}{
+		"guid": "route-table-guid", "subnet_id": subnetId, "route_table_id": routeTableId,
+	})
+	if subnet, _ := server.GetResource(fakeqcloud.KIND_SUBNET, FAKE_QCLOUD_REGION, subnetId); subnet["RouteTableId"] != routeTableId {
+		t.Fatalf("subnet route table=%v,want %s", subnet["RouteTableId"], routeTableId)
+	}
+
+	routePolicyInput := func(gatewayId string, overwrite string) map[string]interface{} {
+		return map[string]interface{}{
+			"guid": "route-policy-guid", "route_table_id": routeTableId, "dest_cidr": "192.168.0.0/24",
+			"gateway_type": "NORMAL_CVM", "gateway_id": gatewayId, "overwrite": overwrite,
+		}
+	}
+	routePolicyId := outputId(t, processFakeQcloud(t, "route-policy", "create", routePolicyInput("10.0.1.10", "")))
+	if retriedId := outputId(t, processFakeQcloud(t, "route-policy", "create", routePolicyInput("10.0.1.10", ""))); retriedId != routePolicyId {
+		t.Fatalf("retried route policy id=%s,want %s", retriedId, routePolicyId)
+	}
+	if calls := server.Calls("CreateRoutes"); len(calls) != 1 {
+		t.Fatalf("got %d CreateRoutes calls,want 1", len(calls))
+	}
+	if err := processFakeQcloudError(t, "route-policy", "create", routePolicyInput("10.0.1.11", "false")); !strings.Contains(err, "route conflict") {
+		t.Fatalf("create route policy to another gateway err=%s", err)
+	}
+	if replacedId := outputId(t, processFakeQcloud(t, "route-policy", "create", routePolicyInput("10.0.1.11", "true"))); replacedId != routePolicyId {
+		t.Fatalf("replaced route policy id=%s,want %s", replacedId, routePolicyId)
+	}
+	described := processFakeQcloud(t, "route-table", "describe", map[string]interface{}{"guid": "route-table-guid", "id": routeTableId})
+	if described[0]["routes"] != "192.168.0.0/24,NORMAL_CVM,10.0.1.11" {
+		t.Fatalf("route table routes=%v,want the route replaced", described[0]["routes"])
+	}
+	//the same route disabled since is enabled again instead of returned as it is
+	processFakeQcloud(t, "route-table", "replace-routes", map[string]interface{}{
+		"guid": "route-table-guid", "id": routeTableId, "routes": "192.168.0.0/24,NORMAL_CVM,10.0.1.11,disabled",
+	})
+	if enabledId := outputId(t, processFakeQcloud(t, "route-policy", "create", routePolicyInput("10.0.1.11", ""))); enabledId != routePolicyId {
+		t.Fatalf("enabled route policy id=%s,want %s", enabledId, routePolicyId)
+	}
+	described = processFakeQcloud(t, "route-table", "describe", map[string]interface{}{"guid": "route-table-guid", "id": routeTableId})
+	if described[0]["routes"] != "192.168.0.0/24,NORMAL_CVM,10.0.1.11" || len(server.Calls("EnableRoutes")) != 1 {
+		t.Fatalf("route table routes=%v,want the route enabled", described[0]["routes"])
+	}
+	processFakeQcloud(t, "route-policy", "terminate", map[string]interface{}{
+		"guid": "route-policy-guid", "id": routePolicyId, "route_table_id": routeTableId,
+	})
+	if routeTable, _ := server.GetResource(fakeqcloud.KIND_ROUTE_TABLE, FAKE_QCLOUD_REGION, routeTableId); len(routeTable["RouteSet"].([]interface{})) != 0 {
+		t.Fatalf("route policy should be deleted, routes=%v", routeTable["RouteSet"])
+	}
+
+	securityGroupId := outputId(t, processFakeQcloud(t, "security-group", "create", map[string]interface{}{
+		"guid": "security-group-guid", "name": "security-group-test", "description": "test",
+	}))
+	processFakeQcloud(t, "security-group", "terminate", map[string]interface{}{
+		"guid": "security-group-guid", "id": securityGroupId,
+	})
+	if _, found := server.GetResource(fakeqcloud.KIND_SECURITY_GROUP, FAKE_QCLOUD_REGION, securityGroupId); found {
+		t.Fatalf("security group %s should be deleted", securityGroupId)
+	}
+
+	processFakeQcloud(t, "subnet", "terminate", map[string]interface{}{"guid": "subnet-guid", "id": subnetId})
+	processFakeQcloud(t, "route-table", "terminate", map[string]interface{}{"guid": "route-table-guid", "id": routeTableId})
+	processFakeQcloud(t, "vpc", "terminate", map[string]interface{}{"guid": "vpc-guid", "id": vpcId})
+	if _, found := server.GetResource(fakeqcloud.KIND_VPC, FAKE_QCLOUD_REGION, vpcId); found {
+		t.Fatalf("vpc %s should be deleted", vpcId)
+	}
+}
+
+func TestFakeQcloudVpcTopology(t *testing.T) {
+	server, cleanup := setupFakeQcloud(t)
+	defer cleanup()
+
+	vpcId, subnetId := createFakeSubnet(t)
+	securityGroupId := outputId(t, processFakeQcloud(t, "security-group", "create", map[string]interface{}{
+		"guid": "security-group-guid", "name": "security-group-test", "description": "test",
+	}))
+	nicId := outputId(t, processFakeQcloud(t, "elastic-nic", "create", map[string]interface{}{
+		"guid": "nic-guid", "name": "nic-test", "vpc_id": vpcId, "subnet_id": subnetId, "security_group_id": []string{securityGroupId},
+	}))
+	server.AddResource(fakeqcloud.KIND_NAT_GATEWAY, FAKE_QCLOUD_REGION, "nat-1", map[string]interface{}{
+		"NatGatewayId": "nat-1", "NatGatewayName": "nat-test", "VpcId": vpcId, "State": "AVAILABLE",
+	})
+	server.AddResource(fakeqcloud.KIND_PEERING_CONNECTION, FAKE_QCLOUD_REGION, "pcx-1", map[string]interface{}{
+		"PeeringConnectionId": "pcx-1", "SourceVpcId": "vpc-other", "PeerVpcId": vpcId, "State": "ACTIVE",
+	})
+	server.AddResource(fakeqcloud.KIND_PEERING_CONNECTION, FAKE_QCLOUD_REGION, "pcx-2", map[string]interface{}{
+		"PeeringConnectionId": "pcx-2", "SourceVpcId": "vpc-other", "PeerVpcId": "vpc-another", "State": "ACTIVE",
+	})
+
+	modifyInput := map[string]interface{}{"guid": "vpc-guid", "id": vpcId, "name": "vpc-renamed", "dns_servers": "10.0.0.2,10.0.0.3", "domain_name": "example.com"}
+	modified := processFakeQcloud(t, "vpc", "modify", modifyInput)
+	if len(modified) != 1 || modified[0]["name"] != "vpc-renamed" || modified[0]["dns_servers"] != "10.0.0.2,10.0.0.3" || modified[0]["domain_name"] != "example.com" {
+		t.Fatalf("modify outputs=%v,want the new attributes", modified)
+	}
+	body, _ := json.Marshal(map[string]interface{}{"inputs": []map[string]interface{}{modifyInput}})
+	response, err := Process(&PluginRequest{
+		Version:      DEFAULT_API_VERSION,
+		ProviderName: DEFAULT_PROVIDER_NAME,
+		Name:         "vpc",
+		Action:       "modify",
+		Parameters:   bytes.NewReader(body),
+		DryRun:       true,
+	})
+	if err != nil {
+		t.Fatalf("dry run modify meet err=%v", err)
+	}
+	if previews := response.Results.(*DryRunOutputs).Outputs; len(previews) != 1 || previews[0].Operation != DRY_RUN_OPERATION_NONE {
+		t.Fatalf("dry run previews=%v,want nothing to modify", previews)
+	}
+	processFakeQcloud(t, "vpc", "modify", modifyInput)
+	if calls := server.Calls("ModifyVpcAttribute"); len(calls) != 1 {
+		t.Fatalf("got %d ModifyVpcAttribute calls,want 1", len(calls))
+	}
+
+	described := processFakeQcloud(t, "vpc", "describe", map[string]interface{}{"guid": "vpc-guid", "id": vpcId})
+	if len(described) != 1 || described[0]["name"] != "vpc-renamed" {
+		t.Fatalf("describe outputs=%v,want vpc %s", described, vpcId)
+	}
+	var topology struct {
+		Vpc                map[string]interface{}   `json:"vpc"`
+		Subnets            []map[string]interface{} `json:"subnets"`
+		RouteTables        []map[string]interface{} `json:"route_tables"`
+		SecurityGroups     []map[string]interface{} `json:"security_groups"`
+		NatGateways        []map[string]interface{} `json:"nat_gateways"`
+		PeeringConnections []map[string]interface{} `json:"peering_connections"`
+		NetworkInterfaces  []map[string]interface{} `json:"network_interfaces"`
+	}
+	if err := json.Unmarshal([]byte(described[0]["topology"].(string)), &topology); err != nil {
+		t.Fatalf("topology %v is invalid, err=%v", described[0]["topology"], err)
+	}
+	if topology.Vpc["VpcId"] != vpcId || topology.Vpc["DomainName"] != "example.com" {
+		t.Fatalf("topology vpc=%v,want %s", topology.Vpc, vpcId)
+	}
+	if len(topology.Subnets) != 1 || topology.Subnets[0]["SubnetId"] != subnetId {
+		t.Fatalf("topology subnets=%v,want %s", topology.Subnets, subnetId)
+	}
+	if len(topology.RouteTables) != 1 || len(topology.RouteTables[0]["AssociationSet"].([]interface{})) != 1 {
+		t.Fatalf("topology route tables=%v,want the main route table of %s", topology.RouteTables, subnetId)
+	}
+	if len(topology.NetworkInterfaces) != 1 || topology.NetworkInterfaces[0]["NetworkInterfaceId"] != nicId {
+		t.Fatalf("topology network interfaces=%v,want %s", topology.NetworkInterfaces, nicId)
+	}
+	if len(topology.SecurityGroups) != 1 || topology.SecurityGroups[0]["SecurityGroupId"] != securityGroupId {
+		t.Fatalf("topology security groups=%v,want %s", topology.SecurityGroups, securityGroupId)
+	}
+	if len(topology.NatGateways) != 1 || len(topology.PeeringConnections) != 1 || topology.PeeringConnections[0]["PeeringConnectionId"] != "pcx-1" {
+		t.Fatalf("topology nat gateways=%v peering connections=%v,want nat-1 and pcx-1", topology.NatGateways, topology.PeeringConnections)
+	}
+}
+
+func TestFakeQcloudRouteTable(t *testing.T) {
+	server, cleanup := setupFakeQcloud(t)
+	defer cleanup()
+
+	vpcId, subnetId := createFakeSubnet(t)
+	routeTableId := outputId(t, processFakeQcloud(t, "route-table", "create", map[string]interface{}{"guid": "rtb-guid", "name": "rtb", "vpc_id": vpcId}))
+	processFakeQcloud(t, "route-table", "associate-subnet", map[string]interface{}{"guid": "rtb-guid", "subnet_id": subnetId, "route_table_id": routeTableId})
+
+	renameInput := map[string]interface{}{"guid": "rtb-guid", "id": routeTableId, "name": "rtb-renamed"}
+	processFakeQcloud(t, "route-table", "rename", renameInput)
+	processFakeQcloud(t, "route-table", "rename", renameInput)
+	if calls := server.Calls("ModifyRouteTableAttribute"); len(calls) != 1 {
+		t.Fatalf("got %d ModifyRouteTableAttribute calls,want 1", len(calls))
+	}
+
+	routes := "192.168.0.0/24,NORMAL_CVM,10.0.1.10;172.16.0.0/16,NORMAL_CVM,10.0.1.11,disabled"
+	replaced := processFakeQcloud(t, "route-table", "replace-routes", map[string]interface{}{"guid": "rtb-guid", "id": routeTableId, "routes": routes})
+	if replaced[0]["routes"] != routes {
+		t.Fatalf("replace-routes outputs=%v,want routes %s", replaced, routes)
+	}
+	processFakeQcloud(t, "route-table", "replace-routes", map[string]interface{}{"guid": "rtb-guid", "id": routeTableId, "routes": routes})
+	if calls := server.Calls("CreateRoutes"); len(calls) != 1 {
+		t.Fatalf("got %d CreateRoutes calls,want 1", len(calls))
+	}
+
+	//the route to 192.168.0.0/24 is moved to another gateway in place and the other one is enabled
+	routes = "192.168.0.0/24,NORMAL_CVM,10.0.1.12;172.16.0.0/16,NORMAL_CVM,10.0.1.11"
+	processFakeQcloud(t, "route-table", "replace-routes", map[string]interface{}{"guid": "rtb-guid", "id": routeTableId, "routes": routes})
+	described := processFakeQcloud(t, "route-table", "describe", map[string]interface{}{"guid": "rtb-guid", "id": routeTableId})
+	if described[0]["name"] != "rtb-renamed" || described[0]["subnet_ids"] != subnetId || described[0]["routes"] != routes {
+		t.Fatalf("describe outputs=%v,want name rtb-renamed,subnet %s and routes %s", described, subnetId, routes)
+	}
+	if replaceCalls, deleteCalls := server.Calls("ReplaceRoutes"), server.Calls("DeleteRoutes"); len(replaceCalls) != 1 || len(deleteCalls) != 0 {
+		t.Fatalf("got %d ReplaceRoutes and %d DeleteRoutes calls,want the route replaced", len(replaceCalls), len(deleteCalls))
+	}
+
+	//a missing routes input never deletes the routes, only delete_all_routes does
+	if err := processFakeQcloudError(t, "route-table", "replace-routes", map[string]interface{}{"guid": "rtb-guid", "id": routeTableId}); !strings.Contains(err, "delete_all_routes") {
+		t.Fatalf("replace-routes without routes err=%s,want delete_all_routes required", err)
+	}
+	processFakeQcloud(t, "route-table", "replace-routes", map[string]interface{}{"guid": "rtb-guid", "id": routeTableId, "delete_all_routes": "true"})
+	if described = processFakeQcloud(t, "route-table", "describe", map[string]interface{}{"guid": "rtb-guid", "id": routeTableId}); described[0]["routes"] != nil {
+		t.Fatalf("describe outputs=%v,want no routes", described)
+	}
+}
+
+func TestFakeQcloudNatGatewayAndPeeringConnection(t *testing.T) {
+	server, cleanup := setupFakeQcloud(t)
+	defer cleanup()
+	server.AsyncSteps = 2
+
+	vpcId, _ := createFakeSubnet(t)
+	natGatewayId := outputId(t, processFakeQcloud(t, "nat-gateway", "create", map[string]interface{}{
+		"guid": "nat-guid", "name": "nat-test", "vpc_id": vpcId, "auto_alloc_eip_num": 1,
+	}))
+	natGateway, _ := server.GetResource(fakeqcloud.KIND_NAT_GATEWAY, FAKE_QCLOUD_REGION, natGatewayId)
+	if natGateway["State"] != "AVAILABLE" {
+		t.Fatalf("nat gateway %s is %v,want AVAILABLE", natGatewayId, natGateway["State"])
+	}
+
+	peerVpcId := outputId(t, processFakeQcloud(t, "vpc", "create", map[string]interface{}{
+		"guid": "peer-vpc-guid", "name": "vpc-peer", "cidr_block": "10.1.0.0/16",
+	}))
+	peeringConnectionInput := map[string]interface{}{
+		"guid": "pcx-guid", "name": "pcx-test", "vpc_id": vpcId, "peer_vpc_id": peerVpcId,
+		"peer_provider_params": fakeqcloud.ProviderParams(FAKE_QCLOUD_REGION, FAKE_QCLOUD_ZONE),
+	}
+	peeringConnectionInput["id"] = outputId(t, processFakeQcloud(t, "peering-connection", "create", peeringConnectionInput))
+
+	processFakeQcloud(t, "peering-connection", "terminate", peeringConnectionInput)
+	processFakeQcloud(t, "nat-gateway", "terminate", map[string]interface{}{"guid": "nat-guid", "id": natGatewayId})
+	if _, found := server.GetResource(fakeqcloud.KIND_NAT_GATEWAY, FAKE_QCLOUD_REGION, natGatewayId); found {
+		t.Fatalf("nat gateway %s should be deleted", natGatewayId)
+	}
+	if _, found := server.GetResource(fakeqcloud.KIND_PEERING_CONNECTION, FAKE_QCLOUD_REGION, peeringConnectionInput["id"].(string)); found {
+		t.Fatalf("peering connection %v should be deleted", peeringConnectionInput["id"])
+	}
+	//both are gone, terminating them again is skipped
+	processFakeQcloud(t, "peering-connection", "terminate", peeringConnectionInput)
+	processFakeQcloud(t, "nat-gateway", "terminate", map[string]interface{}{"guid": "nat-guid", "id": natGatewayId})
+	if calls := server.Calls("DeleteNatGateway"); len(calls) != 1 {
+		t.Fatalf("got %d DeleteNatGateway calls,want 1", len(calls))
+	}
+}
+
+func TestFakeQcloudSubnetPrefixLength(t *testing.T) {
+	server, cleanup := setupFakeQcloud(t)
+	defer cleanup()
+
+	vpcId, _ := createFakeSubnet(t)
+	const count = 4
+	cidrBlocks := make(chan string, count)
+	errs := make(chan error, count)
+	for i := 0; i < count; i++ {
+		go func(i int) {
+			response, err := runFakeQcloud("subnet", "create", map[string]interface{}{
+				"guid": fmt.Sprintf("subnet-guid-%d", i), "name": fmt.Sprintf("subnet-%d", i), "prefix_length": 24, "vpc_id": vpcId,
+			})
+			if err != nil {
+				errs <- err
+				return
+			}
+			cidrBlocks <- response.Results.(*SubnetOutputs).Outputs[0].CidrBlock
+		}(i)
+	}
+
+	allocated := map[string]bool{}
+	for i := 0; i < count; i++ {
+		select {
+		case err := <-errs:
+			t.Fatalf("create subnet with prefix_length meet err=%v", err)
+		case cidrBlock := <-cidrBlocks:
+			allocated[cidrBlock] = true
+		}
+	}
+	for _, want := range []string{"10.0.0.0/24", "10.0.2.0/24", "10.0.3.0/24", "10.0.4.0/24"} {
+		if !allocated[want] {
+			t.Fatalf("allocated cidrs=%v,want %s", allocated, want)
+		}
+	}
+	if calls := server.Calls("CreateSubnet"); len(calls) != count+1 {
+		t.Fatalf("got %d CreateSubnet calls,want %d", len(calls), count+1)
+	}
+
+	if err := processFakeQcloudError(t, "subnet", "create", map[string]interface{}{
+		"guid": "subnet-guid", "name": "subnet", "cidr_block": "10.0.5.0/24", "prefix_length": 24, "vpc_id": vpcId,
+	}); !strings.Contains(err, "can't be used together with prefix_length") {
+		t.Fatalf("create subnet with cidr_block and prefix_length err=%s", err)
+	}
+}
+
+func TestFakeQcloudVpcCidrs(t *testing.T) {
+	server, cleanup := setupFakeQcloud(t)
+	defer cleanup()
+
+	vpcId, _ := createFakeSubnet(t)
+	cidrsInput := map[string]interface{}{"guid": "vpc-guid", "id": vpcId, "secondary_cidr_blocks": "10.1.0.0/16"}
+	added := processFakeQcloud(t, "vpc", "add-cidrs", cidrsInput)
+	if len(added) != 1 || added[0]["secondary_cidr_blocks"] != "10.1.0.0/16" {
+		t.Fatalf("add-cidrs outputs=%v,want secondary cidr 10.1.0.0/16", added)
+	}
+	processFakeQcloud(t, "vpc", "add-cidrs", cidrsInput)
+	if calls := server.Calls("CreateAssistantCidr"); len(calls) != 1 {
+		t.Fatalf("got %d CreateAssistantCidr calls,want 1", len(calls))
+	}
+
+	if err := processFakeQcloudError(t, "subnet", "create", map[string]interface{}{
+		"guid": "subnet-out-guid", "name": "subnet-out", "cidr_block": "10.2.1.0/24", "vpc_id": vpcId,
+	}); !strings.Contains(err, "is not in the cidr blocks") {
+		t.Fatalf("create subnet out of the vpc cidrs err=%s", err)
+	}
+	secondarySubnetId := outputId(t, processFakeQcloud(t, "subnet", "create", map[string]interface{}{
+		"guid": "subnet-secondary-guid", "name": "subnet-secondary", "cidr_block": "10.1.1.0/24", "vpc_id": vpcId,
+	}))
+	if err := processFakeQcloudError(t, "vpc", "remove-cidrs", cidrsInput); !strings.Contains(err, "still has subnet["+secondarySubnetId+"]") {
+		t.Fatalf("remove-cidrs err=%s,want subnet %s in the way", err, secondarySubnetId)
+	}
+
+	assigned := processFakeQcloud(t, "vpc", "assign-ipv6", map[string]interface{}{"guid": "vpc-guid", "id": vpcId})
+	vpcIpv6CidrBlock, _ := assigned[0]["ipv6_cidr_block"].(string)
+	if !strings.HasSuffix(vpcIpv6CidrBlock, "::/56") {
+		t.Fatalf("assign-ipv6 outputs=%v,want a /56 ipv6 cidr", assigned)
+	}
+	processFakeQcloud(t, "vpc", "assign-ipv6", map[string]interface{}{"guid": "vpc-guid", "id": vpcId})
+	if calls := server.Calls("AssignIpv6CidrBlock"); len(calls) != 1 {
+		t.Fatalf("got %d AssignIpv6CidrBlock calls,want 1", len(calls))
+	}
+
+	subnetIpv6CidrBlock := strings.Replace(vpcIpv6CidrBlock, "00::/56", "01::/64", 1)
+	subnetInput := map[string]interface{}{"guid": "subnet-secondary-guid", "id": secondarySubnetId, "vpc_id": vpcId, "ipv6_cidr_block": subnetIpv6CidrBlock}
+	processFakeQcloud(t, "subnet", "assign-ipv6", subnetInput)
+	processFakeQcloud(t, "subnet", "assign-ipv6", subnetInput)
+	if subnet, _ := server.GetResource(fakeqcloud.KIND_SUBNET, FAKE_QCLOUD_REGION, secondarySubnetId); subnet["Ipv6CidrBlock"] != subnetIpv6CidrBlock {
+		t.Fatalf("subnet=%v,want ipv6 cidr %s", subnet, subnetIpv6CidrBlock)
+	}
+	if calls := server.Calls("AssignIpv6SubnetCidrBlock"); len(calls) != 1 {
+		t.Fatalf("got %d AssignIpv6SubnetCidrBlock calls,want 1", len(calls))
+	}
+
+	processFakeQcloud(t, "subnet", "terminate", map[string]interface{}{"guid": "subnet-secondary-guid", "id": secondarySubnetId})
+	removed := processFakeQcloud(t, "vpc", "remove-cidrs", cidrsInput)
+	if len(removed) != 1 || removed[0]["secondary_cidr_blocks"] != nil {
+		t.Fatalf("remove-cidrs outputs=%v,want no secondary cidr", removed)
+	}
+}
+
+func TestFakeQcloudIpamCheck(t *testing.T) {
+	server, cleanup := setupFakeQcloud(t)
+	defer cleanup()
+
+	vpcId, _ := createFakeSubnet(t)
+	if err := processFakeQcloudError(t, "vpc", "create", map[string]interface{}{
+		"guid": "vpc-overlap-guid", "name": "vpc-overlap", "cidr_block": "10.0.128.0/17",
+	}); !strings.Contains(err, "cidr_block(10.0.128.0/17) overlaps with 10.0.0.0/16 of vpc["+vpcId+"]") {
+		t.Fatalf("create overlapped vpc err=%s", err)
+	}
+	if calls := server.Calls("CreateVpc"); len(calls) != 1 {
+		t.Fatalf("got %d CreateVpc calls,want 1", len(calls))
+	}
+
+	//the vpcs created before the check or out of the plugin may still overlap
+	server.AddResource(fakeqcloud.KIND_VPC, FAKE_QCLOUD_REGION, "vpc-legacy", map[string]interface{}{
+		"VpcId": "vpc-legacy", "CidrBlock": "10.0.128.0/17", "AssistantCidrSet": []interface{}{},
+	})
+	server.AddResource(fakeqcloud.KIND_VPC, "ap-shanghai", "vpc-shanghai", map[string]interface{}{
+		"VpcId": "vpc-shanghai", "CidrBlock": "172.16.0.0/16", "AssistantCidrSet": []interface{}{map[string]interface{}{"CidrBlock": "10.0.1.0/24"}},
+	})
+	overlaps := processFakeQcloud(t, "ipam", "check", map[string]interface{}{"guid": "ipam-guid", "regions": FAKE_QCLOUD_REGION + ",ap-shanghai"})
+	got := []string{}
+	for _, overlap := range overlaps {
+		got = append(got, fmt.Sprintf("%s/%s-%s/%s:%s", overlap["vpc_id"], overlap["cidr_block"], overlap["peer_vpc_id"], overlap["peer_cidr_block"], overlap["overlap_cidr_block"]))
+	}
+	sort.Strings(got)
+	want := []string{
+		vpcId + "/10.0.0.0/16-vpc-legacy/10.0.128.0/17:10.0.128.0/17",
+		vpcId + "/10.0.0.0/16-vpc-shanghai/10.0.1.0/24:10.0.1.0/24",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ipam check overlaps=%v,want %v", got, want)
+	}
+}
+
+func TestFakeQcloudDatabase(t *testing.T) {
+	server, cleanup := setupFakeQcloud(t)
+	defer cleanup()
+	vpcId, subnetId := createFakeSubnet(t)
+
+	redisId := outputId(t, processFakeQcloud(t, "redis", "create", map[string]interface{}{
+		"guid": "redis-guid", "type_id": 2, "mem_size": 1024, "goods_num": 1, "period": 1,
+		"password": "Abcd1234", "billing_mode": 0, "vpc_id": vpcId, "subnet_id": subnetId,
+	}))
+	if _, found := server.GetResource(fakeqcloud.KIND_REDIS_INSTANCE, FAKE_QCLOUD_REGION, redisId); !found {
+		t.Fatalf("redis %s not found in fake qcloud", redisId)
+	}
+
+	mariadbId := outputId(t, processFakeQcloud(t, "mariadb", "create", map[string]interface{}{
+		"guid": "mariadb-guid", "seed": "seed", "user_name": "test", "zones": FAKE_QCLOUD_ZONE,
+		"vpc_id": vpcId, "subnet_id": subnetId, "node_count": 2, "memory_size": 2, "storage_size": 10,
+		"charge_period": 1, "db_version": "10.1.9",
+	}))
+	if mariadb, _ := server.GetResource(fakeqcloud.KIND_MARIADB_INSTANCE, FAKE_QCLOUD_REGION, mariadbId); mariadb["Status"] != float64(fakeqcloud.MARIADB_STATUS_RUNNING) {
+		t.Fatalf("mariadb %s status=%v,want running", mariadbId, mariadb["Status"])
+	}
+
+	mysqlId := outputId(t, processFakeQcloud(t, "mysql-vm", "create", map[string]interface{}{
+		"guid": "mysql-guid", "seed": "seed", "engine_version": "5.7", "memory": 1000, "volume": 50,
+		"vpc_id": vpcId, "subnet_id": subnetId, "name": "mysql-test", "count": 1, "charge_type": "POSTPAID_BY_HOUR",
+	}))
+	if mysql, _ := server.GetResource(fakeqcloud.KIND_CDB_INSTANCE, FAKE_QCLOUD_REGION, mysqlId); mysql["InitFlag"] != float64(1) {
+		t.Fatalf("mysql %s should be initialized, init flag=%v", mysqlId, mysql["InitFlag"])
+	}
+	processFakeQcloud(t, "mysql-vm", "terminate", map[string]interface{}{"guid": "mysql-guid", "id": mysqlId})
+	if mysql, _ := server.GetResource(fakeqcloud.KIND_CDB_INSTANCE, FAKE_QCLOUD_REGION, mysqlId); mysql["Status"] != float64(fakeqcloud.CDB_STATUS_ISOLATED) {
+		t.Fatalf("mysql %s status=%v,want isolated", mysqlId, mysql["Status"])
+	}
+}
+
+//the vm and storage actions sleep between their calls, they are skipped in short mode
+func TestFakeQcloudVmAndStorage(t *testing.T) {
+	if testing.Short() {
+		t.Skip("vm and storage actions sleep between qcloud calls")
+	}
+	server, cleanup := setupFakeQcloud(t)
+	defer cleanup()
+
+	vpcId, subnetId := createFakeSubnet(t)
+	vmId := outputId(t, processFakeQcloud(t, "vm", "create", map[string]interface{}{
+		"guid": "vm-guid", "seed": "seed", "vpc_id": vpcId, "subnet_id": subnetId, "instance_name": "vm-test",
+		"instance_type": "S2.MEDIUM4", "image_id": fakeqcloud.PUBLIC_IMAGE_ID, "system_disk_size": 50,
+		"instance_charge_type": "POSTPAID_BY_HOUR", "data_disks": "CLOUD_PREMIUM:100", "key_ids": "skey-test",
+		"tags": "env=test", "internet_max_bandwidth_out": 5,
+	}))
+	vm, _ := server.GetResource(fakeqcloud.KIND_CVM_INSTANCE, FAKE_QCLOUD_REGION, vmId)
+	if vm["InstanceState"] != "RUNNING" {
+		t.Fatalf("vm %s state=%v,want RUNNING", vmId, vm["InstanceState"])
+	}
+	if len(vm["DataDisks"].([]interface{})) != 1 || len(vm["PublicIpAddresses"].([]interface{})) != 1 || len(vm["Tags"].([]interface{})) != 1 {
+		t.Fatalf("vm %s should have a data disk, a public ip and a tag, vm=%v", vmId, vm)
+	}
+
+	//qcloud only resizes stopped vms, the vm is stopped and started again
+	resized := processFakeQcloud(t, "vm", "resize", map[string]interface{}{"guid": "vm-guid", "id": vmId, "instance_type": "S2.LARGE8"})
+	if resized[0]["cpu"] != "4" || resized[0]["instance_state"] != "RUNNING" {
+		t.Fatalf("resized vm=%v,want 4 cpu and RUNNING", resized[0])
+	}
+	//the vm is started again only after qcloud reports the password reset succeeded
+	server.AsyncSteps = 2
+	reset := processFakeQcloud(t, "vm", "reset-password", map[string]interface{}{"guid": "vm-guid", "seed": "seed", "id": vmId})
+	server.AsyncSteps = 0
+	if reset[0]["password"] == "" || reset[0]["instance_state"] != "RUNNING" {
+		t.Fatalf("reset vm=%v,want new password and RUNNING", reset[0])
+	}
+	if vm, _ := server.GetResource(fakeqcloud.KIND_CVM_INSTANCE, FAKE_QCLOUD_REGION, vmId); vm["LatestOperationState"] != "SUCCESS" {
+		t.Fatalf("vm %s latest operation=%v,want SUCCESS", vmId, vm["LatestOperationState"])
+	}
+	//the vm is still RUNNING when the reinstall is submitted, it is waited for by the reinstall request
+	server.AsyncSteps = 2
+	reinstalled := processFakeQcloud(t, "vm", "reinstall", map[string]interface{}{"guid": "vm-guid", "seed": "seed", "id": vmId, "image_id": "img-new"})
+	server.AsyncSteps = 0
+	if vm, _ := server.GetResource(fakeqcloud.KIND_CVM_INSTANCE, FAKE_QCLOUD_REGION, vmId); vm["ImageId"] != "img-new" || vm["LatestOperationState"] != "SUCCESS" {
+		t.Fatalf("vm %s image=%v,latest operation=%v,want img-new and SUCCESS", vmId, vm["ImageId"], vm["LatestOperationState"])
+	}
+	if reinstalled[0]["instance_state"] != "RUNNING" {
+		t.Fatalf("reinstalled vm=%v,want RUNNING", reinstalled[0])
+	}
+
+	stopped := processFakeQcloud(t, "vm", "stop", map[string]interface{}{
+		"guid": "vm-guid", "id": vmId, "wait": "true", "stop_type": "SOFT_FIRST", "stopped_mode": "STOP_CHARGING",
+	})
+	if stopped[0]["instance_state"] != "STOPPED" {
+		t.Fatalf("stopped vm=%v,want STOPPED", stopped[0])
+	}
+	if params := server.Calls("StopInstances")[len(server.Calls("StopInstances"))-1].Params; params["StopType"] != "SOFT_FIRST" || params["StoppedMode"] != "STOP_CHARGING" {
+		t.Fatalf("StopInstances params=%v,want SOFT_FIRST and STOP_CHARGING", params)
+	}
+	if started := processFakeQcloud(t, "vm", "start", map[string]interface{}{"guid": "vm-guid", "id": vmId, "wait": "true"}); started[0]["instance_state"] != "RUNNING" {
+		t.Fatalf("started vm=%v,want RUNNING", started[0])
+	}
+	//the vm goes RUNNING->REBOOTING->RUNNING, it is waited for by the reboot request instead of the first RUNNING
+	server.AsyncSteps = 2
+	rebooted := processFakeQcloud(t, "vm", "reboot", map[string]interface{}{"guid": "vm-guid", "id": vmId, "wait": "true"})
+	server.AsyncSteps = 0
+	if rebooted[0]["instance_state"] != "RUNNING" || len(server.Calls("RebootInstances")) != 1 {
+		t.Fatalf("rebooted vm=%v,want RUNNING after one reboot", rebooted[0])
+	}
+	if vm, _ := server.GetResource(fakeqcloud.KIND_CVM_INSTANCE, FAKE_QCLOUD_REGION, vmId); vm["LatestOperation"] != "RebootInstances" || vm["LatestOperationState"] != "SUCCESS" {
+		t.Fatalf("vm %s latest operation=%v %v,want RebootInstances SUCCESS", vmId, vm["LatestOperation"], vm["LatestOperationState"])
+	}
+
+	converted := processFakeQcloud(t, "vm", "convert-to-prepaid", map[string]interface{}{"guid": "vm-guid", "id": vmId, "instance_charge_period": 1})
+	if converted[0]["instance_charge_type"] != "PREPAID" || converted[0]["renew_flag"] != "NOTIFY_AND_AUTO_RENEW" {
+		t.Fatalf("converted vm=%v,want PREPAID renewed automatically", converted[0])
+	}
+	renewed := processFakeQcloud(t, "vm", "renew", map[string]interface{}{"guid": "vm-guid", "id": vmId, "instance_charge_period": 2})
+	if renewed[0]["expired_time"].(string) <= converted[0]["expired_time"].(string) {
+		t.Fatalf("renewed vm expires at %v,want later than %v", renewed[0]["expired_time"], converted[0]["expired_time"])
+	}
+	//the same renewal without idempotency_key renews again, with idempotency_key it is replayed
+	renewedAgain := processFakeQcloud(t, "vm", "renew", map[string]interface{}{"guid": "vm-guid", "id": vmId, "instance_charge_period": 2})
+	if renewedAgain[0]["expired_time"].(string) <= renewed[0]["expired_time"].(string) {
+		t.Fatalf("renewed again vm expires at %v,want later than %v", renewedAgain[0]["expired_time"], renewed[0]["expired_time"])
+	}
+	for i := 0; i < 2; i++ {
+		processFakeQcloud(t, "vm", "renew", map[string]interface{}{"guid": "vm-guid", "id": vmId, "instance_charge_period": 2, "idempotency_key": "renew-1"})
+	}
+	if calls := len(server.Calls("RenewInstances")); calls != 3 {
+		t.Fatalf("RenewInstances called %d times,want 3", calls)
+	}
+	renewInput := func(key string) map[string]interface{} {
+		return map[string]interface{}{"guid": "vm-guid", "id": vmId, "instance_charge_period": 2, "idempotency_key": key}
+	}
+	if previews := dryRunFakeQcloud(t, "vm", "renew", renewInput("renew-1"), renewInput("renew-2")); len(previews) != 2 ||
+		previews[0].Operation != DRY_RUN_OPERATION_NONE || previews[1].Operation != DRY_RUN_OPERATION_MODIFY {
+		t.Fatalf("dry run renew previews=%v,want renew-1 replayed and renew-2 renewed", previews)
+	}
+	processFakeQcloud(t, "vm", "modify-renew-flag", map[string]interface{}{"guid": "vm-guid", "id": vmId, "renew_flag": "NOTIFY_AND_MANUAL_RENEW"})
+	if expiring := processFakeQcloud(t, "vm", "expiry-report", map[string]interface{}{"guid": "report-guid", "expire_within_days": 30}); len(expiring) != 0 {
+		t.Fatalf("expiry report within 30 days got %v,want none", expiring)
+	}
+	expiring := processFakeQcloud(t, "vm", "expiry-report", map[string]interface{}{"guid": "report-guid", "expire_within_days": 250})
+	if len(expiring) != 1 || expiring[0]["id"] != vmId || expiring[0]["renew_flag"] != "NOTIFY_AND_MANUAL_RENEW" {
+		t.Fatalf("expiry report within 250 days got %v,want %s", expiring, vmId)
+	}
+
+	created := processFakeQcloud(t, "storage", "create", map[string]interface{}{
+		"guid": "storage-guid", "disk_type": "CLOUD_PREMIUM", "disk_size": 50, "disk_name": "storage-test",
+		"disk_charge_type": "POSTPAID_BY_HOUR", "instance_id": vmId, "delete_with_instance": "false",
+	})
+	storageId := outputId(t, created)
+	//the data disk created with the vm is /dev/vdb
+	if created[0]["device_name_hint"] != "/dev/vdc" {
+		t.Fatalf("created storage=%v,want device hint /dev/vdc", created[0])
+	}
+	if disk, _ := server.GetResource(fakeqcloud.KIND_CBS_DISK, FAKE_QCLOUD_REGION, storageId); disk["InstanceId"] != vmId || disk["DeleteWithInstance"] != false {
+		t.Fatalf("storage %s should be attached to %s and kept after the vm is deleted, disk=%v", storageId, vmId, disk)
+	}
+	resizedDisk := processFakeQcloud(t, "storage", "resize", map[string]interface{}{"guid": "storage-guid", "id": storageId, "disk_size": 100})
+	if resizedDisk[0]["disk_size"] != "100" || resizedDisk[0]["disk_state"] != "ATTACHED" {
+		t.Fatalf("resized storage=%v,want 100GB and ATTACHED", resizedDisk[0])
+	}
+	processFakeQcloud(t, "storage", "resize", map[string]interface{}{"guid": "storage-guid", "id": storageId, "disk_size": 100})
+	if len(server.Calls("ResizeDisk")) != 1 {
+		t.Fatalf("storage already in the size should not be resized again, calls=%d", len(server.Calls("ResizeDisk")))
+	}
+	if msg := processFakeQcloudError(t, "storage", "resize", map[string]interface{}{"guid": "storage-guid", "id": storageId, "disk_size": 50}); !strings.Contains(msg, "can't be shrunk") {
+		t.Fatalf("shrink storage got error %q", msg)
+	}
+	detached := processFakeQcloud(t, "storage", "detach", map[string]interface{}{"guid": "storage-guid", "id": storageId})
+	if detached[0]["disk_state"] != "UNATTACHED" || detached[0]["instance_id"] != nil {
+		t.Fatalf("detached storage=%v,want UNATTACHED", detached[0])
+	}
+	attached := processFakeQcloud(t, "storage", "attach", map[string]interface{}{"guid": "storage-guid", "id": storageId, "instance_id": vmId})
+	if attached[0]["disk_state"] != "ATTACHED" || attached[0]["instance_id"] != vmId {
+		t.Fatalf("attached storage=%v,want ATTACHED to %s", attached[0], vmId)
+	}
+	if msg := processFakeQcloudError(t, "storage", "attach", map[string]interface{}{"guid": "storage-guid", "id": storageId, "instance_id": "ins-other"}); !strings.Contains(msg, "attached to another instance") {
+		t.Fatalf("attach storage to another vm got error %q", msg)
+	}
+	processFakeQcloud(t, "storage", "terminate", map[string]interface{}{"guid": "storage-guid", "id": storageId})
+	if _, found := server.GetResource(fakeqcloud.KIND_CBS_DISK, FAKE_QCLOUD_REGION, storageId); found {
+		t.Fatalf("storage %s should be terminated", storageId)
+	}
+
+	processFakeQcloud(t, "vm", "terminate", map[string]interface{}{"guid": "vm-guid", "id": vmId})
+	if _, found := server.GetResource(fakeqcloud.KIND_CVM_INSTANCE, FAKE_QCLOUD_REGION, vmId); found {
+		t.Fatalf("vm %s should be terminated", vmId)
+	}
+}
+
+func TestFakeQcloudStorageBatchCreate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("vm and storage actions sleep between qcloud calls")
+	}
+	server, cleanup := setupFakeQcloud(t)
+	defer cleanup()
+
+	vpcId, subnetId := createFakeSubnet(t)
+	vmIds := []string{}
+	for i := 0; i < 2; i++ {
+		vmIds = append(vmIds, outputId(t, processFakeQcloud(t, "vm", "create", map[string]interface{}{
+			"guid": fmt.Sprintf("vm-guid-%d", i), "seed": "seed", "vpc_id": vpcId, "subnet_id": subnetId, "instance_name": "vm-test",
+			"instance_type": "S2.MEDIUM4", "image_id": fakeqcloud.PUBLIC_IMAGE_ID, "system_disk_size": 50, "instance_charge_type": "POSTPAID_BY_HOUR",
+		})))
+	}
+
+	input := map[string]interface{}{
+		"guid": "storage-guid", "disk_type": "CLOUD_SSD", "disk_size": 100, "disk_name": "storage-test", "disk_charge_type": "POSTPAID_BY_HOUR",
+		"disk_count": 2, "instance_ids": strings.Join(vmIds, ","), "encrypt": "true", "throughput_performance": 100,
+		"project_id": 1, "tags": "app=db",
+	}
+	outputs := processFakeQcloud(t, "storage", "create", input)
+	if calls := server.Calls("CreateDisks"); len(calls) != 1 || calls[0].Params["DiskCount"] != float64(4) || calls[0].Params["ThroughputPerformance"] != float64(100) {
+		t.Fatalf("want one CreateDisks call of 4 disks with throughput, calls=%v", calls)
+	}
+	if len(outputs) != 4 {
+		t.Fatalf("created %d storages,want 4, outputs=%v", len(outputs), outputs)
+	}
+	for i, output := range outputs {
+		wantInstanceId, wantDevice := vmIds[i/2], []string{"/dev/vdb", "/dev/vdc"}[i%2]
+		if output["instance_id"] != wantInstanceId || output["device_name_hint"] != wantDevice || output["disk_state"] != "ATTACHED" {
+			t.Fatalf("storage %d=%v,want %s on %s", i, output, wantDevice, wantInstanceId)
+		}
+		disk, _ := server.GetResource(fakeqcloud.KIND_CBS_DISK, FAKE_QCLOUD_REGION, output["id"].(string))
+		if disk["Encrypt"] != true || disk["Placement"].(map[string]interface{})["ProjectId"] != float64(1) || len(disk["Tags"].([]interface{})) != 1 {
+			t.Fatalf("storage %d should be encrypted in project 1 with a tag, disk=%v", i, disk)
+		}
+	}
+
+	//a retry replays the outputs without creating any disk
+	replays := processFakeQcloud(t, "storage", "create", input)
+	if calls := len(server.Calls("CreateDisks")); calls != 1 || len(replays) != 4 || replays[3]["id"] != outputs[3]["id"] {
+		t.Fatalf("retry called CreateDisks %d times with outputs %v,want the former outputs", calls, replays)
+	}
+	if msg := processFakeQcloudError(t, "storage", "create", map[string]interface{}{"guid": "storage-guid", "instance_ids": vmIds[0] + "," + vmIds[0]}); !strings.Contains(msg, "listed more than once") {
+		t.Fatalf("create storages on a duplicated instance got error %q", msg)
+	}
+}
+
+func TestFakeQcloudStorageOrphans(t *testing.T) {
+	if testing.Short() {
+		t.Skip("vm and storage actions sleep between qcloud calls")
+	}
+	server, cleanup := setupFakeQcloud(t)
+	defer cleanup()
+
+	vpcId, subnetId := createFakeSubnet(t)
+	vmId := outputId(t, processFakeQcloud(t, "vm", "create", map[string]interface{}{
+		"guid": "vm-guid", "seed": "seed", "vpc_id": vpcId, "subnet_id": subnetId, "instance_name": "vm-test",
+		"instance_type": "S2.MEDIUM4", "image_id": fakeqcloud.PUBLIC_IMAGE_ID, "system_disk_size": 50, "instance_charge_type": "POSTPAID_BY_HOUR",
+	}))
+	keptId := outputId(t, processFakeQcloud(t, "storage", "create", map[string]interface{}{
+		"guid": "storage-guid", "disk_type": "CLOUD_PREMIUM", "disk_size": 50, "disk_name": "kept", "disk_charge_type": "POSTPAID_BY_HOUR",
+		"instance_id": vmId, "delete_with_instance": "false",
+	}))
+	for _, region := range []string{FAKE_QCLOUD_REGION, "ap-shanghai"} {
+		server.AddResource(fakeqcloud.KIND_CBS_DISK, region, "disk-old-"+region, map[string]interface{}{
+			"DiskId": "disk-old-" + region, "DiskName": "old", "DiskType": "CLOUD_BASIC", "DiskSize": 20, "DiskUsage": "DATA_DISK",
+			"DiskChargeType": "POSTPAID_BY_HOUR", "DiskState": "UNATTACHED", "InstanceId": "", "LastAttachInsId": "ins-gone",
+			"CreateTime": time.Now().AddDate(0, 0, -100).In(storageTimeLocation).Format(STORAGE_TIME_LAYOUT),
+		})
+	}
+	//an old disk detached lately and an old prepaid disk are not deleted
+	server.AddResource(fakeqcloud.KIND_CBS_DISK, FAKE_QCLOUD_REGION, "disk-detached", map[string]interface{}{
+		"DiskId": "disk-detached", "DiskName": "detached", "DiskType": "CLOUD_BASIC", "DiskSize": 20, "DiskUsage": "DATA_DISK",
+		"DiskChargeType": "POSTPAID_BY_HOUR", "DiskState": "UNATTACHED", "InstanceId": "", "LastAttachInsId": "ins-gone",
+		"CreateTime": time.Now().AddDate(0, 0, -101).In(storageTimeLocation).Format(STORAGE_TIME_LAYOUT),
+		"operationLogs": []map[string]interface{}{{
+			"DiskId": "disk-detached", "Operation": STORAGE_OPERATION_DETACH, "OperationState": STORAGE_OPERATION_STATE_SUCCESS,
+			"StartTime": time.Now().AddDate(0, 0, -5).In(storageTimeLocation).Format(STORAGE_TIME_LAYOUT),
+		}},
+	})
+	server.AddResource(fakeqcloud.KIND_CBS_DISK, FAKE_QCLOUD_REGION, "disk-prepaid", map[string]interface{}{
+		"DiskId": "disk-prepaid", "DiskName": "prepaid", "DiskType": "CLOUD_BASIC", "DiskSize": 20, "DiskUsage": "DATA_DISK",
+		"DiskChargeType": CHARGE_TYPE_PREPAID, "DiskState": "UNATTACHED", "InstanceId": "", "LastAttachInsId": "ins-gone",
+		"CreateTime": time.Now().AddDate(0, 0, -102).In(storageTimeLocation).Format(STORAGE_TIME_LAYOUT),
+	})
+
+	//the disk kept after the vm is terminated becomes an orphan
+	processFakeQcloud(t, "vm", "terminate", map[string]interface{}{"guid": "vm-guid", "id": vmId})
+	orphans := processFakeQcloud(t, "storage", "find-orphans", map[string]interface{}{"guid": "orphan-guid"})
+	if len(orphans) != 4 || orphans[0]["id"] != "disk-prepaid" || orphans[1]["id"] != "disk-detached" || orphans[1]["unattached_days"] != "5" ||
+		orphans[2]["id"] != "disk-old-"+FAKE_QCLOUD_REGION || orphans[2]["unattached_days"] != "100" || orphans[3]["id"] != keptId {
+		t.Fatalf("orphans=%v,want the prepaid, detached and old disks and %s", orphans, keptId)
+	}
+	if orphans[3]["last_instance_id"] != vmId || orphans[3]["unattached_days"] != "0" || orphans[3]["deleted"] != "false" {
+		t.Fatalf("orphan %v should be last attached to %s", orphans[3], vmId)
+	}
+
+	input := map[string]interface{}{"guid": "orphan-guid", "regions": FAKE_QCLOUD_REGION + ",ap-shanghai", "delete_older_than_days": 30}
+	input["provider_params"] = fakeqcloud.ProviderParams(FAKE_QCLOUD_REGION, FAKE_QCLOUD_ZONE)
+	body, _ := json.Marshal(map[string]interface{}{"inputs": []map[string]interface{}{input}})
+	response, err := Process(&PluginRequest{
+		Version:      DEFAULT_API_VERSION,
+		ProviderName: DEFAULT_PROVIDER_NAME,
+		Name:         "storage",
+		Action:       "find-orphans",
+		Parameters:   bytes.NewReader(body),
+		DryRun:       true,
+	})
+	if err != nil {
+		t.Fatalf("dry run find-orphans meet err=%v", err)
+	}
+	previews := response.Results.(*DryRunOutputs).Outputs
+	if len(previews) != 5 || previews[0].Operation != DRY_RUN_OPERATION_NONE || previews[1].Operation != DRY_RUN_OPERATION_NONE ||
+		previews[2].Operation != DRY_RUN_OPERATION_DELETE || previews[3].Operation != DRY_RUN_OPERATION_NONE || previews[4].Id != "disk-old-ap-shanghai" {
+		t.Fatalf("dry run previews=%v,want the old disks deleted and the others kept", previews)
+	}
+	if len(server.Calls("TerminateDisks")) != 0 {
+		t.Fatalf("dry run should not terminate any disk")
+	}
+
+	deleted := processFakeQcloud(t, "storage", "find-orphans", input)
+	if len(deleted) != 5 || deleted[0]["deleted"] != "false" || deleted[1]["deleted"] != "false" || deleted[2]["deleted"] != "true" ||
+		deleted[3]["deleted"] != "false" || deleted[4]["region"] != "ap-shanghai" || deleted[4]["deleted"] != "true" {
+		t.Fatalf("find-orphans outputs=%v,want the old disks of both regions deleted", deleted)
+	}
+	for _, region := range []string{FAKE_QCLOUD_REGION, "ap-shanghai"} {
+		if _, found := server.GetResource(fakeqcloud.KIND_CBS_DISK, region, "disk-old-"+region); found {
+			t.Fatalf("old disk of region %s should be terminated", region)
+		}
+	}
+	for _, id := range []string{keptId, "disk-detached", "disk-prepaid"} {
+		if _, found := server.GetResource(fakeqcloud.KIND_CBS_DISK, FAKE_QCLOUD_REGION, id); !found {
+			t.Fatalf("storage %s should not be terminated", id)
+		}
+	}
+
+	//prepaid disks are deleted only when asked to
+	input["include_prepaid"] = "true"
+	deleted = processFakeQcloud(t, "storage", "find-orphans", input)
+	if len(deleted) != 3 || deleted[0]["id"] != "disk-prepaid" || deleted[0]["deleted"] != "true" || deleted[1]["deleted"] != "false" {
+		t.Fatalf("find-orphans outputs=%v,want the prepaid disk deleted", deleted)
+	}
+	if _, found := server.GetResource(fakeqcloud.KIND_CBS_DISK, FAKE_QCLOUD_REGION, "disk-prepaid"); found {
+		t.Fatalf("prepaid disk should be terminated with include_prepaid")
+	}
+}
+
+func TestFakeQcloudVmBatchCreate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("vm actions sleep between qcloud calls")
+	}
+	server, cleanup := setupFakeQcloud(t)
+	defer cleanup()
+
+	vpcId, subnetId := createFakeSubnet(t)
+	inputs := func() []map[string]interface{} {
+		vms := []map[string]interface{}{}
+		for i, instanceType := range []string{"S2.MEDIUM4", "S2.MEDIUM4", "S2.LARGE8", "S2.MEDIUM4"} {
+			vms = append(vms, map[string]interface{}{
+				"guid": fmt.Sprintf("vm-guid-%d", i), "seed": "seed", "vpc_id": vpcId, "subnet_id": subnetId,
+				"instance_name": "vm-test", "instance_type": instanceType, "image_id": fakeqcloud.PUBLIC_IMAGE_ID,
+				"system_disk_size": 50, "instance_charge_type": "POSTPAID_BY_HOUR", "tags": "batch=test",
+				"key_ids": "skey-test",
+			})
+		}
+		return vms
+	}
+
+	outputs := processFakeQcloud(t, "vm", "create", inputs()...)
+	if calls := len(server.Calls("RunInstances")); calls != 2 {
+		t.Fatalf("RunInstances called %d times,want 2", calls)
+	}
+	ids := map[string]bool{}
+	for i, output := range outputs {
+		if output["guid"] != fmt.Sprintf("vm-guid-%d", i) {
+			t.Fatalf("output %d guid=%v,want vm-guid-%d", i, output["guid"], i)
+		}
+		ids[output["id"].(string)] = true
+	}
+	if len(ids) != 4 || outputs[2]["cpu"] != "4" {
+		t.Fatalf("want 4 different vms with the third one of S2.LARGE8, outputs=%v", outputs)
+	}
+
+	described := processFakeQcloud(t, "vm", "describe", map[string]interface{}{"guid": "describe-guid", "tags": "batch=test"})
+	if len(described) != len(outputs) {
+		t.Fatalf("describe by tag got %d vms,want %d", len(described), len(outputs))
+	}
+	describedTypes := map[interface{}]interface{}{}
+	for _, output := range described {
+		describedTypes[output["id"]] = output["instance_type"]
+	}
+	for i, input := range inputs() {
+		if describedTypes[outputs[i]["id"]] != input["instance_type"] {
+			t.Fatalf("describe by tag got %v,want %s with type %v", described, outputs[i]["id"], input["instance_type"])
+		}
+	}
+	described = processFakeQcloud(t, "vm", "describe", map[string]interface{}{"guid": "describe-guid", "instance_private_ip": outputs[1]["instance_private_ip"]})
+	if len(described) != 1 || described[0]["id"] != outputs[1]["id"] {
+		t.Fatalf("describe by private ip got %v,want %v", described, outputs[1]["id"])
+	}
+
+	//a retry replays the outputs without creating any vm
+	replays := processFakeQcloud(t, "vm", "create", inputs()...)
+	if calls := len(server.Calls("RunInstances")); calls != 2 {
+		t.Fatalf("RunInstances called %d times after retry,want 2", calls)
+	}
+	for i := range replays {
+		if replays[i]["id"] != outputs[i]["id"] {
+			t.Fatalf("replay %d id=%v,want %v", i, replays[i]["id"], outputs[i]["id"])
+		}
+	}
+
+	//vms with generated passwords are launched one by one, each with its own password
+	passwordInputs := inputs()[:2]
+	for i, input := range passwordInputs {
+		input["guid"] = fmt.Sprintf("password-vm-guid-%d", i)
+		delete(input, "key_ids")
+	}
+	passwordOutputs := processFakeQcloud(t, "vm", "create", passwordInputs...)
+	if calls := len(server.Calls("RunInstances")); calls != 4 {
+		t.Fatalf("RunInstances called %d times after vms with passwords,want 4", calls)
+	}
+	passwords := map[interface{}]bool{}
+	for i, output := range passwordOutputs {
+		password := decryptFakeVmPassword(t, output, "seed")
+		if vm, _ := server.GetResource(fakeqcloud.KIND_CVM_INSTANCE, FAKE_QCLOUD_REGION, output["id"].(string)); vm["password"] != password {
+			t.Fatalf("vm %d password=%s does not match the instance password=%v", i, password, vm["password"])
+		}
+		passwords[password] = true
+	}
+	if len(passwords) != len(passwordOutputs) {
+		t.Fatalf("vms with generated passwords should not share one,outputs=%v", passwordOutputs)
+	}
+}
+
+func TestFakeQcloudVmCreateResume(t *testing.T) {
+	server, cleanup := setupFakeQcloud(t)
+	defer cleanup()
+
+	vpcId, subnetId := createFakeSubnet(t)
+	input := func() map[string]interface{} {
+		return map[string]interface{}{
+			"guid": "vm-guid", "seed": "seed", "vpc_id": vpcId, "subnet_id": subnetId, "instance_name": "vm-test",
+			"instance_type": "S2.MEDIUM4", "image_id": fakeqcloud.PUBLIC_IMAGE_ID, "system_disk_size": 50,
+			"instance_charge_type": "POSTPAID_BY_HOUR",
+		}
+	}
+
+	//the vm is launched but stays PENDING longer than the wait
+	server.AsyncSteps = VM_CREATE_WAIT_TIMEOUT/WAIT_POLL_SECONDS + 5
+	if err := processFakeQcloudError(t, "vm", "create", input()); !strings.Contains(err, VM_WAIT_STATE_TIMEOUT_ERROR.Error()) {
+		t.Fatalf("err=%s,want wait timeout", err)
+	}
+
+	//the retry waits for the same vm and returns the password it was launched with
+	outputs := processFakeQcloud(t, "vm", "create", input())
+	if calls := len(server.Calls("RunInstances")); calls != 1 {
+		t.Fatalf("RunInstances called %d times,want 1", calls)
+	}
+	vm, _ := server.GetResource(fakeqcloud.KIND_CVM_INSTANCE, FAKE_QCLOUD_REGION, outputs[0]["id"].(string))
+	if vm["InstanceState"] != "RUNNING" || vm["password"] != decryptFakeVmPassword(t, outputs[0], "seed") {
+		t.Fatalf("vm=%v should be RUNNING with the password of output=%v", vm, outputs[0])
+	}
+}
+
+func TestFakeQcloudStorageCreateRetry(t *testing.T) {
+	server, cleanup := setupFakeQcloud(t)
+	defer cleanup()
+
+	vpcId, subnetId := createFakeSubnet(t)
+	vmId := outputId(t, processFakeQcloud(t, "vm", "create", map[string]interface{}{
+		"guid": "vm-guid", "seed": "seed", "vpc_id": vpcId, "subnet_id": subnetId, "instance_name": "vm-test",
+		"instance_type": "S2.MEDIUM4", "image_id": fakeqcloud.PUBLIC_IMAGE_ID, "system_disk_size": 50,
+		"instance_charge_type": "POSTPAID_BY_HOUR", "key_ids": "skey-test",
+	}))
+	input := func() map[string]interface{} {
+		return map[string]interface{}{
+			"guid": "storage-guid", "disk_type": "CLOUD_PREMIUM", "disk_size": 100, "disk_name": "data",
+			"disk_charge_type": "POSTPAID_BY_HOUR", "instance_id": vmId, "disk_count": 2,
+		}
+	}
+
+	//the disks are created and attached, but stay ATTACHING longer than the wait
+	server.AsyncSteps = STORAGE_WAIT_TIMEOUT/WAIT_POLL_SECONDS + 5
+	if err := processFakeQcloudError(t, "storage", "create", input()); !strings.Contains(err, "timeout") {
+		t.Fatalf("err=%s,want wait timeout", err)
+	}
+
+	//the retry gets the same disks by the client token instead of buying others
+	outputs := processFakeQcloud(t, "storage", "create", input())
+	createCalls := server.Calls("CreateDisks")
+	if len(createCalls) != 2 || createCalls[0].Params["ClientToken"] == nil || createCalls[0].Params["ClientToken"] != createCalls[1].Params["ClientToken"] {
+		t.Fatalf("CreateDisks calls=%v,want 2 calls with the same client token", createCalls)
+	}
+	if len(outputs) != 2 || len(server.Calls("AttachDisks")) != 1 {
+		t.Fatalf("outputs=%v,attach calls=%d,want 2 disks attached once", outputs, len(server.Calls("AttachDisks")))
+	}
+	vm, _ := server.GetResource(fakeqcloud.KIND_CVM_INSTANCE, FAKE_QCLOUD_REGION, vmId)
+	if disks := vm["DataDisks"].([]interface{}); len(disks) != 2 {
+		t.Fatalf("vm has %d data disks,want 2", len(disks))
+	}
+}
+
+func TestFakeQcloudImage(t *testing.T) {
+	if testing.Short() {
+		t.Skip("image actions sleep between qcloud calls")
+	}
+	server, cleanup := setupFakeQcloud(t)
+	defer cleanup()
+
+	vpcId, subnetId := createFakeSubnet(t)
+	vmId := outputId(t, processFakeQcloud(t, "vm", "create", map[string]interface{}{
+		"guid": "vm-guid", "seed": "seed", "vpc_id": vpcId, "subnet_id": subnetId, "instance_name": "vm-test",
+		"instance_type": "S2.MEDIUM4", "image_id": fakeqcloud.PUBLIC_IMAGE_ID, "system_disk_size": 50, "instance_charge_type": "POSTPAID_BY_HOUR",
+	}))
+
+	created := processFakeQcloud(t, "image", "create", map[string]interface{}{
+		"guid": "image-guid", "image_name": "golden", "instance_id": vmId, "force_poweroff": "true",
+	})
+	imageId := outputId(t, created)
+	if created[0]["image_state"] != "NORMAL" {
+		t.Fatalf("created image=%v,want NORMAL", created[0])
+	}
+	//a retry finds the image by its name instead of making another one
+	if retried := processFakeQcloud(t, "image", "create", map[string]interface{}{
+		"guid": "image-guid", "image_name": "golden", "instance_id": vmId, "force_poweroff": "true",
+	}); outputId(t, retried) != imageId || len(server.Calls("CreateImage")) != 1 {
+		t.Fatalf("retried image=%v,want %s created once", retried, imageId)
+	}
+
+	copied := processFakeQcloud(t, "image", "copy", map[string]interface{}{"guid": "image-guid", "id": imageId, "destination_regions": "ap-shanghai"})
+	copiedId, _ := copied[0]["destination_image_ids"].(string)
+	if image, found := server.GetResource(fakeqcloud.KIND_CVM_IMAGE, "ap-shanghai", copiedId); !found || image["ImageName"] != "golden" {
+		t.Fatalf("copied image %s of %v not found in ap-shanghai", copiedId, copied[0])
+	}
+	processFakeQcloud(t, "image", "copy", map[string]interface{}{"guid": "image-guid", "id": imageId, "destination_regions": "ap-shanghai"})
+	if calls := len(server.Calls("SyncImages")); calls != 1 {
+		t.Fatalf("SyncImages called %d times,want the copy made once", calls)
+	}
+
+	processFakeQcloud(t, "image", "share", map[string]interface{}{"guid": "image-guid", "id": imageId, "account_ids": "100001"})
+	if image, _ := server.GetResource(fakeqcloud.KIND_CVM_IMAGE, FAKE_QCLOUD_REGION, imageId); len(image["sharedAccounts"].([]interface{})) != 1 {
+		t.Fatalf("image %s should be shared with one account, image=%v", imageId, image)
+	}
+	previews := dryRunFakeQcloud(t, "image", "share", map[string]interface{}{"guid": "image-guid", "id": imageId, "account_ids": "100001,100002"})
+	if len(previews) != 1 || previews[0].Operation != DRY_RUN_OPERATION_MODIFY || previews[0].Detail != "share image with accounts[100002]" {
+		t.Fatalf("dry run share previews=%v,want only 100002 shared", previews)
+	}
+	if described := processFakeQcloud(t, "image", "describe", map[string]interface{}{"guid": "image-guid"}); len(described) != 1 || described[0]["id"] != imageId {
+		t.Fatalf("described images=%v,want %s", described, imageId)
+	}
+
+	//templates name the image instead of hard coding its id in each region
+	processFakeQcloud(t, "vm", "reinstall", map[string]interface{}{"guid": "vm-guid", "seed": "seed", "id": vmId, "image_id": "golden"})
+	if vm, _ := server.GetResource(fakeqcloud.KIND_CVM_INSTANCE, FAKE_QCLOUD_REGION, vmId); vm["ImageId"] != imageId {
+		t.Fatalf("vm %s image=%v,want %s", vmId, vm["ImageId"], imageId)
+	}
+
+	processFakeQcloud(t, "image", "terminate", map[string]interface{}{"guid": "image-guid", "id": imageId})
+	if _, found := server.GetResource(fakeqcloud.KIND_CVM_IMAGE, FAKE_QCLOUD_REGION, imageId); found {
+		t.Fatalf("image %s should be deleted", imageId)
+	}
+}
+
+func TestFakeQcloudPlacementGroup(t *testing.T) {
+	if testing.Short() {
+		t.Skip("vm actions sleep between qcloud calls")
+	}
+	server, cleanup := setupFakeQcloud(t)
+	defer cleanup()
+
+	vpcId, subnetId := createFakeSubnet(t)
+	otherZone := FAKE_QCLOUD_REGION + "-2"
+	server.AddResource(fakeqcloud.KIND_SUBNET, FAKE_QCLOUD_REGION, "subnet-zone2", map[string]interface{}{
+		"SubnetId": "subnet-zone2", "VpcId": vpcId, "CidrBlock": "10.0.2.0/24", "Zone": otherZone,
+	})
+	groupId := outputId(t, processFakeQcloud(t, "placement-group", "create", map[string]interface{}{
+		"guid": "group-guid", "name": "ha", "type": "HOST",
+	}))
+	//the same input replays the group created before
+	previews := dryRunFakeQcloud(t, "placement-group", "create", map[string]interface{}{"guid": "group-guid", "name": "ha", "type": "HOST"})
+	if len(previews) != 1 || previews[0].Operation != DRY_RUN_OPERATION_NONE {
+		t.Fatalf("dry run create previews=%v,want the group created before", previews)
+	}
+
+	inputs := []map[string]interface{}{}
+	for i := 0; i < 4; i++ {
+		inputs = append(inputs, map[string]interface{}{
+			"guid": fmt.Sprintf("vm-guid-%d", i), "seed": "seed", "vpc_id": vpcId, "instance_name": "vm-ha",
+			"instance_type": "S2.MEDIUM4", "image_id": fakeqcloud.PUBLIC_IMAGE_ID, "system_disk_size": 50, "instance_charge_type": "POSTPAID_BY_HOUR",
+			"zones": FAKE_QCLOUD_ZONE + "," + otherZone, "subnet_id": subnetId + ",subnet-zone2", "placement_group_id": groupId,
+			"key_ids": "skey-test",
+		})
+	}
+	created := processFakeQcloud(t, "vm", "create", inputs...)
+	if calls := len(server.Calls("RunInstances")); calls != 2 {
+		t.Fatalf("RunInstances called %d times,want one call per zone", calls)
+	}
+	for i, output := range created {
+		wantZone, wantSubnet := FAKE_QCLOUD_ZONE, subnetId
+		if i%2 == 1 {
+			wantZone, wantSubnet = otherZone, "subnet-zone2"
+		}
+		vm, _ := server.GetResource(fakeqcloud.KIND_CVM_INSTANCE, FAKE_QCLOUD_REGION, output["id"].(string))
+		zone := vm["Placement"].(map[string]interface{})["Zone"]
+		subnet := vm["VirtualPrivateCloud"].(map[string]interface{})["SubnetId"]
+		if zone != wantZone || subnet != wantSubnet {
+			t.Fatalf("vm %d in zone %v subnet %v,want zone %s subnet %s", i, zone, subnet, wantZone, wantSubnet)
+		}
+	}
+
+	described := processFakeQcloud(t, "placement-group", "describe", map[string]interface{}{"guid": "group-guid", "id": groupId})
+	if len(described) != 1 || described[0]["current_num"] != "4" {
+		t.Fatalf("described groups=%v,want 4 vms in %s", described, groupId)
+	}
+
+	for _, output := range created {
+		processFakeQcloud(t, "vm", "terminate", map[string]interface{}{"guid": output["guid"], "id": output["id"]})
+	}
+	processFakeQcloud(t, "placement-group", "terminate", map[string]interface{}{"guid": "group-guid", "id": groupId})
+	if _, found := server.GetResource(fakeqcloud.KIND_CVM_DISASTER_RECOVER_GROUP, FAKE_QCLOUD_REGION, groupId); found {
+		t.Fatalf("placement group %s should be deleted", groupId)
+	}
+}
+
+func TestFakeQcloudVmAvailability(t *testing.T) {
+	if testing.Short() {
+		t.Skip("vm actions sleep between qcloud calls")
+	}
+	server, cleanup := setupFakeQcloud(t)
+	defer cleanup()
+
+	vpcId, subnetId := createFakeSubnet(t)
+	otherZone := FAKE_QCLOUD_REGION + "-2"
+	server.AddResource(fakeqcloud.KIND_SUBNET, FAKE_QCLOUD_REGION, "subnet-zone2", map[string]interface{}{
+		"SubnetId": "subnet-zone2", "VpcId": vpcId, "CidrBlock": "10.0.2.0/24", "Zone": otherZone,
+	})
+	server.SoldOut(FAKE_QCLOUD_ZONE, "S2.MEDIUM4")
+
+	quotas := processFakeQcloud(t, "vm", "check-quota", map[string]interface{}{
+		"guid": "quota-guid", "instance_type": "S2.MEDIUM4", "instance_charge_type": "POSTPAID_BY_HOUR",
+	})
+	if len(quotas) != 3 {
+		t.Fatalf("got %d quotas,want one for each zone", len(quotas))
+	}
+	for _, quota := range quotas {
+		if wantSoldOut := fmt.Sprint(quota["zone"] == FAKE_QCLOUD_ZONE); quota["sold_out"] != wantSoldOut || quota["cpu"] != "2" {
+			t.Fatalf("quota=%v,want sold_out %s", quota, wantSoldOut)
+		}
+	}
+
+	vmInput := func() map[string]interface{} {
+		return map[string]interface{}{
+			"guid": "vm-guid", "seed": "seed", "vpc_id": vpcId, "subnet_id": subnetId, "instance_name": "vm-test",
+			"instance_type": "S2.MEDIUM4", "image_id": fakeqcloud.PUBLIC_IMAGE_ID, "system_disk_size": 50,
+		}
+	}
+	if err := processFakeQcloudError(t, "vm", "create", vmInput()); !strings.Contains(err, "is not on sale in zone("+FAKE_QCLOUD_ZONE+")") {
+		t.Fatalf("create sold out vm err=%s", err)
+	}
+	input := vmInput()
+	input["instance_type"], input["image_id"] = "S2.LARGE8", "img-gone"
+	if err := processFakeQcloudError(t, "vm", "create", input); !strings.Contains(err, "image(img-gone) not found") {
+		t.Fatalf("create vm of missing image err=%s", err)
+	}
+	if calls := len(server.Calls("RunInstances")); calls != 0 {
+		t.Fatalf("RunInstances called %d times,want the inputs checked first", calls)
+	}
+
+	input = vmInput()
+	input["zone_fallback"] = "true"
+	vmId := outputId(t, processFakeQcloud(t, "vm", "create", input))
+	vm, _ := server.GetResource(fakeqcloud.KIND_CVM_INSTANCE, FAKE_QCLOUD_REGION, vmId)
+	if zone := vm["Placement"].(map[string]interface{})["Zone"]; zone != otherZone || vm["VirtualPrivateCloud"].(map[string]interface{})["SubnetId"] != "subnet-zone2" {
+		t.Fatalf("vm in zone %v subnet %v,want zone %s subnet subnet-zone2", zone, vm["VirtualPrivateCloud"], otherZone)
+	}
+
+	//the replay of a done key returns the vm created even after the instance type is sold out everywhere
+	for i := 1; i <= 3; i++ {
+		server.SoldOut(fmt.Sprintf("%s-%d", FAKE_QCLOUD_REGION, i), "S2.MEDIUM4")
+	}
+	quotaCalls, imageCalls := len(server.Calls("DescribeZoneInstanceConfigInfos")), len(server.Calls("DescribeImages"))
+	input = vmInput()
+	input["zone_fallback"] = "true"
+	if replayed := outputId(t, processFakeQcloud(t, "vm", "create", input)); replayed != vmId || len(server.Calls("RunInstances")) != 1 {
+		t.Fatalf("replayed vm %s,want %s created once", replayed, vmId)
+	}
+	if len(server.Calls("DescribeZoneInstanceConfigInfos")) != quotaCalls || len(server.Calls("DescribeImages")) != imageCalls {
+		t.Fatalf("replay of a done key should not look up quotas or images")
+	}
+}
+
+func TestFakeQcloudSnapshot(t *testing.T) {
+	if testing.Short() {
+		t.Skip("storage and snapshot actions sleep between qcloud calls")
+	}
+	server, cleanup := setupFakeQcloud(t)
+	defer cleanup()
+
+	diskId := outputId(t, processFakeQcloud(t, "storage", "create", map[string]interface{}{
+		"guid": "storage-guid", "disk_type": "CLOUD_PREMIUM", "disk_size": 50, "disk_name": "data", "disk_charge_type": "POSTPAID_BY_HOUR",
+	}))
+	created := processFakeQcloud(t, "snapshot", "create", map[string]interface{}{"guid": "snapshot-guid", "disk_id": diskId, "name": "backup"})
+	snapshotId := outputId(t, created)
+	if created[0]["state"] != "NORMAL" || created[0]["disk_size"] != "50" {
+		t.Fatalf("created snapshot=%v,want NORMAL of 50GB", created[0])
+	}
+
+	restored := processFakeQcloud(t, "storage", "create", map[string]interface{}{
+		"guid": "restored-guid", "disk_type": "CLOUD_PREMIUM", "disk_name": "restored", "disk_charge_type": "POSTPAID_BY_HOUR", "snapshot_id": snapshotId,
+	})
+	if disk, _ := server.GetResource(fakeqcloud.KIND_CBS_DISK, FAKE_QCLOUD_REGION, outputId(t, restored)); disk["SnapshotId"] != snapshotId || restored[0]["disk_size"] != "50" {
+		t.Fatalf("restored disk=%v,want 50GB from %s", disk, snapshotId)
+	}
+	server.AsyncSteps = 2
+	rolledBack := processFakeQcloud(t, "snapshot", "rollback", map[string]interface{}{"guid": "snapshot-guid", "id": snapshotId, "disk_id": diskId})
+	server.AsyncSteps = 0
+	if rolledBack[0]["state"] != "NORMAL" || len(server.Calls("ApplySnapshot")) != 1 {
+		t.Fatalf("rolled back snapshot=%v,want NORMAL after one rollback", rolledBack[0])
+	}
+	if disk, _ := server.GetResource(fakeqcloud.KIND_CBS_DISK, FAKE_QCLOUD_REGION, diskId); disk["Rollbacking"] != false || fmt.Sprint(disk["RollbackPercent"]) != "100" {
+		t.Fatalf("rolled back disk=%v,want the rollback done", disk)
+	}
+	//the snapshot of another disk is refused before ApplySnapshot, as the dry run reports it
+	if msg := processFakeQcloudError(t, "snapshot", "rollback", map[string]interface{}{"guid": "snapshot-guid", "id": snapshotId, "disk_id": outputId(t, restored)}); !strings.Contains(msg, "is taken from disk["+diskId+"]") {
+		t.Fatalf("rollback another disk got error %q", msg)
+	}
+	if calls := len(server.Calls("ApplySnapshot")); calls != 1 {
+		t.Fatalf("ApplySnapshot called %d times,want the rollback of another disk refused first", calls)
+	}
+
+	policyId := outputId(t, processFakeQcloud(t, "snapshot", "create-policy", map[string]interface{}{
+		"guid": "policy-guid", "name": "daily", "day_of_week": "0,6", "hour": "1,13", "retention_days": 3,
+	}))
+	bound := processFakeQcloud(t, "snapshot", "bind-policy", map[string]interface{}{"guid": "policy-guid", "id": policyId, "disk_ids": diskId})
+	if bound[0]["disk_ids"] != diskId || bound[0]["day_of_week"] != "0,6" || bound[0]["hour"] != "1,13" {
+		t.Fatalf("bound policy=%v,want %s bound at 1,13 on 0,6", bound[0], diskId)
+	}
+	previews := dryRunFakeQcloud(t, "snapshot", "bind-policy", map[string]interface{}{"guid": "policy-guid", "id": policyId, "disk_ids": diskId})
+	if len(previews) != 1 || previews[0].Operation != DRY_RUN_OPERATION_NONE {
+		t.Fatalf("dry run bind previews=%v,want the disk bound before", previews)
+	}
+	processFakeQcloud(t, "snapshot", "bind-policy", map[string]interface{}{"guid": "policy-guid", "id": policyId, "disk_ids": diskId})
+	if calls := len(server.Calls("BindAutoSnapshotPolicy")); calls != 1 {
+		t.Fatalf("BindAutoSnapshotPolicy called %d times,want bound disks skipped", calls)
+	}
+	if unbound := processFakeQcloud(t, "snapshot", "unbind-policy", map[string]interface{}{"guid": "policy-guid", "id": policyId, "disk_ids": diskId}); unbound[0]["disk_ids"] != nil {
+		t.Fatalf("unbound policy=%v,want no disks", unbound[0])
+	}
+	processFakeQcloud(t, "snapshot", "terminate-policy", map[string]interface{}{"guid": "policy-guid", "id": policyId})
+	if _, found := server.GetResource(fakeqcloud.KIND_CBS_AUTO_SNAPSHOT_POLICY, FAKE_QCLOUD_REGION, policyId); found {
+		t.Fatalf("snapshot policy %s should be deleted", policyId)
+	}
+
+	processFakeQcloud(t, "snapshot", "terminate", map[string]interface{}{"guid": "snapshot-guid", "id": snapshotId})
+	if described := processFakeQcloud(t, "snapshot", "describe", map[string]interface{}{"guid": "snapshot-guid", "disk_id": diskId}); len(described) != 0 {
+		t.Fatalf("described snapshots=%v,want none after terminate", described)
+	}
+}
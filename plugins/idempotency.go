@@ -0,0 +1,403 @@
+package plugins
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DEFAULT_IDEMPOTENCY_STORE_DIR = "./data/idempotency"
+	IDEMPOTENCY_RECORD_EXPIRE     = 7 * 24 * time.Hour
+	//a running record without progress is left by a plugin which exited before the create api returned,
+	//its key is released after this time so the operation can be retried once the resource is checked
+	IDEMPOTENCY_INTERRUPTED_RELEASE = time.Hour
+
+	IDEMPOTENCY_STATUS_RUNNING = "running"
+	IDEMPOTENCY_STATUS_DONE    = "done"
+	IDEMPOTENCY_STATUS_FAILED  = "failed"
+)
+
+//idempotencyRecord keeps the progress saved by a running operation, so a retry after the operation
+//failed or was interrupted resumes from it instead of starting over
+type idempotencyRecord struct {
+	Key        string          `json:"key"`
+	Status     string          `json:"status"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	Progress   json.RawMessage `json:"progress,omitempty"`
+	CreateTime time.Time       `json:"create_time"`
+	UpdateTime time.Time       `json:"update_time"`
+}
+
+//idempotencyCall is an operation running in this process, retries with the same key wait for it
+type idempotencyCall struct {
+	done   chan struct{}
+	result []byte
+	err    error
+}
+
+//idempotencyStore keeps one json file per key, so records survive restart of the plugin
+type idempotencyStore struct {
+	mutex    sync.Mutex
+	dir      string
+	inFlight map[string]*idempotencyCall
+}
+
+var idempotencyStoreInstance = newIdempotencyStore(DEFAULT_IDEMPOTENCY_STORE_DIR)
+
+func newIdempotencyStore(dir string) *idempotencyStore {
+	return &idempotencyStore{
+		dir:      dir,
+		inFlight: make(map[string]*idempotencyCall),
+	}
+}
+
+func InitIdempotencyStore(dir string) {
+	if dir == "" {
+		dir = DEFAULT_IDEMPOTENCY_STORE_DIR
+	}
+	idempotencyStoreInstance = newIdempotencyStore(dir)
+	idempotencyStoreInstance.removeExpiredRecords()
+}
+
+//removeExpiredRecords deletes the records expired and the temp files left by a crash when the plugin starts
+func (store *idempotencyStore) removeExpiredRecords() {
+	files, err := ioutil.ReadDir(store.dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logrus.Errorf("read idempotency store dir[%s] meet err=%v", store.dir, err)
+		}
+		return
+	}
+
+	for _, file := range files {
+		path := filepath.Join(store.dir, file.Name())
+		switch filepath.Ext(file.Name()) {
+		case ".tmp":
+			//a temp file is only left when the plugin exits while writing a record
+		case ".json":
+			record := idempotencyRecord{}
+			data, err := ioutil.ReadFile(path)
+			if err != nil || json.Unmarshal(data, &record) != nil || time.Since(record.UpdateTime) <= IDEMPOTENCY_RECORD_EXPIRE {
+				continue
+			}
+		default:
+			continue
+		}
+		if err = os.Remove(path); err != nil {
+			logrus.Errorf("remove expired idempotency record[%s] meet err=%v", path, err)
+		}
+	}
+}
+
+//getIdempotencyKey returns the key given by orchestrator, or guid with the hash of the whole input
+func getIdempotencyKey(scope string, guid string, idempotencyKey string, input interface{}) string {
+	if idempotencyKey != "" {
+		return scope + "/" + idempotencyKey
+	}
+
+	inputBytes, _ := json.Marshal(input)
+	sum := sha256.Sum256(inputBytes)
+	return fmt.Sprintf("%s/%s-%s", scope, guid, hex.EncodeToString(sum[:]))
+}
+
+//getIdempotencyClientToken fits the key into the 64 ascii chars limit of qcloud ClientToken
+func getIdempotencyClientToken(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (store *idempotencyStore) recordPath(key string) string {
+	return filepath.Join(store.dir, getIdempotencyClientToken(key)+".json")
+}
+
+func (store *idempotencyStore) readRecord(key string) (*idempotencyRecord, error) {
+	data, err := ioutil.ReadFile(store.recordPath(key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	record := idempotencyRecord{}
+	if err = json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("idempotency record of key[%s] is broken,err=%v", key, err)
+	}
+	if time.Since(record.UpdateTime) > IDEMPOTENCY_RECORD_EXPIRE {
+		return nil, store.removeRecord(key)
+	}
+	return &record, nil
+}
+
+func (store *idempotencyStore) writeRecord(record *idempotencyRecord) error {
+	if err := os.MkdirAll(store.dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	//write to a temp file then rename, a crash never leaves a half written record
+	path := store.recordPath(record.Key)
+	tmpPath := path + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func (store *idempotencyStore) removeRecord(key string) error {
+	err := os.Remove(store.recordPath(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+//start returns the stored result if the key is done, or the running call to wait for,
+//otherwise it marks the key running and returns a new call for the caller to finish
+func (store *idempotencyStore) start(key string) ([]byte, *idempotencyCall, bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if call, found := store.inFlight[key]; found {
+		return nil, call, false, nil
+	}
+
+	record, err := store.readRecord(key)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if record != nil && record.Status == IDEMPOTENCY_STATUS_DONE {
+		return record.Result, nil, false, nil
+	}
+	if record != nil && record.Status == IDEMPOTENCY_STATUS_RUNNING && record.Progress == nil {
+		releaseTime := record.UpdateTime.Add(IDEMPOTENCY_INTERRUPTED_RELEASE)
+		if time.Now().Before(releaseTime) {
+			return nil, nil, false, fmt.Errorf("operation with idempotency key[%s] started at %v was interrupted, please check the resource in qcloud, the key can be retried after %v",
+				key, record.CreateTime, releaseTime)
+		}
+		logrus.Warnf("idempotency key[%s] interrupted at %v is released, the operation runs again", key, record.UpdateTime)
+		record = nil
+	}
+
+	now := time.Now()
+	if record == nil {
+		record = &idempotencyRecord{Key: key, CreateTime: now}
+	} else {
+		logrus.Infof("idempotency key[%s] is %s with progress, resume it", key, record.Status)
+	}
+	record.Status = IDEMPOTENCY_STATUS_RUNNING
+	record.UpdateTime = now
+	if err = store.writeRecord(record); err != nil {
+		return nil, nil, false, err
+	}
+	call := &idempotencyCall{done: make(chan struct{})}
+	store.inFlight[key] = call
+	return nil, call, true, nil
+}
+
+func (store *idempotencyStore) finish(key string, call *idempotencyCall, result []byte, err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	call.result, call.err = result, err
+	if err != nil {
+		//failed operation can be retried with the same key, and resumes from the progress it saved
+		record, readErr := store.readRecord(key)
+		if readErr == nil && record != nil && record.Progress != nil {
+			record.Status = IDEMPOTENCY_STATUS_FAILED
+			record.UpdateTime = time.Now()
+			if writeErr := store.writeRecord(record); writeErr != nil {
+				logrus.Errorf("write idempotency record of key[%s] meet err=%v", key, writeErr)
+			}
+		} else if removeErr := store.removeRecord(key); removeErr != nil {
+			logrus.Errorf("remove idempotency record of key[%s] meet err=%v", key, removeErr)
+		}
+	} else {
+		record, readErr := store.readRecord(key)
+		if readErr != nil || record == nil {
+			record = &idempotencyRecord{Key: key, CreateTime: time.Now()}
+		}
+		record.Status = IDEMPOTENCY_STATUS_DONE
+		record.Result = result
+		record.Progress = nil
+		record.UpdateTime = time.Now()
+		if writeErr := store.writeRecord(record); writeErr != nil {
+			logrus.Errorf("write idempotency record of key[%s] meet err=%v", key, writeErr)
+		}
+	}
+
+	delete(store.inFlight, key)
+	close(call.done)
+}
+
+//saveProgress keeps the progress of the running key until the key is done
+func (store *idempotencyStore) saveProgress(key string, progress interface{}) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	record, err := store.readRecord(key)
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		record = &idempotencyRecord{Key: key, Status: IDEMPOTENCY_STATUS_RUNNING, CreateTime: time.Now()}
+	}
+	if record.Progress, err = json.Marshal(progress); err != nil {
+		return err
+	}
+	record.UpdateTime = time.Now()
+	return store.writeRecord(record)
+}
+
+//loadProgress reads the progress saved by the last run of the key, false if there is none
+func (store *idempotencyStore) loadProgress(key string, progress interface{}) (bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	record, err := store.readRecord(key)
+	if err != nil || record == nil || record.Progress == nil {
+		return false, err
+	}
+	return true, json.Unmarshal(record.Progress, progress)
+}
+
+//isDone tells whether a retry of the key replays the stored result, without marking the key running
+func (store *idempotencyStore) isDone(key string) (bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	record, err := store.readRecord(key)
+	if err != nil || record == nil {
+		return false, err
+	}
+	return record.Status == IDEMPOTENCY_STATUS_DONE, nil
+}
+
+func isIdempotencyKeyDone(key string) (bool, error) {
+	return idempotencyStoreInstance.isDone(key)
+}
+
+func saveIdempotencyProgress(key string, progress interface{}) error {
+	return idempotencyStoreInstance.saveProgress(key, progress)
+}
+
+func loadIdempotencyProgress(key string, progress interface{}) (bool, error) {
+	return idempotencyStoreInstance.loadProgress(key, progress)
+}
+
+//idempotencyCreateProgress is saved by the idempotency key once the create api returns, a retry after a failed
+//wait or a crash picks up the resources it created instead of creating others, Ids may be deal ids of an order
+type idempotencyCreateProgress struct {
+	Ids       []string `json:"ids"`
+	RequestId string   `json:"request_id,omitempty"`
+}
+
+//saveIdempotencyCreatedIds only logs the error, the resources are created anyway
+func saveIdempotencyCreatedIds(key string, requestId string, ids ...string) {
+	if err := saveIdempotencyProgress(key, idempotencyCreateProgress{Ids: ids, RequestId: requestId}); err != nil {
+		logrus.Errorf("save created ids%v of idempotency key[%s] meet err=%v", ids, key, err)
+	}
+}
+
+//loadIdempotencyCreatedIds returns nil if the create api of the key was never called
+func loadIdempotencyCreatedIds(key string) (*idempotencyCreateProgress, error) {
+	progress := idempotencyCreateProgress{}
+	found, err := loadIdempotencyProgress(key, &progress)
+	if err != nil || !found || len(progress.Ids) == 0 {
+		return nil, err
+	}
+	logrus.Infof("idempotency key[%s] has created%v, resume from them", key, progress.Ids)
+	return &progress, nil
+}
+
+//getIdempotencyCreatedId returns the id of the input, or the first id created by the last try of the key,
+//so creates which return an existing resource by id pick up the one created before
+func getIdempotencyCreatedId(key string, id string) (string, error) {
+	if id != "" {
+		return id, nil
+	}
+	progress, err := loadIdempotencyCreatedIds(key)
+	if err != nil || progress == nil {
+		return "", err
+	}
+	return progress.Ids[0], nil
+}
+
+//runWithIdempotencyKey runs create only once for one key, replays get the original result into output
+func runWithIdempotencyKey(key string, output interface{}, create func() (interface{}, error)) error {
+	return runBatchWithIdempotencyKeys([]string{key}, []interface{}{output}, func(indexes []int) ([]interface{}, error) {
+		createOutput, err := create()
+		return []interface{}{createOutput}, err
+	})
+}
+
+//runBatchWithIdempotencyKeys calls create once with the indexes of the keys neither done nor running,
+//create returns their results in the same order, the results of all the keys are read into outputs
+func runBatchWithIdempotencyKeys(keys []string, outputs []interface{}, create func(indexes []int) ([]interface{}, error)) error {
+	store := idempotencyStoreInstance
+	results := make([][]byte, len(keys))
+	calls := make([]*idempotencyCall, len(keys))
+	owned := make([]bool, len(keys))
+	ownedIndexes := []int{}
+
+	var err error
+	for i, key := range keys {
+		if results[i], calls[i], owned[i], err = store.start(key); err != nil {
+			break
+		}
+		if owned[i] {
+			ownedIndexes = append(ownedIndexes, i)
+		}
+	}
+
+	if err == nil && len(ownedIndexes) > 0 {
+		var createOutputs []interface{}
+		createOutputs, err = create(ownedIndexes)
+		if err == nil && len(createOutputs) != len(ownedIndexes) {
+			err = fmt.Errorf("got %d results for %d idempotency keys", len(createOutputs), len(ownedIndexes))
+		}
+		for n, i := range ownedIndexes {
+			if err == nil {
+				results[i], err = json.Marshal(createOutputs[n])
+			}
+		}
+	}
+	//the keys are released before waiting for others, so batches sharing keys never wait for each other
+	for _, i := range ownedIndexes {
+		store.finish(keys[i], calls[i], results[i], err)
+	}
+	if err != nil {
+		return err
+	}
+
+	for i, key := range keys {
+		if calls[i] != nil && !owned[i] {
+			logrus.Infof("idempotency key[%s] is running, wait for it", key)
+			<-calls[i].done
+			if calls[i].err != nil {
+				return calls[i].err
+			}
+			results[i] = calls[i].result
+		} else if calls[i] == nil {
+			logrus.Infof("idempotency key[%s] is done, return the original result", key)
+		}
+
+		if err = json.Unmarshal(results[i], outputs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,313 @@
+package plugins
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func setupIdempotencyStore(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "idempotency")
+	if err != nil {
+		t.Fatalf("create temp dir meet err=%v", err)
+	}
+	InitIdempotencyStore(dir)
+	return func() {
+		InitIdempotencyStore(DEFAULT_IDEMPOTENCY_STORE_DIR)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestGetIdempotencyKey(t *testing.T) {
+	input := VmInput{Guid: "guid", InstanceName: "vm1"}
+	if getIdempotencyKey("vm-create", input.Guid, "", input) != getIdempotencyKey("vm-create", input.Guid, "", input) {
+		t.Fatalf("same input should get the same key")
+	}
+	changed := VmInput{Guid: "guid", InstanceName: "vm2"}
+	if getIdempotencyKey("vm-create", input.Guid, "", input) == getIdempotencyKey("vm-create", changed.Guid, "", changed) {
+		t.Fatalf("different input should get different key")
+	}
+	if key := getIdempotencyKey("vm-create", input.Guid, "key", input); key != "vm-create/key" {
+		t.Fatalf("key=%s,want vm-create/key", key)
+	}
+	if len(getIdempotencyClientToken("vm-create/key")) != 64 {
+		t.Fatalf("client token should be 64 chars")
+	}
+}
+
+func TestRunWithIdempotencyKeyReplay(t *testing.T) {
+	defer setupIdempotencyStore(t)()
+
+	count := 0
+	create := func() (interface{}, error) {
+		count++
+		return VmOutput{Guid: "guid", Id: "ins-1"}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		output := VmOutput{}
+		if err := runWithIdempotencyKey("vm-create/key", &output, create); err != nil {
+			t.Fatalf("run meet err=%v", err)
+		}
+		if output.Id != "ins-1" {
+			t.Fatalf("output id=%s,want ins-1", output.Id)
+		}
+	}
+	if count != 1 {
+		t.Fatalf("create called %d times,want 1", count)
+	}
+
+	//records are persistent, a restarted plugin still replays the result
+	InitIdempotencyStore(idempotencyStoreInstance.dir)
+	output := VmOutput{}
+	if err := runWithIdempotencyKey("vm-create/key", &output, create); err != nil || output.Id != "ins-1" || count != 1 {
+		t.Fatalf("replay after restart failed, err=%v,output=%++v,count=%d", err, output, count)
+	}
+}
+
+func TestRunWithIdempotencyKeyRetryAfterFailure(t *testing.T) {
+	defer setupIdempotencyStore(t)()
+
+	output := VmOutput{}
+	err := runWithIdempotencyKey("vm-create/key", &output, func() (interface{}, error) {
+		return nil, errors.New("create failed")
+	})
+	if err == nil {
+		t.Fatalf("failure of create should be returned")
+	}
+
+	err = runWithIdempotencyKey("vm-create/key", &output, func() (interface{}, error) {
+		return VmOutput{Id: "ins-2"}, nil
+	})
+	if err != nil || output.Id != "ins-2" {
+		t.Fatalf("failed key should be retried, err=%v,output=%++v", err, output)
+	}
+}
+
+func TestRunWithIdempotencyKeyResumeProgress(t *testing.T) {
+	defer setupIdempotencyStore(t)()
+
+	//the progress saved before the failure is kept for the retry
+	output := VmOutput{}
+	err := runWithIdempotencyKey("vm-create/key", &output, func() (interface{}, error) {
+		if err := saveIdempotencyProgress("vm-create/key", vmCreateProgress{InstanceId: "ins-1", Password: "encrypted"}); err != nil {
+			t.Fatalf("save progress meet err=%v", err)
+		}
+		return nil, errors.New("wait failed")
+	})
+	if err == nil {
+		t.Fatalf("failure of create should be returned")
+	}
+
+	//a restarted plugin resumes from the progress too
+	InitIdempotencyStore(idempotencyStoreInstance.dir)
+	err = runWithIdempotencyKey("vm-create/key", &output, func() (interface{}, error) {
+		progress := vmCreateProgress{}
+		if found, err := loadIdempotencyProgress("vm-create/key", &progress); err != nil || !found {
+			t.Fatalf("progress should be found, err=%v", err)
+		}
+		return VmOutput{Id: progress.InstanceId, Password: progress.Password}, nil
+	})
+	if err != nil || output.Id != "ins-1" || output.Password != "encrypted" {
+		t.Fatalf("retry should resume the progress, err=%v,output=%++v", err, output)
+	}
+
+	record, err := idempotencyStoreInstance.readRecord("vm-create/key")
+	if err != nil || record.Status != IDEMPOTENCY_STATUS_DONE || record.Progress != nil {
+		t.Fatalf("done record should drop the progress, err=%v,record=%++v", err, record)
+	}
+}
+
+func TestRunWithIdempotencyKeyAttachRunning(t *testing.T) {
+	defer setupIdempotencyStore(t)()
+
+	var count int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	create := func() (interface{}, error) {
+		if atomic.AddInt32(&count, 1) == 1 {
+			close(started)
+		}
+		<-release
+		return VmOutput{Id: "ins-3"}, nil
+	}
+
+	var wg sync.WaitGroup
+	outputs := make([]VmOutput, 2)
+	errs := make([]error, 2)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		errs[0] = runWithIdempotencyKey("vm-create/key", &outputs[0], create)
+	}()
+	<-started
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		errs[1] = runWithIdempotencyKey("vm-create/key", &outputs[1], create)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for i := range outputs {
+		if errs[i] != nil || outputs[i].Id != "ins-3" {
+			t.Fatalf("call %d got err=%v,output=%++v", i, errs[i], outputs[i])
+		}
+	}
+	if count != 1 {
+		t.Fatalf("create called %d times,want 1", count)
+	}
+}
+
+func TestRunWithIdempotencyKeyInterrupted(t *testing.T) {
+	defer setupIdempotencyStore(t)()
+
+	//a running record without an in-process call is left by a crashed plugin
+	now := time.Now()
+	record := &idempotencyRecord{Key: "vm-create/key", Status: IDEMPOTENCY_STATUS_RUNNING, CreateTime: now, UpdateTime: now}
+	if err := idempotencyStoreInstance.writeRecord(record); err != nil {
+		t.Fatalf("write record meet err=%v", err)
+	}
+
+	output := VmOutput{}
+	err := runWithIdempotencyKey("vm-create/key", &output, func() (interface{}, error) {
+		t.Fatalf("interrupted operation should not be created again")
+		return nil, nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "can be retried after") {
+		t.Fatalf("interrupted operation should return error with the release time, err=%v", err)
+	}
+}
+
+func TestRunWithIdempotencyKeyInterruptedReleased(t *testing.T) {
+	defer setupIdempotencyStore(t)()
+
+	//the key of an interrupted operation is released after a while, so it is not stuck until the record expires
+	updateTime := time.Now().Add(-IDEMPOTENCY_INTERRUPTED_RELEASE - time.Minute)
+	record := &idempotencyRecord{Key: "vm-create/key", Status: IDEMPOTENCY_STATUS_RUNNING, CreateTime: updateTime, UpdateTime: updateTime}
+	if err := idempotencyStoreInstance.writeRecord(record); err != nil {
+		t.Fatalf("write record meet err=%v", err)
+	}
+
+	output := VmOutput{}
+	err := runWithIdempotencyKey("vm-create/key", &output, func() (interface{}, error) {
+		return VmOutput{Id: "ins-1"}, nil
+	})
+	if err != nil || output.Id != "ins-1" {
+		t.Fatalf("released operation should run again, err=%v,output=%++v", err, output)
+	}
+}
+
+func TestRemoveExpiredIdempotencyRecords(t *testing.T) {
+	defer setupIdempotencyStore(t)()
+
+	expireTime := time.Now().Add(-IDEMPOTENCY_RECORD_EXPIRE - time.Minute)
+	records := []*idempotencyRecord{
+		{Key: "vm-create/expired", Status: IDEMPOTENCY_STATUS_DONE, CreateTime: expireTime, UpdateTime: expireTime},
+		{Key: "vm-create/fresh", Status: IDEMPOTENCY_STATUS_DONE, CreateTime: time.Now(), UpdateTime: time.Now()},
+	}
+	for _, record := range records {
+		if err := idempotencyStoreInstance.writeRecord(record); err != nil {
+			t.Fatalf("write record meet err=%v", err)
+		}
+	}
+	tmpPath := idempotencyStoreInstance.recordPath("vm-create/crashed") + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, []byte("{"), 0644); err != nil {
+		t.Fatalf("write temp file meet err=%v", err)
+	}
+
+	//expired records and temp files are removed when the plugin starts
+	InitIdempotencyStore(idempotencyStoreInstance.dir)
+	if _, err := os.Stat(idempotencyStoreInstance.recordPath("vm-create/expired")); !os.IsNotExist(err) {
+		t.Fatalf("expired record should be removed, err=%v", err)
+	}
+	if _, err := os.Stat(tmpPath); !os.IsNotExist(err) {
+		t.Fatalf("temp file should be removed, err=%v", err)
+	}
+	if _, err := os.Stat(idempotencyStoreInstance.recordPath("vm-create/fresh")); err != nil {
+		t.Fatalf("fresh record should be kept, err=%v", err)
+	}
+
+	//an expired record read later is removed too
+	if err := idempotencyStoreInstance.writeRecord(records[0]); err != nil {
+		t.Fatalf("write record meet err=%v", err)
+	}
+	if done, err := isIdempotencyKeyDone("vm-create/expired"); err != nil || done {
+		t.Fatalf("expired record should not be done, done=%v,err=%v", done, err)
+	}
+	if _, err := os.Stat(idempotencyStoreInstance.recordPath("vm-create/expired")); !os.IsNotExist(err) {
+		t.Fatalf("expired record should be removed when read, err=%v", err)
+	}
+}
+
+func TestRunWithIdempotencyKeyInterruptedWithProgress(t *testing.T) {
+	defer setupIdempotencyStore(t)()
+
+	//a crashed plugin which saved the progress can be resumed
+	now := time.Now()
+	record := &idempotencyRecord{Key: "vm-create/key", Status: IDEMPOTENCY_STATUS_RUNNING, Progress: []byte(`{"instance_id":"ins-1"}`), CreateTime: now, UpdateTime: now}
+	if err := idempotencyStoreInstance.writeRecord(record); err != nil {
+		t.Fatalf("write record meet err=%v", err)
+	}
+
+	output := VmOutput{}
+	err := runWithIdempotencyKey("vm-create/key", &output, func() (interface{}, error) {
+		progress := vmCreateProgress{}
+		loadIdempotencyProgress("vm-create/key", &progress)
+		return VmOutput{Id: progress.InstanceId}, nil
+	})
+	if err != nil || output.Id != "ins-1" {
+		t.Fatalf("interrupted operation with progress should resume, err=%v,output=%++v", err, output)
+	}
+}
+
+func TestRunBatchWithIdempotencyKeys(t *testing.T) {
+	defer setupIdempotencyStore(t)()
+
+	output := VmOutput{}
+	if err := runWithIdempotencyKey("vm-create/key-2", &output, func() (interface{}, error) {
+		return VmOutput{Id: "ins-2"}, nil
+	}); err != nil {
+		t.Fatalf("run meet err=%v", err)
+	}
+
+	//only the keys not done yet are created
+	keys := []string{"vm-create/key-1", "vm-create/key-2", "vm-create/key-3"}
+	outputs := make([]VmOutput, len(keys))
+	createdIndexes := []int{}
+	err := runBatchWithIdempotencyKeys(keys, []interface{}{&outputs[0], &outputs[1], &outputs[2]}, func(indexes []int) ([]interface{}, error) {
+		createdIndexes = indexes
+		return []interface{}{VmOutput{Id: "ins-1"}, VmOutput{Id: "ins-3"}}, nil
+	})
+	if err != nil {
+		t.Fatalf("run batch meet err=%v", err)
+	}
+	if len(createdIndexes) != 2 || createdIndexes[0] != 0 || createdIndexes[1] != 2 {
+		t.Fatalf("created indexes=%v,want [0 2]", createdIndexes)
+	}
+	for i, want := range []string{"ins-1", "ins-2", "ins-3"} {
+		if outputs[i].Id != want {
+			t.Fatalf("output %d id=%s,want %s", i, outputs[i].Id, want)
+		}
+	}
+
+	//a failed batch releases all its keys
+	err = runBatchWithIdempotencyKeys([]string{"vm-create/key-4", "vm-create/key-5"}, []interface{}{&output, &output}, func(indexes []int) ([]interface{}, error) {
+		return nil, errors.New("create failed")
+	})
+	if err == nil {
+		t.Fatalf("failure of create should be returned")
+	}
+	if err = runWithIdempotencyKey("vm-create/key-5", &output, func() (interface{}, error) {
+		return VmOutput{Id: "ins-5"}, nil
+	}); err != nil || output.Id != "ins-5" {
+		t.Fatalf("failed key should be retried, err=%v,output=%++v", err, output)
+	}
+}
@@ -0,0 +1,748 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+)
+
+const (
+	IMAGE_STATE_NORMAL       = "NORMAL"
+	IMAGE_STATE_CREATEFAILED = "CREATEFAILED"
+
+	IMAGE_TYPE_PRIVATE = "PRIVATE_IMAGE"
+
+	IMAGE_PERMISSION_SHARE  = "SHARE"
+	IMAGE_PERMISSION_CANCEL = "CANCEL"
+
+	IMAGE_ID_PREFIX = "img-"
+	//the most images qcloud returns in one DescribeImages call
+	IMAGE_DESCRIBE_PAGE_SIZE = 100
+	//making an image copies the whole disks, it takes much longer than changing a vm
+	IMAGE_WAIT_TIMEOUT = 1800
+)
+
+var ImageActions = make(map[string]Action)
+
+func init() {
+	ImageActions["create"] = new(ImageCreateAction)
+	ImageActions["copy"] = new(ImageCopyAction)
+	ImageActions["share"] = new(ImageShareAction)
+	ImageActions["terminate"] = new(ImageTerminateAction)
+	ImageActions["describe"] = new(ImageDescribeAction)
+}
+
+type ImageInputs struct {
+	Inputs []ImageInput `json:"inputs,omitempty"`
+}
+
+type ImageInput struct {
+	Guid             string `json:"guid,omitempty"`
+	ProviderParams   string `json:"provider_params,omitempty"`
+	Id               string `json:"id,omitempty"`
+	ImageName        string `json:"image_name,omitempty"`
+	ImageDescription string `json:"image_description,omitempty"`
+	InstanceId       string `json:"instance_id,omitempty"`
+	ForcePoweroff    string `json:"force_poweroff,omitempty"`
+	DataDiskIds      string `json:"data_disk_ids,omitempty"`
+
+	//comma separated lists of copy and share
+	DestinationRegions string `json:"destination_regions,omitempty"`
+	AccountIds         string `json:"account_ids,omitempty"`
+	Permission         string `json:"permission,omitempty"`
+	ImageType          string `json:"image_type,omitempty"`
+}
+
+type ImageOutputs struct {
+	Outputs []ImageOutput `json:"outputs,omitempty"`
+}
+
+type ImageOutput struct {
+	Guid                string `json:"guid,omitempty"`
+	RequestId           string `json:"request_id,omitempty"`
+	Id                  string `json:"id,omitempty"`
+	ImageName           string `json:"image_name,omitempty"`
+	ImageDescription    string `json:"image_description,omitempty"`
+	ImageState          string `json:"image_state,omitempty"`
+	ImageType           string `json:"image_type,omitempty"`
+	ImageSize           string `json:"image_size,omitempty"`
+	OsName              string `json:"os_name,omitempty"`
+	Platform            string `json:"platform,omitempty"`
+	Architecture        string `json:"architecture,omitempty"`
+	CreatedTime         string `json:"created_time,omitempty"`
+	DestinationImageIds string `json:"destination_image_ids,omitempty"`
+}
+
+type ImagePlugin struct{}
+
+func (plugin *ImagePlugin) GetActionByName(actionName string) (Action, error) {
+	action, found := ImageActions[actionName]
+	if !found {
+		return nil, fmt.Errorf("image plugin,action[%s] not found", actionName)
+	}
+	return action, nil
+}
+
+type ImageAction struct {
+}
+
+func (action *ImageAction) ReadParam(param interface{}) (interface{}, error) {
+	var inputs ImageInputs
+	err := UnmarshalJson(param, &inputs)
+	if err != nil {
+		return nil, err
+	}
+	return inputs, nil
+}
+
+func (action *ImageAction) CheckParam(input interface{}) error {
+	images, ok := input.(ImageInputs)
+	if !ok {
+		return INVALID_PARAMETERS
+	}
+
+	for _, image := range images.Inputs {
+		if image.Id == "" {
+			return errors.New("input id is empty")
+		}
+	}
+	return nil
+}
+
+func createImageClient(providerParams string) (CvmAPI, error) {
+	paramsMap, _ := GetMapFromProviderParams(providerParams)
+	return createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+}
+
+//describeAllImages pages over DescribeImages until all the images matching the request are returned
+func describeAllImages(client CvmAPI, request *cvm.DescribeImagesRequest) ([]*cvm.Image, string, error) {
+	request.Limit = common.Uint64Ptr(IMAGE_DESCRIBE_PAGE_SIZE)
+
+	images := []*cvm.Image{}
+	requestId := ""
+	for offset := uint64(0); ; {
+		request.Offset = common.Uint64Ptr(offset)
+		response, err := client.DescribeImages(request)
+		if err != nil {
+			logrus.Errorf("cvm DescribeImages meet err=%v", err)
+			return nil, "", err
+		}
+
+		requestId = *response.Response.RequestId
+		images = append(images, response.Response.ImageSet...)
+		offset += uint64(len(response.Response.ImageSet))
+		if len(response.Response.ImageSet) == 0 || int64(offset) >= *response.Response.TotalCount {
+			break
+		}
+	}
+	return images, requestId, nil
+}
+
+func newImageFilter(name string, values ...string) *cvm.Filter {
+	return &cvm.Filter{Name: common.StringPtr(name), Values: common.StringPtrs(values)}
+}
+
+//queryImageById returns false if the image is not found
+func queryImageById(client CvmAPI, imageId string) (*cvm.Image, bool, error) {
+	request := cvm.NewDescribeImagesRequest()
+	request.ImageIds = []*string{&imageId}
+	images, _, err := describeAllImages(client, request)
+	if err != nil || len(images) == 0 {
+		return nil, false, err
+	}
+	return images[0], true, nil
+}
+
+//queryPrivateImageByName returns false if the account has no image of the name in the region
+func queryPrivateImageByName(client CvmAPI, imageName string) (*cvm.Image, bool, error) {
+	request := cvm.NewDescribeImagesRequest()
+	request.Filters = []*cvm.Filter{
+		newImageFilter("image-type", IMAGE_TYPE_PRIVATE),
+		newImageFilter("image-name", imageName),
+	}
+	images, _, err := describeAllImages(client, request)
+	if err != nil || len(images) == 0 {
+		return nil, false, err
+	}
+	if len(images) > 1 {
+		return nil, false, fmt.Errorf("found %d private images named %s", len(images), imageName)
+	}
+	return images[0], true, nil
+}
+
+//resolveImageId returns image ids as they are, and looks up the id of an image name in the region of the client,
+//so templates can name the same image copied to several regions
+func resolveImageId(client CvmAPI, image string) (string, error) {
+	if image == "" || strings.HasPrefix(image, IMAGE_ID_PREFIX) {
+		return image, nil
+	}
+
+	request := cvm.NewDescribeImagesRequest()
+	request.Filters = []*cvm.Filter{newImageFilter("image-name", image)}
+	images, _, err := describeAllImages(client, request)
+	if err != nil {
+		return "", err
+	}
+	if len(images) != 1 {
+		return "", fmt.Errorf("image name(%s) matches %d images, should be 1", image, len(images))
+	}
+	logrus.Infof("image name[%s] is resolved to image[%s]", image, *images[0].ImageId)
+	return *images[0].ImageId, nil
+}
+
+//waitImageNormal polls the image until it can be used, images failed to create are reported at once
+func waitImageNormal(client CvmAPI, imageId string, timeout int) (*cvm.Image, error) {
+	count := 0
+	for {
+		time.Sleep(waitPollInterval)
+		image, found, err := queryImageById(client, imageId)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, fmt.Errorf("image[%s] not found", imageId)
+		}
+		switch *image.ImageState {
+		case IMAGE_STATE_NORMAL:
+			return image, nil
+		case IMAGE_STATE_CREATEFAILED:
+			return nil, fmt.Errorf("image[%s] is in state[%s]", imageId, *image.ImageState)
+		}
+
+		count++
+		if count*WAIT_POLL_SECONDS > timeout {
+			return nil, fmt.Errorf("qcloud wait image[%s] timeout", imageId)
+		}
+	}
+}
+
+func newImageOutput(guid string, requestId string, image *cvm.Image) ImageOutput {
+	output := ImageOutput{
+		Guid:             guid,
+		RequestId:        requestId,
+		Id:               stringValue(image.ImageId),
+		ImageName:        stringValue(image.ImageName),
+		ImageDescription: stringValue(image.ImageDescription),
+		ImageState:       stringValue(image.ImageState),
+		ImageType:        stringValue(image.ImageType),
+		OsName:           stringValue(image.OsName),
+		Platform:         stringValue(image.Platform),
+		Architecture:     stringValue(image.Architecture),
+		CreatedTime:      stringValue(image.CreatedTime),
+	}
+	if image.ImageSize != nil {
+		output.ImageSize = strconv.FormatInt(*image.ImageSize, 10)
+	}
+	return output
+}
+
+type ImageCreateAction struct {
+	ImageAction
+}
+
+func (action *ImageCreateAction) CheckParam(input interface{}) error {
+	images, ok := input.(ImageInputs)
+	if !ok {
+		return INVALID_PARAMETERS
+	}
+
+	for _, image := range images.Inputs {
+		if image.ImageName == "" {
+			return errors.New("input image_name is empty")
+		}
+		if image.InstanceId == "" {
+			return errors.New("input instance_id is empty")
+		}
+		if image.ForcePoweroff != "" {
+			if _, err := strconv.ParseBool(image.ForcePoweroff); err != nil {
+				return fmt.Errorf("invalid force_poweroff(%s)", image.ForcePoweroff)
+			}
+		}
+	}
+	return nil
+}
+
+func (action *ImageCreateAction) Do(input interface{}) (interface{}, error) {
+	images, _ := input.(ImageInputs)
+	outputs := ImageOutputs{}
+	for _, image := range images.Inputs {
+		output, err := createImage(image)
+		if err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+//createImage makes the image from the instance and waits until it can be used,
+//the private image names are unique in a region, so a retry waits for the image made by the first try
+func createImage(image ImageInput) (ImageOutput, error) {
+	client, err := createImageClient(image.ProviderParams)
+	if err != nil {
+		return ImageOutput{}, err
+	}
+
+	requestId := ""
+	existed, found, err := queryPrivateImageByName(client, image.ImageName)
+	if err != nil {
+		return ImageOutput{}, err
+	}
+	if !found {
+		request := cvm.NewCreateImageRequest()
+		request.ImageName = &image.ImageName
+		request.InstanceId = &image.InstanceId
+		if image.ImageDescription != "" {
+			request.ImageDescription = &image.ImageDescription
+		}
+		if forcePoweroff, _ := strconv.ParseBool(image.ForcePoweroff); forcePoweroff {
+			request.ForcePoweroff = common.StringPtr("TRUE")
+		}
+		if dataDiskIds := splitCommaValues(image.DataDiskIds); len(dataDiskIds) > 0 {
+			request.DataDiskIds = common.StringPtrs(dataDiskIds)
+		}
+
+		response, err := client.CreateImage(request)
+		if err != nil {
+			return ImageOutput{}, err
+		}
+		requestId = *response.Response.RequestId
+		logrus.Infof("Create image[%v] from VM[%v] has been submitted, RequestID is [%v]", image.ImageName, image.InstanceId, requestId)
+
+		//CreateImage does not return the image id, the new image is found by its name
+		if existed, found, err = queryPrivateImageByName(client, image.ImageName); err != nil {
+			return ImageOutput{}, err
+		}
+		if !found {
+			return ImageOutput{}, fmt.Errorf("created image[%s] not found", image.ImageName)
+		}
+	} else {
+		logrus.Infof("image[%s] named %s already exists", *existed.ImageId, image.ImageName)
+	}
+
+	created, err := waitImageNormal(client, *existed.ImageId, IMAGE_WAIT_TIMEOUT)
+	if err != nil {
+		return ImageOutput{}, err
+	}
+	return newImageOutput(image.Guid, requestId, created), nil
+}
+
+func (action *ImageCreateAction) DryRun(input interface{}) (interface{}, error) {
+	images, _ := input.(ImageInputs)
+	outputs := DryRunOutputs{}
+	for _, image := range images.Inputs {
+		client, err := createImageClient(image.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		existed, found, err := queryPrivateImageByName(client, image.ImageName)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			outputs.Outputs = append(outputs.Outputs, newCreateDryRunOutput(image.Guid, *existed.ImageId, true, ""))
+			continue
+		}
+
+		detail := fmt.Sprintf("create image[%s] from instance[%s]", image.ImageName, image.InstanceId)
+		if image.DataDiskIds != "" {
+			detail += fmt.Sprintf(" with data disks[%s]", image.DataDiskIds)
+		}
+		if forcePoweroff, _ := strconv.ParseBool(image.ForcePoweroff); forcePoweroff {
+			detail += ", the instance is powered off if it can't be shut down"
+		}
+		output := newCreateDryRunOutput(image.Guid, "", false, detail)
+		_, instanceFound, err := queryVmInstanceInfo(client, image.InstanceId)
+		if err != nil {
+			return nil, err
+		}
+		if !instanceFound {
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("instance[%s] not found", image.InstanceId))
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+type ImageCopyAction struct {
+	ImageAction
+}
+
+func (action *ImageCopyAction) CheckParam(input interface{}) error {
+	if err := action.ImageAction.CheckParam(input); err != nil {
+		return err
+	}
+	images, _ := input.(ImageInputs)
+	for _, image := range images.Inputs {
+		if len(splitCommaValues(image.DestinationRegions)) == 0 {
+			return errors.New("input destination_regions is empty")
+		}
+	}
+	return nil
+}
+
+func (action *ImageCopyAction) Do(input interface{}) (interface{}, error) {
+	images, _ := input.(ImageInputs)
+	outputs := ImageOutputs{}
+	for _, image := range images.Inputs {
+		output, err := copyImage(image)
+		if err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+//copyImage copies the image to the regions which have no image of the same name yet,
+//and waits until the copies in all the regions can be used
+func copyImage(image ImageInput) (ImageOutput, error) {
+	paramsMap, _ := GetMapFromProviderParams(image.ProviderParams)
+	client, err := createImageClient(image.ProviderParams)
+	if err != nil {
+		return ImageOutput{}, err
+	}
+
+	source, found, err := queryImageById(client, image.Id)
+	if err != nil {
+		return ImageOutput{}, err
+	}
+	if !found {
+		return ImageOutput{}, fmt.Errorf("image[%s] not found", image.Id)
+	}
+
+	regions := splitCommaValues(image.DestinationRegions)
+	regionClients := make([]CvmAPI, len(regions))
+	missingRegions := []string{}
+	for i, region := range regions {
+		if regionClients[i], err = createCvmClient(region, paramsMap["SecretID"], paramsMap["SecretKey"]); err != nil {
+			return ImageOutput{}, err
+		}
+		if _, found, err = queryPrivateImageByName(regionClients[i], *source.ImageName); err != nil {
+			return ImageOutput{}, err
+		}
+		if !found {
+			missingRegions = append(missingRegions, region)
+		}
+	}
+
+	output := newImageOutput(image.Guid, "", source)
+	if len(missingRegions) > 0 {
+		request := cvm.NewSyncImagesRequest()
+		request.ImageIds = []*string{&image.Id}
+		request.DestinationRegions = common.StringPtrs(missingRegions)
+		response, err := client.SyncImages(request)
+		if err != nil {
+			return ImageOutput{}, err
+		}
+		output.RequestId = *response.Response.RequestId
+		logrus.Infof("Copy image[%v] to regions%v has been submitted, RequestID is [%v]", image.Id, missingRegions, output.RequestId)
+	}
+
+	destinationImageIds := []string{}
+	for i, region := range regions {
+		copied, found, err := queryPrivateImageByName(regionClients[i], *source.ImageName)
+		if err != nil {
+			return ImageOutput{}, err
+		}
+		if !found {
+			return ImageOutput{}, fmt.Errorf("copy of image[%s] not found in region[%s]", image.Id, region)
+		}
+		if _, err = waitImageNormal(regionClients[i], *copied.ImageId, IMAGE_WAIT_TIMEOUT); err != nil {
+			return ImageOutput{}, err
+		}
+		destinationImageIds = append(destinationImageIds, *copied.ImageId)
+	}
+	output.DestinationImageIds = strings.Join(destinationImageIds, ",")
+	return output, nil
+}
+
+func (action *ImageCopyAction) DryRun(input interface{}) (interface{}, error) {
+	images, _ := input.(ImageInputs)
+	outputs := DryRunOutputs{}
+	for _, image := range images.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(image.ProviderParams)
+		client, err := createImageClient(image.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		source, found, err := queryImageById(client, image.Id)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			output := newDryRunOutput(image.Guid, image.Id, false, DRY_RUN_OPERATION_NONE, "")
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("image[%s] not found", image.Id))
+			outputs.Outputs = append(outputs.Outputs, output)
+			continue
+		}
+
+		regions := splitCommaValues(image.DestinationRegions)
+		missingRegions := []string{}
+		for _, region := range regions {
+			regionClient, err := createCvmClient(region, paramsMap["SecretID"], paramsMap["SecretKey"])
+			if err != nil {
+				return nil, err
+			}
+			if _, found, err = queryPrivateImageByName(regionClient, *source.ImageName); err != nil {
+				return nil, err
+			}
+			if !found {
+				missingRegions = append(missingRegions, region)
+			}
+		}
+
+		if len(missingRegions) == 0 {
+			outputs.Outputs = append(outputs.Outputs, newDryRunOutput(image.Guid, image.Id, true, DRY_RUN_OPERATION_NONE,
+				fmt.Sprintf("image already copied to regions%v", regions)))
+			continue
+		}
+		outputs.Outputs = append(outputs.Outputs, newDryRunOutput(image.Guid, image.Id, true, DRY_RUN_OPERATION_CREATE,
+			fmt.Sprintf("copy image named %s to regions%v", *source.ImageName, missingRegions)))
+	}
+	return &outputs, nil
+}
+
+type ImageShareAction struct {
+	ImageAction
+}
+
+func (action *ImageShareAction) CheckParam(input interface{}) error {
+	if err := action.ImageAction.CheckParam(input); err != nil {
+		return err
+	}
+	images, _ := input.(ImageInputs)
+	for _, image := range images.Inputs {
+		if len(splitCommaValues(image.AccountIds)) == 0 {
+			return errors.New("input account_ids is empty")
+		}
+		if image.Permission != "" {
+			if err := isValidValue(image.Permission, []string{IMAGE_PERMISSION_SHARE, IMAGE_PERMISSION_CANCEL}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (action *ImageShareAction) Do(input interface{}) (interface{}, error) {
+	images, _ := input.(ImageInputs)
+	outputs := ImageOutputs{}
+	for _, image := range images.Inputs {
+		client, err := createImageClient(image.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		permission := image.Permission
+		if permission == "" {
+			permission = IMAGE_PERMISSION_SHARE
+		}
+		request := cvm.NewModifyImageSharePermissionRequest()
+		request.ImageId = &image.Id
+		request.AccountIds = common.StringPtrs(splitCommaValues(image.AccountIds))
+		request.Permission = &permission
+		response, err := client.ModifyImageSharePermission(request)
+		if err != nil {
+			return nil, err
+		}
+		logrus.Infof("%s image[%v] with accounts[%v] has been submitted, RequestID is [%v]", permission, image.Id, image.AccountIds, *response.Response.RequestId)
+
+		outputs.Outputs = append(outputs.Outputs, ImageOutput{
+			Guid:      image.Guid,
+			RequestId: *response.Response.RequestId,
+			Id:        image.Id,
+		})
+	}
+	return &outputs, nil
+}
+
+//describeImageSharedAccounts returns the accounts the image is shared with
+func describeImageSharedAccounts(client CvmAPI, imageId string) ([]string, error) {
+	request := cvm.NewDescribeImageSharePermissionRequest()
+	request.ImageId = &imageId
+	response, err := client.DescribeImageSharePermission(request)
+	if err != nil {
+		logrus.Errorf("cvm DescribeImageSharePermission meet err=%v", err)
+		return nil, err
+	}
+
+	accountIds := []string{}
+	for _, permission := range response.Response.SharePermissionSet {
+		accountIds = append(accountIds, stringValue(permission.AccountId))
+	}
+	return accountIds, nil
+}
+
+func (action *ImageShareAction) DryRun(input interface{}) (interface{}, error) {
+	images, _ := input.(ImageInputs)
+	outputs := DryRunOutputs{}
+	for _, image := range images.Inputs {
+		client, err := createImageClient(image.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		_, found, err := queryImageById(client, image.Id)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			output := newDryRunOutput(image.Guid, image.Id, false, DRY_RUN_OPERATION_NONE, "")
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("image[%s] not found", image.Id))
+			outputs.Outputs = append(outputs.Outputs, output)
+			continue
+		}
+		sharedAccountIds, err := describeImageSharedAccounts(client, image.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		//sharing changes the accounts not shared yet, canceling changes the accounts shared
+		share := image.Permission != IMAGE_PERMISSION_CANCEL
+		changedAccountIds := []string{}
+		for _, accountId := range splitCommaValues(image.AccountIds) {
+			if isStringInList(accountId, sharedAccountIds) != share {
+				changedAccountIds = append(changedAccountIds, accountId)
+			}
+		}
+
+		output := newDryRunOutput(image.Guid, image.Id, true, DRY_RUN_OPERATION_NONE, "")
+		switch {
+		case len(changedAccountIds) == 0 && share:
+			output.Detail = fmt.Sprintf("image already shared with accounts[%s]", image.AccountIds)
+		case len(changedAccountIds) == 0:
+			output.Detail = fmt.Sprintf("image not shared with accounts[%s]", image.AccountIds)
+		case share:
+			output.Operation = DRY_RUN_OPERATION_MODIFY
+			output.Detail = fmt.Sprintf("share image with accounts[%s]", strings.Join(changedAccountIds, ","))
+		default:
+			output.Operation = DRY_RUN_OPERATION_MODIFY
+			output.Detail = fmt.Sprintf("cancel sharing image with accounts[%s]", strings.Join(changedAccountIds, ","))
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+type ImageTerminateAction struct {
+	ImageAction
+}
+
+func (action *ImageTerminateAction) Do(input interface{}) (interface{}, error) {
+	images, _ := input.(ImageInputs)
+	outputs := ImageOutputs{}
+	for _, image := range images.Inputs {
+		client, err := createImageClient(image.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		output := ImageOutput{Guid: image.Guid, Id: image.Id}
+		_, found, err := queryImageById(client, image.Id)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			logrus.Infof("image[%s] is already deleted", image.Id)
+			outputs.Outputs = append(outputs.Outputs, output)
+			continue
+		}
+
+		request := cvm.NewDeleteImagesRequest()
+		request.ImageIds = []*string{&image.Id}
+		response, err := client.DeleteImages(request)
+		if err != nil {
+			return nil, err
+		}
+		logrus.Infof("Delete image[%v] has been submitted, RequestID is [%v]", image.Id, *response.Response.RequestId)
+
+		output.RequestId = *response.Response.RequestId
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+func (action *ImageTerminateAction) DryRun(input interface{}) (interface{}, error) {
+	images, _ := input.(ImageInputs)
+	outputs := DryRunOutputs{}
+	for _, image := range images.Inputs {
+		client, err := createImageClient(image.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		existed, found, err := queryImageById(client, image.Id)
+		if err != nil {
+			return nil, err
+		}
+		detail := ""
+		if found {
+			detail = fmt.Sprintf("delete image named %s in state[%s]", stringValue(existed.ImageName), stringValue(existed.ImageState))
+		}
+		outputs.Outputs = append(outputs.Outputs, newDeleteDryRunOutput(image.Guid, image.Id, found, detail))
+	}
+	return &outputs, nil
+}
+
+type ImageDescribeAction struct {
+	ImageAction
+}
+
+//the images are looked up by ids, or by name and type, the private images are returned without them
+func (action *ImageDescribeAction) CheckParam(input interface{}) error {
+	images, ok := input.(ImageInputs)
+	if !ok {
+		return INVALID_PARAMETERS
+	}
+
+	for _, image := range images.Inputs {
+		if image.Id != "" && (image.ImageName != "" || image.ImageType != "") {
+			return errors.New("input id can't be used together with image_name or image_type")
+		}
+	}
+	return nil
+}
+
+func (action *ImageDescribeAction) Do(input interface{}) (interface{}, error) {
+	images, _ := input.(ImageInputs)
+	outputs := ImageOutputs{}
+	for _, image := range images.Inputs {
+		client, err := createImageClient(image.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		request := cvm.NewDescribeImagesRequest()
+		if imageIds := splitCommaValues(image.Id); len(imageIds) > 0 {
+			request.ImageIds = common.StringPtrs(imageIds)
+		} else {
+			imageType := image.ImageType
+			if imageType == "" {
+				imageType = IMAGE_TYPE_PRIVATE
+			}
+			request.Filters = append(request.Filters, newImageFilter("image-type", imageType))
+			if image.ImageName != "" {
+				request.Filters = append(request.Filters, newImageFilter("image-name", image.ImageName))
+			}
+		}
+
+		found, requestId, err := describeAllImages(client, request)
+		if err != nil {
+			return nil, err
+		}
+		logrus.Infof("describe images of guid[%s] found %d images", image.Guid, len(found))
+		for _, item := range found {
+			outputs.Outputs = append(outputs.Outputs, newImageOutput(image.Guid, requestId, item))
+		}
+	}
+	return &outputs, nil
+}
@@ -0,0 +1,152 @@
+package plugins
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+var IpamActions = make(map[string]Action)
+
+func init() {
+	IpamActions["check"] = new(IpamCheckAction)
+}
+
+type IpamInputs struct {
+	Inputs []IpamInput `json:"inputs,omitempty"`
+}
+
+type IpamInput struct {
+	Guid           string `json:"guid,omitempty"`
+	ProviderParams string `json:"provider_params,omitempty"`
+	Regions        string `json:"regions,omitempty"`
+}
+
+type IpamOutputs struct {
+	Outputs []IpamOutput `json:"outputs,omitempty"`
+}
+
+//IpamOutput is one pair of vpc cidrs which overlap
+type IpamOutput struct {
+	Guid             string `json:"guid,omitempty"`
+	Region           string `json:"region,omitempty"`
+	VpcId            string `json:"vpc_id,omitempty"`
+	CidrBlock        string `json:"cidr_block,omitempty"`
+	PeerRegion       string `json:"peer_region,omitempty"`
+	PeerVpcId        string `json:"peer_vpc_id,omitempty"`
+	PeerCidrBlock    string `json:"peer_cidr_block,omitempty"`
+	OverlapCidrBlock string `json:"overlap_cidr_block,omitempty"`
+}
+
+type IpamPlugin struct{}
+
+func (plugin *IpamPlugin) GetActionByName(actionName string) (Action, error) {
+	action, found := IpamActions[actionName]
+	if !found {
+		return nil, fmt.Errorf("ipam plugin,action[%s] not found", actionName)
+	}
+	return action, nil
+}
+
+type IpamCheckAction struct {
+}
+
+func (action *IpamCheckAction) ReadParam(param interface{}) (interface{}, error) {
+	var inputs IpamInputs
+	err := UnmarshalJson(param, &inputs)
+	if err != nil {
+		return nil, err
+	}
+	return inputs, nil
+}
+
+func (action *IpamCheckAction) CheckParam(input interface{}) error {
+	if _, ok := input.(IpamInputs); !ok {
+		return INVALID_PARAMETERS
+	}
+	return nil
+}
+
+//getIpamRegions returns the regions of the input, the region of provider_params without them
+func getIpamRegions(input IpamInput) []string {
+	if regions := splitCommaValues(input.Regions); len(regions) > 0 {
+		return regions
+	}
+	paramsMap, _ := GetMapFromProviderParams(input.ProviderParams)
+	return []string{paramsMap["Region"]}
+}
+
+type ipamVpcCidr struct {
+	region    string
+	vpcId     string
+	cidrBlock string
+}
+
+//describeIpamVpcCidrs returns the primary and secondary cidrs of all the vpcs in the regions
+func describeIpamVpcCidrs(input IpamInput) ([]ipamVpcCidr, error) {
+	paramsMap, _ := GetMapFromProviderParams(input.ProviderParams)
+	vpcCidrs := []ipamVpcCidr{}
+	for _, region := range getIpamRegions(input) {
+		client, err := CreateVpcClient(region, paramsMap["SecretID"], paramsMap["SecretKey"])
+		if err != nil {
+			return nil, err
+		}
+		vpcs, err := describeAllVpcs(client)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range vpcs {
+			for _, cidrBlock := range getVpcCidrBlocks(item) {
+				vpcCidrs = append(vpcCidrs, ipamVpcCidr{region: region, vpcId: stringValue(item.VpcId), cidrBlock: cidrBlock})
+			}
+		}
+	}
+	return vpcCidrs, nil
+}
+
+//findOverlappedVpcCidrs reports every pair of cidrs of different vpcs which overlap,
+//cidrs either nest or are disjoint so the overlap is the smaller one of the pair
+func findOverlappedVpcCidrs(guid string, vpcCidrs []ipamVpcCidr) []IpamOutput {
+	outputs := []IpamOutput{}
+	for i, vpcCidr := range vpcCidrs {
+		for _, peer := range vpcCidrs[i+1:] {
+			if vpcCidr.region == peer.region && vpcCidr.vpcId == peer.vpcId {
+				continue
+			}
+			if !isCidrOverlap(vpcCidr.cidrBlock, peer.cidrBlock) {
+				continue
+			}
+
+			overlap := vpcCidr.cidrBlock
+			if isCidrContains(vpcCidr.cidrBlock, peer.cidrBlock) {
+				overlap = peer.cidrBlock
+			}
+			outputs = append(outputs, IpamOutput{
+				Guid:             guid,
+				Region:           vpcCidr.region,
+				VpcId:            vpcCidr.vpcId,
+				CidrBlock:        vpcCidr.cidrBlock,
+				PeerRegion:       peer.region,
+				PeerVpcId:        peer.vpcId,
+				PeerCidrBlock:    peer.cidrBlock,
+				OverlapCidrBlock: overlap,
+			})
+		}
+	}
+	return outputs
+}
+
+func (action *IpamCheckAction) Do(input interface{}) (interface{}, error) {
+	inputs, _ := input.(IpamInputs)
+	outputs := IpamOutputs{}
+	for _, ipamInput := range inputs.Inputs {
+		vpcCidrs, err := describeIpamVpcCidrs(ipamInput)
+		if err != nil {
+			return nil, err
+		}
+		overlaps := findOverlappedVpcCidrs(ipamInput.Guid, vpcCidrs)
+		logrus.Infof("ipam check found %d overlapped cidrs in %d vpc cidrs", len(overlaps), len(vpcCidrs))
+		outputs.Outputs = append(outputs.Outputs, overlaps...)
+	}
+	return &outputs, nil
+}
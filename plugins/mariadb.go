@@ -9,8 +9,6 @@ import (
 
 	"github.com/WeBankPartners/wecube-plugins-qcloud/plugins/utils"
 	"github.com/sirupsen/logrus"
-	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
-	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
 	mariadb "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/mariadb/v20170312"
 )
 
@@ -60,6 +58,7 @@ type MariadbInput struct {
 	//初始化时使用
 	CharacterSet        string `json:"character_set,omitempty"`
 	LowerCaseTableNames string `json:"lower_case_table_names,omitempty"`
+	IdempotencyKey      string `json:"idempotency_key,omitempty"`
 }
 
 type MariadbOutputs struct {
@@ -147,7 +146,11 @@ func (action *MariadbCreateAction) Do(input interface{}) (interface{}, error) {
 	req, _ := input.(MariadbInputs)
 	outputs := MariadbOutputs{}
 	for _, input := range req.Inputs {
-		output, err := action.createAndInitMariadb(&input)
+		output := MariadbOutput{}
+		idempotencyKey := getIdempotencyKey("mariadb-create", input.Guid, input.IdempotencyKey, input)
+		err := runWithIdempotencyKey(idempotencyKey, &output, func() (interface{}, error) {
+			return action.createAndInitMariadb(&input, idempotencyKey)
+		})
 		if err != nil {
 			return nil, err
 		}
@@ -159,6 +162,28 @@ func (action *MariadbCreateAction) Do(input interface{}) (interface{}, error) {
 	return &outputs, nil
 }
 
+func (action *MariadbCreateAction) DryRun(input interface{}) (interface{}, error) {
+	req, _ := input.(MariadbInputs)
+	outputs := DryRunOutputs{}
+	for _, input := range req.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(input.ProviderParams)
+		client, err := CreateMariadbClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+		if err != nil {
+			return nil, err
+		}
+
+		exist, err := isMariadbExist(client, input.Id)
+		if err != nil {
+			return nil, err
+		}
+		detail := fmt.Sprintf("create mariadb instance node_count=%d memory=%dGB storage=%dGB in zones[%s] subnet[%s]",
+			input.NodeCount, input.MemorySize, input.StorageSize, input.Zones, input.SubnetId)
+		outputs.Outputs = append(outputs.Outputs, newCreateDryRunOutput(input.Guid, input.Id, exist, detail))
+	}
+
+	return &outputs, nil
+}
+
 func isValidMariadbVersion(version string) error {
 	validVersions := []string{
 		MARIADB_VERSION_10_0_10,
@@ -178,16 +203,11 @@ func isValidMariadbVersion(version string) error {
 	return errors.New("invalid mariadb version")
 }
 
-func CreateMariadbClient(region, secretId, secretKey string) (client *mariadb.Client, err error) {
-	credential := common.NewCredential(secretId, secretKey)
-
-	clientProfile := profile.NewClientProfile()
-	clientProfile.HttpProfile.Endpoint = "mariadb.tencentcloudapi.com"
-
-	return mariadb.NewClient(credential, region, clientProfile)
+func CreateMariadbClient(region, secretId, secretKey string) (MariadbAPI, error) {
+	return QcloudClientFactory.NewMariadbClient(region, secretId, secretKey)
 }
 
-func getInstanceIdByDealName(client *mariadb.Client, dealName string) (string, error) {
+func getInstanceIdByDealName(client MariadbAPI, dealName string) (string, error) {
 	count := 0
 	request := mariadb.NewDescribeOrdersRequest()
 	request.DealNames = []*string{&dealName}
@@ -214,7 +234,24 @@ func getInstanceIdByDealName(client *mariadb.Client, dealName string) (string, e
 	}
 }
 
-func createMariadbInstance(client *mariadb.Client, input *MariadbInput) (string, string, error) {
+//mariadbCreateProgress is saved by the idempotency key once qcloud accepts the order and after each step,
+//so a retry only runs the steps left, Password is the encrypted password of the account once it is created
+type mariadbCreateProgress struct {
+	DealName    string `json:"deal_name"`
+	RequestId   string `json:"request_id"`
+	InstanceId  string `json:"instance_id,omitempty"`
+	Initialized bool   `json:"initialized,omitempty"`
+	Password    string `json:"password,omitempty"`
+}
+
+func saveMariadbCreateProgress(idempotencyKey string, progress mariadbCreateProgress) {
+	if err := saveIdempotencyProgress(idempotencyKey, progress); err != nil {
+		logrus.Errorf("save progress of mariadb deal[%s] meet err=%v", progress.DealName, err)
+	}
+}
+
+//createMariadbInstance returns the request id and the deal name of the order
+func createMariadbInstance(client MariadbAPI, input *MariadbInput) (string, string, error) {
 	zones := []*string{}
 	for _, zone := range strings.Split(input.Zones, ",") {
 		newZone := zone
@@ -236,16 +273,10 @@ func createMariadbInstance(client *mariadb.Client, input *MariadbInput) (string,
 		return "", "", err
 	}
 
-	instanceId, err := getInstanceIdByDealName(client, *resp.Response.DealName)
-	if err != nil {
-		logrus.Errorf("getInstanceIdByDealName(%s) meet error(%v)", *resp.Response.DealName, err)
-		return "", "", err
-	}
-
-	return *resp.Response.RequestId, instanceId, nil
+	return *resp.Response.RequestId, *resp.Response.DealName, nil
 }
 
-func isMariadbExist(client *mariadb.Client, instanceId string) (bool, error) {
+func isMariadbExist(client MariadbAPI, instanceId string) (bool, error) {
 	if instanceId == "" {
 		return false, nil
 	}
@@ -266,7 +297,7 @@ func isMariadbExist(client *mariadb.Client, instanceId string) (bool, error) {
 
 }
 
-func waitMariadbToDesireStatus(client *mariadb.Client, instanceId string, desireState int64) (string, int64, error) {
+func waitMariadbToDesireStatus(client MariadbAPI, instanceId string, desireState int64) (string, int64, error) {
 	count := 0
 	request := mariadb.NewDescribeDBInstancesRequest()
 	request.InstanceIds = []*string{&instanceId}
@@ -294,7 +325,7 @@ func waitMariadbToDesireStatus(client *mariadb.Client, instanceId string, desire
 
 }
 
-func waitFlowSuccess(client *mariadb.Client, flowId *int64) error {
+func waitFlowSuccess(client MariadbAPI, flowId *int64) error {
 	count := 0
 	req := mariadb.NewDescribeFlowRequest()
 	req.FlowId = flowId
@@ -320,7 +351,7 @@ func waitFlowSuccess(client *mariadb.Client, flowId *int64) error {
 	}
 }
 
-func createMariadbAccount(client *mariadb.Client, instanceId string, userName string, password string) error {
+func createMariadbAccount(client MariadbAPI, instanceId string, userName string, password string) error {
 	accessHost := "%"
 	var readOnly int64 = 0
 
@@ -335,7 +366,7 @@ func createMariadbAccount(client *mariadb.Client, instanceId string, userName st
 	return err
 }
 
-func initMariadb(client *mariadb.Client, instanceId string, charset string, lowCaseTableName string) error {
+func initMariadb(client MariadbAPI, instanceId string, charset string, lowCaseTableName string) error {
 	charSetParamName := "character_set_server"
 	lowCaseParamName := "lower_case_table_names"
 
@@ -360,7 +391,7 @@ func initMariadb(client *mariadb.Client, instanceId string, charset string, lowC
 	return waitFlowSuccess(client, resp.Response.FlowId)
 }
 
-func grantAccountPrivileges(client *mariadb.Client, userName string, instanceId string) error {
+func grantAccountPrivileges(client MariadbAPI, userName string, instanceId string) error {
 	allHost := "%"
 	allDb := "*"
 	allPrivileges := []string{
@@ -386,7 +417,7 @@ func grantAccountPrivileges(client *mariadb.Client, userName string, instanceId
 	return err
 }
 
-func (action *MariadbCreateAction) createAndInitMariadb(input *MariadbInput) (MariadbOutput, error) {
+func (action *MariadbCreateAction) createAndInitMariadb(input *MariadbInput, idempotencyKey string) (MariadbOutput, error) {
 	output := MariadbOutput{
 		Guid: input.Guid,
 		Id:   input.Id,
@@ -425,21 +456,42 @@ func (action *MariadbCreateAction) createAndInitMariadb(input *MariadbInput) (Ma
 		return output, nil
 	}
 
-	requestId, instanceId, err := createMariadbInstance(client, input)
+	//the order placed by the last try of the key is picked up from the step it stopped at
+	progress := mariadbCreateProgress{}
+	found, err := loadIdempotencyProgress(idempotencyKey, &progress)
 	if err != nil {
-		logrus.Errorf("createMariadbInstance meet error(%v)", err)
 		return output, err
 	}
+	if !found {
+		if progress.RequestId, progress.DealName, err = createMariadbInstance(client, input); err != nil {
+			logrus.Errorf("createMariadbInstance meet error(%v)", err)
+			return output, err
+		}
+		saveMariadbCreateProgress(idempotencyKey, progress)
+	}
 
-	_, _, err = waitMariadbToDesireStatus(client, instanceId, MARIADB_WAIT_INIT_STATUS)
-	if err != nil {
-		logrus.Errorf("waitMariadbToDesireState meet error(%v)", err)
-		return output, err
+	if progress.InstanceId == "" {
+		if progress.InstanceId, err = getInstanceIdByDealName(client, progress.DealName); err != nil {
+			logrus.Errorf("getInstanceIdByDealName(%s) meet error(%v)", progress.DealName, err)
+			return output, err
+		}
+		saveMariadbCreateProgress(idempotencyKey, progress)
 	}
+	instanceId := progress.InstanceId
 
-	if err = initMariadb(client, instanceId, input.CharacterSet, input.LowerCaseTableNames); err != nil {
-		logrus.Errorf("initMariadb meet error(%v)", err)
-		return output, err
+	if !progress.Initialized {
+		_, _, err = waitMariadbToDesireStatus(client, instanceId, MARIADB_WAIT_INIT_STATUS)
+		if err != nil {
+			logrus.Errorf("waitMariadbToDesireState meet error(%v)", err)
+			return output, err
+		}
+
+		if err = initMariadb(client, instanceId, input.CharacterSet, input.LowerCaseTableNames); err != nil {
+			logrus.Errorf("initMariadb meet error(%v)", err)
+			return output, err
+		}
+		progress.Initialized = true
+		saveMariadbCreateProgress(idempotencyKey, progress)
 	}
 
 	vip, vport, err := waitMariadbToDesireStatus(client, instanceId, MARIADB_RUNNING_STATUS)
@@ -448,9 +500,18 @@ func (action *MariadbCreateAction) createAndInitMariadb(input *MariadbInput) (Ma
 		return output, err
 	}
 
-	if err = createMariadbAccount(client, instanceId, input.UserName, password); err != nil {
-		logrus.Errorf("createMariadbAccount meet error(%v),password=%v", err, password)
-		return output, err
+	if progress.Password == "" {
+		if err = createMariadbAccount(client, instanceId, input.UserName, password); err != nil {
+			logrus.Errorf("createMariadbAccount meet error(%v),password=%v", err, password)
+			return output, err
+		}
+
+		md5sum := utils.Md5Encode(input.Guid + input.Seed)
+		if progress.Password, err = utils.AesEncode(md5sum[0:16], password); err != nil {
+			logrus.Errorf("AesEncode meet error(%v)", err)
+			return output, err
+		}
+		saveMariadbCreateProgress(idempotencyKey, progress)
 	}
 
 	if err = grantAccountPrivileges(client, input.UserName, instanceId); err != nil {
@@ -458,13 +519,8 @@ func (action *MariadbCreateAction) createAndInitMariadb(input *MariadbInput) (Ma
 		return output, err
 	}
 
-	md5sum := utils.Md5Encode(input.Guid + input.Seed)
-	if output.Password, err = utils.AesEncode(md5sum[0:16], password); err != nil {
-		logrus.Errorf("AesEncode meet error(%v)", err)
-		return output, err
-	}
-
-	output.RequestId = requestId
+	output.Password = progress.Password
+	output.RequestId = progress.RequestId
 	output.Id = instanceId
 	output.PrivateIp = vip
 	output.Port = fmt.Sprintf("%v", vport)
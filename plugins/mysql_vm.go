@@ -7,7 +7,6 @@ import (
 	"github.com/sirupsen/logrus"
 	cdb "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cdb/v20170320"
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
-	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
 	"time"
 )
 
@@ -24,17 +23,8 @@ func init() {
 	MysqlVmActions["restart"] = new(MysqlVmRestartAction)
 }
 
-func CreateMysqlVmClient(region, secretId, secretKey string) (client *cdb.Client, err error) {
-	credential := common.NewCredential(secretId, secretKey)
-
-	clientProfile := profile.NewClientProfile()
-	clientProfile.HttpProfile.Endpoint = "cdb.tencentcloudapi.com"
-	client, err = cdb.NewClient(credential, region, clientProfile)
-	if err != nil {
-		logrus.Errorf("CreateMysqlVmClient meet error=%v", err)
-	}
-
-	return client, err
+func CreateMysqlVmClient(region, secretId, secretKey string) (CdbAPI, error) {
+	return QcloudClientFactory.NewCdbClient(region, secretId, secretKey)
 }
 
 type MysqlVmInputs struct {
@@ -59,6 +49,7 @@ type MysqlVmInput struct {
 	//初始化时使用
 	CharacterSet        string `json:"character_set,omitempty"`
 	LowerCaseTableNames string `json:"lower_case_table_names,omitempty"`
+	IdempotencyKey      string `json:"idempotency_key,omitempty"`
 }
 
 type MysqlVmOutputs struct {
@@ -111,7 +102,7 @@ func (action *MysqlVmCreateAction) CheckParam(input interface{}) error {
 	return nil
 }
 
-func (action *MysqlVmCreateAction) createMysqlVmWithPrepaid(client *cdb.Client, mysqlVmInput *MysqlVmInput) (string, string, error) {
+func (action *MysqlVmCreateAction) createMysqlVmWithPrepaid(client CdbAPI, mysqlVmInput *MysqlVmInput) (string, string, error) {
 	request := cdb.NewCreateDBInstanceRequest()
 	request.Memory = &mysqlVmInput.Memory
 	request.Volume = &mysqlVmInput.Volume
@@ -157,7 +148,7 @@ func getZoneFromProviderParams(ProviderParams string) (string, error) {
 	return zone, nil
 }
 
-func (action *MysqlVmCreateAction) createMysqlVmWithPostByHour(client *cdb.Client, mysqlVmInput *MysqlVmInput) (string, string, error) {
+func (action *MysqlVmCreateAction) createMysqlVmWithPostByHour(client CdbAPI, mysqlVmInput *MysqlVmInput) (string, string, error) {
 	request := cdb.NewCreateDBInstanceHourRequest()
 	request.Memory = &mysqlVmInput.Memory
 	request.Volume = &mysqlVmInput.Volume
@@ -185,7 +176,7 @@ func (action *MysqlVmCreateAction) createMysqlVmWithPostByHour(client *cdb.Clien
 	return *response.Response.InstanceIds[0], *response.Response.RequestId, nil
 }
 
-func initMysqlInstance(client *cdb.Client, instanceId string, charset string, lowerCaseTableName string) (string, string, error) {
+func initMysqlInstance(client CdbAPI, instanceId string, charset string, lowerCaseTableName string) (string, string, error) {
 	var defaultPort int64 = 3306
 	password := utils.CreateRandomPassword()
 	charSetParamName := "character_set_server"
@@ -213,7 +204,7 @@ func initMysqlInstance(client *cdb.Client, instanceId string, charset string, lo
 	return password, fmt.Sprintf("%v", defaultPort), nil
 }
 
-func ensureMysqlInit(client *cdb.Client, instanceId string, charset string, lowerCaseTableName string) (string, string, error) {
+func ensureMysqlInit(client CdbAPI, instanceId string, charset string, lowerCaseTableName string) (string, string, error) {
 	maxTryNum := 20
 
 	for i := 0; i < maxTryNum; i++ {
@@ -230,7 +221,22 @@ func ensureMysqlInit(client *cdb.Client, instanceId string, charset string, lowe
 	return "", "", fmt.Errorf("timeout")
 }
 
-func (action *MysqlVmCreateAction) createMysqlVm(mysqlVmInput *MysqlVmInput) (*MysqlVmOutput, error) {
+//mysqlVmCreateProgress is saved by the idempotency key once qcloud accepts the create call,
+//the encrypted password and the port are saved after the instance is initialized with them
+type mysqlVmCreateProgress struct {
+	InstanceId string `json:"instance_id"`
+	RequestId  string `json:"request_id"`
+	Password   string `json:"password,omitempty"`
+	Port       string `json:"port,omitempty"`
+}
+
+func saveMysqlVmCreateProgress(idempotencyKey string, progress mysqlVmCreateProgress) {
+	if err := saveIdempotencyProgress(idempotencyKey, progress); err != nil {
+		logrus.Errorf("save progress of mysql vm[%s] meet err=%v", progress.InstanceId, err)
+	}
+}
+
+func (action *MysqlVmCreateAction) createMysqlVm(mysqlVmInput *MysqlVmInput, idempotencyKey string) (*MysqlVmOutput, error) {
 	paramsMap, _ := GetMapFromProviderParams(mysqlVmInput.ProviderParams)
 	client, _ := CreateMysqlVmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
 
@@ -246,17 +252,26 @@ func (action *MysqlVmCreateAction) createMysqlVm(mysqlVmInput *MysqlVmInput) (*M
 		}
 	}
 
-	var instanceId, requestId, privateIp string
-	var err error
-	if mysqlVmInput.ChargeType == CHARGE_TYPE_PREPAID {
-		instanceId, requestId, err = action.createMysqlVmWithPrepaid(client, mysqlVmInput)
-	} else {
-		instanceId, requestId, err = action.createMysqlVmWithPostByHour(client, mysqlVmInput)
-	}
+	//the instance created by the last try of the key is only waited for and initialized
+	progress := mysqlVmCreateProgress{}
+	found, err := loadIdempotencyProgress(idempotencyKey, &progress)
 	if err != nil {
 		return nil, err
 	}
+	if !found {
+		if mysqlVmInput.ChargeType == CHARGE_TYPE_PREPAID {
+			progress.InstanceId, progress.RequestId, err = action.createMysqlVmWithPrepaid(client, mysqlVmInput)
+		} else {
+			progress.InstanceId, progress.RequestId, err = action.createMysqlVmWithPostByHour(client, mysqlVmInput)
+		}
+		if err != nil {
+			return nil, err
+		}
+		saveMysqlVmCreateProgress(idempotencyKey, progress)
+	}
+	instanceId := progress.InstanceId
 
+	var privateIp string
 	if instanceId != "" {
 		privateIp, err = action.waitForMysqlVmCreationToFinish(client, instanceId)
 		if err != nil {
@@ -272,29 +287,35 @@ func (action *MysqlVmCreateAction) createMysqlVm(mysqlVmInput *MysqlVmInput) (*M
 		mysqlVmInput.LowerCaseTableNames = DEFAULT_MARIADB_LOWER_CASE_TABLE_NAMES
 	}
 
-	password, port, err := ensureMysqlInit(client, instanceId, mysqlVmInput.CharacterSet, mysqlVmInput.LowerCaseTableNames)
-	if err != nil {
-		return nil, err
+	//an instance initialized by the last try keeps its password, it can't be initialized again
+	if progress.Password == "" {
+		password, port, err := ensureMysqlInit(client, instanceId, mysqlVmInput.CharacterSet, mysqlVmInput.LowerCaseTableNames)
+		if err != nil {
+			return nil, err
+		}
+
+		md5sum := utils.Md5Encode(mysqlVmInput.Guid + mysqlVmInput.Seed)
+		if progress.Password, err = utils.AesEncode(md5sum[0:16], password); err != nil {
+			logrus.Errorf("AesEncode meet error(%v)", err)
+			return nil, err
+		}
+		progress.Port = port
+		saveMysqlVmCreateProgress(idempotencyKey, progress)
 	}
 
 	output := MysqlVmOutput{}
 	output.Guid = mysqlVmInput.Guid
 	output.PrivateIp = privateIp
 	output.Id = instanceId
-	output.RequestId = requestId
-	output.Port = port
+	output.RequestId = progress.RequestId
+	output.Port = progress.Port
 	output.UserName = "root"
-
-	md5sum := utils.Md5Encode(mysqlVmInput.Guid + mysqlVmInput.Seed)
-	if output.Password, err = utils.AesEncode(md5sum[0:16], password); err != nil {
-		logrus.Errorf("AesEncode meet error(%v)", err)
-		return &output, err
-	}
+	output.Password = progress.Password
 
 	return &output, nil
 }
 
-func queryMySqlInstanceInitFlag(client *cdb.Client, instanceId string) (int64, error) {
+func queryMySqlInstanceInitFlag(client CdbAPI, instanceId string) (int64, error) {
 	var initFlag int64 = 0
 	request := cdb.NewDescribeDBInstancesRequest()
 	request.InstanceIds = append(request.InstanceIds, &instanceId)
@@ -310,7 +331,7 @@ func queryMySqlInstanceInitFlag(client *cdb.Client, instanceId string) (int64, e
 	return *response.Response.Items[0].InitFlag, nil
 }
 
-func (action *MysqlVmCreateAction) waitForMysqlVmCreationToFinish(client *cdb.Client, instanceId string) (string, error) {
+func (action *MysqlVmCreateAction) waitForMysqlVmCreationToFinish(client CdbAPI, instanceId string) (string, error) {
 	request := cdb.NewDescribeDBInstancesRequest()
 	request.InstanceIds = append(request.InstanceIds, &instanceId)
 	count := 0
@@ -341,18 +362,45 @@ func (action *MysqlVmCreateAction) Do(input interface{}) (interface{}, error) {
 	mysqlVms, _ := input.(MysqlVmInputs)
 	outputs := MysqlVmOutputs{}
 	for _, mysqlVm := range mysqlVms.Inputs {
-		output, err := action.createMysqlVm(&mysqlVm)
+		output := MysqlVmOutput{}
+		idempotencyKey := getIdempotencyKey("mysql-vm-create", mysqlVm.Guid, mysqlVm.IdempotencyKey, mysqlVm)
+		err := runWithIdempotencyKey(idempotencyKey, &output, func() (interface{}, error) {
+			return action.createMysqlVm(&mysqlVm, idempotencyKey)
+		})
 		if err != nil {
 			return nil, err
 		}
 
-		outputs.Outputs = append(outputs.Outputs, *output)
+		outputs.Outputs = append(outputs.Outputs, output)
 	}
 
 	logrus.Infof("all mysqlVms = %v are created", mysqlVms)
 	return &outputs, nil
 }
 
+func (action *MysqlVmCreateAction) DryRun(input interface{}) (interface{}, error) {
+	mysqlVms, _ := input.(MysqlVmInputs)
+	outputs := DryRunOutputs{}
+	for _, mysqlVm := range mysqlVms.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(mysqlVm.ProviderParams)
+		client, _ := CreateMysqlVmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+
+		exist := false
+		if mysqlVm.Id != "" {
+			_, flag, err := queryMysqlVMInstancesInfo(client, &mysqlVm)
+			if err != nil {
+				return nil, err
+			}
+			exist = flag
+		}
+		detail := fmt.Sprintf("create %s mysql instance version=%s memory=%dMB volume=%dGB in subnet[%s]",
+			mysqlVm.ChargeType, mysqlVm.EngineVersion, mysqlVm.Memory, mysqlVm.Volume, mysqlVm.SubnetId)
+		outputs.Outputs = append(outputs.Outputs, newCreateDryRunOutput(mysqlVm.Guid, mysqlVm.Id, exist, detail))
+	}
+
+	return &outputs, nil
+}
+
 type MysqlVmTerminateAction struct {
 }
 
@@ -404,7 +452,7 @@ func (action *MysqlVmTerminateAction) terminateMysqlVm(mysqlVmInput *MysqlVmInpu
 	return &output, nil
 }
 
-func (action *MysqlVmTerminateAction) waitForMysqlVmTerminationToFinish(client *cdb.Client, instanceId string) error {
+func (action *MysqlVmTerminateAction) waitForMysqlVmTerminationToFinish(client CdbAPI, instanceId string) error {
 	request := cdb.NewDescribeDBInstancesRequest()
 	request.InstanceIds = append(request.InstanceIds, &instanceId)
 	count := 0
@@ -444,6 +492,23 @@ func (action *MysqlVmTerminateAction) Do(input interface{}) (interface{}, error)
 	return &outputs, nil
 }
 
+func (action *MysqlVmTerminateAction) DryRun(input interface{}) (interface{}, error) {
+	mysqlVms, _ := input.(MysqlVmInputs)
+	outputs := DryRunOutputs{}
+	for _, mysqlVm := range mysqlVms.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(mysqlVm.ProviderParams)
+		client, _ := CreateMysqlVmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+
+		_, exist, err := queryMysqlVMInstancesInfo(client, &mysqlVm)
+		if err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, newDeleteDryRunOutput(mysqlVm.Guid, mysqlVm.Id, exist, "isolate mysql instance"))
+	}
+
+	return &outputs, nil
+}
+
 type MysqlVmRestartAction struct {
 }
 
@@ -488,7 +553,7 @@ func (action *MysqlVmRestartAction) restartMysqlVm(mysqlVmInput MysqlVmInput) er
 	return waitForAsyncTaskToFinish(client, *response.Response.AsyncRequestId)
 }
 
-func waitForAsyncTaskToFinish(client *cdb.Client, requestId string) error {
+func waitForAsyncTaskToFinish(client CdbAPI, requestId string) error {
 	taskReq := cdb.NewDescribeAsyncRequestInfoRequest()
 	taskReq.AsyncRequestId = &requestId
 	count := 0
@@ -525,7 +590,29 @@ func (action *MysqlVmRestartAction) Do(input interface{}) (interface{}, error) {
 	return "", nil
 }
 
-func queryMysqlVMInstancesInfo(client *cdb.Client, input *MysqlVmInput) (*MysqlVmOutput, bool, error) {
+func (action *MysqlVmRestartAction) DryRun(input interface{}) (interface{}, error) {
+	mysqlVms, _ := input.(MysqlVmInputs)
+	outputs := DryRunOutputs{}
+	for _, mysqlVm := range mysqlVms.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(mysqlVm.ProviderParams)
+		client, _ := CreateMysqlVmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+
+		_, exist, err := queryMysqlVMInstancesInfo(client, &mysqlVm)
+		if err != nil {
+			return nil, err
+		}
+		output := newDryRunOutput(mysqlVm.Guid, mysqlVm.Id, exist, DRY_RUN_OPERATION_MODIFY, "restart mysql instance")
+		if !exist {
+			output.Operation = DRY_RUN_OPERATION_NONE
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("mysql instance[%s] not found", mysqlVm.Id))
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+
+	return &outputs, nil
+}
+
+func queryMysqlVMInstancesInfo(client CdbAPI, input *MysqlVmInput) (*MysqlVmOutput, bool, error) {
 	output := MysqlVmOutput{}
 
 	request := cdb.NewDescribeDBInstancesRequest()
@@ -6,16 +6,21 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
-	vpcb "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/vpc/v20170312"
-	vpc "github.com/zqfan/tencentcloud-sdk-go/services/vpc/unversioned"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	vpc "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/vpc/v20170312"
 )
 
-func newVpcClient(region, secretId, secretKey string) (*vpc.Client, error) {
-	return vpc.NewClientWithSecretId(
-		secretId,
-		secretKey,
-		region,
-	)
+const (
+	NAT_GATEWAY_STATE_PENDING   = "PENDING"
+	NAT_GATEWAY_STATE_AVAILABLE = "AVAILABLE"
+	NAT_GATEWAY_STATE_FAILED    = "FAILED"
+
+	NAT_GATEWAY_WAIT_TIMEOUT = 300
+)
+
+func createNatGatewayClient(providerParams string) (VpcAPI, error) {
+	paramsMap, _ := GetMapFromProviderParams(providerParams)
+	return CreateVpcClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
 }
 
 var NatGatewayActions = make(map[string]Action)
@@ -56,6 +61,7 @@ type NatGatewayInput struct {
 	Id              string `json:"id,omitempty"`
 	Eip             string `json:"eip,omitempty"`
 	EipId           string `json:"eip_id,omitempty"`
+	IdempotencyKey  string `json:"idempotency_key,omitempty"`
 }
 
 type NatGatewayOutputs struct {
@@ -97,94 +103,122 @@ func (action *NatGatewayCreateAction) CheckParam(input interface{}) error {
 	return nil
 }
 
-func (action *NatGatewayCreateAction) createNatGateway(natGateway *NatGatewayInput) (*NatGatewayOutput, error) {
-	paramsMap, _ := GetMapFromProviderParams(natGateway.ProviderParams)
-	client, _ := newVpcClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+func (action *NatGatewayCreateAction) createNatGateway(natGateway *NatGatewayInput, idempotencyKey string) (*NatGatewayOutput, error) {
+	client, err := createNatGatewayClient(natGateway.ProviderParams)
+	if err != nil {
+		return nil, err
+	}
 
 	//check resource exist
 	if natGateway.Id != "" {
-		queryNatGatewayResponse, flag, err := queryNatGatewayInfo(client, natGateway)
-		if err != nil && flag == false {
+		existed, found, err := queryNatGatewayById(client, natGateway.Id)
+		if err != nil {
 			return nil, err
 		}
-
-		if err == nil && flag == true {
-			return queryNatGatewayResponse, nil
+		if found {
+			output := newNatGatewayOutput(natGateway.Guid, "", existed)
+			return &output, nil
 		}
 	}
 
-	createReq := vpc.NewCreateNatGatewayRequest()
-	createReq.VpcId = &natGateway.VpcId
-	createReq.NatName = &natGateway.Name
-	createReq.MaxConcurrent = &natGateway.MaxConcurrent
-	createReq.Bandwidth = &natGateway.BandWidth
-	createReq.AutoAllocEipNum = &natGateway.AutoAllocEipNum
-
-	if natGateway.AssignedEipSet != "" {
-		createReq.AssignedEipSet = []*string{&natGateway.AssignedEipSet}
+	progress, err := loadIdempotencyCreatedIds(idempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	if progress == nil {
+		if progress, err = submitNatGatewayCreation(client, natGateway); err != nil {
+			return nil, err
+		}
+		saveIdempotencyCreatedIds(idempotencyKey, progress.RequestId, progress.Ids...)
 	}
+	natGatewayId := progress.Ids[0]
 
-	createResp, err := client.CreateNatGateway(createReq)
-	if err != nil || createResp.NatGatewayId == nil {
+	created, err := waitNatGatewayUntil(client, natGatewayId, func(natGateway *vpc.NatGateway) bool {
+		return natGateway == nil || stringValue(natGateway.State) != NAT_GATEWAY_STATE_PENDING
+	})
+	if err != nil {
 		return nil, err
 	}
-	output := NatGatewayOutput{}
-	output.Guid = natGateway.Guid
-	output.RequestId = "legacy qcloud API doesn't support returnning request id"
-	output.Id = *createResp.NatGatewayId
+	if created == nil {
+		return nil, fmt.Errorf("nat gateway[%s] not found", natGatewayId)
+	}
+	if state := stringValue(created.State); state != NAT_GATEWAY_STATE_AVAILABLE {
+		return nil, fmt.Errorf("nat gateway[%s] is %s after created", natGatewayId, state)
+	}
+	output := newNatGatewayOutput(natGateway.Guid, progress.RequestId, created)
+	return &output, nil
+}
 
-	//query eip infp
-	req := vpcb.NewDescribeAddressesRequest()
-	Client, err := CreateEIPClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+func submitNatGatewayCreation(client VpcAPI, natGateway *NatGatewayInput) (*idempotencyCreateProgress, error) {
+	request := vpc.NewCreateNatGatewayRequest()
+	request.VpcId = &natGateway.VpcId
+	request.NatGatewayName = &natGateway.Name
+	if natGateway.MaxConcurrent > 0 {
+		request.MaxConcurrentConnection = common.Uint64Ptr(uint64(natGateway.MaxConcurrent))
+	}
+	if natGateway.BandWidth > 0 {
+		request.InternetMaxBandwidthOut = common.Uint64Ptr(uint64(natGateway.BandWidth))
+	}
+	if natGateway.AutoAllocEipNum > 0 {
+		request.AddressCount = common.Uint64Ptr(uint64(natGateway.AutoAllocEipNum))
+	}
+	if eips := splitCommaValues(natGateway.AssignedEipSet); len(eips) > 0 {
+		request.PublicIpAddresses = common.StringPtrs(eips)
+	}
+	response, err := client.CreateNatGateway(request)
 	if err != nil {
 		return nil, err
 	}
-	count := 0
-	for {
-		queryEIPResponse, err := Client.DescribeAddresses(req)
-		if err != nil {
-			return nil, fmt.Errorf("query eip info meet error : %s", err)
-		}
-		if len(queryEIPResponse.Response.AddressSet) == 0 {
-			continue
-		}
-		flag := false
-		for _, eip := range queryEIPResponse.Response.AddressSet {
-			if *eip.AddressStatus == "BIND" && *eip.InstanceId == output.Id {
-				output.Eip = *eip.AddressIp
-				output.EipId = *eip.AddressId
-				flag = true
-				break
-			}
-		}
-		if flag {
-			break
-		}
-		if count > 20 {
-			return nil, fmt.Errorf("query nat eip info timeout")
-		}
-		count++
+	if len(response.Response.NatGatewaySet) == 0 {
+		return nil, fmt.Errorf("create nat gateway[%s] returns no nat gateway", natGateway.Name)
 	}
-
-	return &output, nil
+	natGatewayId := *response.Response.NatGatewaySet[0].NatGatewayId
+	logrus.Infof("Create nat gateway[%v] has been submitted, Id is [%v], RequestID is [%v]", natGateway.Name, natGatewayId, *response.Response.RequestId)
+	return &idempotencyCreateProgress{Ids: []string{natGatewayId}, RequestId: *response.Response.RequestId}, nil
 }
 
 func (action *NatGatewayCreateAction) Do(input interface{}) (interface{}, error) {
 	natGateways, _ := input.(NatGatewayInputs)
 	outputs := NatGatewayOutputs{}
 	for _, natGateway := range natGateways.Inputs {
-		output, err := action.createNatGateway(&natGateway)
+		output := NatGatewayOutput{}
+		idempotencyKey := getIdempotencyKey("nat-gateway-create", natGateway.Guid, natGateway.IdempotencyKey, natGateway)
+		err := runWithIdempotencyKey(idempotencyKey, &output, func() (interface{}, error) {
+			return action.createNatGateway(&natGateway, idempotencyKey)
+		})
 		if err != nil {
 			return nil, err
 		}
 
-		outputs.Outputs = append(outputs.Outputs, *output)
+		outputs.Outputs = append(outputs.Outputs, output)
 	}
 
 	logrus.Infof("all natGateways = %v are created", natGateways)
 	return &outputs, nil
 }
 
+func (action *NatGatewayCreateAction) DryRun(input interface{}) (interface{}, error) {
+	natGateways, _ := input.(NatGatewayInputs)
+	outputs := DryRunOutputs{}
+	for _, natGateway := range natGateways.Inputs {
+		client, err := createNatGatewayClient(natGateway.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		exist := false
+		if natGateway.Id != "" {
+			if _, exist, err = queryNatGatewayById(client, natGateway.Id); err != nil {
+				return nil, err
+			}
+		}
+		detail := fmt.Sprintf("create nat gateway name=%s in vpc[%s] with %d auto allocated eip", natGateway.Name, natGateway.VpcId, natGateway.AutoAllocEipNum)
+		outputs.Outputs = append(outputs.Outputs, newCreateDryRunOutput(natGateway.Guid, natGateway.Id, exist, detail))
+	}
+
+	return &outputs, nil
+}
+
 type NatGatewayTerminateAction struct {
 }
 
@@ -212,46 +246,33 @@ func (action *NatGatewayTerminateAction) CheckParam(input interface{}) error {
 	return nil
 }
 
+//terminateNatGateway waits until the nat gateway is gone, nat gateways already deleted are skipped
 func (action *NatGatewayTerminateAction) terminateNatGateway(natGateway *NatGatewayInput) (*NatGatewayOutput, error) {
-	paramsMap, _ := GetMapFromProviderParams(natGateway.ProviderParams)
-	c, _ := newVpcClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
-
-	deleteReq := vpc.NewDeleteNatGatewayRequest()
-	deleteReq.VpcId = &natGateway.VpcId
-	deleteReq.NatId = &natGateway.Id
-	deleteResp, err := c.DeleteNatGateway(deleteReq)
+	client, err := createNatGatewayClient(natGateway.ProviderParams)
 	if err != nil {
 		return nil, err
 	}
 
-	taskReq := vpc.NewDescribeVpcTaskResultRequest()
-	taskReq.TaskId = deleteResp.TaskId
-	count := 0
-	for {
-		taskResp, err := c.DescribeVpcTaskResult(taskReq)
-		if err != nil {
-			return nil, err
-		}
-
-		if *taskResp.Data.Status == 0 {
-			break
-		}
-		if *taskResp.Data.Status == 1 {
-			return nil, fmt.Errorf("terminateNatGateway execute failed, err = %v", *taskResp.Data.Output.ErrorMsg)
-		}
-
-		time.Sleep(10 * time.Second)
-		count++
-		if count >= 20 {
-			return nil, fmt.Errorf("terminateNatGateway query result timeout")
-		}
+	output := NatGatewayOutput{Guid: natGateway.Guid, Id: natGateway.Id}
+	if _, found, err := queryNatGatewayById(client, natGateway.Id); err != nil {
+		return nil, err
+	} else if !found {
+		logrus.Infof("nat gateway[%s] is already deleted", natGateway.Id)
+		return &output, nil
 	}
 
-	output := NatGatewayOutput{}
-	output.Guid = natGateway.Guid
-	output.RequestId = "legacy qcloud API doesn't support returnning request id"
-	output.Id = natGateway.Id
+	request := vpc.NewDeleteNatGatewayRequest()
+	request.NatGatewayId = &natGateway.Id
+	response, err := client.DeleteNatGateway(request)
+	if err != nil {
+		return nil, err
+	}
+	logrus.Infof("Delete nat gateway[%v] has been submitted, RequestID is [%v]", natGateway.Id, *response.Response.RequestId)
 
+	if _, err = waitNatGatewayUntil(client, natGateway.Id, func(natGateway *vpc.NatGateway) bool { return natGateway == nil }); err != nil {
+		return nil, err
+	}
+	output.RequestId = *response.Response.RequestId
 	return &output, nil
 }
 
@@ -269,27 +290,70 @@ func (action *NatGatewayTerminateAction) Do(input interface{}) (interface{}, err
 	return &outputs, nil
 }
 
-func queryNatGatewayInfo(client *vpc.Client, input *NatGatewayInput) (*NatGatewayOutput, bool, error) {
-	output := NatGatewayOutput{}
+func (action *NatGatewayTerminateAction) DryRun(input interface{}) (interface{}, error) {
+	natGateways, _ := input.(NatGatewayInputs)
+	outputs := DryRunOutputs{}
+	for _, natGateway := range natGateways.Inputs {
+		client, err := createNatGatewayClient(natGateway.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		_, exist, err := queryNatGatewayById(client, natGateway.Id)
+		if err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, newDeleteDryRunOutput(natGateway.Guid, natGateway.Id, exist, "delete nat gateway"))
+	}
 
-	request := vpc.NewDescribeNatGatewayRequest()
-	request.NatId = &input.Id
-	response, err := client.DescribeNatGateway(request)
+	return &outputs, nil
+}
+
+//queryNatGatewayById returns false if the nat gateway is not found
+func queryNatGatewayById(client VpcAPI, natGatewayId string) (*vpc.NatGateway, bool, error) {
+	request := vpc.NewDescribeNatGatewaysRequest()
+	request.NatGatewayIds = []*string{&natGatewayId}
+	response, err := client.DescribeNatGateways(request)
 	if err != nil {
+		logrus.Errorf("vpc DescribeNatGateways meet err=%v", err)
 		return nil, false, err
 	}
-	if len(response.Data) == 0 {
+	if len(response.Response.NatGatewaySet) == 0 {
 		return nil, false, nil
 	}
-	if len(response.Data) > 1 {
-		logrus.Errorf("query natgateway id=%s info find more than 1", input.Id)
-		return nil, false, fmt.Errorf("query natgateway id=%s info find more than 1", input.Id)
+	return response.Response.NatGatewaySet[0], true, nil
+}
+
+//waitNatGatewayUntil polls the nat gateway until it meets the condition, the condition gets nil if the nat gateway is not found
+func waitNatGatewayUntil(client VpcAPI, natGatewayId string, condition func(natGateway *vpc.NatGateway) bool) (*vpc.NatGateway, error) {
+	count := 0
+	for {
+		time.Sleep(waitPollInterval)
+		natGateway, _, err := queryNatGatewayById(client, natGatewayId)
+		if err != nil {
+			return nil, err
+		}
+		if condition(natGateway) {
+			return natGateway, nil
+		}
+
+		count++
+		if count*WAIT_POLL_SECONDS > NAT_GATEWAY_WAIT_TIMEOUT {
+			return nil, fmt.Errorf("qcloud wait nat gateway[%s] timeout", natGatewayId)
+		}
 	}
-	output.Guid = input.Guid
-	output.Id = input.Id
-	output.Eip = input.Eip
-	output.EipId = input.EipId
-	output.RequestId = "legacy qcloud API doesn't support returnning request id"
+}
 
-	return &output, true, nil
+//newNatGatewayOutput takes the first public ip of the nat gateway as its eip
+func newNatGatewayOutput(guid string, requestId string, natGateway *vpc.NatGateway) NatGatewayOutput {
+	output := NatGatewayOutput{
+		Guid:      guid,
+		RequestId: requestId,
+		Id:        stringValue(natGateway.NatGatewayId),
+	}
+	if len(natGateway.PublicIpAddressSet) > 0 {
+		output.Eip = stringValue(natGateway.PublicIpAddressSet[0].PublicIpAddress)
+		output.EipId = stringValue(natGateway.PublicIpAddressSet[0].AddressId)
+	}
+	return output
 }
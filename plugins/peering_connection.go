@@ -3,18 +3,57 @@ package plugins
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
-	vpcExtend "github.com/WeBankPartners/wecube-plugins-qcloud/extend/qcloud"
 	"github.com/sirupsen/logrus"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	tchttp "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/http"
+	vpc "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/vpc/v20170312"
 )
 
-func newVpcPeeringConnectionClient(region, secretId, secretKey string) (*vpcExtend.Client, error) {
-	return vpcExtend.NewClientWithSecretId(
-		secretId,
-		secretKey,
-		region,
-	)
+const (
+	PEERING_CONNECTION_STATE_PENDING = "PENDING"
+	PEERING_CONNECTION_STATE_ACTIVE  = "ACTIVE"
+	PEERING_CONNECTION_STATE_DELETED = "DELETED"
+
+	PEERING_CONNECTION_WAIT_TIMEOUT = 200
+)
+
+func createPeeringConnectionClient(providerParams string) (VpcAPI, error) {
+	paramsMap, _ := GetMapFromProviderParams(providerParams)
+	return CreateVpcClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+}
+
+//the vendored sdk doesn't have the peering connection apis, they are sent as json like the sdk requests
+type createVpcPeeringConnectionRequest struct {
+	*tchttp.BaseRequest
+	SourceVpcId           *string `json:"SourceVpcId,omitempty" name:"SourceVpcId"`
+	PeeringConnectionName *string `json:"PeeringConnectionName,omitempty" name:"PeeringConnectionName"`
+	DestinationVpcId      *string `json:"DestinationVpcId,omitempty" name:"DestinationVpcId"`
+	DestinationUin        *string `json:"DestinationUin,omitempty" name:"DestinationUin"`
+	DestinationRegion     *string `json:"DestinationRegion,omitempty" name:"DestinationRegion"`
+	Bandwidth             *uint64 `json:"Bandwidth,omitempty" name:"Bandwidth"`
+}
+
+type createVpcPeeringConnectionResponse struct {
+	*tchttp.BaseResponse
+	Response *struct {
+		PeeringConnectionId *string `json:"PeeringConnectionId,omitempty" name:"PeeringConnectionId"`
+		RequestId           *string `json:"RequestId,omitempty" name:"RequestId"`
+	} `json:"Response"`
+}
+
+type deleteVpcPeeringConnectionRequest struct {
+	*tchttp.BaseRequest
+	PeeringConnectionId *string `json:"PeeringConnectionId,omitempty" name:"PeeringConnectionId"`
+}
+
+type deleteVpcPeeringConnectionResponse struct {
+	*tchttp.BaseResponse
+	Response *struct {
+		RequestId *string `json:"RequestId,omitempty" name:"RequestId"`
+	} `json:"Response"`
 }
 
 var PeeringConnectionActions = make(map[string]Action)
@@ -92,104 +131,161 @@ func (action *PeeringConnectionCreateAction) CheckParam(input interface{}) error
 	return nil
 }
 
-func (action *PeeringConnectionCreateAction) createPeeringConnectionAtSameRegion(client *vpcExtend.Client, peeringConnection PeeringConnectionInput, paramsMap map[string]string) (string, error) {
-	createReq := vpcExtend.NewCreateVpcPeeringConnectionRequest()
-	createReq.VpcId = &peeringConnection.VpcId
-	createReq.PeerVpcId = &peeringConnection.PeerVpcId
-	createReq.PeeringConnectionName = &peeringConnection.Name
-	createReq.PeerUin = &peeringConnection.PeerUin
-
-	createResp, err := client.CreateVpcPeeringConnection(createReq)
-	if err != nil || createResp.PeeringConnectionId == nil {
-		return "", err
-	}
-	return *createResp.PeeringConnectionId, nil
-}
-func (action *PeeringConnectionCreateAction) createPeeringConnectionCrossRegion(client *vpcExtend.Client, peeringConnection PeeringConnectionInput, paramsMap map[string]string) (string, error) {
-	createReq := vpcExtend.NewCreateVpcPeeringConnectionExRequest()
-	createReq.VpcId = &peeringConnection.VpcId
-	createReq.PeerVpcId = &peeringConnection.PeerVpcId
-	createReq.PeeringConnectionName = &peeringConnection.Name
-	createReq.PeerUin = &peeringConnection.PeerUin
-	region := paramsMap["Region"]
-	createReq.PeerRegion = &region
-	createReq.Bandwidth = &peeringConnection.Bandwidth
-
-	createResp, err := client.CreateVpcPeeringConnectionEx(createReq)
+func (action *PeeringConnectionCreateAction) createPeeringConnection(peeringConnection PeeringConnectionInput) (*PeeringConnectionOutput, error) {
+	paramsMap, _ := GetMapFromProviderParams(peeringConnection.ProviderParams)
+	peerParamsMap, _ := GetMapFromProviderParams(peeringConnection.PeerProviderParams)
+	client, err := createPeeringConnectionClient(peeringConnection.ProviderParams)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	logrus.Infof("createPeeringConnection is completed, UniqVpcPeerId = %v", createResp.UniqVpcPeerId)
 
-	taskReq := vpcExtend.NewDescribeVpcTaskResultRequest()
-	taskReq.TaskId = createResp.TaskId
-	count := 0
-	for {
-		taskResp, err := client.DescribeVpcTaskResult(taskReq)
+	output := PeeringConnectionOutput{Guid: peeringConnection.Guid}
+	//check resource exist
+	if peeringConnection.Id != "" {
+		_, found, err := queryPeeringConnectionById(client, peeringConnection.Id)
 		if err != nil {
-			return "", err
-		}
-
-		if *taskResp.Data.Status == 0 {
-			return *taskResp.Data.Output.UniqVpcPeerId, nil
-		}
-		if *taskResp.Data.Status == 1 {
-			return "", errors.New("createPeeringConnection execute failed ,need retry")
+			return nil, err
 		}
-
-		time.Sleep(10 * time.Second)
-		count++
-		if count >= 20 {
-			return "", errors.New("createPeeringConnection query result timeout")
+		if found {
+			output.Id = peeringConnection.Id
+			return &output, nil
 		}
 	}
 
-	return "", nil
-}
-
-func (action *PeeringConnectionCreateAction) createPeeringConnection(peeringConnection PeeringConnectionInput) (string, error) {
-	paramsMap, _ := GetMapFromProviderParams(peeringConnection.ProviderParams)
-	peerParamsMap, _ := GetMapFromProviderParams(peeringConnection.PeerProviderParams)
-	client, _ := newVpcPeeringConnectionClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+	if err := checkPeeringVpcCidrsNotOverlapped(peeringConnection); err != nil {
+		return nil, err
+	}
 
-	//check resource exist
-	if peeringConnection.Id != "" {
-		PeeringConnectionId, err := queryPeeringConnectionsInfo(client, peeringConnection)
-		if err != nil && PeeringConnectionId == "" {
-			return "", err
+	request := &createVpcPeeringConnectionRequest{BaseRequest: &tchttp.BaseRequest{}}
+	request.Init().WithApiInfo("vpc", vpc.APIVersion, "CreateVpcPeeringConnection")
+	request.SourceVpcId = &peeringConnection.VpcId
+	request.PeeringConnectionName = &peeringConnection.Name
+	request.DestinationVpcId = &peeringConnection.PeerVpcId
+	request.DestinationUin = &peeringConnection.PeerUin
+	destinationRegion := paramsMap["Region"]
+	if peerParamsMap["Region"] != "" {
+		destinationRegion = peerParamsMap["Region"]
+	}
+	request.DestinationRegion = &destinationRegion
+	if peeringConnection.Bandwidth != "" {
+		bandwidth, err := strconv.ParseUint(peeringConnection.Bandwidth, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("peering connection bandwidth(%s) is not a number", peeringConnection.Bandwidth)
 		}
+		request.Bandwidth = common.Uint64Ptr(bandwidth)
+	}
 
-		if err == nil && PeeringConnectionId != "" {
-			return PeeringConnectionId, nil
-		}
+	response := &createVpcPeeringConnectionResponse{BaseResponse: &tchttp.BaseResponse{}}
+	if err := client.Send(request, response); err != nil {
+		logrus.Errorf("vpc CreateVpcPeeringConnection meet err=%v", err)
+		return nil, err
 	}
+	peeringConnectionId := *response.Response.PeeringConnectionId
+	logrus.Infof("Create peering connection[%v] has been submitted, Id is [%v], RequestID is [%v]", peeringConnection.Name, peeringConnectionId, *response.Response.RequestId)
 
-	if paramsMap["Region"] == peerParamsMap["Region"] {
-		return action.createPeeringConnectionAtSameRegion(client, peeringConnection, paramsMap)
-	} else {
-		return action.createPeeringConnectionCrossRegion(client, peeringConnection, peerParamsMap)
+	//a peering connection across accounts stays pending until the peer accepts it
+	created, err := waitPeeringConnectionUntil(client, peeringConnectionId, func(peeringConnection *vpcPeeringConnection) bool {
+		return peeringConnection != nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if state := stringValue(created.State); state != PEERING_CONNECTION_STATE_ACTIVE && state != PEERING_CONNECTION_STATE_PENDING {
+		return nil, fmt.Errorf("peering connection[%s] is %s after created", peeringConnectionId, state)
 	}
+	output.Id = peeringConnectionId
+	output.RequestId = *response.Response.RequestId
+	return &output, nil
 }
 
 func (action *PeeringConnectionCreateAction) Do(input interface{}) (interface{}, error) {
 	peeringConnections, _ := input.(PeeringConnectionInputs)
 	outputs := PeeringConnectionOutputs{}
 	for _, peeringConnection := range peeringConnections.Inputs {
-		peeringConnectionId, err := action.createPeeringConnection(peeringConnection)
+		output, err := action.createPeeringConnection(peeringConnection)
 		if err != nil {
 			return nil, err
 		}
-		output := PeeringConnectionOutput{}
-		output.Id = peeringConnectionId
-		output.Guid = peeringConnection.Guid
-		output.RequestId = "legacy qcloud API doesn't support returnning request id"
-		outputs.Outputs = append(outputs.Outputs, output)
+		outputs.Outputs = append(outputs.Outputs, *output)
 	}
 
 	logrus.Infof("all PeeringConnections = %v are created", peeringConnections)
 	return &outputs, nil
 }
 
+func (action *PeeringConnectionCreateAction) DryRun(input interface{}) (interface{}, error) {
+	peeringConnections, _ := input.(PeeringConnectionInputs)
+	outputs := DryRunOutputs{}
+	for _, peeringConnection := range peeringConnections.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(peeringConnection.ProviderParams)
+		peerParamsMap, _ := GetMapFromProviderParams(peeringConnection.PeerProviderParams)
+		client, err := createPeeringConnectionClient(peeringConnection.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		exist := false
+		if peeringConnection.Id != "" {
+			if _, exist, err = queryPeeringConnectionById(client, peeringConnection.Id); err != nil {
+				return nil, err
+			}
+		}
+		detail := fmt.Sprintf("create peering connection name=%s between vpc[%s] in region[%s] and vpc[%s] in region[%s]",
+			peeringConnection.Name, peeringConnection.VpcId, paramsMap["Region"], peeringConnection.PeerVpcId, peerParamsMap["Region"])
+		output := newCreateDryRunOutput(peeringConnection.Guid, peeringConnection.Id, exist, detail)
+		if !exist {
+			if err := checkPeeringVpcCidrsNotOverlapped(peeringConnection); err != nil {
+				output.Conflicts = append(output.Conflicts, err.Error())
+			}
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+
+	return &outputs, nil
+}
+
+//checkPeeringVpcCidrsNotOverlapped returns an error if any cidr of the vpc overlaps a cidr of the peer vpc,
+//the peer vpc is queried with peer_provider_params falling back to provider_params and skipped if it can't be seen
+func checkPeeringVpcCidrsNotOverlapped(peeringConnection PeeringConnectionInput) error {
+	paramsMap, _ := GetMapFromProviderParams(peeringConnection.ProviderParams)
+	peerParamsMap, _ := GetMapFromProviderParams(peeringConnection.PeerProviderParams)
+	for _, key := range []string{"Region", "SecretID", "SecretKey"} {
+		if peerParamsMap[key] == "" {
+			peerParamsMap[key] = paramsMap[key]
+		}
+	}
+
+	client, err := CreateVpcClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+	if err != nil {
+		return err
+	}
+	localVpc, err := getVpcById(client, peeringConnection.VpcId)
+	if err != nil {
+		return err
+	}
+	peerClient, err := CreateVpcClient(peerParamsMap["Region"], peerParamsMap["SecretID"], peerParamsMap["SecretKey"])
+	if err != nil {
+		return err
+	}
+	peerVpc, found, err := queryVpcById(peerClient, peeringConnection.PeerVpcId)
+	if err != nil {
+		return err
+	}
+	if !found {
+		logrus.Infof("peer vpc[%s] is not found in region[%s], skip the cidr overlap check", peeringConnection.PeerVpcId, peerParamsMap["Region"])
+		return nil
+	}
+
+	for _, cidrBlock := range getVpcCidrBlocks(localVpc) {
+		for _, peerCidrBlock := range getVpcCidrBlocks(peerVpc) {
+			if isCidrOverlap(cidrBlock, peerCidrBlock) {
+				return fmt.Errorf("cidr block(%s) of vpc[%s] overlaps with %s of peer vpc[%s]", cidrBlock, peeringConnection.VpcId, peerCidrBlock, peeringConnection.PeerVpcId)
+			}
+		}
+	}
+	return nil
+}
+
 type PeeringConnectionTerminateAction struct {
 }
 
@@ -223,101 +319,107 @@ func (action *PeeringConnectionTerminateAction) CheckParam(input interface{}) er
 	return nil
 }
 
-func (action *PeeringConnectionTerminateAction) deletePeeringConnectionAtSameRegion(client *vpcExtend.Client, peeringConnection PeeringConnectionInput) error {
-	request := vpcExtend.NewDeleteVpcPeeringConnectionRequest()
-	request.PeeringConnectionId = &peeringConnection.Id
-	response, err := client.DeletePeeringConnection(request)
+//terminatePeeringConnection waits until the peering connection is gone, peering connections already deleted are skipped
+func (action *PeeringConnectionTerminateAction) terminatePeeringConnection(peeringConnection PeeringConnectionInput) (*PeeringConnectionOutput, error) {
+	client, err := createPeeringConnectionClient(peeringConnection.ProviderParams)
 	if err != nil {
-		return fmt.Errorf("terminate peering connection(id = %v) in cloud meet error = %v", peeringConnection.Id, err)
+		return nil, err
 	}
 
-	logrus.Infof("terminate peering connection task id = %v", response.TaskId)
-	return nil
-}
-
-func (action *PeeringConnectionTerminateAction) deletePeeringConnectionCrossRegion(client *vpcExtend.Client, peeringConnection PeeringConnectionInput) error {
-	request := vpcExtend.NewDeleteVpcPeeringConnectionExRequest()
-	request.PeeringConnectionId = &peeringConnection.Id
-	response, err := client.DeletePeeringConnectionEx(request)
-	if err != nil {
-		return fmt.Errorf("terminate peering connection(id = %v) in cloud meet error = %v", peeringConnection.Id, err)
+	output := PeeringConnectionOutput{Guid: peeringConnection.Guid, Id: peeringConnection.Id}
+	if _, found, err := queryPeeringConnectionById(client, peeringConnection.Id); err != nil {
+		return nil, err
+	} else if !found {
+		logrus.Infof("peering connection[%s] is already deleted", peeringConnection.Id)
+		return &output, nil
 	}
 
-	taskReq := vpcExtend.NewDescribeVpcTaskResultRequest()
-	taskReq.TaskId = response.TaskId
-	count := 0
-	for {
-		taskResp, err := client.DescribeVpcTaskResult(taskReq)
-		if err != nil {
-			return err
-		}
-
-		if *taskResp.Data.Status == 0 {
-			return nil
-		}
-		if *taskResp.Data.Status == 1 {
-			return errors.New("terminatePeeringConnection execute failed ,need retry")
-		}
-
-		time.Sleep(10 * time.Second)
-		count++
-		if count >= 20 {
-			return errors.New("terminatePeeringConnection query result timeout")
-		}
+	request := &deleteVpcPeeringConnectionRequest{BaseRequest: &tchttp.BaseRequest{}}
+	request.Init().WithApiInfo("vpc", vpc.APIVersion, "DeleteVpcPeeringConnection")
+	request.PeeringConnectionId = &peeringConnection.Id
+	response := &deleteVpcPeeringConnectionResponse{BaseResponse: &tchttp.BaseResponse{}}
+	if err := client.Send(request, response); err != nil {
+		return nil, fmt.Errorf("terminate peering connection(id = %v) in cloud meet error = %v", peeringConnection.Id, err)
 	}
+	logrus.Infof("Delete peering connection[%v] has been submitted, RequestID is [%v]", peeringConnection.Id, *response.Response.RequestId)
 
-	logrus.Infof("terminate peering connection task id = %v", response.TaskId)
-	return nil
-}
-
-func (action *PeeringConnectionTerminateAction) terminatePeeringConnection(peeringConnection PeeringConnectionInput) error {
-	paramsMap, _ := GetMapFromProviderParams(peeringConnection.ProviderParams)
-	peerParamsMap, _ := GetMapFromProviderParams(peeringConnection.PeerProviderParams)
-	client, _ := newVpcPeeringConnectionClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
-
-	if paramsMap["Region"] == peerParamsMap["Region"] {
-		return action.deletePeeringConnectionAtSameRegion(client, peeringConnection)
-	} else {
-		return action.deletePeeringConnectionCrossRegion(client, peeringConnection)
+	_, err = waitPeeringConnectionUntil(client, peeringConnection.Id, func(peeringConnection *vpcPeeringConnection) bool {
+		return peeringConnection == nil || stringValue(peeringConnection.State) == PEERING_CONNECTION_STATE_DELETED
+	})
+	if err != nil {
+		return nil, err
 	}
+	output.RequestId = *response.Response.RequestId
+	return &output, nil
 }
 
 func (action *PeeringConnectionTerminateAction) Do(input interface{}) (interface{}, error) {
 	peeringConnections, _ := input.(PeeringConnectionInputs)
 	outputs := PeeringConnectionOutputs{}
 	for _, peeringConnection := range peeringConnections.Inputs {
-		err := action.terminatePeeringConnection(peeringConnection)
+		output, err := action.terminatePeeringConnection(peeringConnection)
 		if err != nil {
 			return nil, err
 		}
-		output := PeeringConnectionOutput{}
-		output.Guid = peeringConnection.Guid
-		output.RequestId = "legacy qcloud API doesn't support returnning request id"
-		output.Id = peeringConnection.Id
-		outputs.Outputs = append(outputs.Outputs, output)
+		outputs.Outputs = append(outputs.Outputs, *output)
 	}
 
 	return &outputs, nil
 }
 
-func queryPeeringConnectionsInfo(client *vpcExtend.Client, input PeeringConnectionInput) (string, error) {
+func (action *PeeringConnectionTerminateAction) DryRun(input interface{}) (interface{}, error) {
+	peeringConnections, _ := input.(PeeringConnectionInputs)
+	outputs := DryRunOutputs{}
+	for _, peeringConnection := range peeringConnections.Inputs {
+		client, err := createPeeringConnectionClient(peeringConnection.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
 
-	request := vpcExtend.NewDescribeVpcPeeringConnectionRequest()
-	request.PeeringConnectionId = &input.Id
-	response, err := client.DescribeVpcPeeringConnections(request)
-	if err != nil {
-		logrus.Errorf("query peeringconnections id=%s meet error:", err)
-		return "", err
+		_, exist, err := queryPeeringConnectionById(client, peeringConnection.Id)
+		if err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, newDeleteDryRunOutput(peeringConnection.Guid, peeringConnection.Id, exist, "delete peering connection"))
 	}
 
-	if len(response.Data) == 0 {
-		return "", nil
+	return &outputs, nil
+}
+
+//queryPeeringConnectionById returns false if the peering connection is not found
+func queryPeeringConnectionById(client VpcAPI, peeringConnectionId string) (*vpcPeeringConnection, bool, error) {
+	request := &describeVpcPeeringConnectionsRequest{BaseRequest: &tchttp.BaseRequest{}}
+	request.Init().WithApiInfo("vpc", vpc.APIVersion, "DescribeVpcPeeringConnections")
+	request.Filters = []*vpc.Filter{newVpcFilter("peering-connection-id", peeringConnectionId)}
+	response := &describeVpcPeeringConnectionsResponse{BaseResponse: &tchttp.BaseResponse{}}
+	if err := client.Send(request, response); err != nil {
+		logrus.Errorf("vpc DescribeVpcPeeringConnections meet err=%v", err)
+		return nil, false, err
 	}
 
-	if len(response.Data) > 1 {
-		logrus.Errorf("query peeringconnections id=%s info find more than 1", input.Id)
-		return "", fmt.Errorf("query peeringconnections id=%s info find more than 1", input.Id)
+	if len(response.Response.PeerConnectionSet) == 0 {
+		return nil, false, nil
 	}
+	return response.Response.PeerConnectionSet[0], true, nil
+}
+
+//waitPeeringConnectionUntil polls the peering connection until it meets the condition,
+//the condition gets nil if the peering connection is not found
+func waitPeeringConnectionUntil(client VpcAPI, peeringConnectionId string, condition func(peeringConnection *vpcPeeringConnection) bool) (*vpcPeeringConnection, error) {
+	count := 0
+	for {
+		time.Sleep(waitPollInterval)
+		peeringConnection, _, err := queryPeeringConnectionById(client, peeringConnectionId)
+		if err != nil {
+			return nil, err
+		}
+		if condition(peeringConnection) {
+			return peeringConnection, nil
+		}
 
-	return input.Id, nil
+		count++
+		if count*WAIT_POLL_SECONDS > PEERING_CONNECTION_WAIT_TIMEOUT {
+			return nil, fmt.Errorf("qcloud wait peering connection[%s] timeout", peeringConnectionId)
+		}
+	}
 }
@@ -0,0 +1,98 @@
+package plugins
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckPeeringVpcCidrsNotOverlapped(t *testing.T) {
+	cases := []struct {
+		name      string
+		responses []mockResponse
+		wantErr   string
+		wantCalls []string
+	}{
+		{
+			name: "disjoint",
+			responses: []mockResponse{
+				mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16"}]}`),
+				mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-2","CidrBlock":"10.1.0.0/16"}]}`),
+			},
+			wantCalls: []string{"vpc.DescribeVpcs", "vpc.DescribeVpcs"},
+		},
+		{
+			name: "secondary cidr overlaps",
+			responses: []mockResponse{
+				mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16","AssistantCidrSet":[{"CidrBlock":"172.16.0.0/16"}]}]}`),
+				mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-2","CidrBlock":"172.16.1.0/24"}]}`),
+			},
+			wantErr:   "cidr block(172.16.0.0/16) of vpc[vpc-1] overlaps with 172.16.1.0/24 of peer vpc[vpc-2]",
+			wantCalls: []string{"vpc.DescribeVpcs", "vpc.DescribeVpcs"},
+		},
+		{
+			name: "peer vpc not visible",
+			responses: []mockResponse{
+				mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16"}]}`),
+				mockOk(`{"TotalCount":0,"VpcSet":[]}`),
+			},
+			wantCalls: []string{"vpc.DescribeVpcs", "vpc.DescribeVpcs"},
+		},
+		{
+			name:      "vpc not found",
+			responses: []mockResponse{mockOk(`{"TotalCount":0,"VpcSet":[]}`)},
+			wantErr:   "vpc[vpc-1] not found",
+			wantCalls: []string{"vpc.DescribeVpcs"},
+		},
+	}
+	for _, c := range cases {
+		clients, cleanup := setupMockClients(t, map[string][]mockResponse{"vpc.DescribeVpcs": c.responses})
+		err := checkPeeringVpcCidrsNotOverlapped(PeeringConnectionInput{
+			ProviderParams:     MOCK_PROVIDER_PARAMS,
+			PeerProviderParams: "Region=ap-shanghai",
+			VpcId:              "vpc-1",
+			PeerVpcId:          "vpc-2",
+		})
+		cleanup()
+		if c.wantErr == "" && err != nil {
+			t.Errorf("%s: err=%v", c.name, err)
+		}
+		if c.wantErr != "" && (err == nil || !strings.Contains(err.Error(), c.wantErr)) {
+			t.Errorf("%s: err=%v,want %s", c.name, err, c.wantErr)
+		}
+		if !reflect.DeepEqual(clients.calls, c.wantCalls) {
+			t.Errorf("%s: calls=%v,want %v", c.name, clients.calls, c.wantCalls)
+		}
+	}
+}
+
+//peerRegionFailedFactory fails to create the vpc client of the peer region
+type peerRegionFailedFactory struct {
+	*mockClientFactory
+}
+
+func (factory *peerRegionFailedFactory) NewVpcClient(region, secretId, secretKey string) (VpcAPI, error) {
+	if region == "ap-shanghai" {
+		return nil, fmt.Errorf("create vpc client of region[%s] failed", region)
+	}
+	return factory.mockClientFactory.NewVpcClient(region, secretId, secretKey)
+}
+
+func TestCheckPeeringVpcCidrsClientFailed(t *testing.T) {
+	clients, cleanup := setupMockClients(t, map[string][]mockResponse{
+		"vpc.DescribeVpcs": {mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16"}]}`)},
+	})
+	defer cleanup()
+	QcloudClientFactory = &peerRegionFailedFactory{&mockClientFactory{clients}}
+
+	err := checkPeeringVpcCidrsNotOverlapped(PeeringConnectionInput{
+		ProviderParams:     MOCK_PROVIDER_PARAMS,
+		PeerProviderParams: "Region=ap-shanghai",
+		VpcId:              "vpc-1",
+		PeerVpcId:          "vpc-2",
+	})
+	if err == nil || !strings.Contains(err.Error(), "create vpc client of region[ap-shanghai] failed") {
+		t.Fatalf("err=%v,want the client error", err)
+	}
+}
@@ -0,0 +1,376 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+)
+
+const (
+	//the vms of a group are spread across physical hosts, switches or racks
+	PLACEMENT_GROUP_TYPE_HOST = "HOST"
+	PLACEMENT_GROUP_TYPE_SW   = "SW"
+	PLACEMENT_GROUP_TYPE_RACK = "RACK"
+
+	//the most groups qcloud returns in one DescribeDisasterRecoverGroups call
+	PLACEMENT_GROUP_DESCRIBE_PAGE_SIZE = 100
+)
+
+var PlacementGroupActions = make(map[string]Action)
+
+func init() {
+	PlacementGroupActions["create"] = new(PlacementGroupCreateAction)
+	PlacementGroupActions["terminate"] = new(PlacementGroupTerminateAction)
+	PlacementGroupActions["describe"] = new(PlacementGroupDescribeAction)
+}
+
+type PlacementGroupInputs struct {
+	Inputs []PlacementGroupInput `json:"inputs,omitempty"`
+}
+
+type PlacementGroupInput struct {
+	Guid           string `json:"guid,omitempty"`
+	ProviderParams string `json:"provider_params,omitempty"`
+	Id             string `json:"id,omitempty"`
+	Name           string `json:"name,omitempty"`
+	Type           string `json:"type,omitempty"`
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
+type PlacementGroupOutputs struct {
+	Outputs []PlacementGroupOutput `json:"outputs,omitempty"`
+}
+
+type PlacementGroupOutput struct {
+	Guid          string `json:"guid,omitempty"`
+	RequestId     string `json:"request_id,omitempty"`
+	Id            string `json:"id,omitempty"`
+	Name          string `json:"name,omitempty"`
+	Type          string `json:"type,omitempty"`
+	CvmQuotaTotal string `json:"cvm_quota_total,omitempty"`
+	CurrentNum    string `json:"current_num,omitempty"`
+	InstanceIds   string `json:"instance_ids,omitempty"`
+	CreatedTime   string `json:"created_time,omitempty"`
+}
+
+type PlacementGroupPlugin struct{}
+
+func (plugin *PlacementGroupPlugin) GetActionByName(actionName string) (Action, error) {
+	action, found := PlacementGroupActions[actionName]
+	if !found {
+		return nil, fmt.Errorf("placement group plugin,action[%s] not found", actionName)
+	}
+	return action, nil
+}
+
+type PlacementGroupAction struct {
+}
+
+func (action *PlacementGroupAction) ReadParam(param interface{}) (interface{}, error) {
+	var inputs PlacementGroupInputs
+	err := UnmarshalJson(param, &inputs)
+	if err != nil {
+		return nil, err
+	}
+	return inputs, nil
+}
+
+func (action *PlacementGroupAction) CheckParam(input interface{}) error {
+	groups, ok := input.(PlacementGroupInputs)
+	if !ok {
+		return INVALID_PARAMETERS
+	}
+
+	for _, group := range groups.Inputs {
+		if group.Id == "" {
+			return errors.New("input id is empty")
+		}
+	}
+	return nil
+}
+
+func createPlacementGroupClient(providerParams string) (CvmAPI, error) {
+	paramsMap, _ := GetMapFromProviderParams(providerParams)
+	return createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+}
+
+//describeAllPlacementGroups pages over DescribeDisasterRecoverGroups until all the groups matching the request are returned
+func describeAllPlacementGroups(client CvmAPI, request *cvm.DescribeDisasterRecoverGroupsRequest) ([]*cvm.DisasterRecoverGroup, string, error) {
+	request.Limit = common.Int64Ptr(PLACEMENT_GROUP_DESCRIBE_PAGE_SIZE)
+
+	groups := []*cvm.DisasterRecoverGroup{}
+	requestId := ""
+	for offset := int64(0); ; {
+		request.Offset = common.Int64Ptr(offset)
+		response, err := client.DescribeDisasterRecoverGroups(request)
+		if err != nil {
+			logrus.Errorf("cvm DescribeDisasterRecoverGroups meet err=%v", err)
+			return nil, "", err
+		}
+
+		requestId = *response.Response.RequestId
+		groups = append(groups, response.Response.DisasterRecoverGroupSet...)
+		offset += int64(len(response.Response.DisasterRecoverGroupSet))
+		if len(response.Response.DisasterRecoverGroupSet) == 0 || offset >= *response.Response.TotalCount {
+			break
+		}
+	}
+	return groups, requestId, nil
+}
+
+//queryPlacementGroupById returns false if the group is not found
+func queryPlacementGroupById(client CvmAPI, groupId string) (*cvm.DisasterRecoverGroup, bool, error) {
+	request := cvm.NewDescribeDisasterRecoverGroupsRequest()
+	request.DisasterRecoverGroupIds = []*string{&groupId}
+	groups, _, err := describeAllPlacementGroups(client, request)
+	if err != nil || len(groups) == 0 {
+		return nil, false, err
+	}
+	return groups[0], true, nil
+}
+
+func newPlacementGroupOutput(guid string, requestId string, group *cvm.DisasterRecoverGroup) PlacementGroupOutput {
+	output := PlacementGroupOutput{
+		Guid:        guid,
+		RequestId:   requestId,
+		Id:          stringValue(group.DisasterRecoverGroupId),
+		Name:        stringValue(group.Name),
+		Type:        stringValue(group.Type),
+		CreatedTime: stringValue(group.CreateTime),
+	}
+	if group.CvmQuotaTotal != nil {
+		output.CvmQuotaTotal = strconv.FormatInt(*group.CvmQuotaTotal, 10)
+	}
+	if group.CurrentNum != nil {
+		output.CurrentNum = strconv.FormatInt(*group.CurrentNum, 10)
+	}
+	instanceIds := []string{}
+	for _, instanceId := range group.InstanceIds {
+		instanceIds = append(instanceIds, *instanceId)
+	}
+	output.InstanceIds = strings.Join(instanceIds, ",")
+	return output
+}
+
+type PlacementGroupCreateAction struct {
+	PlacementGroupAction
+}
+
+func (action *PlacementGroupCreateAction) CheckParam(input interface{}) error {
+	groups, ok := input.(PlacementGroupInputs)
+	if !ok {
+		return INVALID_PARAMETERS
+	}
+
+	for _, group := range groups.Inputs {
+		if group.Name == "" {
+			return errors.New("input name is empty")
+		}
+		if err := isValidValue(group.Type, []string{PLACEMENT_GROUP_TYPE_HOST, PLACEMENT_GROUP_TYPE_SW, PLACEMENT_GROUP_TYPE_RACK}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (action *PlacementGroupCreateAction) Do(input interface{}) (interface{}, error) {
+	groups, _ := input.(PlacementGroupInputs)
+	outputs := PlacementGroupOutputs{}
+	for _, group := range groups.Inputs {
+		output := PlacementGroupOutput{}
+		idempotencyKey := getIdempotencyKey("placement-group-create", group.Guid, group.IdempotencyKey, group)
+		err := runWithIdempotencyKey(idempotencyKey, &output, func() (interface{}, error) {
+			return createPlacementGroup(group, getIdempotencyClientToken(idempotencyKey))
+		})
+		if err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+//createPlacementGroup returns the group of the input id if it exists,
+//qcloud creates the group only once for the same client token
+func createPlacementGroup(group PlacementGroupInput, clientToken string) (PlacementGroupOutput, error) {
+	client, err := createPlacementGroupClient(group.ProviderParams)
+	if err != nil {
+		return PlacementGroupOutput{}, err
+	}
+
+	if group.Id != "" {
+		existed, found, err := queryPlacementGroupById(client, group.Id)
+		if err != nil {
+			return PlacementGroupOutput{}, err
+		}
+		if found {
+			return newPlacementGroupOutput(group.Guid, "", existed), nil
+		}
+	}
+
+	request := cvm.NewCreateDisasterRecoverGroupRequest()
+	request.Name = &group.Name
+	request.Type = &group.Type
+	request.ClientToken = &clientToken
+	response, err := client.CreateDisasterRecoverGroup(request)
+	if err != nil {
+		return PlacementGroupOutput{}, err
+	}
+	logrus.Infof("Create placement group[%v] has been submitted, Id is [%v], RequestID is [%v]", group.Name, *response.Response.DisasterRecoverGroupId, *response.Response.RequestId)
+
+	return newPlacementGroupOutput(group.Guid, *response.Response.RequestId, &cvm.DisasterRecoverGroup{
+		DisasterRecoverGroupId: response.Response.DisasterRecoverGroupId,
+		Name:                   response.Response.Name,
+		Type:                   response.Response.Type,
+		CvmQuotaTotal:          response.Response.CvmQuotaTotal,
+		CurrentNum:             response.Response.CurrentNum,
+		CreateTime:             response.Response.CreateTime,
+	}), nil
+}
+
+//the group is created unless it exists or the idempotency key replays the group created before
+func (action *PlacementGroupCreateAction) DryRun(input interface{}) (interface{}, error) {
+	groups, _ := input.(PlacementGroupInputs)
+	outputs := DryRunOutputs{}
+	for _, group := range groups.Inputs {
+		client, err := createPlacementGroupClient(group.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		exist := false
+		if group.Id != "" {
+			if _, exist, err = queryPlacementGroupById(client, group.Id); err != nil {
+				return nil, err
+			}
+		}
+		if !exist {
+			if exist, err = isIdempotencyKeyDone(getIdempotencyKey("placement-group-create", group.Guid, group.IdempotencyKey, group)); err != nil {
+				return nil, err
+			}
+		}
+		detail := fmt.Sprintf("create placement group[%s] of type[%s]", group.Name, group.Type)
+		outputs.Outputs = append(outputs.Outputs, newCreateDryRunOutput(group.Guid, group.Id, exist, detail))
+	}
+	return &outputs, nil
+}
+
+type PlacementGroupTerminateAction struct {
+	PlacementGroupAction
+}
+
+func (action *PlacementGroupTerminateAction) Do(input interface{}) (interface{}, error) {
+	groups, _ := input.(PlacementGroupInputs)
+	outputs := PlacementGroupOutputs{}
+	for _, group := range groups.Inputs {
+		client, err := createPlacementGroupClient(group.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		output := PlacementGroupOutput{Guid: group.Guid, Id: group.Id}
+		existed, found, err := queryPlacementGroupById(client, group.Id)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			logrus.Infof("placement group[%s] is already deleted", group.Id)
+			outputs.Outputs = append(outputs.Outputs, output)
+			continue
+		}
+		if len(existed.InstanceIds) > 0 {
+			return nil, fmt.Errorf("placement group[%s] still has %d vms", group.Id, len(existed.InstanceIds))
+		}
+
+		request := cvm.NewDeleteDisasterRecoverGroupsRequest()
+		request.DisasterRecoverGroupIds = []*string{&group.Id}
+		response, err := client.DeleteDisasterRecoverGroups(request)
+		if err != nil {
+			return nil, err
+		}
+		logrus.Infof("Delete placement group[%v] has been submitted, RequestID is [%v]", group.Id, *response.Response.RequestId)
+
+		output.RequestId = *response.Response.RequestId
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+func (action *PlacementGroupTerminateAction) DryRun(input interface{}) (interface{}, error) {
+	groups, _ := input.(PlacementGroupInputs)
+	outputs := DryRunOutputs{}
+	for _, group := range groups.Inputs {
+		client, err := createPlacementGroupClient(group.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		existed, found, err := queryPlacementGroupById(client, group.Id)
+		if err != nil {
+			return nil, err
+		}
+		detail := ""
+		if found {
+			detail = fmt.Sprintf("delete placement group[%s] of type[%s]", stringValue(existed.Name), stringValue(existed.Type))
+		}
+		output := newDeleteDryRunOutput(group.Guid, group.Id, found, detail)
+		if found && len(existed.InstanceIds) > 0 {
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("placement group still has %d vms", len(existed.InstanceIds)))
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+type PlacementGroupDescribeAction struct {
+	PlacementGroupAction
+}
+
+//the groups are looked up by ids or by name, all the groups of the region are returned without them
+func (action *PlacementGroupDescribeAction) CheckParam(input interface{}) error {
+	groups, ok := input.(PlacementGroupInputs)
+	if !ok {
+		return INVALID_PARAMETERS
+	}
+
+	for _, group := range groups.Inputs {
+		if group.Id != "" && group.Name != "" {
+			return errors.New("input id can't be used together with name")
+		}
+	}
+	return nil
+}
+
+func (action *PlacementGroupDescribeAction) Do(input interface{}) (interface{}, error) {
+	groups, _ := input.(PlacementGroupInputs)
+	outputs := PlacementGroupOutputs{}
+	for _, group := range groups.Inputs {
+		client, err := createPlacementGroupClient(group.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		request := cvm.NewDescribeDisasterRecoverGroupsRequest()
+		if groupIds := splitCommaValues(group.Id); len(groupIds) > 0 {
+			request.DisasterRecoverGroupIds = common.StringPtrs(groupIds)
+		}
+		if group.Name != "" {
+			request.Name = &group.Name
+		}
+
+		found, requestId, err := describeAllPlacementGroups(client, request)
+		if err != nil {
+			return nil, err
+		}
+		logrus.Infof("describe placement groups of guid[%s] found %d groups", group.Guid, len(found))
+		for _, item := range found {
+			outputs.Outputs = append(outputs.Outputs, newPlacementGroupOutput(group.Guid, requestId, item))
+		}
+	}
+	return &outputs, nil
+}
@@ -7,9 +7,15 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	DEFAULT_PROVIDER_NAME = "qcloud"
+	DEFAULT_API_VERSION   = "v1"
+)
+
 var (
 	pluginsMutex sync.Mutex
-	plugins      = make(map[string]Plugin)
+	//plugins of each provider and api version, a provider offers the same plugin names and payloads as qcloud
+	providers = make(map[string]map[string]map[string]Plugin)
 )
 
 type Plugin interface {
@@ -23,24 +29,85 @@ type Action interface {
 }
 
 func RegisterPlugin(name string, plugin Plugin) {
+	RegisterProviderPlugin(DEFAULT_PROVIDER_NAME, name, plugin)
+}
+
+func RegisterProviderPlugin(providerName string, name string, plugin Plugin) {
+	RegisterVersionPlugin(providerName, DEFAULT_API_VERSION, name, plugin)
+}
+
+//RegisterVersionPlugin registers the plugin of a new api version, the plugins and actions
+//not registered in the new version are still served by the earlier versions
+func RegisterVersionPlugin(providerName string, version string, name string, plugin Plugin) {
 	pluginsMutex.Lock()
 	defer pluginsMutex.Unlock()
 
+	if _, err := parseApiVersion(version); err != nil {
+		logrus.Fatalf("plugin %q of cloud provider %q meet err=%v", name, providerName, err)
+	}
+
+	versions, found := providers[providerName]
+	if !found {
+		versions = make(map[string]map[string]Plugin)
+		providers[providerName] = versions
+	}
+	plugins, found := versions[version]
+	if !found {
+		plugins = make(map[string]Plugin)
+		versions[version] = plugins
+	}
+
 	if _, found := plugins[name]; found {
-		logrus.Fatalf("cloud provider %q was registered twice", name)
+		logrus.Fatalf("plugin %q of cloud provider %q version %q was registered twice", name, providerName, version)
 	}
 
 	plugins[name] = plugin
 }
 
-func getPluginByName(name string) (Plugin, error) {
+//getActionByVersion looks up the action from the requested version down to the lowest one
+func getActionByVersion(providerName string, version string, name string, actionName string) (Action, error) {
 	pluginsMutex.Lock()
 	defer pluginsMutex.Unlock()
-	plugin, found := plugins[name]
+
+	if providerName == "" {
+		return nil, fmt.Errorf("provider name is empty")
+	}
+	versions, found := providers[providerName]
 	if !found {
-		return nil, fmt.Errorf("plugin[%s] not found", name)
+		return nil, fmt.Errorf("provider[%s] not found", providerName)
+	}
+
+	if version == "" {
+		return nil, fmt.Errorf("api version is empty")
+	}
+	if _, found := versions[version]; !found {
+		return nil, fmt.Errorf("api version[%s] of provider[%s] not supported, supported versions=%v", version, providerName, sortApiVersions(versions))
 	}
-	return plugin, nil
+
+	var actionErr error
+	requested, _ := parseApiVersion(version)
+	sortedVersions := sortApiVersions(versions)
+	for i := len(sortedVersions) - 1; i >= 0; i-- {
+		if n, _ := parseApiVersion(sortedVersions[i]); n > requested {
+			continue
+		}
+		plugin, found := versions[sortedVersions[i]][name]
+		if !found {
+			continue
+		}
+		action, err := plugin.GetActionByName(actionName)
+		if err == nil {
+			return action, nil
+		}
+		if actionErr == nil {
+			actionErr = err
+		}
+	}
+
+	if actionErr != nil {
+		return nil, actionErr
+	}
+	return nil, fmt.Errorf("plugin[%s] of provider[%s] not found", name, providerName)
 }
 
 func init() {
@@ -59,7 +126,10 @@ func init() {
 	RegisterPlugin("eip", new(EIPPlugin))
 	RegisterPlugin("mariadb", new(MariadbPlugin))
 	RegisterPlugin("route-policy", new(RoutePolicyPlugin))
-
+	RegisterPlugin("image", new(ImagePlugin))
+	RegisterPlugin("placement-group", new(PlacementGroupPlugin))
+	RegisterPlugin("snapshot", new(SnapshotPlugin))
+	RegisterPlugin("ipam", new(IpamPlugin))
 }
 
 type PluginRequest struct {
@@ -67,6 +137,7 @@ type PluginRequest struct {
 	ProviderName string
 	Name         string
 	Action       string
+	DryRun       bool
 	Parameters   interface{}
 }
 
@@ -81,24 +152,19 @@ func Process(pluginRequest *PluginRequest) (*PluginResponse, error) {
 	var err error
 	defer func() {
 		if err != nil {
-			logrus.Errorf("plguin[%v]-action[%v] meet error = %v", pluginRequest.Name, pluginRequest.Action, err)
+			logrus.Errorf("provider[%v]-plguin[%v]-action[%v] meet error = %v", pluginRequest.ProviderName, pluginRequest.Name, pluginRequest.Action, err)
 			pluginResponse.ResultCode = "1"
 			pluginResponse.ResultMsg = fmt.Sprint(err)
 		} else {
-			logrus.Infof("plguin[%v]-action[%v] completed", pluginRequest.Name, pluginRequest.Action)
+			logrus.Infof("provider[%v]-plguin[%v]-action[%v] completed", pluginRequest.ProviderName, pluginRequest.Name, pluginRequest.Action)
 			pluginResponse.ResultCode = "0"
 			pluginResponse.ResultMsg = "success"
 		}
 	}()
 
-	logrus.Infof("plguin[%v]-action[%v] start...", pluginRequest.Name, pluginRequest.Action)
+	logrus.Infof("provider[%v]-plguin[%v]-action[%v] start...", pluginRequest.ProviderName, pluginRequest.Name, pluginRequest.Action)
 
-	plugin, err := getPluginByName(pluginRequest.Name)
-	if err != nil {
-		return &pluginResponse, err
-	}
-
-	action, err := plugin.GetActionByName(pluginRequest.Action)
+	action, err := getActionByVersion(pluginRequest.ProviderName, pluginRequest.Version, pluginRequest.Name, pluginRequest.Action)
 	if err != nil {
 		return &pluginResponse, err
 	}
@@ -114,6 +180,17 @@ func Process(pluginRequest *PluginRequest) (*PluginResponse, error) {
 		return &pluginResponse, err
 	}
 
+	if pluginRequest.DryRun {
+		dryRunAction, ok := action.(DryRunAction)
+		if !ok {
+			err = fmt.Errorf("plugin[%s]-action[%s] do not support dry run", pluginRequest.Name, pluginRequest.Action)
+			return &pluginResponse, err
+		}
+		logrus.Infof("action dry run with parameters = %v", actionParam)
+		pluginResponse.Results, err = dryRunAction.DryRun(actionParam)
+		return &pluginResponse, err
+	}
+
 	logrus.Infof("action do with parameters = %v", actionParam)
 	pluginResponse.Results, err = action.Do(actionParam)
 
@@ -0,0 +1,88 @@
+package plugins
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProcessProvider(t *testing.T) {
+	fakeAction := &dryRunTestAction{}
+	RegisterProviderPlugin("fake", "vm", &dryRunTestPlugin{
+		actions: map[string]Action{"create": fakeAction},
+	})
+
+	response, err := Process(&PluginRequest{Version: DEFAULT_API_VERSION, ProviderName: "fake", Name: "vm", Action: "create"})
+	if err != nil || response.ResultCode != "0" {
+		t.Fatalf("process fake provider meet err=%v,response=%++v", err, response)
+	}
+	if !fakeAction.doCalled {
+		t.Fatalf("action of fake provider should be done")
+	}
+
+	cases := []struct {
+		providerName string
+		name         string
+		errMsg       string
+	}{
+		{"", "vm", "provider name is empty"},
+		{"unknown", "vm", "provider[unknown] not found"},
+		{"fake", "storage", "plugin[storage] of provider[fake] not found"},
+	}
+	for _, c := range cases {
+		response, err := Process(&PluginRequest{Version: DEFAULT_API_VERSION, ProviderName: c.providerName, Name: c.name, Action: "create"})
+		if err == nil || !strings.Contains(err.Error(), c.errMsg) || response.ResultCode != "1" {
+			t.Errorf("provider[%s]-plugin[%s] got err=%v,want %s", c.providerName, c.name, err, c.errMsg)
+		}
+	}
+
+	if _, err := getActionByVersion(DEFAULT_PROVIDER_NAME, DEFAULT_API_VERSION, "vm", "create"); err != nil {
+		t.Fatalf("qcloud vm plugin should be registered by default, err=%v", err)
+	}
+}
+
+func TestProcessApiVersion(t *testing.T) {
+	v1Create := &dryRunTestAction{}
+	v1Start := &dryRunTestAction{}
+	v2Create := &dryRunTestAction{}
+	RegisterVersionPlugin("fake-version", "v1", "vm", &dryRunTestPlugin{
+		actions: map[string]Action{"create": v1Create, "start": v1Start},
+	})
+	RegisterVersionPlugin("fake-version", "v2", "vm", &dryRunTestPlugin{
+		actions: map[string]Action{"create": v2Create},
+	})
+
+	cases := []struct {
+		version    string
+		actionName string
+		action     *dryRunTestAction
+	}{
+		{"v1", "create", v1Create},
+		{"v2", "create", v2Create},
+		//actions not changed in v2 are served by v1
+		{"v2", "start", v1Start},
+	}
+	for _, c := range cases {
+		action, err := getActionByVersion("fake-version", c.version, "vm", c.actionName)
+		if err != nil || action != c.action {
+			t.Errorf("version[%s]-action[%s] got wrong action, err=%v", c.version, c.actionName, err)
+		}
+	}
+
+	for _, version := range []string{"", "v3", "latest"} {
+		response, err := Process(&PluginRequest{Version: version, ProviderName: "fake-version", Name: "vm", Action: "create"})
+		if err == nil || response.ResultCode != "1" {
+			t.Errorf("version[%s] should not be supported", version)
+		}
+	}
+
+	for _, provider := range GetCatalogue().Providers {
+		if provider.Name != "fake-version" {
+			continue
+		}
+		if len(provider.Versions) != 2 || provider.Versions[0].Version != "v1" || provider.Versions[1].Version != "v2" {
+			t.Fatalf("catalogue versions=%++v,want v1 and v2", provider.Versions)
+		}
+		return
+	}
+	t.Fatalf("provider fake-version not found in catalogue")
+}
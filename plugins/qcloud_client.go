@@ -0,0 +1,238 @@
+package plugins
+
+import (
+	"github.com/sirupsen/logrus"
+	cbs "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cbs/v20170312"
+	cdb "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cdb/v20170320"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	tchttp "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/http"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+	mariadb "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/mariadb/v20170312"
+	redis "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/redis/v20180412"
+	vpc "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/vpc/v20170312"
+)
+
+const (
+	QCLOUD_ENDPOINT_CBS     = "cbs.tencentcloudapi.com"
+	QCLOUD_ENDPOINT_CDB     = "cdb.tencentcloudapi.com"
+	QCLOUD_ENDPOINT_REDIS   = "redis.tencentcloudapi.com"
+	QCLOUD_ENDPOINT_MARIADB = "mariadb.tencentcloudapi.com"
+)
+
+//CvmAPI is the part of the cvm sdk client used by the plugins
+type CvmAPI interface {
+	//Send is used for the requests the vendored sdk doesn't know
+	Send(request tchttp.Request, response tchttp.Response) error
+
+	RunInstances(request *cvm.RunInstancesRequest) (*cvm.RunInstancesResponse, error)
+	DescribeInstances(request *cvm.DescribeInstancesRequest) (*cvm.DescribeInstancesResponse, error)
+	StartInstances(request *cvm.StartInstancesRequest) (*cvm.StartInstancesResponse, error)
+	StopInstances(request *cvm.StopInstancesRequest) (*cvm.StopInstancesResponse, error)
+	RebootInstances(request *cvm.RebootInstancesRequest) (*cvm.RebootInstancesResponse, error)
+	RenewInstances(request *cvm.RenewInstancesRequest) (*cvm.RenewInstancesResponse, error)
+	ModifyInstancesChargeType(request *cvm.ModifyInstancesChargeTypeRequest) (*cvm.ModifyInstancesChargeTypeResponse, error)
+	ModifyInstancesRenewFlag(request *cvm.ModifyInstancesRenewFlagRequest) (*cvm.ModifyInstancesRenewFlagResponse, error)
+	CreateImage(request *cvm.CreateImageRequest) (*cvm.CreateImageResponse, error)
+	DescribeImages(request *cvm.DescribeImagesRequest) (*cvm.DescribeImagesResponse, error)
+	SyncImages(request *cvm.SyncImagesRequest) (*cvm.SyncImagesResponse, error)
+	ModifyImageSharePermission(request *cvm.ModifyImageSharePermissionRequest) (*cvm.ModifyImageSharePermissionResponse, error)
+	DescribeImageSharePermission(request *cvm.DescribeImageSharePermissionRequest) (*cvm.DescribeImageSharePermissionResponse, error)
+	DeleteImages(request *cvm.DeleteImagesRequest) (*cvm.DeleteImagesResponse, error)
+	CreateDisasterRecoverGroup(request *cvm.CreateDisasterRecoverGroupRequest) (*cvm.CreateDisasterRecoverGroupResponse, error)
+	DescribeDisasterRecoverGroups(request *cvm.DescribeDisasterRecoverGroupsRequest) (*cvm.DescribeDisasterRecoverGroupsResponse, error)
+	DeleteDisasterRecoverGroups(request *cvm.DeleteDisasterRecoverGroupsRequest) (*cvm.DeleteDisasterRecoverGroupsResponse, error)
+	DescribeZoneInstanceConfigInfos(request *cvm.DescribeZoneInstanceConfigInfosRequest) (*cvm.DescribeZoneInstanceConfigInfosResponse, error)
+	ModifyInstancesAttribute(request *cvm.ModifyInstancesAttributeRequest) (*cvm.ModifyInstancesAttributeResponse, error)
+	TerminateInstances(request *cvm.TerminateInstancesRequest) (*cvm.TerminateInstancesResponse, error)
+	DescribeZones(request *cvm.DescribeZonesRequest) (*cvm.DescribeZonesResponse, error)
+	ResetInstancesType(request *cvm.ResetInstancesTypeRequest) (*cvm.ResetInstancesTypeResponse, error)
+	ResetInstance(request *cvm.ResetInstanceRequest) (*cvm.ResetInstanceResponse, error)
+	ResetInstancesPassword(request *cvm.ResetInstancesPasswordRequest) (*cvm.ResetInstancesPasswordResponse, error)
+}
+
+//VpcAPI is the part of the vpc sdk client used by the plugins
+type VpcAPI interface {
+	//Send is used for the requests the vendored sdk doesn't know
+	Send(request tchttp.Request, response tchttp.Response) error
+
+	CreateVpc(request *vpc.CreateVpcRequest) (*vpc.CreateVpcResponse, error)
+	DeleteVpc(request *vpc.DeleteVpcRequest) (*vpc.DeleteVpcResponse, error)
+	DescribeVpcs(request *vpc.DescribeVpcsRequest) (*vpc.DescribeVpcsResponse, error)
+	ModifyVpcAttribute(request *vpc.ModifyVpcAttributeRequest) (*vpc.ModifyVpcAttributeResponse, error)
+	AssignIpv6CidrBlock(request *vpc.AssignIpv6CidrBlockRequest) (*vpc.AssignIpv6CidrBlockResponse, error)
+
+	CreateSubnet(request *vpc.CreateSubnetRequest) (*vpc.CreateSubnetResponse, error)
+	DeleteSubnet(request *vpc.DeleteSubnetRequest) (*vpc.DeleteSubnetResponse, error)
+	DescribeSubnets(request *vpc.DescribeSubnetsRequest) (*vpc.DescribeSubnetsResponse, error)
+	AssignIpv6SubnetCidrBlock(request *vpc.AssignIpv6SubnetCidrBlockRequest) (*vpc.AssignIpv6SubnetCidrBlockResponse, error)
+
+	CreateRouteTable(request *vpc.CreateRouteTableRequest) (*vpc.CreateRouteTableResponse, error)
+	DeleteRouteTable(request *vpc.DeleteRouteTableRequest) (*vpc.DeleteRouteTableResponse, error)
+	DescribeRouteTables(request *vpc.DescribeRouteTablesRequest) (*vpc.DescribeRouteTablesResponse, error)
+	ReplaceRouteTableAssociation(request *vpc.ReplaceRouteTableAssociationRequest) (*vpc.ReplaceRouteTableAssociationResponse, error)
+	CreateRoutes(request *vpc.CreateRoutesRequest) (*vpc.CreateRoutesResponse, error)
+	DeleteRoutes(request *vpc.DeleteRoutesRequest) (*vpc.DeleteRoutesResponse, error)
+	DescribeRouteConflicts(request *vpc.DescribeRouteConflictsRequest) (*vpc.DescribeRouteConflictsResponse, error)
+	ModifyRouteTableAttribute(request *vpc.ModifyRouteTableAttributeRequest) (*vpc.ModifyRouteTableAttributeResponse, error)
+	EnableRoutes(request *vpc.EnableRoutesRequest) (*vpc.EnableRoutesResponse, error)
+	DisableRoutes(request *vpc.DisableRoutesRequest) (*vpc.DisableRoutesResponse, error)
+	ReplaceRoutes(request *vpc.ReplaceRoutesRequest) (*vpc.ReplaceRoutesResponse, error)
+
+	CreateSecurityGroup(request *vpc.CreateSecurityGroupRequest) (*vpc.CreateSecurityGroupResponse, error)
+	DeleteSecurityGroup(request *vpc.DeleteSecurityGroupRequest) (*vpc.DeleteSecurityGroupResponse, error)
+	DescribeSecurityGroups(request *vpc.DescribeSecurityGroupsRequest) (*vpc.DescribeSecurityGroupsResponse, error)
+	CreateSecurityGroupPolicies(request *vpc.CreateSecurityGroupPoliciesRequest) (*vpc.CreateSecurityGroupPoliciesResponse, error)
+	DeleteSecurityGroupPolicies(request *vpc.DeleteSecurityGroupPoliciesRequest) (*vpc.DeleteSecurityGroupPoliciesResponse, error)
+	DescribeSecurityGroupPolicies(request *vpc.DescribeSecurityGroupPoliciesRequest) (*vpc.DescribeSecurityGroupPoliciesResponse, error)
+
+	AllocateAddresses(request *vpc.AllocateAddressesRequest) (*vpc.AllocateAddressesResponse, error)
+	ReleaseAddresses(request *vpc.ReleaseAddressesRequest) (*vpc.ReleaseAddressesResponse, error)
+	DescribeAddresses(request *vpc.DescribeAddressesRequest) (*vpc.DescribeAddressesResponse, error)
+	AssociateAddress(request *vpc.AssociateAddressRequest) (*vpc.AssociateAddressResponse, error)
+	DisassociateAddress(request *vpc.DisassociateAddressRequest) (*vpc.DisassociateAddressResponse, error)
+	DescribeAddressQuota(request *vpc.DescribeAddressQuotaRequest) (*vpc.DescribeAddressQuotaResponse, error)
+
+	CreateNetworkInterface(request *vpc.CreateNetworkInterfaceRequest) (*vpc.CreateNetworkInterfaceResponse, error)
+	DeleteNetworkInterface(request *vpc.DeleteNetworkInterfaceRequest) (*vpc.DeleteNetworkInterfaceResponse, error)
+	DescribeNetworkInterfaces(request *vpc.DescribeNetworkInterfacesRequest) (*vpc.DescribeNetworkInterfacesResponse, error)
+	AttachNetworkInterface(request *vpc.AttachNetworkInterfaceRequest) (*vpc.AttachNetworkInterfaceResponse, error)
+	DetachNetworkInterface(request *vpc.DetachNetworkInterfaceRequest) (*vpc.DetachNetworkInterfaceResponse, error)
+
+	CreateNatGateway(request *vpc.CreateNatGatewayRequest) (*vpc.CreateNatGatewayResponse, error)
+	DeleteNatGateway(request *vpc.DeleteNatGatewayRequest) (*vpc.DeleteNatGatewayResponse, error)
+	DescribeNatGateways(request *vpc.DescribeNatGatewaysRequest) (*vpc.DescribeNatGatewaysResponse, error)
+}
+
+//CbsAPI is the part of the cbs sdk client used by the plugins
+type CbsAPI interface {
+	//Send is used for the requests carrying parameters the vendored sdk doesn't know
+	Send(request tchttp.Request, response tchttp.Response) error
+	DescribeDisks(request *cbs.DescribeDisksRequest) (*cbs.DescribeDisksResponse, error)
+	DescribeDiskOperationLogs(request *cbs.DescribeDiskOperationLogsRequest) (*cbs.DescribeDiskOperationLogsResponse, error)
+	AttachDisks(request *cbs.AttachDisksRequest) (*cbs.AttachDisksResponse, error)
+	DetachDisks(request *cbs.DetachDisksRequest) (*cbs.DetachDisksResponse, error)
+	TerminateDisks(request *cbs.TerminateDisksRequest) (*cbs.TerminateDisksResponse, error)
+	ResizeDisk(request *cbs.ResizeDiskRequest) (*cbs.ResizeDiskResponse, error)
+	CreateSnapshot(request *cbs.CreateSnapshotRequest) (*cbs.CreateSnapshotResponse, error)
+	DeleteSnapshots(request *cbs.DeleteSnapshotsRequest) (*cbs.DeleteSnapshotsResponse, error)
+	DescribeSnapshots(request *cbs.DescribeSnapshotsRequest) (*cbs.DescribeSnapshotsResponse, error)
+	ApplySnapshot(request *cbs.ApplySnapshotRequest) (*cbs.ApplySnapshotResponse, error)
+	CreateAutoSnapshotPolicy(request *cbs.CreateAutoSnapshotPolicyRequest) (*cbs.CreateAutoSnapshotPolicyResponse, error)
+	DeleteAutoSnapshotPolicies(request *cbs.DeleteAutoSnapshotPoliciesRequest) (*cbs.DeleteAutoSnapshotPoliciesResponse, error)
+	DescribeAutoSnapshotPolicies(request *cbs.DescribeAutoSnapshotPoliciesRequest) (*cbs.DescribeAutoSnapshotPoliciesResponse, error)
+	BindAutoSnapshotPolicy(request *cbs.BindAutoSnapshotPolicyRequest) (*cbs.BindAutoSnapshotPolicyResponse, error)
+	UnbindAutoSnapshotPolicy(request *cbs.UnbindAutoSnapshotPolicyRequest) (*cbs.UnbindAutoSnapshotPolicyResponse, error)
+}
+
+//CdbAPI is the part of the cdb(mysql) sdk client used by the plugins
+type CdbAPI interface {
+	CreateDBInstance(request *cdb.CreateDBInstanceRequest) (*cdb.CreateDBInstanceResponse, error)
+	CreateDBInstanceHour(request *cdb.CreateDBInstanceHourRequest) (*cdb.CreateDBInstanceHourResponse, error)
+	DescribeDBInstances(request *cdb.DescribeDBInstancesRequest) (*cdb.DescribeDBInstancesResponse, error)
+	InitDBInstances(request *cdb.InitDBInstancesRequest) (*cdb.InitDBInstancesResponse, error)
+	IsolateDBInstance(request *cdb.IsolateDBInstanceRequest) (*cdb.IsolateDBInstanceResponse, error)
+	RestartDBInstances(request *cdb.RestartDBInstancesRequest) (*cdb.RestartDBInstancesResponse, error)
+	DescribeAsyncRequestInfo(request *cdb.DescribeAsyncRequestInfoRequest) (*cdb.DescribeAsyncRequestInfoResponse, error)
+	DescribeDBSecurityGroups(request *cdb.DescribeDBSecurityGroupsRequest) (*cdb.DescribeDBSecurityGroupsResponse, error)
+	ModifyDBInstanceSecurityGroups(request *cdb.ModifyDBInstanceSecurityGroupsRequest) (*cdb.ModifyDBInstanceSecurityGroupsResponse, error)
+}
+
+//RedisAPI is the part of the redis sdk client used by the plugins
+type RedisAPI interface {
+	CreateInstances(request *redis.CreateInstancesRequest) (*redis.CreateInstancesResponse, error)
+	DescribeInstanceDealDetail(request *redis.DescribeInstanceDealDetailRequest) (*redis.DescribeInstanceDealDetailResponse, error)
+	DescribeInstances(request *redis.DescribeInstancesRequest) (*redis.DescribeInstancesResponse, error)
+}
+
+//MariadbAPI is the part of the mariadb sdk client used by the plugins
+type MariadbAPI interface {
+	CreateDBInstance(request *mariadb.CreateDBInstanceRequest) (*mariadb.CreateDBInstanceResponse, error)
+	DescribeOrders(request *mariadb.DescribeOrdersRequest) (*mariadb.DescribeOrdersResponse, error)
+	DescribeDBInstances(request *mariadb.DescribeDBInstancesRequest) (*mariadb.DescribeDBInstancesResponse, error)
+	InitDBInstances(request *mariadb.InitDBInstancesRequest) (*mariadb.InitDBInstancesResponse, error)
+	DescribeFlow(request *mariadb.DescribeFlowRequest) (*mariadb.DescribeFlowResponse, error)
+	CreateAccount(request *mariadb.CreateAccountRequest) (*mariadb.CreateAccountResponse, error)
+	GrantAccountPrivileges(request *mariadb.GrantAccountPrivilegesRequest) (*mariadb.GrantAccountPrivilegesResponse, error)
+}
+
+//ClientFactory creates the qcloud clients of the plugins, unit tests replace it to inject mock clients
+type ClientFactory interface {
+	NewCvmClient(region, secretId, secretKey string) (CvmAPI, error)
+	NewVpcClient(region, secretId, secretKey string) (VpcAPI, error)
+	NewCbsClient(region, secretId, secretKey string) (CbsAPI, error)
+	NewCdbClient(region, secretId, secretKey string) (CdbAPI, error)
+	NewRedisClient(region, secretId, secretKey string) (RedisAPI, error)
+	NewMariadbClient(region, secretId, secretKey string) (MariadbAPI, error)
+}
+
+var QcloudClientFactory ClientFactory = new(SdkClientFactory)
+
+//SdkClientFactory creates the clients of tencentcloud-sdk-go
+type SdkClientFactory struct{}
+
+func newClientProfile(endpoint string) *profile.ClientProfile {
+	clientProfile := profile.NewClientProfile()
+	clientProfile.HttpProfile.Endpoint = endpoint
+	return clientProfile
+}
+
+func (factory *SdkClientFactory) NewCvmClient(region, secretId, secretKey string) (CvmAPI, error) {
+	client, err := cvm.NewClient(common.NewCredential(secretId, secretKey), region, newClientProfile(QCLOUD_ENDPOINT_CVM))
+	if err != nil {
+		logrus.Errorf("Create Qcloud vm client failed,err=%v", err)
+		return nil, err
+	}
+	WithQcloudHttpTransport(&client.Client)
+	return client, nil
+}
+
+func (factory *SdkClientFactory) NewVpcClient(region, secretId, secretKey string) (VpcAPI, error) {
+	client, err := vpc.NewClient(common.NewCredential(secretId, secretKey), region, newClientProfile(QCLOUD_ENDPOINT_VPC))
+	if err != nil {
+		logrus.Errorf("Create Qcloud vpc client failed,err=%v", err)
+		return nil, err
+	}
+	WithQcloudHttpTransport(&client.Client)
+	return client, nil
+}
+
+func (factory *SdkClientFactory) NewCbsClient(region, secretId, secretKey string) (CbsAPI, error) {
+	client, err := cbs.NewClient(common.NewCredential(secretId, secretKey), region, newClientProfile(QCLOUD_ENDPOINT_CBS))
+	if err != nil {
+		logrus.Errorf("Create Qcloud cbs client failed,err=%v", err)
+		return nil, err
+	}
+	WithQcloudHttpTransport(&client.Client)
+	return client, nil
+}
+
+func (factory *SdkClientFactory) NewCdbClient(region, secretId, secretKey string) (CdbAPI, error) {
+	client, err := cdb.NewClient(common.NewCredential(secretId, secretKey), region, newClientProfile(QCLOUD_ENDPOINT_CDB))
+	if err != nil {
+		logrus.Errorf("Create Qcloud cdb client failed,err=%v", err)
+		return nil, err
+	}
+	WithQcloudHttpTransport(&client.Client)
+	return client, nil
+}
+
+func (factory *SdkClientFactory) NewRedisClient(region, secretId, secretKey string) (RedisAPI, error) {
+	client, err := redis.NewClient(common.NewCredential(secretId, secretKey), region, newClientProfile(QCLOUD_ENDPOINT_REDIS))
+	if err != nil {
+		logrus.Errorf("Create Qcloud redis client failed,err=%v", err)
+		return nil, err
+	}
+	WithQcloudHttpTransport(&client.Client)
+	return client, nil
+}
+
+func (factory *SdkClientFactory) NewMariadbClient(region, secretId, secretKey string) (MariadbAPI, error) {
+	client, err := mariadb.NewClient(common.NewCredential(secretId, secretKey), region, newClientProfile(QCLOUD_ENDPOINT_MARIADB))
+	if err != nil {
+		logrus.Errorf("Create Qcloud mariadb client failed,err=%v", err)
+		return nil, err
+	}
+	WithQcloudHttpTransport(&client.Client)
+	return client, nil
+}
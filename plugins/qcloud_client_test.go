@@ -0,0 +1,675 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	cbs "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cbs/v20170312"
+	cdb "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cdb/v20170320"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/errors"
+	tchttp "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/http"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+	mariadb "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/mariadb/v20170312"
+	redis "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/redis/v20180412"
+	vpc "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/vpc/v20170312"
+)
+
+const (
+	MOCK_PROVIDER_PARAMS = "Region=ap-guangzhou;AvailableZone=ap-guangzhou-3;SecretID=mock-secret-id;SecretKey=mock-secret-key"
+	MOCK_REQUEST_ID      = "mock-request-id"
+)
+
+//mockResponse is the Response part of a qcloud api response, or the error the api returns
+type mockResponse struct {
+	body string
+	err  error
+}
+
+func mockOk(body string) mockResponse {
+	return mockResponse{body: body}
+}
+
+func mockError(code string) mockResponse {
+	return mockResponse{err: errors.NewTencentCloudSDKError(code, "mock error", MOCK_REQUEST_ID)}
+}
+
+//mockClients serves the calls of all the mock clients with the responses queued for each api,
+//apis are named like vpc.CreateVpc and the last response of an api is reused once the others are consumed
+type mockClients struct {
+	responses map[string][]mockResponse
+	calls     []string
+}
+
+func (clients *mockClients) call(api string, request interface{}, response interface{}) error {
+	clients.calls = append(clients.calls, api)
+	responses := clients.responses[api]
+	if len(responses) == 0 {
+		return fmt.Errorf("%s is not mocked", api)
+	}
+	next := responses[0]
+	if len(responses) > 1 {
+		clients.responses[api] = responses[1:]
+	}
+	if next.err != nil {
+		return next.err
+	}
+
+	body := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(next.body), &body); err != nil {
+		return fmt.Errorf("%s mock response %s is invalid, err=%v", api, next.body, err)
+	}
+	if _, found := body["RequestId"]; !found {
+		body["RequestId"] = MOCK_REQUEST_ID
+	}
+	data, _ := json.Marshal(map[string]interface{}{"Response": body})
+	return json.Unmarshal(data, response)
+}
+
+//mockClientFactory hands out mock clients which share the same responses and calls
+type mockClientFactory struct {
+	*mockClients
+}
+
+func (factory *mockClientFactory) NewCvmClient(region, secretId, secretKey string) (CvmAPI, error) {
+	return &mockCvmClient{factory.mockClients}, nil
+}
+
+func (factory *mockClientFactory) NewVpcClient(region, secretId, secretKey string) (VpcAPI, error) {
+	return &mockVpcClient{factory.mockClients}, nil
+}
+
+func (factory *mockClientFactory) NewCbsClient(region, secretId, secretKey string) (CbsAPI, error) {
+	return &mockCbsClient{factory.mockClients}, nil
+}
+
+func (factory *mockClientFactory) NewCdbClient(region, secretId, secretKey string) (CdbAPI, error) {
+	return &mockCdbClient{factory.mockClients}, nil
+}
+
+func (factory *mockClientFactory) NewRedisClient(region, secretId, secretKey string) (RedisAPI, error) {
+	return &mockRedisClient{factory.mockClients}, nil
+}
+
+func (factory *mockClientFactory) NewMariadbClient(region, secretId, secretKey string) (MariadbAPI, error) {
+	return &mockMariadbClient{factory.mockClients}, nil
+}
+
+//setupWaitPollInterval makes the wait loops poll without sleeping for seconds
+func setupWaitPollInterval() func() {
+	interval := waitPollInterval
+	waitPollInterval = time.Millisecond
+	return func() {
+		waitPollInterval = interval
+	}
+}
+
+//setupMockClients injects mock clients serving the responses into the plugins
+func setupMockClients(t *testing.T, responses map[string][]mockResponse) (*mockClients, func()) {
+	cleanupIdempotencyStore := setupIdempotencyStore(t)
+	cleanupWaitPollInterval := setupWaitPollInterval()
+	clients := &mockClients{responses: map[string][]mockResponse{}}
+	for api, apiResponses := range responses {
+		clients.responses[api] = append([]mockResponse{}, apiResponses...)
+	}
+	QcloudClientFactory = &mockClientFactory{clients}
+	return clients, func() {
+		QcloudClientFactory = new(SdkClientFactory)
+		cleanupIdempotencyStore()
+		cleanupWaitPollInterval()
+	}
+}
+
+type mockCvmClient struct {
+	*mockClients
+}
+
+func (client *mockCvmClient) Send(request tchttp.Request, response tchttp.Response) error {
+	return client.call("cvm."+request.GetAction(), request, response)
+}
+
+func (client *mockCvmClient) RunInstances(request *cvm.RunInstancesRequest) (*cvm.RunInstancesResponse, error) {
+	response := cvm.NewRunInstancesResponse()
+	return response, client.call("cvm.RunInstances", request, response)
+}
+
+func (client *mockCvmClient) DescribeInstances(request *cvm.DescribeInstancesRequest) (*cvm.DescribeInstancesResponse, error) {
+	response := cvm.NewDescribeInstancesResponse()
+	return response, client.call("cvm.DescribeInstances", request, response)
+}
+
+func (client *mockCvmClient) StartInstances(request *cvm.StartInstancesRequest) (*cvm.StartInstancesResponse, error) {
+	response := cvm.NewStartInstancesResponse()
+	return response, client.call("cvm.StartInstances", request, response)
+}
+
+func (client *mockCvmClient) StopInstances(request *cvm.StopInstancesRequest) (*cvm.StopInstancesResponse, error) {
+	response := cvm.NewStopInstancesResponse()
+	return response, client.call("cvm.StopInstances", request, response)
+}
+
+func (client *mockCvmClient) RebootInstances(request *cvm.RebootInstancesRequest) (*cvm.RebootInstancesResponse, error) {
+	response := cvm.NewRebootInstancesResponse()
+	return response, client.call("cvm.RebootInstances", request, response)
+}
+
+func (client *mockCvmClient) RenewInstances(request *cvm.RenewInstancesRequest) (*cvm.RenewInstancesResponse, error) {
+	response := cvm.NewRenewInstancesResponse()
+	return response, client.call("cvm.RenewInstances", request, response)
+}
+
+func (client *mockCvmClient) ModifyInstancesChargeType(request *cvm.ModifyInstancesChargeTypeRequest) (*cvm.ModifyInstancesChargeTypeResponse, error) {
+	response := cvm.NewModifyInstancesChargeTypeResponse()
+	return response, client.call("cvm.ModifyInstancesChargeType", request, response)
+}
+
+func (client *mockCvmClient) ModifyInstancesRenewFlag(request *cvm.ModifyInstancesRenewFlagRequest) (*cvm.ModifyInstancesRenewFlagResponse, error) {
+	response := cvm.NewModifyInstancesRenewFlagResponse()
+	return response, client.call("cvm.ModifyInstancesRenewFlag", request, response)
+}
+
+func (client *mockCvmClient) CreateImage(request *cvm.CreateImageRequest) (*cvm.CreateImageResponse, error) {
+	response := cvm.NewCreateImageResponse()
+	return response, client.call("cvm.CreateImage", request, response)
+}
+
+func (client *mockCvmClient) DescribeImages(request *cvm.DescribeImagesRequest) (*cvm.DescribeImagesResponse, error) {
+	response := cvm.NewDescribeImagesResponse()
+	return response, client.call("cvm.DescribeImages", request, response)
+}
+
+func (client *mockCvmClient) SyncImages(request *cvm.SyncImagesRequest) (*cvm.SyncImagesResponse, error) {
+	response := cvm.NewSyncImagesResponse()
+	return response, client.call("cvm.SyncImages", request, response)
+}
+
+func (client *mockCvmClient) ModifyImageSharePermission(request *cvm.ModifyImageSharePermissionRequest) (*cvm.ModifyImageSharePermissionResponse, error) {
+	response := cvm.NewModifyImageSharePermissionResponse()
+	return response, client.call("cvm.ModifyImageSharePermission", request, response)
+}
+
+func (client *mockCvmClient) DescribeImageSharePermission(request *cvm.DescribeImageSharePermissionRequest) (*cvm.DescribeImageSharePermissionResponse, error) {
+	response := cvm.NewDescribeImageSharePermissionResponse()
+	return response, client.call("cvm.DescribeImageSharePermission", request, response)
+}
+
+func (client *mockCvmClient) DeleteImages(request *cvm.DeleteImagesRequest) (*cvm.DeleteImagesResponse, error) {
+	response := cvm.NewDeleteImagesResponse()
+	return response, client.call("cvm.DeleteImages", request, response)
+}
+
+func (client *mockCvmClient) CreateDisasterRecoverGroup(request *cvm.CreateDisasterRecoverGroupRequest) (*cvm.CreateDisasterRecoverGroupResponse, error) {
+	response := cvm.NewCreateDisasterRecoverGroupResponse()
+	return response, client.call("cvm.CreateDisasterRecoverGroup", request, response)
+}
+
+func (client *mockCvmClient) DescribeDisasterRecoverGroups(request *cvm.DescribeDisasterRecoverGroupsRequest) (*cvm.DescribeDisasterRecoverGroupsResponse, error) {
+	response := cvm.NewDescribeDisasterRecoverGroupsResponse()
+	return response, client.call("cvm.DescribeDisasterRecoverGroups", request, response)
+}
+
+func (client *mockCvmClient) DeleteDisasterRecoverGroups(request *cvm.DeleteDisasterRecoverGroupsRequest) (*cvm.DeleteDisasterRecoverGroupsResponse, error) {
+	response := cvm.NewDeleteDisasterRecoverGroupsResponse()
+	return response, client.call("cvm.DeleteDisasterRecoverGroups", request, response)
+}
+
+func (client *mockCvmClient) DescribeZoneInstanceConfigInfos(request *cvm.DescribeZoneInstanceConfigInfosRequest) (*cvm.DescribeZoneInstanceConfigInfosResponse, error) {
+	response := cvm.NewDescribeZoneInstanceConfigInfosResponse()
+	return response, client.call("cvm.DescribeZoneInstanceConfigInfos", request, response)
+}
+
+func (client *mockCvmClient) ModifyInstancesAttribute(request *cvm.ModifyInstancesAttributeRequest) (*cvm.ModifyInstancesAttributeResponse, error) {
+	response := cvm.NewModifyInstancesAttributeResponse()
+	return response, client.call("cvm.ModifyInstancesAttribute", request, response)
+}
+
+func (client *mockCvmClient) TerminateInstances(request *cvm.TerminateInstancesRequest) (*cvm.TerminateInstancesResponse, error) {
+	response := cvm.NewTerminateInstancesResponse()
+	return response, client.call("cvm.TerminateInstances", request, response)
+}
+
+func (client *mockCvmClient) DescribeZones(request *cvm.DescribeZonesRequest) (*cvm.DescribeZonesResponse, error) {
+	response := cvm.NewDescribeZonesResponse()
+	return response, client.call("cvm.DescribeZones", request, response)
+}
+
+func (client *mockCvmClient) ResetInstancesType(request *cvm.ResetInstancesTypeRequest) (*cvm.ResetInstancesTypeResponse, error) {
+	response := cvm.NewResetInstancesTypeResponse()
+	return response, client.call("cvm.ResetInstancesType", request, response)
+}
+
+func (client *mockCvmClient) ResetInstance(request *cvm.ResetInstanceRequest) (*cvm.ResetInstanceResponse, error) {
+	response := cvm.NewResetInstanceResponse()
+	return response, client.call("cvm.ResetInstance", request, response)
+}
+
+func (client *mockCvmClient) ResetInstancesPassword(request *cvm.ResetInstancesPasswordRequest) (*cvm.ResetInstancesPasswordResponse, error) {
+	response := cvm.NewResetInstancesPasswordResponse()
+	return response, client.call("cvm.ResetInstancesPassword", request, response)
+}
+
+type mockVpcClient struct {
+	*mockClients
+}
+
+func (client *mockVpcClient) Send(request tchttp.Request, response tchttp.Response) error {
+	return client.call("vpc."+request.GetAction(), request, response)
+}
+
+func (client *mockVpcClient) CreateVpc(request *vpc.CreateVpcRequest) (*vpc.CreateVpcResponse, error) {
+	response := vpc.NewCreateVpcResponse()
+	return response, client.call("vpc.CreateVpc", request, response)
+}
+
+func (client *mockVpcClient) DeleteVpc(request *vpc.DeleteVpcRequest) (*vpc.DeleteVpcResponse, error) {
+	response := vpc.NewDeleteVpcResponse()
+	return response, client.call("vpc.DeleteVpc", request, response)
+}
+
+func (client *mockVpcClient) DescribeVpcs(request *vpc.DescribeVpcsRequest) (*vpc.DescribeVpcsResponse, error) {
+	response := vpc.NewDescribeVpcsResponse()
+	return response, client.call("vpc.DescribeVpcs", request, response)
+}
+
+func (client *mockVpcClient) ModifyVpcAttribute(request *vpc.ModifyVpcAttributeRequest) (*vpc.ModifyVpcAttributeResponse, error) {
+	response := vpc.NewModifyVpcAttributeResponse()
+	return response, client.call("vpc.ModifyVpcAttribute", request, response)
+}
+
+func (client *mockVpcClient) AssignIpv6CidrBlock(request *vpc.AssignIpv6CidrBlockRequest) (*vpc.AssignIpv6CidrBlockResponse, error) {
+	response := vpc.NewAssignIpv6CidrBlockResponse()
+	return response, client.call("vpc.AssignIpv6CidrBlock", request, response)
+}
+
+func (client *mockVpcClient) CreateSubnet(request *vpc.CreateSubnetRequest) (*vpc.CreateSubnetResponse, error) {
+	response := vpc.NewCreateSubnetResponse()
+	return response, client.call("vpc.CreateSubnet", request, response)
+}
+
+func (client *mockVpcClient) DeleteSubnet(request *vpc.DeleteSubnetRequest) (*vpc.DeleteSubnetResponse, error) {
+	response := vpc.NewDeleteSubnetResponse()
+	return response, client.call("vpc.DeleteSubnet", request, response)
+}
+
+func (client *mockVpcClient) DescribeSubnets(request *vpc.DescribeSubnetsRequest) (*vpc.DescribeSubnetsResponse, error) {
+	response := vpc.NewDescribeSubnetsResponse()
+	return response, client.call("vpc.DescribeSubnets", request, response)
+}
+
+func (client *mockVpcClient) AssignIpv6SubnetCidrBlock(request *vpc.AssignIpv6SubnetCidrBlockRequest) (*vpc.AssignIpv6SubnetCidrBlockResponse, error) {
+	response := vpc.NewAssignIpv6SubnetCidrBlockResponse()
+	return response, client.call("vpc.AssignIpv6SubnetCidrBlock", request, response)
+}
+
+func (client *mockVpcClient) CreateRouteTable(request *vpc.CreateRouteTableRequest) (*vpc.CreateRouteTableResponse, error) {
+	response := vpc.NewCreateRouteTableResponse()
+	return response, client.call("vpc.CreateRouteTable", request, response)
+}
+
+func (client *mockVpcClient) DeleteRouteTable(request *vpc.DeleteRouteTableRequest) (*vpc.DeleteRouteTableResponse, error) {
+	response := vpc.NewDeleteRouteTableResponse()
+	return response, client.call("vpc.DeleteRouteTable", request, response)
+}
+
+func (client *mockVpcClient) DescribeRouteTables(request *vpc.DescribeRouteTablesRequest) (*vpc.DescribeRouteTablesResponse, error) {
+	response := vpc.NewDescribeRouteTablesResponse()
+	return response, client.call("vpc.DescribeRouteTables", request, response)
+}
+
+func (client *mockVpcClient) ReplaceRouteTableAssociation(request *vpc.ReplaceRouteTableAssociationRequest) (*vpc.ReplaceRouteTableAssociationResponse, error) {
+	response := vpc.NewReplaceRouteTableAssociationResponse()
+	return response, client.call("vpc.ReplaceRouteTableAssociation", request, response)
+}
+
+func (client *mockVpcClient) CreateRoutes(request *vpc.CreateRoutesRequest) (*vpc.CreateRoutesResponse, error) {
+	response := vpc.NewCreateRoutesResponse()
+	return response, client.call("vpc.CreateRoutes", request, response)
+}
+
+func (client *mockVpcClient) DeleteRoutes(request *vpc.DeleteRoutesRequest) (*vpc.DeleteRoutesResponse, error) {
+	response := vpc.NewDeleteRoutesResponse()
+	return response, client.call("vpc.DeleteRoutes", request, response)
+}
+
+func (client *mockVpcClient) DescribeRouteConflicts(request *vpc.DescribeRouteConflictsRequest) (*vpc.DescribeRouteConflictsResponse, error) {
+	response := vpc.NewDescribeRouteConflictsResponse()
+	return response, client.call("vpc.DescribeRouteConflicts", request, response)
+}
+
+func (client *mockVpcClient) ModifyRouteTableAttribute(request *vpc.ModifyRouteTableAttributeRequest) (*vpc.ModifyRouteTableAttributeResponse, error) {
+	response := vpc.NewModifyRouteTableAttributeResponse()
+	return response, client.call("vpc.ModifyRouteTableAttribute", request, response)
+}
+
+func (client *mockVpcClient) EnableRoutes(request *vpc.EnableRoutesRequest) (*vpc.EnableRoutesResponse, error) {
+	response := vpc.NewEnableRoutesResponse()
+	return response, client.call("vpc.EnableRoutes", request, response)
+}
+
+func (client *mockVpcClient) DisableRoutes(request *vpc.DisableRoutesRequest) (*vpc.DisableRoutesResponse, error) {
+	response := vpc.NewDisableRoutesResponse()
+	return response, client.call("vpc.DisableRoutes", request, response)
+}
+
+func (client *mockVpcClient) ReplaceRoutes(request *vpc.ReplaceRoutesRequest) (*vpc.ReplaceRoutesResponse, error) {
+	response := vpc.NewReplaceRoutesResponse()
+	return response, client.call("vpc.ReplaceRoutes", request, response)
+}
+
+func (client *mockVpcClient) CreateSecurityGroup(request *vpc.CreateSecurityGroupRequest) (*vpc.CreateSecurityGroupResponse, error) {
+	response := vpc.NewCreateSecurityGroupResponse()
+	return response, client.call("vpc.CreateSecurityGroup", request, response)
+}
+
+func (client *mockVpcClient) DeleteSecurityGroup(request *vpc.DeleteSecurityGroupRequest) (*vpc.DeleteSecurityGroupResponse, error) {
+	response := vpc.NewDeleteSecurityGroupResponse()
+	return response, client.call("vpc.DeleteSecurityGroup", request, response)
+}
+
+func (client *mockVpcClient) DescribeSecurityGroups(request *vpc.DescribeSecurityGroupsRequest) (*vpc.DescribeSecurityGroupsResponse, error) {
+	response := vpc.NewDescribeSecurityGroupsResponse()
+	return response, client.call("vpc.DescribeSecurityGroups", request, response)
+}
+
+func (client *mockVpcClient) CreateSecurityGroupPolicies(request *vpc.CreateSecurityGroupPoliciesRequest) (*vpc.CreateSecurityGroupPoliciesResponse, error) {
+	response := vpc.NewCreateSecurityGroupPoliciesResponse()
+	return response, client.call("vpc.CreateSecurityGroupPolicies", request, response)
+}
+
+func (client *mockVpcClient) DeleteSecurityGroupPolicies(request *vpc.DeleteSecurityGroupPoliciesRequest) (*vpc.DeleteSecurityGroupPoliciesResponse, error) {
+	response := vpc.NewDeleteSecurityGroupPoliciesResponse()
+	return response, client.call("vpc.DeleteSecurityGroupPolicies", request, response)
+}
+
+func (client *mockVpcClient) DescribeSecurityGroupPolicies(request *vpc.DescribeSecurityGroupPoliciesRequest) (*vpc.DescribeSecurityGroupPoliciesResponse, error) {
+	response := vpc.NewDescribeSecurityGroupPoliciesResponse()
+	return response, client.call("vpc.DescribeSecurityGroupPolicies", request, response)
+}
+
+func (client *mockVpcClient) AllocateAddresses(request *vpc.AllocateAddressesRequest) (*vpc.AllocateAddressesResponse, error) {
+	response := vpc.NewAllocateAddressesResponse()
+	return response, client.call("vpc.AllocateAddresses", request, response)
+}
+
+func (client *mockVpcClient) ReleaseAddresses(request *vpc.ReleaseAddressesRequest) (*vpc.ReleaseAddressesResponse, error) {
+	response := vpc.NewReleaseAddressesResponse()
+	return response, client.call("vpc.ReleaseAddresses", request, response)
+}
+
+func (client *mockVpcClient) DescribeAddresses(request *vpc.DescribeAddressesRequest) (*vpc.DescribeAddressesResponse, error) {
+	response := vpc.NewDescribeAddressesResponse()
+	return response, client.call("vpc.DescribeAddresses", request, response)
+}
+
+func (client *mockVpcClient) AssociateAddress(request *vpc.AssociateAddressRequest) (*vpc.AssociateAddressResponse, error) {
+	response := vpc.NewAssociateAddressResponse()
+	return response, client.call("vpc.AssociateAddress", request, response)
+}
+
+func (client *mockVpcClient) DisassociateAddress(request *vpc.DisassociateAddressRequest) (*vpc.DisassociateAddressResponse, error) {
+	response := vpc.NewDisassociateAddressResponse()
+	return response, client.call("vpc.DisassociateAddress", request, response)
+}
+
+func (client *mockVpcClient) DescribeAddressQuota(request *vpc.DescribeAddressQuotaRequest) (*vpc.DescribeAddressQuotaResponse, error) {
+	response := vpc.NewDescribeAddressQuotaResponse()
+	return response, client.call("vpc.DescribeAddressQuota", request, response)
+}
+
+func (client *mockVpcClient) CreateNetworkInterface(request *vpc.CreateNetworkInterfaceRequest) (*vpc.CreateNetworkInterfaceResponse, error) {
+	response := vpc.NewCreateNetworkInterfaceResponse()
+	return response, client.call("vpc.CreateNetworkInterface", request, response)
+}
+
+func (client *mockVpcClient) DeleteNetworkInterface(request *vpc.DeleteNetworkInterfaceRequest) (*vpc.DeleteNetworkInterfaceResponse, error) {
+	response := vpc.NewDeleteNetworkInterfaceResponse()
+	return response, client.call("vpc.DeleteNetworkInterface", request, response)
+}
+
+func (client *mockVpcClient) DescribeNetworkInterfaces(request *vpc.DescribeNetworkInterfacesRequest) (*vpc.DescribeNetworkInterfacesResponse, error) {
+	response := vpc.NewDescribeNetworkInterfacesResponse()
+	return response, client.call("vpc.DescribeNetworkInterfaces", request, response)
+}
+
+func (client *mockVpcClient) AttachNetworkInterface(request *vpc.AttachNetworkInterfaceRequest) (*vpc.AttachNetworkInterfaceResponse, error) {
+	response := vpc.NewAttachNetworkInterfaceResponse()
+	return response, client.call("vpc.AttachNetworkInterface", request, response)
+}
+
+func (client *mockVpcClient) DetachNetworkInterface(request *vpc.DetachNetworkInterfaceRequest) (*vpc.DetachNetworkInterfaceResponse, error) {
+	response := vpc.NewDetachNetworkInterfaceResponse()
+	return response, client.call("vpc.DetachNetworkInterface", request, response)
+}
+
+func (client *mockVpcClient) CreateNatGateway(request *vpc.CreateNatGatewayRequest) (*vpc.CreateNatGatewayResponse, error) {
+	response := vpc.NewCreateNatGatewayResponse()
+	return response, client.call("vpc.CreateNatGateway", request, response)
+}
+
+func (client *mockVpcClient) DeleteNatGateway(request *vpc.DeleteNatGatewayRequest) (*vpc.DeleteNatGatewayResponse, error) {
+	response := vpc.NewDeleteNatGatewayResponse()
+	return response, client.call("vpc.DeleteNatGateway", request, response)
+}
+
+func (client *mockVpcClient) DescribeNatGateways(request *vpc.DescribeNatGatewaysRequest) (*vpc.DescribeNatGatewaysResponse, error) {
+	response := vpc.NewDescribeNatGatewaysResponse()
+	return response, client.call("vpc.DescribeNatGateways", request, response)
+}
+
+type mockCbsClient struct {
+	*mockClients
+}
+
+func (client *mockCbsClient) Send(request tchttp.Request, response tchttp.Response) error {
+	return client.call("cbs."+request.GetAction(), request, response)
+}
+
+func (client *mockCbsClient) DescribeDisks(request *cbs.DescribeDisksRequest) (*cbs.DescribeDisksResponse, error) {
+	response := cbs.NewDescribeDisksResponse()
+	return response, client.call("cbs.DescribeDisks", request, response)
+}
+
+func (client *mockCbsClient) DescribeDiskOperationLogs(request *cbs.DescribeDiskOperationLogsRequest) (*cbs.DescribeDiskOperationLogsResponse, error) {
+	response := cbs.NewDescribeDiskOperationLogsResponse()
+	return response, client.call("cbs.DescribeDiskOperationLogs", request, response)
+}
+
+func (client *mockCbsClient) AttachDisks(request *cbs.AttachDisksRequest) (*cbs.AttachDisksResponse, error) {
+	response := cbs.NewAttachDisksResponse()
+	return response, client.call("cbs.AttachDisks", request, response)
+}
+
+func (client *mockCbsClient) DetachDisks(request *cbs.DetachDisksRequest) (*cbs.DetachDisksResponse, error) {
+	response := cbs.NewDetachDisksResponse()
+	return response, client.call("cbs.DetachDisks", request, response)
+}
+
+func (client *mockCbsClient) TerminateDisks(request *cbs.TerminateDisksRequest) (*cbs.TerminateDisksResponse, error) {
+	response := cbs.NewTerminateDisksResponse()
+	return response, client.call("cbs.TerminateDisks", request, response)
+}
+
+func (client *mockCbsClient) ResizeDisk(request *cbs.ResizeDiskRequest) (*cbs.ResizeDiskResponse, error) {
+	response := cbs.NewResizeDiskResponse()
+	return response, client.call("cbs.ResizeDisk", request, response)
+}
+
+func (client *mockCbsClient) CreateSnapshot(request *cbs.CreateSnapshotRequest) (*cbs.CreateSnapshotResponse, error) {
+	response := cbs.NewCreateSnapshotResponse()
+	return response, client.call("cbs.CreateSnapshot", request, response)
+}
+
+func (client *mockCbsClient) DeleteSnapshots(request *cbs.DeleteSnapshotsRequest) (*cbs.DeleteSnapshotsResponse, error) {
+	response := cbs.NewDeleteSnapshotsResponse()
+	return response, client.call("cbs.DeleteSnapshots", request, response)
+}
+
+func (client *mockCbsClient) DescribeSnapshots(request *cbs.DescribeSnapshotsRequest) (*cbs.DescribeSnapshotsResponse, error) {
+	response := cbs.NewDescribeSnapshotsResponse()
+	return response, client.call("cbs.DescribeSnapshots", request, response)
+}
+
+func (client *mockCbsClient) ApplySnapshot(request *cbs.ApplySnapshotRequest) (*cbs.ApplySnapshotResponse, error) {
+	response := cbs.NewApplySnapshotResponse()
+	return response, client.call("cbs.ApplySnapshot", request, response)
+}
+
+func (client *mockCbsClient) CreateAutoSnapshotPolicy(request *cbs.CreateAutoSnapshotPolicyRequest) (*cbs.CreateAutoSnapshotPolicyResponse, error) {
+	response := cbs.NewCreateAutoSnapshotPolicyResponse()
+	return response, client.call("cbs.CreateAutoSnapshotPolicy", request, response)
+}
+
+func (client *mockCbsClient) DeleteAutoSnapshotPolicies(request *cbs.DeleteAutoSnapshotPoliciesRequest) (*cbs.DeleteAutoSnapshotPoliciesResponse, error) {
+	response := cbs.NewDeleteAutoSnapshotPoliciesResponse()
+	return response, client.call("cbs.DeleteAutoSnapshotPolicies", request, response)
+}
+
+func (client *mockCbsClient) DescribeAutoSnapshotPolicies(request *cbs.DescribeAutoSnapshotPoliciesRequest) (*cbs.DescribeAutoSnapshotPoliciesResponse, error) {
+	response := cbs.NewDescribeAutoSnapshotPoliciesResponse()
+	return response, client.call("cbs.DescribeAutoSnapshotPolicies", request, response)
+}
+
+func (client *mockCbsClient) BindAutoSnapshotPolicy(request *cbs.BindAutoSnapshotPolicyRequest) (*cbs.BindAutoSnapshotPolicyResponse, error) {
+	response := cbs.NewBindAutoSnapshotPolicyResponse()
+	return response, client.call("cbs.BindAutoSnapshotPolicy", request, response)
+}
+
+func (client *mockCbsClient) UnbindAutoSnapshotPolicy(request *cbs.UnbindAutoSnapshotPolicyRequest) (*cbs.UnbindAutoSnapshotPolicyResponse, error) {
+	response := cbs.NewUnbindAutoSnapshotPolicyResponse()
+	return response, client.call("cbs.UnbindAutoSnapshotPolicy", request, response)
+}
+
+type mockCdbClient struct {
+	*mockClients
+}
+
+func (client *mockCdbClient) CreateDBInstance(request *cdb.CreateDBInstanceRequest) (*cdb.CreateDBInstanceResponse, error) {
+	response := cdb.NewCreateDBInstanceResponse()
+	return response, client.call("cdb.CreateDBInstance", request, response)
+}
+
+func (client *mockCdbClient) CreateDBInstanceHour(request *cdb.CreateDBInstanceHourRequest) (*cdb.CreateDBInstanceHourResponse, error) {
+	response := cdb.NewCreateDBInstanceHourResponse()
+	return response, client.call("cdb.CreateDBInstanceHour", request, response)
+}
+
+func (client *mockCdbClient) DescribeDBInstances(request *cdb.DescribeDBInstancesRequest) (*cdb.DescribeDBInstancesResponse, error) {
+	response := cdb.NewDescribeDBInstancesResponse()
+	return response, client.call("cdb.DescribeDBInstances", request, response)
+}
+
+func (client *mockCdbClient) InitDBInstances(request *cdb.InitDBInstancesRequest) (*cdb.InitDBInstancesResponse, error) {
+	response := cdb.NewInitDBInstancesResponse()
+	return response, client.call("cdb.InitDBInstances", request, response)
+}
+
+func (client *mockCdbClient) IsolateDBInstance(request *cdb.IsolateDBInstanceRequest) (*cdb.IsolateDBInstanceResponse, error) {
+	response := cdb.NewIsolateDBInstanceResponse()
+	return response, client.call("cdb.IsolateDBInstance", request, response)
+}
+
+func (client *mockCdbClient) RestartDBInstances(request *cdb.RestartDBInstancesRequest) (*cdb.RestartDBInstancesResponse, error) {
+	response := cdb.NewRestartDBInstancesResponse()
+	return response, client.call("cdb.RestartDBInstances", request, response)
+}
+
+func (client *mockCdbClient) DescribeAsyncRequestInfo(request *cdb.DescribeAsyncRequestInfoRequest) (*cdb.DescribeAsyncRequestInfoResponse, error) {
+	response := cdb.NewDescribeAsyncRequestInfoResponse()
+	return response, client.call("cdb.DescribeAsyncRequestInfo", request, response)
+}
+
+func (client *mockCdbClient) DescribeDBSecurityGroups(request *cdb.DescribeDBSecurityGroupsRequest) (*cdb.DescribeDBSecurityGroupsResponse, error) {
+	response := cdb.NewDescribeDBSecurityGroupsResponse()
+	return response, client.call("cdb.DescribeDBSecurityGroups", request, response)
+}
+
+func (client *mockCdbClient) ModifyDBInstanceSecurityGroups(request *cdb.ModifyDBInstanceSecurityGroupsRequest) (*cdb.ModifyDBInstanceSecurityGroupsResponse, error) {
+	response := cdb.NewModifyDBInstanceSecurityGroupsResponse()
+	return response, client.call("cdb.ModifyDBInstanceSecurityGroups", request, response)
+}
+
+type mockRedisClient struct {
+	*mockClients
+}
+
+func (client *mockRedisClient) CreateInstances(request *redis.CreateInstancesRequest) (*redis.CreateInstancesResponse, error) {
+	response := redis.NewCreateInstancesResponse()
+	return response, client.call("redis.CreateInstances", request, response)
+}
+
+func (client *mockRedisClient) DescribeInstanceDealDetail(request *redis.DescribeInstanceDealDetailRequest) (*redis.DescribeInstanceDealDetailResponse, error) {
+	response := redis.NewDescribeInstanceDealDetailResponse()
+	return response, client.call("redis.DescribeInstanceDealDetail", request, response)
+}
+
+func (client *mockRedisClient) DescribeInstances(request *redis.DescribeInstancesRequest) (*redis.DescribeInstancesResponse, error) {
+	response := redis.NewDescribeInstancesResponse()
+	return response, client.call("redis.DescribeInstances", request, response)
+}
+
+type mockMariadbClient struct {
+	*mockClients
+}
+
+func (client *mockMariadbClient) CreateDBInstance(request *mariadb.CreateDBInstanceRequest) (*mariadb.CreateDBInstanceResponse, error) {
+	response := mariadb.NewCreateDBInstanceResponse()
+	return response, client.call("mariadb.CreateDBInstance", request, response)
+}
+
+func (client *mockMariadbClient) DescribeOrders(request *mariadb.DescribeOrdersRequest) (*mariadb.DescribeOrdersResponse, error) {
+	response := mariadb.NewDescribeOrdersResponse()
+	return response, client.call("mariadb.DescribeOrders", request, response)
+}
+
+func (client *mockMariadbClient) DescribeDBInstances(request *mariadb.DescribeDBInstancesRequest) (*mariadb.DescribeDBInstancesResponse, error) {
+	response := mariadb.NewDescribeDBInstancesResponse()
+	return response, client.call("mariadb.DescribeDBInstances", request, response)
+}
+
+func (client *mockMariadbClient) InitDBInstances(request *mariadb.InitDBInstancesRequest) (*mariadb.InitDBInstancesResponse, error) {
+	response := mariadb.NewInitDBInstancesResponse()
+	return response, client.call("mariadb.InitDBInstances", request, response)
+}
+
+func (client *mockMariadbClient) DescribeFlow(request *mariadb.DescribeFlowRequest) (*mariadb.DescribeFlowResponse, error) {
+	response := mariadb.NewDescribeFlowResponse()
+	return response, client.call("mariadb.DescribeFlow", request, response)
+}
+
+func (client *mockMariadbClient) CreateAccount(request *mariadb.CreateAccountRequest) (*mariadb.CreateAccountResponse, error) {
+	response := mariadb.NewCreateAccountResponse()
+	return response, client.call("mariadb.CreateAccount", request, response)
+}
+
+func (client *mockMariadbClient) GrantAccountPrivileges(request *mariadb.GrantAccountPrivilegesRequest) (*mariadb.GrantAccountPrivilegesResponse, error) {
+	response := mariadb.NewGrantAccountPrivilegesResponse()
+	return response, client.call("mariadb.GrantAccountPrivileges", request, response)
+}
+
+func TestMockClients(t *testing.T) {
+	clients, cleanup := setupMockClients(t, map[string][]mockResponse{
+		"vpc.DescribeVpcs": {mockError("InternalError"), mockOk(`{"TotalCount":1,"VpcSet":[{"VpcId":"vpc-1"}]}`)},
+	})
+	defer cleanup()
+
+	client, _ := CreateVpcClient("ap-guangzhou", "mock-secret-id", "mock-secret-key")
+	if _, err := client.DescribeVpcs(vpc.NewDescribeVpcsRequest()); err == nil {
+		t.Fatalf("first call should return the mock error")
+	}
+	for i := 0; i < 2; i++ {
+		response, err := client.DescribeVpcs(vpc.NewDescribeVpcsRequest())
+		if err != nil {
+			t.Fatalf("describe vpcs meet err=%v", err)
+		}
+		if *response.Response.VpcSet[0].VpcId != "vpc-1" || *response.Response.RequestId != MOCK_REQUEST_ID {
+			t.Fatalf("unexpected response %s", response.ToJsonString())
+		}
+	}
+	if _, err := client.CreateVpc(vpc.NewCreateVpcRequest()); err == nil {
+		t.Fatalf("api without responses should fail")
+	}
+	if len(clients.calls) != 4 {
+		t.Fatalf("calls=%v,want 4 calls", clients.calls)
+	}
+}
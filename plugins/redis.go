@@ -7,8 +7,6 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
-	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
-	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
 	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
 	redis "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/redis/v20180412"
 )
@@ -24,13 +22,8 @@ func init() {
 	RedisActions["create"] = new(RedisCreateAction)
 }
 
-func CreateRedisClient(region, secretId, secretKey string) (client *redis.Client, err error) {
-	credential := common.NewCredential(secretId, secretKey)
-
-	clientProfile := profile.NewClientProfile()
-	clientProfile.HttpProfile.Endpoint = "redis.tencentcloudapi.com"
-
-	return redis.NewClient(credential, region, clientProfile)
+func CreateRedisClient(region, secretId, secretKey string) (RedisAPI, error) {
+	return QcloudClientFactory.NewRedisClient(region, secretId, secretKey)
 }
 
 type RedisInputs struct {
@@ -49,6 +42,7 @@ type RedisInput struct {
 	VpcID          string `json:"vpc_id,omitempty"`
 	SubnetID       string `json:"subnet_id,omitempty"`
 	ID             string `json:"id,omitempty"`
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
 }
 
 type RedisOutputs struct {
@@ -109,7 +103,7 @@ func (action *RedisCreateAction) CheckParam(input interface{}) error {
 	return nil
 }
 
-func (action *RedisCreateAction) createRedis(redisInput *RedisInput) (*RedisOutput, error) {
+func (action *RedisCreateAction) createRedis(redisInput *RedisInput, idempotencyKey string) (*RedisOutput, error) {
 	paramsMap, err := GetMapFromProviderParams(redisInput.ProviderParams)
 	client, _ := CreateRedisClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
 
@@ -125,6 +119,15 @@ func (action *RedisCreateAction) createRedis(redisInput *RedisInput) (*RedisOutp
 		}
 	}
 
+	//the deal of the last try of the key is only waited for
+	progress, err := loadIdempotencyCreatedIds(idempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	if progress != nil {
+		return action.waitForRedisDeal(client, redisInput, progress.Ids[0], progress.RequestId)
+	}
+
 	zonemap, err := GetAvaliableZoneInfo(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
 	if err != nil {
 		return nil, err
@@ -136,8 +139,6 @@ func (action *RedisCreateAction) createRedis(redisInput *RedisInput) (*RedisOutp
 		return nil, err
 	}
 
-	output := RedisOutput{}
-
 	if redisInput.ID != "" {
 		queryRedisInstanceResponse, flag, err := queryRedisInstancesInfo(client, redisInput)
 		if err != nil && flag == false {
@@ -175,15 +176,21 @@ func (action *RedisCreateAction) createRedis(redisInput *RedisInput) (*RedisOutp
 	logrus.Info("create redis instance response = ", *response.Response.RequestId)
 
 	logrus.Info("new redis instance dealid = ", *response.Response.DealId)
+	saveIdempotencyCreatedIds(idempotencyKey, *response.Response.RequestId, *response.Response.DealId)
+
+	return action.waitForRedisDeal(client, redisInput, *response.Response.DealId, *response.Response.RequestId)
+}
 
-	instanceid, err := action.waitForRedisInstancesCreationToFinish(client, *response.Response.DealId)
+func (action *RedisCreateAction) waitForRedisDeal(client RedisAPI, redisInput *RedisInput, dealId string, requestId string) (*RedisOutput, error) {
+	instanceid, err := action.waitForRedisInstancesCreationToFinish(client, dealId)
 	if err != nil {
 		return nil, err
 	}
 
-	output.RequestId = *response.Response.RequestId
+	output := RedisOutput{}
+	output.RequestId = requestId
 	output.Guid = redisInput.Guid
-	output.DealID = *response.Response.DealId
+	output.DealID = dealId
 	output.ID = instanceid
 
 	return &output, nil
@@ -193,18 +200,55 @@ func (action *RedisCreateAction) Do(input interface{}) (interface{}, error) {
 	rediss, _ := input.(RedisInputs)
 	outputs := RedisOutputs{}
 	for _, redis := range rediss.Inputs {
-		redisOutput, err := action.createRedis(&redis)
+		redisOutput := RedisOutput{}
+		idempotencyKey := getIdempotencyKey("redis-create", redis.Guid, redis.IdempotencyKey, redis)
+		err := runWithIdempotencyKey(idempotencyKey, &redisOutput, func() (interface{}, error) {
+			return action.createRedis(&redis, idempotencyKey)
+		})
 		if err != nil {
 			return nil, err
 		}
-		outputs.Outputs = append(outputs.Outputs, *redisOutput)
+		outputs.Outputs = append(outputs.Outputs, redisOutput)
 	}
 
 	logrus.Infof("all rediss = %v are created", rediss)
 	return &outputs, nil
 }
 
-func (action *RedisCreateAction) waitForRedisInstancesCreationToFinish(client *redis.Client, dealid string) (string, error) {
+func (action *RedisCreateAction) DryRun(input interface{}) (interface{}, error) {
+	rediss, _ := input.(RedisInputs)
+	outputs := DryRunOutputs{}
+	for _, redisInput := range rediss.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(redisInput.ProviderParams)
+		client, _ := CreateRedisClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+
+		exist := false
+		if redisInput.ID != "" {
+			_, flag, err := queryRedisInstancesInfo(client, &redisInput)
+			if err != nil {
+				return nil, err
+			}
+			exist = flag
+		}
+
+		detail := fmt.Sprintf("create %d redis instance type=%d mem_size=%dMB in zone[%s]", redisInput.GoodsNum, redisInput.TypeID, redisInput.MemSize, paramsMap["AvailableZone"])
+		output := newCreateDryRunOutput(redisInput.Guid, redisInput.ID, exist, detail)
+		if !exist {
+			zonemap, err := GetAvaliableZoneInfo(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+			if err != nil {
+				return nil, err
+			}
+			if _, found := zonemap[paramsMap["AvailableZone"]]; !found {
+				output.Conflicts = append(output.Conflicts, fmt.Sprintf("available zone[%s] not found", paramsMap["AvailableZone"]))
+			}
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+
+	return &outputs, nil
+}
+
+func (action *RedisCreateAction) waitForRedisInstancesCreationToFinish(client RedisAPI, dealid string) (string, error) {
 	request := redis.NewDescribeInstanceDealDetailRequest()
 	request.DealIds = append(request.DealIds, &dealid)
 	var instanceids string
@@ -238,13 +282,8 @@ func (action *RedisCreateAction) waitForRedisInstancesCreationToFinish(client *r
 	}
 }
 
-func CreateDescribeZonesClient(region, secretId, secretKey string) (client *cvm.Client, err error) {
-	credential := common.NewCredential(secretId, secretKey)
-
-	clientProfile := profile.NewClientProfile()
-	clientProfile.HttpProfile.Endpoint = "cvm.tencentcloudapi.com"
-
-	return cvm.NewClient(credential, region, clientProfile)
+func CreateDescribeZonesClient(region, secretId, secretKey string) (CvmAPI, error) {
+	return QcloudClientFactory.NewCvmClient(region, secretId, secretKey)
 }
 
 func GetAvaliableZoneInfo(region, secretid, secretkey string) (map[string]int, error) {
@@ -272,7 +311,7 @@ func GetAvaliableZoneInfo(region, secretid, secretkey string) (map[string]int, e
 	return ZoneMap, nil
 }
 
-func queryRedisInstancesInfo(client *redis.Client, input *RedisInput) (*RedisOutput, bool, error) {
+func queryRedisInstancesInfo(client RedisAPI, input *RedisInput) (*RedisOutput, bool, error) {
 	output := RedisOutput{}
 
 	var limit uint64
@@ -4,7 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"github.com/sirupsen/logrus"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
 	vpc "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/vpc/v20170312"
+	"net"
 	"strconv"
 	"strings"
 )
@@ -42,6 +44,7 @@ type CreateRoutePolicyInput struct {
 	GatewayType     string `json:"gateway_type,omitempty"`
 	GatewayId       string `json:"gateway_id,omitempty"`
 	Description     string `json:"desc,omitempty"`
+	Overwrite       string `json:"overwrite,omitempty"`
 }
 
 type CreateRoutePolicyOutputs struct {
@@ -82,13 +85,8 @@ func isValidGatewayType(gatewayType string) error {
 	return fmt.Errorf("invalid gatewayType %s", gatewayType)
 }
 
-func isRouteConflicts(input CreateRoutePolicyInput) error {
-	paramsMap, _ := GetMapFromProviderParams(input.ProviderParams)
-	client, err := CreateRouteTableClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
-	if err != nil {
-		return err
-	}
-
+//queryRouteConflicts returns the routes of the route table to the same destination
+func queryRouteConflicts(client VpcAPI, input CreateRoutePolicyInput) ([]*vpc.Route, error) {
 	request := vpc.NewDescribeRouteConflictsRequest()
 	request.RouteTableId = &input.RouteTableId
 	request.DestinationCidrBlocks = []*string{&input.DestinationCidr}
@@ -96,26 +94,108 @@ func isRouteConflicts(input CreateRoutePolicyInput) error {
 	response, err := client.DescribeRouteConflicts(request)
 	if err != nil {
 		logrus.Errorf("DescribeRouteConflicts meet err=%v", err)
-		return err
+		return nil, err
 	}
 	if len(response.Response.RouteConflictSet) != 1 {
-		return fmt.Errorf("len(confilctSet)=%d,must be one", len(response.Response.RouteConflictSet))
+		return nil, fmt.Errorf("len(confilctSet)=%d,must be one", len(response.Response.RouteConflictSet))
+	}
+	return response.Response.RouteConflictSet[0].ConflictSet, nil
+}
+
+func getRoutePolicyOverwrite(input CreateRoutePolicyInput) bool {
+	overwrite, _ := strconv.ParseBool(input.Overwrite)
+	return overwrite
+}
+
+func newRoutePolicyEntry(input CreateRoutePolicyInput) routeEntry {
+	return routeEntry{
+		destinationCidr: input.DestinationCidr,
+		gatewayType:     strings.ToUpper(input.GatewayType),
+		gatewayId:       input.GatewayId,
+		enabled:         true,
+	}
+}
+
+//routePolicyPlan is what create does for an input, returning the existing route after enabling it if disabled,
+//replacing a route or creating a new one, the conflicts are the routes to the same destination which can't be replaced
+type routePolicyPlan struct {
+	existing  *vpc.Route
+	replaced  *vpc.Route
+	conflicts []*vpc.Route
+}
+
+func (plan routePolicyPlan) conflictsError() error {
+	conflicts := []string{}
+	for _, route := range plan.conflicts {
+		conflicts = append(conflicts, fmt.Sprintf("%s(%d)", newRouteEntry(route), *route.RouteId))
 	}
-	if len(response.Response.RouteConflictSet[0].ConflictSet) > 0 {
-		conflictDestCidrs := []string{}
-		for _, route := range response.Response.RouteConflictSet[0].ConflictSet {
-			conflictCidr := fmt.Sprintf("%s(%d)", *route.DestinationCidrBlock, *route.RouteId)
-			conflictDestCidrs = append(conflictDestCidrs, conflictCidr)
-		}
-		logrus.Errorf("route conflict,conflictSet=%++v", strings.Join(conflictDestCidrs, ","))
-		return fmt.Errorf("route conflict,confclitSet=%++v", strings.Join(conflictDestCidrs, ","))
+	return fmt.Errorf("route conflict,conflictSet=%s, set overwrite to true to replace the route", strings.Join(conflicts, ","))
+}
+
+//planRoutePolicy looks for a route to the same destination and gateway, it is returned instead of creating a duplicate,
+//a user route to the same destination but another gateway is replaced if overwrite is true, otherwise it conflicts
+func planRoutePolicy(client VpcAPI, input CreateRoutePolicyInput) (routePolicyPlan, error) {
+	plan := routePolicyPlan{}
+	routes, err := queryRouteConflicts(client, input)
+	if err != nil {
+		return plan, err
 	}
 
+	entry := newRoutePolicyEntry(input)
+	for _, route := range routes {
+		if entry.sameTarget(newRouteEntry(route)) {
+			plan.existing = route
+			return plan, nil
+		}
+	}
+	overwrite := getRoutePolicyOverwrite(input)
+	for _, route := range routes {
+		replaceable := stringValue(route.DestinationCidrBlock) == input.DestinationCidr && (route.RouteType == nil || *route.RouteType == ROUTE_TYPE_USER)
+		if overwrite && replaceable && plan.replaced == nil {
+			plan.replaced = route
+		} else {
+			plan.conflicts = append(plan.conflicts, route)
+		}
+	}
+	return plan, nil
+}
+
+//checkRouteDestinationCidr returns an error if the dest_cidr overlaps the cidrs of the vpc the route table belongs to,
+//such a route would take the traffic of the vpc away
+func checkRouteDestinationCidr(input CreateRoutePolicyInput) error {
+	paramsMap, _ := GetMapFromProviderParams(input.ProviderParams)
+	client, err := CreateRouteTableClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+	if err != nil {
+		return err
+	}
+
+	request := vpc.NewDescribeRouteTablesRequest()
+	request.RouteTableIds = []*string{&input.RouteTableId}
+	response, err := client.DescribeRouteTables(request)
+	if err != nil {
+		logrus.Errorf("DescribeRouteTables meet err=%v", err)
+		return err
+	}
+	if len(response.Response.RouteTableSet) == 0 {
+		return fmt.Errorf("route table[%s] not found", input.RouteTableId)
+	}
+
+	vpcId := *response.Response.RouteTableSet[0].VpcId
+	item, err := getVpcById(client, vpcId)
+	if err != nil {
+		return err
+	}
+	for _, cidrBlock := range getVpcCidrBlocks(item) {
+		if isCidrContains(cidrBlock, input.DestinationCidr) {
+			return fmt.Errorf("dest_cidr(%s) is inside %s of vpc[%s]", input.DestinationCidr, cidrBlock, vpcId)
+		}
+	}
 	return nil
 }
 
 func (action *CreateRoutePolicyAction) CheckParam(input interface{}) error {
 	inputs, _ := input.(CreateRoutePolicyInputs)
+	destCidrs := map[string]bool{}
 	for _, input := range inputs.Inputs {
 		if input.ProviderParams == "" {
 			return errors.New("CreateRoutePolicyAction input ProviderParams is empty")
@@ -126,16 +206,25 @@ func (action *CreateRoutePolicyAction) CheckParam(input interface{}) error {
 		if err := isValidGatewayType(input.GatewayType); err != nil {
 			return err
 		}
-		if err := isRouteConflicts(input); err != nil {
-			return err
-		}
-
 		if input.RouteTableId == "" {
 			return errors.New("CreateRoutePolicyAction input RouteTableId is empty")
 		}
 		if input.DestinationCidr == "" {
 			return errors.New("CreateRoutePolicyAction input DestinationCidr is empty")
 		}
+		if _, _, err := net.ParseCIDR(input.DestinationCidr); err != nil {
+			return fmt.Errorf("CreateRoutePolicyAction invalid dest_cidr(%s)", input.DestinationCidr)
+		}
+		if input.Overwrite != "" {
+			if _, err := strconv.ParseBool(input.Overwrite); err != nil {
+				return fmt.Errorf("CreateRoutePolicyAction invalid overwrite(%s)", input.Overwrite)
+			}
+		}
+		key := input.RouteTableId + "/" + input.DestinationCidr
+		if destCidrs[key] {
+			return fmt.Errorf("CreateRoutePolicyAction dest_cidr(%s) of route table[%s] is duplicated", input.DestinationCidr, input.RouteTableId)
+		}
+		destCidrs[key] = true
 		if input.GatewayId == "" {
 			return errors.New("CreateRoutePolicyAction input GatewayId is empty")
 		}
@@ -144,46 +233,154 @@ func (action *CreateRoutePolicyAction) CheckParam(input interface{}) error {
 	return nil
 }
 
+func newRoutePolicyRoute(input CreateRoutePolicyInput, routeId *uint64) *vpc.Route {
+	route := &vpc.Route{
+		RouteId:              routeId,
+		DestinationCidrBlock: common.StringPtr(input.DestinationCidr),
+		GatewayType:          common.StringPtr(strings.ToUpper(input.GatewayType)),
+		GatewayId:            common.StringPtr(input.GatewayId),
+		Enabled:              common.BoolPtr(true),
+	}
+	if input.Description != "" {
+		route.RouteDescription = common.StringPtr(input.Description)
+	}
+	return route
+}
+
+func createRoutePolicy(client VpcAPI, input CreateRoutePolicyInput, plan routePolicyPlan) (CreateRoutePolicyOutput, error) {
+	output := CreateRoutePolicyOutput{Guid: input.Guid}
+	if plan.existing != nil {
+		logrus.Infof("route to %s already exists in route table[%s]", newRouteEntry(plan.existing), input.RouteTableId)
+		output.Id = fmt.Sprintf("%d", *plan.existing.RouteId)
+		if newRouteEntry(plan.existing).enabled {
+			return output, nil
+		}
+
+		//a disabled route carries no traffic, it is enabled like a created one would be
+		request := vpc.NewEnableRoutesRequest()
+		request.RouteTableId = &input.RouteTableId
+		request.RouteIds = []*uint64{plan.existing.RouteId}
+		response, err := client.EnableRoutes(request)
+		if err != nil {
+			return output, err
+		}
+		logrus.Infof("enable route %s in route table[%s] has been submitted, RequestID is [%s]",
+			newRouteEntry(plan.existing), input.RouteTableId, *response.Response.RequestId)
+		output.RequestId = *response.Response.RequestId
+		return output, nil
+	}
+
+	//the replaced route keeps its id
+	if plan.replaced != nil {
+		request := vpc.NewReplaceRoutesRequest()
+		request.RouteTableId = &input.RouteTableId
+		request.Routes = []*vpc.Route{newRoutePolicyRoute(input, plan.replaced.RouteId)}
+		response, err := client.ReplaceRoutes(request)
+		if err != nil {
+			return output, err
+		}
+		logrus.Infof("replace route %s with %s in route table[%s] has been submitted, RequestID is [%s]",
+			newRouteEntry(plan.replaced), newRoutePolicyEntry(input), input.RouteTableId, *response.Response.RequestId)
+		output.RequestId = *response.Response.RequestId
+		output.Id = fmt.Sprintf("%d", *plan.replaced.RouteId)
+		return output, nil
+	}
+
+	request := vpc.NewCreateRoutesRequest()
+	request.RouteTableId = &input.RouteTableId
+	request.Routes = []*vpc.Route{newRoutePolicyRoute(input, nil)}
+	response, err := client.CreateRoutes(request)
+	if err != nil {
+		return output, err
+	}
+	if *response.Response.TotalCount != 1 {
+		return output, fmt.Errorf("createRoutePolicy add count(%d)!=1", *response.Response.TotalCount)
+	}
+	output.RequestId = *response.Response.RequestId
+	output.Id = fmt.Sprintf("%d", *response.Response.RouteTableSet[0].RouteSet[0].RouteId)
+	return output, nil
+}
+
 func (action *CreateRoutePolicyAction) Do(input interface{}) (interface{}, error) {
 	outputs := CreateRoutePolicyOutputs{}
 	inputs, _ := input.(CreateRoutePolicyInputs)
-	enable := true
 
+	//make sure no route will be changed if any of them conflicts
+	clients := []VpcAPI{}
+	plans := []routePolicyPlan{}
 	for _, input := range inputs.Inputs {
 		paramsMap, _ := GetMapFromProviderParams(input.ProviderParams)
 		client, err := CreateRouteTableClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
 		if err != nil {
 			return nil, err
 		}
-
-		request := vpc.NewCreateRoutesRequest()
-		request.RouteTableId = &input.RouteTableId
-		gatewayType := strings.ToUpper(input.GatewayType)
-		route := vpc.Route{
-			DestinationCidrBlock: &input.DestinationCidr,
-			GatewayType:          &gatewayType,
-			GatewayId:            &input.GatewayId,
-			Enabled:              &enable,
+		plan, err := planRoutePolicy(client, input)
+		if err != nil {
+			return nil, err
 		}
-		if input.Description != "" {
-			route.RouteDescription = &input.Description
+		if len(plan.conflicts) > 0 {
+			return nil, plan.conflictsError()
 		}
-		request.Routes = []*vpc.Route{&route}
+		if plan.existing == nil {
+			if err := checkRouteDestinationCidr(input); err != nil {
+				return nil, err
+			}
+		}
+		clients = append(clients, client)
+		plans = append(plans, plan)
+	}
 
-		response, err := client.CreateRoutes(request)
+	for i, input := range inputs.Inputs {
+		output, err := createRoutePolicy(clients[i], input, plans[i])
 		if err != nil {
 			return nil, err
 		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
 
-		if *response.Response.TotalCount != 1 {
-			return nil, fmt.Errorf("createRoutePolicy add count(%d)!=1", response.Response.TotalCount)
+	return &outputs, nil
+}
+
+func (action *CreateRoutePolicyAction) DryRun(input interface{}) (interface{}, error) {
+	outputs := DryRunOutputs{}
+	inputs, _ := input.(CreateRoutePolicyInputs)
+
+	for _, input := range inputs.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(input.ProviderParams)
+		client, err := CreateRouteTableClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+		if err != nil {
+			return nil, err
+		}
+		plan, err := planRoutePolicy(client, input)
+		if err != nil {
+			return nil, err
+		}
+		if plan.existing != nil {
+			existingId := fmt.Sprintf("%d", *plan.existing.RouteId)
+			if newRouteEntry(plan.existing).enabled {
+				outputs.Outputs = append(outputs.Outputs, newCreateDryRunOutput(input.Guid, existingId, true, ""))
+			} else {
+				detail := fmt.Sprintf("enable route %s in route table[%s]", newRouteEntry(plan.existing), input.RouteTableId)
+				outputs.Outputs = append(outputs.Outputs, newDryRunOutput(input.Guid, existingId, true, DRY_RUN_OPERATION_MODIFY, detail))
+			}
+			continue
 		}
 
-		output := CreateRoutePolicyOutput{
-			RequestId: *response.Response.RequestId,
-			Guid:      input.Guid,
+		var output DryRunOutput
+		if plan.replaced != nil {
+			detail := fmt.Sprintf("replace route %s with %s in route table[%s]", newRouteEntry(plan.replaced), newRoutePolicyEntry(input), input.RouteTableId)
+			output = newDryRunOutput(input.Guid, fmt.Sprintf("%d", *plan.replaced.RouteId), true, DRY_RUN_OPERATION_MODIFY, detail)
+		} else {
+			detail := fmt.Sprintf("create route dest_cidr=%s to %s gateway[%s] in route table[%s]",
+				input.DestinationCidr, strings.ToUpper(input.GatewayType), input.GatewayId, input.RouteTableId)
+			output = newCreateDryRunOutput(input.Guid, input.Id, false, detail)
+		}
+		for _, route := range plan.conflicts {
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("route conflicts with %s(%d)", newRouteEntry(route), *route.RouteId))
+		}
+		if err := checkRouteDestinationCidr(input); err != nil {
+			output.Conflicts = append(output.Conflicts, err.Error())
 		}
-		output.Id = fmt.Sprintf("%d", *response.Response.RouteTableSet[0].RouteSet[0].RouteId)
 		outputs.Outputs = append(outputs.Outputs, output)
 	}
 
@@ -275,3 +472,36 @@ func (action *DeleteRoutePolicyAction) Do(input interface{}) (interface{}, error
 	}
 	return &outputs, nil
 }
+
+func (action *DeleteRoutePolicyAction) DryRun(input interface{}) (interface{}, error) {
+	inputs, _ := input.(DeleteRoutePolicyInputs)
+	outputs := DryRunOutputs{}
+
+	for _, input := range inputs.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(input.ProviderParams)
+		client, err := CreateRouteTableClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+		if err != nil {
+			return nil, err
+		}
+
+		request := vpc.NewDescribeRouteTablesRequest()
+		request.RouteTableIds = []*string{&input.RouteTableId}
+		response, err := client.DescribeRouteTables(request)
+		if err != nil {
+			return nil, err
+		}
+
+		exist := false
+		detail := ""
+		for _, routeTable := range response.Response.RouteTableSet {
+			for _, route := range routeTable.RouteSet {
+				if fmt.Sprintf("%d", *route.RouteId) == input.Id {
+					exist = true
+					detail = fmt.Sprintf("delete route dest_cidr=%s from route table[%s]", *route.DestinationCidrBlock, input.RouteTableId)
+				}
+			}
+		}
+		outputs.Outputs = append(outputs.Outputs, newDeleteDryRunOutput(input.Guid, input.Id, exist, detail))
+	}
+	return &outputs, nil
+}
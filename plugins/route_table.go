@@ -3,19 +3,32 @@ package plugins
 import (
 	"errors"
 	"fmt"
+	"net"
+	"strconv"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
-	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
 	vpc "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/vpc/v20170312"
 )
 
+const (
+	//only the user routes can be created, modified or deleted, the others are managed by qcloud
+	ROUTE_TYPE_USER = "USER"
+
+	ROUTE_STATE_ENABLED  = "enabled"
+	ROUTE_STATE_DISABLED = "disabled"
+)
+
 var RouteTableActions = make(map[string]Action)
 
 func init() {
 	RouteTableActions["create"] = new(RouteTableCreateAction)
 	RouteTableActions["terminate"] = new(RouteTableTerminateAction)
 	RouteTableActions["associate-subnet"] = new(RouteTableAssociateSubnetAction)
+	RouteTableActions["describe"] = new(RouteTableDescribeAction)
+	RouteTableActions["rename"] = new(RouteTableRenameAction)
+	RouteTableActions["replace-routes"] = new(RouteTableReplaceRoutesAction)
 }
 
 type RouteTablePlugin struct {
@@ -30,13 +43,8 @@ func (plugin *RouteTablePlugin) GetActionByName(actionName string) (Action, erro
 	return action, nil
 }
 
-func CreateRouteTableClient(region, secretId, secretKey string) (client *vpc.Client, err error) {
-	credential := common.NewCredential(secretId, secretKey)
-
-	clientProfile := profile.NewClientProfile()
-	clientProfile.HttpProfile.Endpoint = "vpc.tencentcloudapi.com"
-
-	return vpc.NewClient(credential, region, clientProfile)
+func CreateRouteTableClient(region, secretId, secretKey string) (VpcAPI, error) {
+	return QcloudClientFactory.NewVpcClient(region, secretId, secretKey)
 }
 
 type RouteTableInputs struct {
@@ -49,6 +57,10 @@ type RouteTableInput struct {
 	Id             string `json:"id,omitempty"`
 	Name           string `json:"name,omitempty"`
 	VpcId          string `json:"vpc_id,omitempty"`
+	Routes         string `json:"routes,omitempty"`
+
+	//replace-routes only deletes all the user routes with an empty routes input when it is true
+	DeleteAllRoutes string `json:"delete_all_routes,omitempty"`
 }
 
 type RouteTableOutputs struct {
@@ -56,9 +68,15 @@ type RouteTableOutputs struct {
 }
 
 type RouteTableOutput struct {
-	RequestId string `json:"request_id,omitempty"`
-	Guid      string `json:"guid,omitempty"`
-	Id        string `json:"id,omitempty"`
+	RequestId   string `json:"request_id,omitempty"`
+	Guid        string `json:"guid,omitempty"`
+	Id          string `json:"id,omitempty"`
+	Name        string `json:"name,omitempty"`
+	VpcId       string `json:"vpc_id,omitempty"`
+	Main        string `json:"main,omitempty"`
+	SubnetIds   string `json:"subnet_ids,omitempty"`
+	Routes      string `json:"routes,omitempty"`
+	CreatedTime string `json:"created_time,omitempty"`
 }
 
 type RouteTableCreateAction struct {
@@ -146,6 +164,29 @@ func (action *RouteTableCreateAction) Do(input interface{}) (interface{}, error)
 	return &outputs, nil
 }
 
+func (action *RouteTableCreateAction) DryRun(input interface{}) (interface{}, error) {
+	inputs, _ := input.(RouteTableInputs)
+	outputs := DryRunOutputs{}
+	for _, input := range inputs.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(input.ProviderParams)
+		client, err := CreateRouteTableClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+		if err != nil {
+			return nil, err
+		}
+
+		exist := false
+		if input.Id != "" {
+			if exist, err = queryRouteTablesInfo(client, input.Id); err != nil {
+				return nil, err
+			}
+		}
+		detail := fmt.Sprintf("create route table name=%s in vpc[%s]", input.Name, input.VpcId)
+		outputs.Outputs = append(outputs.Outputs, newCreateDryRunOutput(input.Guid, input.Id, exist, detail))
+	}
+
+	return &outputs, nil
+}
+
 type RouteTableTerminateAction struct {
 }
 
@@ -233,7 +274,18 @@ func (action *RouteTableTerminateAction) Do(input interface{}) (interface{}, err
 	return &outputs, nil
 }
 
-func queryRouteTablesInfo(client *vpc.Client, id string) (bool, error) {
+//the route table has been checked to exist without association in CheckParam
+func (action *RouteTableTerminateAction) DryRun(input interface{}) (interface{}, error) {
+	routeTables, _ := input.(RouteTableInputs)
+	outputs := DryRunOutputs{}
+	for _, routeTable := range routeTables.Inputs {
+		outputs.Outputs = append(outputs.Outputs, newDeleteDryRunOutput(routeTable.Guid, routeTable.Id, true, "delete route table"))
+	}
+
+	return &outputs, nil
+}
+
+func queryRouteTablesInfo(client VpcAPI, id string) (bool, error) {
 	request := vpc.NewDescribeRouteTablesRequest()
 	request.RouteTableIds = append(request.RouteTableIds, &id)
 	response, err := client.DescribeRouteTables(request)
@@ -332,3 +384,654 @@ func (action *RouteTableAssociateSubnetAction) Do(input interface{}) (interface{
 
 	return &outputs, nil
 }
+
+func (action *RouteTableAssociateSubnetAction) DryRun(input interface{}) (interface{}, error) {
+	outputs := DryRunOutputs{}
+	inputs, _ := input.(AssociateRouteTableInputs)
+	for _, input := range inputs.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(input.ProviderParams)
+		client, err := CreateRouteTableClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+		if err != nil {
+			return nil, err
+		}
+
+		output := newDryRunOutput(input.Guid, input.RouteTableId, false, DRY_RUN_OPERATION_MODIFY,
+			fmt.Sprintf("associate subnet[%s] with route table[%s]", input.SubnetId, input.RouteTableId))
+		if output.Exist, err = queryRouteTablesInfo(client, input.RouteTableId); err != nil {
+			return nil, err
+		}
+		if !output.Exist {
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("route table[%s] not found", input.RouteTableId))
+		}
+		_, subnetExist, err := querySubnetsInfo(client, &SubnetInput{Id: input.SubnetId})
+		if err != nil {
+			return nil, err
+		}
+		if !subnetExist {
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("subnet[%s] not found", input.SubnetId))
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+
+	return &outputs, nil
+}
+
+//---------------describe, rename and replace routes-----------------------//
+type RouteTableAction struct {
+}
+
+func (action *RouteTableAction) ReadParam(param interface{}) (interface{}, error) {
+	var inputs RouteTableInputs
+	err := UnmarshalJson(param, &inputs)
+	if err != nil {
+		return nil, err
+	}
+	return inputs, nil
+}
+
+func (action *RouteTableAction) CheckParam(input interface{}) error {
+	routeTables, ok := input.(RouteTableInputs)
+	if !ok {
+		return INVALID_PARAMETERS
+	}
+
+	for _, routeTable := range routeTables.Inputs {
+		if routeTable.Id == "" {
+			return errors.New("input id is empty")
+		}
+	}
+	return nil
+}
+
+func createRouteTableClientByProviderParams(providerParams string) (VpcAPI, error) {
+	paramsMap, _ := GetMapFromProviderParams(providerParams)
+	return CreateRouteTableClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+}
+
+func queryRouteTableById(client VpcAPI, id string) (*vpc.RouteTable, bool, error) {
+	request := vpc.NewDescribeRouteTablesRequest()
+	request.RouteTableIds = []*string{&id}
+	response, err := client.DescribeRouteTables(request)
+	if err != nil {
+		logrus.Errorf("DescribeRouteTables meet err=%v", err)
+		return nil, false, err
+	}
+	if len(response.Response.RouteTableSet) == 0 {
+		return nil, false, nil
+	}
+	return response.Response.RouteTableSet[0], true, nil
+}
+
+func getRouteTableById(client VpcAPI, id string) (*vpc.RouteTable, error) {
+	routeTable, found, err := queryRouteTableById(client, id)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("route table[%s] not found", id)
+	}
+	return routeTable, nil
+}
+
+//routeEntry is one route of the routes input, like 192.168.0.0/24,NORMAL_CVM,10.0.1.10 or with a trailing ",disabled"
+type routeEntry struct {
+	destinationCidr string
+	gatewayType     string
+	gatewayId       string
+	enabled         bool
+}
+
+func (entry routeEntry) String() string {
+	route := fmt.Sprintf("%s,%s,%s", entry.destinationCidr, entry.gatewayType, entry.gatewayId)
+	if !entry.enabled {
+		route += "," + ROUTE_STATE_DISABLED
+	}
+	return route
+}
+
+//sameTarget is true if both routes send the same destination to the same gateway
+func (entry routeEntry) sameTarget(other routeEntry) bool {
+	return entry.destinationCidr == other.destinationCidr && entry.gatewayType == other.gatewayType && entry.gatewayId == other.gatewayId
+}
+
+//parseRouteEntries parses routes separated by ";", the destination of each route must be unique
+func parseRouteEntries(routes string) ([]routeEntry, error) {
+	entries := []routeEntry{}
+	destinations := make(map[string]bool)
+	for _, route := range strings.Split(routes, ";") {
+		if route = strings.TrimSpace(route); route == "" {
+			continue
+		}
+		fields := strings.Split(route, ",")
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		if len(fields) < 3 || len(fields) > 4 || fields[2] == "" {
+			return nil, fmt.Errorf("invalid route(%s), should be like 192.168.0.0/24,NORMAL_CVM,10.0.1.10[,disabled]", route)
+		}
+		if _, _, err := net.ParseCIDR(fields[0]); err != nil {
+			return nil, fmt.Errorf("invalid route(%s) dest_cidr", route)
+		}
+		if err := isValidGatewayType(fields[1]); err != nil {
+			return nil, err
+		}
+		entry := routeEntry{destinationCidr: fields[0], gatewayType: strings.ToUpper(fields[1]), gatewayId: fields[2], enabled: true}
+		if len(fields) == 4 {
+			switch fields[3] {
+			case ROUTE_STATE_ENABLED:
+			case ROUTE_STATE_DISABLED:
+				entry.enabled = false
+			default:
+				return nil, fmt.Errorf("invalid route(%s) state, should be %s or %s", route, ROUTE_STATE_ENABLED, ROUTE_STATE_DISABLED)
+			}
+		}
+		if destinations[entry.destinationCidr] {
+			return nil, fmt.Errorf("route to %s is duplicated", entry.destinationCidr)
+		}
+		destinations[entry.destinationCidr] = true
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func newRouteEntry(route *vpc.Route) routeEntry {
+	return routeEntry{
+		destinationCidr: stringValue(route.DestinationCidrBlock),
+		gatewayType:     stringValue(route.GatewayType),
+		gatewayId:       stringValue(route.GatewayId),
+		enabled:         route.Enabled == nil || *route.Enabled,
+	}
+}
+
+//getUserRoutes returns the routes of the route table which can be managed, the routes without type are user routes
+func getUserRoutes(routeTable *vpc.RouteTable) []*vpc.Route {
+	routes := []*vpc.Route{}
+	for _, route := range routeTable.RouteSet {
+		if route.RouteType == nil || *route.RouteType == ROUTE_TYPE_USER {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+func formatRouteEntries(entries []routeEntry) string {
+	routes := []string{}
+	for _, entry := range entries {
+		routes = append(routes, entry.String())
+	}
+	return strings.Join(routes, ";")
+}
+
+func formatUserRoutes(routeTable *vpc.RouteTable) string {
+	entries := []routeEntry{}
+	for _, route := range getUserRoutes(routeTable) {
+		entries = append(entries, newRouteEntry(route))
+	}
+	return formatRouteEntries(entries)
+}
+
+func newRouteTableOutput(guid string, requestId string, routeTable *vpc.RouteTable) RouteTableOutput {
+	subnetIds := []string{}
+	for _, association := range routeTable.AssociationSet {
+		subnetIds = append(subnetIds, stringValue(association.SubnetId))
+	}
+	return RouteTableOutput{
+		Guid:        guid,
+		RequestId:   requestId,
+		Id:          stringValue(routeTable.RouteTableId),
+		Name:        stringValue(routeTable.RouteTableName),
+		VpcId:       stringValue(routeTable.VpcId),
+		Main:        fmt.Sprint(routeTable.Main != nil && *routeTable.Main),
+		SubnetIds:   strings.Join(subnetIds, ","),
+		Routes:      formatUserRoutes(routeTable),
+		CreatedTime: stringValue(routeTable.CreatedTime),
+	}
+}
+
+//RouteTableDescribeAction returns the route table with its associated subnets and user routes,
+//the routes are in the same format as the routes input of replace-routes
+type RouteTableDescribeAction struct {
+	RouteTableAction
+}
+
+func (action *RouteTableDescribeAction) Do(input interface{}) (interface{}, error) {
+	routeTables, _ := input.(RouteTableInputs)
+	outputs := RouteTableOutputs{}
+	for _, input := range routeTables.Inputs {
+		client, err := createRouteTableClientByProviderParams(input.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+		routeTable, err := getRouteTableById(client, input.Id)
+		if err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, newRouteTableOutput(input.Guid, "", routeTable))
+	}
+	return &outputs, nil
+}
+
+type RouteTableRenameAction struct {
+	RouteTableAction
+}
+
+func (action *RouteTableRenameAction) CheckParam(input interface{}) error {
+	if err := action.RouteTableAction.CheckParam(input); err != nil {
+		return err
+	}
+	for _, routeTable := range input.(RouteTableInputs).Inputs {
+		if routeTable.Name == "" {
+			return errors.New("input name is empty")
+		}
+	}
+	return nil
+}
+
+func renameRouteTable(input RouteTableInput) (RouteTableOutput, error) {
+	client, err := createRouteTableClientByProviderParams(input.ProviderParams)
+	if err != nil {
+		return RouteTableOutput{}, err
+	}
+
+	routeTable, err := getRouteTableById(client, input.Id)
+	if err != nil {
+		return RouteTableOutput{}, err
+	}
+	if stringValue(routeTable.RouteTableName) == input.Name {
+		logrus.Infof("route table[%s] is already named %s", input.Id, input.Name)
+		return newRouteTableOutput(input.Guid, "", routeTable), nil
+	}
+
+	request := vpc.NewModifyRouteTableAttributeRequest()
+	request.RouteTableId = &input.Id
+	request.RouteTableName = &input.Name
+	response, err := client.ModifyRouteTableAttribute(request)
+	if err != nil {
+		return RouteTableOutput{}, err
+	}
+	logrus.Infof("rename route table[%s] to %s has been submitted, RequestID is [%s]", input.Id, input.Name, *response.Response.RequestId)
+
+	routeTable.RouteTableName = &input.Name
+	return newRouteTableOutput(input.Guid, *response.Response.RequestId, routeTable), nil
+}
+
+func (action *RouteTableRenameAction) Do(input interface{}) (interface{}, error) {
+	routeTables, _ := input.(RouteTableInputs)
+	outputs := RouteTableOutputs{}
+	for _, input := range routeTables.Inputs {
+		output, err := renameRouteTable(input)
+		if err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+func (action *RouteTableRenameAction) DryRun(input interface{}) (interface{}, error) {
+	routeTables, _ := input.(RouteTableInputs)
+	outputs := DryRunOutputs{}
+	for _, input := range routeTables.Inputs {
+		client, err := createRouteTableClientByProviderParams(input.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		routeTable, exist, err := queryRouteTableById(client, input.Id)
+		if err != nil {
+			return nil, err
+		}
+		output := newDryRunOutput(input.Guid, input.Id, exist, DRY_RUN_OPERATION_NONE, "")
+		if !exist {
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("route table[%s] not found", input.Id))
+		} else if stringValue(routeTable.RouteTableName) == input.Name {
+			output.Detail = fmt.Sprintf("route table is already named %s", input.Name)
+		} else {
+			output.Operation = DRY_RUN_OPERATION_MODIFY
+			output.Detail = fmt.Sprintf("rename route table from %s to %s", stringValue(routeTable.RouteTableName), input.Name)
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+//RouteTableReplaceRoutesAction makes the user routes of the route table the same as the routes input,
+//an empty routes input deletes all the user routes only with delete_all_routes, a missing input never does
+type RouteTableReplaceRoutesAction struct {
+	RouteTableAction
+}
+
+func (action *RouteTableReplaceRoutesAction) CheckParam(input interface{}) error {
+	if err := action.RouteTableAction.CheckParam(input); err != nil {
+		return err
+	}
+	for _, routeTable := range input.(RouteTableInputs).Inputs {
+		entries, err := parseRouteEntries(routeTable.Routes)
+		if err != nil {
+			return err
+		}
+		deleteAll := false
+		if routeTable.DeleteAllRoutes != "" {
+			if deleteAll, err = strconv.ParseBool(routeTable.DeleteAllRoutes); err != nil {
+				return fmt.Errorf("invalid delete_all_routes(%s)", routeTable.DeleteAllRoutes)
+			}
+		}
+		if len(entries) == 0 && !deleteAll {
+			return fmt.Errorf("route table[%s] input routes is empty, set delete_all_routes to true to delete all the user routes", routeTable.Id)
+		}
+		if len(entries) > 0 && deleteAll {
+			return fmt.Errorf("route table[%s] input routes can't be used together with delete_all_routes", routeTable.Id)
+		}
+	}
+	return nil
+}
+
+//routeChanges is what replace-routes does to the route table, in the order of replacing, creating, enabling, disabling and deleting,
+//the stale routes are deleted last so that the routes still wanted keep working until the new ones are in place
+type routeChanges struct {
+	replaced []routeReplacement
+	created  []routeEntry
+	enabled  []*vpc.Route
+	disabled []*vpc.Route
+	deleted  []*vpc.Route
+}
+
+//routeReplacement moves the destination of the route to the gateway of the entry, the route keeps its id and state
+type routeReplacement struct {
+	route *vpc.Route
+	entry routeEntry
+}
+
+func (replacement routeReplacement) String() string {
+	return fmt.Sprintf("%s->%s,%s", newRouteEntry(replacement.route), replacement.entry.gatewayType, replacement.entry.gatewayId)
+}
+
+func (changes routeChanges) isEmpty() bool {
+	return len(changes.replaced)+len(changes.created)+len(changes.enabled)+len(changes.disabled)+len(changes.deleted) == 0
+}
+
+//changeState enables or disables the route if its state is not the wanted one
+func (changes *routeChanges) changeState(route *vpc.Route, enabled bool, wantEnabled bool) {
+	if wantEnabled && !enabled {
+		changes.enabled = append(changes.enabled, route)
+	} else if !wantEnabled && enabled {
+		changes.disabled = append(changes.disabled, route)
+	}
+}
+
+func describeRouteChange(action string, routes []string) string {
+	return fmt.Sprintf("%s %d routes[%s]", action, len(routes), strings.Join(routes, ";"))
+}
+
+func formatRoutes(routes []*vpc.Route) []string {
+	formatted := []string{}
+	for _, route := range routes {
+		formatted = append(formatted, newRouteEntry(route).String())
+	}
+	return formatted
+}
+
+func (changes routeChanges) String() string {
+	replaced := []string{}
+	for _, replacement := range changes.replaced {
+		replaced = append(replaced, replacement.String())
+	}
+	created := []string{}
+	for _, entry := range changes.created {
+		created = append(created, entry.String())
+	}
+
+	descriptions := []string{}
+	for _, change := range []struct {
+		action string
+		routes []string
+	}{
+		{"replace", replaced},
+		{"create", created},
+		{"enable", formatRoutes(changes.enabled)},
+		{"disable", formatRoutes(changes.disabled)},
+		{"delete", formatRoutes(changes.deleted)},
+	} {
+		if len(change.routes) > 0 {
+			descriptions = append(descriptions, describeRouteChange(change.action, change.routes))
+		}
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+//computeRouteChanges keeps the user routes with the same destination and gateway as a wanted route and only fixes their state,
+//the routes to a wanted destination through another gateway are replaced, the others are deleted and the missing wanted routes are created
+func computeRouteChanges(current []*vpc.Route, wanted []routeEntry) routeChanges {
+	changes := routeChanges{}
+	kept := make(map[string]bool)
+	unmatched := []*vpc.Route{}
+	for _, route := range current {
+		entry := newRouteEntry(route)
+		matched := false
+		for _, wantedEntry := range wanted {
+			if !kept[wantedEntry.destinationCidr] && wantedEntry.sameTarget(entry) {
+				matched = true
+				kept[wantedEntry.destinationCidr] = true
+				changes.changeState(route, entry.enabled, wantedEntry.enabled)
+				break
+			}
+		}
+		if !matched {
+			unmatched = append(unmatched, route)
+		}
+	}
+	for _, route := range unmatched {
+		entry := newRouteEntry(route)
+		replaced := false
+		for _, wantedEntry := range wanted {
+			if !kept[wantedEntry.destinationCidr] && wantedEntry.destinationCidr == entry.destinationCidr {
+				replaced = true
+				kept[wantedEntry.destinationCidr] = true
+				changes.replaced = append(changes.replaced, routeReplacement{route: route, entry: wantedEntry})
+				changes.changeState(route, entry.enabled, wantedEntry.enabled)
+				break
+			}
+		}
+		if !replaced {
+			changes.deleted = append(changes.deleted, route)
+		}
+	}
+	for _, wantedEntry := range wanted {
+		if !kept[wantedEntry.destinationCidr] {
+			changes.created = append(changes.created, wantedEntry)
+		}
+	}
+	return changes
+}
+
+func getRouteIds(routes []*vpc.Route) []*uint64 {
+	routeIds := []*uint64{}
+	for _, route := range routes {
+		routeIds = append(routeIds, route.RouteId)
+	}
+	return routeIds
+}
+
+//applyRouteChanges moves the destinations to their new gateways in place with ReplaceRoutes and deletes the stale routes last,
+//the routes created disabled are disabled after they are created.
+//qcloud can't change the routes in one call, the error tells which changes are already applied if it fails halfway
+func applyRouteChanges(client VpcAPI, routeTableId string, changes routeChanges) (string, error) {
+	requestId := ""
+	applied := []string{}
+	fail := func(change string, err error) (string, error) {
+		if len(applied) == 0 {
+			return "", err
+		}
+		return "", fmt.Errorf("route table[%s] is partly changed, %s applied but %s failed, err=%v", routeTableId, strings.Join(applied, ", "), change, err)
+	}
+
+	if len(changes.replaced) > 0 {
+		request := vpc.NewReplaceRoutesRequest()
+		request.RouteTableId = &routeTableId
+		replaced := []string{}
+		for _, replacement := range changes.replaced {
+			request.Routes = append(request.Routes, &vpc.Route{
+				RouteId:              replacement.route.RouteId,
+				DestinationCidrBlock: common.StringPtr(replacement.entry.destinationCidr),
+				GatewayType:          common.StringPtr(replacement.entry.gatewayType),
+				GatewayId:            common.StringPtr(replacement.entry.gatewayId),
+			})
+			replaced = append(replaced, replacement.String())
+		}
+		change := describeRouteChange("replace", replaced)
+		response, err := client.ReplaceRoutes(request)
+		if err != nil {
+			return fail(change, err)
+		}
+		requestId = *response.Response.RequestId
+		applied = append(applied, change)
+	}
+
+	if len(changes.created) > 0 {
+		request := vpc.NewCreateRoutesRequest()
+		request.RouteTableId = &routeTableId
+		created := []string{}
+		for _, entry := range changes.created {
+			request.Routes = append(request.Routes, &vpc.Route{
+				DestinationCidrBlock: common.StringPtr(entry.destinationCidr),
+				GatewayType:          common.StringPtr(entry.gatewayType),
+				GatewayId:            common.StringPtr(entry.gatewayId),
+			})
+			created = append(created, entry.String())
+		}
+		change := describeRouteChange("create", created)
+		response, err := client.CreateRoutes(request)
+		if err != nil {
+			return fail(change, err)
+		}
+		requestId = *response.Response.RequestId
+		applied = append(applied, change)
+
+		for _, entry := range changes.created {
+			if entry.enabled {
+				continue
+			}
+			for _, routeTable := range response.Response.RouteTableSet {
+				for _, route := range routeTable.RouteSet {
+					if stringValue(route.DestinationCidrBlock) == entry.destinationCidr {
+						changes.disabled = append(changes.disabled, route)
+					}
+				}
+			}
+		}
+	}
+
+	if len(changes.enabled) > 0 {
+		change := describeRouteChange("enable", formatRoutes(changes.enabled))
+		request := vpc.NewEnableRoutesRequest()
+		request.RouteTableId = &routeTableId
+		request.RouteIds = getRouteIds(changes.enabled)
+		response, err := client.EnableRoutes(request)
+		if err != nil {
+			return fail(change, err)
+		}
+		requestId = *response.Response.RequestId
+		applied = append(applied, change)
+	}
+
+	if len(changes.disabled) > 0 {
+		change := describeRouteChange("disable", formatRoutes(changes.disabled))
+		request := vpc.NewDisableRoutesRequest()
+		request.RouteTableId = &routeTableId
+		request.RouteIds = getRouteIds(changes.disabled)
+		response, err := client.DisableRoutes(request)
+		if err != nil {
+			return fail(change, err)
+		}
+		requestId = *response.Response.RequestId
+		applied = append(applied, change)
+	}
+
+	if len(changes.deleted) > 0 {
+		change := describeRouteChange("delete", formatRoutes(changes.deleted))
+		request := vpc.NewDeleteRoutesRequest()
+		request.RouteTableId = &routeTableId
+		for _, route := range changes.deleted {
+			request.Routes = append(request.Routes, &vpc.Route{RouteId: route.RouteId})
+		}
+		response, err := client.DeleteRoutes(request)
+		if err != nil {
+			return fail(change, err)
+		}
+		requestId = *response.Response.RequestId
+	}
+	return requestId, nil
+}
+
+func replaceRouteTableRoutes(input RouteTableInput) (RouteTableOutput, error) {
+	client, err := createRouteTableClientByProviderParams(input.ProviderParams)
+	if err != nil {
+		return RouteTableOutput{}, err
+	}
+
+	wanted, _ := parseRouteEntries(input.Routes)
+	routeTable, err := getRouteTableById(client, input.Id)
+	if err != nil {
+		return RouteTableOutput{}, err
+	}
+	changes := computeRouteChanges(getUserRoutes(routeTable), wanted)
+	if changes.isEmpty() {
+		logrus.Infof("route table[%s] already has the routes", input.Id)
+		return newRouteTableOutput(input.Guid, "", routeTable), nil
+	}
+
+	logrus.Infof("replace routes of route table[%s]: %s", input.Id, changes)
+	requestId, err := applyRouteChanges(client, input.Id, changes)
+	if err != nil {
+		return RouteTableOutput{}, err
+	}
+	if routeTable, err = getRouteTableById(client, input.Id); err != nil {
+		return RouteTableOutput{}, err
+	}
+	return newRouteTableOutput(input.Guid, requestId, routeTable), nil
+}
+
+func (action *RouteTableReplaceRoutesAction) Do(input interface{}) (interface{}, error) {
+	routeTables, _ := input.(RouteTableInputs)
+	outputs := RouteTableOutputs{}
+	for _, input := range routeTables.Inputs {
+		output, err := replaceRouteTableRoutes(input)
+		if err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+func (action *RouteTableReplaceRoutesAction) DryRun(input interface{}) (interface{}, error) {
+	routeTables, _ := input.(RouteTableInputs)
+	outputs := DryRunOutputs{}
+	for _, input := range routeTables.Inputs {
+		client, err := createRouteTableClientByProviderParams(input.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		routeTable, exist, err := queryRouteTableById(client, input.Id)
+		if err != nil {
+			return nil, err
+		}
+		output := newDryRunOutput(input.Guid, input.Id, exist, DRY_RUN_OPERATION_NONE, "")
+		if !exist {
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("route table[%s] not found", input.Id))
+			outputs.Outputs = append(outputs.Outputs, output)
+			continue
+		}
+
+		wanted, _ := parseRouteEntries(input.Routes)
+		if changes := computeRouteChanges(getUserRoutes(routeTable), wanted); changes.isEmpty() {
+			output.Detail = "route table already has the routes"
+		} else {
+			output.Operation = DRY_RUN_OPERATION_MODIFY
+			output.Detail = changes.String()
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
@@ -0,0 +1,77 @@
+package plugins
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	vpc "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/vpc/v20170312"
+)
+
+func TestParseRouteEntries(t *testing.T) {
+	cases := []struct {
+		routes  string
+		want    string
+		wantErr string
+	}{
+		{"", "", ""},
+		{" 192.168.0.0/24, normal_cvm, 10.0.1.10 ;", "192.168.0.0/24,NORMAL_CVM,10.0.1.10", ""},
+		{"192.168.0.0/24,NAT,nat-1,enabled;172.16.0.0/16,PEERCONNECTION,pcx-1,disabled", "192.168.0.0/24,NAT,nat-1;172.16.0.0/16,PEERCONNECTION,pcx-1,disabled", ""},
+		{"192.168.0.0/24,NAT", "", "invalid route(192.168.0.0/24,NAT)"},
+		{"192.168.0.0,NAT,nat-1", "", "invalid route(192.168.0.0,NAT,nat-1) dest_cidr"},
+		{"192.168.0.0/24,LOCAL,local", "", "invalid gatewayType LOCAL"},
+		{"192.168.0.0/24,NAT,nat-1,off", "", "state, should be enabled or disabled"},
+		{"192.168.0.0/24,NAT,nat-1;192.168.0.0/24,NAT,nat-2", "", "route to 192.168.0.0/24 is duplicated"},
+	}
+	for _, c := range cases {
+		entries, err := parseRouteEntries(c.routes)
+		if c.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("parseRouteEntries(%s) err=%v,want %s", c.routes, err, c.wantErr)
+			}
+			continue
+		}
+		if got := formatRouteEntries(entries); err != nil || got != c.want {
+			t.Errorf("parseRouteEntries(%s)=%s,%v,want %s", c.routes, got, err, c.want)
+		}
+	}
+}
+
+func TestComputeRouteChanges(t *testing.T) {
+	newRoute := func(routeId uint64, route string) *vpc.Route {
+		entries, _ := parseRouteEntries(route)
+		return &vpc.Route{
+			RouteId:              common.Uint64Ptr(routeId),
+			DestinationCidrBlock: common.StringPtr(entries[0].destinationCidr),
+			GatewayType:          common.StringPtr(entries[0].gatewayType),
+			GatewayId:            common.StringPtr(entries[0].gatewayId),
+			Enabled:              common.BoolPtr(entries[0].enabled),
+		}
+	}
+	current := []*vpc.Route{
+		newRoute(1, "192.168.0.0/24,NORMAL_CVM,10.0.1.10"),
+		newRoute(2, "172.16.0.0/16,PEERCONNECTION,pcx-1,disabled"),
+		newRoute(3, "10.1.0.0/16,NAT,nat-1"),
+	}
+	cases := []struct {
+		routes string
+		want   string
+	}{
+		{"192.168.0.0/24,NORMAL_CVM,10.0.1.10;172.16.0.0/16,PEERCONNECTION,pcx-1,disabled;10.1.0.0/16,NAT,nat-1", ""},
+		{"", "delete 3 routes[192.168.0.0/24,NORMAL_CVM,10.0.1.10;172.16.0.0/16,PEERCONNECTION,pcx-1,disabled;10.1.0.0/16,NAT,nat-1]"},
+		{"192.168.0.0/24,NORMAL_CVM,10.0.1.11;172.16.0.0/16,PEERCONNECTION,pcx-1;10.1.0.0/16,NAT,nat-1,disabled;10.2.0.0/16,NAT,nat-1",
+			"replace 1 routes[192.168.0.0/24,NORMAL_CVM,10.0.1.10->NORMAL_CVM,10.0.1.11], create 1 routes[10.2.0.0/16,NAT,nat-1], " +
+				"enable 1 routes[172.16.0.0/16,PEERCONNECTION,pcx-1,disabled], disable 1 routes[10.1.0.0/16,NAT,nat-1]"},
+		{"172.16.0.0/16,NAT,nat-2;10.2.0.0/16,NAT,nat-1",
+			"replace 1 routes[172.16.0.0/16,PEERCONNECTION,pcx-1,disabled->NAT,nat-2], create 1 routes[10.2.0.0/16,NAT,nat-1], " +
+				"enable 1 routes[172.16.0.0/16,PEERCONNECTION,pcx-1,disabled], delete 2 routes[192.168.0.0/24,NORMAL_CVM,10.0.1.10;10.1.0.0/16,NAT,nat-1]"},
+	}
+	for _, c := range cases {
+		wanted, _ := parseRouteEntries(c.routes)
+		changes := computeRouteChanges(current, wanted)
+		if got := fmt.Sprint(changes); got != c.want || changes.isEmpty() != (c.want == "") {
+			t.Errorf("computeRouteChanges(%s)=%s,want %s", c.routes, got, c.want)
+		}
+	}
+}
@@ -5,12 +5,13 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
-	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
 	vpc "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/vpc/v20170312"
 )
 
 const (
 	QCLOUD_ENDPOINT_VPC = "vpc.tencentcloudapi.com"
+
+	MAX_SECURITY_GROUP_POLICY_NUM = 100
 )
 
 type SecurityGroupPlugin struct{}
@@ -32,17 +33,8 @@ func (plugin *SecurityGroupPlugin) GetActionByName(actionName string) (Action, e
 	return action, nil
 }
 
-func createVpcClient(region, secretId, secretKey string) (client *vpc.Client, err error) {
-	credential := common.NewCredential(secretId, secretKey)
-
-	clientProfile := profile.NewClientProfile()
-	clientProfile.HttpProfile.Endpoint = QCLOUD_ENDPOINT_VPC
-
-	client, err = vpc.NewClient(credential, region, clientProfile)
-	if err != nil {
-		logrus.Errorf("Create Qcloud vm client failed,err=%v", err)
-	}
-	return
+func createVpcClient(region, secretId, secretKey string) (VpcAPI, error) {
+	return QcloudClientFactory.NewVpcClient(region, secretId, secretKey)
 }
 
 type SecurityGroupInputs struct {
@@ -55,6 +47,7 @@ type SecurityGroupInput struct {
 	Name           string `json:"name,omitempty"`
 	Id             string `json:"id,omitempty"`
 	Description    string `json:"description,omitempty"`
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
 }
 
 type SecurityGroupOutputs struct {
@@ -102,6 +95,7 @@ type SecurityGroupParam struct {
 	GroupDescription       string
 	SecurityGroupId        string
 	SecurityGroupPolicySet *vpc.SecurityGroupPolicySet `json:"SecurityGroupPolicySet"`
+	IdempotencyKey         string
 }
 
 type SecurityGroupCreation struct{}
@@ -134,45 +128,89 @@ func (action *SecurityGroupCreation) Do(input interface{}) (interface{}, error)
 	}
 
 	for _, securityGroup := range SecurityGroups {
-		paramsMap, err := GetMapFromProviderParams(securityGroup.ProviderParams)
-		client, err := createVpcClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+		output := SecurityGroupOutput{}
+		idempotencyKey := getIdempotencyKey("security-group-create", securityGroup.Guid, securityGroup.IdempotencyKey, securityGroup)
+		err := runWithIdempotencyKey(idempotencyKey, &output, func() (interface{}, error) {
+			return createSecurityGroup(&securityGroup, idempotencyKey)
+		})
 		if err != nil {
 			return outputs, err
 		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
 
-		//check resource exsit
-		if securityGroup.SecurityGroupId != "" {
-			querySecurityGroupResponse, flag, err := querySecurityGroupsInfo(client, &securityGroup)
-			if err != nil && flag == false {
-				return outputs, err
-			}
+	return outputs, nil
+}
 
-			if err == nil && flag == true {
-				outputs.Outputs = append(outputs.Outputs, querySecurityGroupResponse)
-				continue
-			}
+func createSecurityGroup(securityGroup *SecurityGroupParam, idempotencyKey string) (SecurityGroupOutput, error) {
+	paramsMap, err := GetMapFromProviderParams(securityGroup.ProviderParams)
+	client, err := createVpcClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+	if err != nil {
+		return SecurityGroupOutput{}, err
+	}
+
+	//check resource exsit
+	if securityGroup.SecurityGroupId, err = getIdempotencyCreatedId(idempotencyKey, securityGroup.SecurityGroupId); err != nil {
+		return SecurityGroupOutput{}, err
+	}
+	if securityGroup.SecurityGroupId != "" {
+		querySecurityGroupResponse, flag, err := querySecurityGroupsInfo(client, securityGroup)
+		if err != nil && flag == false {
+			return SecurityGroupOutput{}, err
+		}
+
+		if err == nil && flag == true {
+			return querySecurityGroupResponse, nil
 		}
+	}
+
+	createSecurityGroup := vpc.NewCreateSecurityGroupRequest()
+	createSecurityGroup.GroupName = common.StringPtr(securityGroup.GroupName)
+	createSecurityGroup.GroupDescription = common.StringPtr(securityGroup.GroupDescription)
 
-		createSecurityGroup := vpc.NewCreateSecurityGroupRequest()
-		createSecurityGroup.GroupName = common.StringPtr(securityGroup.GroupName)
-		createSecurityGroup.GroupDescription = common.StringPtr(securityGroup.GroupDescription)
+	createSecurityGroupresp, err := client.CreateSecurityGroup(createSecurityGroup)
+	if err != nil {
+		return SecurityGroupOutput{}, err
+	}
+	saveIdempotencyCreatedIds(idempotencyKey, *createSecurityGroupresp.Response.RequestId, *createSecurityGroupresp.Response.SecurityGroup.SecurityGroupId)
+	output := SecurityGroupOutput{
+		Id:        *createSecurityGroupresp.Response.SecurityGroup.SecurityGroupId,
+		RequestId: *createSecurityGroupresp.Response.RequestId,
+		Guid:      securityGroup.Guid,
+	}
+
+	securityGroup.SecurityGroupId = *createSecurityGroupresp.Response.SecurityGroup.SecurityGroupId
+	logrus.Infof("create SecurityGroup's request has been submitted, SecurityGroupId is [%v], RequestID is [%v]", securityGroup.SecurityGroupId, *createSecurityGroupresp.Response.RequestId)
+	return output, nil
+}
+
+func (action *SecurityGroupCreation) DryRun(input interface{}) (interface{}, error) {
+	securityGroups, _ := input.(SecurityGroupInputs)
+	outputs := DryRunOutputs{}
+
+	SecurityGroups, err := checkSecurityGroup(securityGroups.Inputs)
+	if err != nil {
+		return outputs, err
+	}
 
-		createSecurityGroupresp, err := client.CreateSecurityGroup(createSecurityGroup)
+	for _, securityGroup := range SecurityGroups {
+		paramsMap, _ := GetMapFromProviderParams(securityGroup.ProviderParams)
+		client, err := createVpcClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
 		if err != nil {
 			return outputs, err
 		}
-		output := SecurityGroupOutput{
-			Id:        *createSecurityGroupresp.Response.SecurityGroup.SecurityGroupId,
-			RequestId: *createSecurityGroupresp.Response.RequestId,
-			Guid:      securityGroup.Guid,
-		}
 
-		securityGroup.SecurityGroupId = *createSecurityGroupresp.Response.SecurityGroup.SecurityGroupId
-		logrus.Infof("create SecurityGroup's request has been submitted, SecurityGroupId is [%v], RequestID is [%v]", securityGroup.SecurityGroupId, *createSecurityGroupresp.Response.RequestId)
-		outputs.Outputs = append(outputs.Outputs, output)
+		exist := false
+		if securityGroup.SecurityGroupId != "" {
+			if _, exist, err = querySecurityGroupsInfo(client, &securityGroup); err != nil {
+				return outputs, err
+			}
+		}
+		detail := fmt.Sprintf("create security group name=%s", securityGroup.GroupName)
+		outputs.Outputs = append(outputs.Outputs, newCreateDryRunOutput(securityGroup.Guid, securityGroup.SecurityGroupId, exist, detail))
 	}
 
-	return outputs, nil
+	return &outputs, nil
 }
 
 func checkSecurityGroup(actionParams []SecurityGroupInput) ([]SecurityGroupParam, error) {
@@ -197,6 +235,7 @@ func buildNewSecurityGroup(actionParam SecurityGroupInput) (SecurityGroupParam,
 		GroupName:        actionParam.Name,
 		SecurityGroupId:  actionParam.Id,
 		GroupDescription: actionParam.Description,
+		IdempotencyKey:   actionParam.IdempotencyKey,
 		SecurityGroupPolicySet: &vpc.SecurityGroupPolicySet{
 			Egress:  []*vpc.SecurityGroupPolicy{},
 			Ingress: []*vpc.SecurityGroupPolicy{},
@@ -283,6 +322,30 @@ func (action *SecurityGroupTermination) Do(input interface{}) (interface{}, erro
 	return &outputs, nil
 }
 
+func (action *SecurityGroupTermination) DryRun(input interface{}) (interface{}, error) {
+	securityGroups, _ := input.(SecurityGroupInputs)
+	outputs := DryRunOutputs{}
+
+	for _, securityGroup := range securityGroups.Inputs {
+		paramsMap, err := GetMapFromProviderParams(securityGroup.ProviderParams)
+		if err != nil {
+			return outputs, err
+		}
+		client, err := createVpcClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+		if err != nil {
+			return outputs, err
+		}
+
+		_, exist, err := querySecurityGroupsInfo(client, &SecurityGroupParam{SecurityGroupId: securityGroup.Id})
+		if err != nil {
+			return outputs, err
+		}
+		outputs.Outputs = append(outputs.Outputs, newDeleteDryRunOutput(securityGroup.Guid, securityGroup.Id, exist, "delete security group"))
+	}
+
+	return &outputs, nil
+}
+
 type SecurityGroupCreatePolicies struct {
 }
 
@@ -330,6 +393,58 @@ func (action *SecurityGroupCreatePolicies) Do(input interface{}) (interface{}, e
 	return outputs, nil
 }
 
+//dryRunSecurityGroupPolicies reports the policies to be changed, adding policies may conflict with the quota of one security group
+func dryRunSecurityGroupPolicies(securityGroupPolicies SecurityGroupPolicyInputs, operation string) (*DryRunOutputs, error) {
+	outputs := DryRunOutputs{}
+	securityGroups, err := checkSecurityGroupPolicy(securityGroupPolicies.Inputs)
+	if err != nil {
+		return &outputs, err
+	}
+
+	for _, securityGroup := range securityGroups {
+		paramsMap, _ := GetMapFromProviderParams(securityGroup.ProviderParams)
+		client, err := createVpcClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+		if err != nil {
+			return &outputs, err
+		}
+
+		ingressNum := len(securityGroup.SecurityGroupPolicySet.Ingress)
+		egressNum := len(securityGroup.SecurityGroupPolicySet.Egress)
+		output := newDryRunOutput(securityGroup.Guid, securityGroup.SecurityGroupId, false, operation,
+			fmt.Sprintf("%s %d ingress and %d egress policies", operation, ingressNum, egressNum))
+		if _, output.Exist, err = querySecurityGroupsInfo(client, &securityGroup); err != nil {
+			return &outputs, err
+		}
+		if !output.Exist {
+			output.Operation = DRY_RUN_OPERATION_NONE
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("security group[%s] not found", securityGroup.SecurityGroupId))
+			outputs.Outputs = append(outputs.Outputs, output)
+			continue
+		}
+
+		if operation == DRY_RUN_OPERATION_CREATE {
+			policySet, err := QuerySecurityGroupPolicies(securityGroup.ProviderParams, securityGroup.SecurityGroupId)
+			if err != nil {
+				return &outputs, err
+			}
+			if freeNum := MAX_SECURITY_GROUP_POLICY_NUM - len(policySet.Ingress); ingressNum > freeNum {
+				output.Conflicts = append(output.Conflicts, fmt.Sprintf("ingress policies quota exceeded, free=%d,want=%d", freeNum, ingressNum))
+			}
+			if freeNum := MAX_SECURITY_GROUP_POLICY_NUM - len(policySet.Egress); egressNum > freeNum {
+				output.Conflicts = append(output.Conflicts, fmt.Sprintf("egress policies quota exceeded, free=%d,want=%d", freeNum, egressNum))
+			}
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+
+	return &outputs, nil
+}
+
+func (action *SecurityGroupCreatePolicies) DryRun(input interface{}) (interface{}, error) {
+	securityGroupPolicies, _ := input.(SecurityGroupPolicyInputs)
+	return dryRunSecurityGroupPolicies(securityGroupPolicies, DRY_RUN_OPERATION_CREATE)
+}
+
 func checkSecurityGroupPolicy(actionParams []SecurityGroupPolicyInput) ([]SecurityGroupParam, error) {
 	securityGroups := []SecurityGroupParam{}
 
@@ -423,12 +538,15 @@ func updateSecurityGroupPolicies(securityGroup *SecurityGroupParam, securityGrou
 	return securityGroups, fmt.Errorf("not exist SecurityGroupParam[%v]", &securityGroup)
 }
 
-func createSecurityGroupPolicies(client *vpc.Client, input *SecurityGroupParam) (interface{}, error) {
+func createSecurityGroupPolicies(client VpcAPI, input *SecurityGroupParam) (interface{}, error) {
 	//check resource exsit
 	if input.SecurityGroupId != "" {
-		querySecurityGroupResponse, flag, err := querySecurityGroupsInfo(client, input)
+		_, flag, err := querySecurityGroupsInfo(client, input)
+		if err != nil {
+			return nil, err
+		}
 		if flag == false {
-			return querySecurityGroupResponse, err
+			return nil, fmt.Errorf("security group(%s) not found", input.SecurityGroupId)
 		}
 	}
 
@@ -509,12 +627,20 @@ func (action *SecurityGroupDeletePolicies) Do(input interface{}) (interface{}, e
 
 }
 
-func deleteSecurityGroupPolicies(client *vpc.Client, input *SecurityGroupParam) (interface{}, error) {
+func (action *SecurityGroupDeletePolicies) DryRun(input interface{}) (interface{}, error) {
+	securityGroupPolicies, _ := input.(SecurityGroupPolicyInputs)
+	return dryRunSecurityGroupPolicies(securityGroupPolicies, DRY_RUN_OPERATION_DELETE)
+}
+
+func deleteSecurityGroupPolicies(client VpcAPI, input *SecurityGroupParam) (interface{}, error) {
 	//check resource exsit
 	if input.SecurityGroupId != "" {
-		querySecurityGroupResponse, flag, err := querySecurityGroupsInfo(client, input)
+		_, flag, err := querySecurityGroupsInfo(client, input)
+		if err != nil {
+			return nil, err
+		}
 		if flag == false {
-			return querySecurityGroupResponse, err
+			return nil, fmt.Errorf("security group(%s) not found", input.SecurityGroupId)
 		}
 	}
 	deletePolicies := vpc.NewDeleteSecurityGroupPoliciesRequest()
@@ -538,7 +664,7 @@ func deleteSecurityGroupPolicies(client *vpc.Client, input *SecurityGroupParam)
 	return output, nil
 }
 
-func querySecurityGroupsInfo(client *vpc.Client, input *SecurityGroupParam) (SecurityGroupOutput, bool, error) {
+func querySecurityGroupsInfo(client VpcAPI, input *SecurityGroupParam) (SecurityGroupOutput, bool, error) {
 	output := SecurityGroupOutput{}
 
 	request := vpc.NewDescribeSecurityGroupsRequest()
@@ -0,0 +1,1022 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	cbs "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cbs/v20170312"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+)
+
+const (
+	SNAPSHOT_STATE_NORMAL      = "NORMAL"
+	SNAPSHOT_STATE_CREATING    = "CREATING"
+	SNAPSHOT_STATE_ROLLBACKING = "ROLLBACKING"
+
+	//the most snapshots or policies qcloud returns in one describe call
+	SNAPSHOT_DESCRIBE_PAGE_SIZE = 100
+	//a snapshot copies the whole disk, it takes much longer than changing a disk
+	SNAPSHOT_WAIT_TIMEOUT = 1800
+	//a rollback not seen running by then is taken as done once its progress reaches 100
+	SNAPSHOT_ROLLBACK_START_TIMEOUT = 30
+)
+
+var SnapshotActions = make(map[string]Action)
+
+func init() {
+	SnapshotActions["create"] = new(SnapshotCreateAction)
+	SnapshotActions["terminate"] = new(SnapshotTerminateAction)
+	SnapshotActions["describe"] = new(SnapshotDescribeAction)
+	SnapshotActions["rollback"] = new(SnapshotRollbackAction)
+	SnapshotActions["create-policy"] = new(SnapshotPolicyCreateAction)
+	SnapshotActions["terminate-policy"] = new(SnapshotPolicyTerminateAction)
+	SnapshotActions["bind-policy"] = new(SnapshotPolicyBindAction)
+	SnapshotActions["unbind-policy"] = new(SnapshotPolicyUnbindAction)
+}
+
+type SnapshotInputs struct {
+	Inputs []SnapshotInput `json:"inputs,omitempty"`
+}
+
+type SnapshotInput struct {
+	Guid           string `json:"guid,omitempty"`
+	ProviderParams string `json:"provider_params,omitempty"`
+	Id             string `json:"id,omitempty"`
+	Name           string `json:"name,omitempty"`
+	DiskId         string `json:"disk_id,omitempty"`
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
+type SnapshotOutputs struct {
+	Outputs []SnapshotOutput `json:"outputs,omitempty"`
+}
+
+type SnapshotOutput struct {
+	Guid        string `json:"guid,omitempty"`
+	RequestId   string `json:"request_id,omitempty"`
+	Id          string `json:"id,omitempty"`
+	Name        string `json:"name,omitempty"`
+	DiskId      string `json:"disk_id,omitempty"`
+	DiskSize    string `json:"disk_size,omitempty"`
+	DiskUsage   string `json:"disk_usage,omitempty"`
+	State       string `json:"state,omitempty"`
+	CreatedTime string `json:"created_time,omitempty"`
+}
+
+type SnapshotPolicyInputs struct {
+	Inputs []SnapshotPolicyInput `json:"inputs,omitempty"`
+}
+
+//a snapshot is taken at each hour of each day of week, 0 is sunday
+type SnapshotPolicyInput struct {
+	Guid           string `json:"guid,omitempty"`
+	ProviderParams string `json:"provider_params,omitempty"`
+	Id             string `json:"id,omitempty"`
+	Name           string `json:"name,omitempty"`
+	DayOfWeek      string `json:"day_of_week,omitempty"`
+	Hour           string `json:"hour,omitempty"`
+	RetentionDays  uint64 `json:"retention_days,omitempty"`
+	IsActivated    string `json:"is_activated,omitempty"`
+	DiskIds        string `json:"disk_ids,omitempty"`
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
+type SnapshotPolicyOutputs struct {
+	Outputs []SnapshotPolicyOutput `json:"outputs,omitempty"`
+}
+
+type SnapshotPolicyOutput struct {
+	Guid            string `json:"guid,omitempty"`
+	RequestId       string `json:"request_id,omitempty"`
+	Id              string `json:"id,omitempty"`
+	Name            string `json:"name,omitempty"`
+	State           string `json:"state,omitempty"`
+	IsActivated     string `json:"is_activated,omitempty"`
+	DayOfWeek       string `json:"day_of_week,omitempty"`
+	Hour            string `json:"hour,omitempty"`
+	RetentionDays   string `json:"retention_days,omitempty"`
+	DiskIds         string `json:"disk_ids,omitempty"`
+	NextTriggerTime string `json:"next_trigger_time,omitempty"`
+	CreatedTime     string `json:"created_time,omitempty"`
+}
+
+type SnapshotPlugin struct{}
+
+func (plugin *SnapshotPlugin) GetActionByName(actionName string) (Action, error) {
+	action, found := SnapshotActions[actionName]
+	if !found {
+		return nil, fmt.Errorf("snapshot plugin,action[%s] not found", actionName)
+	}
+	return action, nil
+}
+
+type SnapshotAction struct {
+}
+
+func (action *SnapshotAction) ReadParam(param interface{}) (interface{}, error) {
+	var inputs SnapshotInputs
+	err := UnmarshalJson(param, &inputs)
+	if err != nil {
+		return nil, err
+	}
+	return inputs, nil
+}
+
+func (action *SnapshotAction) CheckParam(input interface{}) error {
+	snapshots, ok := input.(SnapshotInputs)
+	if !ok {
+		return INVALID_PARAMETERS
+	}
+
+	for _, snapshot := range snapshots.Inputs {
+		if snapshot.Id == "" {
+			return errors.New("input id is empty")
+		}
+	}
+	return nil
+}
+
+//describeAllSnapshots pages over DescribeSnapshots until all the snapshots matching the request are returned
+func describeAllSnapshots(client CbsAPI, request *cbs.DescribeSnapshotsRequest) ([]*cbs.Snapshot, string, error) {
+	request.Limit = common.Uint64Ptr(SNAPSHOT_DESCRIBE_PAGE_SIZE)
+
+	snapshots := []*cbs.Snapshot{}
+	requestId := ""
+	for offset := uint64(0); ; {
+		request.Offset = common.Uint64Ptr(offset)
+		response, err := client.DescribeSnapshots(request)
+		if err != nil {
+			logrus.Errorf("cbs DescribeSnapshots meet err=%v", err)
+			return nil, "", err
+		}
+
+		requestId = *response.Response.RequestId
+		snapshots = append(snapshots, response.Response.SnapshotSet...)
+		offset += uint64(len(response.Response.SnapshotSet))
+		if len(response.Response.SnapshotSet) == 0 || offset >= *response.Response.TotalCount {
+			break
+		}
+	}
+	return snapshots, requestId, nil
+}
+
+//querySnapshotById returns false if the snapshot is not found
+func querySnapshotById(client CbsAPI, snapshotId string) (*cbs.Snapshot, bool, error) {
+	request := cbs.NewDescribeSnapshotsRequest()
+	request.SnapshotIds = []*string{&snapshotId}
+	snapshots, _, err := describeAllSnapshots(client, request)
+	if err != nil || len(snapshots) == 0 {
+		return nil, false, err
+	}
+	return snapshots[0], true, nil
+}
+
+//waitSnapshotUntil polls the snapshot until it meets the condition, the condition gets nil if the snapshot is not found
+func waitSnapshotUntil(client CbsAPI, snapshotId string, condition func(snapshot *cbs.Snapshot) bool) (*cbs.Snapshot, error) {
+	count := 0
+	for {
+		time.Sleep(waitPollInterval)
+		snapshot, _, err := querySnapshotById(client, snapshotId)
+		if err != nil {
+			return nil, err
+		}
+		if condition(snapshot) {
+			return snapshot, nil
+		}
+
+		count++
+		if count*WAIT_POLL_SECONDS > SNAPSHOT_WAIT_TIMEOUT {
+			return nil, fmt.Errorf("qcloud wait snapshot[%s] timeout", snapshotId)
+		}
+	}
+}
+
+//waitSnapshotNormal waits until the snapshot can be used, snapshots gone while waiting are reported at once
+func waitSnapshotNormal(client CbsAPI, snapshotId string) (*cbs.Snapshot, error) {
+	snapshot, err := waitSnapshotUntil(client, snapshotId, func(snapshot *cbs.Snapshot) bool {
+		return snapshot == nil || stringValue(snapshot.SnapshotState) == SNAPSHOT_STATE_NORMAL
+	})
+	if err == nil && snapshot == nil {
+		return nil, fmt.Errorf("snapshot[%s] not found", snapshotId)
+	}
+	return snapshot, err
+}
+
+func newSnapshotOutput(guid string, requestId string, snapshot *cbs.Snapshot) SnapshotOutput {
+	output := SnapshotOutput{
+		Guid:        guid,
+		RequestId:   requestId,
+		Id:          stringValue(snapshot.SnapshotId),
+		Name:        stringValue(snapshot.SnapshotName),
+		DiskId:      stringValue(snapshot.DiskId),
+		DiskUsage:   stringValue(snapshot.DiskUsage),
+		State:       stringValue(snapshot.SnapshotState),
+		CreatedTime: stringValue(snapshot.CreateTime),
+	}
+	if snapshot.DiskSize != nil {
+		output.DiskSize = strconv.FormatUint(*snapshot.DiskSize, 10)
+	}
+	return output
+}
+
+type SnapshotCreateAction struct {
+	SnapshotAction
+}
+
+func (action *SnapshotCreateAction) CheckParam(input interface{}) error {
+	snapshots, ok := input.(SnapshotInputs)
+	if !ok {
+		return INVALID_PARAMETERS
+	}
+
+	for _, snapshot := range snapshots.Inputs {
+		if snapshot.DiskId == "" {
+			return errors.New("input disk_id is empty")
+		}
+	}
+	return nil
+}
+
+func (action *SnapshotCreateAction) Do(input interface{}) (interface{}, error) {
+	snapshots, _ := input.(SnapshotInputs)
+	outputs := SnapshotOutputs{}
+	for _, snapshot := range snapshots.Inputs {
+		output := SnapshotOutput{}
+		idempotencyKey := getIdempotencyKey("snapshot-create", snapshot.Guid, snapshot.IdempotencyKey, snapshot)
+		err := runWithIdempotencyKey(idempotencyKey, &output, func() (interface{}, error) {
+			return createSnapshot(snapshot)
+		})
+		if err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+//createSnapshot returns the snapshot of the input id once it is ready if it exists
+func createSnapshot(snapshot SnapshotInput) (SnapshotOutput, error) {
+	client, err := createStorageClient(snapshot.ProviderParams)
+	if err != nil {
+		return SnapshotOutput{}, err
+	}
+
+	snapshotId := snapshot.Id
+	requestId := ""
+	found := false
+	if snapshotId != "" {
+		if _, found, err = querySnapshotById(client, snapshotId); err != nil {
+			return SnapshotOutput{}, err
+		}
+	}
+	if !found {
+		request := cbs.NewCreateSnapshotRequest()
+		request.DiskId = &snapshot.DiskId
+		if snapshot.Name != "" {
+			request.SnapshotName = &snapshot.Name
+		}
+		response, err := client.CreateSnapshot(request)
+		if err != nil {
+			return SnapshotOutput{}, err
+		}
+		logrus.Infof("Create snapshot of disk[%v] has been submitted, Id is [%v], RequestID is [%v]", snapshot.DiskId, *response.Response.SnapshotId, *response.Response.RequestId)
+		snapshotId = *response.Response.SnapshotId
+		requestId = *response.Response.RequestId
+	}
+
+	created, err := waitSnapshotNormal(client, snapshotId)
+	if err != nil {
+		return SnapshotOutput{}, err
+	}
+	return newSnapshotOutput(snapshot.Guid, requestId, created), nil
+}
+
+func (action *SnapshotCreateAction) DryRun(input interface{}) (interface{}, error) {
+	snapshots, _ := input.(SnapshotInputs)
+	outputs := DryRunOutputs{}
+	for _, snapshot := range snapshots.Inputs {
+		client, err := createStorageClient(snapshot.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		exist := false
+		if snapshot.Id != "" {
+			if _, exist, err = querySnapshotById(client, snapshot.Id); err != nil {
+				return nil, err
+			}
+		}
+		if !exist {
+			if exist, err = isIdempotencyKeyDone(getIdempotencyKey("snapshot-create", snapshot.Guid, snapshot.IdempotencyKey, snapshot)); err != nil {
+				return nil, err
+			}
+		}
+		output := newCreateDryRunOutput(snapshot.Guid, snapshot.Id, exist, fmt.Sprintf("create snapshot named %s of disk[%s]", snapshot.Name, snapshot.DiskId))
+		if !exist {
+			if _, found, err := queryStorageById(client, snapshot.DiskId); err != nil {
+				return nil, err
+			} else if !found {
+				output.Conflicts = append(output.Conflicts, fmt.Sprintf("storage[%s] not found", snapshot.DiskId))
+			}
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+type SnapshotTerminateAction struct {
+	SnapshotAction
+}
+
+func (action *SnapshotTerminateAction) Do(input interface{}) (interface{}, error) {
+	snapshots, _ := input.(SnapshotInputs)
+	outputs := SnapshotOutputs{}
+	for _, snapshot := range snapshots.Inputs {
+		client, err := createStorageClient(snapshot.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		output := SnapshotOutput{Guid: snapshot.Guid, Id: snapshot.Id}
+		if _, found, err := querySnapshotById(client, snapshot.Id); err != nil {
+			return nil, err
+		} else if !found {
+			logrus.Infof("snapshot[%s] is already deleted", snapshot.Id)
+			outputs.Outputs = append(outputs.Outputs, output)
+			continue
+		}
+
+		request := cbs.NewDeleteSnapshotsRequest()
+		request.SnapshotIds = []*string{&snapshot.Id}
+		response, err := client.DeleteSnapshots(request)
+		if err != nil {
+			return nil, err
+		}
+		logrus.Infof("Delete snapshot[%v] has been submitted, RequestID is [%v]", snapshot.Id, *response.Response.RequestId)
+
+		if _, err = waitSnapshotUntil(client, snapshot.Id, func(snapshot *cbs.Snapshot) bool { return snapshot == nil }); err != nil {
+			return nil, err
+		}
+		output.RequestId = *response.Response.RequestId
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+func (action *SnapshotTerminateAction) DryRun(input interface{}) (interface{}, error) {
+	snapshots, _ := input.(SnapshotInputs)
+	outputs := DryRunOutputs{}
+	for _, snapshot := range snapshots.Inputs {
+		client, err := createStorageClient(snapshot.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		existed, exist, err := querySnapshotById(client, snapshot.Id)
+		if err != nil {
+			return nil, err
+		}
+		detail := "delete snapshot"
+		if exist {
+			detail = fmt.Sprintf("delete snapshot named %s of disk[%s]", stringValue(existed.SnapshotName), stringValue(existed.DiskId))
+		}
+		outputs.Outputs = append(outputs.Outputs, newDeleteDryRunOutput(snapshot.Guid, snapshot.Id, exist, detail))
+	}
+	return &outputs, nil
+}
+
+type SnapshotDescribeAction struct {
+	SnapshotAction
+}
+
+//the snapshots are looked up by ids, or by disk and name, all the snapshots of the region are returned without them
+func (action *SnapshotDescribeAction) CheckParam(input interface{}) error {
+	snapshots, ok := input.(SnapshotInputs)
+	if !ok {
+		return INVALID_PARAMETERS
+	}
+
+	for _, snapshot := range snapshots.Inputs {
+		if snapshot.Id != "" && (snapshot.DiskId != "" || snapshot.Name != "") {
+			return errors.New("input id can't be used together with disk_id or name")
+		}
+	}
+	return nil
+}
+
+func (action *SnapshotDescribeAction) Do(input interface{}) (interface{}, error) {
+	snapshots, _ := input.(SnapshotInputs)
+	outputs := SnapshotOutputs{}
+	for _, snapshot := range snapshots.Inputs {
+		client, err := createStorageClient(snapshot.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		request := cbs.NewDescribeSnapshotsRequest()
+		if snapshotIds := splitCommaValues(snapshot.Id); len(snapshotIds) > 0 {
+			request.SnapshotIds = common.StringPtrs(snapshotIds)
+		}
+		if snapshot.DiskId != "" {
+			request.Filters = append(request.Filters, newCbsFilter("disk-id", snapshot.DiskId))
+		}
+		if snapshot.Name != "" {
+			request.Filters = append(request.Filters, newCbsFilter("snapshot-name", snapshot.Name))
+		}
+
+		found, requestId, err := describeAllSnapshots(client, request)
+		if err != nil {
+			return nil, err
+		}
+		logrus.Infof("describe snapshots of guid[%s] found %d snapshots", snapshot.Guid, len(found))
+		for _, item := range found {
+			outputs.Outputs = append(outputs.Outputs, newSnapshotOutput(snapshot.Guid, requestId, item))
+		}
+	}
+	return &outputs, nil
+}
+
+func newCbsFilter(name string, values ...string) *cbs.Filter {
+	return &cbs.Filter{Name: common.StringPtr(name), Values: common.StringPtrs(values)}
+}
+
+type SnapshotRollbackAction struct {
+	SnapshotAction
+}
+
+//qcloud rolls back a disk only to its own snapshots, attached disks need their vms stopped
+func (action *SnapshotRollbackAction) CheckParam(input interface{}) error {
+	if err := action.SnapshotAction.CheckParam(input); err != nil {
+		return err
+	}
+
+	snapshots, _ := input.(SnapshotInputs)
+	for _, snapshot := range snapshots.Inputs {
+		if snapshot.DiskId == "" {
+			return errors.New("input disk_id is empty")
+		}
+	}
+	return nil
+}
+
+func (action *SnapshotRollbackAction) Do(input interface{}) (interface{}, error) {
+	snapshots, _ := input.(SnapshotInputs)
+	outputs := SnapshotOutputs{}
+	for _, snapshot := range snapshots.Inputs {
+		client, err := createStorageClient(snapshot.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		existed, found, err := querySnapshotById(client, snapshot.Id)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, fmt.Errorf("snapshot[%s] not found", snapshot.Id)
+		}
+		if state := stringValue(existed.SnapshotState); state != SNAPSHOT_STATE_NORMAL {
+			return nil, fmt.Errorf("snapshot[%s] is %s", snapshot.Id, state)
+		}
+		if diskId := stringValue(existed.DiskId); diskId != snapshot.DiskId {
+			return nil, fmt.Errorf("snapshot[%s] is taken from disk[%s], can't roll back disk[%s]", snapshot.Id, diskId, snapshot.DiskId)
+		}
+
+		request := cbs.NewApplySnapshotRequest()
+		request.SnapshotId = &snapshot.Id
+		request.DiskId = &snapshot.DiskId
+		response, err := client.ApplySnapshot(request)
+		if err != nil {
+			return nil, err
+		}
+		logrus.Infof("Rollback disk[%v] to snapshot[%v] has been submitted, RequestID is [%v]", snapshot.DiskId, snapshot.Id, *response.Response.RequestId)
+
+		if err = waitStorageRollbackDone(client, snapshot.DiskId); err != nil {
+			return nil, err
+		}
+		rolledBack, err := waitSnapshotNormal(client, snapshot.Id)
+		if err != nil {
+			return nil, err
+		}
+		output := newSnapshotOutput(snapshot.Guid, *response.Response.RequestId, rolledBack)
+		output.DiskId = snapshot.DiskId
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+func (action *SnapshotRollbackAction) DryRun(input interface{}) (interface{}, error) {
+	snapshots, _ := input.(SnapshotInputs)
+	outputs := DryRunOutputs{}
+	for _, snapshot := range snapshots.Inputs {
+		client, err := createStorageClient(snapshot.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		existed, found, err := querySnapshotById(client, snapshot.Id)
+		if err != nil {
+			return nil, err
+		}
+		output := newDryRunOutput(snapshot.Guid, snapshot.Id, found, DRY_RUN_OPERATION_MODIFY, fmt.Sprintf("roll back disk[%s] to the snapshot, the data written after it is lost", snapshot.DiskId))
+		if !found {
+			output.Operation = DRY_RUN_OPERATION_NONE
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("snapshot[%s] not found", snapshot.Id))
+		} else {
+			if state := stringValue(existed.SnapshotState); state != SNAPSHOT_STATE_NORMAL {
+				output.Conflicts = append(output.Conflicts, fmt.Sprintf("snapshot is %s", state))
+			}
+			if diskId := stringValue(existed.DiskId); diskId != snapshot.DiskId {
+				output.Conflicts = append(output.Conflicts, fmt.Sprintf("snapshot is taken from disk[%s]", diskId))
+			}
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+//waitStorageRollbackDone waits until the disk starts rolling back and then stops,
+//the disk is not rolling back yet right after ApplySnapshot returns and a quick rollback may finish between two polls
+func waitStorageRollbackDone(client CbsAPI, diskId string) error {
+	started := false
+	polls := 0
+	disk, err := waitStorageUntil(client, diskId, SNAPSHOT_WAIT_TIMEOUT, func(disk *cbs.Disk) bool {
+		if disk == nil {
+			return true
+		}
+		polls++
+		if disk.Rollbacking != nil && *disk.Rollbacking {
+			started = true
+			return false
+		}
+		if started {
+			return true
+		}
+		return polls*WAIT_POLL_SECONDS > SNAPSHOT_ROLLBACK_START_TIMEOUT && disk.RollbackPercent != nil && *disk.RollbackPercent == 100
+	})
+	if err == nil && disk == nil {
+		return fmt.Errorf("storage[%s] not found", diskId)
+	}
+	return err
+}
+
+type SnapshotPolicyAction struct {
+}
+
+func (action *SnapshotPolicyAction) ReadParam(param interface{}) (interface{}, error) {
+	var inputs SnapshotPolicyInputs
+	err := UnmarshalJson(param, &inputs)
+	if err != nil {
+		return nil, err
+	}
+	return inputs, nil
+}
+
+func (action *SnapshotPolicyAction) CheckParam(input interface{}) error {
+	policies, ok := input.(SnapshotPolicyInputs)
+	if !ok {
+		return INVALID_PARAMETERS
+	}
+
+	for _, policy := range policies.Inputs {
+		if policy.Id == "" {
+			return errors.New("input id is empty")
+		}
+	}
+	return nil
+}
+
+//describeAllSnapshotPolicies pages over DescribeAutoSnapshotPolicies until all the policies matching the request are returned
+func describeAllSnapshotPolicies(client CbsAPI, request *cbs.DescribeAutoSnapshotPoliciesRequest) ([]*cbs.AutoSnapshotPolicy, string, error) {
+	request.Limit = common.Uint64Ptr(SNAPSHOT_DESCRIBE_PAGE_SIZE)
+
+	policies := []*cbs.AutoSnapshotPolicy{}
+	requestId := ""
+	for offset := uint64(0); ; {
+		request.Offset = common.Uint64Ptr(offset)
+		response, err := client.DescribeAutoSnapshotPolicies(request)
+		if err != nil {
+			logrus.Errorf("cbs DescribeAutoSnapshotPolicies meet err=%v", err)
+			return nil, "", err
+		}
+
+		requestId = *response.Response.RequestId
+		policies = append(policies, response.Response.AutoSnapshotPolicySet...)
+		offset += uint64(len(response.Response.AutoSnapshotPolicySet))
+		if len(response.Response.AutoSnapshotPolicySet) == 0 || offset >= *response.Response.TotalCount {
+			break
+		}
+	}
+	return policies, requestId, nil
+}
+
+//querySnapshotPolicyById returns false if the policy is not found
+func querySnapshotPolicyById(client CbsAPI, policyId string) (*cbs.AutoSnapshotPolicy, bool, error) {
+	request := cbs.NewDescribeAutoSnapshotPoliciesRequest()
+	request.AutoSnapshotPolicyIds = []*string{&policyId}
+	policies, _, err := describeAllSnapshotPolicies(client, request)
+	if err != nil || len(policies) == 0 {
+		return nil, false, err
+	}
+	return policies[0], true, nil
+}
+
+//parseSnapshotPolicyValues parses the comma separated days or hours, each of them must be in [0,max]
+func parseSnapshotPolicyValues(name string, values string, max uint64) ([]*uint64, error) {
+	result := []*uint64{}
+	for _, value := range splitCommaValues(values) {
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || n > max {
+			return nil, fmt.Errorf("invalid %s(%s)", name, values)
+		}
+		result = append(result, common.Uint64Ptr(n))
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("input %s is empty", name)
+	}
+	return result, nil
+}
+
+func joinUint64Values(values []*uint64) string {
+	result := []string{}
+	for _, value := range values {
+		result = append(result, strconv.FormatUint(*value, 10))
+	}
+	return strings.Join(result, ",")
+}
+
+func newSnapshotPolicyOutput(guid string, requestId string, policy *cbs.AutoSnapshotPolicy) SnapshotPolicyOutput {
+	output := SnapshotPolicyOutput{
+		Guid:            guid,
+		RequestId:       requestId,
+		Id:              stringValue(policy.AutoSnapshotPolicyId),
+		Name:            stringValue(policy.AutoSnapshotPolicyName),
+		State:           stringValue(policy.AutoSnapshotPolicyState),
+		NextTriggerTime: stringValue(policy.NextTriggerTime),
+		CreatedTime:     stringValue(policy.CreateTime),
+	}
+	if policy.IsActivated != nil {
+		output.IsActivated = strconv.FormatBool(*policy.IsActivated)
+	}
+	if policy.RetentionDays != nil {
+		output.RetentionDays = strconv.FormatUint(*policy.RetentionDays, 10)
+	}
+	if len(policy.Policy) > 0 {
+		output.DayOfWeek = joinUint64Values(policy.Policy[0].DayOfWeek)
+		output.Hour = joinUint64Values(policy.Policy[0].Hour)
+	}
+	diskIds := []string{}
+	for _, diskId := range policy.DiskIdSet {
+		diskIds = append(diskIds, *diskId)
+	}
+	output.DiskIds = strings.Join(diskIds, ",")
+	return output
+}
+
+type SnapshotPolicyCreateAction struct {
+	SnapshotPolicyAction
+}
+
+func (action *SnapshotPolicyCreateAction) CheckParam(input interface{}) error {
+	policies, ok := input.(SnapshotPolicyInputs)
+	if !ok {
+		return INVALID_PARAMETERS
+	}
+
+	for _, policy := range policies.Inputs {
+		if policy.Name == "" {
+			return errors.New("input name is empty")
+		}
+		if _, err := parseSnapshotPolicyValues("day_of_week", policy.DayOfWeek, 6); err != nil {
+			return err
+		}
+		if _, err := parseSnapshotPolicyValues("hour", policy.Hour, 23); err != nil {
+			return err
+		}
+		if policy.IsActivated != "" {
+			if _, err := strconv.ParseBool(policy.IsActivated); err != nil {
+				return fmt.Errorf("invalid is_activated(%s)", policy.IsActivated)
+			}
+		}
+	}
+	return nil
+}
+
+func (action *SnapshotPolicyCreateAction) Do(input interface{}) (interface{}, error) {
+	policies, _ := input.(SnapshotPolicyInputs)
+	outputs := SnapshotPolicyOutputs{}
+	for _, policy := range policies.Inputs {
+		output := SnapshotPolicyOutput{}
+		idempotencyKey := getIdempotencyKey("snapshot-policy-create", policy.Guid, policy.IdempotencyKey, policy)
+		err := runWithIdempotencyKey(idempotencyKey, &output, func() (interface{}, error) {
+			return createSnapshotPolicy(policy)
+		})
+		if err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+func (action *SnapshotPolicyCreateAction) DryRun(input interface{}) (interface{}, error) {
+	policies, _ := input.(SnapshotPolicyInputs)
+	outputs := DryRunOutputs{}
+	for _, policy := range policies.Inputs {
+		client, err := createStorageClient(policy.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		exist := false
+		if policy.Id != "" {
+			if _, exist, err = querySnapshotPolicyById(client, policy.Id); err != nil {
+				return nil, err
+			}
+		}
+		if !exist {
+			if exist, err = isIdempotencyKeyDone(getIdempotencyKey("snapshot-policy-create", policy.Guid, policy.IdempotencyKey, policy)); err != nil {
+				return nil, err
+			}
+		}
+		detail := fmt.Sprintf("create snapshot policy named %s taking snapshots at hours[%s] of days of week[%s]", policy.Name, policy.Hour, policy.DayOfWeek)
+		outputs.Outputs = append(outputs.Outputs, newCreateDryRunOutput(policy.Guid, policy.Id, exist, detail))
+	}
+	return &outputs, nil
+}
+
+//createSnapshotPolicy returns the policy of the input id if it exists,
+//the snapshots are kept forever when retention_days is not set
+func createSnapshotPolicy(policy SnapshotPolicyInput) (SnapshotPolicyOutput, error) {
+	client, err := createStorageClient(policy.ProviderParams)
+	if err != nil {
+		return SnapshotPolicyOutput{}, err
+	}
+
+	if policy.Id != "" {
+		existed, found, err := querySnapshotPolicyById(client, policy.Id)
+		if err != nil {
+			return SnapshotPolicyOutput{}, err
+		}
+		if found {
+			return newSnapshotPolicyOutput(policy.Guid, "", existed), nil
+		}
+	}
+
+	days, _ := parseSnapshotPolicyValues("day_of_week", policy.DayOfWeek, 6)
+	hours, _ := parseSnapshotPolicyValues("hour", policy.Hour, 23)
+	request := cbs.NewCreateAutoSnapshotPolicyRequest()
+	request.AutoSnapshotPolicyName = &policy.Name
+	request.Policy = []*cbs.Policy{{DayOfWeek: days, Hour: hours}}
+	if policy.RetentionDays > 0 {
+		request.RetentionDays = &policy.RetentionDays
+	} else {
+		request.IsPermanent = common.BoolPtr(true)
+	}
+	if policy.IsActivated != "" {
+		isActivated, _ := strconv.ParseBool(policy.IsActivated)
+		request.IsActivated = &isActivated
+	}
+	response, err := client.CreateAutoSnapshotPolicy(request)
+	if err != nil {
+		return SnapshotPolicyOutput{}, err
+	}
+	logrus.Infof("Create snapshot policy[%v] has been submitted, Id is [%v], RequestID is [%v]", policy.Name, *response.Response.AutoSnapshotPolicyId, *response.Response.RequestId)
+
+	created, found, err := querySnapshotPolicyById(client, *response.Response.AutoSnapshotPolicyId)
+	if err != nil {
+		return SnapshotPolicyOutput{}, err
+	}
+	if !found {
+		return SnapshotPolicyOutput{}, fmt.Errorf("snapshot policy[%s] not found", *response.Response.AutoSnapshotPolicyId)
+	}
+	return newSnapshotPolicyOutput(policy.Guid, *response.Response.RequestId, created), nil
+}
+
+type SnapshotPolicyTerminateAction struct {
+	SnapshotPolicyAction
+}
+
+func (action *SnapshotPolicyTerminateAction) Do(input interface{}) (interface{}, error) {
+	policies, _ := input.(SnapshotPolicyInputs)
+	outputs := SnapshotPolicyOutputs{}
+	for _, policy := range policies.Inputs {
+		client, err := createStorageClient(policy.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		output := SnapshotPolicyOutput{Guid: policy.Guid, Id: policy.Id}
+		if _, found, err := querySnapshotPolicyById(client, policy.Id); err != nil {
+			return nil, err
+		} else if !found {
+			logrus.Infof("snapshot policy[%s] is already deleted", policy.Id)
+			outputs.Outputs = append(outputs.Outputs, output)
+			continue
+		}
+
+		request := cbs.NewDeleteAutoSnapshotPoliciesRequest()
+		request.AutoSnapshotPolicyIds = []*string{&policy.Id}
+		response, err := client.DeleteAutoSnapshotPolicies(request)
+		if err != nil {
+			return nil, err
+		}
+		logrus.Infof("Delete snapshot policy[%v] has been submitted, RequestID is [%v]", policy.Id, *response.Response.RequestId)
+
+		output.RequestId = *response.Response.RequestId
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+func (action *SnapshotPolicyTerminateAction) DryRun(input interface{}) (interface{}, error) {
+	policies, _ := input.(SnapshotPolicyInputs)
+	outputs := DryRunOutputs{}
+	for _, policy := range policies.Inputs {
+		client, err := createStorageClient(policy.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		existed, exist, err := querySnapshotPolicyById(client, policy.Id)
+		if err != nil {
+			return nil, err
+		}
+		detail := "delete snapshot policy"
+		if exist && len(existed.DiskIdSet) > 0 {
+			detail = fmt.Sprintf("delete snapshot policy bound to %d disks", len(existed.DiskIdSet))
+		}
+		outputs.Outputs = append(outputs.Outputs, newDeleteDryRunOutput(policy.Guid, policy.Id, exist, detail))
+	}
+	return &outputs, nil
+}
+
+//checkSnapshotPolicyDisks requires the policy id and the disks to bind or unbind
+func checkSnapshotPolicyDisks(input interface{}) error {
+	policies, ok := input.(SnapshotPolicyInputs)
+	if !ok {
+		return INVALID_PARAMETERS
+	}
+
+	for _, policy := range policies.Inputs {
+		if policy.Id == "" {
+			return errors.New("input id is empty")
+		}
+		if len(splitCommaValues(policy.DiskIds)) == 0 {
+			return errors.New("input disk_ids is empty")
+		}
+	}
+	return nil
+}
+
+//getSnapshotPolicyDisksToChange returns the input disks which are not yet bound or unbound as desired
+func getSnapshotPolicyDisksToChange(policy SnapshotPolicyInput, existed *cbs.AutoSnapshotPolicy, bind bool) []string {
+	bound := map[string]bool{}
+	for _, diskId := range existed.DiskIdSet {
+		bound[*diskId] = true
+	}
+	diskIds := []string{}
+	for _, diskId := range splitCommaValues(policy.DiskIds) {
+		if bound[diskId] != bind {
+			diskIds = append(diskIds, diskId)
+		}
+	}
+	return diskIds
+}
+
+//changeSnapshotPolicyDisks binds or unbinds the disks which are not yet in the desired state and returns the policy after it
+func changeSnapshotPolicyDisks(policy SnapshotPolicyInput, bind bool) (SnapshotPolicyOutput, error) {
+	client, err := createStorageClient(policy.ProviderParams)
+	if err != nil {
+		return SnapshotPolicyOutput{}, err
+	}
+
+	existed, found, err := querySnapshotPolicyById(client, policy.Id)
+	if err != nil {
+		return SnapshotPolicyOutput{}, err
+	}
+	if !found {
+		return SnapshotPolicyOutput{}, fmt.Errorf("snapshot policy[%s] not found", policy.Id)
+	}
+	diskIds := getSnapshotPolicyDisksToChange(policy, existed, bind)
+	if len(diskIds) == 0 {
+		logrus.Infof("disks%v of snapshot policy[%s] are already changed", policy.DiskIds, policy.Id)
+		return newSnapshotPolicyOutput(policy.Guid, "", existed), nil
+	}
+
+	requestId := ""
+	if bind {
+		request := cbs.NewBindAutoSnapshotPolicyRequest()
+		request.AutoSnapshotPolicyId = &policy.Id
+		request.DiskIds = common.StringPtrs(diskIds)
+		response, err := client.BindAutoSnapshotPolicy(request)
+		if err != nil {
+			return SnapshotPolicyOutput{}, err
+		}
+		requestId = *response.Response.RequestId
+	} else {
+		request := cbs.NewUnbindAutoSnapshotPolicyRequest()
+		request.AutoSnapshotPolicyId = &policy.Id
+		request.DiskIds = common.StringPtrs(diskIds)
+		response, err := client.UnbindAutoSnapshotPolicy(request)
+		if err != nil {
+			return SnapshotPolicyOutput{}, err
+		}
+		requestId = *response.Response.RequestId
+	}
+	logrus.Infof("Change disks%v of snapshot policy[%v] has been submitted, RequestID is [%v]", diskIds, policy.Id, requestId)
+
+	changed, found, err := querySnapshotPolicyById(client, policy.Id)
+	if err != nil {
+		return SnapshotPolicyOutput{}, err
+	}
+	if !found {
+		return SnapshotPolicyOutput{}, fmt.Errorf("snapshot policy[%s] not found", policy.Id)
+	}
+	return newSnapshotPolicyOutput(policy.Guid, requestId, changed), nil
+}
+
+//dryRunSnapshotPolicyDisks previews binding or unbinding the disks which are not yet in the desired state
+func dryRunSnapshotPolicyDisks(input interface{}, bind bool) (interface{}, error) {
+	policies, _ := input.(SnapshotPolicyInputs)
+	outputs := DryRunOutputs{}
+	for _, policy := range policies.Inputs {
+		client, err := createStorageClient(policy.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		existed, found, err := querySnapshotPolicyById(client, policy.Id)
+		if err != nil {
+			return nil, err
+		}
+		output := newDryRunOutput(policy.Guid, policy.Id, found, DRY_RUN_OPERATION_NONE, "")
+		if !found {
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("snapshot policy[%s] not found", policy.Id))
+		} else if diskIds := getSnapshotPolicyDisksToChange(policy, existed, bind); len(diskIds) == 0 {
+			output.Detail = fmt.Sprintf("disks%v are already changed", splitCommaValues(policy.DiskIds))
+		} else if bind {
+			output.Operation = DRY_RUN_OPERATION_MODIFY
+			output.Detail = fmt.Sprintf("bind disks%v to snapshot policy", diskIds)
+		} else {
+			output.Operation = DRY_RUN_OPERATION_MODIFY
+			output.Detail = fmt.Sprintf("unbind disks%v from snapshot policy", diskIds)
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+type SnapshotPolicyBindAction struct {
+	SnapshotPolicyAction
+}
+
+func (action *SnapshotPolicyBindAction) CheckParam(input interface{}) error {
+	return checkSnapshotPolicyDisks(input)
+}
+
+func (action *SnapshotPolicyBindAction) Do(input interface{}) (interface{}, error) {
+	policies, _ := input.(SnapshotPolicyInputs)
+	outputs := SnapshotPolicyOutputs{}
+	for _, policy := range policies.Inputs {
+		output, err := changeSnapshotPolicyDisks(policy, true)
+		if err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+func (action *SnapshotPolicyBindAction) DryRun(input interface{}) (interface{}, error) {
+	return dryRunSnapshotPolicyDisks(input, true)
+}
+
+type SnapshotPolicyUnbindAction struct {
+	SnapshotPolicyAction
+}
+
+func (action *SnapshotPolicyUnbindAction) CheckParam(input interface{}) error {
+	return checkSnapshotPolicyDisks(input)
+}
+
+func (action *SnapshotPolicyUnbindAction) Do(input interface{}) (interface{}, error) {
+	policies, _ := input.(SnapshotPolicyInputs)
+	outputs := SnapshotPolicyOutputs{}
+	for _, policy := range policies.Inputs {
+		output, err := changeSnapshotPolicyDisks(policy, false)
+		if err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+func (action *SnapshotPolicyUnbindAction) DryRun(input interface{}) (interface{}, error) {
+	return dryRunSnapshotPolicyDisks(input, false)
+}
@@ -1,30 +1,73 @@
 package plugins
 
 import (
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	cbs "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cbs/v20170312"
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
-	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
+	tchttp "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/http"
 )
 
+const (
+	STORAGE_STATE_UNATTACHED = "UNATTACHED"
+	STORAGE_STATE_ATTACHED   = "ATTACHED"
+	STORAGE_STATE_EXPANDING  = "EXPANDING"
+	//prepaid disks are moved to the recycle bin instead of being deleted at once
+	STORAGE_STATE_TORECYCLE = "TORECYCLE"
+
+	STORAGE_WAIT_TIMEOUT = 300
+
+	//the most disks one CreateDisks call creates and one AttachDisks call attaches
+	STORAGE_CREATE_MAX_DISK_COUNT = 50
+	STORAGE_ATTACH_MAX_DISK_COUNT = 10
+
+	STORAGE_ENCRYPT = "ENCRYPT"
+
+	//the most disks qcloud returns in one DescribeDisks call and terminates in one TerminateDisks call
+	STORAGE_DESCRIBE_PAGE_SIZE  = 100
+	STORAGE_TERMINATE_MAX_COUNT = 100
+
+	//qcloud reports the time of disks in this layout, in Beijing time
+	STORAGE_TIME_LAYOUT    = "2006-01-02 15:04:05"
+	STORAGE_TIME_ZONE      = "Asia/Shanghai"
+	STORAGE_TIME_ZONE_SECS = 8 * 60 * 60
+
+	//the disks detached are found in the operation logs, which one DescribeDiskOperationLogs call returns for at most 10 disks
+	STORAGE_OPERATION_DETACH             = "CBS_OPERATION_DETACH"
+	STORAGE_OPERATION_STATE_SUCCESS      = "SUCCESS"
+	STORAGE_OPERATION_LOG_MAX_DISK_COUNT = 10
+)
+
+//storageTimeLocation parses the time of disks whatever the timezone the plugin runs in
+var storageTimeLocation = loadStorageTimeLocation()
+
+func loadStorageTimeLocation() *time.Location {
+	location, err := time.LoadLocation(STORAGE_TIME_ZONE)
+	if err != nil {
+		logrus.Warnf("load timezone %s meet err=%v, use UTC+8 instead", STORAGE_TIME_ZONE, err)
+		return time.FixedZone(STORAGE_TIME_ZONE, STORAGE_TIME_ZONE_SECS)
+	}
+	return location
+}
+
 var StorageActions = make(map[string]Action)
 
 func init() {
 	StorageActions["create"] = new(StorageCreateAction)
 	StorageActions["terminate"] = new(StorageTerminateAction)
+	StorageActions["attach"] = new(StorageAttachAction)
+	StorageActions["detach"] = new(StorageDetachAction)
+	StorageActions["resize"] = new(StorageResizeAction)
+	StorageActions["find-orphans"] = new(StorageFindOrphansAction)
 }
 
-func CreateCbsClient(region, secretId, secretKey string) (client *cbs.Client, err error) {
-	credential := common.NewCredential(secretId, secretKey)
-
-	clientProfile := profile.NewClientProfile()
-	clientProfile.HttpProfile.Endpoint = "cbs.tencentcloudapi.com"
-
-	return cbs.NewClient(credential, region, clientProfile)
+func CreateCbsClient(region, secretId, secretKey string) (CbsAPI, error) {
+	return QcloudClientFactory.NewCbsClient(region, secretId, secretKey)
 }
 
 type StorageInputs struct {
@@ -32,15 +75,32 @@ type StorageInputs struct {
 }
 
 type StorageInput struct {
-	Guid             string `json:"guid,omitempty"`
-	ProviderParams   string `json:"provider_params,omitempty"`
-	DiskType         string `json:"disk_type,omitempty"`
-	DiskSize         uint64 `json:"disk_size,omitempty"`
-	DiskName         string `json:"disk_name,omitempty"`
-	Id               string `json:"id,omitempty"`
-	DiskChargeType   string `json:"disk_charge_type,omitempty"`
-	DiskChargePeriod string `json:"disk_charge_period,omitempty"`
-	InstanceId       string `json:"instance_id,omitempty"`
+	Guid               string `json:"guid,omitempty"`
+	ProviderParams     string `json:"provider_params,omitempty"`
+	DiskType           string `json:"disk_type,omitempty"`
+	DiskSize           uint64 `json:"disk_size,omitempty"`
+	DiskName           string `json:"disk_name,omitempty"`
+	Id                 string `json:"id,omitempty"`
+	DiskChargeType     string `json:"disk_charge_type,omitempty"`
+	DiskChargePeriod   string `json:"disk_charge_period,omitempty"`
+	InstanceId         string `json:"instance_id,omitempty"`
+	DeleteWithInstance string `json:"delete_with_instance,omitempty"`
+	SnapshotId         string `json:"snapshot_id,omitempty"`
+	IdempotencyKey     string `json:"idempotency_key,omitempty"`
+
+	//disk_count disks are created for each of instance_id and instance_ids
+	DiskCount             uint64 `json:"disk_count,omitempty"`
+	Encrypt               string `json:"encrypt,omitempty"`
+	ThroughputPerformance uint64 `json:"throughput_performance,omitempty"`
+	ProjectId             int64  `json:"project_id,omitempty"`
+	Tags                  string `json:"tags,omitempty"`
+	InstanceIds           string `json:"instance_ids,omitempty"`
+
+	//find-orphans looks for unattached disks in regions and terminates those unattached for delete_older_than_days,
+	//prepaid disks are only terminated with include_prepaid
+	Regions             string `json:"regions,omitempty"`
+	DeleteOlderThanDays int64  `json:"delete_older_than_days,omitempty"`
+	IncludePrepaid      string `json:"include_prepaid,omitempty"`
 }
 
 type StorageOutputs struct {
@@ -48,9 +108,23 @@ type StorageOutputs struct {
 }
 
 type StorageOutput struct {
-	Guid      string `json:"guid,omitempty"`
-	RequestId string `json:"request_id,omitempty"`
-	Id        string `json:"id,omitempty"`
+	Guid       string `json:"guid,omitempty"`
+	RequestId  string `json:"request_id,omitempty"`
+	Id         string `json:"id,omitempty"`
+	DiskState  string `json:"disk_state,omitempty"`
+	DiskSize   string `json:"disk_size,omitempty"`
+	InstanceId string `json:"instance_id,omitempty"`
+	//a guess from the attach order, the device name is not read from the instance
+	DeviceNameHint string `json:"device_name_hint,omitempty"`
+
+	Region         string `json:"region,omitempty"`
+	DiskName       string `json:"disk_name,omitempty"`
+	DiskType       string `json:"disk_type,omitempty"`
+	DiskChargeType string `json:"disk_charge_type,omitempty"`
+	CreatedTime    string `json:"created_time,omitempty"`
+	UnattachedDays string `json:"unattached_days,omitempty"`
+	LastInstanceId string `json:"last_instance_id,omitempty"`
+	Deleted        string `json:"deleted,omitempty"`
 }
 
 type StoragePlugin struct {
@@ -66,10 +140,10 @@ func (plugin *StoragePlugin) GetActionByName(actionName string) (Action, error)
 	return action, nil
 }
 
-type StorageCreateAction struct {
+type StorageAction struct {
 }
 
-func (action *StorageCreateAction) ReadParam(param interface{}) (interface{}, error) {
+func (action *StorageAction) ReadParam(param interface{}) (interface{}, error) {
 	var inputs StorageInputs
 	err := UnmarshalJson(param, &inputs)
 	if err != nil {
@@ -78,12 +152,299 @@ func (action *StorageCreateAction) ReadParam(param interface{}) (interface{}, er
 	return inputs, nil
 }
 
+func (action *StorageAction) CheckParam(input interface{}) error {
+	storages, ok := input.(StorageInputs)
+	if !ok {
+		return INVALID_PARAMETERS
+	}
+
+	for _, storage := range storages.Inputs {
+		if storage.Id == "" {
+			return errors.New("input id is empty")
+		}
+	}
+	return nil
+}
+
+func createStorageClient(providerParams string) (CbsAPI, error) {
+	paramsMap, _ := GetMapFromProviderParams(providerParams)
+	return CreateCbsClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+}
+
+//the disks are deleted together with the instance unless delete_with_instance is false
+func checkStorageDeleteWithInstance(storage StorageInput) error {
+	if storage.DeleteWithInstance != "" {
+		if _, err := strconv.ParseBool(storage.DeleteWithInstance); err != nil {
+			return fmt.Errorf("invalid delete_with_instance(%s)", storage.DeleteWithInstance)
+		}
+	}
+	return nil
+}
+
+func getStorageDeleteWithInstance(storage StorageInput) bool {
+	if storage.DeleteWithInstance == "" {
+		return true
+	}
+	deleteWithInstance, _ := strconv.ParseBool(storage.DeleteWithInstance)
+	return deleteWithInstance
+}
+
+//queryStorageById returns false if the disk is not found
+func queryStorageById(client CbsAPI, diskId string) (*cbs.Disk, bool, error) {
+	request := cbs.NewDescribeDisksRequest()
+	request.DiskIds = []*string{&diskId}
+	response, err := client.DescribeDisks(request)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(response.Response.DiskSet) == 0 {
+		return nil, false, nil
+	}
+
+	if len(response.Response.DiskSet) > 1 {
+		logrus.Errorf("query storage disk id=%s info find more than 1", diskId)
+		return nil, false, fmt.Errorf("query storage disk id=%s info find more than 1", diskId)
+	}
+	return response.Response.DiskSet[0], true, nil
+}
+
+func queryStorageInfo(client CbsAPI, input *StorageInput) (*StorageOutput, bool, error) {
+	disk, found, err := queryStorageById(client, input.Id)
+	if err != nil || !found {
+		return nil, false, err
+	}
+
+	output := newStorageOutput(input.Guid, "", disk)
+	return &output, true, nil
+}
+
+//waitStorageUntil polls the disk until it meets the condition, the condition gets nil if the disk is not found
+func waitStorageUntil(client CbsAPI, diskId string, timeout int, condition func(disk *cbs.Disk) bool) (*cbs.Disk, error) {
+	count := 0
+	for {
+		time.Sleep(waitPollInterval)
+		disk, _, err := queryStorageById(client, diskId)
+		if err != nil {
+			return nil, err
+		}
+		if condition(disk) {
+			return disk, nil
+		}
+
+		count++
+		if count*WAIT_POLL_SECONDS > timeout {
+			return nil, fmt.Errorf("qcloud wait storage[%s] timeout", diskId)
+		}
+	}
+}
+
+func waitStorageInState(client CbsAPI, diskId string, desireState string) (*cbs.Disk, error) {
+	return waitStorageUntil(client, diskId, STORAGE_WAIT_TIMEOUT, func(disk *cbs.Disk) bool {
+		return disk != nil && stringValue(disk.DiskState) == desireState
+	})
+}
+
+//queryStoragesByIds returns the disks found in the order of the ids
+func queryStoragesByIds(client CbsAPI, diskIds []string) ([]*cbs.Disk, error) {
+	request := cbs.NewDescribeDisksRequest()
+	request.DiskIds = common.StringPtrs(diskIds)
+	request.Limit = common.Uint64Ptr(uint64(len(diskIds)))
+	response, err := client.DescribeDisks(request)
+	if err != nil {
+		return nil, err
+	}
+
+	disks := map[string]*cbs.Disk{}
+	for _, disk := range response.Response.DiskSet {
+		disks[stringValue(disk.DiskId)] = disk
+	}
+	found := []*cbs.Disk{}
+	for _, diskId := range diskIds {
+		if disk, ok := disks[diskId]; ok {
+			found = append(found, disk)
+		}
+	}
+	return found, nil
+}
+
+//waitStoragesUntil polls the disks together until the disks found meet the condition
+func waitStoragesUntil(client CbsAPI, diskIds []string, condition func(disks []*cbs.Disk) bool) ([]*cbs.Disk, error) {
+	count := 0
+	for {
+		time.Sleep(waitPollInterval)
+		disks, err := queryStoragesByIds(client, diskIds)
+		if err != nil {
+			return nil, err
+		}
+		if condition(disks) {
+			return disks, nil
+		}
+
+		count++
+		if count*WAIT_POLL_SECONDS > STORAGE_WAIT_TIMEOUT {
+			return nil, fmt.Errorf("qcloud wait storages%v timeout", diskIds)
+		}
+	}
+}
+
+//waitStoragesCreated waits for the new disks to be unattached, or attached by an earlier try of the same create
+func waitStoragesCreated(client CbsAPI, diskIds []string) ([]*cbs.Disk, error) {
+	return waitStoragesUntil(client, diskIds, func(disks []*cbs.Disk) bool {
+		if len(disks) != len(diskIds) {
+			return false
+		}
+		for _, disk := range disks {
+			if state := stringValue(disk.DiskState); state != STORAGE_STATE_UNATTACHED && state != STORAGE_STATE_ATTACHED {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func waitStoragesInState(client CbsAPI, diskIds []string, desireState string) ([]*cbs.Disk, error) {
+	return waitStoragesUntil(client, diskIds, func(disks []*cbs.Disk) bool {
+		if len(disks) != len(diskIds) {
+			return false
+		}
+		for _, disk := range disks {
+			if stringValue(disk.DiskState) != desireState {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func newStorageOutput(guid string, requestId string, disk *cbs.Disk) StorageOutput {
+	output := StorageOutput{
+		Guid:       guid,
+		RequestId:  requestId,
+		Id:         stringValue(disk.DiskId),
+		DiskState:  stringValue(disk.DiskState),
+		InstanceId: stringValue(disk.InstanceId),
+	}
+	if disk.DiskSize != nil {
+		output.DiskSize = strconv.FormatUint(*disk.DiskSize, 10)
+	}
+	return output
+}
+
+//attachStorage returns at once if the disk is already attached to the instance
+func attachStorage(client CbsAPI, storage StorageInput) (StorageOutput, error) {
+	disk, found, err := queryStorageById(client, storage.Id)
+	if err != nil {
+		return StorageOutput{}, err
+	}
+	if !found {
+		return StorageOutput{}, fmt.Errorf("storage[%s] not found", storage.Id)
+	}
+	if stringValue(disk.DiskState) == STORAGE_STATE_ATTACHED && stringValue(disk.InstanceId) == storage.InstanceId {
+		logrus.Infof("storage[%s] is already attached to instance[%s]", storage.Id, storage.InstanceId)
+		return newStorageOutput(storage.Guid, "", disk), nil
+	}
+	if stringValue(disk.InstanceId) != "" {
+		return StorageOutput{}, fmt.Errorf("storage[%s] is attached to another instance[%s]", storage.Id, *disk.InstanceId)
+	}
+
+	request := cbs.NewAttachDisksRequest()
+	request.DiskIds = []*string{&storage.Id}
+	request.InstanceId = &storage.InstanceId
+	deleteWithInstance := getStorageDeleteWithInstance(storage)
+	request.DeleteWithInstance = &deleteWithInstance
+	response, err := client.AttachDisks(request)
+	if err != nil {
+		return StorageOutput{}, fmt.Errorf("attach storage(id = %v,instanceId = %v) in cloud meet err = %v", storage.Id, storage.InstanceId, err)
+	}
+	logrus.Infof("attach storage request id = %v", *response.Response.RequestId)
+
+	if disk, err = waitStorageInState(client, storage.Id, STORAGE_STATE_ATTACHED); err != nil {
+		return StorageOutput{}, err
+	}
+	return newStorageOutput(storage.Guid, *response.Response.RequestId, disk), nil
+}
+
+//detachStorage returns at once if the disk is not attached
+func detachStorage(client CbsAPI, storage StorageInput, disk *cbs.Disk) (StorageOutput, error) {
+	if stringValue(disk.DiskState) == STORAGE_STATE_UNATTACHED {
+		logrus.Infof("storage[%s] is already detached", storage.Id)
+		return newStorageOutput(storage.Guid, "", disk), nil
+	}
+
+	request := cbs.NewDetachDisksRequest()
+	request.DiskIds = []*string{&storage.Id}
+	response, err := client.DetachDisks(request)
+	if err != nil {
+		return StorageOutput{}, fmt.Errorf("detach storage(id = %v) in cloud meet error = %v", storage.Id, err)
+	}
+	logrus.Infof("detach storage request id = %v", *response.Response.RequestId)
+
+	if disk, err = waitStorageInState(client, storage.Id, STORAGE_STATE_UNATTACHED); err != nil {
+		return StorageOutput{}, err
+	}
+	return newStorageOutput(storage.Guid, *response.Response.RequestId, disk), nil
+}
+
+type StorageCreateAction struct {
+	StorageAction
+}
+
+//getStorageTargetInstances returns instance_id and instance_ids in order,
+//each of them gets disk_count disks
+func getStorageTargetInstances(storage StorageInput) []string {
+	instanceIds := []string{}
+	if storage.InstanceId != "" {
+		instanceIds = append(instanceIds, storage.InstanceId)
+	}
+	return append(instanceIds, splitCommaValues(storage.InstanceIds)...)
+}
+
+func getStorageDiskCount(storage StorageInput) uint64 {
+	if storage.DiskCount == 0 {
+		return 1
+	}
+	return storage.DiskCount
+}
+
 func (action *StorageCreateAction) CheckParam(input interface{}) error {
-	_, ok := input.(StorageInputs)
+	storages, ok := input.(StorageInputs)
 	if !ok {
 		return fmt.Errorf("storageCreateAtion:input type=%T not right", input)
 	}
 
+	for _, storage := range storages.Inputs {
+		if err := checkStorageDeleteWithInstance(storage); err != nil {
+			return err
+		}
+		if storage.Encrypt != "" {
+			if _, err := strconv.ParseBool(storage.Encrypt); err != nil {
+				return fmt.Errorf("invalid encrypt(%s)", storage.Encrypt)
+			}
+		}
+		if _, err := parseVmTags(storage.Tags); err != nil {
+			return err
+		}
+		if storage.ProjectId < 0 {
+			return fmt.Errorf("invalid project_id(%d)", storage.ProjectId)
+		}
+
+		instanceIds := getStorageTargetInstances(storage)
+		seen := map[string]bool{}
+		for _, instanceId := range instanceIds {
+			if seen[instanceId] {
+				return fmt.Errorf("instance(%s) is listed more than once", instanceId)
+			}
+			seen[instanceId] = true
+		}
+		diskCount := getStorageDiskCount(storage)
+		if len(instanceIds) > 1 {
+			diskCount *= uint64(len(instanceIds))
+		}
+		if diskCount > STORAGE_CREATE_MAX_DISK_COUNT {
+			return fmt.Errorf("disk_count(%d) on %d instances exceeds %d disks of one CreateDisks call", getStorageDiskCount(storage), len(instanceIds), STORAGE_CREATE_MAX_DISK_COUNT)
+		}
+	}
 	return nil
 }
 
@@ -92,76 +453,160 @@ func (action *StorageCreateAction) Do(input interface{}) (interface{}, error) {
 	outputs := StorageOutputs{}
 
 	for _, storage := range storages.Inputs {
-		output, err := action.createStorage(&storage)
+		created := []StorageOutput{}
+		idempotencyKey := getIdempotencyKey("storage-create", storage.Guid, storage.IdempotencyKey, storage)
+		err := runWithIdempotencyKey(idempotencyKey, &created, func() (interface{}, error) {
+			return action.createStorage(storage, getIdempotencyClientToken(idempotencyKey))
+		})
 		if err != nil {
 			return nil, err
 		}
 
-		storage.Id = output.Id
-
-		err = action.attachStorage(&storage)
-		if err != nil {
-			return nil, err
-		}
-
-		outputs.Outputs = append(outputs.Outputs, *output)
+		outputs.Outputs = append(outputs.Outputs, created...)
 	}
 
 	logrus.Infof("all storages = %v are created", storages)
 	return &outputs, nil
 }
 
-func (action *StorageCreateAction) attachStorage(storage *StorageInput) error {
-	paramsMap, _ := GetMapFromProviderParams(storage.ProviderParams)
-	client, _ := CreateCbsClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+func (action *StorageCreateAction) DryRun(input interface{}) (interface{}, error) {
+	storages, _ := input.(StorageInputs)
+	outputs := DryRunOutputs{}
 
-	tryTimes := 10
-	for i := 1; i <= tryTimes; i++ {
-		time.Sleep(time.Duration(5) * time.Second)
+	for _, storage := range storages.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(storage.ProviderParams)
+		client, _ := CreateCbsClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+
+		exist := false
+		if diskIds := splitCommaValues(storage.Id); len(diskIds) > 0 {
+			disks, err := queryStoragesByIds(client, diskIds)
+			if err != nil {
+				return nil, err
+			}
+			exist = len(disks) == len(diskIds)
+		}
 
-		request := cbs.NewAttachDisksRequest()
-		request.DiskIds = []*string{&storage.Id}
-		request.InstanceId = &storage.InstanceId
-		deleteWithInstance := true
-		request.DeleteWithInstance = &deleteWithInstance
-		response, err := client.AttachDisks(request)
-		if err != nil {
-			if i == tryTimes {
-				logrus.Errorf("attach storage (id = %v,instanceId = %v) in cloud meet err = %v, try times = %v",
-					storage.Id, storage.InstanceId, err, i)
-			} else {
-				logrus.Infof("waiting for storage(id = %v) to be attached, try times = %v", storage.Id, i)
+		instanceIds := getStorageTargetInstances(storage)
+		detail := fmt.Sprintf("create %d %s disks size=%dGB in zone[%s] and attach them to instances%v", getStorageDiskCount(storage), storage.DiskType, storage.DiskSize, paramsMap["AvailableZone"], instanceIds)
+		if storage.SnapshotId != "" {
+			detail += fmt.Sprintf(" from snapshot[%s]", storage.SnapshotId)
+		}
+		output := newCreateDryRunOutput(storage.Guid, storage.Id, exist, detail)
+		if !exist && len(instanceIds) > 0 {
+			cvmClient, err := createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+			if err != nil {
+				return nil, err
+			}
+			for _, instanceId := range instanceIds {
+				if _, found, err := queryVmInstanceInfo(cvmClient, instanceId); err != nil {
+					return nil, err
+				} else if !found {
+					output.Conflicts = append(output.Conflicts, fmt.Sprintf("instance[%s] to attach not found", instanceId))
+				}
 			}
-			continue
 		}
-		logrus.Infof("attach storage request id = %v", response.Response.RequestId)
-		break
+		outputs.Outputs = append(outputs.Outputs, output)
 	}
 
-	return nil
+	return &outputs, nil
 }
 
-func (action *StorageCreateAction) createStorage(storage *StorageInput) (*StorageOutput, error) {
-	paramsMap, err := GetMapFromProviderParams(storage.ProviderParams)
-	client, _ := CreateCbsClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+//createDisksRequest adds the CreateDisks parameters which the vendored sdk doesn't know yet,
+//it is sent as json like the sdk requests
+type createDisksRequest struct {
+	*cbs.CreateDisksRequest
+	ThroughputPerformance *uint64 `json:"ThroughputPerformance,omitempty" name:"ThroughputPerformance"`
+}
+
+//createStorage creates all the disks of the input in one CreateDisks call, then attaches disk_count disks to each target instance,
+//the disks of the input ids are used instead if all of them exist, qcloud returns the disks of an earlier try for the same client token
+func (action *StorageCreateAction) createStorage(storage StorageInput, clientToken string) ([]StorageOutput, error) {
+	paramsMap, _ := GetMapFromProviderParams(storage.ProviderParams)
+	client, err := CreateCbsClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+	if err != nil {
+		return nil, err
+	}
+
+	instanceIds := getStorageTargetInstances(storage)
+	diskCount := getStorageDiskCount(storage)
+	if len(instanceIds) > 1 {
+		diskCount *= uint64(len(instanceIds))
+	}
 
 	//check resource exist
-	if storage.Id != "" {
-		queryStorageResponse, flag, err := queryStorageInfo(client, storage)
-		if err != nil && flag == false {
+	requestId := ""
+	diskIds := splitCommaValues(storage.Id)
+	disks := []*cbs.Disk{}
+	if len(diskIds) > 0 {
+		if disks, err = queryStoragesByIds(client, diskIds); err != nil {
 			return nil, err
 		}
+	}
+	if len(diskIds) == 0 || len(disks) != len(diskIds) {
+		if requestId, diskIds, err = action.createDisks(client, storage, paramsMap["AvailableZone"], diskCount, clientToken); err != nil {
+			return nil, err
+		}
+		if disks, err = waitStoragesCreated(client, diskIds); err != nil {
+			return nil, err
+		}
+	} else if len(instanceIds) > 0 && uint64(len(diskIds)) != diskCount {
+		return nil, fmt.Errorf("found %d disks of id(%s), want %d disks for instances%v", len(diskIds), storage.Id, diskCount, instanceIds)
+	}
 
-		if err == nil && flag == true {
-			return queryStorageResponse, nil
+	deviceNameHints := map[string]string{}
+	if len(instanceIds) > 0 {
+		perInstance := len(diskIds) / len(instanceIds)
+		attached := 0
+		for i, instanceId := range instanceIds {
+			count, err := attachNewStorages(client, storage, disks[i*perInstance:(i+1)*perInstance], instanceId)
+			if err != nil {
+				return nil, err
+			}
+			attached += count
+		}
+		if attached > 0 {
+			if disks, err = waitStoragesInState(client, diskIds, STORAGE_STATE_ATTACHED); err != nil {
+				return nil, err
+			}
+		}
+		if deviceNameHints, err = getStorageDeviceNameHints(storage.ProviderParams, instanceIds); err != nil {
+			return nil, err
 		}
 	}
 
-	request := cbs.NewCreateDisksRequest()
+	outputs := []StorageOutput{}
+	for _, disk := range disks {
+		output := newStorageOutput(storage.Guid, requestId, disk)
+		output.DeviceNameHint = deviceNameHints[output.Id]
+		outputs = append(outputs, output)
+	}
+	return outputs, nil
+}
+
+func (action *StorageCreateAction) createDisks(client CbsAPI, storage StorageInput, zone string, diskCount uint64, clientToken string) (string, []string, error) {
+	request := &createDisksRequest{CreateDisksRequest: cbs.NewCreateDisksRequest()}
+	request.ClientToken = &clientToken
 	request.DiskName = &storage.DiskName
 	request.DiskType = &storage.DiskType
-	request.DiskSize = &storage.DiskSize
 	request.DiskChargeType = &storage.DiskChargeType
+	request.DiskCount = &diskCount
+	//the disk created from a snapshot is as large as the snapshot unless disk_size is given
+	if storage.DiskSize > 0 {
+		request.DiskSize = &storage.DiskSize
+	}
+	if storage.SnapshotId != "" {
+		request.SnapshotId = &storage.SnapshotId
+	}
+	if encrypt, _ := strconv.ParseBool(storage.Encrypt); encrypt {
+		request.Encrypt = common.StringPtr(STORAGE_ENCRYPT)
+	}
+	if storage.ThroughputPerformance > 0 {
+		request.ThroughputPerformance = &storage.ThroughputPerformance
+	}
+	tags, _ := parseVmTags(storage.Tags)
+	for _, tag := range tags {
+		request.Tags = append(request.Tags, &cbs.Tag{Key: common.StringPtr(tag.Key), Value: common.StringPtr(tag.Value)})
+	}
 
 	if storage.DiskChargeType == CHARGE_TYPE_PREPAID {
 		period, _ := strconv.ParseUint(storage.DiskChargePeriod, 0, 64)
@@ -172,147 +617,655 @@ func (action *StorageCreateAction) createStorage(storage *StorageInput) (*Storag
 		}
 	}
 
-	availableZone := paramsMap["AvailableZone"]
-	placement := cbs.Placement{Zone: &availableZone}
+	placement := cbs.Placement{Zone: &zone}
+	if storage.ProjectId > 0 {
+		placement.ProjectId = common.Uint64Ptr(uint64(storage.ProjectId))
+	}
 	request.Placement = &placement
 
-	response, err := client.CreateDisks(request)
-	if err != nil {
-		return nil, fmt.Errorf("create storage in cloud meet err = %v", err)
+	response := cbs.NewCreateDisksResponse()
+	if err := client.Send(request, response); err != nil {
+		return "", nil, fmt.Errorf("create storage in cloud meet err = %v", err)
 	}
 
-	if len(response.Response.DiskIdSet) == 0 {
-		return nil, fmt.Errorf("no storage is created")
+	if uint64(len(response.Response.DiskIdSet)) != diskCount {
+		return "", nil, fmt.Errorf("%d storages are created,want %d", len(response.Response.DiskIdSet), diskCount)
 	}
+	diskIds := []string{}
+	for _, diskId := range response.Response.DiskIdSet {
+		diskIds = append(diskIds, *diskId)
+	}
+	logrus.Infof("create storages%v request id = %v", diskIds, *response.Response.RequestId)
+	return *response.Response.RequestId, diskIds, nil
+}
 
-	output := StorageOutput{}
-	output.Guid = storage.Guid
-	output.RequestId = *response.Response.RequestId
-	output.Id = *response.Response.DiskIdSet[0]
+//attachNewStorages attaches the disks not yet on the instance and returns how many of them are attached,
+//AttachDisks takes at most 10 disks each time
+func attachNewStorages(client CbsAPI, storage StorageInput, disks []*cbs.Disk, instanceId string) (int, error) {
+	diskIds := []string{}
+	for _, disk := range disks {
+		if stringValue(disk.InstanceId) == instanceId {
+			continue
+		}
+		if stringValue(disk.InstanceId) != "" {
+			return 0, fmt.Errorf("storage[%s] is attached to another instance[%s]", *disk.DiskId, *disk.InstanceId)
+		}
+		diskIds = append(diskIds, *disk.DiskId)
+	}
+
+	deleteWithInstance := getStorageDeleteWithInstance(storage)
+	for start := 0; start < len(diskIds); start += STORAGE_ATTACH_MAX_DISK_COUNT {
+		end := start + STORAGE_ATTACH_MAX_DISK_COUNT
+		if end > len(diskIds) {
+			end = len(diskIds)
+		}
+		request := cbs.NewAttachDisksRequest()
+		request.DiskIds = common.StringPtrs(diskIds[start:end])
+		request.InstanceId = &instanceId
+		request.DeleteWithInstance = &deleteWithInstance
+		response, err := client.AttachDisks(request)
+		if err != nil {
+			return 0, fmt.Errorf("attach storages(ids = %v,instanceId = %v) in cloud meet err = %v", diskIds[start:end], instanceId, err)
+		}
+		logrus.Infof("attach storages%v request id = %v", diskIds[start:end], *response.Response.RequestId)
+	}
+	return len(diskIds), nil
+}
+
+//getStorageDeviceNameHints guesses the device names of the data disks of the instances from their order as /dev/vdb, /dev/vdc and so on,
+//the guess is not verified on the instance and is wrong once disks are detached and attached again or the image names devices differently
+func getStorageDeviceNameHints(providerParams string, instanceIds []string) (map[string]string, error) {
+	paramsMap, _ := GetMapFromProviderParams(providerParams)
+	client, err := createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+	if err != nil {
+		return nil, err
+	}
 
-	return &output, nil
+	deviceNames := map[string]string{}
+	for _, instanceId := range instanceIds {
+		instance, found, err := queryVmInstanceInfo(client, instanceId)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, fmt.Errorf("instance[%s] not found", instanceId)
+		}
+		for i, dataDisk := range instance.DataDisks {
+			if dataDisk.DiskId != nil && i < 25 {
+				deviceNames[*dataDisk.DiskId] = fmt.Sprintf("/dev/vd%c", 'b'+i)
+			}
+		}
+	}
+	return deviceNames, nil
 }
 
 type StorageTerminateAction struct {
+	StorageAction
 }
 
-func (action *StorageTerminateAction) ReadParam(param interface{}) (interface{}, error) {
-	var inputs StorageInputs
-	err := UnmarshalJson(param, &inputs)
+func (action *StorageTerminateAction) Do(input interface{}) (interface{}, error) {
+	storages, _ := input.(StorageInputs)
+	outputs := StorageOutputs{}
+
+	for _, storage := range storages.Inputs {
+		output, err := action.terminateStorage(storage)
+		if err != nil {
+			return nil, err
+		}
+
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+
+	return &outputs, nil
+}
+
+func (action *StorageTerminateAction) DryRun(input interface{}) (interface{}, error) {
+	storages, _ := input.(StorageInputs)
+	outputs := DryRunOutputs{}
+
+	for _, storage := range storages.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(storage.ProviderParams)
+		client, _ := CreateCbsClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+
+		_, exist, err := queryStorageInfo(client, &storage)
+		if err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, newDeleteDryRunOutput(storage.Guid, storage.Id, exist, "detach and terminate disk"))
+	}
+
+	return &outputs, nil
+}
+
+//terminateStorage detaches the disk first if it is attached, disks already terminated are skipped
+func (action *StorageTerminateAction) terminateStorage(storage StorageInput) (StorageOutput, error) {
+	client, err := createStorageClient(storage.ProviderParams)
 	if err != nil {
-		return nil, err
+		return StorageOutput{}, err
 	}
-	return inputs, nil
+
+	output := StorageOutput{Guid: storage.Guid, Id: storage.Id}
+	disk, found, err := queryStorageById(client, storage.Id)
+	if err != nil {
+		return StorageOutput{}, err
+	}
+	if !found || stringValue(disk.DiskState) == STORAGE_STATE_TORECYCLE {
+		logrus.Infof("storage[%s] is already terminated", storage.Id)
+		return output, nil
+	}
+	if _, err = detachStorage(client, storage, disk); err != nil {
+		return StorageOutput{}, err
+	}
+
+	request := cbs.NewTerminateDisksRequest()
+	request.DiskIds = []*string{&storage.Id}
+	response, err := client.TerminateDisks(request)
+	if err != nil {
+		return StorageOutput{}, fmt.Errorf("terminate storage(id = %v) meet error = %v", storage.Id, err)
+	}
+	logrus.Infof("terminate storage request id = %v", *response.Response.RequestId)
+
+	_, err = waitStorageUntil(client, storage.Id, STORAGE_WAIT_TIMEOUT, func(disk *cbs.Disk) bool {
+		return disk == nil || stringValue(disk.DiskState) == STORAGE_STATE_TORECYCLE
+	})
+	if err != nil {
+		return StorageOutput{}, err
+	}
+	output.RequestId = *response.Response.RequestId
+	return output, nil
 }
 
-func (action *StorageTerminateAction) CheckParam(input interface{}) error {
-	storages, ok := input.(StorageInputs)
-	if !ok {
-		return fmt.Errorf("storageTerminationAtion:input type=%T not right", input)
+type StorageAttachAction struct {
+	StorageAction
+}
+
+func (action *StorageAttachAction) CheckParam(input interface{}) error {
+	if err := action.StorageAction.CheckParam(input); err != nil {
+		return err
 	}
 
+	storages, _ := input.(StorageInputs)
 	for _, storage := range storages.Inputs {
-		if storage.Id == "" {
-			return fmt.Errorf("storageTerminateAction storage_id is empty")
+		if storage.InstanceId == "" {
+			return errors.New("input instance_id is empty")
+		}
+		if err := checkStorageDeleteWithInstance(storage); err != nil {
+			return err
 		}
 	}
-
 	return nil
 }
 
-func (action *StorageTerminateAction) Do(input interface{}) (interface{}, error) {
+func (action *StorageAttachAction) Do(input interface{}) (interface{}, error) {
 	storages, _ := input.(StorageInputs)
 	outputs := StorageOutputs{}
 
 	for _, storage := range storages.Inputs {
-		err := action.detachStorage(&storage)
+		client, err := createStorageClient(storage.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+		output, err := attachStorage(client, storage)
 		if err != nil {
 			return nil, err
 		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
 
-		output, err := action.terminateStorage(&storage)
+	return &outputs, nil
+}
+
+//dryRunStorageChange previews the change of each disk with preview, the disks not found are reported as conflicts
+func dryRunStorageChange(storages StorageInputs, preview func(storage StorageInput, disk *cbs.Disk, output *DryRunOutput)) (*DryRunOutputs, error) {
+	outputs := DryRunOutputs{}
+	for _, storage := range storages.Inputs {
+		client, err := createStorageClient(storage.ProviderParams)
 		if err != nil {
 			return nil, err
 		}
 
-		outputs.Outputs = append(outputs.Outputs, *output)
+		disk, found, err := queryStorageById(client, storage.Id)
+		if err != nil {
+			return nil, err
+		}
+		output := newDryRunOutput(storage.Guid, storage.Id, found, DRY_RUN_OPERATION_NONE, "")
+		if !found {
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("storage[%s] not found", storage.Id))
+		} else {
+			preview(storage, disk, &output)
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+func (action *StorageAttachAction) DryRun(input interface{}) (interface{}, error) {
+	storages, _ := input.(StorageInputs)
+	return dryRunStorageChange(storages, func(storage StorageInput, disk *cbs.Disk, output *DryRunOutput) {
+		switch stringValue(disk.InstanceId) {
+		case storage.InstanceId:
+			output.Detail = fmt.Sprintf("disk already attached to instance[%s]", storage.InstanceId)
+		case "":
+			output.Operation = DRY_RUN_OPERATION_MODIFY
+			output.Detail = fmt.Sprintf("attach disk to instance[%s] with delete_with_instance=%v", storage.InstanceId, getStorageDeleteWithInstance(storage))
+		default:
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("storage is attached to another instance[%s]", *disk.InstanceId))
+		}
+	})
+}
+
+type StorageDetachAction struct {
+	StorageAction
+}
+
+func (action *StorageDetachAction) Do(input interface{}) (interface{}, error) {
+	storages, _ := input.(StorageInputs)
+	outputs := StorageOutputs{}
+
+	for _, storage := range storages.Inputs {
+		client, err := createStorageClient(storage.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+		disk, found, err := queryStorageById(client, storage.Id)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, fmt.Errorf("storage[%s] not found", storage.Id)
+		}
+		output, err := detachStorage(client, storage, disk)
+		if err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
 	}
 
 	return &outputs, nil
 }
 
-func (action *StorageTerminateAction) detachStorage(storage *StorageInput) error {
-	paramsMap, err := GetMapFromProviderParams(storage.ProviderParams)
-	client, _ := CreateCbsClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+func (action *StorageDetachAction) DryRun(input interface{}) (interface{}, error) {
+	storages, _ := input.(StorageInputs)
+	return dryRunStorageChange(storages, func(storage StorageInput, disk *cbs.Disk, output *DryRunOutput) {
+		if stringValue(disk.DiskState) == STORAGE_STATE_UNATTACHED {
+			output.Detail = "disk already detached"
+			return
+		}
+		output.Operation = DRY_RUN_OPERATION_MODIFY
+		output.Detail = fmt.Sprintf("detach disk from instance[%s]", stringValue(disk.InstanceId))
+	})
+}
 
-	request := cbs.NewDetachDisksRequest()
-	request.DiskIds = []*string{&storage.Id}
-	response, err := client.DetachDisks(request)
+type StorageResizeAction struct {
+	StorageAction
+}
+
+func (action *StorageResizeAction) CheckParam(input interface{}) error {
+	if err := action.StorageAction.CheckParam(input); err != nil {
+		return err
+	}
+
+	storages, _ := input.(StorageInputs)
+	for _, storage := range storages.Inputs {
+		if storage.DiskSize == 0 {
+			return errors.New("input disk_size is empty")
+		}
+	}
+	return nil
+}
+
+func (action *StorageResizeAction) Do(input interface{}) (interface{}, error) {
+	storages, _ := input.(StorageInputs)
+	outputs := StorageOutputs{}
+
+	for _, storage := range storages.Inputs {
+		output, err := action.resizeStorage(storage)
+		if err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+
+	return &outputs, nil
+}
+
+//resizeStorage returns at once if the disk is already in the size, qcloud disks can't be shrunk
+func (action *StorageResizeAction) resizeStorage(storage StorageInput) (StorageOutput, error) {
+	client, err := createStorageClient(storage.ProviderParams)
+	if err != nil {
+		return StorageOutput{}, err
+	}
+
+	disk, found, err := queryStorageById(client, storage.Id)
 	if err != nil {
-		return fmt.Errorf("detach storage(id = %v) in cloud meet error = %v", storage.Id, err)
+		return StorageOutput{}, err
+	}
+	if !found {
+		return StorageOutput{}, fmt.Errorf("storage[%s] not found", storage.Id)
+	}
+	currentSize := uint64(0)
+	if disk.DiskSize != nil {
+		currentSize = *disk.DiskSize
+	}
+	if currentSize == storage.DiskSize {
+		logrus.Infof("storage[%s] is already %dGB", storage.Id, storage.DiskSize)
+		return newStorageOutput(storage.Guid, "", disk), nil
+	}
+	if currentSize > storage.DiskSize {
+		return StorageOutput{}, fmt.Errorf("storage[%s] can't be shrunk from %dGB to %dGB", storage.Id, currentSize, storage.DiskSize)
+	}
+
+	request := cbs.NewResizeDiskRequest()
+	request.DiskId = &storage.Id
+	request.DiskSize = &storage.DiskSize
+	response, err := client.ResizeDisk(request)
+	if err != nil {
+		return StorageOutput{}, fmt.Errorf("resize storage(id = %v) in cloud meet error = %v", storage.Id, err)
+	}
+	logrus.Infof("resize storage request id = %v", *response.Response.RequestId)
+
+	disk, err = waitStorageUntil(client, storage.Id, STORAGE_WAIT_TIMEOUT, func(disk *cbs.Disk) bool {
+		return disk != nil && disk.DiskSize != nil && *disk.DiskSize == storage.DiskSize && stringValue(disk.DiskState) != STORAGE_STATE_EXPANDING
+	})
+	if err != nil {
+		return StorageOutput{}, err
+	}
+	return newStorageOutput(storage.Guid, *response.Response.RequestId, disk), nil
+}
+
+func (action *StorageResizeAction) DryRun(input interface{}) (interface{}, error) {
+	storages, _ := input.(StorageInputs)
+	return dryRunStorageChange(storages, func(storage StorageInput, disk *cbs.Disk, output *DryRunOutput) {
+		currentSize := uint64(0)
+		if disk.DiskSize != nil {
+			currentSize = *disk.DiskSize
+		}
+		switch {
+		case currentSize == storage.DiskSize:
+			output.Detail = fmt.Sprintf("disk already %dGB", storage.DiskSize)
+		case currentSize > storage.DiskSize:
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("storage can't be shrunk from %dGB to %dGB", currentSize, storage.DiskSize))
+		default:
+			output.Operation = DRY_RUN_OPERATION_MODIFY
+			output.Detail = fmt.Sprintf("expand disk from %dGB to %dGB", currentSize, storage.DiskSize)
+		}
+	})
+}
+
+type StorageFindOrphansAction struct {
+	StorageAction
+}
+
+//the disks are only reported unless delete_older_than_days is given
+func (action *StorageFindOrphansAction) CheckParam(input interface{}) error {
+	storages, ok := input.(StorageInputs)
+	if !ok {
+		return INVALID_PARAMETERS
+	}
+
+	for _, storage := range storages.Inputs {
+		if storage.DeleteOlderThanDays < 0 {
+			return fmt.Errorf("invalid delete_older_than_days(%d)", storage.DeleteOlderThanDays)
+		}
+		if storage.IncludePrepaid != "" {
+			if _, err := strconv.ParseBool(storage.IncludePrepaid); err != nil {
+				return fmt.Errorf("invalid include_prepaid(%s)", storage.IncludePrepaid)
+			}
+		}
 	}
-	logrus.Infof("detach storage request id = %v", response.Response.RequestId)
 	return nil
 }
 
-func (action *StorageTerminateAction) terminateStorage(storage *StorageInput) (*StorageOutput, error) {
-	paramsMap, _ := GetMapFromProviderParams(storage.ProviderParams)
+//orphanStorage is the cbs disk with the instance it was last attached to, which the vendored sdk doesn't know yet
+type orphanStorage struct {
+	*cbs.Disk
+	LastAttachInsId *string `json:"LastAttachInsId,omitempty" name:"LastAttachInsId"`
+}
 
-	client, _ := CreateCbsClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+type describeOrphanStoragesResponse struct {
+	*tchttp.BaseResponse
+	Response *struct {
+		TotalCount *uint64          `json:"TotalCount,omitempty" name:"TotalCount"`
+		DiskSet    []*orphanStorage `json:"DiskSet,omitempty" name:"DiskSet"`
+		RequestId  *string          `json:"RequestId,omitempty" name:"RequestId"`
+	} `json:"Response"`
+}
 
-	request := cbs.NewTerminateDisksRequest()
-	request.DiskIds = []*string{&storage.Id}
+//getStorageOrphanRegions returns the regions of the input, the region of provider_params without them
+func getStorageOrphanRegions(storage StorageInput) []string {
+	if regions := splitCommaValues(storage.Regions); len(regions) > 0 {
+		return regions
+	}
+	paramsMap, _ := GetMapFromProviderParams(storage.ProviderParams)
+	return []string{paramsMap["Region"]}
+}
 
-	tryTimes := 10
+//describeOrphanStorages pages over DescribeDisks until all the unattached data disks of the region are returned,
+//the oldest first
+func describeOrphanStorages(client CbsAPI) ([]*orphanStorage, string, error) {
+	request := cbs.NewDescribeDisksRequest()
+	request.Filters = []*cbs.Filter{
+		newCbsFilter("disk-usage", "DATA_DISK"),
+		newCbsFilter("disk-state", STORAGE_STATE_UNATTACHED),
+	}
+	request.Limit = common.Uint64Ptr(STORAGE_DESCRIBE_PAGE_SIZE)
+
+	disks := []*orphanStorage{}
 	requestId := ""
-	for i := 1; i <= tryTimes; i++ {
-		time.Sleep(time.Duration(5) * time.Second)
+	for offset := uint64(0); ; {
+		request.Offset = common.Uint64Ptr(offset)
+		response := &describeOrphanStoragesResponse{BaseResponse: &tchttp.BaseResponse{}}
+		if err := client.Send(request, response); err != nil {
+			logrus.Errorf("cbs DescribeDisks meet err=%v", err)
+			return nil, "", err
+		}
 
-		response, err := client.TerminateDisks(request)
+		requestId = *response.Response.RequestId
+		disks = append(disks, response.Response.DiskSet...)
+		offset += uint64(len(response.Response.DiskSet))
+		if len(response.Response.DiskSet) == 0 || offset >= *response.Response.TotalCount {
+			break
+		}
+	}
+
+	sort.SliceStable(disks, func(i, j int) bool {
+		return stringValue(disks[i].CreateTime) < stringValue(disks[j].CreateTime)
+	})
+	return disks, requestId, nil
+}
+
+//describeStorageDetachTimes returns the time each disk was detached last, disks never detached are left out
+func describeStorageDetachTimes(client CbsAPI, diskIds []string) (map[string]time.Time, error) {
+	detachTimes := map[string]time.Time{}
+	for start := 0; start < len(diskIds); start += STORAGE_OPERATION_LOG_MAX_DISK_COUNT {
+		end := start + STORAGE_OPERATION_LOG_MAX_DISK_COUNT
+		if end > len(diskIds) {
+			end = len(diskIds)
+		}
+		request := cbs.NewDescribeDiskOperationLogsRequest()
+		request.Filters = []*cbs.Filter{newCbsFilter("disk-id", diskIds[start:end]...)}
+		response, err := client.DescribeDiskOperationLogs(request)
 		if err != nil {
-			if i == tryTimes {
-				logrus.Errorf("terminate storage(id = %v) meet error = %v, try times = %v",
-					storage.Id, err, i)
-			} else {
-				logrus.Infof("waiting for storage(id = %v) to be detached, try times = %v", storage.Id, i)
+			logrus.Errorf("cbs DescribeDiskOperationLogs meet err=%v", err)
+			return nil, err
+		}
+
+		for _, log := range response.Response.DiskOperationLogSet {
+			if stringValue(log.Operation) != STORAGE_OPERATION_DETACH || stringValue(log.OperationState) != STORAGE_OPERATION_STATE_SUCCESS {
+				continue
+			}
+			detachTime, err := time.ParseInLocation(STORAGE_TIME_LAYOUT, stringValue(log.StartTime), storageTimeLocation)
+			if err != nil {
+				return nil, fmt.Errorf("storage[%s] has invalid detach time(%s)", stringValue(log.DiskId), stringValue(log.StartTime))
+			}
+			if last, found := detachTimes[stringValue(log.DiskId)]; !found || detachTime.After(last) {
+				detachTimes[stringValue(log.DiskId)] = detachTime
 			}
-			continue
 		}
-		requestId = *response.Response.RequestId
-		logrus.Infof("terminate storage request id = %v", response.Response.RequestId)
-		break
 	}
+	return detachTimes, nil
+}
 
-	output := StorageOutput{}
-	output.Guid = storage.Guid
-	output.RequestId = requestId
-	output.Id = storage.Id
+//getStorageUnattachedDays returns the whole days since the disk was detached last, or created if it was never attached
+func getStorageUnattachedDays(disk *orphanStorage, detachTime time.Time, now time.Time) (int64, error) {
+	unattachedTime, err := time.ParseInLocation(STORAGE_TIME_LAYOUT, stringValue(disk.CreateTime), storageTimeLocation)
+	if err != nil {
+		return 0, fmt.Errorf("storage[%s] has invalid create time(%s)", stringValue(disk.DiskId), stringValue(disk.CreateTime))
+	}
+	if detachTime.After(unattachedTime) {
+		unattachedTime = detachTime
+	}
+	return int64(now.Sub(unattachedTime).Hours() / 24), nil
+}
 
-	return &output, nil
+func newStorageOrphanOutput(guid string, requestId string, region string, disk *orphanStorage, unattachedDays int64) StorageOutput {
+	output := newStorageOutput(guid, requestId, disk.Disk)
+	output.Region = region
+	output.DiskName = stringValue(disk.DiskName)
+	output.DiskType = stringValue(disk.DiskType)
+	output.DiskChargeType = stringValue(disk.DiskChargeType)
+	output.CreatedTime = stringValue(disk.CreateTime)
+	output.UnattachedDays = strconv.FormatInt(unattachedDays, 10)
+	output.LastInstanceId = stringValue(disk.LastAttachInsId)
+	output.Deleted = "false"
+	return output
 }
 
-func queryStorageInfo(client *cbs.Client, input *StorageInput) (*StorageOutput, bool, error) {
-	output := StorageOutput{}
+//findOrphanStorages reports the unattached disks of the region, with the ones unattached long enough to be deleted
+func findOrphanStorages(client CbsAPI, storage StorageInput, region string, now time.Time) ([]StorageOutput, []string, error) {
+	disks, requestId, err := describeOrphanStorages(client)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	request := cbs.NewDescribeDisksRequest()
-	request.DiskIds = append(request.DiskIds, &input.Id)
-	response, err := client.DescribeDisks(request)
+	diskIds := []string{}
+	for _, disk := range disks {
+		diskIds = append(diskIds, stringValue(disk.DiskId))
+	}
+	detachTimes, err := describeStorageDetachTimes(client, diskIds)
 	if err != nil {
-		return nil, false, err
+		return nil, nil, err
 	}
 
-	if len(response.Response.DiskSet) == 0 {
-		return nil, false, nil
+	includePrepaid, _ := strconv.ParseBool(storage.IncludePrepaid)
+	outputs := []StorageOutput{}
+	expiredIds := []string{}
+	for _, disk := range disks {
+		unattachedDays, err := getStorageUnattachedDays(disk, detachTimes[stringValue(disk.DiskId)], now)
+		if err != nil {
+			return nil, nil, err
+		}
+		outputs = append(outputs, newStorageOrphanOutput(storage.Guid, requestId, region, disk, unattachedDays))
+		if stringValue(disk.DiskChargeType) == CHARGE_TYPE_PREPAID && !includePrepaid {
+			continue
+		}
+		if storage.DeleteOlderThanDays > 0 && unattachedDays >= storage.DeleteOlderThanDays {
+			expiredIds = append(expiredIds, stringValue(disk.DiskId))
+		}
 	}
+	logrus.Infof("find orphans of guid[%s] found %d unattached disks in region[%s], %d of them to be deleted", storage.Guid, len(outputs), region, len(expiredIds))
+	return outputs, expiredIds, nil
+}
 
-	if len(response.Response.DiskSet) > 1 {
-		logrus.Errorf("query storage disk id=%s info find more than 1", input.Id)
-		return nil, false, fmt.Errorf("query storage disk id=%s info find more than 1", input.Id)
+//terminateOrphanStorages terminates the disks and waits until they are gone or in the recycle bin
+func terminateOrphanStorages(client CbsAPI, diskIds []string) error {
+	for start := 0; start < len(diskIds); start += STORAGE_TERMINATE_MAX_COUNT {
+		end := start + STORAGE_TERMINATE_MAX_COUNT
+		if end > len(diskIds) {
+			end = len(diskIds)
+		}
+		request := cbs.NewTerminateDisksRequest()
+		request.DiskIds = common.StringPtrs(diskIds[start:end])
+		response, err := client.TerminateDisks(request)
+		if err != nil {
+			return fmt.Errorf("terminate orphan storages%v meet error = %v", diskIds[start:end], err)
+		}
+		logrus.Infof("terminate orphan storages%v request id = %v", diskIds[start:end], *response.Response.RequestId)
 	}
 
-	output.Guid = input.Guid
-	output.Id = input.Id
-	output.RequestId = *response.Response.RequestId
+	_, err := waitStoragesUntil(client, diskIds, func(disks []*cbs.Disk) bool {
+		for _, disk := range disks {
+			if stringValue(disk.DiskState) != STORAGE_STATE_TORECYCLE {
+				return false
+			}
+		}
+		return true
+	})
+	return err
+}
 
-	return &output, true, nil
+func (action *StorageFindOrphansAction) Do(input interface{}) (interface{}, error) {
+	storages, _ := input.(StorageInputs)
+	outputs := StorageOutputs{}
+	now := time.Now()
+
+	for _, storage := range storages.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(storage.ProviderParams)
+		for _, region := range getStorageOrphanRegions(storage) {
+			client, err := CreateCbsClient(region, paramsMap["SecretID"], paramsMap["SecretKey"])
+			if err != nil {
+				return nil, err
+			}
+
+			orphans, expiredIds, err := findOrphanStorages(client, storage, region, now)
+			if err != nil {
+				return nil, err
+			}
+			if len(expiredIds) > 0 {
+				if err = terminateOrphanStorages(client, expiredIds); err != nil {
+					return nil, err
+				}
+			}
+
+			deleted := map[string]bool{}
+			for _, diskId := range expiredIds {
+				deleted[diskId] = true
+			}
+			for _, orphan := range orphans {
+				if deleted[orphan.Id] {
+					orphan.Deleted = "true"
+				}
+				outputs.Outputs = append(outputs.Outputs, orphan)
+			}
+		}
+	}
+	return &outputs, nil
+}
+
+//DryRun previews which orphans would be deleted, the others are only reported
+func (action *StorageFindOrphansAction) DryRun(input interface{}) (interface{}, error) {
+	storages, _ := input.(StorageInputs)
+	outputs := DryRunOutputs{}
+	now := time.Now()
+
+	for _, storage := range storages.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(storage.ProviderParams)
+		for _, region := range getStorageOrphanRegions(storage) {
+			client, err := CreateCbsClient(region, paramsMap["SecretID"], paramsMap["SecretKey"])
+			if err != nil {
+				return nil, err
+			}
+
+			orphans, expiredIds, err := findOrphanStorages(client, storage, region, now)
+			if err != nil {
+				return nil, err
+			}
+			expired := map[string]bool{}
+			for _, diskId := range expiredIds {
+				expired[diskId] = true
+			}
+			for _, orphan := range orphans {
+				detail := fmt.Sprintf("%s disk size=%sGB in region[%s] unattached for %s days", orphan.DiskChargeType, orphan.DiskSize, region, orphan.UnattachedDays)
+				if orphan.LastInstanceId != "" {
+					detail += fmt.Sprintf(", last attached to instance[%s]", orphan.LastInstanceId)
+				}
+				if expired[orphan.Id] {
+					outputs.Outputs = append(outputs.Outputs, newDryRunOutput(storage.Guid, orphan.Id, true, DRY_RUN_OPERATION_DELETE, "terminate "+detail))
+				} else {
+					outputs.Outputs = append(outputs.Outputs, newDryRunOutput(storage.Guid, orphan.Id, true, DRY_RUN_OPERATION_NONE, "keep "+detail))
+				}
+			}
+		}
+	}
+	return &outputs, nil
 }
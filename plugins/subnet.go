@@ -4,13 +4,29 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"strings"
+	"sync"
 
 	"github.com/sirupsen/logrus"
-	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
-	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
+	tchttp "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/http"
 	vpc "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/vpc/v20170312"
 )
 
+const (
+	SUBNET_IPV6_PREFIX_LENGTH = 64
+
+	//the prefix lengths qcloud accepts for an ipv4 subnet
+	SUBNET_MIN_PREFIX_LENGTH = 16
+	SUBNET_MAX_PREFIX_LENGTH = 28
+)
+
+//subnetAllocationMutexes serializes the cidr allocation and creation of subnets in the same vpc,
+//so concurrent requests in this process don't pick the same free block
+var (
+	subnetAllocationMutex   sync.Mutex
+	subnetAllocationMutexes = make(map[string]*sync.Mutex)
+)
+
 var SubnetActions = make(map[string]Action)
 
 func init() {
@@ -18,15 +34,11 @@ func init() {
 	SubnetActions["terminate"] = new(SubnetTerminateAction)
 	SubnetActions["create-with-routetable"] = new(CreateSubnetWithRouteTableAction)
 	SubnetActions["terminate-with-routetable"] = new(TerminateSubnetWithRouteTableAction)
+	SubnetActions["assign-ipv6"] = new(SubnetAssignIpv6Action)
 }
 
-func CreateSubnetClient(region, secretId, secretKey string) (client *vpc.Client, err error) {
-	credential := common.NewCredential(secretId, secretKey)
-
-	clientProfile := profile.NewClientProfile()
-	clientProfile.HttpProfile.Endpoint = "vpc.tencentcloudapi.com"
-
-	return vpc.NewClient(credential, region, clientProfile)
+func CreateSubnetClient(region, secretId, secretKey string) (VpcAPI, error) {
+	return QcloudClientFactory.NewVpcClient(region, secretId, secretKey)
 }
 
 type SubnetInputs struct {
@@ -39,8 +51,11 @@ type SubnetInput struct {
 	Id             string `json:"id,omitempty"`
 	Name           string `json:"name,omitempty"`
 	CidrBlock      string `json:"cidr_block,omitempty"`
+	PrefixLength   int64  `json:"prefix_length,omitempty"`
+	Ipv6CidrBlock  string `json:"ipv6_cidr_block,omitempty"`
 	VpcId          string `json:"vpc_id,omitempty"`
 	RouteTableId   string `json:"route_table_id,omitempty"`
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
 }
 
 type SubnetOutputs struct {
@@ -48,10 +63,12 @@ type SubnetOutputs struct {
 }
 
 type SubnetOutput struct {
-	RequestId    string `json:"request_id,omitempty"`
-	Guid         string `json:"guid,omitempty"`
-	Id           string `json:"id,omitempty"`
-	RouteTableId string `json:"route_table_id,omitempty"`
+	RequestId     string `json:"request_id,omitempty"`
+	Guid          string `json:"guid,omitempty"`
+	Id            string `json:"id,omitempty"`
+	RouteTableId  string `json:"route_table_id,omitempty"`
+	CidrBlock     string `json:"cidr_block,omitempty"`
+	Ipv6CidrBlock string `json:"ipv6_cidr_block,omitempty"`
 }
 
 type SubnetPlugin struct {
@@ -92,15 +109,70 @@ func (action *SubnetCreateAction) CheckParam(input interface{}) error {
 		if subnet.Name == "" {
 			return errors.New("subnetCreateAtion input name is empty")
 		}
-		if _, _, err := net.ParseCIDR(subnet.CidrBlock); err != nil {
-			return fmt.Errorf("subnetCreateAtion invalid cidr_block [%s]", subnet.CidrBlock)
+		if subnet.CidrBlock != "" && subnet.PrefixLength != 0 {
+			return errors.New("subnetCreateAtion input cidr_block can't be used together with prefix_length")
+		}
+		if subnet.CidrBlock == "" && subnet.PrefixLength == 0 {
+			return errors.New("subnetCreateAtion input cidr_block and prefix_length are both empty")
+		}
+		if subnet.PrefixLength != 0 && (subnet.PrefixLength < SUBNET_MIN_PREFIX_LENGTH || subnet.PrefixLength > SUBNET_MAX_PREFIX_LENGTH) {
+			return fmt.Errorf("subnetCreateAtion invalid prefix_length [%d], it must be in [%d, %d]", subnet.PrefixLength, SUBNET_MIN_PREFIX_LENGTH, SUBNET_MAX_PREFIX_LENGTH)
+		}
+		if subnet.CidrBlock != "" {
+			if _, _, err := net.ParseCIDR(subnet.CidrBlock); err != nil {
+				return fmt.Errorf("subnetCreateAtion invalid cidr_block [%s]", subnet.CidrBlock)
+			}
+		}
+		if subnet.Ipv6CidrBlock != "" && !isIpv6SubnetCidr(subnet.Ipv6CidrBlock) {
+			return fmt.Errorf("subnetCreateAtion invalid ipv6_cidr_block [%s], it must be a /%d ipv6 cidr", subnet.Ipv6CidrBlock, SUBNET_IPV6_PREFIX_LENGTH)
+		}
+		if err := checkSubnetInVpcCidrBlocks(subnet); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
-func (action *SubnetCreateAction) createSubnet(subnet *SubnetInput) (*SubnetOutput, error) {
+//checkSubnetInVpcCidrBlocks checks the cidrs of the subnet against the primary, secondary and ipv6 cidrs of its vpc,
+//a missing vpc is left to CreateSubnet and DryRun to report
+func checkSubnetInVpcCidrBlocks(subnet SubnetInput) error {
+	client, err := createVpcClientByProviderParams(subnet.ProviderParams)
+	if err != nil {
+		return err
+	}
+	item, found, err := queryVpcById(client, subnet.VpcId)
+	if err != nil || !found {
+		return err
+	}
+
+	if subnet.CidrBlock != "" {
+		cidrBlocks := getVpcCidrBlocks(item)
+		inVpc := false
+		for _, cidrBlock := range cidrBlocks {
+			if isCidrContains(cidrBlock, subnet.CidrBlock) {
+				inVpc = true
+				break
+			}
+		}
+		if !inVpc {
+			return fmt.Errorf("cidr_block(%s) is not in the cidr blocks[%s] of vpc[%s]", subnet.CidrBlock, strings.Join(cidrBlocks, ","), subnet.VpcId)
+		}
+	}
+
+	if subnet.Ipv6CidrBlock != "" {
+		vpcIpv6CidrBlock := stringValue(item.Ipv6CidrBlock)
+		if vpcIpv6CidrBlock == "" {
+			return fmt.Errorf("vpc[%s] has no ipv6 cidr block", subnet.VpcId)
+		}
+		if !isCidrContains(vpcIpv6CidrBlock, subnet.Ipv6CidrBlock) {
+			return fmt.Errorf("ipv6_cidr_block(%s) is not in the ipv6 cidr block(%s) of vpc[%s]", subnet.Ipv6CidrBlock, vpcIpv6CidrBlock, subnet.VpcId)
+		}
+	}
+	return nil
+}
+
+func (action *SubnetCreateAction) createSubnet(subnet *SubnetInput, idempotencyKey string) (*SubnetOutput, error) {
 	paramsMap, _ := GetMapFromProviderParams(subnet.ProviderParams)
 	client, err := CreateSubnetClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
 	if err != nil {
@@ -108,6 +180,9 @@ func (action *SubnetCreateAction) createSubnet(subnet *SubnetInput) (*SubnetOutp
 	}
 
 	//check resource exist
+	if subnet.Id, err = getIdempotencyCreatedId(idempotencyKey, subnet.Id); err != nil {
+		return nil, err
+	}
 	if subnet.Id != "" {
 		querysubnetresponse, flag, err := querySubnetsInfo(client, subnet)
 		if err != nil && flag == false {
@@ -119,10 +194,20 @@ func (action *SubnetCreateAction) createSubnet(subnet *SubnetInput) (*SubnetOutp
 		}
 	}
 
+	cidrBlock := subnet.CidrBlock
+	if cidrBlock == "" {
+		unlock := lockSubnetAllocation(subnet.VpcId)
+		defer unlock()
+		if cidrBlock, err = allocateSubnetCidrBlockInVpc(client, subnet.VpcId, subnet.PrefixLength); err != nil {
+			return nil, err
+		}
+		logrus.Infof("allocate cidr block[%s] for subnet[%s] in vpc[%s]", cidrBlock, subnet.Name, subnet.VpcId)
+	}
+
 	request := vpc.NewCreateSubnetRequest()
 	request.VpcId = &subnet.VpcId
 	request.SubnetName = &subnet.Name
-	request.CidrBlock = &subnet.CidrBlock
+	request.CidrBlock = &cidrBlock
 	az := paramsMap["AvailableZone"]
 	request.Zone = &az
 
@@ -130,31 +215,215 @@ func (action *SubnetCreateAction) createSubnet(subnet *SubnetInput) (*SubnetOutp
 	if err != nil {
 		return nil, fmt.Errorf("failed to CreateSubnet, error=%s", err)
 	}
+	saveIdempotencyCreatedIds(idempotencyKey, *response.Response.RequestId, *response.Response.Subnet.SubnetId)
 
 	output := SubnetOutput{}
 	output.Guid = subnet.Guid
 	output.RequestId = *response.Response.RequestId
 	output.Id = *response.Response.Subnet.SubnetId
+	output.CidrBlock = cidrBlock
+
+	//the subnet is deleted if its ipv6 cidr can't be assigned, so that a retry creates it again
+	if subnet.Ipv6CidrBlock != "" {
+		if _, err = assignSubnetIpv6CidrBlock(client, subnet.VpcId, output.Id, subnet.Ipv6CidrBlock); err != nil {
+			terminateAction := SubnetTerminateAction{}
+			if _, terminateErr := terminateAction.terminateSubnet(&SubnetInput{ProviderParams: subnet.ProviderParams, Id: output.Id}); terminateErr != nil {
+				logrus.Errorf("delete subnet[%s] meet err=%v", output.Id, terminateErr)
+			}
+			return nil, err
+		}
+		output.Ipv6CidrBlock = subnet.Ipv6CidrBlock
+	}
 
 	return &output, nil
 }
 
+//lockSubnetAllocation locks the subnet allocation of the vpc and returns the unlock function
+func lockSubnetAllocation(vpcId string) func() {
+	subnetAllocationMutex.Lock()
+	mutex, found := subnetAllocationMutexes[vpcId]
+	if !found {
+		mutex = &sync.Mutex{}
+		subnetAllocationMutexes[vpcId] = mutex
+	}
+	subnetAllocationMutex.Unlock()
+
+	mutex.Lock()
+	return mutex.Unlock
+}
+
+func allocateSubnetCidrBlockInVpc(client VpcAPI, vpcId string, prefixLength int64) (string, error) {
+	item, err := getVpcById(client, vpcId)
+	if err != nil {
+		return "", err
+	}
+	subnets, err := describeVpcSubnets(client, vpcId)
+	if err != nil {
+		return "", err
+	}
+	subnetCidrBlocks := []string{}
+	for _, subnet := range subnets {
+		subnetCidrBlocks = append(subnetCidrBlocks, stringValue(subnet.CidrBlock))
+	}
+	return allocateSubnetCidrBlock(getVpcCidrBlocks(item), subnetCidrBlocks, prefixLength)
+}
+
+//allocateSubnetCidrBlock returns the lowest block of the prefix length which doesn't overlap the subnets,
+//the vpc cidrs are tried in order so the primary cidr fills up before the secondary ones
+func allocateSubnetCidrBlock(vpcCidrBlocks []string, subnetCidrBlocks []string, prefixLength int64) (string, error) {
+	for _, vpcCidrBlock := range vpcCidrBlocks {
+		freeCidrBlocks, err := subtractCidrs(vpcCidrBlock, subnetCidrBlocks)
+		if err != nil {
+			return "", err
+		}
+		//the free cidrs are aligned, so the first one long enough starts the lowest free block
+		for _, freeCidrBlock := range freeCidrBlocks {
+			ip, ipNet, _ := net.ParseCIDR(freeCidrBlock)
+			if ones, _ := ipNet.Mask.Size(); ip.To4() != nil && int64(ones) <= prefixLength {
+				return fmt.Sprintf("%s/%d", ip.String(), prefixLength), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no free /%d cidr block left in the cidr blocks[%s]", prefixLength, strings.Join(vpcCidrBlocks, ","))
+}
+
+func assignSubnetIpv6CidrBlock(client VpcAPI, vpcId string, subnetId string, ipv6CidrBlock string) (string, error) {
+	request := vpc.NewAssignIpv6SubnetCidrBlockRequest()
+	request.VpcId = &vpcId
+	request.Ipv6SubnetCidrBlocks = []*vpc.Ipv6SubnetCidrBlock{{SubnetId: &subnetId, Ipv6CidrBlock: &ipv6CidrBlock}}
+	response, err := client.AssignIpv6SubnetCidrBlock(request)
+	if err != nil {
+		logrus.Errorf("vpc AssignIpv6SubnetCidrBlock meet err=%v", err)
+		return "", err
+	}
+	logrus.Infof("Assign ipv6 cidr block[%v] to subnet[%v] has been submitted, RequestID is [%v]", ipv6CidrBlock, subnetId, *response.Response.RequestId)
+	return *response.Response.RequestId, nil
+}
+
+//querySubnetById returns false if the subnet is not found
+func querySubnetById(client VpcAPI, subnetId string) (*qcloudSubnet, bool, error) {
+	request := vpc.NewDescribeSubnetsRequest()
+	request.SubnetIds = []*string{&subnetId}
+	response := &describeSubnetsResponse{BaseResponse: &tchttp.BaseResponse{}}
+	if err := client.Send(request, response); err != nil {
+		logrus.Errorf("vpc DescribeSubnets meet err=%v", err)
+		return nil, false, err
+	}
+	if len(response.Response.SubnetSet) == 0 {
+		return nil, false, nil
+	}
+	return response.Response.SubnetSet[0], true, nil
+}
+
 func (action *SubnetCreateAction) Do(input interface{}) (interface{}, error) {
 	subnets, _ := input.(SubnetInputs)
 	outputs := SubnetOutputs{}
 	for _, subnet := range subnets.Inputs {
-		output, err := action.createSubnet(&subnet)
+		output := SubnetOutput{}
+		idempotencyKey := getIdempotencyKey("subnet-create", subnet.Guid, subnet.IdempotencyKey, subnet)
+		err := runWithIdempotencyKey(idempotencyKey, &output, func() (interface{}, error) {
+			return action.createSubnet(&subnet, idempotencyKey)
+		})
 		if err != nil {
 			return nil, err
 		}
 
-		outputs.Outputs = append(outputs.Outputs, *output)
+		outputs.Outputs = append(outputs.Outputs, output)
 	}
 
 	logrus.Infof("all subnet = %v are created", subnets)
 	return &outputs, nil
 }
 
+func isIpv4Cidr(cidr string) bool {
+	ip, _, err := net.ParseCIDR(cidr)
+	return err == nil && ip.To4() != nil
+}
+
+//qcloud splits the /56 ipv6 cidr of a vpc into /64 subnets
+func isIpv6SubnetCidr(cidr string) bool {
+	ip, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil || ip.To4() != nil {
+		return false
+	}
+	ones, _ := ipNet.Mask.Size()
+	return ones == SUBNET_IPV6_PREFIX_LENGTH
+}
+
+//dryRunCreateSubnet reports whether the subnet exists, and if not, whether its vpc exists and its cidr overlaps others
+func dryRunCreateSubnet(client VpcAPI, subnet *SubnetInput, availableZone string) (*DryRunOutput, error) {
+	exist := false
+	if subnet.Id != "" {
+		_, flag, err := querySubnetsInfo(client, subnet)
+		if err != nil {
+			return nil, err
+		}
+		exist = flag
+	}
+
+	cidrBlock := subnet.CidrBlock
+	if cidrBlock == "" {
+		cidrBlock = fmt.Sprintf("/%d", subnet.PrefixLength)
+	}
+	detail := fmt.Sprintf("create subnet name=%s cidr=%s in vpc[%s] zone[%s]", subnet.Name, cidrBlock, subnet.VpcId, availableZone)
+	output := newCreateDryRunOutput(subnet.Guid, subnet.Id, exist, detail)
+	if exist {
+		return &output, nil
+	}
+
+	item, vpcExist, err := queryVpcById(client, subnet.VpcId)
+	if err != nil {
+		return nil, err
+	}
+	if !vpcExist {
+		output.Conflicts = append(output.Conflicts, fmt.Sprintf("vpc[%s] not found", subnet.VpcId))
+		return &output, nil
+	}
+
+	request := vpc.NewDescribeSubnetsRequest()
+	filterName := "vpc-id"
+	request.Filters = []*vpc.Filter{&vpc.Filter{Name: &filterName, Values: []*string{&subnet.VpcId}}}
+	response, err := client.DescribeSubnets(request)
+	if err != nil {
+		return nil, err
+	}
+	subnetCidrBlocks := []string{}
+	for _, existSubnet := range response.Response.SubnetSet {
+		if isCidrOverlap(subnet.CidrBlock, *existSubnet.CidrBlock) {
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("cidr overlaps with subnet[%s] cidr=%s", *existSubnet.SubnetId, *existSubnet.CidrBlock))
+		}
+		subnetCidrBlocks = append(subnetCidrBlocks, *existSubnet.CidrBlock)
+	}
+	if subnet.CidrBlock == "" {
+		if cidrBlock, err = allocateSubnetCidrBlock(getVpcCidrBlocks(item), subnetCidrBlocks, subnet.PrefixLength); err != nil {
+			output.Conflicts = append(output.Conflicts, err.Error())
+		} else {
+			output.Detail = fmt.Sprintf("create subnet name=%s cidr=%s in vpc[%s] zone[%s]", subnet.Name, cidrBlock, subnet.VpcId, availableZone)
+		}
+	}
+	return &output, nil
+}
+
+func (action *SubnetCreateAction) DryRun(input interface{}) (interface{}, error) {
+	subnets, _ := input.(SubnetInputs)
+	outputs := DryRunOutputs{}
+	for _, subnet := range subnets.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(subnet.ProviderParams)
+		client, err := CreateSubnetClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+		if err != nil {
+			return nil, err
+		}
+
+		output, err := dryRunCreateSubnet(client, &subnet, paramsMap["AvailableZone"])
+		if err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, *output)
+	}
+
+	return &outputs, nil
+}
+
 type SubnetTerminateAction struct {
 }
 
@@ -215,7 +484,27 @@ func (action *SubnetTerminateAction) Do(input interface{}) (interface{}, error)
 	return &outputs, nil
 }
 
-func querySubnetsInfo(client *vpc.Client, input *SubnetInput) (*SubnetOutput, bool, error) {
+func (action *SubnetTerminateAction) DryRun(input interface{}) (interface{}, error) {
+	subnets, _ := input.(SubnetInputs)
+	outputs := DryRunOutputs{}
+	for _, subnet := range subnets.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(subnet.ProviderParams)
+		client, err := CreateSubnetClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+		if err != nil {
+			return nil, err
+		}
+
+		_, exist, err := querySubnetsInfo(client, &subnet)
+		if err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, newDeleteDryRunOutput(subnet.Guid, subnet.Id, exist, "delete subnet"))
+	}
+
+	return &outputs, nil
+}
+
+func querySubnetsInfo(client VpcAPI, input *SubnetInput) (*SubnetOutput, bool, error) {
 	output := SubnetOutput{}
 
 	request := vpc.NewDescribeSubnetsRequest()
@@ -237,6 +526,7 @@ func querySubnetsInfo(client *vpc.Client, input *SubnetInput) (*SubnetOutput, bo
 	output.Guid = input.Guid
 	output.Id = input.Id
 	output.RequestId = *response.Response.RequestId
+	output.CidrBlock = stringValue(response.Response.SubnetSet[0].CidrBlock)
 
 	return &output, true, nil
 }
@@ -281,7 +571,7 @@ func destroySubnetWithRouteTable(providerParams string, subnetId string, routeTa
 	return nil
 }
 
-func createSubnetWithRouteTable(input *SubnetInput) (*SubnetOutput, error) {
+func createSubnetWithRouteTable(input *SubnetInput, idempotencyKey string) (*SubnetOutput, error) {
 	var err error
 	output := &SubnetOutput{
 		Guid: input.Guid,
@@ -294,11 +584,13 @@ func createSubnetWithRouteTable(input *SubnetInput) (*SubnetOutput, error) {
 	}()
 
 	action := SubnetCreateAction{}
-	createSubnetOutput, err := action.createSubnet(input)
+	createSubnetOutput, err := action.createSubnet(input, idempotencyKey)
 	if err != nil {
 		return output, err
 	}
 	output.Id = createSubnetOutput.Id
+	output.CidrBlock = createSubnetOutput.CidrBlock
+	output.Ipv6CidrBlock = createSubnetOutput.Ipv6CidrBlock
 
 	//create routeTable
 	routeTableInput := RouteTableInput{
@@ -325,10 +617,45 @@ func (action *CreateSubnetWithRouteTableAction) Do(input interface{}) (interface
 	subnets, _ := input.(SubnetInputs)
 	outputs := SubnetOutputs{}
 	for _, subnet := range subnets.Inputs {
-		output, err := createSubnetWithRouteTable(&subnet)
+		output := SubnetOutput{}
+		idempotencyKey := getIdempotencyKey("subnet-create-with-routetable", subnet.Guid, subnet.IdempotencyKey, subnet)
+		err := runWithIdempotencyKey(idempotencyKey, &output, func() (interface{}, error) {
+			return createSubnetWithRouteTable(&subnet, idempotencyKey)
+		})
+		if err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+
+	return &outputs, nil
+}
+
+func (action *CreateSubnetWithRouteTableAction) DryRun(input interface{}) (interface{}, error) {
+	subnets, _ := input.(SubnetInputs)
+	outputs := DryRunOutputs{}
+	for _, subnet := range subnets.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(subnet.ProviderParams)
+		client, err := CreateSubnetClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+		if err != nil {
+			return nil, err
+		}
+
+		output, err := dryRunCreateSubnet(client, &subnet, paramsMap["AvailableZone"])
 		if err != nil {
 			return nil, err
 		}
+
+		routeTableExist := false
+		if subnet.RouteTableId != "" {
+			if routeTableExist, err = queryRouteTablesInfo(client, subnet.RouteTableId); err != nil {
+				return nil, err
+			}
+		}
+		if !routeTableExist {
+			output.Operation = DRY_RUN_OPERATION_CREATE
+			output.Detail = fmt.Sprintf("%s, create route table name=subnet-%s and associate it with subnet", output.Detail, subnet.Name)
+		}
 		outputs.Outputs = append(outputs.Outputs, *output)
 	}
 
@@ -373,3 +700,132 @@ func (action *TerminateSubnetWithRouteTableAction) Do(input interface{}) (interf
 	}
 	return outputs, nil
 }
+
+func (action *TerminateSubnetWithRouteTableAction) DryRun(input interface{}) (interface{}, error) {
+	subnets, _ := input.(SubnetInputs)
+	outputs := DryRunOutputs{}
+	for _, subnet := range subnets.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(subnet.ProviderParams)
+		client, err := CreateSubnetClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+		if err != nil {
+			return nil, err
+		}
+
+		_, subnetExist, err := querySubnetsInfo(client, &subnet)
+		if err != nil {
+			return nil, err
+		}
+		routeTableExist, err := queryRouteTablesInfo(client, subnet.RouteTableId)
+		if err != nil {
+			return nil, err
+		}
+
+		output := newDeleteDryRunOutput(subnet.Guid, subnet.Id, subnetExist || routeTableExist,
+			fmt.Sprintf("delete subnet(exist=%v) and route table[%s](exist=%v)", subnetExist, subnet.RouteTableId, routeTableExist))
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+
+	return &outputs, nil
+}
+
+//a subnet gets one /64 ipv6 cidr of the ipv6 cidr of its vpc
+type SubnetAssignIpv6Action struct {
+}
+
+func (action *SubnetAssignIpv6Action) ReadParam(param interface{}) (interface{}, error) {
+	createAction := SubnetCreateAction{}
+	return createAction.ReadParam(param)
+}
+
+func (action *SubnetAssignIpv6Action) CheckParam(input interface{}) error {
+	subnets, ok := input.(SubnetInputs)
+	if !ok {
+		return INVALID_PARAMETERS
+	}
+
+	for _, subnet := range subnets.Inputs {
+		if subnet.Id == "" {
+			return errors.New("input id is empty")
+		}
+		if subnet.VpcId == "" {
+			return errors.New("input vpc_id is empty")
+		}
+		if !isIpv6SubnetCidr(subnet.Ipv6CidrBlock) {
+			return fmt.Errorf("invalid ipv6_cidr_block(%s), it must be a /%d ipv6 cidr", subnet.Ipv6CidrBlock, SUBNET_IPV6_PREFIX_LENGTH)
+		}
+		if err := checkSubnetInVpcCidrBlocks(SubnetInput{ProviderParams: subnet.ProviderParams, VpcId: subnet.VpcId, Ipv6CidrBlock: subnet.Ipv6CidrBlock}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//getSubnetIpv6CidrBlockToAssign returns false if the subnet already has the ipv6 cidr of the input
+func getSubnetIpv6CidrBlockToAssign(client VpcAPI, subnet SubnetInput) (bool, error) {
+	existed, found, err := querySubnetById(client, subnet.Id)
+	if err != nil {
+		return false, err
+	}
+	if !found {
+		return false, fmt.Errorf("subnet[%s] not found", subnet.Id)
+	}
+	switch ipv6CidrBlock := stringValue(existed.Ipv6CidrBlock); ipv6CidrBlock {
+	case "":
+		return true, nil
+	case subnet.Ipv6CidrBlock:
+		return false, nil
+	default:
+		return false, fmt.Errorf("subnet[%s] already has ipv6 cidr block(%s)", subnet.Id, ipv6CidrBlock)
+	}
+}
+
+func (action *SubnetAssignIpv6Action) Do(input interface{}) (interface{}, error) {
+	subnets, _ := input.(SubnetInputs)
+	outputs := SubnetOutputs{}
+	for _, subnet := range subnets.Inputs {
+		client, err := createVpcClientByProviderParams(subnet.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		output := SubnetOutput{Guid: subnet.Guid, Id: subnet.Id, Ipv6CidrBlock: subnet.Ipv6CidrBlock}
+		toAssign, err := getSubnetIpv6CidrBlockToAssign(client, subnet)
+		if err != nil {
+			return nil, err
+		}
+		if !toAssign {
+			logrus.Infof("subnet[%s] already has ipv6 cidr block[%s]", subnet.Id, subnet.Ipv6CidrBlock)
+			outputs.Outputs = append(outputs.Outputs, output)
+			continue
+		}
+
+		if output.RequestId, err = assignSubnetIpv6CidrBlock(client, subnet.VpcId, subnet.Id, subnet.Ipv6CidrBlock); err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+func (action *SubnetAssignIpv6Action) DryRun(input interface{}) (interface{}, error) {
+	subnets, _ := input.(SubnetInputs)
+	outputs := DryRunOutputs{}
+	for _, subnet := range subnets.Inputs {
+		client, err := createVpcClientByProviderParams(subnet.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		output := newDryRunOutput(subnet.Guid, subnet.Id, true, DRY_RUN_OPERATION_NONE, "")
+		if toAssign, err := getSubnetIpv6CidrBlockToAssign(client, subnet); err != nil {
+			output.Conflicts = append(output.Conflicts, err.Error())
+		} else if !toAssign {
+			output.Detail = fmt.Sprintf("subnet already has ipv6 cidr block[%s]", subnet.Ipv6CidrBlock)
+		} else {
+			output.Operation = DRY_RUN_OPERATION_MODIFY
+			output.Detail = fmt.Sprintf("assign ipv6 cidr block[%s] to subnet", subnet.Ipv6CidrBlock)
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
@@ -0,0 +1,39 @@
+package plugins
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAllocateSubnetCidrBlock(t *testing.T) {
+	cases := []struct {
+		name         string
+		vpcCidrs     []string
+		subnetCidrs  []string
+		prefixLength int64
+		want         string
+		wantErr      string
+	}{
+		{"empty vpc", []string{"10.0.0.0/16"}, nil, 24, "10.0.0.0/24", ""},
+		{"next block", []string{"10.0.0.0/16"}, []string{"10.0.0.0/24", "10.0.1.0/24"}, 24, "10.0.2.0/24", ""},
+		{"fills the gap", []string{"10.0.0.0/16"}, []string{"10.0.0.0/24", "10.0.2.0/24"}, 24, "10.0.1.0/24", ""},
+		{"skips a larger subnet", []string{"10.0.0.0/16"}, []string{"10.0.0.0/20"}, 24, "10.0.16.0/24", ""},
+		{"aligns after a smaller subnet", []string{"10.0.0.0/16"}, []string{"10.0.0.0/28"}, 24, "10.0.1.0/24", ""},
+		{"smaller block in the gap", []string{"10.0.0.0/16"}, []string{"10.0.0.0/28", "10.0.0.32/27"}, 28, "10.0.0.16/28", ""},
+		{"secondary cidr", []string{"10.0.0.0/24", "10.1.0.0/16"}, []string{"10.0.0.0/25", "10.0.0.128/25"}, 24, "10.1.0.0/24", ""},
+		{"prefix longer than vpc cidr", []string{"10.0.0.0/24", "10.1.0.0/16"}, nil, 20, "10.1.0.0/20", ""},
+		{"full", []string{"10.0.0.0/24"}, []string{"10.0.0.0/25", "10.0.0.128/25"}, 26, "", "no free /26 cidr block left in the cidr blocks[10.0.0.0/24]"},
+	}
+	for _, c := range cases {
+		got, err := allocateSubnetCidrBlock(c.vpcCidrs, c.subnetCidrs, c.prefixLength)
+		if c.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("%s: err=%v,want %s", c.name, err, c.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("%s: allocateSubnetCidrBlock=%s,%v,want %s", c.name, got, err, c.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package plugins
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Catalogue struct {
+	Providers []ProviderCatalogue `json:"providers"`
+}
+
+type ProviderCatalogue struct {
+	Name     string             `json:"name"`
+	Versions []VersionCatalogue `json:"versions"`
+}
+
+type VersionCatalogue struct {
+	Version string   `json:"version"`
+	Plugins []string `json:"plugins"`
+}
+
+//api version is "v" followed by a positive number, such as v1,v2
+func parseApiVersion(version string) (int, error) {
+	if !strings.HasPrefix(version, "v") {
+		return 0, fmt.Errorf("invalid api version[%s]", version)
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(version, "v"))
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid api version[%s]", version)
+	}
+	return n, nil
+}
+
+//sortApiVersions returns the registered versions from low to high
+func sortApiVersions(versions map[string]map[string]Plugin) []string {
+	sorted := []string{}
+	for version := range versions {
+		sorted = append(sorted, version)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		n1, _ := parseApiVersion(sorted[i])
+		n2, _ := parseApiVersion(sorted[j])
+		return n1 < n2
+	})
+	return sorted
+}
+
+//GetCatalogue lists the supported api versions of each provider, and the plugins each version serves
+func GetCatalogue() *Catalogue {
+	pluginsMutex.Lock()
+	defer pluginsMutex.Unlock()
+
+	catalogue := Catalogue{}
+	providerNames := []string{}
+	for providerName := range providers {
+		providerNames = append(providerNames, providerName)
+	}
+	sort.Strings(providerNames)
+
+	for _, providerName := range providerNames {
+		providerCatalogue := ProviderCatalogue{Name: providerName}
+		//plugins of earlier versions are still served by the later ones
+		served := make(map[string]bool)
+		for _, version := range sortApiVersions(providers[providerName]) {
+			for name := range providers[providerName][version] {
+				served[name] = true
+			}
+			versionCatalogue := VersionCatalogue{Version: version}
+			for name := range served {
+				versionCatalogue.Plugins = append(versionCatalogue.Plugins, name)
+			}
+			sort.Strings(versionCatalogue.Plugins)
+			providerCatalogue.Versions = append(providerCatalogue.Versions, versionCatalogue)
+		}
+		catalogue.Providers = append(catalogue.Providers, providerCatalogue)
+	}
+
+	return &catalogue
+}
@@ -3,26 +3,63 @@ package plugins
 import (
 	"fmt"
 
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"math"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/WeBankPartners/wecube-plugins-qcloud/plugins/utils"
 	"github.com/sirupsen/logrus"
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
-	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
+	tchttp "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/http"
 	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+	vpc "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/vpc/v20170312"
 )
 
 const (
 	INSTANCE_STATE_RUNNING = "RUNNING"
+	INSTANCE_STATE_STOPPED = "STOPPED"
+
+	//the latest operation of an instance tells whether an async api like ResetInstancesPassword is done
+	INSTANCE_OPERATION_STATE_SUCCESS = "SUCCESS"
+	INSTANCE_OPERATION_STATE_FAILED  = "FAILED"
 )
 
 const (
 	QCLOUD_ENDPOINT_CVM              = "cvm.tencentcloudapi.com"
 	INSTANCE_CHARGE_TYPE_PREPAID     = "PREPAID"
 	RENEW_FLAG_NOTIFY_AND_AUTO_RENEW = "NOTIFY_AND_AUTO_RENEW"
+
+	RENEW_FLAG_NOTIFY_AND_MANUAL_RENEW         = "NOTIFY_AND_MANUAL_RENEW"
+	RENEW_FLAG_DISABLE_NOTIFY_AND_MANUAL_RENEW = "DISABLE_NOTIFY_AND_MANUAL_RENEW"
+
+	VM_DEFAULT_SYSTEM_DISK_TYPE           = "CLOUD_PREMIUM"
+	VM_DEFAULT_INTERNET_MAX_BANDWIDTH_OUT = 10
+	TAG_RESOURCE_TYPE_INSTANCE            = "instance"
+
+	//how many RunInstances calls one create request runs at the same time
+	VM_CREATE_CONCURRENCY = 5
+	//the most instances qcloud launches in one RunInstances call
+	VM_RUN_INSTANCES_MAX_COUNT = 100
+	//seconds to wait for one created vm to run, and more for each other vm launched together
+	VM_CREATE_WAIT_TIMEOUT              = 120
+	VM_CREATE_WAIT_TIMEOUT_PER_INSTANCE = 5
+	//the most instances qcloud returns in one DescribeInstances call
+	VM_DESCRIBE_PAGE_SIZE = 100
+
+	VM_STOP_TYPE_SOFT             = "SOFT"
+	VM_STOP_TYPE_HARD             = "HARD"
+	VM_STOP_TYPE_SOFT_FIRST       = "SOFT_FIRST"
+	VM_STOPPED_MODE_KEEP_CHARGING = "KEEP_CHARGING"
+	VM_STOPPED_MODE_STOP_CHARGING = "STOP_CHARGING"
+
+	INSTANCE_CHARGE_TYPE_POSTPAID_BY_HOUR = "POSTPAID_BY_HOUR"
+	VM_INSTANCE_TYPE_STATUS_SELL          = "SELL"
 )
 
 var (
@@ -49,6 +86,36 @@ type VmInput struct {
 	InstanceChargeType   string `json:"instance_charge_type,omitempty"`
 	InstanceChargePeriod int64  `json:"instance_charge_period,omitempty"`
 	InstancePrivateIp    string `json:"instance_private_ip,omitempty"`
+	IdempotencyKey       string `json:"idempotency_key,omitempty"`
+
+	//optional settings passed through to RunInstances
+	SystemDiskType          string `json:"system_disk_type,omitempty"`
+	DataDisks               string `json:"data_disks,omitempty"`
+	SecurityGroupIds        string `json:"security_group_ids,omitempty"`
+	KeyIds                  string `json:"key_ids,omitempty"`
+	HostName                string `json:"host_name,omitempty"`
+	UserData                string `json:"user_data,omitempty"`
+	ProjectId               int64  `json:"project_id,omitempty"`
+	Tags                    string `json:"tags,omitempty"`
+	InternetChargeType      string `json:"internet_charge_type,omitempty"`
+	InternetMaxBandwidthOut int64  `json:"internet_max_bandwidth_out,omitempty"`
+
+	//optional settings of start, stop and reboot
+	Wait        string `json:"wait,omitempty"`
+	StopType    string `json:"stop_type,omitempty"`
+	StoppedMode string `json:"stopped_mode,omitempty"`
+
+	//optional settings of prepaid vms
+	RenewFlag        string `json:"renew_flag,omitempty"`
+	ExpireWithinDays int64  `json:"expire_within_days,omitempty"`
+
+	//optional placement of new vms, the vms with zones are spread round-robin across the zones,
+	//each zone takes the subnet at the same place of subnet_id
+	Zone             string `json:"zone,omitempty"`
+	Zones            string `json:"zones,omitempty"`
+	PlacementGroupId string `json:"placement_group_id,omitempty"`
+	//the vms are moved to another zone of the region when their instance type is sold out in their zone
+	ZoneFallback string `json:"zone_fallback,omitempty"`
 }
 
 type VmOutputs struct {
@@ -64,6 +131,36 @@ type VmOutput struct {
 	Password          string `json:"password,omitempty"`
 	InstanceState     string `json:"instance_state,omitempty"`
 	InstancePrivateIp string `json:"instance_private_ip,omitempty"`
+
+	//attributes returned by describe
+	InstanceName       string `json:"instance_name,omitempty"`
+	InstanceType       string `json:"instance_type,omitempty"`
+	Zone               string `json:"zone,omitempty"`
+	VpcId              string `json:"vpc_id,omitempty"`
+	SubnetId           string `json:"subnet_id,omitempty"`
+	ImageId            string `json:"image_id,omitempty"`
+	SystemDiskType     string `json:"system_disk_type,omitempty"`
+	SystemDiskSize     string `json:"system_disk_size,omitempty"`
+	DataDisks          string `json:"data_disks,omitempty"`
+	SecurityGroupIds   string `json:"security_group_ids,omitempty"`
+	InstancePublicIp   string `json:"instance_public_ip,omitempty"`
+	InstanceChargeType string `json:"instance_charge_type,omitempty"`
+	ExpiredTime        string `json:"expired_time,omitempty"`
+	RenewFlag          string `json:"renew_flag,omitempty"`
+	CreatedTime        string `json:"created_time,omitempty"`
+	Tags               string `json:"tags,omitempty"`
+
+	//days before the prepaid instance expires, negative once it has expired
+	ExpireInDays string `json:"expire_in_days,omitempty"`
+
+	//availability of the instance types returned by check-quota
+	InstanceFamily string `json:"instance_family,omitempty"`
+	Status         string `json:"status,omitempty"`
+	SoldOut        string `json:"sold_out,omitempty"`
+	UnitPrice      string `json:"unit_price,omitempty"`
+	OriginalPrice  string `json:"original_price,omitempty"`
+	DiscountPrice  string `json:"discount_price,omitempty"`
+	ChargeUnit     string `json:"charge_unit,omitempty"`
 }
 
 type VmPlugin struct{}
@@ -75,6 +172,16 @@ func init() {
 	VMActions["terminate"] = new(VMTerminateAction)
 	VMActions["start"] = new(VMStartAction)
 	VMActions["stop"] = new(VMStopAction)
+	VMActions["reboot"] = new(VMRebootAction)
+	VMActions["resize"] = new(VMResizeAction)
+	VMActions["reinstall"] = new(VMReinstallAction)
+	VMActions["reset-password"] = new(VMResetPasswordAction)
+	VMActions["describe"] = new(VMDescribeAction)
+	VMActions["renew"] = new(VMRenewAction)
+	VMActions["convert-to-prepaid"] = new(VMConvertToPrepaidAction)
+	VMActions["modify-renew-flag"] = new(VMModifyRenewFlagAction)
+	VMActions["expiry-report"] = new(VMExpiryReportAction)
+	VMActions["check-quota"] = new(VMCheckQuotaAction)
 }
 
 func (plugin *VmPlugin) GetActionByName(actionName string) (Action, error) {
@@ -122,13 +229,22 @@ type QcloudRunInstanceStruct struct {
 	DataDisks             []DataDisksStruct         `json:"DataDisks,omitempty"`
 	VirtualPrivateCloud   VirtualPrivateCloudStruct `json:"VirtualPrivateCloud,omitempty"`
 	LoginSettings         LoginSettingsStruct       `json:"LoginSettings,omitempty"`
-	SecurityGroupIds      []string
+	SecurityGroupIds      []string                  `json:"SecurityGroupIds,omitempty"`
 	InternetAccessible    InternetAccessible
+	ClientToken           string                   `json:"ClientToken,omitempty"`
+	InstanceCount         int64                    `json:"InstanceCount,omitempty"`
+	InstanceName          string                   `json:"InstanceName,omitempty"`
+	HostName              string                   `json:"HostName,omitempty"`
+	UserData              string                   `json:"UserData,omitempty"`
+	TagSpecification      []TagSpecificationStruct `json:"TagSpecification,omitempty"`
+	//only one placement group is supported by qcloud
+	DisasterRecoverGroupIds []string `json:"DisasterRecoverGroupIds,omitempty"`
 }
 
 type InternetAccessible struct {
-	PublicIpAssigned        bool `json:"PublicIpAssigned"`
-	InternetMaxBandwidthOut int  `json:"InternetMaxBandwidthOut"`
+	InternetChargeType      string `json:"InternetChargeType,omitempty"`
+	PublicIpAssigned        bool   `json:"PublicIpAssigned"`
+	InternetMaxBandwidthOut int64  `json:"InternetMaxBandwidthOut"`
 }
 
 type PlacementStruct struct {
@@ -155,23 +271,23 @@ type VirtualPrivateCloudStruct struct {
 	PrivateIpAddresses []string `json:"PrivateIpAddresses,omitempty"`
 }
 type LoginSettingsStruct struct {
-	Password string
+	Password string   `json:"Password,omitempty"`
+	KeyIds   []string `json:"KeyIds,omitempty"`
+}
+type TagSpecificationStruct struct {
+	ResourceType string
+	Tags         []TagStruct
+}
+type TagStruct struct {
+	Key   string
+	Value string
 }
 
-func createCvmClient(region, secretId, secretKey string) (client *cvm.Client, err error) {
-	credential := common.NewCredential(secretId, secretKey)
-
-	clientProfile := profile.NewClientProfile()
-	clientProfile.HttpProfile.Endpoint = QCLOUD_ENDPOINT_CVM
-
-	client, err = cvm.NewClient(credential, region, clientProfile)
-	if err != nil {
-		logrus.Errorf("Create Qcloud vm client failed,err=%v", err)
-	}
-	return
+func createCvmClient(region, secretId, secretKey string) (CvmAPI, error) {
+	return QcloudClientFactory.NewCvmClient(region, secretId, secretKey)
 }
 
-func describeInstancesFromCvm(client *cvm.Client, describeInstancesParams cvm.DescribeInstancesRequest) (response *cvm.DescribeInstancesResponse, err error) {
+func describeInstancesFromCvm(client CvmAPI, describeInstancesParams cvm.DescribeInstancesRequest) (response *cvm.DescribeInstancesResponse, err error) {
 	request := cvm.NewDescribeInstancesRequest()
 	describeInstancesParamsByteArray, _ := json.Marshal(describeInstancesParams)
 	request.FromJsonString(string(describeInstancesParamsByteArray))
@@ -186,7 +302,7 @@ func describeInstancesFromCvm(client *cvm.Client, describeInstancesParams cvm.De
 	return response, err
 }
 
-func getInstanceByInstanceId(client *cvm.Client, instanceId string) (*cvm.Instance, error) {
+func getInstanceByInstanceId(client CvmAPI, instanceId string) (*cvm.Instance, error) {
 	describeInstancesParams := cvm.DescribeInstancesRequest{
 		InstanceIds: []*string{&instanceId},
 	}
@@ -203,7 +319,7 @@ func getInstanceByInstanceId(client *cvm.Client, instanceId string) (*cvm.Instan
 	return describeInstancesResponse.Response.InstanceSet[0], nil
 }
 
-func isInstanceInDesireState(client *cvm.Client, instanceId string, desireState string) error {
+func isInstanceInDesireState(client CvmAPI, instanceId string, desireState string) error {
 	instance, err := getInstanceByInstanceId(client, instanceId)
 	if err != nil {
 		return err
@@ -216,35 +332,107 @@ func isInstanceInDesireState(client *cvm.Client, instanceId string, desireState
 	return nil
 }
 
-func waitVmInDesireState(client *cvm.Client, instanceId string, desireState string, timeout int) error {
-	count := 0
+//waitVmsInDesireState polls the instances together until all of them are in desire state
+func waitVmsInDesireState(client CvmAPI, instanceIds []string, desireState string, timeout int) error {
+	return waitVmsUntil(client, instanceIds, timeout, func(instance *cvm.Instance) bool {
+		return *instance.InstanceState == desireState
+	})
+}
 
+//waitVmsUntil polls the instances together until all of them meet the condition
+func waitVmsUntil(client CvmAPI, instanceIds []string, timeout int, condition func(instance *cvm.Instance) bool) error {
+	count := 0
+	//qcloud returns 20 instances by default
+	describeInstancesParams := cvm.DescribeInstancesRequest{
+		InstanceIds: common.StringPtrs(instanceIds),
+		Limit:       common.Int64Ptr(int64(len(instanceIds))),
+	}
 	for {
-		time.Sleep(5 * time.Second)
-		instance, err := getInstanceByInstanceId(client, instanceId)
+		time.Sleep(waitPollInterval)
+		describeInstancesResponse, err := describeInstancesFromCvm(client, describeInstancesParams)
 		if err != nil {
 			return err
 		}
+		if len(describeInstancesResponse.Response.InstanceSet) != len(instanceIds) {
+			logrus.Errorf("found %d instances of vms%v", len(describeInstancesResponse.Response.InstanceSet), instanceIds)
+			return VM_NOT_FOUND_ERROR
+		}
 
-		if *instance.InstanceState == desireState {
+		met := 0
+		for _, instance := range describeInstancesResponse.Response.InstanceSet {
+			if condition(instance) {
+				met++
+			}
+		}
+		if met == len(instanceIds) {
 			break
 		}
 
 		count++
-		if count*5 > timeout {
+		if count*WAIT_POLL_SECONDS > timeout {
 			return VM_WAIT_STATE_TIMEOUT_ERROR
 		}
 	}
 	return nil
 }
 
-func waitVmTerminateDone(client *cvm.Client, instanceId string, timeout int) error {
+//vmLatestOperation is the latest operation of a cvm instance, which the vendored sdk doesn't know yet
+type vmLatestOperation struct {
+	InstanceId               *string `json:"InstanceId,omitempty" name:"InstanceId"`
+	LatestOperation          *string `json:"LatestOperation,omitempty" name:"LatestOperation"`
+	LatestOperationState     *string `json:"LatestOperationState,omitempty" name:"LatestOperationState"`
+	LatestOperationRequestId *string `json:"LatestOperationRequestId,omitempty" name:"LatestOperationRequestId"`
+}
+
+type describeVmLatestOperationsResponse struct {
+	*tchttp.BaseResponse
+	Response *struct {
+		InstanceSet []*vmLatestOperation `json:"InstanceSet,omitempty" name:"InstanceSet"`
+		RequestId   *string              `json:"RequestId,omitempty" name:"RequestId"`
+	} `json:"Response"`
+}
+
+//waitVmOperationDone waits until the latest operation of the vm is the one of requestId and it succeeds
+func waitVmOperationDone(client CvmAPI, instanceId string, requestId string, timeout int) error {
+	count := 0
+	request := cvm.NewDescribeInstancesRequest()
+	request.InstanceIds = []*string{&instanceId}
+	for {
+		time.Sleep(waitPollInterval)
+		response := &describeVmLatestOperationsResponse{BaseResponse: &tchttp.BaseResponse{}}
+		if err := client.Send(request, response); err != nil {
+			logrus.Errorf("cvm DescribeInstances meet err=%v", err)
+			return err
+		}
+		if len(response.Response.InstanceSet) != 1 {
+			logrus.Errorf("found vm[%s] have %d instance", instanceId, len(response.Response.InstanceSet))
+			return VM_NOT_FOUND_ERROR
+		}
+
+		operation := response.Response.InstanceSet[0]
+		if stringValue(operation.LatestOperationRequestId) == requestId {
+			switch stringValue(operation.LatestOperationState) {
+			case INSTANCE_OPERATION_STATE_SUCCESS:
+				return nil
+			case INSTANCE_OPERATION_STATE_FAILED:
+				return fmt.Errorf("vm[%s] %s of request[%s] failed", instanceId, stringValue(operation.LatestOperation), requestId)
+			}
+		}
+
+		count++
+		if count*WAIT_POLL_SECONDS > timeout {
+			return VM_WAIT_STATE_TIMEOUT_ERROR
+		}
+	}
+}
+
+func waitVmTerminateDone(client CvmAPI, instanceId string, timeout int) error {
 	count := 0
 	describeInstancesParams := cvm.DescribeInstancesRequest{
 		InstanceIds: []*string{&instanceId},
 	}
 	for {
-		time.Sleep(5 * time.Second)
+		time.Sleep(waitPollInterval)
 		describeInstancesResponse, err := describeInstancesFromCvm(client, describeInstancesParams)
 		if err != nil {
 			return err
@@ -254,7 +442,7 @@ func waitVmTerminateDone(client *cvm.Client, instanceId string, timeout int) err
 		}
 
 		count++
-		if count*5 > timeout {
+		if count*WAIT_POLL_SECONDS > timeout {
 			return VM_WAIT_STATE_TIMEOUT_ERROR
 		}
 	}
@@ -266,353 +454,2164 @@ type VMCreateAction struct {
 }
 
 func (action *VMCreateAction) CheckParam(input interface{}) error {
-	_, ok := input.(VmInputs)
+	vms, ok := input.(VmInputs)
 	if !ok {
 		return INVALID_PARAMETERS
 	}
 
-	return nil
-}
-
-func (action *VMCreateAction) Do(input interface{}) (interface{}, error) {
-	vms, _ := input.(VmInputs)
-	outputs := VmOutputs{}
 	for _, vm := range vms.Inputs {
-		output := VmOutput{}
-
-		paramsMap, err := GetMapFromProviderParams(vm.ProviderParams)
-		logrus.Debugf("actionParam:%v", vm)
-		client, err := createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
-		if err != nil {
-			return nil, err
+		if _, err := parseVmDataDisks(vm.DataDisks); err != nil {
+			return err
+		}
+		if _, err := parseVmTags(vm.Tags); err != nil {
+			return err
+		}
+		if vm.InternetMaxBandwidthOut < 0 {
+			return fmt.Errorf("invalid internet_max_bandwidth_out(%v)", vm.InternetMaxBandwidthOut)
+		}
+		if err := checkVmRenewFlag(vm.RenewFlag); err != nil {
+			return err
+		}
+		if err := checkVmZones(vm); err != nil {
+			return err
 		}
-		password := utils.CreateRandomPassword()
-
-		runInstanceRequest := QcloudRunInstanceStruct{
-			Placement: PlacementStruct{
-				Zone: paramsMap["AvailableZone"],
-			},
-			ImageId:            vm.ImageId,
-			InstanceChargeType: vm.InstanceChargeType,
-			InstanceType:       vm.InstanceType,
-			SystemDisk: SystemDiskStruct{
-				DiskType: "CLOUD_PREMIUM",
-				DiskSize: vm.SystemDiskSize,
-			},
-			VirtualPrivateCloud: VirtualPrivateCloudStruct{
-				VpcId:    vm.VpcId,
-				SubnetId: vm.SubnetId,
-			},
-			LoginSettings: LoginSettingsStruct{
-				Password: password,
-			},
-			InternetAccessible: InternetAccessible{
-				PublicIpAssigned:        false,
-				InternetMaxBandwidthOut: 10,
-			},
-		}
-
-		if vm.InstancePrivateIp != "" {
-			runInstanceRequest.VirtualPrivateCloud.PrivateIpAddresses = []string{vm.InstancePrivateIp}
-		}
-
-		if vm.InstanceChargeType == INSTANCE_CHARGE_TYPE_PREPAID {
-			runInstanceRequest.InstanceChargePrepaid = InstanceChargePrepaidStruct{
-				Period:    vm.InstanceChargePeriod,
-				RenewFlag: RENEW_FLAG_NOTIFY_AND_AUTO_RENEW,
+		if vm.ZoneFallback != "" {
+			if _, err := strconv.ParseBool(vm.ZoneFallback); err != nil {
+				return fmt.Errorf("invalid zone_fallback(%s)", vm.ZoneFallback)
 			}
 		}
+	}
 
-		//check resources exsit
-		if vm.Id != "" {
-			describeInstancesParams := cvm.DescribeInstancesRequest{
-				InstanceIds: []*string{&vm.Id},
-			}
+	//qcloud fails late with vague errors, so the instance types and images are looked up first
+	return forPendingVmCreates(vms.Inputs, getVmCreateKeys(vms.Inputs), checkVmsAvailable)
+}
 
-			describeInstancesResponse, err := describeInstancesFromCvm(client, describeInstancesParams)
-			if err != nil {
-				return nil, err
-			}
+func getVmCreateKeys(vms []VmInput) []string {
+	keys := make([]string, len(vms))
+	for i, vm := range vms {
+		keys[i] = getIdempotencyKey("vm-create", vm.Guid, vm.IdempotencyKey, vm)
+	}
+	return keys
+}
 
-			if len(describeInstancesResponse.Response.InstanceSet) > 1 {
-				logrus.Errorf("check vm exsit found vm[%s] have %d instance", vm.Id, len(describeInstancesResponse.Response.InstanceSet))
-				return nil, VM_NOT_FOUND_ERROR
-			}
+//forPendingVmCreates runs the lookups in qcloud only for the inputs whose idempotency key is not done,
+//a retry of a done key replays its output and should not fail because a quota or an image changed since
+func forPendingVmCreates(vms []VmInput, keys []string, lookup func(vms []VmInput) error) error {
+	indexes := []int{}
+	pendingVms := []VmInput{}
+	for i, vm := range vms {
+		done, err := isIdempotencyKeyDone(keys[i])
+		if err != nil {
+			return err
+		}
+		if !done {
+			indexes = append(indexes, i)
+			pendingVms = append(pendingVms, vm)
+		}
+	}
+	if len(pendingVms) == 0 {
+		return nil
+	}
 
-			if len(describeInstancesResponse.Response.InstanceSet) == 1 {
-				output.RequestId = *describeInstancesResponse.Response.RequestId
-				output.Guid = vm.Guid
-				output.Id = vm.Id
-				output.Memory = strconv.Itoa(int(*describeInstancesResponse.Response.InstanceSet[0].Memory))
-				output.Cpu = strconv.Itoa(int(*describeInstancesResponse.Response.InstanceSet[0].CPU))
-				output.InstanceState = *describeInstancesResponse.Response.InstanceSet[0].InstanceState
-				output.InstancePrivateIp = *describeInstancesResponse.Response.InstanceSet[0].PrivateIpAddresses[0]
-				outputs.Outputs = append(outputs.Outputs, output)
-				continue
-			}
+	if err := lookup(pendingVms); err != nil {
+		return err
+	}
+	for n, i := range indexes {
+		vms[i] = pendingVms[n]
+	}
+	return nil
+}
+
+//checkVmZones makes sure each zone has its own subnet, since a subnet belongs to one zone
+func checkVmZones(vm VmInput) error {
+	zones := splitCommaValues(vm.Zones)
+	subnets := splitCommaValues(vm.SubnetId)
+	if len(zones) == 0 {
+		if len(subnets) > 1 {
+			return fmt.Errorf("subnet_id(%s) should be one subnet without zones", vm.SubnetId)
 		}
+		return nil
+	}
+	if vm.Zone != "" {
+		return errors.New("input zone can't be used together with zones")
+	}
+	if len(subnets) != len(zones) {
+		return fmt.Errorf("zones(%s) and subnet_id(%s) should have the same count", vm.Zones, vm.SubnetId)
+	}
+	return nil
+}
 
-		//check resources exsit
-		if vm.Id != "" {
-			describeInstancesParams := cvm.DescribeInstancesRequest{
-				InstanceIds: []*string{&vm.Id},
-			}
+//spreadVmsAcrossZones assigns each vm with zones the zone and subnet of its turn,
+//the vms with the same zones and subnets take turns in input order, so a retry gets the same placement
+func spreadVmsAcrossZones(vms []VmInput) {
+	turns := map[string]int{}
+	for i, vm := range vms {
+		zones := splitCommaValues(vm.Zones)
+		if len(zones) == 0 {
+			continue
+		}
+		subnets := splitCommaValues(vm.SubnetId)
+		key := vm.ProviderParams + "/" + vm.Zones + "/" + vm.SubnetId
+		turn := turns[key] % len(zones)
+		turns[key]++
+
+		vms[i].Zone = zones[turn]
+		vms[i].SubnetId = subnets[turn]
+		vms[i].Zones = ""
+	}
+}
 
-			describeInstancesResponse, err := describeInstancesFromCvm(client, describeInstancesParams)
-			if err != nil {
-				return nil, err
-			}
+//getVmZone returns the zone assigned to the vm, or the AvailableZone of provider_params
+func getVmZone(vm VmInput, paramsMap map[string]string) string {
+	if vm.Zone != "" {
+		return vm.Zone
+	}
+	return paramsMap["AvailableZone"]
+}
 
-			if len(describeInstancesResponse.Response.InstanceSet) > 1 {
-				logrus.Errorf("check vm exsit found vm[%s] have %d instance", vm.Id, len(describeInstancesResponse.Response.InstanceSet))
-				return nil, VM_NOT_FOUND_ERROR
-			}
+//getVmChargeType returns the charge type of the vm, qcloud charges by hour when it is not given
+func getVmChargeType(vm VmInput) string {
+	if vm.InstanceChargeType != "" {
+		return vm.InstanceChargeType
+	}
+	return INSTANCE_CHARGE_TYPE_POSTPAID_BY_HOUR
+}
 
-			if len(describeInstancesResponse.Response.InstanceSet) == 1 {
-				output.RequestId = *describeInstancesResponse.Response.RequestId
-				output.Guid = vm.Guid
-				output.Id = vm.Id
-				output.Memory = strconv.Itoa(int(*describeInstancesResponse.Response.InstanceSet[0].Memory))
-				output.Cpu = strconv.Itoa(int(*describeInstancesResponse.Response.InstanceSet[0].CPU))
-				output.InstanceState = *describeInstancesResponse.Response.InstanceSet[0].InstanceState
-				output.InstancePrivateIp = *describeInstancesResponse.Response.InstanceSet[0].PrivateIpAddresses[0]
-				outputs.Outputs = append(outputs.Outputs, output)
+//vmZoneQuotas is what the zones of a region sell, keyed by zone, instance type and charge type
+type vmZoneQuotas map[string]*cvm.InstanceTypeQuotaItem
 
-				continue
-			}
-		}
+func vmZoneQuotaKey(zone string, instanceType string, chargeType string) string {
+	return zone + "/" + instanceType + "/" + chargeType
+}
 
-		request := cvm.NewRunInstancesRequest()
-		byteRunInstancesRequestData, _ := json.Marshal(runInstanceRequest)
-		logrus.Debugf("byteRunInstancesRequestData=%v", string(byteRunInstancesRequestData))
-		request.FromJsonString(string(byteRunInstancesRequestData))
+func (quotas vmZoneQuotas) onSale(zone string, instanceType string, chargeType string) bool {
+	item, found := quotas[vmZoneQuotaKey(zone, instanceType, chargeType)]
+	return found && stringValue(item.Status) == VM_INSTANCE_TYPE_STATUS_SELL
+}
 
-		resp, err := client.RunInstances(request)
-		if err != nil {
-			return nil, err
+//zonesOnSale returns the zones selling the instance type in zone order
+func (quotas vmZoneQuotas) zonesOnSale(instanceType string, chargeType string) []string {
+	zones := []string{}
+	for _, item := range quotas {
+		if *item.InstanceType == instanceType && *item.InstanceChargeType == chargeType && stringValue(item.Status) == VM_INSTANCE_TYPE_STATUS_SELL {
+			zones = append(zones, *item.Zone)
 		}
+	}
+	sort.Strings(zones)
+	return zones
+}
 
-		vm.Id = *resp.Response.InstanceIdSet[0]
-		logrus.Infof("Create VM's request has been submitted, InstanceId is [%v], RequestID is [%v]", vm.Id, *resp.Response.RequestId)
+func describeVmZoneQuotas(client CvmAPI, filters []*cvm.Filter) ([]*cvm.InstanceTypeQuotaItem, string, error) {
+	request := cvm.NewDescribeZoneInstanceConfigInfosRequest()
+	request.Filters = filters
+	response, err := client.DescribeZoneInstanceConfigInfos(request)
+	if err != nil {
+		logrus.Errorf("cvm DescribeZoneInstanceConfigInfos meet err=%v", err)
+		return nil, "", err
+	}
+	return response.Response.InstanceTypeQuotaSet, *response.Response.RequestId, nil
+}
 
-		if err = waitVmInDesireState(client, vm.Id, INSTANCE_STATE_RUNNING, 120); err != nil {
-			return nil, err
+//queryVmZoneQuotas looks up the instance types of the vms once for each region, keyed by provider params
+func queryVmZoneQuotas(vms []VmInput) (map[string]vmZoneQuotas, error) {
+	instanceTypes := map[string][]string{}
+	seen := map[string]bool{}
+	for _, vm := range vms {
+		if key := vm.ProviderParams + "/" + vm.InstanceType; !seen[key] {
+			seen[key] = true
+			instanceTypes[vm.ProviderParams] = append(instanceTypes[vm.ProviderParams], vm.InstanceType)
 		}
-		logrus.Infof("Created VM's state is [%v] now", INSTANCE_STATE_RUNNING)
+	}
 
-		describeInstancesParams := cvm.DescribeInstancesRequest{
-			InstanceIds: []*string{&vm.Id},
+	regionQuotas := map[string]vmZoneQuotas{}
+	for providerParams, types := range instanceTypes {
+		paramsMap, _ := GetMapFromProviderParams(providerParams)
+		client, err := createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+		if err != nil {
+			return nil, err
 		}
-
-		describeInstancesResponse, err := describeInstancesFromCvm(client, describeInstancesParams)
+		items, _, err := describeVmZoneQuotas(client, []*cvm.Filter{
+			{Name: common.StringPtr("instance-type"), Values: common.StringPtrs(types)},
+		})
 		if err != nil {
 			return nil, err
 		}
 
-		md5sum := utils.Md5Encode(vm.Guid + vm.Seed)
-		if output.Password, err = utils.AesEncode(md5sum[0:16], password); err != nil {
-			logrus.Errorf("AesEncode meet error(%v)", err)
-			return nil, errors.New("aes encode error")
+		quotas := vmZoneQuotas{}
+		for _, item := range items {
+			quotas[vmZoneQuotaKey(*item.Zone, *item.InstanceType, *item.InstanceChargeType)] = item
 		}
-
-		output.RequestId = *describeInstancesResponse.Response.RequestId
-		output.Guid = vm.Guid
-		output.Id = vm.Id
-		output.Memory = strconv.Itoa(int(*describeInstancesResponse.Response.InstanceSet[0].Memory))
-		output.Cpu = strconv.Itoa(int(*describeInstancesResponse.Response.InstanceSet[0].CPU))
-		output.InstanceState = *describeInstancesResponse.Response.InstanceSet[0].InstanceState
-		output.InstancePrivateIp = *describeInstancesResponse.Response.InstanceSet[0].PrivateIpAddresses[0]
-		outputs.Outputs = append(outputs.Outputs, output)
+		regionQuotas[providerParams] = quotas
 	}
-
-	return &outputs, nil
+	return regionQuotas, nil
 }
 
-type VMTerminateAction struct {
-	VMAction
-}
+//checkVmsAvailable makes sure the new vms can be created before any of them is,
+//the instance type should be on sale in their zones and the image should exist in their regions.
+//the inputs with id are left alone, they were created before
+func checkVmsAvailable(vms []VmInput) error {
+	checkVms := []VmInput{}
+	for _, vm := range vms {
+		if vm.Id == "" && vm.InstanceType != "" {
+			checkVms = append(checkVms, vm)
+		}
+	}
+	regionQuotas, err := queryVmZoneQuotas(checkVms)
+	if err != nil {
+		return err
+	}
 
-func (action *VMTerminateAction) Do(input interface{}) (interface{}, error) {
-	vms, _ := input.(VmInputs)
-	outputs := VmOutputs{}
+	for _, vm := range checkVms {
+		paramsMap, _ := GetMapFromProviderParams(vm.ProviderParams)
+		quotas := regionQuotas[vm.ProviderParams]
+		chargeType := getVmChargeType(vm)
+		if fallback, _ := strconv.ParseBool(vm.ZoneFallback); fallback {
+			if len(quotas.zonesOnSale(vm.InstanceType, chargeType)) == 0 {
+				return fmt.Errorf("instance type(%s) charged by %s is not on sale in any zone of region(%s)", vm.InstanceType, chargeType, paramsMap["Region"])
+			}
+			continue
+		}
 
-	for _, vm := range vms.Inputs {
-		paramsMap, err := GetMapFromProviderParams(vm.ProviderParams)
+		zones := splitCommaValues(vm.Zones)
+		if len(zones) == 0 {
+			zones = []string{getVmZone(vm, paramsMap)}
+		}
+		for _, zone := range zones {
+			if !quotas.onSale(zone, vm.InstanceType, chargeType) {
+				return fmt.Errorf("instance type(%s) charged by %s is not on sale in zone(%s)", vm.InstanceType, chargeType, zone)
+			}
+		}
+	}
+	return checkVmImagesExist(checkVms)
+}
 
-		terminateInstancesRequestData := cvm.TerminateInstancesRequest{
-			InstanceIds: []*string{&vm.Id},
+//checkVmImagesExist looks up the image ids once for each region, image names are checked by resolving them
+func checkVmImagesExist(vms []VmInput) error {
+	imageIds := map[string][]string{}
+	seen := map[string]bool{}
+	for _, vm := range vms {
+		if key := vm.ProviderParams + "/" + vm.ImageId; vm.ImageId != "" && !seen[key] {
+			seen[key] = true
+			imageIds[vm.ProviderParams] = append(imageIds[vm.ProviderParams], vm.ImageId)
 		}
+	}
 
+	for providerParams, images := range imageIds {
+		paramsMap, _ := GetMapFromProviderParams(providerParams)
 		client, err := createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
 		if err != nil {
-			return nil, err
+			return err
 		}
-		terminateInstancesRequest := cvm.NewTerminateInstancesRequest()
-		byteTerminateInstancesRequestData, _ := json.Marshal(terminateInstancesRequestData)
-		terminateInstancesRequest.FromJsonString(string(byteTerminateInstancesRequestData))
 
-		response, err := client.TerminateInstances(terminateInstancesRequest)
-		if err != nil {
-			return nil, err
+		ids := []string{}
+		for _, image := range images {
+			if !strings.HasPrefix(image, IMAGE_ID_PREFIX) {
+				if _, err = resolveImageId(client, image); err != nil {
+					return err
+				}
+				continue
+			}
+			ids = append(ids, image)
 		}
-		logrus.Infof("Terminate VM[%v] has been submitted in Qcloud, RequestID is [%v]", vm.Id, *response.Response.RequestId)
-
-		if err = waitVmTerminateDone(client, vm.Id, 600); err != nil {
-			return nil, err
+		if len(ids) == 0 {
+			continue
 		}
-		output := VmOutput{}
-		output.RequestId = *response.Response.RequestId
-		output.Guid = vm.Guid
-		output.Id = vm.Id
-
-		outputs.Outputs = append(outputs.Outputs, output)
 
-		logrus.Infof("Terminated VM[%v] has been done", vm.Id)
+		request := cvm.NewDescribeImagesRequest()
+		request.ImageIds = common.StringPtrs(ids)
+		found, _, err := describeAllImages(client, request)
+		if err != nil {
+			return err
+		}
+		foundIds := map[string]bool{}
+		for _, image := range found {
+			foundIds[*image.ImageId] = true
+		}
+		for _, id := range ids {
+			if !foundIds[id] {
+				return fmt.Errorf("image(%s) not found in region(%s)", id, paramsMap["Region"])
+			}
+		}
 	}
-
-	return &outputs, nil
+	return nil
 }
 
-type VMStartAction struct {
-	VMAction
-}
+//fallbackVmZones moves the vms allowed to fall back out of the zones not selling their instance type,
+//each one goes to the first zone on sale which has a subnet of its vpc
+func fallbackVmZones(vms []VmInput) error {
+	fallbackVms := []VmInput{}
+	for _, vm := range vms {
+		if fallback, _ := strconv.ParseBool(vm.ZoneFallback); fallback && vm.Id == "" && vm.InstanceType != "" {
+			fallbackVms = append(fallbackVms, vm)
+		}
+	}
+	if len(fallbackVms) == 0 {
+		return nil
+	}
+	regionQuotas, err := queryVmZoneQuotas(fallbackVms)
+	if err != nil {
+		return err
+	}
 
-func (action *VMStartAction) Do(input interface{}) (interface{}, error) {
-	vms, _ := input.(VmInputs)
-	outputs := VmOutputs{}
-	for _, vm := range vms.Inputs {
-		requestId, err := action.startInstance(vm)
-		if err != nil {
-			return nil, err
+	for i, vm := range vms {
+		if fallback, _ := strconv.ParseBool(vm.ZoneFallback); !fallback || vm.Id != "" || vm.InstanceType == "" {
+			continue
+		}
+		paramsMap, _ := GetMapFromProviderParams(vm.ProviderParams)
+		quotas := regionQuotas[vm.ProviderParams]
+		chargeType := getVmChargeType(vm)
+		zone := getVmZone(vm, paramsMap)
+		if quotas.onSale(zone, vm.InstanceType, chargeType) {
+			continue
 		}
 
-		output := VmOutput{}
-		output.RequestId = requestId
-		output.Guid = vm.Guid
-		output.Id = vm.Id
-		outputs.Outputs = append(outputs.Outputs, output)
+		moved := false
+		for _, candidate := range quotas.zonesOnSale(vm.InstanceType, chargeType) {
+			subnetId, found, err := queryVpcSubnetInZone(vm.ProviderParams, vm.VpcId, candidate)
+			if err != nil {
+				return err
+			}
+			if found {
+				logrus.Infof("instance type[%s] is not on sale in zone[%s], vm of guid[%s] falls back to zone[%s] subnet[%s]", vm.InstanceType, zone, vm.Guid, candidate, subnetId)
+				vms[i].Zone = candidate
+				vms[i].SubnetId = subnetId
+				moved = true
+				break
+			}
+		}
+		if !moved {
+			return fmt.Errorf("no zone of region(%s) sells instance type(%s) and has a subnet of vpc(%s)", paramsMap["Region"], vm.InstanceType, vm.VpcId)
+		}
 	}
-
-	return &outputs, nil
+	return nil
 }
 
-func (action *VMStartAction) startInstance(vm VmInput) (string, error) {
-	paramsMap, _ := GetMapFromProviderParams(vm.ProviderParams)
-
-	client, err := createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+//queryVpcSubnetInZone returns the first subnet of the vpc in the zone, false if the vpc has none there
+func queryVpcSubnetInZone(providerParams string, vpcId string, zone string) (string, bool, error) {
+	paramsMap, _ := GetMapFromProviderParams(providerParams)
+	client, err := CreateVpcClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
 
-	request := cvm.NewStartInstancesRequest()
-	request.InstanceIds = append(request.InstanceIds, &vm.Id)
-
-	response, err := client.StartInstances(request)
+	request := vpc.NewDescribeSubnetsRequest()
+	request.Filters = []*vpc.Filter{
+		{Name: common.StringPtr("vpc-id"), Values: common.StringPtrs([]string{vpcId})},
+		{Name: common.StringPtr("zone"), Values: common.StringPtrs([]string{zone})},
+	}
+	response, err := client.DescribeSubnets(request)
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
-	return *response.Response.RequestId, nil
+	if len(response.Response.SubnetSet) == 0 {
+		return "", false, nil
+	}
+	return *response.Response.SubnetSet[0].SubnetId, true, nil
 }
 
-type VMStopAction struct {
-	VMAction
+//splitCommaValues splits values like "sg-1,sg-2" and drops the empty ones
+func splitCommaValues(values string) []string {
+	result := []string{}
+	for _, value := range strings.Split(values, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
 }
 
-func (action *VMStopAction) Do(input interface{}) (interface{}, error) {
-	vms, _ := input.(VmInputs)
-	outputs := VmOutputs{}
-	for _, vm := range vms.Inputs {
-		output, err := action.stopInstance(&vm)
-		if err != nil {
-			return nil, err
+//parseVmDataDisks parses data disks like "CLOUD_PREMIUM:100,CLOUD_SSD:200",
+//the data disks are deleted together with the instance
+func parseVmDataDisks(dataDisks string) ([]DataDisksStruct, error) {
+	disks := []DataDisksStruct{}
+	for _, dataDisk := range splitCommaValues(dataDisks) {
+		kv := strings.Split(dataDisk, ":")
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid data disk(%s), should be like CLOUD_PREMIUM:100", dataDisk)
 		}
-
-		outputs.Outputs = append(outputs.Outputs, *output)
+		size, err := strconv.ParseInt(kv[1], 10, 64)
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("invalid data disk(%s) size", dataDisk)
+		}
+		disks = append(disks, DataDisksStruct{
+			DiskType:           kv[0],
+			DiskSize:           size,
+			DeleteWithInstance: true,
+		})
 	}
+	return disks, nil
+}
 
-	return &outputs, nil
+//parseVmTags parses tags like "env=prod;team=ops" in order
+func parseVmTags(tags string) ([]TagStruct, error) {
+	result := []TagStruct{}
+	for _, tag := range strings.Split(tags, ";") {
+		if tag = strings.TrimSpace(tag); tag == "" {
+			continue
+		}
+		kv := strings.SplitN(tag, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid tag(%s), should be like key=value", tag)
+		}
+		result = append(result, TagStruct{Key: kv[0], Value: kv[1]})
+	}
+	return result, nil
 }
 
-func (action *VMStopAction) stopInstance(vm *VmInput) (*VmOutput, error) {
-	paramsMap, _ := GetMapFromProviderParams(vm.ProviderParams)
+func buildRunInstanceRequest(vm VmInput, zone string, password string, clientToken string) (QcloudRunInstanceStruct, error) {
+	runInstanceRequest := QcloudRunInstanceStruct{
+		Placement: PlacementStruct{
+			Zone:      zone,
+			ProjectId: vm.ProjectId,
+		},
+		ImageId:            vm.ImageId,
+		InstanceChargeType: vm.InstanceChargeType,
+		InstanceType:       vm.InstanceType,
+		SystemDisk: SystemDiskStruct{
+			DiskType: VM_DEFAULT_SYSTEM_DISK_TYPE,
+			DiskSize: vm.SystemDiskSize,
+		},
+		VirtualPrivateCloud: VirtualPrivateCloudStruct{
+			VpcId:    vm.VpcId,
+			SubnetId: vm.SubnetId,
+		},
+		LoginSettings: LoginSettingsStruct{
+			Password: password,
+			KeyIds:   splitCommaValues(vm.KeyIds),
+		},
+		SecurityGroupIds: splitCommaValues(vm.SecurityGroupIds),
+		InternetAccessible: InternetAccessible{
+			PublicIpAssigned:        false,
+			InternetMaxBandwidthOut: VM_DEFAULT_INTERNET_MAX_BANDWIDTH_OUT,
+		},
+		ClientToken:  clientToken,
+		InstanceName: vm.InstanceName,
+		HostName:     vm.HostName,
+	}
 
-	client, err := createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
-	if err != nil {
-		return nil, err
+	if vm.PlacementGroupId != "" {
+		runInstanceRequest.DisasterRecoverGroupIds = []string{vm.PlacementGroupId}
 	}
 
-	request := cvm.NewStopInstancesRequest()
-	request.InstanceIds = append(request.InstanceIds, &vm.Id)
+	if vm.SystemDiskType != "" {
+		runInstanceRequest.SystemDisk.DiskType = vm.SystemDiskType
+	}
 
-	response, err := client.StopInstances(request)
+	dataDisks, err := parseVmDataDisks(vm.DataDisks)
 	if err != nil {
-		return nil, err
+		return runInstanceRequest, err
+	}
+	runInstanceRequest.DataDisks = dataDisks
+
+	//a public ip is only assigned when the bandwidth is given
+	if vm.InternetMaxBandwidthOut > 0 {
+		runInstanceRequest.InternetAccessible = InternetAccessible{
+			InternetChargeType:      vm.InternetChargeType,
+			PublicIpAssigned:        true,
+			InternetMaxBandwidthOut: vm.InternetMaxBandwidthOut,
+		}
 	}
 
-	output := VmOutput{}
-	output.RequestId = *response.Response.RequestId
-	output.Guid = vm.Guid
-	output.Id = vm.Id
+	//qcloud wants the user data in base64
+	if vm.UserData != "" {
+		runInstanceRequest.UserData = base64.StdEncoding.EncodeToString([]byte(vm.UserData))
+	}
 
-	return &output, nil
-}
+	tags, err := parseVmTags(vm.Tags)
+	if err != nil {
+		return runInstanceRequest, err
+	}
+	if len(tags) > 0 {
+		runInstanceRequest.TagSpecification = []TagSpecificationStruct{
+			{ResourceType: TAG_RESOURCE_TYPE_INSTANCE, Tags: tags},
+		}
+	}
 
-func QueryCvmInstance(providerParams string, filter Filter) ([]*cvm.Instance, error) {
-	validFilterNames := []string{"instanceId", "privateIpAddress"}
-	filterValues := common.StringPtrs(filter.Values)
-	var limit int64
+	if vm.InstancePrivateIp != "" {
+		runInstanceRequest.VirtualPrivateCloud.PrivateIpAddresses = []string{vm.InstancePrivateIp}
+	}
 
-	paramsMap, err := GetMapFromProviderParams(providerParams)
+	if vm.InstanceChargeType == INSTANCE_CHARGE_TYPE_PREPAID {
+		runInstanceRequest.InstanceChargePrepaid = InstanceChargePrepaidStruct{
+			Period:    vm.InstanceChargePeriod,
+			RenewFlag: getVmRenewFlag(vm),
+		}
+	}
+	return runInstanceRequest, nil
+}
+
+//resolveVmImageIds replaces the image names of the inputs with the image ids of their regions
+func resolveVmImageIds(vms []VmInput) error {
+	resolved := map[string]string{}
+	for i, vm := range vms {
+		if vm.ImageId == "" || strings.HasPrefix(vm.ImageId, IMAGE_ID_PREFIX) {
+			continue
+		}
+		paramsMap, _ := GetMapFromProviderParams(vm.ProviderParams)
+		key := paramsMap["Region"] + "/" + vm.ImageId
+		if imageId, found := resolved[key]; found {
+			vms[i].ImageId = imageId
+			continue
+		}
+
+		client, err := createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+		if err != nil {
+			return err
+		}
+		if vms[i].ImageId, err = resolveImageId(client, vm.ImageId); err != nil {
+			return err
+		}
+		resolved[key] = vms[i].ImageId
+	}
+	return nil
+}
+
+//vmCreateTask is the inputs created by one RunInstances call
+type vmCreateTask struct {
+	indexes []int
+	keys    []string
+}
+
+//groupVmCreateTasks puts the new vms with identical specs logged in with key pairs into one task of at most
+//VM_RUN_INSTANCES_MAX_COUNT vms, the vms to check by id, with a fixed private ip or a password are created one by one
+//as one RunInstances call sets the same password to all its instances
+func groupVmCreateTasks(vms []VmInput, keys []string) []*vmCreateTask {
+	tasks := []*vmCreateTask{}
+	groups := make(map[string]*vmCreateTask)
+	for i, vm := range vms {
+		groupKey := ""
+		if vm.Id == "" && vm.InstancePrivateIp == "" && len(splitCommaValues(vm.KeyIds)) > 0 {
+			request, _ := buildRunInstanceRequest(vm, vm.Zone, "", "")
+			requestBytes, _ := json.Marshal(request)
+			groupKey = vm.ProviderParams + string(requestBytes)
+		}
+
+		if task, found := groups[groupKey]; found && groupKey != "" && len(task.indexes) < VM_RUN_INSTANCES_MAX_COUNT {
+			task.indexes = append(task.indexes, i)
+			task.keys = append(task.keys, keys[i])
+			continue
+		}
+		task := &vmCreateTask{indexes: []int{i}, keys: []string{keys[i]}}
+		if groupKey != "" {
+			groups[groupKey] = task
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+func (action *VMCreateAction) Do(input interface{}) (interface{}, error) {
+	vms, _ := input.(VmInputs)
+	keys := getVmCreateKeys(vms.Inputs)
+	outputs := make([]VmOutput, len(vms.Inputs))
+	//zones and image names are resolved after the keys are taken, so a retry keeps the keys of the first try
+	spreadVmsAcrossZones(vms.Inputs)
+	err := forPendingVmCreates(vms.Inputs, keys, func(pendingVms []VmInput) error {
+		if err := fallbackVmZones(pendingVms); err != nil {
+			return err
+		}
+		return resolveVmImageIds(pendingVms)
+	})
 	if err != nil {
 		return nil, err
 	}
+
+	tasks := groupVmCreateTasks(vms.Inputs, keys)
+	errs := make([]error, len(tasks))
+	taskIndexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for n := 0; n < VM_CREATE_CONCURRENCY && n < len(tasks); n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for taskIndex := range taskIndexes {
+				errs[taskIndex] = runVmCreateTask(vms.Inputs, keys, tasks[taskIndex], outputs)
+			}
+		}()
+	}
+	for taskIndex := range tasks {
+		taskIndexes <- taskIndex
+	}
+	close(taskIndexes)
+	wg.Wait()
+
+	//the vms created are kept by idempotency keys, a retry returns them without creating again
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &VmOutputs{Outputs: outputs}, nil
+}
+
+func runVmCreateTask(vms []VmInput, keys []string, task *vmCreateTask, outputs []VmOutput) error {
+	taskOutputs := []interface{}{}
+	for _, i := range task.indexes {
+		taskOutputs = append(taskOutputs, &outputs[i])
+	}
+
+	return runBatchWithIdempotencyKeys(task.keys, taskOutputs, func(indexes []int) ([]interface{}, error) {
+		createVms := []VmInput{}
+		createKeys := []string{}
+		for _, n := range indexes {
+			createVms = append(createVms, vms[task.indexes[n]])
+			createKeys = append(createKeys, task.keys[n])
+		}
+
+		//qcloud will not launch other instances for the same client token
+		clientToken := getIdempotencyClientToken(strings.Join(createKeys, ","))
+		var createOutputs []VmOutput
+		var err error
+		if len(createVms) == 1 {
+			var output VmOutput
+			output, err = createVm(createVms[0], createKeys[0], clientToken)
+			createOutputs = []VmOutput{output}
+		} else {
+			createOutputs, err = createVmsWithInstanceCount(createVms, createKeys, clientToken)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		results := []interface{}{}
+		for _, output := range createOutputs {
+			results = append(results, output)
+		}
+		return results, nil
+	})
+}
+
+func createVm(vm VmInput, idempotencyKey string, clientToken string) (VmOutput, error) {
+	output := VmOutput{}
+
+	paramsMap, err := GetMapFromProviderParams(vm.ProviderParams)
+	logrus.Debugf("actionParam:%v", vm)
 	client, err := createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
 	if err != nil {
-		return nil, err
+		return output, err
 	}
 
-	if err := isValidValue(filter.Name, validFilterNames); err != nil {
-		return nil, err
+	//check resources exsit
+	if vm.Id != "" {
+		describeInstancesParams := cvm.DescribeInstancesRequest{
+			InstanceIds: []*string{&vm.Id},
+		}
+
+		describeInstancesResponse, err := describeInstancesFromCvm(client, describeInstancesParams)
+		if err != nil {
+			return output, err
+		}
+
+		if len(describeInstancesResponse.Response.InstanceSet) > 1 {
+			logrus.Errorf("check vm exsit found vm[%s] have %d instance", vm.Id, len(describeInstancesResponse.Response.InstanceSet))
+			return output, VM_NOT_FOUND_ERROR
+		}
+
+		if len(describeInstancesResponse.Response.InstanceSet) == 1 {
+			output.RequestId = *describeInstancesResponse.Response.RequestId
+			output.Guid = vm.Guid
+			output.Id = vm.Id
+			output.Memory = strconv.Itoa(int(*describeInstancesResponse.Response.InstanceSet[0].Memory))
+			output.Cpu = strconv.Itoa(int(*describeInstancesResponse.Response.InstanceSet[0].CPU))
+			output.InstanceState = *describeInstancesResponse.Response.InstanceSet[0].InstanceState
+			output.InstancePrivateIp = *describeInstancesResponse.Response.InstanceSet[0].PrivateIpAddresses[0]
+			return output, nil
+		}
 	}
 
-	request := cvm.NewDescribeInstancesRequest()
-	limit = int64(len(filterValues))
-	request.Limit = &limit
-	name, err := TransLittleCamelcaseToShortLineFormat(filter.Name)
+	outputs, err := createVmsWithInstanceCount([]VmInput{vm}, []string{idempotencyKey}, clientToken)
+	if err != nil {
+		return output, err
+	}
+	return outputs[0], nil
+}
+
+//vmCreateProgress is saved by the idempotency key of the vm once qcloud accepts the RunInstances call,
+//a retry after the wait failed resumes the instance with the same password instead of launching it again
+type vmCreateProgress struct {
+	InstanceId string `json:"instance_id"`
+	Password   string `json:"password,omitempty"`
+}
+
+//launchVms launches the vms of the indexes with the spec of the first one in one RunInstances call,
+//only one vm is launched with a password, which is encrypted by its guid and seed
+func launchVms(client CvmAPI, vms []VmInput, keys []string, indexes []int, clientToken string, progresses []vmCreateProgress) error {
+	vm := vms[indexes[0]]
+	paramsMap, _ := GetMapFromProviderParams(vm.ProviderParams)
+
+	//instances logged in with key pairs have no password
+	password := ""
+	if len(splitCommaValues(vm.KeyIds)) == 0 {
+		password = utils.CreateRandomPassword()
+	}
+
+	runInstanceRequest, err := buildRunInstanceRequest(vm, getVmZone(vm, paramsMap), password, clientToken)
+	if err != nil {
+		return err
+	}
+	if len(indexes) > 1 {
+		runInstanceRequest.InstanceCount = int64(len(indexes))
+	}
+
+	request := cvm.NewRunInstancesRequest()
+	byteRunInstancesRequestData, _ := json.Marshal(runInstanceRequest)
+	logrus.Debugf("byteRunInstancesRequestData=%v", string(byteRunInstancesRequestData))
+	request.FromJsonString(string(byteRunInstancesRequestData))
+
+	resp, err := client.RunInstances(request)
+	if err != nil {
+		return err
+	}
+	if len(resp.Response.InstanceIdSet) != len(indexes) {
+		return fmt.Errorf("run %d instances but got %d instance ids", len(indexes), len(resp.Response.InstanceIdSet))
+	}
+	logrus.Infof("Create VM's request has been submitted, InstanceIds are %v, RequestID is [%v]", common.StringValues(resp.Response.InstanceIdSet), *resp.Response.RequestId)
+
+	for n, i := range indexes {
+		progresses[i] = vmCreateProgress{InstanceId: *resp.Response.InstanceIdSet[n]}
+		if password != "" {
+			if progresses[i].Password, err = encryptVmPassword(vms[i], password); err != nil {
+				return err
+			}
+		}
+		if err = saveIdempotencyProgress(keys[i], progresses[i]); err != nil {
+			logrus.Errorf("save progress of vm[%s] meet err=%v", progresses[i].InstanceId, err)
+		}
+	}
+	return nil
+}
+
+//createVmsWithInstanceCount launches the vms with the spec of the first one in one RunInstances call,
+//the vms launched by an earlier try of their idempotency keys are only waited for
+func createVmsWithInstanceCount(vms []VmInput, keys []string, clientToken string) ([]VmOutput, error) {
+	paramsMap, err := GetMapFromProviderParams(vms[0].ProviderParams)
+	client, err := createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
 	if err != nil {
 		return nil, err
 	}
-	cvmFilter := &cvm.Filter{
-		Name:   common.StringPtr(name),
-		Values: common.StringPtrs(filter.Values),
+
+	progresses := make([]vmCreateProgress, len(vms))
+	launchIndexes := []int{}
+	for i, key := range keys {
+		found, err := loadIdempotencyProgress(key, &progresses[i])
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			logrus.Infof("vm[%s] of guid[%s] was launched before, wait for it", progresses[i].InstanceId, vms[i].Guid)
+		} else {
+			launchIndexes = append(launchIndexes, i)
+		}
+	}
+	if len(launchIndexes) > 0 {
+		if err = launchVms(client, vms, keys, launchIndexes, clientToken, progresses); err != nil {
+			return nil, err
+		}
 	}
-	request.Filters = append(request.Filters, cvmFilter)
 
-	response, err := client.DescribeInstances(request)
+	instanceIds := []string{}
+	for _, progress := range progresses {
+		instanceIds = append(instanceIds, progress.InstanceId)
+	}
+	if err = waitVmsInDesireState(client, instanceIds, INSTANCE_STATE_RUNNING, getVmCreateWaitTimeout(len(instanceIds))); err != nil {
+		return nil, err
+	}
+	logrus.Infof("Created VM's state is [%v] now", INSTANCE_STATE_RUNNING)
+
+	//qcloud returns 20 instances by default
+	describeInstancesParams := cvm.DescribeInstancesRequest{
+		InstanceIds: common.StringPtrs(instanceIds),
+		Limit:       common.Int64Ptr(int64(len(instanceIds))),
+	}
+	describeInstancesResponse, err := describeInstancesFromCvm(client, describeInstancesParams)
 	if err != nil {
-		logrus.Errorf("cvm DescribeInstances meet err=%v", err)
 		return nil, err
 	}
+	instances := make(map[string]*cvm.Instance)
+	for _, instance := range describeInstancesResponse.Response.InstanceSet {
+		instances[*instance.InstanceId] = instance
+	}
 
-	return response.Response.InstanceSet, nil
+	outputs := []VmOutput{}
+	for i, vm := range vms {
+		instance, found := instances[instanceIds[i]]
+		if !found {
+			logrus.Errorf("created vm[%s] not found", instanceIds[i])
+			return nil, VM_NOT_FOUND_ERROR
+		}
+
+		output := VmOutput{}
+		output.Password = progresses[i].Password
+		output.RequestId = *describeInstancesResponse.Response.RequestId
+		output.Guid = vm.Guid
+		output.Id = instanceIds[i]
+		output.Memory = strconv.Itoa(int(*instance.Memory))
+		output.Cpu = strconv.Itoa(int(*instance.CPU))
+		output.InstanceState = *instance.InstanceState
+		output.InstancePrivateIp = *instance.PrivateIpAddresses[0]
+		outputs = append(outputs, output)
+	}
+	return outputs, nil
 }
 
-func BindCvmInstanceSecurityGroups(providerParams string, instanceId string, securityGroups []string) error {
-	paramsMap, err := GetMapFromProviderParams(providerParams)
+//getVmCreateWaitTimeout gives qcloud more time to launch more instances in one call
+func getVmCreateWaitTimeout(instanceCount int) int {
+	return VM_CREATE_WAIT_TIMEOUT + (instanceCount-1)*VM_CREATE_WAIT_TIMEOUT_PER_INSTANCE
+}
+
+//encryptVmPassword encrypts the password with the guid and seed of the vm
+func encryptVmPassword(vm VmInput, password string) (string, error) {
+	md5sum := utils.Md5Encode(vm.Guid + vm.Seed)
+	encryptedPassword, err := utils.AesEncode(md5sum[0:16], password)
 	if err != nil {
+		logrus.Errorf("AesEncode meet error(%v)", err)
+		return "", errors.New("aes encode error")
+	}
+	return encryptedPassword, nil
+}
+
+type VMTerminateAction struct {
+	VMAction
+}
+
+func (action *VMTerminateAction) Do(input interface{}) (interface{}, error) {
+	vms, _ := input.(VmInputs)
+	outputs := VmOutputs{}
+
+	for _, vm := range vms.Inputs {
+		paramsMap, err := GetMapFromProviderParams(vm.ProviderParams)
+
+		terminateInstancesRequestData := cvm.TerminateInstancesRequest{
+			InstanceIds: []*string{&vm.Id},
+		}
+
+		client, err := createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+		if err != nil {
+			return nil, err
+		}
+		terminateInstancesRequest := cvm.NewTerminateInstancesRequest()
+		byteTerminateInstancesRequestData, _ := json.Marshal(terminateInstancesRequestData)
+		terminateInstancesRequest.FromJsonString(string(byteTerminateInstancesRequestData))
+
+		response, err := client.TerminateInstances(terminateInstancesRequest)
+		if err != nil {
+			return nil, err
+		}
+		logrus.Infof("Terminate VM[%v] has been submitted in Qcloud, RequestID is [%v]", vm.Id, *response.Response.RequestId)
+
+		if err = waitVmTerminateDone(client, vm.Id, 600); err != nil {
+			return nil, err
+		}
+		output := VmOutput{}
+		output.RequestId = *response.Response.RequestId
+		output.Guid = vm.Guid
+		output.Id = vm.Id
+
+		outputs.Outputs = append(outputs.Outputs, output)
+
+		logrus.Infof("Terminated VM[%v] has been done", vm.Id)
+	}
+
+	return &outputs, nil
+}
+
+//checkVmStateChangeParams validates the options shared by start, stop and reboot
+func checkVmStateChangeParams(vms VmInputs, withStopType bool, withStoppedMode bool) error {
+	for _, vm := range vms.Inputs {
+		if vm.Wait != "" {
+			if _, err := strconv.ParseBool(vm.Wait); err != nil {
+				return fmt.Errorf("invalid wait(%s)", vm.Wait)
+			}
+		}
+		if withStopType && vm.StopType != "" {
+			if err := isValidValue(vm.StopType, []string{VM_STOP_TYPE_SOFT, VM_STOP_TYPE_HARD, VM_STOP_TYPE_SOFT_FIRST}); err != nil {
+				return err
+			}
+		}
+		if withStoppedMode && vm.StoppedMode != "" {
+			if err := isValidValue(vm.StoppedMode, []string{VM_STOPPED_MODE_KEEP_CHARGING, VM_STOPPED_MODE_STOP_CHARGING}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//changeVmsState submits the change of all the vms before waiting for any of them,
+//so the vms change their state at the same time, waitRequest waits for the change request to succeed
+//before the state, for a change like reboot which leaves the vm in the desire state when it is submitted
+func changeVmsState(vms VmInputs, desireState string, waitRequest bool, change func(client CvmAPI, vm VmInput) (string, error)) (*VmOutputs, error) {
+	clients := make([]CvmAPI, len(vms.Inputs))
+	requestIds := make([]string, len(vms.Inputs))
+	for i, vm := range vms.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(vm.ProviderParams)
+		client, err := createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+		if err != nil {
+			return nil, err
+		}
+		if requestIds[i], err = change(client, vm); err != nil {
+			return nil, err
+		}
+		clients[i] = client
+	}
+
+	outputs := VmOutputs{}
+	for i, vm := range vms.Inputs {
+		if wait, _ := strconv.ParseBool(vm.Wait); wait {
+			if waitRequest {
+				if err := waitVmOperationDone(clients[i], vm.Id, requestIds[i], 600); err != nil {
+					return nil, err
+				}
+			}
+			if err := waitVmsInDesireState(clients[i], []string{vm.Id}, desireState, 600); err != nil {
+				return nil, err
+			}
+		}
+
+		//without waiting the state is the one right after the change, such as STARTING
+		output, err := describeVmOutput(clients[i], vm)
+		if err != nil {
+			return nil, err
+		}
+		output.RequestId = requestIds[i]
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+type VMStartAction struct {
+	VMAction
+}
+
+func (action *VMStartAction) CheckParam(input interface{}) error {
+	if err := action.VMAction.CheckParam(input); err != nil {
 		return err
 	}
-	client, err := createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+	return checkVmStateChangeParams(input.(VmInputs), false, false)
+}
+
+func (action *VMStartAction) Do(input interface{}) (interface{}, error) {
+	vms, _ := input.(VmInputs)
+	return changeVmsState(vms, INSTANCE_STATE_RUNNING, false, action.startInstance)
+}
+
+func (action *VMStartAction) startInstance(client CvmAPI, vm VmInput) (string, error) {
+	request := cvm.NewStartInstancesRequest()
+	request.InstanceIds = append(request.InstanceIds, &vm.Id)
+
+	response, err := client.StartInstances(request)
 	if err != nil {
-		return err
+		return "", err
 	}
+	logrus.Infof("Start VM[%v] has been submitted, RequestID is [%v]", vm.Id, *response.Response.RequestId)
+	return *response.Response.RequestId, nil
+}
 
-	request := cvm.NewModifyInstancesAttributeRequest()
-	request.InstanceIds = common.StringPtrs([]string{instanceId})
-	request.SecurityGroups = common.StringPtrs(securityGroups)
-	if _, err = client.ModifyInstancesAttribute(request); err != nil {
-		logrus.Errorf("cvm AssociateSecurityGroups meet err=%v", err)
+type VMStopAction struct {
+	VMAction
+}
+
+func (action *VMStopAction) CheckParam(input interface{}) error {
+	if err := action.VMAction.CheckParam(input); err != nil {
+		return err
 	}
+	return checkVmStateChangeParams(input.(VmInputs), true, true)
+}
 
+func (action *VMStopAction) Do(input interface{}) (interface{}, error) {
+	vms, _ := input.(VmInputs)
+	return changeVmsState(vms, INSTANCE_STATE_STOPPED, false, action.stopInstance)
+}
+
+func (action *VMStopAction) stopInstance(client CvmAPI, vm VmInput) (string, error) {
+	request := cvm.NewStopInstancesRequest()
+	request.InstanceIds = append(request.InstanceIds, &vm.Id)
+	if vm.StopType != "" {
+		request.StopType = &vm.StopType
+	}
+	//qcloud only stops charging the pay-as-you-go instances with cloud disks
+	if vm.StoppedMode != "" {
+		request.StoppedMode = &vm.StoppedMode
+	}
+
+	response, err := client.StopInstances(request)
+	if err != nil {
+		return "", err
+	}
+	logrus.Infof("Stop VM[%v] has been submitted, RequestID is [%v]", vm.Id, *response.Response.RequestId)
+	return *response.Response.RequestId, nil
+}
+
+type VMRebootAction struct {
+	VMAction
+}
+
+func (action *VMRebootAction) CheckParam(input interface{}) error {
+	if err := action.VMAction.CheckParam(input); err != nil {
+		return err
+	}
+	return checkVmStateChangeParams(input.(VmInputs), true, false)
+}
+
+func (action *VMRebootAction) Do(input interface{}) (interface{}, error) {
+	vms, _ := input.(VmInputs)
+	return changeVmsState(vms, INSTANCE_STATE_RUNNING, true, action.rebootInstance)
+}
+
+func (action *VMRebootAction) rebootInstance(client CvmAPI, vm VmInput) (string, error) {
+	request := cvm.NewRebootInstancesRequest()
+	request.InstanceIds = append(request.InstanceIds, &vm.Id)
+	if vm.StopType != "" {
+		request.StopType = &vm.StopType
+	}
+
+	response, err := client.RebootInstances(request)
+	if err != nil {
+		return "", err
+	}
+	logrus.Infof("Reboot VM[%v] has been submitted, RequestID is [%v]", vm.Id, *response.Response.RequestId)
+	return *response.Response.RequestId, nil
+}
+
+func queryVmInstanceInfo(client CvmAPI, instanceId string) (*cvm.Instance, bool, error) {
+	describeInstancesParams := cvm.DescribeInstancesRequest{
+		InstanceIds: []*string{&instanceId},
+	}
+	describeInstancesResponse, err := describeInstancesFromCvm(client, describeInstancesParams)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(describeInstancesResponse.Response.InstanceSet) == 0 {
+		return nil, false, nil
+	}
+	if len(describeInstancesResponse.Response.InstanceSet) > 1 {
+		logrus.Errorf("check vm exsit found vm[%s] have %d instance", instanceId, len(describeInstancesResponse.Response.InstanceSet))
+		return nil, false, VM_NOT_FOUND_ERROR
+	}
+	return describeInstancesResponse.Response.InstanceSet[0], true, nil
+}
+
+func (action *VMCreateAction) DryRun(input interface{}) (interface{}, error) {
+	vms, _ := input.(VmInputs)
+	outputs := DryRunOutputs{}
+	keys := getVmCreateKeys(vms.Inputs)
+	spreadVmsAcrossZones(vms.Inputs)
+	if err := forPendingVmCreates(vms.Inputs, keys, fallbackVmZones); err != nil {
+		return nil, err
+	}
+	for _, vm := range vms.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(vm.ProviderParams)
+		client, err := createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+		if err != nil {
+			return nil, err
+		}
+
+		exist := false
+		if vm.Id != "" {
+			if _, exist, err = queryVmInstanceInfo(client, vm.Id); err != nil {
+				return nil, err
+			}
+		}
+		detail := fmt.Sprintf("run instance type[%s] image[%s] in zone[%s] subnet[%s]", vm.InstanceType, vm.ImageId, getVmZone(vm, paramsMap), vm.SubnetId)
+		outputs.Outputs = append(outputs.Outputs, newCreateDryRunOutput(vm.Guid, vm.Id, exist, detail))
+	}
+	return &outputs, nil
+}
+
+func (action *VMTerminateAction) DryRun(input interface{}) (interface{}, error) {
+	vms, _ := input.(VmInputs)
+	outputs := DryRunOutputs{}
+	for _, vm := range vms.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(vm.ProviderParams)
+		client, err := createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+		if err != nil {
+			return nil, err
+		}
+
+		instance, exist, err := queryVmInstanceInfo(client, vm.Id)
+		if err != nil {
+			return nil, err
+		}
+		detail := ""
+		if exist {
+			detail = fmt.Sprintf("terminate instance in state[%s]", *instance.InstanceState)
+		}
+		outputs.Outputs = append(outputs.Outputs, newDeleteDryRunOutput(vm.Guid, vm.Id, exist, detail))
+	}
+	return &outputs, nil
+}
+
+func dryRunVmStateChange(vms VmInputs, desireState string) (*DryRunOutputs, error) {
+	outputs := DryRunOutputs{}
+	for _, vm := range vms.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(vm.ProviderParams)
+		client, err := createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+		if err != nil {
+			return nil, err
+		}
+
+		instance, exist, err := queryVmInstanceInfo(client, vm.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		output := newDryRunOutput(vm.Guid, vm.Id, exist, DRY_RUN_OPERATION_NONE, "")
+		switch {
+		case !exist:
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("instance[%s] not found", vm.Id))
+		case *instance.InstanceState == desireState:
+			output.Detail = fmt.Sprintf("instance already in state[%s]", desireState)
+		default:
+			output.Operation = DRY_RUN_OPERATION_MODIFY
+			output.Detail = fmt.Sprintf("change instance state from [%s] to [%s]", *instance.InstanceState, desireState)
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+//dryRunVmChange previews the change of each vm with preview, the vms not found are reported as conflicts
+func dryRunVmChange(vms VmInputs, preview func(client CvmAPI, vm VmInput, instance *cvm.Instance, output *DryRunOutput) error) (*DryRunOutputs, error) {
+	outputs := DryRunOutputs{}
+	for _, vm := range vms.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(vm.ProviderParams)
+		client, err := createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+		if err != nil {
+			return nil, err
+		}
+
+		instance, exist, err := queryVmInstanceInfo(client, vm.Id)
+		if err != nil {
+			return nil, err
+		}
+		output := newDryRunOutput(vm.Guid, vm.Id, exist, DRY_RUN_OPERATION_NONE, "")
+		if !exist {
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("instance[%s] not found", vm.Id))
+		} else if err = preview(client, vm, instance, &output); err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+func (action *VMStartAction) DryRun(input interface{}) (interface{}, error) {
+	vms, _ := input.(VmInputs)
+	return dryRunVmStateChange(vms, INSTANCE_STATE_RUNNING)
+}
+
+func (action *VMStopAction) DryRun(input interface{}) (interface{}, error) {
+	vms, _ := input.(VmInputs)
+	return dryRunVmStateChange(vms, INSTANCE_STATE_STOPPED)
+}
+
+//only running vms can be rebooted
+func (action *VMRebootAction) DryRun(input interface{}) (interface{}, error) {
+	vms, _ := input.(VmInputs)
+	return dryRunVmChange(vms, func(client CvmAPI, vm VmInput, instance *cvm.Instance, output *DryRunOutput) error {
+		if *instance.InstanceState != INSTANCE_STATE_RUNNING {
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("instance is in state[%s], should be %s", *instance.InstanceState, INSTANCE_STATE_RUNNING))
+			return nil
+		}
+		output.Operation = DRY_RUN_OPERATION_MODIFY
+		output.Detail = "reboot instance"
+		if vm.StopType != "" {
+			output.Detail += fmt.Sprintf(" with stop type[%s]", vm.StopType)
+		}
+		return nil
+	})
+}
+
+func QueryCvmInstance(providerParams string, filter Filter) ([]*cvm.Instance, error) {
+	validFilterNames := []string{"instanceId", "privateIpAddress"}
+	filterValues := common.StringPtrs(filter.Values)
+	var limit int64
+
+	paramsMap, err := GetMapFromProviderParams(providerParams)
+	if err != nil {
+		return nil, err
+	}
+	client, err := createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := isValidValue(filter.Name, validFilterNames); err != nil {
+		return nil, err
+	}
+
+	request := cvm.NewDescribeInstancesRequest()
+	limit = int64(len(filterValues))
+	request.Limit = &limit
+	name, err := TransLittleCamelcaseToShortLineFormat(filter.Name)
+	if err != nil {
+		return nil, err
+	}
+	cvmFilter := &cvm.Filter{
+		Name:   common.StringPtr(name),
+		Values: common.StringPtrs(filter.Values),
+	}
+	request.Filters = append(request.Filters, cvmFilter)
+
+	response, err := client.DescribeInstances(request)
+	if err != nil {
+		logrus.Errorf("cvm DescribeInstances meet err=%v", err)
+		return nil, err
+	}
+
+	return response.Response.InstanceSet, nil
+}
+
+func BindCvmInstanceSecurityGroups(providerParams string, instanceId string, securityGroups []string) error {
+	paramsMap, err := GetMapFromProviderParams(providerParams)
+	if err != nil {
+		return err
+	}
+	client, err := createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+	if err != nil {
+		return err
+	}
+
+	request := cvm.NewModifyInstancesAttributeRequest()
+	request.InstanceIds = common.StringPtrs([]string{instanceId})
+	request.SecurityGroups = common.StringPtrs(securityGroups)
+	if _, err = client.ModifyInstancesAttribute(request); err != nil {
+		logrus.Errorf("cvm AssociateSecurityGroups meet err=%v", err)
+	}
+
+	return err
+}
+
+//describeVmOutput returns the current cpu, memory and state of the vm
+func describeVmOutput(client CvmAPI, vm VmInput) (VmOutput, error) {
+	output := VmOutput{}
+	describeInstancesParams := cvm.DescribeInstancesRequest{
+		InstanceIds: []*string{&vm.Id},
+	}
+	describeInstancesResponse, err := describeInstancesFromCvm(client, describeInstancesParams)
+	if err != nil {
+		return output, err
+	}
+	if len(describeInstancesResponse.Response.InstanceSet) != 1 {
+		logrus.Errorf("found vm[%s] have %d instance", vm.Id, len(describeInstancesResponse.Response.InstanceSet))
+		return output, VM_NOT_FOUND_ERROR
+	}
+
+	instance := describeInstancesResponse.Response.InstanceSet[0]
+	output.RequestId = *describeInstancesResponse.Response.RequestId
+	output.Guid = vm.Guid
+	output.Id = vm.Id
+	output.Memory = strconv.Itoa(int(*instance.Memory))
+	output.Cpu = strconv.Itoa(int(*instance.CPU))
+	output.InstanceState = *instance.InstanceState
+	if len(instance.PrivateIpAddresses) > 0 {
+		output.InstancePrivateIp = *instance.PrivateIpAddresses[0]
+	}
+	return output, nil
+}
+
+//runWithVmStopped stops the running vm before change and starts it again afterwards,
+//the vm is started again even if change fails
+func runWithVmStopped(client CvmAPI, instance *cvm.Instance, change func() error) error {
+	instanceId := *instance.InstanceId
+	wasRunning := *instance.InstanceState == INSTANCE_STATE_RUNNING
+	if !wasRunning && *instance.InstanceState != INSTANCE_STATE_STOPPED {
+		return fmt.Errorf("vm[%s] is in state[%s], should be %s or %s", instanceId, *instance.InstanceState, INSTANCE_STATE_RUNNING, INSTANCE_STATE_STOPPED)
+	}
+
+	if wasRunning {
+		request := cvm.NewStopInstancesRequest()
+		request.InstanceIds = []*string{&instanceId}
+		if _, err := client.StopInstances(request); err != nil {
+			return err
+		}
+		if err := waitVmsInDesireState(client, []string{instanceId}, INSTANCE_STATE_STOPPED, 600); err != nil {
+			return err
+		}
+		logrus.Infof("vm[%s] is stopped for the change", instanceId)
+	}
+
+	err := change()
+	if !wasRunning {
+		return err
+	}
+
+	request := cvm.NewStartInstancesRequest()
+	request.InstanceIds = []*string{&instanceId}
+	if _, startErr := client.StartInstances(request); startErr != nil {
+		logrus.Errorf("start vm[%s] after the change meet err=%v", instanceId, startErr)
+		if err == nil {
+			err = startErr
+		}
+		return err
+	}
+	if waitErr := waitVmsInDesireState(client, []string{instanceId}, INSTANCE_STATE_RUNNING, 600); waitErr != nil && err == nil {
+		err = waitErr
+	}
 	return err
 }
+
+//previewRunWithVmStopped previews the change done by runWithVmStopped
+func previewRunWithVmStopped(instance *cvm.Instance, output *DryRunOutput, detail string) {
+	switch *instance.InstanceState {
+	case INSTANCE_STATE_RUNNING:
+		output.Operation = DRY_RUN_OPERATION_MODIFY
+		output.Detail = detail + ", the instance is stopped before and started again after"
+	case INSTANCE_STATE_STOPPED:
+		output.Operation = DRY_RUN_OPERATION_MODIFY
+		output.Detail = detail
+	default:
+		output.Conflicts = append(output.Conflicts, fmt.Sprintf("instance is in state[%s], should be %s or %s",
+			*instance.InstanceState, INSTANCE_STATE_RUNNING, INSTANCE_STATE_STOPPED))
+	}
+}
+
+type VMResizeAction struct {
+	VMAction
+}
+
+func (action *VMResizeAction) CheckParam(input interface{}) error {
+	if err := action.VMAction.CheckParam(input); err != nil {
+		return err
+	}
+	vms, _ := input.(VmInputs)
+	for _, vm := range vms.Inputs {
+		if vm.InstanceType == "" {
+			return errors.New("input instance_type is empty")
+		}
+	}
+	return nil
+}
+
+func (action *VMResizeAction) Do(input interface{}) (interface{}, error) {
+	vms, _ := input.(VmInputs)
+	outputs := VmOutputs{}
+	for _, vm := range vms.Inputs {
+		output, err := resizeVm(vm)
+		if err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+func resizeVm(vm VmInput) (VmOutput, error) {
+	paramsMap, _ := GetMapFromProviderParams(vm.ProviderParams)
+	client, err := createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+	if err != nil {
+		return VmOutput{}, err
+	}
+
+	instance, err := getInstanceByInstanceId(client, vm.Id)
+	if err != nil {
+		return VmOutput{}, err
+	}
+	if *instance.InstanceType == vm.InstanceType {
+		logrus.Infof("vm[%s] is already of instance type[%s]", vm.Id, vm.InstanceType)
+		return describeVmOutput(client, vm)
+	}
+
+	//qcloud only changes the instance type of stopped instances
+	err = runWithVmStopped(client, instance, func() error {
+		request := cvm.NewResetInstancesTypeRequest()
+		request.InstanceIds = []*string{&vm.Id}
+		request.InstanceType = &vm.InstanceType
+		response, err := client.ResetInstancesType(request)
+		if err != nil {
+			return err
+		}
+		logrus.Infof("Resize VM[%v] to [%v] has been submitted, RequestID is [%v]", vm.Id, vm.InstanceType, *response.Response.RequestId)
+
+		return waitVmsUntil(client, []string{vm.Id}, 600, func(instance *cvm.Instance) bool {
+			return *instance.InstanceType == vm.InstanceType && *instance.InstanceState == INSTANCE_STATE_STOPPED
+		})
+	})
+	if err != nil {
+		return VmOutput{}, err
+	}
+	return describeVmOutput(client, vm)
+}
+
+func (action *VMResizeAction) DryRun(input interface{}) (interface{}, error) {
+	vms, _ := input.(VmInputs)
+	return dryRunVmChange(vms, func(client CvmAPI, vm VmInput, instance *cvm.Instance, output *DryRunOutput) error {
+		if *instance.InstanceType == vm.InstanceType {
+			output.Detail = fmt.Sprintf("instance already of type[%s]", vm.InstanceType)
+			return nil
+		}
+		previewRunWithVmStopped(instance, output, fmt.Sprintf("change instance type from [%s] to [%s]", *instance.InstanceType, vm.InstanceType))
+		return nil
+	})
+}
+
+type VMReinstallAction struct {
+	VMAction
+}
+
+func (action *VMReinstallAction) CheckParam(input interface{}) error {
+	if err := action.VMAction.CheckParam(input); err != nil {
+		return err
+	}
+	vms, _ := input.(VmInputs)
+	for _, vm := range vms.Inputs {
+		if vm.ImageId == "" {
+			return errors.New("input image_id is empty")
+		}
+	}
+	return nil
+}
+
+func (action *VMReinstallAction) Do(input interface{}) (interface{}, error) {
+	vms, _ := input.(VmInputs)
+	outputs := VmOutputs{}
+	for _, vm := range vms.Inputs {
+		output, err := reinstallVm(vm)
+		if err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+func reinstallVm(vm VmInput) (VmOutput, error) {
+	paramsMap, _ := GetMapFromProviderParams(vm.ProviderParams)
+	client, err := createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+	if err != nil {
+		return VmOutput{}, err
+	}
+
+	if vm.ImageId, err = resolveImageId(client, vm.ImageId); err != nil {
+		return VmOutput{}, err
+	}
+
+	//the reinstalled vm gets a new password like a created one, unless it logs in with key pairs
+	request := cvm.NewResetInstanceRequest()
+	request.InstanceId = &vm.Id
+	request.ImageId = &vm.ImageId
+	request.LoginSettings = &cvm.LoginSettings{}
+	password := ""
+	if keyIds := splitCommaValues(vm.KeyIds); len(keyIds) > 0 {
+		request.LoginSettings.KeyIds = common.StringPtrs(keyIds)
+	} else {
+		password = utils.CreateRandomPassword()
+		request.LoginSettings.Password = &password
+	}
+	if vm.HostName != "" {
+		request.HostName = &vm.HostName
+	}
+
+	response, err := client.ResetInstance(request)
+	if err != nil {
+		return VmOutput{}, err
+	}
+	logrus.Infof("Reinstall VM[%v] with image[%v] has been submitted, RequestID is [%v]", vm.Id, vm.ImageId, *response.Response.RequestId)
+
+	//qcloud starts the vm with the new image whether it was running or stopped, the image and the state
+	//of the vm may already match before the reinstall starts, so only the reinstall request is waited for
+	if err = waitVmOperationDone(client, vm.Id, *response.Response.RequestId, 600); err != nil {
+		return VmOutput{}, err
+	}
+
+	output, err := describeVmOutput(client, vm)
+	if err != nil || password == "" {
+		return output, err
+	}
+	output.Password, err = encryptVmPassword(vm, password)
+	return output, err
+}
+
+func (action *VMReinstallAction) DryRun(input interface{}) (interface{}, error) {
+	vms, _ := input.(VmInputs)
+	return dryRunVmChange(vms, func(client CvmAPI, vm VmInput, instance *cvm.Instance, output *DryRunOutput) error {
+		imageId, err := resolveImageId(client, vm.ImageId)
+		if err != nil {
+			return err
+		}
+		login := "a new password"
+		if len(splitCommaValues(vm.KeyIds)) > 0 {
+			login = "key pairs[" + vm.KeyIds + "]"
+		}
+		output.Operation = DRY_RUN_OPERATION_MODIFY
+		output.Detail = fmt.Sprintf("reinstall instance from image[%s] to [%s] with %s, the system disk is wiped and the instance is started", *instance.ImageId, imageId, login)
+		return nil
+	})
+}
+
+type VMResetPasswordAction struct {
+	VMAction
+}
+
+func (action *VMResetPasswordAction) Do(input interface{}) (interface{}, error) {
+	vms, _ := input.(VmInputs)
+	outputs := VmOutputs{}
+	for _, vm := range vms.Inputs {
+		output, err := resetVmPassword(vm)
+		if err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+func resetVmPassword(vm VmInput) (VmOutput, error) {
+	paramsMap, _ := GetMapFromProviderParams(vm.ProviderParams)
+	client, err := createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+	if err != nil {
+		return VmOutput{}, err
+	}
+
+	instance, err := getInstanceByInstanceId(client, vm.Id)
+	if err != nil {
+		return VmOutput{}, err
+	}
+
+	//qcloud only resets the password of stopped instances
+	password := utils.CreateRandomPassword()
+	err = runWithVmStopped(client, instance, func() error {
+		request := cvm.NewResetInstancesPasswordRequest()
+		request.InstanceIds = []*string{&vm.Id}
+		request.Password = &password
+		response, err := client.ResetInstancesPassword(request)
+		if err != nil {
+			return err
+		}
+		logrus.Infof("Reset password of VM[%v] has been submitted, RequestID is [%v]", vm.Id, *response.Response.RequestId)
+
+		//the vm is started again only after the new password is set
+		return waitVmOperationDone(client, vm.Id, *response.Response.RequestId, 600)
+	})
+	if err != nil {
+		return VmOutput{}, err
+	}
+
+	output, err := describeVmOutput(client, vm)
+	if err != nil {
+		return output, err
+	}
+	output.Password, err = encryptVmPassword(vm, password)
+	return output, err
+}
+
+func (action *VMResetPasswordAction) DryRun(input interface{}) (interface{}, error) {
+	vms, _ := input.(VmInputs)
+	return dryRunVmChange(vms, func(client CvmAPI, vm VmInput, instance *cvm.Instance, output *DryRunOutput) error {
+		previewRunWithVmStopped(instance, output, "reset the password of instance to a new one")
+		return nil
+	})
+}
+
+type VMDescribeAction struct {
+	VMAction
+}
+
+//the instances are looked up by ids, or by private ip, name and tags, all the instances are returned without them
+func (action *VMDescribeAction) CheckParam(input interface{}) error {
+	vms, ok := input.(VmInputs)
+	if !ok {
+		return INVALID_PARAMETERS
+	}
+
+	for _, vm := range vms.Inputs {
+		if _, err := parseVmTags(vm.Tags); err != nil {
+			return err
+		}
+		if vm.Id != "" && (vm.InstancePrivateIp != "" || vm.InstanceName != "" || vm.Tags != "") {
+			return errors.New("input id can't be used together with instance_private_ip, instance_name or tags")
+		}
+	}
+	return nil
+}
+
+func (action *VMDescribeAction) Do(input interface{}) (interface{}, error) {
+	vms, _ := input.(VmInputs)
+	outputs := VmOutputs{}
+	for _, vm := range vms.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(vm.ProviderParams)
+		client, err := createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+		if err != nil {
+			return nil, err
+		}
+
+		instances, requestId, err := describeVmInstances(client, vm)
+		if err != nil {
+			return nil, err
+		}
+		logrus.Infof("describe vms of guid[%s] found %d instances", vm.Guid, len(instances))
+		for _, instance := range instances {
+			outputs.Outputs = append(outputs.Outputs, newVmDescribeOutput(vm.Guid, requestId, instance))
+		}
+	}
+	return &outputs, nil
+}
+
+//describeVmInstances pages over DescribeInstances for all the instances matching the input
+func describeVmInstances(client CvmAPI, vm VmInput) ([]*cvm.Instance, string, error) {
+	request := cvm.NewDescribeInstancesRequest()
+	if instanceIds := splitCommaValues(vm.Id); len(instanceIds) > 0 {
+		request.InstanceIds = common.StringPtrs(instanceIds)
+	}
+	if vm.InstancePrivateIp != "" {
+		request.Filters = append(request.Filters, &cvm.Filter{Name: common.StringPtr("private-ip-address"), Values: common.StringPtrs(splitCommaValues(vm.InstancePrivateIp))})
+	}
+	if vm.InstanceName != "" {
+		request.Filters = append(request.Filters, &cvm.Filter{Name: common.StringPtr("instance-name"), Values: []*string{&vm.InstanceName}})
+	}
+	tags, _ := parseVmTags(vm.Tags)
+	for _, tag := range tags {
+		request.Filters = append(request.Filters, &cvm.Filter{Name: common.StringPtr("tag:" + tag.Key), Values: []*string{common.StringPtr(tag.Value)}})
+	}
+	return describeAllVmInstances(client, request)
+}
+
+//describeAllVmInstances pages over DescribeInstances until all the instances matching the request are returned
+func describeAllVmInstances(client CvmAPI, request *cvm.DescribeInstancesRequest) ([]*cvm.Instance, string, error) {
+	request.Limit = common.Int64Ptr(VM_DESCRIBE_PAGE_SIZE)
+
+	instances := []*cvm.Instance{}
+	requestId := ""
+	for offset := int64(0); ; {
+		request.Offset = common.Int64Ptr(offset)
+		response, err := client.DescribeInstances(request)
+		if err != nil {
+			logrus.Errorf("cvm DescribeInstances meet err=%v", err)
+			return nil, "", err
+		}
+
+		requestId = *response.Response.RequestId
+		instances = append(instances, response.Response.InstanceSet...)
+		offset += int64(len(response.Response.InstanceSet))
+		if len(response.Response.InstanceSet) == 0 || offset >= *response.Response.TotalCount {
+			break
+		}
+	}
+	return instances, requestId, nil
+}
+
+//newVmDescribeOutput flattens the instance, the lists are joined in the formats of the create inputs
+func newVmDescribeOutput(guid string, requestId string, instance *cvm.Instance) VmOutput {
+	output := VmOutput{
+		Guid:               guid,
+		RequestId:          requestId,
+		Id:                 stringValue(instance.InstanceId),
+		InstanceName:       stringValue(instance.InstanceName),
+		InstanceType:       stringValue(instance.InstanceType),
+		InstanceState:      stringValue(instance.InstanceState),
+		ImageId:            stringValue(instance.ImageId),
+		InstanceChargeType: stringValue(instance.InstanceChargeType),
+		ExpiredTime:        stringValue(instance.ExpiredTime),
+		RenewFlag:          stringValue(instance.RenewFlag),
+		CreatedTime:        stringValue(instance.CreatedTime),
+		InstancePrivateIp:  strings.Join(common.StringValues(instance.PrivateIpAddresses), ","),
+		InstancePublicIp:   strings.Join(common.StringValues(instance.PublicIpAddresses), ","),
+		SecurityGroupIds:   strings.Join(common.StringValues(instance.SecurityGroupIds), ","),
+	}
+	if instance.CPU != nil {
+		output.Cpu = strconv.Itoa(int(*instance.CPU))
+	}
+	if instance.Memory != nil {
+		output.Memory = strconv.Itoa(int(*instance.Memory))
+	}
+	if instance.Placement != nil {
+		output.Zone = stringValue(instance.Placement.Zone)
+	}
+	if instance.VirtualPrivateCloud != nil {
+		output.VpcId = stringValue(instance.VirtualPrivateCloud.VpcId)
+		output.SubnetId = stringValue(instance.VirtualPrivateCloud.SubnetId)
+	}
+	if instance.SystemDisk != nil {
+		output.SystemDiskType = stringValue(instance.SystemDisk.DiskType)
+		if instance.SystemDisk.DiskSize != nil {
+			output.SystemDiskSize = strconv.FormatInt(*instance.SystemDisk.DiskSize, 10)
+		}
+	}
+
+	dataDisks := []string{}
+	for _, dataDisk := range instance.DataDisks {
+		if dataDisk.DiskType != nil && dataDisk.DiskSize != nil {
+			dataDisks = append(dataDisks, fmt.Sprintf("%s:%d", *dataDisk.DiskType, *dataDisk.DiskSize))
+		}
+	}
+	output.DataDisks = strings.Join(dataDisks, ",")
+
+	tags := []string{}
+	for _, tag := range instance.Tags {
+		if tag.Key != nil && tag.Value != nil {
+			tags = append(tags, *tag.Key+"="+*tag.Value)
+		}
+	}
+	output.Tags = strings.Join(tags, ";")
+	return output
+}
+
+//getVmRenewFlag returns the renew flag of the input, prepaid vms are renewed automatically by default
+func getVmRenewFlag(vm VmInput) string {
+	if vm.RenewFlag == "" {
+		return RENEW_FLAG_NOTIFY_AND_AUTO_RENEW
+	}
+	return vm.RenewFlag
+}
+
+func checkVmRenewFlag(renewFlag string) error {
+	if renewFlag == "" {
+		return nil
+	}
+	return isValidValue(renewFlag, []string{RENEW_FLAG_NOTIFY_AND_AUTO_RENEW, RENEW_FLAG_NOTIFY_AND_MANUAL_RENEW, RENEW_FLAG_DISABLE_NOTIFY_AND_MANUAL_RENEW})
+}
+
+//checkVmChargePeriod validates the months of the prepaid actions, the renew flag is optional
+func checkVmChargePeriod(input interface{}) error {
+	vms, _ := input.(VmInputs)
+	for _, vm := range vms.Inputs {
+		if vm.InstanceChargePeriod <= 0 {
+			return fmt.Errorf("invalid instance_charge_period(%v)", vm.InstanceChargePeriod)
+		}
+		if err := checkVmRenewFlag(vm.RenewFlag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//newVmChargeOutput reports the billing attributes of the vm
+func newVmChargeOutput(guid string, requestId string, instance *cvm.Instance) VmOutput {
+	return VmOutput{
+		Guid:               guid,
+		RequestId:          requestId,
+		Id:                 stringValue(instance.InstanceId),
+		InstanceState:      stringValue(instance.InstanceState),
+		InstanceChargeType: stringValue(instance.InstanceChargeType),
+		ExpiredTime:        stringValue(instance.ExpiredTime),
+		RenewFlag:          stringValue(instance.RenewFlag),
+	}
+}
+
+type VMRenewAction struct {
+	VMAction
+}
+
+func (action *VMRenewAction) CheckParam(input interface{}) error {
+	if err := action.VMAction.CheckParam(input); err != nil {
+		return err
+	}
+	return checkVmChargePeriod(input)
+}
+
+//renewing twice costs twice, so a retry with the same idempotency_key replays the first renewal,
+//renewals without idempotency_key are never deduplicated as the same input may renew the vm again on purpose
+func (action *VMRenewAction) Do(input interface{}) (interface{}, error) {
+	vms, _ := input.(VmInputs)
+	outputs := VmOutputs{}
+	for _, vm := range vms.Inputs {
+		output := VmOutput{}
+		var err error
+		if vm.IdempotencyKey == "" {
+			output, err = renewVm(vm)
+		} else {
+			err = runWithIdempotencyKey(getIdempotencyKey("vm-renew", vm.Guid, vm.IdempotencyKey, vm), &output, func() (interface{}, error) {
+				return renewVm(vm)
+			})
+		}
+		if err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+func renewVm(vm VmInput) (VmOutput, error) {
+	paramsMap, _ := GetMapFromProviderParams(vm.ProviderParams)
+	client, err := createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+	if err != nil {
+		return VmOutput{}, err
+	}
+
+	instance, err := getInstanceByInstanceId(client, vm.Id)
+	if err != nil {
+		return VmOutput{}, err
+	}
+	if *instance.InstanceChargeType != INSTANCE_CHARGE_TYPE_PREPAID {
+		return VmOutput{}, fmt.Errorf("vm[%s] is charged by %s, only %s vms can be renewed", vm.Id, *instance.InstanceChargeType, INSTANCE_CHARGE_TYPE_PREPAID)
+	}
+
+	request := cvm.NewRenewInstancesRequest()
+	request.InstanceIds = []*string{&vm.Id}
+	request.InstanceChargePrepaid = &cvm.InstanceChargePrepaid{Period: &vm.InstanceChargePeriod}
+	if vm.RenewFlag != "" {
+		request.InstanceChargePrepaid.RenewFlag = &vm.RenewFlag
+	}
+	response, err := client.RenewInstances(request)
+	if err != nil {
+		return VmOutput{}, err
+	}
+	logrus.Infof("Renew VM[%v] for %d months has been submitted, RequestID is [%v]", vm.Id, vm.InstanceChargePeriod, *response.Response.RequestId)
+
+	if instance, err = getInstanceByInstanceId(client, vm.Id); err != nil {
+		return VmOutput{}, err
+	}
+	return newVmChargeOutput(vm.Guid, *response.Response.RequestId, instance), nil
+}
+
+func (action *VMRenewAction) DryRun(input interface{}) (interface{}, error) {
+	vms, _ := input.(VmInputs)
+	return dryRunVmChange(vms, func(client CvmAPI, vm VmInput, instance *cvm.Instance, output *DryRunOutput) error {
+		if *instance.InstanceChargeType != INSTANCE_CHARGE_TYPE_PREPAID {
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("instance is charged by %s, only %s vms can be renewed", *instance.InstanceChargeType, INSTANCE_CHARGE_TYPE_PREPAID))
+			return nil
+		}
+		if vm.IdempotencyKey != "" {
+			done, err := isIdempotencyKeyDone(getIdempotencyKey("vm-renew", vm.Guid, vm.IdempotencyKey, vm))
+			if err != nil {
+				return err
+			}
+			if done {
+				output.Detail = fmt.Sprintf("instance already renewed with idempotency_key[%s]", vm.IdempotencyKey)
+				return nil
+			}
+		}
+		output.Operation = DRY_RUN_OPERATION_MODIFY
+		output.Detail = fmt.Sprintf("renew instance expiring at %s for %d months", stringValue(instance.ExpiredTime), vm.InstanceChargePeriod)
+		if vm.RenewFlag != "" {
+			output.Detail += fmt.Sprintf(" with renew flag[%s]", vm.RenewFlag)
+		}
+		return nil
+	})
+}
+
+type VMConvertToPrepaidAction struct {
+	VMAction
+}
+
+func (action *VMConvertToPrepaidAction) CheckParam(input interface{}) error {
+	if err := action.VMAction.CheckParam(input); err != nil {
+		return err
+	}
+	return checkVmChargePeriod(input)
+}
+
+func (action *VMConvertToPrepaidAction) Do(input interface{}) (interface{}, error) {
+	vms, _ := input.(VmInputs)
+	outputs := VmOutputs{}
+	for _, vm := range vms.Inputs {
+		output, err := convertVmToPrepaid(vm)
+		if err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+func convertVmToPrepaid(vm VmInput) (VmOutput, error) {
+	paramsMap, _ := GetMapFromProviderParams(vm.ProviderParams)
+	client, err := createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+	if err != nil {
+		return VmOutput{}, err
+	}
+
+	instance, err := getInstanceByInstanceId(client, vm.Id)
+	if err != nil {
+		return VmOutput{}, err
+	}
+	if *instance.InstanceChargeType == INSTANCE_CHARGE_TYPE_PREPAID {
+		logrus.Infof("vm[%s] is already %s", vm.Id, INSTANCE_CHARGE_TYPE_PREPAID)
+		return newVmChargeOutput(vm.Guid, "", instance), nil
+	}
+
+	request := cvm.NewModifyInstancesChargeTypeRequest()
+	request.InstanceIds = []*string{&vm.Id}
+	request.InstanceChargeType = common.StringPtr(INSTANCE_CHARGE_TYPE_PREPAID)
+	request.InstanceChargePrepaid = &cvm.InstanceChargePrepaid{
+		Period:    &vm.InstanceChargePeriod,
+		RenewFlag: common.StringPtr(getVmRenewFlag(vm)),
+	}
+	response, err := client.ModifyInstancesChargeType(request)
+	if err != nil {
+		return VmOutput{}, err
+	}
+	logrus.Infof("Convert VM[%v] to %s has been submitted, RequestID is [%v]", vm.Id, INSTANCE_CHARGE_TYPE_PREPAID, *response.Response.RequestId)
+
+	err = waitVmsUntil(client, []string{vm.Id}, 600, func(instance *cvm.Instance) bool {
+		return *instance.InstanceChargeType == INSTANCE_CHARGE_TYPE_PREPAID
+	})
+	if err != nil {
+		return VmOutput{}, err
+	}
+	if instance, err = getInstanceByInstanceId(client, vm.Id); err != nil {
+		return VmOutput{}, err
+	}
+	return newVmChargeOutput(vm.Guid, *response.Response.RequestId, instance), nil
+}
+
+func (action *VMConvertToPrepaidAction) DryRun(input interface{}) (interface{}, error) {
+	vms, _ := input.(VmInputs)
+	return dryRunVmChange(vms, func(client CvmAPI, vm VmInput, instance *cvm.Instance, output *DryRunOutput) error {
+		if *instance.InstanceChargeType == INSTANCE_CHARGE_TYPE_PREPAID {
+			output.Detail = fmt.Sprintf("instance already %s", INSTANCE_CHARGE_TYPE_PREPAID)
+			return nil
+		}
+		output.Operation = DRY_RUN_OPERATION_MODIFY
+		output.Detail = fmt.Sprintf("convert instance from %s to %s for %d months with renew flag[%s]",
+			*instance.InstanceChargeType, INSTANCE_CHARGE_TYPE_PREPAID, vm.InstanceChargePeriod, getVmRenewFlag(vm))
+		return nil
+	})
+}
+
+type VMModifyRenewFlagAction struct {
+	VMAction
+}
+
+func (action *VMModifyRenewFlagAction) CheckParam(input interface{}) error {
+	if err := action.VMAction.CheckParam(input); err != nil {
+		return err
+	}
+	vms, _ := input.(VmInputs)
+	for _, vm := range vms.Inputs {
+		if vm.RenewFlag == "" {
+			return errors.New("input renew_flag is empty")
+		}
+		if err := checkVmRenewFlag(vm.RenewFlag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (action *VMModifyRenewFlagAction) Do(input interface{}) (interface{}, error) {
+	vms, _ := input.(VmInputs)
+	outputs := VmOutputs{}
+	for _, vm := range vms.Inputs {
+		output, err := modifyVmRenewFlag(vm)
+		if err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+func modifyVmRenewFlag(vm VmInput) (VmOutput, error) {
+	paramsMap, _ := GetMapFromProviderParams(vm.ProviderParams)
+	client, err := createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+	if err != nil {
+		return VmOutput{}, err
+	}
+
+	instance, err := getInstanceByInstanceId(client, vm.Id)
+	if err != nil {
+		return VmOutput{}, err
+	}
+	if stringValue(instance.RenewFlag) == vm.RenewFlag {
+		logrus.Infof("vm[%s] already has renew flag[%s]", vm.Id, vm.RenewFlag)
+		return newVmChargeOutput(vm.Guid, "", instance), nil
+	}
+
+	request := cvm.NewModifyInstancesRenewFlagRequest()
+	request.InstanceIds = []*string{&vm.Id}
+	request.RenewFlag = &vm.RenewFlag
+	response, err := client.ModifyInstancesRenewFlag(request)
+	if err != nil {
+		return VmOutput{}, err
+	}
+	logrus.Infof("Modify renew flag of VM[%v] to [%v] has been submitted, RequestID is [%v]", vm.Id, vm.RenewFlag, *response.Response.RequestId)
+
+	if instance, err = getInstanceByInstanceId(client, vm.Id); err != nil {
+		return VmOutput{}, err
+	}
+	return newVmChargeOutput(vm.Guid, *response.Response.RequestId, instance), nil
+}
+
+func (action *VMModifyRenewFlagAction) DryRun(input interface{}) (interface{}, error) {
+	vms, _ := input.(VmInputs)
+	return dryRunVmChange(vms, func(client CvmAPI, vm VmInput, instance *cvm.Instance, output *DryRunOutput) error {
+		if stringValue(instance.RenewFlag) == vm.RenewFlag {
+			output.Detail = fmt.Sprintf("instance already has renew flag[%s]", vm.RenewFlag)
+			return nil
+		}
+		output.Operation = DRY_RUN_OPERATION_MODIFY
+		output.Detail = fmt.Sprintf("change renew flag from [%s] to [%s]", stringValue(instance.RenewFlag), vm.RenewFlag)
+		return nil
+	})
+}
+
+type VMExpiryReportAction struct {
+	VMAction
+}
+
+//every input reports the prepaid instances of its region which expire within expire_within_days
+func (action *VMExpiryReportAction) CheckParam(input interface{}) error {
+	vms, ok := input.(VmInputs)
+	if !ok {
+		return INVALID_PARAMETERS
+	}
+	for _, vm := range vms.Inputs {
+		if vm.ExpireWithinDays <= 0 {
+			return fmt.Errorf("invalid expire_within_days(%v)", vm.ExpireWithinDays)
+		}
+	}
+	return nil
+}
+
+func (action *VMExpiryReportAction) Do(input interface{}) (interface{}, error) {
+	vms, _ := input.(VmInputs)
+	outputs := VmOutputs{}
+	now := time.Now()
+	for _, vm := range vms.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(vm.ProviderParams)
+		client, err := createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+		if err != nil {
+			return nil, err
+		}
+
+		request := cvm.NewDescribeInstancesRequest()
+		request.Filters = []*cvm.Filter{
+			{Name: common.StringPtr("instance-charge-type"), Values: common.StringPtrs([]string{INSTANCE_CHARGE_TYPE_PREPAID})},
+		}
+		instances, requestId, err := describeAllVmInstances(client, request)
+		if err != nil {
+			return nil, err
+		}
+
+		expiring, err := getExpiringVmOutputs(vm.Guid, requestId, instances, now, vm.ExpireWithinDays)
+		if err != nil {
+			return nil, err
+		}
+		logrus.Infof("expiry report of guid[%s] found %d of %d prepaid instances expire within %d days", vm.Guid, len(expiring), len(instances), vm.ExpireWithinDays)
+		outputs.Outputs = append(outputs.Outputs, expiring...)
+	}
+	return &outputs, nil
+}
+
+//getExpiringVmOutputs returns the instances expiring before now plus the days, the soonest first
+func getExpiringVmOutputs(guid string, requestId string, instances []*cvm.Instance, now time.Time, days int64) ([]VmOutput, error) {
+	deadline := now.Add(time.Duration(days) * 24 * time.Hour)
+	expiredTimes := map[string]time.Time{}
+	outputs := []VmOutput{}
+	for _, instance := range instances {
+		if stringValue(instance.ExpiredTime) == "" {
+			continue
+		}
+		expiredTime, err := time.Parse(time.RFC3339, *instance.ExpiredTime)
+		if err != nil {
+			return nil, fmt.Errorf("vm[%s] has invalid expired time(%s)", stringValue(instance.InstanceId), *instance.ExpiredTime)
+		}
+		if expiredTime.After(deadline) {
+			continue
+		}
+
+		output := newVmChargeOutput(guid, requestId, instance)
+		output.InstanceName = stringValue(instance.InstanceName)
+		output.ExpireInDays = strconv.Itoa(int(math.Floor(expiredTime.Sub(now).Hours() / 24)))
+		expiredTimes[output.Id] = expiredTime
+		outputs = append(outputs, output)
+	}
+
+	sort.SliceStable(outputs, func(i, j int) bool {
+		return expiredTimes[outputs[i].Id].Before(expiredTimes[outputs[j].Id])
+	})
+	return outputs, nil
+}
+
+type VMCheckQuotaAction struct {
+	VMAction
+}
+
+//nothing is required, the instance types of all the zones in the region are reported without filters
+func (action *VMCheckQuotaAction) CheckParam(input interface{}) error {
+	vms, ok := input.(VmInputs)
+	if !ok {
+		return INVALID_PARAMETERS
+	}
+
+	for _, vm := range vms.Inputs {
+		if vm.Zone != "" && vm.Zones != "" {
+			return errors.New("input zone can't be used together with zones")
+		}
+	}
+	return nil
+}
+
+func (action *VMCheckQuotaAction) Do(input interface{}) (interface{}, error) {
+	vms, _ := input.(VmInputs)
+	outputs := VmOutputs{}
+	for _, vm := range vms.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(vm.ProviderParams)
+		client, err := createCvmClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+		if err != nil {
+			return nil, err
+		}
+
+		filters := []*cvm.Filter{}
+		for name, values := range map[string][]string{
+			"instance-type":        splitCommaValues(vm.InstanceType),
+			"instance-charge-type": splitCommaValues(vm.InstanceChargeType),
+			"zone":                 splitCommaValues(vm.Zone + "," + vm.Zones),
+		} {
+			if len(values) > 0 {
+				filters = append(filters, &cvm.Filter{Name: common.StringPtr(name), Values: common.StringPtrs(values)})
+			}
+		}
+		sort.Slice(filters, func(i, j int) bool { return *filters[i].Name < *filters[j].Name })
+
+		items, requestId, err := describeVmZoneQuotas(client, filters)
+		if err != nil {
+			return nil, err
+		}
+		logrus.Infof("check quota of guid[%s] found %d instance types", vm.Guid, len(items))
+		outputs.Outputs = append(outputs.Outputs, getVmQuotaOutputs(vm.Guid, requestId, items)...)
+	}
+	return &outputs, nil
+}
+
+//getVmQuotaOutputs reports the instance types in order of zone, instance type and charge type
+func getVmQuotaOutputs(guid string, requestId string, items []*cvm.InstanceTypeQuotaItem) []VmOutput {
+	outputs := []VmOutput{}
+	for _, item := range items {
+		output := VmOutput{
+			Guid:               guid,
+			RequestId:          requestId,
+			Zone:               stringValue(item.Zone),
+			InstanceType:       stringValue(item.InstanceType),
+			InstanceChargeType: stringValue(item.InstanceChargeType),
+			InstanceFamily:     stringValue(item.InstanceFamily),
+			Status:             stringValue(item.Status),
+			SoldOut:            strconv.FormatBool(stringValue(item.Status) != VM_INSTANCE_TYPE_STATUS_SELL),
+		}
+		if item.Cpu != nil {
+			output.Cpu = strconv.FormatInt(*item.Cpu, 10)
+		}
+		if item.Memory != nil {
+			output.Memory = strconv.FormatInt(*item.Memory, 10)
+		}
+		if item.Price != nil {
+			output.ChargeUnit = stringValue(item.Price.ChargeUnit)
+			//postpaid types are priced by unit, prepaid ones by the original and discount price of a month
+			if item.Price.UnitPrice != nil {
+				output.UnitPrice = strconv.FormatFloat(*item.Price.UnitPrice, 'f', -1, 64)
+			}
+			if item.Price.OriginalPrice != nil {
+				output.OriginalPrice = strconv.FormatFloat(*item.Price.OriginalPrice, 'f', -1, 64)
+			}
+			if item.Price.DiscountPrice != nil {
+				output.DiscountPrice = strconv.FormatFloat(*item.Price.DiscountPrice, 'f', -1, 64)
+			}
+		}
+		outputs = append(outputs, output)
+	}
+
+	sort.SliceStable(outputs, func(i, j int) bool {
+		a, b := outputs[i], outputs[j]
+		if a.Zone != b.Zone {
+			return a.Zone < b.Zone
+		}
+		if a.InstanceType != b.InstanceType {
+			return a.InstanceType < b.InstanceType
+		}
+		return a.InstanceChargeType < b.InstanceChargeType
+	})
+	return outputs
+}
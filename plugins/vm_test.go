@@ -0,0 +1,294 @@
+package plugins
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+)
+
+func TestBuildRunInstanceRequest(t *testing.T) {
+	vm := VmInput{
+		InstanceName:            "vm",
+		InstanceType:            "S2.MEDIUM4",
+		ImageId:                 "img-1",
+		SystemDiskSize:          50,
+		SystemDiskType:          "CLOUD_SSD",
+		DataDisks:               "CLOUD_PREMIUM:100, CLOUD_SSD:200",
+		SecurityGroupIds:        "sg-1,sg-2",
+		KeyIds:                  "skey-1",
+		HostName:                "host-1",
+		UserData:                "#!/bin/sh\necho hello",
+		ProjectId:               1001,
+		Tags:                    "env=prod;team=ops=infra",
+		InternetChargeType:      "TRAFFIC_POSTPAID_BY_HOUR",
+		InternetMaxBandwidthOut: 5,
+	}
+	request, err := buildRunInstanceRequest(vm, "ap-guangzhou-3", "", "token")
+	if err != nil {
+		t.Fatalf("buildRunInstanceRequest meet err=%v", err)
+	}
+
+	if request.Placement.ProjectId != 1001 || request.SystemDisk.DiskType != "CLOUD_SSD" || request.HostName != "host-1" {
+		t.Fatalf("placement=%v,system disk=%v,host name=%s", request.Placement, request.SystemDisk, request.HostName)
+	}
+	wantDisks := []DataDisksStruct{
+		{DiskType: "CLOUD_PREMIUM", DiskSize: 100, DeleteWithInstance: true},
+		{DiskType: "CLOUD_SSD", DiskSize: 200, DeleteWithInstance: true},
+	}
+	if !reflect.DeepEqual(request.DataDisks, wantDisks) {
+		t.Fatalf("data disks=%v,want %v", request.DataDisks, wantDisks)
+	}
+	if !reflect.DeepEqual(request.SecurityGroupIds, []string{"sg-1", "sg-2"}) {
+		t.Fatalf("security groups=%v", request.SecurityGroupIds)
+	}
+	if request.LoginSettings.Password != "" || !reflect.DeepEqual(request.LoginSettings.KeyIds, []string{"skey-1"}) {
+		t.Fatalf("login settings=%v,want key skey-1 only", request.LoginSettings)
+	}
+	if userData, _ := base64.StdEncoding.DecodeString(request.UserData); string(userData) != vm.UserData {
+		t.Fatalf("user data=%s,want base64 of %s", request.UserData, vm.UserData)
+	}
+	wantTags := []TagSpecificationStruct{{ResourceType: "instance", Tags: []TagStruct{{"env", "prod"}, {"team", "ops=infra"}}}}
+	if !reflect.DeepEqual(request.TagSpecification, wantTags) {
+		t.Fatalf("tags=%v,want %v", request.TagSpecification, wantTags)
+	}
+	wantInternet := InternetAccessible{InternetChargeType: "TRAFFIC_POSTPAID_BY_HOUR", PublicIpAssigned: true, InternetMaxBandwidthOut: 5}
+	if request.InternetAccessible != wantInternet {
+		t.Fatalf("internet accessible=%v,want %v", request.InternetAccessible, wantInternet)
+	}
+}
+
+func TestBuildRunInstanceRequestDefaults(t *testing.T) {
+	request, err := buildRunInstanceRequest(VmInput{SystemDiskSize: 50}, "ap-guangzhou-3", "password", "token")
+	if err != nil {
+		t.Fatalf("buildRunInstanceRequest meet err=%v", err)
+	}
+	if request.SystemDisk.DiskType != VM_DEFAULT_SYSTEM_DISK_TYPE || len(request.DataDisks) != 0 || len(request.SecurityGroupIds) != 0 {
+		t.Fatalf("system disk=%v,data disks=%v,security groups=%v", request.SystemDisk, request.DataDisks, request.SecurityGroupIds)
+	}
+	if request.LoginSettings.Password != "password" || len(request.LoginSettings.KeyIds) != 0 {
+		t.Fatalf("login settings=%v,want password only", request.LoginSettings)
+	}
+	if request.InternetAccessible.PublicIpAssigned || request.InternetAccessible.InternetMaxBandwidthOut != VM_DEFAULT_INTERNET_MAX_BANDWIDTH_OUT {
+		t.Fatalf("internet accessible=%v,want no public ip", request.InternetAccessible)
+	}
+	if request.UserData != "" || request.TagSpecification != nil {
+		t.Fatalf("user data=%s,tags=%v,want empty", request.UserData, request.TagSpecification)
+	}
+
+	request, _ = buildRunInstanceRequest(VmInput{InstanceChargeType: "PREPAID", InstanceChargePeriod: 12}, "ap-guangzhou-3", "password", "token")
+	if request.InstanceChargePrepaid.Period != 12 || request.InstanceChargePrepaid.RenewFlag != RENEW_FLAG_NOTIFY_AND_AUTO_RENEW {
+		t.Fatalf("prepaid=%v,want 12 months renewed automatically", request.InstanceChargePrepaid)
+	}
+}
+
+func TestVmCreateCheckParam(t *testing.T) {
+	action := new(VMCreateAction)
+	for _, c := range []struct {
+		vm      VmInput
+		wantErr string
+	}{
+		{VmInput{DataDisks: "CLOUD_PREMIUM:100", Tags: "env=prod"}, ""},
+		{VmInput{DataDisks: "CLOUD_PREMIUM"}, "invalid data disk(CLOUD_PREMIUM)"},
+		{VmInput{DataDisks: "CLOUD_PREMIUM:0"}, "invalid data disk(CLOUD_PREMIUM:0) size"},
+		{VmInput{Tags: "env"}, "invalid tag(env)"},
+		{VmInput{InternetMaxBandwidthOut: -1}, "invalid internet_max_bandwidth_out(-1)"},
+		{VmInput{RenewFlag: "ALWAYS"}, "ALWAYS is not valid value"},
+		{VmInput{Zones: "ap-guangzhou-3,ap-guangzhou-4", SubnetId: "subnet-3,subnet-4"}, ""},
+		{VmInput{Zones: "ap-guangzhou-3,ap-guangzhou-4", SubnetId: "subnet-3"}, "should have the same count"},
+		{VmInput{Zones: "ap-guangzhou-3", SubnetId: "subnet-3", Zone: "ap-guangzhou-3"}, "input zone can't be used together with zones"},
+		{VmInput{SubnetId: "subnet-3,subnet-4"}, "should be one subnet without zones"},
+	} {
+		err := action.CheckParam(VmInputs{Inputs: []VmInput{c.vm}})
+		if c.wantErr == "" && err != nil {
+			t.Fatalf("check %v meet err=%v", c.vm, err)
+		}
+		if c.wantErr != "" && (err == nil || !strings.Contains(err.Error(), c.wantErr)) {
+			t.Fatalf("check %v err=%v,want %s", c.vm, err, c.wantErr)
+		}
+	}
+}
+
+func TestGroupVmCreateTasks(t *testing.T) {
+	spec := VmInput{ProviderParams: MOCK_PROVIDER_PARAMS, InstanceType: "S2.MEDIUM4", ImageId: "img-1", SystemDiskSize: 50, KeyIds: "skey-1"}
+	vms := []VmInput{spec, spec, spec, spec, spec, spec, spec, spec}
+	for i := range vms {
+		vms[i].Guid = fmt.Sprintf("guid-%d", i)
+	}
+	vms[2].InstanceType = "S2.LARGE8"
+	vms[3].Id = "ins-exist"
+	vms[4].InstancePrivateIp = "10.0.1.10"
+	//vms with generated passwords never share one
+	vms[6].KeyIds = ""
+	vms[7].KeyIds = ""
+	keys := []string{"key-0", "key-1", "key-2", "key-3", "key-4", "key-5", "key-6", "key-7"}
+
+	tasks := groupVmCreateTasks(vms, keys)
+	wantIndexes := [][]int{{0, 1, 5}, {2}, {3}, {4}, {6}, {7}}
+	if len(tasks) != len(wantIndexes) {
+		t.Fatalf("got %d tasks,want %d", len(tasks), len(wantIndexes))
+	}
+	for i, task := range tasks {
+		if !reflect.DeepEqual(task.indexes, wantIndexes[i]) {
+			t.Fatalf("task %d indexes=%v,want %v", i, task.indexes, wantIndexes[i])
+		}
+		if task.keys[0] != keys[task.indexes[0]] {
+			t.Fatalf("task %d keys=%v", i, task.keys)
+		}
+	}
+}
+
+func TestGroupVmCreateTasksMaxCount(t *testing.T) {
+	spec := VmInput{ProviderParams: MOCK_PROVIDER_PARAMS, InstanceType: "S2.MEDIUM4", KeyIds: "skey-1"}
+	count := VM_RUN_INSTANCES_MAX_COUNT*2 + 1
+	vms := make([]VmInput, count)
+	keys := make([]string, count)
+	for i := range vms {
+		vms[i] = spec
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+
+	tasks := groupVmCreateTasks(vms, keys)
+	wantCounts := []int{VM_RUN_INSTANCES_MAX_COUNT, VM_RUN_INSTANCES_MAX_COUNT, 1}
+	if len(tasks) != len(wantCounts) {
+		t.Fatalf("got %d tasks,want %d", len(tasks), len(wantCounts))
+	}
+	for i, task := range tasks {
+		if len(task.indexes) != wantCounts[i] || task.indexes[0] != i*VM_RUN_INSTANCES_MAX_COUNT {
+			t.Fatalf("task %d has %d vms from %d,want %d", i, len(task.indexes), task.indexes[0], wantCounts[i])
+		}
+	}
+	if timeout := getVmCreateWaitTimeout(VM_RUN_INSTANCES_MAX_COUNT); timeout <= getVmCreateWaitTimeout(1) {
+		t.Fatalf("wait timeout of a full batch=%d should be longer than one vm", timeout)
+	}
+}
+
+func TestSpreadVmsAcrossZones(t *testing.T) {
+	spec := VmInput{ProviderParams: MOCK_PROVIDER_PARAMS, InstanceType: "S2.MEDIUM4", Zones: "zone-1,zone-2,zone-3", SubnetId: "subnet-1,subnet-2,subnet-3", KeyIds: "skey-1"}
+	vms := []VmInput{spec, spec, spec, spec, {ProviderParams: MOCK_PROVIDER_PARAMS, SubnetId: "subnet-0", KeyIds: "skey-1"}, spec}
+	spreadVmsAcrossZones(vms)
+
+	wantZones := []string{"zone-1", "zone-2", "zone-3", "zone-1", "", "zone-2"}
+	for i, vm := range vms {
+		if vm.Zone != wantZones[i] || vm.Zones != "" {
+			t.Fatalf("vm %d zone=%s zones=%s,want %s", i, vm.Zone, vm.Zones, wantZones[i])
+		}
+		if wantZones[i] != "" && vm.SubnetId != strings.Replace(wantZones[i], "zone", "subnet", 1) {
+			t.Fatalf("vm %d in zone %s got subnet %s", i, vm.Zone, vm.SubnetId)
+		}
+	}
+	if vms[4].SubnetId != "subnet-0" {
+		t.Fatalf("vm without zones subnet=%s,want subnet-0", vms[4].SubnetId)
+	}
+
+	//the vms of each zone are launched together, apart from the other zones
+	tasks := groupVmCreateTasks(vms, []string{"key-0", "key-1", "key-2", "key-3", "key-4", "key-5"})
+	wantIndexes := [][]int{{0, 3}, {1, 5}, {2}, {4}}
+	if len(tasks) != len(wantIndexes) {
+		t.Fatalf("got %d tasks,want %d", len(tasks), len(wantIndexes))
+	}
+	for i, task := range tasks {
+		if !reflect.DeepEqual(task.indexes, wantIndexes[i]) {
+			t.Fatalf("task %d indexes=%v,want %v", i, task.indexes, wantIndexes[i])
+		}
+	}
+
+	request, _ := buildRunInstanceRequest(VmInput{PlacementGroupId: "ps-1"}, getVmZone(vms[1], nil), "", "token")
+	if request.Placement.Zone != "zone-2" || !reflect.DeepEqual(request.DisasterRecoverGroupIds, []string{"ps-1"}) {
+		t.Fatalf("placement=%v,groups=%v,want zone-2 in ps-1", request.Placement, request.DisasterRecoverGroupIds)
+	}
+}
+
+func TestVmZoneQuotas(t *testing.T) {
+	quotas := vmZoneQuotas{}
+	for _, item := range []struct{ zone, chargeType, status string }{
+		{"zone-3", "POSTPAID_BY_HOUR", "SELL"},
+		{"zone-1", "POSTPAID_BY_HOUR", "SELL"},
+		{"zone-2", "POSTPAID_BY_HOUR", "SOLD_OUT"},
+		{"zone-2", "PREPAID", "SELL"},
+	} {
+		quotas[vmZoneQuotaKey(item.zone, "S2.MEDIUM4", item.chargeType)] = &cvm.InstanceTypeQuotaItem{
+			Zone:               common.StringPtr(item.zone),
+			InstanceType:       common.StringPtr("S2.MEDIUM4"),
+			InstanceChargeType: common.StringPtr(item.chargeType),
+			Status:             common.StringPtr(item.status),
+		}
+	}
+
+	if zones := quotas.zonesOnSale("S2.MEDIUM4", "POSTPAID_BY_HOUR"); !reflect.DeepEqual(zones, []string{"zone-1", "zone-3"}) {
+		t.Fatalf("zones on sale=%v,want zone-1 and zone-3", zones)
+	}
+	if quotas.onSale("zone-2", "S2.MEDIUM4", "POSTPAID_BY_HOUR") || !quotas.onSale("zone-2", "S2.MEDIUM4", "PREPAID") {
+		t.Fatalf("zone-2 should sell S2.MEDIUM4 by month only")
+	}
+	if quotas.onSale("zone-1", "S2.LARGE8", "POSTPAID_BY_HOUR") {
+		t.Fatalf("zone-1 should not sell the instance type missing in quotas")
+	}
+	if chargeType := getVmChargeType(VmInput{}); chargeType != INSTANCE_CHARGE_TYPE_POSTPAID_BY_HOUR {
+		t.Fatalf("default charge type=%s", chargeType)
+	}
+}
+
+func TestGetExpiringVmOutputs(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	instance := func(id string, expiredTime string) *cvm.Instance {
+		return &cvm.Instance{InstanceId: common.StringPtr(id), InstanceChargeType: common.StringPtr("PREPAID"), ExpiredTime: common.StringPtr(expiredTime)}
+	}
+	instances := []*cvm.Instance{
+		instance("ins-later", "2020-03-01T00:00:00Z"),
+		instance("ins-soon", "2020-01-02T12:00:00Z"),
+		instance("ins-expired", "2019-12-30T00:00:00Z"),
+		{InstanceId: common.StringPtr("ins-postpaid"), InstanceChargeType: common.StringPtr("POSTPAID_BY_HOUR")},
+	}
+
+	outputs, err := getExpiringVmOutputs("guid", "request", instances, now, 30)
+	if err != nil {
+		t.Fatalf("getExpiringVmOutputs meet err=%v", err)
+	}
+	got := []string{}
+	for _, output := range outputs {
+		got = append(got, output.Id+":"+output.ExpireInDays)
+	}
+	if want := []string{"ins-expired:-2", "ins-soon:1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expiring vms=%v,want %v", got, want)
+	}
+
+	if _, err = getExpiringVmOutputs("guid", "request", []*cvm.Instance{instance("ins-1", "tomorrow")}, now, 30); err == nil {
+		t.Fatalf("invalid expired time should fail")
+	}
+}
+
+func TestResolveVmImageIds(t *testing.T) {
+	clients, cleanup := setupMockClients(t, map[string][]mockResponse{
+		"cvm.DescribeImages": {
+			mockOk(`{"TotalCount":1,"ImageSet":[{"ImageId":"img-golden","ImageName":"golden"}]}`),
+			mockOk(`{"TotalCount":0,"ImageSet":[]}`),
+		},
+	})
+	defer cleanup()
+
+	vms := []VmInput{
+		{ProviderParams: MOCK_PROVIDER_PARAMS, ImageId: "golden"},
+		{ProviderParams: MOCK_PROVIDER_PARAMS, ImageId: "img-1"},
+		{ProviderParams: MOCK_PROVIDER_PARAMS, ImageId: "golden"},
+	}
+	if err := resolveVmImageIds(vms); err != nil {
+		t.Fatalf("resolveVmImageIds meet err=%v", err)
+	}
+	if vms[0].ImageId != "img-golden" || vms[1].ImageId != "img-1" || vms[2].ImageId != "img-golden" {
+		t.Fatalf("resolved vms=%v", vms)
+	}
+	if !reflect.DeepEqual(clients.calls, []string{"cvm.DescribeImages"}) {
+		t.Fatalf("calls=%v,want the name resolved once", clients.calls)
+	}
+
+	err := resolveVmImageIds([]VmInput{{ProviderParams: MOCK_PROVIDER_PARAMS, ImageId: "missing"}})
+	if err == nil || !strings.Contains(err.Error(), "image name(missing) matches 0 images") {
+		t.Fatalf("resolve missing image err=%v", err)
+	}
+}
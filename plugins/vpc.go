@@ -1,30 +1,41 @@
 package plugins
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
+	"strconv"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
-	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
+	tchttp "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/http"
 	vpc "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/vpc/v20170312"
 )
 
+const (
+	//the most items qcloud returns in one describe call of the vpc apis
+	VPC_DESCRIBE_PAGE_SIZE = 100
+
+	//qcloud takes the first dns server of a vpc as the primary one and the others as backups
+	VPC_MAX_DNS_SERVERS = 4
+)
+
 var VpcActions = make(map[string]Action)
 
 func init() {
 	VpcActions["create"] = new(VpcCreateAction)
 	VpcActions["terminate"] = new(VpcTerminateAction)
+	VpcActions["describe"] = new(VpcDescribeAction)
+	VpcActions["modify"] = new(VpcModifyAction)
+	VpcActions["add-cidrs"] = new(VpcAddCidrsAction)
+	VpcActions["remove-cidrs"] = new(VpcRemoveCidrsAction)
+	VpcActions["assign-ipv6"] = new(VpcAssignIpv6Action)
 }
 
-func CreateVpcClient(region, secretId, secretKey string) (client *vpc.Client, err error) {
-	credential := common.NewCredential(secretId, secretKey)
-
-	clientProfile := profile.NewClientProfile()
-	clientProfile.HttpProfile.Endpoint = "vpc.tencentcloudapi.com"
-
-	return vpc.NewClient(credential, region, clientProfile)
+func CreateVpcClient(region, secretId, secretKey string) (VpcAPI, error) {
+	return QcloudClientFactory.NewVpcClient(region, secretId, secretKey)
 }
 
 type VpcInputs struct {
@@ -32,11 +43,15 @@ type VpcInputs struct {
 }
 
 type VpcInput struct {
-	Guid           string `json:"guid,omitempty"`
-	ProviderParams string `json:"provider_params,omitempty"`
-	Id             string `json:"id,omitempty"`
-	Name           string `json:"name,omitempty"`
-	CidrBlock      string `json:"cidr_block,omitempty"`
+	Guid                string `json:"guid,omitempty"`
+	ProviderParams      string `json:"provider_params,omitempty"`
+	Id                  string `json:"id,omitempty"`
+	Name                string `json:"name,omitempty"`
+	CidrBlock           string `json:"cidr_block,omitempty"`
+	SecondaryCidrBlocks string `json:"secondary_cidr_blocks,omitempty"`
+	DnsServers          string `json:"dns_servers,omitempty"`
+	DomainName          string `json:"domain_name,omitempty"`
+	IdempotencyKey      string `json:"idempotency_key,omitempty"`
 }
 
 type VpcOutputs struct {
@@ -44,9 +59,17 @@ type VpcOutputs struct {
 }
 
 type VpcOutput struct {
-	RequestId string `json:"request_id,omitempty"`
-	Guid      string `json:"guid,omitempty"`
-	Id        string `json:"id,omitempty"`
+	RequestId           string `json:"request_id,omitempty"`
+	Guid                string `json:"guid,omitempty"`
+	Id                  string `json:"id,omitempty"`
+	Name                string `json:"name,omitempty"`
+	CidrBlock           string `json:"cidr_block,omitempty"`
+	SecondaryCidrBlocks string `json:"secondary_cidr_blocks,omitempty"`
+	Ipv6CidrBlock       string `json:"ipv6_cidr_block,omitempty"`
+	DnsServers          string `json:"dns_servers,omitempty"`
+	DomainName          string `json:"domain_name,omitempty"`
+	CreatedTime         string `json:"created_time,omitempty"`
+	Topology            string `json:"topology,omitempty"`
 }
 
 type VpcPlugin struct {
@@ -80,23 +103,34 @@ func (action *VpcCreateAction) CheckParam(input interface{}) error {
 		return fmt.Errorf("vpcCreateAtion:input type=%T not right", input)
 	}
 
-	for _, vpc := range vpcs.Inputs {
+	for i, vpc := range vpcs.Inputs {
 		if vpc.Name == "" {
 			return errors.New("vpcCreateAtion input name is empty")
 		}
 		if _, _, err := net.ParseCIDR(vpc.CidrBlock); err != nil {
 			return fmt.Errorf("vpcCreateAtion invalid vpcCidr[%s]", vpc.CidrBlock)
 		}
+
+		paramsMap, _ := GetMapFromProviderParams(vpc.ProviderParams)
+		for _, other := range vpcs.Inputs[:i] {
+			otherParamsMap, _ := GetMapFromProviderParams(other.ProviderParams)
+			if paramsMap["Region"] == otherParamsMap["Region"] && isCidrOverlap(vpc.CidrBlock, other.CidrBlock) {
+				return fmt.Errorf("vpcCreateAtion vpcCidr[%s] overlaps with vpcCidr[%s] of another input", vpc.CidrBlock, other.CidrBlock)
+			}
+		}
 	}
 
 	return nil
 }
 
-func (action *VpcCreateAction) createVpc(vpcInput *VpcInput) (*VpcOutput, error) {
+func (action *VpcCreateAction) createVpc(vpcInput *VpcInput, idempotencyKey string) (*VpcOutput, error) {
 	paramsMap, err := GetMapFromProviderParams(vpcInput.ProviderParams)
 	client, _ := CreateVpcClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
 
 	//check resource exist
+	if vpcInput.Id, err = getIdempotencyCreatedId(idempotencyKey, vpcInput.Id); err != nil {
+		return nil, err
+	}
 	if vpcInput.Id != "" {
 		queryVpcsResponse, flag, err := queryVpcsInfo(client, vpcInput)
 		if err != nil && flag == false {
@@ -108,6 +142,10 @@ func (action *VpcCreateAction) createVpc(vpcInput *VpcInput) (*VpcOutput, error)
 		}
 	}
 
+	if err = checkVpcCidrNotOverlapped(client, vpcInput.CidrBlock); err != nil {
+		return nil, err
+	}
+
 	request := vpc.NewCreateVpcRequest()
 	request.VpcName = &vpcInput.Name
 	request.CidrBlock = &vpcInput.CidrBlock
@@ -118,6 +156,8 @@ func (action *VpcCreateAction) createVpc(vpcInput *VpcInput) (*VpcOutput, error)
 		return nil, err
 	}
 
+	saveIdempotencyCreatedIds(idempotencyKey, *response.Response.RequestId, *response.Response.Vpc.VpcId)
+
 	output := VpcOutput{}
 	output.RequestId = *response.Response.RequestId
 	output.Guid = vpcInput.Guid
@@ -130,17 +170,49 @@ func (action *VpcCreateAction) Do(input interface{}) (interface{}, error) {
 	vpcs, _ := input.(VpcInputs)
 	outputs := VpcOutputs{}
 	for _, vpc := range vpcs.Inputs {
-		vpcOutput, err := action.createVpc(&vpc)
+		vpcOutput := VpcOutput{}
+		idempotencyKey := getIdempotencyKey("vpc-create", vpc.Guid, vpc.IdempotencyKey, vpc)
+		err := runWithIdempotencyKey(idempotencyKey, &vpcOutput, func() (interface{}, error) {
+			return action.createVpc(&vpc, idempotencyKey)
+		})
 		if err != nil {
 			return nil, err
 		}
-		outputs.Outputs = append(outputs.Outputs, *vpcOutput)
+		outputs.Outputs = append(outputs.Outputs, vpcOutput)
 	}
 
 	logrus.Infof("all vpcs = %v are created", vpcs)
 	return &outputs, nil
 }
 
+func (action *VpcCreateAction) DryRun(input interface{}) (interface{}, error) {
+	vpcs, _ := input.(VpcInputs)
+	outputs := DryRunOutputs{}
+	for _, vpcInput := range vpcs.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(vpcInput.ProviderParams)
+		client, _ := CreateVpcClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+
+		exist := false
+		if vpcInput.Id != "" {
+			_, flag, err := queryVpcsInfo(client, &vpcInput)
+			if err != nil {
+				return nil, err
+			}
+			exist = flag
+		}
+		detail := fmt.Sprintf("create vpc name=%s cidr=%s", vpcInput.Name, vpcInput.CidrBlock)
+		output := newCreateDryRunOutput(vpcInput.Guid, vpcInput.Id, exist, detail)
+		if !exist {
+			if err := checkVpcCidrNotOverlapped(client, vpcInput.CidrBlock); err != nil {
+				output.Conflicts = append(output.Conflicts, err.Error())
+			}
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+
+	return &outputs, nil
+}
+
 type VpcTerminateAction struct {
 }
 
@@ -200,7 +272,37 @@ func (action *VpcTerminateAction) Do(input interface{}) (interface{}, error) {
 	return &outputs, nil
 }
 
-func queryVpcsInfo(client *vpc.Client, input *VpcInput) (*VpcOutput, bool, error) {
+func (action *VpcTerminateAction) DryRun(input interface{}) (interface{}, error) {
+	vpcs, _ := input.(VpcInputs)
+	outputs := DryRunOutputs{}
+	for _, vpcInput := range vpcs.Inputs {
+		paramsMap, _ := GetMapFromProviderParams(vpcInput.ProviderParams)
+		client, _ := CreateVpcClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+
+		_, exist, err := queryVpcsInfo(client, &vpcInput)
+		if err != nil {
+			return nil, err
+		}
+		output := newDeleteDryRunOutput(vpcInput.Guid, vpcInput.Id, exist, "delete vpc")
+		if exist {
+			request := vpc.NewDescribeSubnetsRequest()
+			filterName := "vpc-id"
+			request.Filters = []*vpc.Filter{&vpc.Filter{Name: &filterName, Values: []*string{&vpcInput.Id}}}
+			response, err := client.DescribeSubnets(request)
+			if err != nil {
+				return nil, err
+			}
+			for _, subnet := range response.Response.SubnetSet {
+				output.Conflicts = append(output.Conflicts, fmt.Sprintf("subnet[%s] still in vpc", *subnet.SubnetId))
+			}
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+
+	return &outputs, nil
+}
+
+func queryVpcsInfo(client VpcAPI, input *VpcInput) (*VpcOutput, bool, error) {
 	output := VpcOutput{}
 
 	request := vpc.NewDescribeVpcsRequest()
@@ -225,3 +327,872 @@ func queryVpcsInfo(client *vpc.Client, input *VpcInput) (*VpcOutput, bool, error
 
 	return &output, true, nil
 }
+
+type VpcAction struct {
+}
+
+func (action *VpcAction) ReadParam(param interface{}) (interface{}, error) {
+	var inputs VpcInputs
+	err := UnmarshalJson(param, &inputs)
+	if err != nil {
+		return nil, err
+	}
+	return inputs, nil
+}
+
+func (action *VpcAction) CheckParam(input interface{}) error {
+	vpcs, ok := input.(VpcInputs)
+	if !ok {
+		return INVALID_PARAMETERS
+	}
+
+	for _, vpcInput := range vpcs.Inputs {
+		if vpcInput.Id == "" {
+			return errors.New("input id is empty")
+		}
+	}
+	return nil
+}
+
+func createVpcClientByProviderParams(providerParams string) (VpcAPI, error) {
+	paramsMap, _ := GetMapFromProviderParams(providerParams)
+	return CreateVpcClient(paramsMap["Region"], paramsMap["SecretID"], paramsMap["SecretKey"])
+}
+
+func newVpcFilter(name string, values ...string) *vpc.Filter {
+	return &vpc.Filter{Name: common.StringPtr(name), Values: common.StringPtrs(values)}
+}
+
+//qcloudVpc adds the fields of DescribeVpcs which the vendored sdk doesn't know yet
+type qcloudVpc struct {
+	*vpc.Vpc
+	Ipv6CidrBlock *string `json:"Ipv6CidrBlock,omitempty" name:"Ipv6CidrBlock"`
+}
+
+type describeVpcsResponse struct {
+	*tchttp.BaseResponse
+	Response *struct {
+		TotalCount *uint64      `json:"TotalCount,omitempty" name:"TotalCount"`
+		VpcSet     []*qcloudVpc `json:"VpcSet,omitempty" name:"VpcSet"`
+		RequestId  *string      `json:"RequestId,omitempty" name:"RequestId"`
+	} `json:"Response"`
+}
+
+//queryVpcById returns false if the vpc is not found
+func queryVpcById(client VpcAPI, vpcId string) (*qcloudVpc, bool, error) {
+	request := vpc.NewDescribeVpcsRequest()
+	request.VpcIds = []*string{&vpcId}
+	response := &describeVpcsResponse{BaseResponse: &tchttp.BaseResponse{}}
+	if err := client.Send(request, response); err != nil {
+		logrus.Errorf("vpc DescribeVpcs meet err=%v", err)
+		return nil, false, err
+	}
+	if len(response.Response.VpcSet) == 0 {
+		return nil, false, nil
+	}
+	return response.Response.VpcSet[0], true, nil
+}
+
+func getVpcById(client VpcAPI, vpcId string) (*qcloudVpc, error) {
+	item, found, err := queryVpcById(client, vpcId)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("vpc[%s] not found", vpcId)
+	}
+	return item, nil
+}
+
+//describeAllVpcs pages over DescribeVpcs until all the vpcs of the region are returned
+func describeAllVpcs(client VpcAPI) ([]*qcloudVpc, error) {
+	request := vpc.NewDescribeVpcsRequest()
+	request.Limit = common.StringPtr(strconv.Itoa(VPC_DESCRIBE_PAGE_SIZE))
+
+	vpcs := []*qcloudVpc{}
+	for offset := uint64(0); ; {
+		request.Offset = common.StringPtr(strconv.FormatUint(offset, 10))
+		response := &describeVpcsResponse{BaseResponse: &tchttp.BaseResponse{}}
+		if err := client.Send(request, response); err != nil {
+			logrus.Errorf("vpc DescribeVpcs meet err=%v", err)
+			return nil, err
+		}
+
+		vpcs = append(vpcs, response.Response.VpcSet...)
+		offset += uint64(len(response.Response.VpcSet))
+		if len(response.Response.VpcSet) == 0 || offset >= *response.Response.TotalCount {
+			break
+		}
+	}
+	return vpcs, nil
+}
+
+//checkVpcCidrNotOverlapped returns an error if the cidr overlaps the primary or secondary cidrs of a vpc in the region,
+//such vpcs can't be peered or routed to each other later
+func checkVpcCidrNotOverlapped(client VpcAPI, cidrBlock string) error {
+	vpcs, err := describeAllVpcs(client)
+	if err != nil {
+		return err
+	}
+	for _, item := range vpcs {
+		for _, vpcCidrBlock := range getVpcCidrBlocks(item) {
+			if isCidrOverlap(cidrBlock, vpcCidrBlock) {
+				return fmt.Errorf("cidr_block(%s) overlaps with %s of vpc[%s]", cidrBlock, vpcCidrBlock, stringValue(item.VpcId))
+			}
+		}
+	}
+	return nil
+}
+
+func getVpcDnsServers(item *qcloudVpc) string {
+	dnsServers := []string{}
+	for _, dnsServer := range item.DnsServerSet {
+		dnsServers = append(dnsServers, *dnsServer)
+	}
+	return strings.Join(dnsServers, ",")
+}
+
+//getVpcSecondaryCidrBlocks returns the assistant cidrs of the vpc
+func getVpcSecondaryCidrBlocks(item *qcloudVpc) []string {
+	cidrBlocks := []string{}
+	for _, assistantCidr := range item.AssistantCidrSet {
+		cidrBlocks = append(cidrBlocks, *assistantCidr.CidrBlock)
+	}
+	return cidrBlocks
+}
+
+//getVpcCidrBlocks returns the primary ipv4 cidr of the vpc followed by the secondary ones
+func getVpcCidrBlocks(item *qcloudVpc) []string {
+	return append([]string{stringValue(item.CidrBlock)}, getVpcSecondaryCidrBlocks(item)...)
+}
+
+func newVpcOutput(guid string, requestId string, item *qcloudVpc) VpcOutput {
+	return VpcOutput{
+		Guid:                guid,
+		RequestId:           requestId,
+		Id:                  stringValue(item.VpcId),
+		Name:                stringValue(item.VpcName),
+		CidrBlock:           stringValue(item.CidrBlock),
+		SecondaryCidrBlocks: strings.Join(getVpcSecondaryCidrBlocks(item), ","),
+		Ipv6CidrBlock:       stringValue(item.Ipv6CidrBlock),
+		DnsServers:          getVpcDnsServers(item),
+		DomainName:          stringValue(item.DomainName),
+		CreatedTime:         stringValue(item.CreatedTime),
+	}
+}
+
+//qcloudSubnet adds the fields of DescribeSubnets which the vendored sdk doesn't know yet
+type qcloudSubnet struct {
+	*vpc.Subnet
+	Ipv6CidrBlock *string `json:"Ipv6CidrBlock,omitempty" name:"Ipv6CidrBlock"`
+}
+
+type describeSubnetsResponse struct {
+	*tchttp.BaseResponse
+	Response *struct {
+		TotalCount *uint64         `json:"TotalCount,omitempty" name:"TotalCount"`
+		SubnetSet  []*qcloudSubnet `json:"SubnetSet,omitempty" name:"SubnetSet"`
+		RequestId  *string         `json:"RequestId,omitempty" name:"RequestId"`
+	} `json:"Response"`
+}
+
+//describeVpcSubnets pages over DescribeSubnets until all the subnets of the vpc are returned
+func describeVpcSubnets(client VpcAPI, vpcId string) ([]*qcloudSubnet, error) {
+	request := vpc.NewDescribeSubnetsRequest()
+	request.Filters = []*vpc.Filter{newVpcFilter("vpc-id", vpcId)}
+	request.Limit = common.StringPtr(strconv.Itoa(VPC_DESCRIBE_PAGE_SIZE))
+
+	subnets := []*qcloudSubnet{}
+	for offset := uint64(0); ; {
+		request.Offset = common.StringPtr(strconv.FormatUint(offset, 10))
+		response := &describeSubnetsResponse{BaseResponse: &tchttp.BaseResponse{}}
+		if err := client.Send(request, response); err != nil {
+			logrus.Errorf("vpc DescribeSubnets meet err=%v", err)
+			return nil, err
+		}
+
+		subnets = append(subnets, response.Response.SubnetSet...)
+		offset += uint64(len(response.Response.SubnetSet))
+		if len(response.Response.SubnetSet) == 0 || offset >= *response.Response.TotalCount {
+			break
+		}
+	}
+	return subnets, nil
+}
+
+//describeVpcRouteTables pages over DescribeRouteTables until all the route tables of the vpc are returned with their routes
+func describeVpcRouteTables(client VpcAPI, vpcId string) ([]*vpc.RouteTable, error) {
+	request := vpc.NewDescribeRouteTablesRequest()
+	request.Filters = []*vpc.Filter{newVpcFilter("vpc-id", vpcId)}
+	request.Limit = common.StringPtr(strconv.Itoa(VPC_DESCRIBE_PAGE_SIZE))
+
+	routeTables := []*vpc.RouteTable{}
+	for offset := uint64(0); ; {
+		request.Offset = common.StringPtr(strconv.FormatUint(offset, 10))
+		response, err := client.DescribeRouteTables(request)
+		if err != nil {
+			logrus.Errorf("vpc DescribeRouteTables meet err=%v", err)
+			return nil, err
+		}
+
+		routeTables = append(routeTables, response.Response.RouteTableSet...)
+		offset += uint64(len(response.Response.RouteTableSet))
+		if len(response.Response.RouteTableSet) == 0 || offset >= *response.Response.TotalCount {
+			break
+		}
+	}
+	return routeTables, nil
+}
+
+//describeVpcNatGateways pages over DescribeNatGateways until all the nat gateways of the vpc are returned
+func describeVpcNatGateways(client VpcAPI, vpcId string) ([]*vpc.NatGateway, error) {
+	request := vpc.NewDescribeNatGatewaysRequest()
+	request.Filters = []*vpc.Filter{newVpcFilter("vpc-id", vpcId)}
+	request.Limit = common.Uint64Ptr(VPC_DESCRIBE_PAGE_SIZE)
+
+	natGateways := []*vpc.NatGateway{}
+	for offset := uint64(0); ; {
+		request.Offset = common.Uint64Ptr(offset)
+		response, err := client.DescribeNatGateways(request)
+		if err != nil {
+			logrus.Errorf("vpc DescribeNatGateways meet err=%v", err)
+			return nil, err
+		}
+
+		natGateways = append(natGateways, response.Response.NatGatewaySet...)
+		offset += uint64(len(response.Response.NatGatewaySet))
+		if len(response.Response.NatGatewaySet) == 0 || offset >= *response.Response.TotalCount {
+			break
+		}
+	}
+	return natGateways, nil
+}
+
+//describeVpcNetworkInterfaces pages over DescribeNetworkInterfaces until all the network interfaces of the vpc are returned,
+//the primary network interfaces of the vms are among them
+func describeVpcNetworkInterfaces(client VpcAPI, vpcId string) ([]*vpc.NetworkInterface, error) {
+	request := vpc.NewDescribeNetworkInterfacesRequest()
+	request.Filters = []*vpc.Filter{newVpcFilter("vpc-id", vpcId)}
+	request.Limit = common.Uint64Ptr(VPC_DESCRIBE_PAGE_SIZE)
+
+	nics := []*vpc.NetworkInterface{}
+	for offset := uint64(0); ; {
+		request.Offset = common.Uint64Ptr(offset)
+		response, err := client.DescribeNetworkInterfaces(request)
+		if err != nil {
+			logrus.Errorf("vpc DescribeNetworkInterfaces meet err=%v", err)
+			return nil, err
+		}
+
+		nics = append(nics, response.Response.NetworkInterfaceSet...)
+		offset += uint64(len(response.Response.NetworkInterfaceSet))
+		if len(response.Response.NetworkInterfaceSet) == 0 || offset >= *response.Response.TotalCount {
+			break
+		}
+	}
+	return nics, nil
+}
+
+//describeSecurityGroupsByIds describes the groups VPC_DESCRIBE_PAGE_SIZE ids a call
+func describeSecurityGroupsByIds(client VpcAPI, groupIds []string) ([]*vpc.SecurityGroup, error) {
+	groups := []*vpc.SecurityGroup{}
+	for start := 0; start < len(groupIds); start += VPC_DESCRIBE_PAGE_SIZE {
+		end := start + VPC_DESCRIBE_PAGE_SIZE
+		if end > len(groupIds) {
+			end = len(groupIds)
+		}
+
+		request := vpc.NewDescribeSecurityGroupsRequest()
+		request.SecurityGroupIds = common.StringPtrs(groupIds[start:end])
+		request.Limit = common.StringPtr(strconv.Itoa(VPC_DESCRIBE_PAGE_SIZE))
+		response, err := client.DescribeSecurityGroups(request)
+		if err != nil {
+			logrus.Errorf("vpc DescribeSecurityGroups meet err=%v", err)
+			return nil, err
+		}
+		groups = append(groups, response.Response.SecurityGroupSet...)
+	}
+	return groups, nil
+}
+
+//vpcPeeringConnection is a PeerConnection of DescribeVpcPeeringConnections
+type vpcPeeringConnection struct {
+	PeeringConnectionId   *string `json:"PeeringConnectionId,omitempty" name:"PeeringConnectionId"`
+	PeeringConnectionName *string `json:"PeeringConnectionName,omitempty" name:"PeeringConnectionName"`
+	SourceVpcId           *string `json:"SourceVpcId,omitempty" name:"SourceVpcId"`
+	PeerVpcId             *string `json:"PeerVpcId,omitempty" name:"PeerVpcId"`
+	SourceRegion          *string `json:"SourceRegion,omitempty" name:"SourceRegion"`
+	DestinationRegion     *string `json:"DestinationRegion,omitempty" name:"DestinationRegion"`
+	State                 *string `json:"State,omitempty" name:"State"`
+	Bandwidth             *int64  `json:"Bandwidth,omitempty" name:"Bandwidth"`
+	CreateTime            *string `json:"CreateTime,omitempty" name:"CreateTime"`
+}
+
+//the vendored sdk doesn't have DescribeVpcPeeringConnections, it is sent as json like the sdk requests
+type describeVpcPeeringConnectionsRequest struct {
+	*tchttp.BaseRequest
+	Filters []*vpc.Filter `json:"Filters,omitempty" name:"Filters"`
+	Offset  *uint64       `json:"Offset,omitempty" name:"Offset"`
+	Limit   *uint64       `json:"Limit,omitempty" name:"Limit"`
+}
+
+type describeVpcPeeringConnectionsResponse struct {
+	*tchttp.BaseResponse
+	Response *struct {
+		TotalCount        *uint64                 `json:"TotalCount,omitempty" name:"TotalCount"`
+		PeerConnectionSet []*vpcPeeringConnection `json:"PeerConnectionSet,omitempty" name:"PeerConnectionSet"`
+		RequestId         *string                 `json:"RequestId,omitempty" name:"RequestId"`
+	} `json:"Response"`
+}
+
+//describeVpcPeeringConnections pages over DescribeVpcPeeringConnections until all the peering connections
+//on either side of the vpc are returned
+func describeVpcPeeringConnections(client VpcAPI, vpcId string) ([]*vpcPeeringConnection, error) {
+	request := &describeVpcPeeringConnectionsRequest{BaseRequest: &tchttp.BaseRequest{}}
+	request.Init().WithApiInfo("vpc", vpc.APIVersion, "DescribeVpcPeeringConnections")
+	request.Filters = []*vpc.Filter{newVpcFilter("vpc-id", vpcId)}
+	request.Limit = common.Uint64Ptr(VPC_DESCRIBE_PAGE_SIZE)
+
+	peeringConnections := []*vpcPeeringConnection{}
+	for offset := uint64(0); ; {
+		request.Offset = common.Uint64Ptr(offset)
+		response := &describeVpcPeeringConnectionsResponse{BaseResponse: &tchttp.BaseResponse{}}
+		if err := client.Send(request, response); err != nil {
+			logrus.Errorf("vpc DescribeVpcPeeringConnections meet err=%v", err)
+			return nil, err
+		}
+
+		peeringConnections = append(peeringConnections, response.Response.PeerConnectionSet...)
+		offset += uint64(len(response.Response.PeerConnectionSet))
+		if len(response.Response.PeerConnectionSet) == 0 || offset >= *response.Response.TotalCount {
+			break
+		}
+	}
+	return peeringConnections, nil
+}
+
+//vpcTopology is the document of a vpc and everything in it, the items keep the fields of the qcloud apis
+type vpcTopology struct {
+	Vpc                *qcloudVpc              `json:"vpc"`
+	Subnets            []*qcloudSubnet         `json:"subnets"`
+	RouteTables        []*vpc.RouteTable       `json:"route_tables"`
+	SecurityGroups     []*vpc.SecurityGroup    `json:"security_groups"`
+	NatGateways        []*vpc.NatGateway       `json:"nat_gateways"`
+	PeeringConnections []*vpcPeeringConnection `json:"peering_connections"`
+	NetworkInterfaces  []*vpc.NetworkInterface `json:"network_interfaces"`
+}
+
+//describeVpcTopology describes everything in the vpc, qcloud security groups don't belong to a vpc
+//so the topology has the groups bound to the network interfaces of the vpc
+func describeVpcTopology(client VpcAPI, item *qcloudVpc) (*vpcTopology, error) {
+	vpcId := *item.VpcId
+	topology := &vpcTopology{Vpc: item}
+
+	var err error
+	if topology.Subnets, err = describeVpcSubnets(client, vpcId); err != nil {
+		return nil, err
+	}
+	if topology.RouteTables, err = describeVpcRouteTables(client, vpcId); err != nil {
+		return nil, err
+	}
+	if topology.NatGateways, err = describeVpcNatGateways(client, vpcId); err != nil {
+		return nil, err
+	}
+	if topology.PeeringConnections, err = describeVpcPeeringConnections(client, vpcId); err != nil {
+		return nil, err
+	}
+	if topology.NetworkInterfaces, err = describeVpcNetworkInterfaces(client, vpcId); err != nil {
+		return nil, err
+	}
+
+	groupIds := []string{}
+	bound := make(map[string]bool)
+	for _, nic := range topology.NetworkInterfaces {
+		for _, groupId := range nic.GroupSet {
+			if !bound[*groupId] {
+				bound[*groupId] = true
+				groupIds = append(groupIds, *groupId)
+			}
+		}
+	}
+	if topology.SecurityGroups, err = describeSecurityGroupsByIds(client, groupIds); err != nil {
+		return nil, err
+	}
+	return topology, nil
+}
+
+type VpcDescribeAction struct {
+	VpcAction
+}
+
+func (action *VpcDescribeAction) Do(input interface{}) (interface{}, error) {
+	vpcs, _ := input.(VpcInputs)
+	outputs := VpcOutputs{}
+	for _, vpcInput := range vpcs.Inputs {
+		client, err := createVpcClientByProviderParams(vpcInput.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		item, err := getVpcById(client, vpcInput.Id)
+		if err != nil {
+			return nil, err
+		}
+		topology, err := describeVpcTopology(client, item)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(topology)
+		if err != nil {
+			return nil, err
+		}
+		logrus.Infof("describe vpc[%s] found %d subnets, %d route tables and %d network interfaces",
+			vpcInput.Id, len(topology.Subnets), len(topology.RouteTables), len(topology.NetworkInterfaces))
+
+		output := newVpcOutput(vpcInput.Guid, "", item)
+		output.Topology = string(data)
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+type VpcModifyAction struct {
+	VpcAction
+}
+
+//only the given attributes are modified, dns_servers replaces all the dns servers of the vpc
+func (action *VpcModifyAction) CheckParam(input interface{}) error {
+	vpcs, ok := input.(VpcInputs)
+	if !ok {
+		return INVALID_PARAMETERS
+	}
+
+	for _, vpcInput := range vpcs.Inputs {
+		if vpcInput.Id == "" {
+			return errors.New("input id is empty")
+		}
+		if vpcInput.Name == "" && vpcInput.DnsServers == "" && vpcInput.DomainName == "" {
+			return errors.New("input name, dns_servers and domain_name are all empty")
+		}
+		dnsServers := splitCommaValues(vpcInput.DnsServers)
+		if len(dnsServers) > VPC_MAX_DNS_SERVERS {
+			return fmt.Errorf("dns_servers(%s) has more than %d servers", vpcInput.DnsServers, VPC_MAX_DNS_SERVERS)
+		}
+		for _, dnsServer := range dnsServers {
+			if net.ParseIP(dnsServer) == nil {
+				return fmt.Errorf("invalid dns server(%s)", dnsServer)
+			}
+		}
+	}
+	return nil
+}
+
+//newVpcModifyRequest returns the request with the attributes which differ from the vpc, and a description of them
+func newVpcModifyRequest(vpcInput VpcInput, item *qcloudVpc) (*vpc.ModifyVpcAttributeRequest, []string) {
+	request := vpc.NewModifyVpcAttributeRequest()
+	request.VpcId = item.VpcId
+	changes := []string{}
+	if vpcInput.Name != "" && vpcInput.Name != stringValue(item.VpcName) {
+		request.VpcName = common.StringPtr(vpcInput.Name)
+		changes = append(changes, fmt.Sprintf("name=%s", vpcInput.Name))
+	}
+	dnsServers := splitCommaValues(vpcInput.DnsServers)
+	if len(dnsServers) > 0 && strings.Join(dnsServers, ",") != getVpcDnsServers(item) {
+		request.DnsServers = common.StringPtrs(dnsServers)
+		changes = append(changes, fmt.Sprintf("dns_servers=%s", strings.Join(dnsServers, ",")))
+	}
+	if vpcInput.DomainName != "" && vpcInput.DomainName != stringValue(item.DomainName) {
+		request.DomainName = common.StringPtr(vpcInput.DomainName)
+		changes = append(changes, fmt.Sprintf("domain_name=%s", vpcInput.DomainName))
+	}
+	return request, changes
+}
+
+func (action *VpcModifyAction) Do(input interface{}) (interface{}, error) {
+	vpcs, _ := input.(VpcInputs)
+	outputs := VpcOutputs{}
+	for _, vpcInput := range vpcs.Inputs {
+		output, err := modifyVpc(vpcInput)
+		if err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+func modifyVpc(vpcInput VpcInput) (VpcOutput, error) {
+	client, err := createVpcClientByProviderParams(vpcInput.ProviderParams)
+	if err != nil {
+		return VpcOutput{}, err
+	}
+
+	item, err := getVpcById(client, vpcInput.Id)
+	if err != nil {
+		return VpcOutput{}, err
+	}
+	request, changes := newVpcModifyRequest(vpcInput, item)
+	if len(changes) == 0 {
+		logrus.Infof("vpc[%s] already has the attributes of the input", vpcInput.Id)
+		return newVpcOutput(vpcInput.Guid, "", item), nil
+	}
+
+	response, err := client.ModifyVpcAttribute(request)
+	if err != nil {
+		return VpcOutput{}, err
+	}
+	logrus.Infof("Modify vpc[%v] %v has been submitted, RequestID is [%v]", vpcInput.Id, strings.Join(changes, " "), *response.Response.RequestId)
+
+	if item, err = getVpcById(client, vpcInput.Id); err != nil {
+		return VpcOutput{}, err
+	}
+	return newVpcOutput(vpcInput.Guid, *response.Response.RequestId, item), nil
+}
+
+func (action *VpcModifyAction) DryRun(input interface{}) (interface{}, error) {
+	vpcs, _ := input.(VpcInputs)
+	outputs := DryRunOutputs{}
+	for _, vpcInput := range vpcs.Inputs {
+		client, err := createVpcClientByProviderParams(vpcInput.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		item, exist, err := queryVpcById(client, vpcInput.Id)
+		if err != nil {
+			return nil, err
+		}
+		output := newDryRunOutput(vpcInput.Guid, vpcInput.Id, exist, DRY_RUN_OPERATION_NONE, "")
+		if !exist {
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("vpc[%s] not found", vpcInput.Id))
+		} else if _, changes := newVpcModifyRequest(vpcInput, item); len(changes) == 0 {
+			output.Detail = "vpc already has the attributes"
+		} else {
+			output.Operation = DRY_RUN_OPERATION_MODIFY
+			output.Detail = fmt.Sprintf("modify vpc %s", strings.Join(changes, " "))
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+//assistantCidrRequest is CreateAssistantCidr or DeleteAssistantCidr, which the vendored sdk doesn't have
+type assistantCidrRequest struct {
+	*tchttp.BaseRequest
+	VpcId      *string   `json:"VpcId,omitempty" name:"VpcId"`
+	CidrBlocks []*string `json:"CidrBlocks,omitempty" name:"CidrBlocks"`
+}
+
+type assistantCidrResponse struct {
+	*tchttp.BaseResponse
+	Response *struct {
+		AssistantCidrSet []*vpc.AssistantCidr `json:"AssistantCidrSet,omitempty" name:"AssistantCidrSet"`
+		RequestId        *string              `json:"RequestId,omitempty" name:"RequestId"`
+	} `json:"Response"`
+}
+
+//sendAssistantCidrRequest sends CreateAssistantCidr or DeleteAssistantCidr for the cidrs of the vpc and returns the request id
+func sendAssistantCidrRequest(client VpcAPI, action string, vpcId string, cidrBlocks []string) (string, error) {
+	request := &assistantCidrRequest{BaseRequest: &tchttp.BaseRequest{}}
+	request.Init().WithApiInfo("vpc", vpc.APIVersion, action)
+	request.VpcId = &vpcId
+	request.CidrBlocks = common.StringPtrs(cidrBlocks)
+
+	response := &assistantCidrResponse{BaseResponse: &tchttp.BaseResponse{}}
+	if err := client.Send(request, response); err != nil {
+		logrus.Errorf("vpc %s meet err=%v", action, err)
+		return "", err
+	}
+	logrus.Infof("%s of vpc[%v] cidrs[%v] has been submitted, RequestID is [%v]", action, vpcId, strings.Join(cidrBlocks, ","), *response.Response.RequestId)
+	return *response.Response.RequestId, nil
+}
+
+//the secondary cidrs of an input are ipv4 cidrs which don't overlap each other
+func checkVpcSecondaryCidrBlocks(input interface{}) error {
+	vpcs, ok := input.(VpcInputs)
+	if !ok {
+		return INVALID_PARAMETERS
+	}
+
+	for _, vpcInput := range vpcs.Inputs {
+		if vpcInput.Id == "" {
+			return errors.New("input id is empty")
+		}
+		cidrBlocks := splitCommaValues(vpcInput.SecondaryCidrBlocks)
+		if len(cidrBlocks) == 0 {
+			return errors.New("input secondary_cidr_blocks is empty")
+		}
+		for i, cidrBlock := range cidrBlocks {
+			if !isIpv4Cidr(cidrBlock) {
+				return fmt.Errorf("invalid secondary cidr block(%s)", cidrBlock)
+			}
+			for _, other := range cidrBlocks[:i] {
+				if isCidrOverlap(cidrBlock, other) {
+					return fmt.Errorf("secondary cidr block(%s) overlaps with %s", cidrBlock, other)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+//getVpcCidrBlocksToAdd returns the secondary cidrs of the input the vpc doesn't have yet,
+//they must not overlap the primary or secondary cidrs of the vpc
+func getVpcCidrBlocksToAdd(vpcInput VpcInput, item *qcloudVpc) ([]string, error) {
+	existed := getVpcCidrBlocks(item)
+	cidrBlocks := []string{}
+	for _, cidrBlock := range splitCommaValues(vpcInput.SecondaryCidrBlocks) {
+		if isStringInList(cidrBlock, existed) {
+			continue
+		}
+		for _, existedCidrBlock := range existed {
+			if isCidrOverlap(cidrBlock, existedCidrBlock) {
+				return nil, fmt.Errorf("secondary cidr block(%s) overlaps with %s of vpc[%s]", cidrBlock, existedCidrBlock, vpcInput.Id)
+			}
+		}
+		cidrBlocks = append(cidrBlocks, cidrBlock)
+	}
+	return cidrBlocks, nil
+}
+
+//getVpcCidrBlocksToRemove returns the secondary cidrs of the input the vpc still has,
+//qcloud doesn't remove a cidr which has subnets in it
+func getVpcCidrBlocksToRemove(client VpcAPI, vpcInput VpcInput, item *qcloudVpc) ([]string, error) {
+	secondaryCidrBlocks := getVpcSecondaryCidrBlocks(item)
+	cidrBlocks := []string{}
+	for _, cidrBlock := range splitCommaValues(vpcInput.SecondaryCidrBlocks) {
+		if cidrBlock == stringValue(item.CidrBlock) {
+			return nil, fmt.Errorf("cidr block(%s) is the primary cidr block of vpc[%s]", cidrBlock, vpcInput.Id)
+		}
+		if isStringInList(cidrBlock, secondaryCidrBlocks) {
+			cidrBlocks = append(cidrBlocks, cidrBlock)
+		}
+	}
+	if len(cidrBlocks) == 0 {
+		return cidrBlocks, nil
+	}
+
+	subnets, err := describeVpcSubnets(client, vpcInput.Id)
+	if err != nil {
+		return nil, err
+	}
+	for _, subnet := range subnets {
+		for _, cidrBlock := range cidrBlocks {
+			if isCidrContains(cidrBlock, stringValue(subnet.CidrBlock)) {
+				return nil, fmt.Errorf("secondary cidr block(%s) still has subnet[%s]", cidrBlock, *subnet.SubnetId)
+			}
+		}
+	}
+	return cidrBlocks, nil
+}
+
+type VpcAddCidrsAction struct {
+	VpcAction
+}
+
+func (action *VpcAddCidrsAction) CheckParam(input interface{}) error {
+	return checkVpcSecondaryCidrBlocks(input)
+}
+
+func (action *VpcAddCidrsAction) Do(input interface{}) (interface{}, error) {
+	vpcs, _ := input.(VpcInputs)
+	outputs := VpcOutputs{}
+	for _, vpcInput := range vpcs.Inputs {
+		client, err := createVpcClientByProviderParams(vpcInput.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		item, err := getVpcById(client, vpcInput.Id)
+		if err != nil {
+			return nil, err
+		}
+		cidrBlocks, err := getVpcCidrBlocksToAdd(vpcInput, item)
+		if err != nil {
+			return nil, err
+		}
+		if len(cidrBlocks) == 0 {
+			logrus.Infof("vpc[%s] already has secondary cidr blocks[%s]", vpcInput.Id, vpcInput.SecondaryCidrBlocks)
+			outputs.Outputs = append(outputs.Outputs, newVpcOutput(vpcInput.Guid, "", item))
+			continue
+		}
+
+		requestId, err := sendAssistantCidrRequest(client, "CreateAssistantCidr", vpcInput.Id, cidrBlocks)
+		if err != nil {
+			return nil, err
+		}
+		if item, err = getVpcById(client, vpcInput.Id); err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, newVpcOutput(vpcInput.Guid, requestId, item))
+	}
+	return &outputs, nil
+}
+
+func (action *VpcAddCidrsAction) DryRun(input interface{}) (interface{}, error) {
+	vpcs, _ := input.(VpcInputs)
+	outputs := DryRunOutputs{}
+	for _, vpcInput := range vpcs.Inputs {
+		client, err := createVpcClientByProviderParams(vpcInput.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		item, exist, err := queryVpcById(client, vpcInput.Id)
+		if err != nil {
+			return nil, err
+		}
+		output := newDryRunOutput(vpcInput.Guid, vpcInput.Id, exist, DRY_RUN_OPERATION_NONE, "")
+		if !exist {
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("vpc[%s] not found", vpcInput.Id))
+		} else if cidrBlocks, err := getVpcCidrBlocksToAdd(vpcInput, item); err != nil {
+			output.Conflicts = append(output.Conflicts, err.Error())
+		} else if len(cidrBlocks) == 0 {
+			output.Detail = "vpc already has the secondary cidr blocks"
+		} else {
+			output.Operation = DRY_RUN_OPERATION_MODIFY
+			output.Detail = fmt.Sprintf("add secondary cidr blocks[%s] to vpc", strings.Join(cidrBlocks, ","))
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+type VpcRemoveCidrsAction struct {
+	VpcAction
+}
+
+func (action *VpcRemoveCidrsAction) CheckParam(input interface{}) error {
+	return checkVpcSecondaryCidrBlocks(input)
+}
+
+func (action *VpcRemoveCidrsAction) Do(input interface{}) (interface{}, error) {
+	vpcs, _ := input.(VpcInputs)
+	outputs := VpcOutputs{}
+	for _, vpcInput := range vpcs.Inputs {
+		client, err := createVpcClientByProviderParams(vpcInput.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		item, err := getVpcById(client, vpcInput.Id)
+		if err != nil {
+			return nil, err
+		}
+		cidrBlocks, err := getVpcCidrBlocksToRemove(client, vpcInput, item)
+		if err != nil {
+			return nil, err
+		}
+		if len(cidrBlocks) == 0 {
+			logrus.Infof("secondary cidr blocks[%s] are already removed from vpc[%s]", vpcInput.SecondaryCidrBlocks, vpcInput.Id)
+			outputs.Outputs = append(outputs.Outputs, newVpcOutput(vpcInput.Guid, "", item))
+			continue
+		}
+
+		requestId, err := sendAssistantCidrRequest(client, "DeleteAssistantCidr", vpcInput.Id, cidrBlocks)
+		if err != nil {
+			return nil, err
+		}
+		if item, err = getVpcById(client, vpcInput.Id); err != nil {
+			return nil, err
+		}
+		outputs.Outputs = append(outputs.Outputs, newVpcOutput(vpcInput.Guid, requestId, item))
+	}
+	return &outputs, nil
+}
+
+func (action *VpcRemoveCidrsAction) DryRun(input interface{}) (interface{}, error) {
+	vpcs, _ := input.(VpcInputs)
+	outputs := DryRunOutputs{}
+	for _, vpcInput := range vpcs.Inputs {
+		client, err := createVpcClientByProviderParams(vpcInput.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		item, exist, err := queryVpcById(client, vpcInput.Id)
+		if err != nil {
+			return nil, err
+		}
+		output := newDryRunOutput(vpcInput.Guid, vpcInput.Id, exist, DRY_RUN_OPERATION_NONE, "")
+		if !exist {
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("vpc[%s] not found", vpcInput.Id))
+		} else if cidrBlocks, err := getVpcCidrBlocksToRemove(client, vpcInput, item); err != nil {
+			output.Conflicts = append(output.Conflicts, err.Error())
+		} else if len(cidrBlocks) == 0 {
+			output.Detail = "vpc doesn't have the secondary cidr blocks"
+		} else {
+			output.Operation = DRY_RUN_OPERATION_MODIFY
+			output.Detail = fmt.Sprintf("remove secondary cidr blocks[%s] from vpc", strings.Join(cidrBlocks, ","))
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+//qcloud assigns a /56 ipv6 cidr to a vpc only once
+type VpcAssignIpv6Action struct {
+	VpcAction
+}
+
+func (action *VpcAssignIpv6Action) Do(input interface{}) (interface{}, error) {
+	vpcs, _ := input.(VpcInputs)
+	outputs := VpcOutputs{}
+	for _, vpcInput := range vpcs.Inputs {
+		client, err := createVpcClientByProviderParams(vpcInput.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		item, err := getVpcById(client, vpcInput.Id)
+		if err != nil {
+			return nil, err
+		}
+		output := newVpcOutput(vpcInput.Guid, "", item)
+		if output.Ipv6CidrBlock != "" {
+			logrus.Infof("vpc[%s] already has ipv6 cidr block[%s]", vpcInput.Id, output.Ipv6CidrBlock)
+			outputs.Outputs = append(outputs.Outputs, output)
+			continue
+		}
+
+		request := vpc.NewAssignIpv6CidrBlockRequest()
+		request.VpcId = &vpcInput.Id
+		response, err := client.AssignIpv6CidrBlock(request)
+		if err != nil {
+			return nil, err
+		}
+		logrus.Infof("Assign ipv6 cidr block[%v] to vpc[%v] has been submitted, RequestID is [%v]", *response.Response.Ipv6CidrBlock, vpcInput.Id, *response.Response.RequestId)
+
+		output.RequestId = *response.Response.RequestId
+		output.Ipv6CidrBlock = *response.Response.Ipv6CidrBlock
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
+
+func (action *VpcAssignIpv6Action) DryRun(input interface{}) (interface{}, error) {
+	vpcs, _ := input.(VpcInputs)
+	outputs := DryRunOutputs{}
+	for _, vpcInput := range vpcs.Inputs {
+		client, err := createVpcClientByProviderParams(vpcInput.ProviderParams)
+		if err != nil {
+			return nil, err
+		}
+
+		item, exist, err := queryVpcById(client, vpcInput.Id)
+		if err != nil {
+			return nil, err
+		}
+		output := newDryRunOutput(vpcInput.Guid, vpcInput.Id, exist, DRY_RUN_OPERATION_NONE, "")
+		if !exist {
+			output.Conflicts = append(output.Conflicts, fmt.Sprintf("vpc[%s] not found", vpcInput.Id))
+		} else if ipv6CidrBlock := stringValue(item.Ipv6CidrBlock); ipv6CidrBlock != "" {
+			output.Detail = fmt.Sprintf("vpc already has ipv6 cidr block[%s]", ipv6CidrBlock)
+		} else {
+			output.Operation = DRY_RUN_OPERATION_MODIFY
+			output.Detail = "assign ipv6 cidr block to vpc"
+		}
+		outputs.Outputs = append(outputs.Outputs, output)
+	}
+	return &outputs, nil
+}
@@ -0,0 +1,32 @@
+package plugins
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVpcCreateCheckParamOverlaps(t *testing.T) {
+	cases := []struct {
+		name    string
+		inputs  []VpcInput
+		wantErr string
+	}{
+		{"disjoint", []VpcInput{{Name: "vpc-1", CidrBlock: "10.0.0.0/16"}, {Name: "vpc-2", CidrBlock: "10.1.0.0/16"}}, ""},
+		{"overlaps", []VpcInput{{Name: "vpc-1", CidrBlock: "10.0.0.0/16"}, {Name: "vpc-2", CidrBlock: "10.0.0.0/8"}},
+			"vpcCidr[10.0.0.0/8] overlaps with vpcCidr[10.0.0.0/16] of another input"},
+		{"other region", []VpcInput{
+			{Name: "vpc-1", CidrBlock: "10.0.0.0/16", ProviderParams: "Region=ap-guangzhou"},
+			{Name: "vpc-2", CidrBlock: "10.0.0.0/16", ProviderParams: "Region=ap-shanghai"},
+		}, ""},
+	}
+	action := &VpcCreateAction{}
+	for _, c := range cases {
+		err := action.CheckParam(VpcInputs{Inputs: c.inputs})
+		if c.wantErr == "" && err != nil {
+			t.Errorf("%s: err=%v", c.name, err)
+		}
+		if c.wantErr != "" && (err == nil || !strings.Contains(err.Error(), c.wantErr)) {
+			t.Errorf("%s: err=%v,want %s", c.name, err, c.wantErr)
+		}
+	}
+}
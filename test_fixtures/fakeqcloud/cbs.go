@@ -0,0 +1,445 @@
+package fakeqcloud
+
+import (
+	"time"
+)
+
+const (
+	KIND_CBS_DISK                 = "cbs-disk"
+	KIND_CBS_SNAPSHOT             = "cbs-snapshot"
+	KIND_CBS_AUTO_SNAPSHOT_POLICY = "cbs-auto-snapshot-policy"
+)
+
+func (s *Server) registerCbs() {
+	s.register("cbs", "CreateDisks", createDisks)
+	s.register("cbs", "DescribeDisks", describeDisks)
+	s.register("cbs", "DescribeDiskOperationLogs", describeDiskOperationLogs)
+	s.register("cbs", "AttachDisks", attachDisks)
+	s.register("cbs", "DetachDisks", detachDisks)
+	s.register("cbs", "TerminateDisks", terminateDisks)
+	s.register("cbs", "ResizeDisk", resizeDisk)
+	s.register("cbs", "CreateSnapshot", createSnapshot)
+	s.register("cbs", "DescribeSnapshots", describeSnapshots)
+	s.register("cbs", "DeleteSnapshots", deleteSnapshots)
+	s.register("cbs", "ApplySnapshot", applySnapshot)
+	s.register("cbs", "CreateAutoSnapshotPolicy", createAutoSnapshotPolicy)
+	s.register("cbs", "DescribeAutoSnapshotPolicies", describeAutoSnapshotPolicies)
+	s.register("cbs", "DeleteAutoSnapshotPolicies", deleteAutoSnapshotPolicies)
+	s.register("cbs", "BindAutoSnapshotPolicy", bindAutoSnapshotPolicy)
+	s.register("cbs", "UnbindAutoSnapshotPolicy", unbindAutoSnapshotPolicy)
+}
+
+func createDisks(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "DiskType", "DiskChargeType", "Placement"); err != nil {
+		return nil, err
+	}
+
+	//qcloud returns the disks of the first request for the same client token
+	if clientToken := ctx.str("ClientToken"); clientToken != "" {
+		ids := []string{}
+		for _, item := range s.findResources(KIND_CBS_DISK, ctx.region, func(data map[string]interface{}) bool {
+			return data["clientToken"] == clientToken
+		}) {
+			ids = append(ids, item["DiskId"].(string))
+		}
+		if len(ids) > 0 {
+			return map[string]interface{}{"DiskIdSet": ids}, nil
+		}
+	}
+	size := ctx.int("DiskSize", 10)
+	//the disk created from a snapshot is at least as large as the snapshot
+	if snapshotId := ctx.str("SnapshotId"); snapshotId != "" {
+		snapshot, err := mustGet(s, KIND_CBS_SNAPSHOT, ctx, "SnapshotId", "InvalidSnapshotId.NotFound")
+		if err != nil {
+			return nil, err
+		}
+		if snapshot.data["SnapshotState"] != "NORMAL" {
+			return nil, newApiError("InvalidSnapshot.NotSupported", "snapshot[%s] is %s", snapshotId, snapshot.data["SnapshotState"])
+		}
+		snapshotSize, _ := snapshot.data["DiskSize"].(int64)
+		size = ctx.int("DiskSize", snapshotSize)
+		if size < snapshotSize {
+			return nil, newApiError("InvalidParameterValue.DiskSizeNotMatch", "disk size %d is smaller than snapshot[%s]", size, snapshotId)
+		}
+	}
+	if count := ctx.int("DiskCount", 1); count < 1 || count > 50 {
+		return nil, newApiError("InvalidParameterValue", "DiskCount %d is out of range [1,50]", count)
+	}
+	ids := []string{}
+	for i := int64(0); i < ctx.int("DiskCount", 1); i++ {
+		id := s.newId("disk")
+		data := map[string]interface{}{
+			"DiskId":             id,
+			"DiskName":           ctx.str("DiskName"),
+			"DiskType":           ctx.str("DiskType"),
+			"DiskSize":           size,
+			"DiskChargeType":     ctx.str("DiskChargeType"),
+			"DiskUsage":          "DATA_DISK",
+			"Placement":          ctx.object("Placement"),
+			"Encrypt":            ctx.str("Encrypt") == "ENCRYPT",
+			"Attached":           false,
+			"InstanceId":         "",
+			"DeleteWithInstance": false,
+			"DiskState":          "UNATTACHED",
+			"Rollbacking":        false,
+			"RollbackPercent":    0,
+			"SnapshotId":         ctx.str("SnapshotId"),
+			"Tags":               ctx.objects("Tags"),
+			"CreateTime":         now(),
+			"clientToken":        ctx.str("ClientToken"),
+		}
+		if throughput := ctx.int("ThroughputPerformance", 0); throughput > 0 {
+			data["ThroughputPerformance"] = throughput
+		}
+		s.putResource(KIND_CBS_DISK, ctx.region, id, data)
+		ids = append(ids, id)
+	}
+	return map[string]interface{}{"DiskIdSet": ids}, nil
+}
+
+func describeDisks(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	items := s.listResources(KIND_CBS_DISK, ctx.region, matchFilters(ctx, "DiskIds", "DiskId", map[string]string{
+		"disk-id":          "DiskId",
+		"disk-name":        "DiskName",
+		"disk-type":        "DiskType",
+		"disk-usage":       "DiskUsage",
+		"disk-state":       "DiskState",
+		"disk-charge-type": "DiskChargeType",
+		"instance-id":      "InstanceId",
+	}))
+	return map[string]interface{}{"TotalCount": len(items), "DiskSet": page(ctx, items)}, nil
+}
+
+//logDiskOperation keeps the operation log of the disk, which DescribeDiskOperationLogs returns
+func logDiskOperation(disk map[string]interface{}, operation string) {
+	logs, _ := disk["operationLogs"].([]interface{})
+	disk["operationLogs"] = append(logs, map[string]interface{}{
+		"DiskId":         disk["DiskId"],
+		"Operation":      operation,
+		"OperationState": "SUCCESS",
+		"StartTime":      now(),
+		"EndTime":        now(),
+	})
+}
+
+func describeDiskOperationLogs(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	diskIds := ctx.filters()["disk-id"]
+	if len(diskIds) == 0 || len(diskIds) > 10 {
+		return nil, newApiError("InvalidParameterValue", "filter disk-id should have 1 to 10 disks, got %d", len(diskIds))
+	}
+	logs := []interface{}{}
+	for _, id := range diskIds {
+		if disk, found := s.getResource(KIND_CBS_DISK, ctx.region, id); found {
+			diskLogs, _ := disk.data["operationLogs"].([]interface{})
+			logs = append(logs, diskLogs...)
+		}
+	}
+	return map[string]interface{}{"DiskOperationLogSet": logs}, nil
+}
+
+func getDisks(s *Server, ctx *requestContext) ([]*resource, error) {
+	if err := require(ctx, "DiskIds"); err != nil {
+		return nil, err
+	}
+	disks := []*resource{}
+	for _, id := range ctx.strs("DiskIds") {
+		r, found := s.getResource(KIND_CBS_DISK, ctx.region, id)
+		if !found {
+			return nil, newApiError("InvalidDiskId.NotFound", "disk[%s] not found", id)
+		}
+		disks = append(disks, r)
+	}
+	return disks, nil
+}
+
+//the disk and the instance must be in the same zone, the disks are listed in the DataDisks of the instance in attaching order
+func attachDisks(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	disks, err := getDisks(s, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(disks) > 10 {
+		return nil, newApiError("InvalidParameterValue", "at most 10 disks can be attached at once")
+	}
+	instance, err := mustGet(s, KIND_CVM_INSTANCE, ctx, "InstanceId", "InvalidInstanceId.NotFound")
+	if err != nil {
+		return nil, err
+	}
+	instanceZone := instance.data["Placement"].(map[string]interface{})["Zone"]
+	for _, disk := range disks {
+		if disk.data["DiskState"] != "UNATTACHED" {
+			return nil, newApiError("InvalidDiskId.NotSupported", "disk[%s] is %s", disk.data["DiskId"], disk.data["DiskState"])
+		}
+		if disk.data["Placement"].(map[string]interface{})["Zone"] != instanceZone {
+			return nil, newApiError("InvalidParameterValue", "disk[%s] is not in the zone[%v] of the instance", disk.data["DiskId"], instanceZone)
+		}
+	}
+	for _, disk := range disks {
+		id := disk.data["DiskId"].(string)
+		disk.data["InstanceId"] = ctx.str("InstanceId")
+		disk.data["Attached"] = true
+		disk.data["DeleteWithInstance"] = ctx.params["DeleteWithInstance"] == true
+		disk.data["DiskState"] = "ATTACHING"
+		s.schedule(KIND_CBS_DISK, ctx.region, id, "DiskState", "ATTACHED")
+		dataDisks, _ := instance.data["DataDisks"].([]map[string]interface{})
+		instance.data["DataDisks"] = append(dataDisks, map[string]interface{}{
+			"DiskId":   id,
+			"DiskType": disk.data["DiskType"],
+			"DiskSize": disk.data["DiskSize"],
+		})
+	}
+	return map[string]interface{}{}, nil
+}
+
+func detachDisks(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	disks, err := getDisks(s, ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, disk := range disks {
+		if disk.data["DiskState"] != "ATTACHED" {
+			return nil, newApiError("InvalidDiskId.NotSupported", "disk[%s] is %s", disk.data["DiskId"], disk.data["DiskState"])
+		}
+	}
+	for _, disk := range disks {
+		id := disk.data["DiskId"].(string)
+		if instance, found := s.getResource(KIND_CVM_INSTANCE, ctx.region, disk.data["InstanceId"].(string)); found {
+			dataDisks, _ := instance.data["DataDisks"].([]map[string]interface{})
+			remained := []map[string]interface{}{}
+			for _, dataDisk := range dataDisks {
+				if dataDisk["DiskId"] != id {
+					remained = append(remained, dataDisk)
+				}
+			}
+			instance.data["DataDisks"] = remained
+		}
+		disk.data["LastAttachInsId"] = disk.data["InstanceId"]
+		disk.data["InstanceId"] = ""
+		disk.data["Attached"] = false
+		disk.data["DiskState"] = "DETACHING"
+		logDiskOperation(disk.data, "CBS_OPERATION_DETACH")
+		s.schedule(KIND_CBS_DISK, ctx.region, id, "DiskState", "UNATTACHED")
+	}
+	return map[string]interface{}{}, nil
+}
+
+//the disks attached to a terminated instance are deleted with it unless DeleteWithInstance is false,
+//the others are left unattached
+func releaseInstanceDisks(s *Server, region string, instanceId string) {
+	for _, disk := range s.findResources(KIND_CBS_DISK, region, func(data map[string]interface{}) bool {
+		return data["InstanceId"] == instanceId
+	}) {
+		if disk["DeleteWithInstance"] == true {
+			s.removeResource(KIND_CBS_DISK, region, disk["DiskId"].(string))
+			continue
+		}
+		disk["LastAttachInsId"] = instanceId
+		disk["InstanceId"] = ""
+		disk["Attached"] = false
+		disk["DiskState"] = "UNATTACHED"
+		logDiskOperation(disk, "CBS_OPERATION_DETACH")
+	}
+}
+
+func terminateDisks(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	disks, err := getDisks(s, ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, disk := range disks {
+		if disk.data["DiskState"] != "UNATTACHED" {
+			return nil, newApiError("InvalidDiskId.NotSupported", "disk[%s] is %s", disk.data["DiskId"], disk.data["DiskState"])
+		}
+	}
+	for _, disk := range disks {
+		s.removeResource(KIND_CBS_DISK, ctx.region, disk.data["DiskId"].(string))
+	}
+	return map[string]interface{}{}, nil
+}
+
+//the disk is expanding for a while and then goes back to its former state
+func resizeDisk(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "DiskId", "DiskSize"); err != nil {
+		return nil, err
+	}
+	disk, err := mustGet(s, KIND_CBS_DISK, ctx, "DiskId", "InvalidDiskId.NotFound")
+	if err != nil {
+		return nil, err
+	}
+	state := disk.data["DiskState"]
+	if state != "UNATTACHED" && state != "ATTACHED" {
+		return nil, newApiError("InvalidDiskId.NotSupported", "disk[%s] is %s", disk.data["DiskId"], state)
+	}
+	if size, _ := disk.data["DiskSize"].(int64); ctx.int("DiskSize", 0) <= size {
+		return nil, newApiError("InvalidParameterValue.DiskSizeNotMatch", "disk[%s] can only be expanded", disk.data["DiskId"])
+	}
+	disk.data["DiskSize"] = ctx.int("DiskSize", 0)
+	disk.data["DiskState"] = "EXPANDING"
+	s.schedule(KIND_CBS_DISK, ctx.region, ctx.str("DiskId"), "DiskState", state)
+	return map[string]interface{}{}, nil
+}
+
+//the snapshot is creating for a while before it can be used
+func createSnapshot(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	disk, err := mustGet(s, KIND_CBS_DISK, ctx, "DiskId", "InvalidDiskId.NotFound")
+	if err != nil {
+		return nil, err
+	}
+	id := s.newId("snap")
+	s.putResource(KIND_CBS_SNAPSHOT, ctx.region, id, map[string]interface{}{
+		"SnapshotId":    id,
+		"SnapshotName":  ctx.str("SnapshotName"),
+		"DiskId":        disk.data["DiskId"],
+		"DiskSize":      disk.data["DiskSize"],
+		"DiskUsage":     disk.data["DiskUsage"],
+		"Placement":     disk.data["Placement"],
+		"Encrypt":       disk.data["Encrypt"],
+		"SnapshotState": "CREATING",
+		"CreateTime":    now(),
+	})
+	s.schedule(KIND_CBS_SNAPSHOT, ctx.region, id, "SnapshotState", "NORMAL")
+	return map[string]interface{}{"SnapshotId": id}, nil
+}
+
+func describeSnapshots(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	items := s.listResources(KIND_CBS_SNAPSHOT, ctx.region, matchFilters(ctx, "SnapshotIds", "SnapshotId", map[string]string{
+		"snapshot-id":    "SnapshotId",
+		"snapshot-name":  "SnapshotName",
+		"snapshot-state": "SnapshotState",
+		"disk-id":        "DiskId",
+		"disk-usage":     "DiskUsage",
+	}))
+	return map[string]interface{}{"TotalCount": len(items), "SnapshotSet": page(ctx, items)}, nil
+}
+
+func deleteSnapshots(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "SnapshotIds"); err != nil {
+		return nil, err
+	}
+	for _, id := range ctx.strs("SnapshotIds") {
+		snapshot, found := s.getResource(KIND_CBS_SNAPSHOT, ctx.region, id)
+		if !found {
+			return nil, newApiError("InvalidSnapshotId.NotFound", "snapshot[%s] not found", id)
+		}
+		if snapshot.data["SnapshotState"] != "NORMAL" {
+			return nil, newApiError("InvalidSnapshot.NotSupported", "snapshot[%s] is %s", id, snapshot.data["SnapshotState"])
+		}
+	}
+	for _, id := range ctx.strs("SnapshotIds") {
+		s.removeResource(KIND_CBS_SNAPSHOT, ctx.region, id)
+	}
+	return map[string]interface{}{}, nil
+}
+
+//a disk is rolled back only to its own snapshots, the instance of an attached disk must be stopped
+func applySnapshot(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	snapshot, err := mustGet(s, KIND_CBS_SNAPSHOT, ctx, "SnapshotId", "InvalidSnapshotId.NotFound")
+	if err != nil {
+		return nil, err
+	}
+	disk, err := mustGet(s, KIND_CBS_DISK, ctx, "DiskId", "InvalidDiskId.NotFound")
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.data["SnapshotState"] != "NORMAL" {
+		return nil, newApiError("InvalidSnapshot.NotSupported", "snapshot[%s] is %s", ctx.str("SnapshotId"), snapshot.data["SnapshotState"])
+	}
+	if snapshot.data["DiskId"] != ctx.str("DiskId") {
+		return nil, newApiError("InvalidParameterValue", "snapshot[%s] is not taken from disk[%s]", ctx.str("SnapshotId"), ctx.str("DiskId"))
+	}
+	if instanceId, _ := disk.data["InstanceId"].(string); instanceId != "" {
+		if instance, found := s.getResource(KIND_CVM_INSTANCE, ctx.region, instanceId); found && instance.data["InstanceState"] != "STOPPED" {
+			return nil, newApiError("InvalidInstance.NotSupported", "instance[%s] of disk[%s] is not stopped", instanceId, ctx.str("DiskId"))
+		}
+	}
+	snapshot.data["SnapshotState"] = "ROLLBACKING"
+	disk.data["Rollbacking"] = true
+	disk.data["RollbackPercent"] = 0
+	s.schedule(KIND_CBS_SNAPSHOT, ctx.region, ctx.str("SnapshotId"), "SnapshotState", "NORMAL")
+	s.schedule(KIND_CBS_DISK, ctx.region, ctx.str("DiskId"), "RollbackPercent", 100)
+	s.schedule(KIND_CBS_DISK, ctx.region, ctx.str("DiskId"), "Rollbacking", false)
+	return map[string]interface{}{}, nil
+}
+
+func createAutoSnapshotPolicy(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "Policy"); err != nil {
+		return nil, err
+	}
+	id := s.newId("asp")
+	isActivated, found := ctx.params["IsActivated"].(bool)
+	if !found {
+		isActivated = true
+	}
+	isPermanent, _ := ctx.params["IsPermanent"].(bool)
+	nextTriggerTime := time.Now().Add(time.Hour).In(beijingTime).Format("2006-01-02 15:04:05")
+	s.putResource(KIND_CBS_AUTO_SNAPSHOT_POLICY, ctx.region, id, map[string]interface{}{
+		"AutoSnapshotPolicyId":    id,
+		"AutoSnapshotPolicyName":  ctx.str("AutoSnapshotPolicyName"),
+		"AutoSnapshotPolicyState": "NORMAL",
+		"IsActivated":             isActivated,
+		"IsPermanent":             isPermanent,
+		"RetentionDays":           ctx.int("RetentionDays", 7),
+		"Policy":                  ctx.objects("Policy"),
+		"DiskIdSet":               []interface{}{},
+		"NextTriggerTime":         nextTriggerTime,
+		"CreateTime":              now(),
+	})
+	return map[string]interface{}{"AutoSnapshotPolicyId": id, "NextTriggerTime": nextTriggerTime}, nil
+}
+
+func describeAutoSnapshotPolicies(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	items := s.listResources(KIND_CBS_AUTO_SNAPSHOT_POLICY, ctx.region, matchFilters(ctx, "AutoSnapshotPolicyIds", "AutoSnapshotPolicyId", map[string]string{
+		"auto-snapshot-policy-id":    "AutoSnapshotPolicyId",
+		"auto-snapshot-policy-name":  "AutoSnapshotPolicyName",
+		"auto-snapshot-policy-state": "AutoSnapshotPolicyState",
+	}))
+	return map[string]interface{}{"TotalCount": len(items), "AutoSnapshotPolicySet": page(ctx, items)}, nil
+}
+
+func deleteAutoSnapshotPolicies(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "AutoSnapshotPolicyIds"); err != nil {
+		return nil, err
+	}
+	for _, id := range ctx.strs("AutoSnapshotPolicyIds") {
+		if _, found := s.getResource(KIND_CBS_AUTO_SNAPSHOT_POLICY, ctx.region, id); !found {
+			return nil, newApiError("InvalidAutoSnapshotPolicyId.NotFound", "auto snapshot policy[%s] not found", id)
+		}
+	}
+	for _, id := range ctx.strs("AutoSnapshotPolicyIds") {
+		s.removeResource(KIND_CBS_AUTO_SNAPSHOT_POLICY, ctx.region, id)
+	}
+	return map[string]interface{}{}, nil
+}
+
+//changeAutoSnapshotPolicyDisks checks the policy and the disks, then sets the disks bound to the policy
+func changeAutoSnapshotPolicyDisks(s *Server, ctx *requestContext, bind bool) (map[string]interface{}, error) {
+	policy, err := mustGet(s, KIND_CBS_AUTO_SNAPSHOT_POLICY, ctx, "AutoSnapshotPolicyId", "InvalidAutoSnapshotPolicyId.NotFound")
+	if err != nil {
+		return nil, err
+	}
+	disks, err := getDisks(s, ctx)
+	if err != nil {
+		return nil, err
+	}
+	diskIds := []interface{}{}
+	for _, id := range policy.data["DiskIdSet"].([]interface{}) {
+		if !matchAny(id, ctx.strs("DiskIds")) {
+			diskIds = append(diskIds, id)
+		}
+	}
+	if bind {
+		for _, disk := range disks {
+			diskIds = append(diskIds, disk.data["DiskId"])
+		}
+	}
+	policy.data["DiskIdSet"] = diskIds
+	return map[string]interface{}{}, nil
+}
+
+func bindAutoSnapshotPolicy(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	return changeAutoSnapshotPolicyDisks(s, ctx, true)
+}
+
+func unbindAutoSnapshotPolicy(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	return changeAutoSnapshotPolicyDisks(s, ctx, false)
+}
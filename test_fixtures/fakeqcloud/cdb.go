@@ -0,0 +1,176 @@
+package fakeqcloud
+
+import (
+	"fmt"
+)
+
+const (
+	KIND_CDB_INSTANCE      = "cdb-instance"
+	KIND_CDB_ASYNC_REQUEST = "cdb-async-request"
+
+	CDB_STATUS_CREATING = 0
+	CDB_STATUS_RUNNING  = 1
+	CDB_STATUS_ISOLATED = 5
+
+	CDB_DEFAULT_PORT = 3306
+)
+
+func (s *Server) registerCdb() {
+	s.register("cdb", "CreateDBInstance", createCdbInstances(0))
+	s.register("cdb", "CreateDBInstanceHour", createCdbInstances(1))
+	s.register("cdb", "DescribeDBInstances", describeCdbInstances)
+	s.register("cdb", "InitDBInstances", initCdbInstances)
+	s.register("cdb", "RestartDBInstances", restartCdbInstances)
+	s.register("cdb", "IsolateDBInstance", isolateCdbInstance)
+	s.register("cdb", "DescribeAsyncRequestInfo", describeCdbAsyncRequestInfo)
+	s.register("cdb", "DescribeDBSecurityGroups", describeCdbSecurityGroups)
+	s.register("cdb", "ModifyDBInstanceSecurityGroups", modifyCdbInstanceSecurityGroups)
+}
+
+//payType is 0 for prepaid and 1 for postpaid by hour
+func createCdbInstances(payType int) handlerFunc {
+	return func(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+		if err := require(ctx, "Memory", "Volume", "GoodsNum"); err != nil {
+			return nil, err
+		}
+		ids := []string{}
+		for i := int64(0); i < ctx.int("GoodsNum", 1); i++ {
+			id := s.newId("cdb")
+			s.putResource(KIND_CDB_INSTANCE, ctx.region, id, map[string]interface{}{
+				"InstanceId":       id,
+				"InstanceName":     ctx.str("InstanceName"),
+				"Memory":           ctx.int("Memory", 0),
+				"Volume":           ctx.int("Volume", 0),
+				"EngineVersion":    ctx.str("EngineVersion"),
+				"UniqVpcId":        ctx.str("UniqVpcId"),
+				"UniqSubnetId":     ctx.str("UniqSubnetId"),
+				"Zone":             ctx.str("Zone"),
+				"Region":           ctx.region,
+				"PayType":          payType,
+				"Vip":              fmt.Sprintf("10.2.%d.%d", s.sequence/250, s.sequence%250+2),
+				"Vport":            CDB_DEFAULT_PORT,
+				"InitFlag":         0,
+				"Status":           CDB_STATUS_CREATING,
+				"CreateTime":       now(),
+				"securityGroupIds": toInterfaces(ctx.strs("SecurityGroup")),
+			})
+			s.schedule(KIND_CDB_INSTANCE, ctx.region, id, "Status", CDB_STATUS_RUNNING)
+			ids = append(ids, id)
+		}
+		return map[string]interface{}{"DealIds": []string{s.newId("deal")}, "InstanceIds": ids}, nil
+	}
+}
+
+func describeCdbInstances(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	ids := ctx.strs("InstanceIds")
+	vips := ctx.strs("Vips")
+	items := s.listResources(KIND_CDB_INSTANCE, ctx.region, func(data map[string]interface{}) bool {
+		if len(ids) > 0 && !matchAny(data["InstanceId"], ids) {
+			return false
+		}
+		return len(vips) == 0 || matchAny(data["Vip"], vips)
+	})
+	return map[string]interface{}{"TotalCount": len(items), "Items": items}, nil
+}
+
+func getCdbInstances(s *Server, ctx *requestContext, ids []string) ([]*resource, error) {
+	if len(ids) == 0 {
+		return nil, newApiError("MissingParameter", "parameter[InstanceIds] is missing")
+	}
+	instances := []*resource{}
+	for _, id := range ids {
+		r, found := s.getResource(KIND_CDB_INSTANCE, ctx.region, id)
+		if !found {
+			return nil, newApiError("InvalidParameter.InstanceNotFound", "instance[%s] not found", id)
+		}
+		instances = append(instances, r)
+	}
+	return instances, nil
+}
+
+func (s *Server) newCdbAsyncRequest(region string) string {
+	id := s.newId("async")
+	s.putResource(KIND_CDB_ASYNC_REQUEST, region, id, map[string]interface{}{"Status": "RUNNING", "Info": ""})
+	s.schedule(KIND_CDB_ASYNC_REQUEST, region, id, "Status", "SUCCESS")
+	return id
+}
+
+//an instance can only be initialized once it is running, qcloud returns an error before that
+func initCdbInstances(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	instances, err := getCdbInstances(s, ctx, ctx.strs("InstanceIds"))
+	if err != nil {
+		return nil, err
+	}
+	if err = require(ctx, "NewPassword"); err != nil {
+		return nil, err
+	}
+	for _, instance := range instances {
+		if fmt.Sprint(instance.data["Status"]) != fmt.Sprint(CDB_STATUS_RUNNING) {
+			return nil, newApiError("OperationDenied.InstanceStatusError", "instance[%s] is not running", instance.data["InstanceId"])
+		}
+	}
+	asyncRequestIds := []string{}
+	for _, instance := range instances {
+		id := instance.data["InstanceId"].(string)
+		if port := ctx.int("Vport", 0); port > 0 {
+			instance.data["Vport"] = port
+		}
+		s.schedule(KIND_CDB_INSTANCE, ctx.region, id, "InitFlag", 1)
+		asyncRequestIds = append(asyncRequestIds, s.newCdbAsyncRequest(ctx.region))
+	}
+	return map[string]interface{}{"AsyncRequestIds": asyncRequestIds}, nil
+}
+
+func restartCdbInstances(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if _, err := getCdbInstances(s, ctx, ctx.strs("InstanceIds")); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"AsyncRequestId": s.newCdbAsyncRequest(ctx.region)}, nil
+}
+
+func isolateCdbInstance(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	instances, err := getCdbInstances(s, ctx, []string{ctx.str("InstanceId")})
+	if err != nil {
+		return nil, err
+	}
+	s.schedule(KIND_CDB_INSTANCE, ctx.region, instances[0].data["InstanceId"].(string), "Status", CDB_STATUS_ISOLATED)
+	return map[string]interface{}{"AsyncRequestId": s.newCdbAsyncRequest(ctx.region)}, nil
+}
+
+func describeCdbAsyncRequestInfo(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "AsyncRequestId"); err != nil {
+		return nil, err
+	}
+	data, found := s.describeResource(KIND_CDB_ASYNC_REQUEST, ctx.region, ctx.str("AsyncRequestId"))
+	if !found {
+		return nil, newApiError("InvalidParameter", "async request[%s] not found", ctx.str("AsyncRequestId"))
+	}
+	return map[string]interface{}{"Status": data["Status"], "Info": data["Info"]}, nil
+}
+
+func describeCdbSecurityGroups(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	instances, err := getCdbInstances(s, ctx, []string{ctx.str("InstanceId")})
+	if err != nil {
+		return nil, err
+	}
+	groups := []interface{}{}
+	ids, _ := instances[0].data["securityGroupIds"].([]interface{})
+	for _, id := range ids {
+		groups = append(groups, map[string]interface{}{"SecurityGroupId": id})
+	}
+	return map[string]interface{}{"Groups": groups}, nil
+}
+
+func modifyCdbInstanceSecurityGroups(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	instances, err := getCdbInstances(s, ctx, []string{ctx.str("InstanceId")})
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ctx.strs("SecurityGroupIds") {
+		if _, found := s.getResource(KIND_SECURITY_GROUP, ctx.region, id); !found {
+			return nil, newApiError("InvalidParameter", "security group[%s] not found", id)
+		}
+	}
+	instances[0].data["securityGroupIds"] = toInterfaces(ctx.strs("SecurityGroupIds"))
+	return map[string]interface{}{}, nil
+}
@@ -0,0 +1,101 @@
+package fakeqcloud
+
+import (
+	"fmt"
+	"strings"
+)
+
+//the plugins only read load balancers, tests seed them with AddResource.
+//a load balancer is in the json shape of clb.LoadBalancer, a listener is in the shape of clb.ListenerBackend
+//with its LoadBalancerId, classical listeners also have ListenerPort and InstancePort
+const (
+	KIND_CLB_LOAD_BALANCER = "clb-load-balancer"
+	KIND_CLB_LISTENER      = "clb-listener"
+)
+
+func (s *Server) registerClb() {
+	s.register("clb", "DescribeLoadBalancers", describeLoadBalancers)
+	s.register("clb", "DescribeListeners", describeListeners)
+	s.register("clb", "DescribeTargets", describeTargets)
+	s.register("clb", "DescribeClassicalLBListeners", describeListeners)
+	s.register("clb", "DescribeClassicalLBTargets", describeClassicalLBTargets)
+}
+
+func describeLoadBalancers(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	ids := ctx.strs("LoadBalancerIds")
+	vips := ctx.strs("LoadBalancerVips")
+	items := s.listResources(KIND_CLB_LOAD_BALANCER, ctx.region, func(data map[string]interface{}) bool {
+		if len(ids) > 0 && !matchAny(data["LoadBalancerId"], ids) {
+			return false
+		}
+		if len(vips) == 0 {
+			return true
+		}
+		lbVips, _ := data["LoadBalancerVips"].([]interface{})
+		for _, vip := range lbVips {
+			if matchAny(vip, vips) {
+				return true
+			}
+		}
+		return false
+	})
+	return map[string]interface{}{"TotalCount": len(items), "LoadBalancerSet": items}, nil
+}
+
+//listeners are matched by protocol and port, Port for application load balancers and ListenerPort for classical ones
+func (s *Server) findListeners(ctx *requestContext) []map[string]interface{} {
+	lbId := ctx.str("LoadBalancerId")
+	protocol := ctx.str("Protocol")
+	port := ctx.int("Port", ctx.int("ListenerPort", 0))
+	listenerIds := ctx.strs("ListenerIds")
+	return s.listResources(KIND_CLB_LISTENER, ctx.region, func(data map[string]interface{}) bool {
+		if data["LoadBalancerId"] != lbId {
+			return false
+		}
+		if len(listenerIds) > 0 && !matchAny(data["ListenerId"], listenerIds) {
+			return false
+		}
+		if protocol != "" && !strings.EqualFold(fmt.Sprint(data["Protocol"]), protocol) {
+			return false
+		}
+		listenerPort := data["Port"]
+		if listenerPort == nil {
+			listenerPort = data["ListenerPort"]
+		}
+		return port == 0 || fmt.Sprint(listenerPort) == fmt.Sprint(port)
+	})
+}
+
+func getLoadBalancer(s *Server, ctx *requestContext) error {
+	if _, err := mustGet(s, KIND_CLB_LOAD_BALANCER, ctx, "LoadBalancerId", "InvalidParameter.LBIdNotFound"); err != nil {
+		return err
+	}
+	return nil
+}
+
+func describeListeners(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := getLoadBalancer(s, ctx); err != nil {
+		return nil, err
+	}
+	listeners := s.findListeners(ctx)
+	return map[string]interface{}{"TotalCount": len(listeners), "Listeners": listeners}, nil
+}
+
+func describeTargets(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := getLoadBalancer(s, ctx); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"Listeners": s.findListeners(ctx)}, nil
+}
+
+func describeClassicalLBTargets(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := getLoadBalancer(s, ctx); err != nil {
+		return nil, err
+	}
+	targets := []interface{}{}
+	for _, listener := range s.findListeners(ctx) {
+		items, _ := listener["Targets"].([]interface{})
+		targets = append(targets, items...)
+	}
+	return map[string]interface{}{"Targets": targets}, nil
+}
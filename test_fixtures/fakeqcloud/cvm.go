@@ -0,0 +1,789 @@
+package fakeqcloud
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	KIND_CVM_INSTANCE = "cvm-instance"
+	KIND_CVM_IMAGE    = "cvm-image"
+
+	KIND_CVM_DISASTER_RECOVER_GROUP = "cvm-disaster-recover-group"
+
+	ZONE_NUM_PER_REGION = 3
+
+	//PUBLIC_IMAGE_ID is the public image every region has
+	PUBLIC_IMAGE_ID = "img-9qabwvbn"
+)
+
+//how many instances a disaster recover group of each type holds
+var disasterRecoverGroupQuota = map[string]int64{"HOST": 50, "SW": 20, "RACK": 20}
+
+//the instance types every zone sells when DescribeZoneInstanceConfigInfos is not filtered by instance type
+var instanceTypeCatalog = []string{"S2.SMALL1", "S2.MEDIUM4", "S2.LARGE8", "S3.MEDIUM4", "S3.LARGE8"}
+
+var instanceTypeRegex = regexp.MustCompile(`\.(SMALL|MEDIUM|LARGE|\d*XLARGE)(\d+)$`)
+
+var instanceTypeCpu = map[string]int64{"SMALL": 1, "MEDIUM": 2, "LARGE": 4, "XLARGE": 4, "2XLARGE": 8, "4XLARGE": 16, "8XLARGE": 32}
+
+//cpu and memory of instance types like S2.MEDIUM4
+func parseInstanceType(instanceType string) (int64, int64) {
+	match := instanceTypeRegex.FindStringSubmatch(instanceType)
+	if match == nil {
+		return 1, 1
+	}
+	memory, _ := strconv.ParseInt(match[2], 10, 64)
+	cpu, found := instanceTypeCpu[match[1]]
+	if !found {
+		cpu = 1
+	}
+	return cpu, memory
+}
+
+func (s *Server) registerCvm() {
+	s.register("cvm", "RunInstances", runInstances)
+	s.register("cvm", "DescribeInstances", describeInstances)
+	s.register("cvm", "StartInstances", changeInstancesState("STARTING", "RUNNING"))
+	s.register("cvm", "StopInstances", changeInstancesState("STOPPING", "STOPPED"))
+	s.register("cvm", "RebootInstances", rebootInstances)
+	s.register("cvm", "ModifyInstancesAttribute", modifyInstancesAttribute)
+	s.register("cvm", "TerminateInstances", terminateInstances)
+	s.register("cvm", "DescribeZones", describeZones)
+	s.register("cvm", "ResetInstancesType", resetInstancesType)
+	s.register("cvm", "ResetInstance", resetInstance)
+	s.register("cvm", "ResetInstancesPassword", resetInstancesPassword)
+	s.register("cvm", "RenewInstances", renewInstances)
+	s.register("cvm", "ModifyInstancesChargeType", modifyInstancesChargeType)
+	s.register("cvm", "ModifyInstancesRenewFlag", modifyInstancesRenewFlag)
+	s.register("cvm", "CreateImage", createImage)
+	s.register("cvm", "DescribeImages", describeImages)
+	s.register("cvm", "SyncImages", syncImages)
+	s.register("cvm", "ModifyImageSharePermission", modifyImageSharePermission)
+	s.register("cvm", "DescribeImageSharePermission", describeImageSharePermission)
+	s.register("cvm", "DeleteImages", deleteImages)
+	s.register("cvm", "CreateDisasterRecoverGroup", createDisasterRecoverGroup)
+	s.register("cvm", "DescribeDisasterRecoverGroups", describeDisasterRecoverGroups)
+	s.register("cvm", "DeleteDisasterRecoverGroups", deleteDisasterRecoverGroups)
+	s.register("cvm", "DescribeZoneInstanceConfigInfos", describeZoneInstanceConfigInfos)
+}
+
+func runInstances(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "Placement", "ImageId", "InstanceType"); err != nil {
+		return nil, err
+	}
+
+	//qcloud returns the instances of the first request for the same client token
+	if clientToken := ctx.str("ClientToken"); clientToken != "" {
+		ids := []string{}
+		for _, item := range s.findResources(KIND_CVM_INSTANCE, ctx.region, func(data map[string]interface{}) bool {
+			return data["clientToken"] == clientToken
+		}) {
+			ids = append(ids, item["InstanceId"].(string))
+		}
+		if len(ids) > 0 {
+			return map[string]interface{}{"InstanceIdSet": ids}, nil
+		}
+	}
+
+	//a subnet belongs to one zone, qcloud refuses to run instances of another zone in it
+	zone := ctx.object("Placement")["Zone"]
+	subnetId, _ := ctx.object("VirtualPrivateCloud")["SubnetId"].(string)
+	if subnet, found := s.getResource(KIND_SUBNET, ctx.region, subnetId); found && subnet.data["Zone"] != zone {
+		return nil, newApiError("InvalidParameterValue.SubnetNotMatchZone", "subnet[%s] is in zone[%v], not zone[%v]", subnetId, subnet.data["Zone"], zone)
+	}
+	if s.soldOut[fmt.Sprintf("%v/%s", zone, ctx.str("InstanceType"))] {
+		return nil, newApiError("ResourceInsufficient.SpecifiedInstanceType", "instance type[%s] is sold out in zone[%v]", ctx.str("InstanceType"), zone)
+	}
+	var group *resource
+	if groupIds := ctx.strs("DisasterRecoverGroupIds"); len(groupIds) > 0 {
+		var err error
+		if group, err = getDisasterRecoverGroup(s, ctx, groupIds[0]); err != nil {
+			return nil, err
+		}
+		if count := len(group.data["InstanceIds"].([]interface{})); int64(count)+ctx.int("InstanceCount", 1) > group.data["CvmQuotaTotal"].(int64) {
+			return nil, newApiError("ResourceInsufficient.DisasterRecoverGroupCvmQuota", "disaster recover group[%s] has %d instances in quota %v", groupIds[0], count, group.data["CvmQuotaTotal"])
+		}
+	}
+
+	cpu, memory := parseInstanceType(ctx.str("InstanceType"))
+	tags := []interface{}{}
+	for _, specification := range ctx.objects("TagSpecification") {
+		if items, ok := specification["Tags"].([]interface{}); ok {
+			tags = append(tags, items...)
+		}
+	}
+	internetAccessible := ctx.object("InternetAccessible")
+	keyIds, _ := ctx.object("LoginSettings")["KeyIds"].([]interface{})
+	if keyIds == nil {
+		keyIds = []interface{}{}
+	}
+	ids := []string{}
+	for i := int64(0); i < ctx.int("InstanceCount", 1); i++ {
+		id := s.newId("ins")
+		vpc := ctx.object("VirtualPrivateCloud")
+		privateIps, _ := vpc["PrivateIpAddresses"].([]interface{})
+		if len(privateIps) == 0 {
+			privateIps = []interface{}{fmt.Sprintf("10.0.%d.%d", s.sequence/250, s.sequence%250+2)}
+		}
+		publicIps := []interface{}{}
+		if assigned, _ := internetAccessible["PublicIpAssigned"].(bool); assigned {
+			publicIps = append(publicIps, fmt.Sprintf("203.0.%d.%d", s.sequence/250, s.sequence%250+2))
+		}
+		data := map[string]interface{}{
+			"InstanceId":          id,
+			"InstanceName":        ctx.str("InstanceName"),
+			"InstanceType":        ctx.str("InstanceType"),
+			"InstanceChargeType":  ctx.str("InstanceChargeType"),
+			"ImageId":             ctx.str("ImageId"),
+			"CPU":                 cpu,
+			"Memory":              memory,
+			"Placement":           ctx.object("Placement"),
+			"SystemDisk":          ctx.object("SystemDisk"),
+			"DataDisks":           ctx.objects("DataDisks"),
+			"VirtualPrivateCloud": vpc,
+			"PrivateIpAddresses":  privateIps,
+			"SecurityGroupIds":    toInterfaces(ctx.strs("SecurityGroupIds")),
+			"PublicIpAddresses":   publicIps,
+			"InternetAccessible":  internetAccessible,
+			"LoginSettings":       map[string]interface{}{"KeyIds": keyIds},
+			"Tags":                tags,
+			"hostName":            ctx.str("HostName"),
+			"userData":            ctx.str("UserData"),
+			"password":            ctx.object("LoginSettings")["Password"],
+			"InstanceState":       "PENDING",
+			"CreatedTime":         time.Now().UTC().Format(time.RFC3339),
+			"clientToken":         ctx.str("ClientToken"),
+		}
+		if ctx.str("InstanceChargeType") == "PREPAID" {
+			chargePrepaid(data, time.Now(), ctx.object("InstanceChargePrepaid"))
+		}
+		s.putResource(KIND_CVM_INSTANCE, ctx.region, id, data)
+		s.schedule(KIND_CVM_INSTANCE, ctx.region, id, "InstanceState", "RUNNING")
+		ids = append(ids, id)
+	}
+	if group != nil {
+		group.data["InstanceIds"] = append(group.data["InstanceIds"].([]interface{}), toInterfaces(ids)...)
+		group.data["CurrentNum"] = int64(len(group.data["InstanceIds"].([]interface{})))
+	}
+	return map[string]interface{}{"InstanceIdSet": ids}, nil
+}
+
+var instanceFilterFields = map[string]func(data map[string]interface{}) interface{}{
+	"instance-id":   func(data map[string]interface{}) interface{} { return data["InstanceId"] },
+	"instance-name": func(data map[string]interface{}) interface{} { return data["InstanceName"] },
+	"instance-type": func(data map[string]interface{}) interface{} { return data["InstanceType"] },
+	"instance-charge-type": func(data map[string]interface{}) interface{} {
+		return data["InstanceChargeType"]
+	},
+	"zone": func(data map[string]interface{}) interface{} {
+		return data["Placement"].(map[string]interface{})["Zone"]
+	},
+	"vpc-id": func(data map[string]interface{}) interface{} {
+		return data["VirtualPrivateCloud"].(map[string]interface{})["VpcId"]
+	},
+	"subnet-id": func(data map[string]interface{}) interface{} {
+		return data["VirtualPrivateCloud"].(map[string]interface{})["SubnetId"]
+	},
+	"private-ip-address": func(data map[string]interface{}) interface{} {
+		ips := data["PrivateIpAddresses"].([]interface{})
+		if len(ips) == 0 {
+			return ""
+		}
+		return ips[0]
+	},
+}
+
+func instanceTagValue(data map[string]interface{}, key string) interface{} {
+	tags, _ := data["Tags"].([]interface{})
+	for _, tag := range tags {
+		if item, ok := tag.(map[string]interface{}); ok && item["Key"] == key {
+			return item["Value"]
+		}
+	}
+	return nil
+}
+
+func describeInstances(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	ids := ctx.strs("InstanceIds")
+	filters := ctx.filters()
+	items := s.listResources(KIND_CVM_INSTANCE, ctx.region, func(data map[string]interface{}) bool {
+		if len(ids) > 0 && !matchAny(data["InstanceId"], ids) {
+			return false
+		}
+		for name, values := range filters {
+			if strings.HasPrefix(name, "tag:") {
+				if !matchAny(instanceTagValue(data, strings.TrimPrefix(name, "tag:")), values) {
+					return false
+				}
+				continue
+			}
+			field, found := instanceFilterFields[name]
+			if !found || !matchAny(field(data), values) {
+				return false
+			}
+		}
+		return true
+	})
+	return map[string]interface{}{"TotalCount": len(items), "InstanceSet": page(ctx, items)}, nil
+}
+
+func getInstances(s *Server, ctx *requestContext) ([]*resource, error) {
+	if err := require(ctx, "InstanceIds"); err != nil {
+		return nil, err
+	}
+	instances := []*resource{}
+	for _, id := range ctx.strs("InstanceIds") {
+		r, found := s.getResource(KIND_CVM_INSTANCE, ctx.region, id)
+		if !found {
+			return nil, newApiError("InvalidInstanceId.NotFound", "instance[%s] not found", id)
+		}
+		instances = append(instances, r)
+	}
+	return instances, nil
+}
+
+func changeInstancesState(pendingState string, desireState string) handlerFunc {
+	return func(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+		instances, err := getInstances(s, ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, instance := range instances {
+			id := instance.data["InstanceId"].(string)
+			instance.data["InstanceState"] = pendingState
+			s.schedule(KIND_CVM_INSTANCE, ctx.region, id, "InstanceState", desireState)
+		}
+		return map[string]interface{}{}, nil
+	}
+}
+
+//like qcloud the instance is still RUNNING when the reboot is submitted, it is REBOOTING after AsyncSteps
+//describe calls and RUNNING again after another AsyncSteps calls, then the reboot request succeeds
+func rebootInstances(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	instances, err := getInstances(s, ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, instance := range instances {
+		id := instance.data["InstanceId"].(string)
+		s.schedule(KIND_CVM_INSTANCE, ctx.region, id, "InstanceState", "REBOOTING")
+		s.schedule(KIND_CVM_INSTANCE, ctx.region, id, "InstanceState", "RUNNING")
+		startInstanceOperation(s, ctx, instance, "RebootInstances")
+	}
+	return map[string]interface{}{}, nil
+}
+
+func modifyInstancesAttribute(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	instances, err := getInstances(s, ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, instance := range instances {
+		if name := ctx.str("InstanceName"); name != "" {
+			instance.data["InstanceName"] = name
+		}
+		if _, found := ctx.params["SecurityGroups"]; found {
+			instance.data["SecurityGroupIds"] = toInterfaces(ctx.strs("SecurityGroups"))
+		}
+	}
+	return map[string]interface{}{}, nil
+}
+
+func terminateInstances(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	instances, err := getInstances(s, ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, instance := range instances {
+		id := instance.data["InstanceId"].(string)
+		instance.data["InstanceState"] = "TERMINATING"
+		s.scheduleRemove(KIND_CVM_INSTANCE, ctx.region, id)
+		leaveDisasterRecoverGroups(s, ctx.region, id)
+		releaseInstanceDisks(s, ctx.region, id)
+	}
+	return map[string]interface{}{}, nil
+}
+
+//requireInstancesStopped fails like qcloud when an instance is not stopped
+func requireInstancesStopped(instances []*resource) error {
+	for _, instance := range instances {
+		if instance.data["InstanceState"] != "STOPPED" {
+			return newApiError("UnsupportedOperation.InstanceStateRunning", "instance[%v] is in state[%v], should be stopped",
+				instance.data["InstanceId"], instance.data["InstanceState"])
+		}
+	}
+	return nil
+}
+
+func resetInstancesType(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "InstanceType"); err != nil {
+		return nil, err
+	}
+	instances, err := getInstances(s, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = requireInstancesStopped(instances); err != nil {
+		return nil, err
+	}
+
+	cpu, memory := parseInstanceType(ctx.str("InstanceType"))
+	for _, instance := range instances {
+		instance.data["InstanceType"] = ctx.str("InstanceType")
+		instance.data["CPU"] = cpu
+		instance.data["Memory"] = memory
+	}
+	return map[string]interface{}{}, nil
+}
+
+//reinstalling reboots the instance into the new image
+func resetInstance(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "InstanceId", "ImageId"); err != nil {
+		return nil, err
+	}
+	id := ctx.str("InstanceId")
+	instance, found := s.getResource(KIND_CVM_INSTANCE, ctx.region, id)
+	if !found {
+		return nil, newApiError("InvalidInstanceId.NotFound", "instance[%s] not found", id)
+	}
+
+	keyIds, _ := ctx.object("LoginSettings")["KeyIds"].([]interface{})
+	if keyIds == nil {
+		keyIds = []interface{}{}
+	}
+	instance.data["ImageId"] = ctx.str("ImageId")
+	instance.data["LoginSettings"] = map[string]interface{}{"KeyIds": keyIds}
+	instance.data["password"] = ctx.object("LoginSettings")["Password"]
+	instance.data["InstanceState"] = "STARTING"
+	s.schedule(KIND_CVM_INSTANCE, ctx.region, id, "InstanceState", "RUNNING")
+	startInstanceOperation(s, ctx, instance, "ResetInstance")
+	return map[string]interface{}{}, nil
+}
+
+//startInstanceOperation makes the request the latest operation of the instance, it succeeds after AsyncSteps describe calls
+func startInstanceOperation(s *Server, ctx *requestContext, instance *resource, operation string) {
+	instance.data["LatestOperation"] = operation
+	instance.data["LatestOperationState"] = "OPERATING"
+	instance.data["LatestOperationRequestId"] = ctx.requestId
+	s.schedule(KIND_CVM_INSTANCE, ctx.region, instance.data["InstanceId"].(string), "LatestOperationState", "SUCCESS")
+}
+
+func resetInstancesPassword(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "Password"); err != nil {
+		return nil, err
+	}
+	instances, err := getInstances(s, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if forceStop, _ := ctx.params["ForceStop"].(bool); !forceStop {
+		if err = requireInstancesStopped(instances); err != nil {
+			return nil, err
+		}
+	}
+	//the password is set asynchronously, the latest operation tells when it is done
+	for _, instance := range instances {
+		instance.data["password"] = ctx.str("Password")
+		startInstanceOperation(s, ctx, instance, "ResetInstancesPassword")
+	}
+	return map[string]interface{}{}, nil
+}
+
+//chargePrepaid extends the expired time of the instance by the months of InstanceChargePrepaid
+func chargePrepaid(data map[string]interface{}, from time.Time, prepaid map[string]interface{}) {
+	period := (&requestContext{params: prepaid}).int("Period", 1)
+	data["ExpiredTime"] = from.AddDate(0, int(period), 0).UTC().Format(time.RFC3339)
+	if renewFlag, _ := prepaid["RenewFlag"].(string); renewFlag != "" {
+		data["RenewFlag"] = renewFlag
+	} else if data["RenewFlag"] == nil {
+		data["RenewFlag"] = "NOTIFY_AND_MANUAL_RENEW"
+	}
+}
+
+func requireInstancesPrepaid(instances []*resource) error {
+	for _, instance := range instances {
+		if instance.data["InstanceChargeType"] != "PREPAID" {
+			return newApiError("InvalidInstance.NotSupported", "instance[%v] is charged by %v, should be PREPAID",
+				instance.data["InstanceId"], instance.data["InstanceChargeType"])
+		}
+	}
+	return nil
+}
+
+func renewInstances(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "InstanceChargePrepaid"); err != nil {
+		return nil, err
+	}
+	instances, err := getInstances(s, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = requireInstancesPrepaid(instances); err != nil {
+		return nil, err
+	}
+	for _, instance := range instances {
+		expiredTime, _ := time.Parse(time.RFC3339, instance.data["ExpiredTime"].(string))
+		chargePrepaid(instance.data, expiredTime, ctx.object("InstanceChargePrepaid"))
+	}
+	return map[string]interface{}{}, nil
+}
+
+//only postpaid instances can be converted to prepaid
+func modifyInstancesChargeType(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "InstanceChargeType", "InstanceChargePrepaid"); err != nil {
+		return nil, err
+	}
+	if ctx.str("InstanceChargeType") != "PREPAID" {
+		return nil, newApiError("InvalidParameterValue", "InstanceChargeType(%s) is not supported", ctx.str("InstanceChargeType"))
+	}
+	instances, err := getInstances(s, ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, instance := range instances {
+		if instance.data["InstanceChargeType"] == "PREPAID" {
+			return nil, newApiError("InvalidInstance.NotSupported", "instance[%v] is already PREPAID", instance.data["InstanceId"])
+		}
+	}
+	for _, instance := range instances {
+		chargePrepaid(instance.data, time.Now(), ctx.object("InstanceChargePrepaid"))
+		s.schedule(KIND_CVM_INSTANCE, ctx.region, instance.data["InstanceId"].(string), "InstanceChargeType", "PREPAID")
+	}
+	return map[string]interface{}{}, nil
+}
+
+func modifyInstancesRenewFlag(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "RenewFlag"); err != nil {
+		return nil, err
+	}
+	instances, err := getInstances(s, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = requireInstancesPrepaid(instances); err != nil {
+		return nil, err
+	}
+	for _, instance := range instances {
+		instance.data["RenewFlag"] = ctx.str("RenewFlag")
+	}
+	return map[string]interface{}{}, nil
+}
+
+func privateImageNamed(s *Server, region string, name string) bool {
+	return len(s.findResources(KIND_CVM_IMAGE, region, func(data map[string]interface{}) bool {
+		return data["ImageType"] == "PRIVATE_IMAGE" && data["ImageName"] == name
+	})) > 0
+}
+
+func putImage(s *Server, region string, data map[string]interface{}, pendingState string) {
+	id := s.newId("img")
+	data["ImageId"] = id
+	data["ImageType"] = "PRIVATE_IMAGE"
+	data["ImageState"] = pendingState
+	data["CreatedTime"] = time.Now().UTC().Format(time.RFC3339)
+	data["sharedAccounts"] = []interface{}{}
+	s.putResource(KIND_CVM_IMAGE, region, id, data)
+	s.schedule(KIND_CVM_IMAGE, region, id, "ImageState", "NORMAL")
+}
+
+//the private image names are unique in a region, like qcloud
+func createImage(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "ImageName", "InstanceId"); err != nil {
+		return nil, err
+	}
+	instance, found := s.getResource(KIND_CVM_INSTANCE, ctx.region, ctx.str("InstanceId"))
+	if !found {
+		return nil, newApiError("InvalidInstanceId.NotFound", "instance[%s] not found", ctx.str("InstanceId"))
+	}
+	if instance.data["InstanceState"] != "STOPPED" && ctx.str("ForcePoweroff") != "TRUE" {
+		return nil, newApiError("UnsupportedOperation.InstanceStateRunning", "instance[%s] should be stopped or force powered off", ctx.str("InstanceId"))
+	}
+	if privateImageNamed(s, ctx.region, ctx.str("ImageName")) {
+		return nil, newApiError("InvalidImageName.Duplicate", "image named %s already exists", ctx.str("ImageName"))
+	}
+
+	putImage(s, ctx.region, map[string]interface{}{
+		"ImageName":        ctx.str("ImageName"),
+		"ImageDescription": ctx.str("ImageDescription"),
+		"ImageSize":        instance.data["SystemDisk"].(map[string]interface{})["DiskSize"],
+		"OsName":           "CentOS 7.6 64bit",
+		"Platform":         "CentOS",
+		"Architecture":     "x86_64",
+		"ImageSource":      "CREATE_IMAGE",
+		"instanceId":       ctx.str("InstanceId"),
+	}, "CREATING")
+	return map[string]interface{}{}, nil
+}
+
+//publicImage is listed after the images of the account in every region
+func publicImage() map[string]interface{} {
+	return map[string]interface{}{
+		"ImageId":      PUBLIC_IMAGE_ID,
+		"ImageName":    "CentOS 7.6 64bit",
+		"ImageType":    "PUBLIC_IMAGE",
+		"ImageState":   "NORMAL",
+		"ImageSize":    50,
+		"OsName":       "CentOS 7.6 64bit",
+		"Platform":     "CentOS",
+		"Architecture": "x86_64",
+		"ImageSource":  "OFFICIAL",
+	}
+}
+
+func describeImages(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	match := matchFilters(ctx, "ImageIds", "ImageId", map[string]string{
+		"image-id":   "ImageId",
+		"image-name": "ImageName",
+		"image-type": "ImageType",
+	})
+	items := s.listResources(KIND_CVM_IMAGE, ctx.region, match)
+	if image := publicImage(); match(image) {
+		items = append(items, image)
+	}
+	return map[string]interface{}{"TotalCount": len(items), "ImageSet": page(ctx, items)}, nil
+}
+
+func getImage(s *Server, ctx *requestContext, id string) (*resource, error) {
+	r, found := s.getResource(KIND_CVM_IMAGE, ctx.region, id)
+	if !found {
+		return nil, newApiError("InvalidImageId.NotFound", "image[%s] not found", id)
+	}
+	return r, nil
+}
+
+//the copies keep the name of the image, they are made in the destination regions with new ids
+func syncImages(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "ImageIds", "DestinationRegions"); err != nil {
+		return nil, err
+	}
+	for _, id := range ctx.strs("ImageIds") {
+		image, err := getImage(s, ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if image.data["ImageState"] != "NORMAL" {
+			return nil, newApiError("InvalidImageState", "image[%s] is in state[%v]", id, image.data["ImageState"])
+		}
+		for _, region := range ctx.strs("DestinationRegions") {
+			if privateImageNamed(s, region, image.data["ImageName"].(string)) {
+				return nil, newApiError("InvalidImageName.Duplicate", "image named %v already exists in region[%s]", image.data["ImageName"], region)
+			}
+			copied := map[string]interface{}{}
+			for key, value := range image.data {
+				copied[key] = value
+			}
+			copied["ImageSource"] = "SYNC_IMAGE"
+			putImage(s, region, copied, "SYNCING")
+		}
+	}
+	return map[string]interface{}{}, nil
+}
+
+func modifyImageSharePermission(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "ImageId", "AccountIds", "Permission"); err != nil {
+		return nil, err
+	}
+	image, err := getImage(s, ctx, ctx.str("ImageId"))
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := []interface{}{}
+	for _, account := range image.data["sharedAccounts"].([]interface{}) {
+		if !matchAny(account, ctx.strs("AccountIds")) {
+			accounts = append(accounts, account)
+		}
+	}
+	switch ctx.str("Permission") {
+	case "SHARE":
+		accounts = append(accounts, toInterfaces(ctx.strs("AccountIds"))...)
+	case "CANCEL":
+	default:
+		return nil, newApiError("InvalidParameterValue", "Permission(%s) is not supported", ctx.str("Permission"))
+	}
+	image.data["sharedAccounts"] = accounts
+	return map[string]interface{}{}, nil
+}
+
+func describeImageSharePermission(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "ImageId"); err != nil {
+		return nil, err
+	}
+	image, err := getImage(s, ctx, ctx.str("ImageId"))
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := []interface{}{}
+	for _, account := range image.data["sharedAccounts"].([]interface{}) {
+		permissions = append(permissions, map[string]interface{}{"AccountId": account, "CreatedTime": now()})
+	}
+	return map[string]interface{}{"SharePermissionSet": permissions}, nil
+}
+
+func deleteImages(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "ImageIds"); err != nil {
+		return nil, err
+	}
+	for _, id := range ctx.strs("ImageIds") {
+		if _, err := getImage(s, ctx, id); err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range ctx.strs("ImageIds") {
+		s.removeResource(KIND_CVM_IMAGE, ctx.region, id)
+	}
+	return map[string]interface{}{}, nil
+}
+
+//every region has the zones region-1 to region-3
+func describeZones(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	zones := []map[string]interface{}{}
+	for i := 1; i <= ZONE_NUM_PER_REGION; i++ {
+		zones = append(zones, map[string]interface{}{
+			"Zone":      fmt.Sprintf("%s-%d", ctx.region, i),
+			"ZoneName":  fmt.Sprintf("%s zone %d", ctx.region, i),
+			"ZoneId":    fmt.Sprintf("%d", 100000+i),
+			"ZoneState": "AVAILABLE",
+		})
+	}
+	return map[string]interface{}{"TotalCount": len(zones), "ZoneSet": zones}, nil
+}
+
+func getDisasterRecoverGroup(s *Server, ctx *requestContext, id string) (*resource, error) {
+	r, found := s.getResource(KIND_CVM_DISASTER_RECOVER_GROUP, ctx.region, id)
+	if !found {
+		return nil, newApiError("ResourceNotFound.InvalidPlacementSet", "disaster recover group[%s] not found", id)
+	}
+	return r, nil
+}
+
+//qcloud returns the group of the first request for the same client token
+func createDisasterRecoverGroup(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "Name", "Type"); err != nil {
+		return nil, err
+	}
+	if clientToken := ctx.str("ClientToken"); clientToken != "" {
+		if items := s.findResources(KIND_CVM_DISASTER_RECOVER_GROUP, ctx.region, func(data map[string]interface{}) bool {
+			return data["clientToken"] == clientToken
+		}); len(items) > 0 {
+			return items[0], nil
+		}
+	}
+	quota, found := disasterRecoverGroupQuota[ctx.str("Type")]
+	if !found {
+		return nil, newApiError("InvalidParameterValue", "Type(%s) is not supported", ctx.str("Type"))
+	}
+
+	id := s.newId("ps")
+	group := s.putResource(KIND_CVM_DISASTER_RECOVER_GROUP, ctx.region, id, map[string]interface{}{
+		"DisasterRecoverGroupId": id,
+		"Name":                   ctx.str("Name"),
+		"Type":                   ctx.str("Type"),
+		"CvmQuotaTotal":          quota,
+		"CurrentNum":             int64(0),
+		"InstanceIds":            []interface{}{},
+		"CreateTime":             time.Now().UTC().Format(time.RFC3339),
+		"clientToken":            ctx.str("ClientToken"),
+	})
+	return group.data, nil
+}
+
+func describeDisasterRecoverGroups(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	match := matchFilters(ctx, "DisasterRecoverGroupIds", "DisasterRecoverGroupId", nil)
+	items := s.listResources(KIND_CVM_DISASTER_RECOVER_GROUP, ctx.region, func(data map[string]interface{}) bool {
+		return match(data) && (ctx.str("Name") == "" || data["Name"] == ctx.str("Name"))
+	})
+	return map[string]interface{}{"TotalCount": len(items), "DisasterRecoverGroupSet": page(ctx, items)}, nil
+}
+
+func deleteDisasterRecoverGroups(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "DisasterRecoverGroupIds"); err != nil {
+		return nil, err
+	}
+	for _, id := range ctx.strs("DisasterRecoverGroupIds") {
+		group, err := getDisasterRecoverGroup(s, ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if len(group.data["InstanceIds"].([]interface{})) > 0 {
+			return nil, newApiError("ResourceInUse.DisasterRecoverGroup", "disaster recover group[%s] still has instances", id)
+		}
+	}
+	for _, id := range ctx.strs("DisasterRecoverGroupIds") {
+		s.removeResource(KIND_CVM_DISASTER_RECOVER_GROUP, ctx.region, id)
+	}
+	return map[string]interface{}{}, nil
+}
+
+//terminated instances leave their disaster recover groups
+func leaveDisasterRecoverGroups(s *Server, region string, instanceId string) {
+	for _, group := range s.findResources(KIND_CVM_DISASTER_RECOVER_GROUP, region, nil) {
+		instanceIds := []interface{}{}
+		for _, id := range group["InstanceIds"].([]interface{}) {
+			if id != instanceId {
+				instanceIds = append(instanceIds, id)
+			}
+		}
+		group["InstanceIds"] = instanceIds
+		group["CurrentNum"] = int64(len(instanceIds))
+	}
+}
+
+//every zone sells the instance types by hour and by month unless they are sold out,
+//the hourly price is 0.1 per cpu plus 0.05 per GB memory and a month is 500 hours at 80 percent off
+func describeZoneInstanceConfigInfos(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	filters := ctx.filters()
+	instanceTypes := instanceTypeCatalog
+	if values, found := filters["instance-type"]; found {
+		instanceTypes = []string{}
+		for _, instanceType := range values {
+			if instanceTypeRegex.MatchString(instanceType) {
+				instanceTypes = append(instanceTypes, instanceType)
+			}
+		}
+	}
+
+	items := []map[string]interface{}{}
+	for i := 1; i <= ZONE_NUM_PER_REGION; i++ {
+		zone := fmt.Sprintf("%s-%d", ctx.region, i)
+		for _, instanceType := range instanceTypes {
+			cpu, memory := parseInstanceType(instanceType)
+			hourPrice := 0.1*float64(cpu) + 0.05*float64(memory)
+			status := "SELL"
+			if s.soldOut[zone+"/"+instanceType] {
+				status = "SOLD_OUT"
+			}
+			for _, chargeType := range []string{"POSTPAID_BY_HOUR", "PREPAID"} {
+				price := map[string]interface{}{"ChargeUnit": "HOUR", "UnitPrice": hourPrice, "DiscountPrice": hourPrice}
+				if chargeType == "PREPAID" {
+					price = map[string]interface{}{"OriginalPrice": hourPrice * 500, "DiscountPrice": hourPrice * 400}
+				}
+				item := map[string]interface{}{
+					"Zone":               zone,
+					"InstanceType":       instanceType,
+					"InstanceChargeType": chargeType,
+					"InstanceFamily":     strings.SplitN(instanceType, ".", 2)[0],
+					"TypeName":           instanceType,
+					"Cpu":                cpu,
+					"Memory":             memory,
+					"Status":             status,
+					"Price":              price,
+				}
+				if matchAllFilters(item, filters, map[string]string{
+					"zone":                 "Zone",
+					"instance-type":        "InstanceType",
+					"instance-charge-type": "InstanceChargeType",
+					"instance-family":      "InstanceFamily",
+				}) {
+					items = append(items, item)
+				}
+			}
+		}
+	}
+	return map[string]interface{}{"InstanceTypeQuotaSet": items}, nil
+}
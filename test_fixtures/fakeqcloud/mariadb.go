@@ -0,0 +1,174 @@
+package fakeqcloud
+
+import (
+	"fmt"
+)
+
+const (
+	KIND_MARIADB_INSTANCE = "mariadb-instance"
+	KIND_MARIADB_DEAL     = "mariadb-deal"
+	KIND_MARIADB_FLOW     = "mariadb-flow"
+
+	MARIADB_STATUS_CREATING  = 0
+	MARIADB_STATUS_RUNNING   = 2
+	MARIADB_STATUS_WAIT_INIT = 3
+
+	MARIADB_FLOW_SUCCESS = 0
+	MARIADB_FLOW_RUNNING = 2
+
+	MARIADB_DEFAULT_PORT = 3306
+)
+
+func (s *Server) registerMariadb() {
+	s.register("mariadb", "CreateDBInstance", createMariadbInstance)
+	s.register("mariadb", "DescribeOrders", describeMariadbOrders)
+	s.register("mariadb", "DescribeDBInstances", describeMariadbInstances)
+	s.register("mariadb", "InitDBInstances", initMariadbInstances)
+	s.register("mariadb", "DescribeFlow", describeMariadbFlow)
+	s.register("mariadb", "CreateAccount", createMariadbAccount)
+	s.register("mariadb", "GrantAccountPrivileges", grantMariadbAccountPrivileges)
+}
+
+//the instance ids of the deal are filled in once the deal is delivered,
+//a new instance waits to be initialized before it is running
+func createMariadbInstance(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "Zones", "NodeCount", "Memory", "Storage"); err != nil {
+		return nil, err
+	}
+	zones := ctx.strs("Zones")
+	ids := []interface{}{}
+	for i := int64(0); i < ctx.int("Count", 1); i++ {
+		id := s.newId("tdsql")
+		s.putResource(KIND_MARIADB_INSTANCE, ctx.region, id, map[string]interface{}{
+			"InstanceId":     id,
+			"InstanceName":   id,
+			"Zone":           zones[0],
+			"NodeCount":      ctx.int("NodeCount", 2),
+			"Memory":         ctx.int("Memory", 0),
+			"Storage":        ctx.int("Storage", 0),
+			"UniqueVpcId":    ctx.str("VpcId"),
+			"UniqueSubnetId": ctx.str("SubnetId"),
+			"DbVersion":      ctx.str("DbVersionId"),
+			"Vip":            fmt.Sprintf("10.4.%d.%d", s.sequence/250, s.sequence%250+2),
+			"Vport":          MARIADB_DEFAULT_PORT,
+			"Status":         MARIADB_STATUS_CREATING,
+			"StatusDesc":     "creating",
+			"CreateTime":     now(),
+			"accounts":       map[string]interface{}{},
+		})
+		s.schedule(KIND_MARIADB_INSTANCE, ctx.region, id, "Status", MARIADB_STATUS_WAIT_INIT)
+		ids = append(ids, id)
+	}
+
+	dealName := s.newId("deal")
+	s.putResource(KIND_MARIADB_DEAL, ctx.region, dealName, map[string]interface{}{
+		"DealName":    dealName,
+		"Count":       len(ids),
+		"InstanceIds": []interface{}{},
+		"CreateTime":  now(),
+	})
+	s.schedule(KIND_MARIADB_DEAL, ctx.region, dealName, "InstanceIds", ids)
+	return map[string]interface{}{"DealName": dealName}, nil
+}
+
+func describeMariadbOrders(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "DealNames"); err != nil {
+		return nil, err
+	}
+	deals := []interface{}{}
+	for _, dealName := range ctx.strs("DealNames") {
+		if deal, found := s.describeResource(KIND_MARIADB_DEAL, ctx.region, dealName); found {
+			deals = append(deals, deal)
+		}
+	}
+	return map[string]interface{}{"TotalCount": len(deals), "Deals": deals}, nil
+}
+
+func describeMariadbInstances(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	items := s.listResources(KIND_MARIADB_INSTANCE, ctx.region, matchFilters(ctx, "InstanceIds", "InstanceId", nil))
+	return map[string]interface{}{"TotalCount": len(items), "Instances": items}, nil
+}
+
+func getMariadbInstance(s *Server, ctx *requestContext, id string) (*resource, error) {
+	r, found := s.getResource(KIND_MARIADB_INSTANCE, ctx.region, id)
+	if !found {
+		return nil, newApiError("ResourceNotFound.NoInstanceFound", "instance[%s] not found", id)
+	}
+	return r, nil
+}
+
+func initMariadbInstances(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "InstanceIds", "Params"); err != nil {
+		return nil, err
+	}
+	instances := []*resource{}
+	for _, id := range ctx.strs("InstanceIds") {
+		instance, err := getMariadbInstance(s, ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if fmt.Sprint(instance.data["Status"]) != fmt.Sprint(MARIADB_STATUS_WAIT_INIT) {
+			return nil, newApiError("UnsupportedOperation.OssOperationFailed", "instance[%s] is not waiting for init", id)
+		}
+		instances = append(instances, instance)
+	}
+	for _, instance := range instances {
+		s.schedule(KIND_MARIADB_INSTANCE, ctx.region, instance.data["InstanceId"].(string), "Status", MARIADB_STATUS_RUNNING)
+	}
+
+	s.sequence++
+	flowId := s.sequence
+	flowKey := fmt.Sprint(flowId)
+	s.putResource(KIND_MARIADB_FLOW, ctx.region, flowKey, map[string]interface{}{"Status": MARIADB_FLOW_RUNNING})
+	s.schedule(KIND_MARIADB_FLOW, ctx.region, flowKey, "Status", MARIADB_FLOW_SUCCESS)
+	return map[string]interface{}{"FlowId": flowId, "InstanceIds": toInterfaces(ctx.strs("InstanceIds"))}, nil
+}
+
+func describeMariadbFlow(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "FlowId"); err != nil {
+		return nil, err
+	}
+	flowId := ctx.int("FlowId", 0)
+	flow, found := s.describeResource(KIND_MARIADB_FLOW, ctx.region, fmt.Sprint(flowId))
+	if !found {
+		return nil, newApiError("InvalidParameter", "flow[%d] not found", flowId)
+	}
+	return map[string]interface{}{"Status": flow["Status"]}, nil
+}
+
+func createMariadbAccount(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "InstanceId", "UserName", "Host", "Password"); err != nil {
+		return nil, err
+	}
+	instance, err := getMariadbInstance(s, ctx, ctx.str("InstanceId"))
+	if err != nil {
+		return nil, err
+	}
+	if fmt.Sprint(instance.data["Status"]) != fmt.Sprint(MARIADB_STATUS_RUNNING) {
+		return nil, newApiError("UnsupportedOperation", "instance[%s] is not running", ctx.str("InstanceId"))
+	}
+	accounts := instance.data["accounts"].(map[string]interface{})
+	account := ctx.str("UserName") + "@" + ctx.str("Host")
+	if _, found := accounts[account]; found {
+		return nil, newApiError("ResourceUnavailable.AccountAlreadyExists", "account[%s] already exists", account)
+	}
+	accounts[account] = []interface{}{}
+	return map[string]interface{}{"InstanceId": ctx.str("InstanceId"), "UserName": ctx.str("UserName"), "Host": ctx.str("Host")}, nil
+}
+
+func grantMariadbAccountPrivileges(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "InstanceId", "UserName", "Host", "DbName", "Privileges"); err != nil {
+		return nil, err
+	}
+	instance, err := getMariadbInstance(s, ctx, ctx.str("InstanceId"))
+	if err != nil {
+		return nil, err
+	}
+	accounts := instance.data["accounts"].(map[string]interface{})
+	account := ctx.str("UserName") + "@" + ctx.str("Host")
+	if _, found := accounts[account]; !found {
+		return nil, newApiError("ResourceNotFound.AccountDoesNotExist", "account[%s] not found", account)
+	}
+	accounts[account] = toInterfaces(ctx.strs("Privileges"))
+	return map[string]interface{}{}, nil
+}
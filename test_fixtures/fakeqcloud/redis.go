@@ -0,0 +1,102 @@
+package fakeqcloud
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	KIND_REDIS_INSTANCE = "redis-instance"
+	KIND_REDIS_DEAL     = "redis-deal"
+
+	REDIS_DEAL_STATUS_DELIVERING = 3
+	REDIS_DEAL_STATUS_DONE       = 4
+
+	REDIS_STATUS_CREATING = 1
+	REDIS_STATUS_RUNNING  = 2
+
+	REDIS_DEFAULT_PORT = 6379
+)
+
+func (s *Server) registerRedis() {
+	s.register("redis", "CreateInstances", createRedisInstances)
+	s.register("redis", "DescribeInstanceDealDetail", describeRedisInstanceDealDetail)
+	s.register("redis", "DescribeInstances", describeRedisInstances)
+}
+
+//the instances of a deal are created only after the deal is delivered
+func createRedisInstances(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "ZoneId", "TypeId", "MemSize", "GoodsNum", "Period", "Password", "BillingMode"); err != nil {
+		return nil, err
+	}
+	ids := []interface{}{}
+	for i := int64(0); i < ctx.int("GoodsNum", 1); i++ {
+		id := s.newId("crs")
+		s.putResource(KIND_REDIS_INSTANCE, ctx.region, id, map[string]interface{}{
+			"InstanceId":   id,
+			"InstanceName": id,
+			"ZoneId":       ctx.int("ZoneId", 0),
+			"Type":         ctx.int("TypeId", 0),
+			"Size":         ctx.int("MemSize", 0),
+			"BillingMode":  ctx.int("BillingMode", 0),
+			"UniqVpcId":    ctx.str("VpcId"),
+			"UniqSubnetId": ctx.str("SubnetId"),
+			"WanIp":        fmt.Sprintf("10.3.%d.%d", s.sequence/250, s.sequence%250+2),
+			"Port":         REDIS_DEFAULT_PORT,
+			"Status":       REDIS_STATUS_CREATING,
+			"Createtime":   now(),
+		})
+		s.schedule(KIND_REDIS_INSTANCE, ctx.region, id, "Status", REDIS_STATUS_RUNNING)
+		ids = append(ids, id)
+	}
+
+	dealId := s.newId("deal")
+	s.putResource(KIND_REDIS_DEAL, ctx.region, dealId, map[string]interface{}{
+		"DealId":      dealId,
+		"DealName":    dealId,
+		"ZoneId":      ctx.int("ZoneId", 0),
+		"GoodsNum":    ctx.int("GoodsNum", 1),
+		"Status":      REDIS_DEAL_STATUS_DELIVERING,
+		"InstanceIds": []interface{}{},
+		"CreateTime":  now(),
+	})
+	s.schedule(KIND_REDIS_DEAL, ctx.region, dealId, "InstanceIds", ids)
+	s.schedule(KIND_REDIS_DEAL, ctx.region, dealId, "Status", REDIS_DEAL_STATUS_DONE)
+	return map[string]interface{}{"DealId": dealId}, nil
+}
+
+func describeRedisInstanceDealDetail(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "DealIds"); err != nil {
+		return nil, err
+	}
+	deals := []interface{}{}
+	for _, id := range ctx.strs("DealIds") {
+		if deal, found := s.describeResource(KIND_REDIS_DEAL, ctx.region, id); found {
+			deals = append(deals, deal)
+		}
+	}
+	return map[string]interface{}{"DealDetails": deals}, nil
+}
+
+//SearchKeys matches the instance id, name or ip
+func describeRedisInstances(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	instanceId := ctx.str("InstanceId")
+	searchKeys := ctx.strs("SearchKeys")
+	items := s.listResources(KIND_REDIS_INSTANCE, ctx.region, func(data map[string]interface{}) bool {
+		if instanceId != "" && data["InstanceId"] != instanceId {
+			return false
+		}
+		if len(searchKeys) == 0 {
+			return true
+		}
+		for _, key := range searchKeys {
+			for _, field := range []string{"InstanceId", "InstanceName", "WanIp"} {
+				if strings.Contains(fmt.Sprint(data[field]), key) {
+					return true
+				}
+			}
+		}
+		return false
+	})
+	return map[string]interface{}{"TotalCount": len(items), "InstanceSet": items}, nil
+}
@@ -0,0 +1,158 @@
+package fakeqcloud
+
+import (
+	"encoding/json"
+)
+
+//resource is kept as a map in the json shape of the sdk model, such as cvm.Instance
+type resource struct {
+	region      string
+	data        map[string]interface{}
+	transitions []*transition
+}
+
+//transition changes a field, or removes the resource, after being described some times
+type transition struct {
+	steps  int
+	field  string
+	value  interface{}
+	remove bool
+}
+
+func resourceKey(region string, id string) string {
+	return region + "/" + id
+}
+
+//AddResource seeds a resource in the json shape of the sdk model, such as a clb load balancer
+func (s *Server) AddResource(kind string, region string, id string, data interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	object := make(map[string]interface{})
+	bytes, _ := json.Marshal(data)
+	json.Unmarshal(bytes, &object)
+	s.putResource(kind, region, id, object)
+}
+
+//GetResource returns a copy of the resource, tests use it to check the state in the fake
+func (s *Server) GetResource(kind string, region string, id string) (map[string]interface{}, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	r, found := s.resources[kind][resourceKey(region, id)]
+	if !found {
+		return nil, false
+	}
+	object := make(map[string]interface{})
+	bytes, _ := json.Marshal(r.data)
+	json.Unmarshal(bytes, &object)
+	return object, true
+}
+
+func (s *Server) putResource(kind string, region string, id string, data map[string]interface{}) *resource {
+	if _, found := s.resources[kind]; !found {
+		s.resources[kind] = make(map[string]*resource)
+	}
+	r := &resource{region: region, data: data}
+	s.resources[kind][resourceKey(region, id)] = r
+	return r
+}
+
+//getResource applies the due transitions, it returns false if the resource is not found or gone
+func (s *Server) getResource(kind string, region string, id string) (*resource, bool) {
+	key := resourceKey(region, id)
+	r, found := s.resources[kind][key]
+	if !found || !s.applyTransitions(kind, key, r) {
+		return nil, false
+	}
+	return r, true
+}
+
+func (s *Server) removeResource(kind string, region string, id string) {
+	delete(s.resources[kind], resourceKey(region, id))
+}
+
+//listResources returns the resources of the region in id order, each one counts as being described once
+func (s *Server) listResources(kind string, region string, match func(data map[string]interface{}) bool) []map[string]interface{} {
+	items := []map[string]interface{}{}
+	for _, key := range sortedKeys(s.resources[kind]) {
+		r := s.resources[kind][key]
+		if r.region != region {
+			continue
+		}
+		if s.describe(kind, key, r) && (match == nil || match(r.data)) {
+			items = append(items, r.data)
+		}
+	}
+	return items
+}
+
+//findResources is listResources for lookups inside the fake, it does not count as a describe
+func (s *Server) findResources(kind string, region string, match func(data map[string]interface{}) bool) []map[string]interface{} {
+	items := []map[string]interface{}{}
+	for _, key := range sortedKeys(s.resources[kind]) {
+		r := s.resources[kind][key]
+		if r.region != region {
+			continue
+		}
+		if s.applyTransitions(kind, key, r) && (match == nil || match(r.data)) {
+			items = append(items, r.data)
+		}
+	}
+	return items
+}
+
+//describeResource describes a single resource, it returns false if the resource is not found or gone
+func (s *Server) describeResource(kind string, region string, id string) (map[string]interface{}, bool) {
+	key := resourceKey(region, id)
+	r, found := s.resources[kind][key]
+	if !found || !s.describe(kind, key, r) {
+		return nil, false
+	}
+	return r.data, true
+}
+
+//describe applies the due transitions then moves the resource on by one step,
+//returns false if the resource is gone
+func (s *Server) describe(kind string, key string, r *resource) bool {
+	if !s.applyTransitions(kind, key, r) {
+		return false
+	}
+	if len(r.transitions) > 0 {
+		r.transitions[0].steps--
+	}
+	return true
+}
+
+func (s *Server) applyTransitions(kind string, key string, r *resource) bool {
+	for len(r.transitions) > 0 && r.transitions[0].steps <= 0 {
+		t := r.transitions[0]
+		r.transitions = r.transitions[1:]
+		if t.remove {
+			delete(s.resources[kind], key)
+			return false
+		}
+		r.data[t.field] = t.value
+	}
+	return true
+}
+
+//schedule sets the field after AsyncSteps describe calls, with AsyncSteps 0 it is set at once
+func (s *Server) schedule(kind string, region string, id string, field string, value interface{}) {
+	s.addTransition(kind, region, id, &transition{steps: s.AsyncSteps, field: field, value: value})
+}
+
+//scheduleRemove removes the resource after AsyncSteps describe calls
+func (s *Server) scheduleRemove(kind string, region string, id string) {
+	s.addTransition(kind, region, id, &transition{steps: s.AsyncSteps, remove: true})
+}
+
+func (s *Server) addTransition(kind string, region string, id string, t *transition) {
+	key := resourceKey(region, id)
+	r, found := s.resources[kind][key]
+	if !found {
+		return
+	}
+	r.transitions = append(r.transitions, t)
+	s.applyTransitions(kind, key, r)
+}
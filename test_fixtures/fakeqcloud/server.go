@@ -0,0 +1,396 @@
+//Package fakeqcloud is an in-memory fake of the tencent cloud api endpoints used by the plugins,
+//it checks the TC3-HMAC-SHA256 signature of sdk requests and keeps resources per region in memory
+package fakeqcloud
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_SECRET_ID  = "fake-secret-id"
+	DEFAULT_SECRET_KEY = "fake-secret-key"
+
+	SIGNATURE_EXPIRE = 5 * time.Minute
+
+	//qcloud returns 20 items when the request has no Limit
+	DEFAULT_PAGE_LIMIT = 20
+)
+
+type handlerFunc func(s *Server, ctx *requestContext) (map[string]interface{}, error)
+
+//Server implements http.RoundTripper, set it as the transport of sdk clients to call it without network
+type Server struct {
+	mutex sync.Mutex
+
+	//AsyncSteps is how many describe calls an async resource stays in the intermediate state,
+	//such as a vm stays PENDING before RUNNING
+	AsyncSteps int
+
+	credentials map[string]string
+	soldOut     map[string]bool
+	handlers    map[string]map[string]handlerFunc
+	resources   map[string]map[string]*resource
+	sequence    int
+	calls       []Call
+}
+
+//Call is a request served by the fake, tests can check which apis were called
+type Call struct {
+	Service string
+	Action  string
+	Region  string
+	Params  map[string]interface{}
+}
+
+type ApiError struct {
+	Code    string
+	Message string
+}
+
+func (e *ApiError) Error() string {
+	return fmt.Sprintf("[%s] %s", e.Code, e.Message)
+}
+
+func newApiError(code string, format string, args ...interface{}) *ApiError {
+	return &ApiError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func NewServer() *Server {
+	s := &Server{
+		AsyncSteps:  1,
+		credentials: map[string]string{DEFAULT_SECRET_ID: DEFAULT_SECRET_KEY},
+		soldOut:     make(map[string]bool),
+		handlers:    make(map[string]map[string]handlerFunc),
+		resources:   make(map[string]map[string]*resource),
+	}
+	s.registerCvm()
+	s.registerVpc()
+	s.registerCbs()
+	s.registerCdb()
+	s.registerRedis()
+	s.registerMariadb()
+	s.registerClb()
+	return s
+}
+
+//ProviderParams returns the provider_params of plugin inputs which are accepted by the fake
+func ProviderParams(region string, zone string) string {
+	return fmt.Sprintf("Region=%s;AvailableZone=%s;SecretID=%s;SecretKey=%s", region, zone, DEFAULT_SECRET_ID, DEFAULT_SECRET_KEY)
+}
+
+func (s *Server) AddCredential(secretId string, secretKey string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.credentials[secretId] = secretKey
+}
+
+//SoldOut stops selling the instance type in the zone, RunInstances of it fails like qcloud
+func (s *Server) SoldOut(zone string, instanceType string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.soldOut[zone+"/"+instanceType] = true
+}
+
+func (s *Server) register(service string, action string, handler handlerFunc) {
+	if _, found := s.handlers[service]; !found {
+		s.handlers[service] = make(map[string]handlerFunc)
+	}
+	s.handlers[service][action] = handler
+}
+
+//Calls returns the requests served by the fake, optionally only those of the given action
+func (s *Server) Calls(action string) []Call {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	calls := []Call{}
+	for _, call := range s.calls {
+		if action == "" || call.Action == action {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+func (s *Server) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	response := recorder.Result()
+	response.Request = req
+	return response, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	requestId := s.newRequestId()
+	result, err := s.serve(r, requestId)
+	var response map[string]interface{}
+	if err != nil {
+		apiErr, ok := err.(*ApiError)
+		if !ok {
+			apiErr = newApiError("InternalError", "%v", err)
+		}
+		response = map[string]interface{}{
+			"Error":     map[string]interface{}{"Code": apiErr.Code, "Message": apiErr.Message},
+			"RequestId": requestId,
+		}
+	} else {
+		response = result
+		response["RequestId"] = requestId
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{"Response": response})
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+func (s *Server) serve(r *http.Request, requestId string) (map[string]interface{}, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, newApiError("InvalidParameter", "read body meet err=%v", err)
+	}
+
+	host := r.Host
+	if host == "" {
+		host = r.URL.Host
+	}
+	service := strings.Split(host, ".")[0]
+	action := headerValue(r, "X-TC-Action")
+	region := headerValue(r, "X-TC-Region")
+
+	if err = s.checkSignature(r, host, service, body); err != nil {
+		return nil, err
+	}
+
+	params := make(map[string]interface{})
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err = json.Unmarshal(body, &params); err != nil {
+			return nil, newApiError("InvalidParameter", "request body is not json, err=%v", err)
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.calls = append(s.calls, Call{Service: service, Action: action, Region: region, Params: params})
+	handler, found := s.handlers[service][action]
+	if !found {
+		return nil, newApiError("InvalidAction", "action[%s] of service[%s] is not supported by the fake", action, service)
+	}
+	return handler(s, &requestContext{region: region, requestId: requestId, params: params})
+}
+
+//checkSignature verifies the TC3-HMAC-SHA256 authorization header the same way qcloud does
+func (s *Server) checkSignature(r *http.Request, host string, service string, body []byte) error {
+	authorization := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "TC3-HMAC-SHA256 ") {
+		return newApiError("AuthFailure.SignatureFailure", "only TC3-HMAC-SHA256 signature is supported")
+	}
+
+	fields := make(map[string]string)
+	for _, item := range strings.Split(strings.TrimPrefix(authorization, "TC3-HMAC-SHA256 "), ",") {
+		kv := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		if len(kv) == 2 {
+			fields[kv[0]] = kv[1]
+		}
+	}
+	credential := strings.Split(fields["Credential"], "/")
+	if len(credential) != 4 {
+		return newApiError("AuthFailure.SignatureFailure", "invalid credential[%s]", fields["Credential"])
+	}
+	secretId, date := credential[0], credential[1]
+
+	s.mutex.Lock()
+	secretKey, found := s.credentials[secretId]
+	s.mutex.Unlock()
+	if !found {
+		return newApiError("AuthFailure.SecretIdNotFound", "secret id[%s] not found", secretId)
+	}
+
+	timestamp, err := strconv.ParseInt(headerValue(r, "X-TC-Timestamp"), 10, 64)
+	if err != nil {
+		return newApiError("AuthFailure.InvalidTimestamp", "invalid timestamp[%s]", headerValue(r, "X-TC-Timestamp"))
+	}
+	if diff := time.Since(time.Unix(timestamp, 0)); diff > SIGNATURE_EXPIRE || diff < -SIGNATURE_EXPIRE {
+		return newApiError("AuthFailure.SignatureExpire", "signature expired")
+	}
+
+	canonicalRequest := fmt.Sprintf("%s\n/\n%s\ncontent-type:%s\nhost:%s\n\n%s\n%s",
+		r.Method, r.URL.RawQuery, r.Header.Get("Content-Type"), host, fields["SignedHeaders"], sha256hex(string(body)))
+	credentialScope := fmt.Sprintf("%s/%s/tc3_request", date, service)
+	stringToSign := fmt.Sprintf("TC3-HMAC-SHA256\n%d\n%s\n%s", timestamp, credentialScope, sha256hex(canonicalRequest))
+
+	secretDate := hmacsha256(date, "TC3"+secretKey)
+	secretService := hmacsha256(service, secretDate)
+	secretSigning := hmacsha256("tc3_request", secretService)
+	signature := hex.EncodeToString([]byte(hmacsha256(stringToSign, secretSigning)))
+	if signature != fields["Signature"] {
+		return newApiError("AuthFailure.SignatureFailure", "signature mismatch")
+	}
+	return nil
+}
+
+//headerValue ignores the case of the name, the sdk sets the X-TC headers without canonicalizing them
+func headerValue(r *http.Request, name string) string {
+	for key, values := range r.Header {
+		if strings.EqualFold(key, name) && len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+func sha256hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacsha256(s string, key string) string {
+	hashed := hmac.New(sha256.New, []byte(key))
+	hashed.Write([]byte(s))
+	return string(hashed.Sum(nil))
+}
+
+func (s *Server) newRequestId() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sequence++
+	return fmt.Sprintf("fake-request-%d", s.sequence)
+}
+
+//newId must be called with the mutex held
+func (s *Server) newId(prefix string) string {
+	s.sequence++
+	return fmt.Sprintf("%s-%08d", prefix, s.sequence)
+}
+
+type requestContext struct {
+	region    string
+	requestId string
+	params    map[string]interface{}
+}
+
+func (ctx *requestContext) str(name string) string {
+	if value, ok := ctx.params[name].(string); ok {
+		return value
+	}
+	return ""
+}
+
+func (ctx *requestContext) int(name string, defaultValue int64) int64 {
+	switch value := ctx.params[name].(type) {
+	case float64:
+		return int64(value)
+	case string:
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+func (ctx *requestContext) strs(name string) []string {
+	values := []string{}
+	items, _ := ctx.params[name].([]interface{})
+	for _, item := range items {
+		if value, ok := item.(string); ok {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func (ctx *requestContext) object(name string) map[string]interface{} {
+	value, _ := ctx.params[name].(map[string]interface{})
+	if value == nil {
+		value = make(map[string]interface{})
+	}
+	return value
+}
+
+func (ctx *requestContext) objects(name string) []map[string]interface{} {
+	values := []map[string]interface{}{}
+	items, _ := ctx.params[name].([]interface{})
+	for _, item := range items {
+		if value, ok := item.(map[string]interface{}); ok {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+//filters returns the values of each filter name in "Filters"
+func (ctx *requestContext) filters() map[string][]string {
+	filters := make(map[string][]string)
+	for _, filter := range ctx.objects("Filters") {
+		name, _ := filter["Name"].(string)
+		values, _ := filter["Values"].([]interface{})
+		for _, value := range values {
+			if str, ok := value.(string); ok {
+				filters[name] = append(filters[name], str)
+			}
+		}
+	}
+	return filters
+}
+
+func require(ctx *requestContext, names ...string) error {
+	for _, name := range names {
+		if _, found := ctx.params[name]; !found {
+			return newApiError("MissingParameter", "parameter[%s] is missing", name)
+		}
+	}
+	return nil
+}
+
+//page returns the items in the Offset and Limit of the request
+func page(ctx *requestContext, items []map[string]interface{}) []map[string]interface{} {
+	offset := ctx.int("Offset", 0)
+	limit := ctx.int("Limit", DEFAULT_PAGE_LIMIT)
+	if offset >= int64(len(items)) {
+		return []map[string]interface{}{}
+	}
+	if end := offset + limit; end < int64(len(items)) {
+		return items[offset:end]
+	}
+	return items[offset:]
+}
+
+func matchAny(value interface{}, candidates []string) bool {
+	str := fmt.Sprint(value)
+	for _, candidate := range candidates {
+		if str == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+func toInterfaces(values []string) []interface{} {
+	items := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		items = append(items, value)
+	}
+	return items
+}
+
+func sortedKeys(m map[string]*resource) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
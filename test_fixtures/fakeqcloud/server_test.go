@@ -0,0 +1,95 @@
+package fakeqcloud
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+)
+
+func newCvmClient(t *testing.T, s *Server, secretKey string) *cvm.Client {
+	clientProfile := profile.NewClientProfile()
+	clientProfile.HttpProfile.Endpoint = "cvm.tencentcloudapi.com"
+	client, err := cvm.NewClient(common.NewCredential(DEFAULT_SECRET_ID, secretKey), "ap-guangzhou", clientProfile)
+	if err != nil {
+		t.Fatalf("new cvm client meet err=%v", err)
+	}
+	client.WithHttpTransport(s)
+	return client
+}
+
+func runInstance(client *cvm.Client, clientToken string) (*cvm.RunInstancesResponse, error) {
+	request := cvm.NewRunInstancesRequest()
+	request.Placement = &cvm.Placement{Zone: common.StringPtr("ap-guangzhou-3")}
+	request.ImageId = common.StringPtr("img-test")
+	request.InstanceType = common.StringPtr("S2.MEDIUM4")
+	if clientToken != "" {
+		request.ClientToken = common.StringPtr(clientToken)
+	}
+	return client.RunInstances(request)
+}
+
+func TestSignature(t *testing.T) {
+	s := NewServer()
+	if _, err := runInstance(newCvmClient(t, s, "wrong-key"), ""); err == nil || !strings.Contains(err.Error(), "AuthFailure.SignatureFailure") {
+		t.Fatalf("request signed with wrong key got err=%v,want AuthFailure.SignatureFailure", err)
+	}
+	if len(s.Calls("RunInstances")) != 0 {
+		t.Fatalf("request with wrong signature should not be served")
+	}
+
+	if _, err := runInstance(newCvmClient(t, s, DEFAULT_SECRET_KEY), ""); err != nil {
+		t.Fatalf("request signed with right key meet err=%v", err)
+	}
+}
+
+func TestAsyncState(t *testing.T) {
+	s := NewServer()
+	s.AsyncSteps = 2
+	client := newCvmClient(t, s, DEFAULT_SECRET_KEY)
+
+	response, err := runInstance(client, "")
+	if err != nil {
+		t.Fatalf("run instance meet err=%v", err)
+	}
+	instanceId := response.Response.InstanceIdSet[0]
+
+	request := cvm.NewDescribeInstancesRequest()
+	request.InstanceIds = []*string{instanceId}
+	for _, want := range []string{"PENDING", "PENDING", "RUNNING", "RUNNING"} {
+		describeResponse, err := client.DescribeInstances(request)
+		if err != nil {
+			t.Fatalf("describe instance meet err=%v", err)
+		}
+		if state := *describeResponse.Response.InstanceSet[0].InstanceState; state != want {
+			t.Fatalf("instance state=%s,want %s", state, want)
+		}
+	}
+}
+
+func TestClientToken(t *testing.T) {
+	s := NewServer()
+	client := newCvmClient(t, s, DEFAULT_SECRET_KEY)
+
+	first, err := runInstance(client, "token-1")
+	if err != nil {
+		t.Fatalf("run instance meet err=%v", err)
+	}
+	second, err := runInstance(client, "token-1")
+	if err != nil {
+		t.Fatalf("run instance again meet err=%v", err)
+	}
+	if *first.Response.InstanceIdSet[0] != *second.Response.InstanceIdSet[0] {
+		t.Fatalf("requests with the same client token should return the same instance")
+	}
+
+	other, err := runInstance(client, "token-2")
+	if err != nil {
+		t.Fatalf("run instance with another token meet err=%v", err)
+	}
+	if *other.Response.InstanceIdSet[0] == *first.Response.InstanceIdSet[0] {
+		t.Fatalf("requests with different client tokens should create different instances")
+	}
+}
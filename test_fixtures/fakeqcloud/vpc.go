@@ -0,0 +1,1109 @@
+package fakeqcloud
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	KIND_VPC                     = "vpc"
+	KIND_SUBNET                  = "subnet"
+	KIND_ROUTE_TABLE             = "route-table"
+	KIND_SECURITY_GROUP          = "security-group"
+	KIND_SECURITY_GROUP_POLICIES = "security-group-policies"
+	KIND_ADDRESS                 = "address"
+	KIND_NETWORK_INTERFACE       = "network-interface"
+	KIND_NAT_GATEWAY             = "nat-gateway"
+	KIND_PEERING_CONNECTION      = "peering-connection"
+
+	EIP_QUOTA = 20
+)
+
+func (s *Server) registerVpc() {
+	s.register("vpc", "CreateVpc", createVpc)
+	s.register("vpc", "DescribeVpcs", describeVpcs)
+	s.register("vpc", "DeleteVpc", deleteVpc)
+	s.register("vpc", "ModifyVpcAttribute", modifyVpcAttribute)
+	s.register("vpc", "CreateAssistantCidr", createAssistantCidr)
+	s.register("vpc", "DeleteAssistantCidr", deleteAssistantCidr)
+	s.register("vpc", "AssignIpv6CidrBlock", assignIpv6CidrBlock)
+
+	s.register("vpc", "CreateSubnet", createSubnet)
+	s.register("vpc", "DescribeSubnets", describeSubnets)
+	s.register("vpc", "DeleteSubnet", deleteSubnet)
+	s.register("vpc", "AssignIpv6SubnetCidrBlock", assignIpv6SubnetCidrBlock)
+
+	s.register("vpc", "CreateRouteTable", createRouteTable)
+	s.register("vpc", "DescribeRouteTables", describeRouteTables)
+	s.register("vpc", "DeleteRouteTable", deleteRouteTable)
+	s.register("vpc", "ReplaceRouteTableAssociation", replaceRouteTableAssociation)
+	s.register("vpc", "CreateRoutes", createRoutes)
+	s.register("vpc", "DeleteRoutes", deleteRoutes)
+	s.register("vpc", "DescribeRouteConflicts", describeRouteConflicts)
+	s.register("vpc", "ModifyRouteTableAttribute", modifyRouteTableAttribute)
+	s.register("vpc", "EnableRoutes", enableRoutes)
+	s.register("vpc", "DisableRoutes", disableRoutes)
+	s.register("vpc", "ReplaceRoutes", replaceRoutes)
+
+	s.register("vpc", "CreateSecurityGroup", createSecurityGroup)
+	s.register("vpc", "DescribeSecurityGroups", describeSecurityGroups)
+	s.register("vpc", "DeleteSecurityGroup", deleteSecurityGroup)
+	s.register("vpc", "CreateSecurityGroupPolicies", createSecurityGroupPolicies)
+	s.register("vpc", "DeleteSecurityGroupPolicies", deleteSecurityGroupPolicies)
+	s.register("vpc", "DescribeSecurityGroupPolicies", describeSecurityGroupPolicies)
+
+	s.register("vpc", "AllocateAddresses", allocateAddresses)
+	s.register("vpc", "DescribeAddresses", describeAddresses)
+	s.register("vpc", "ReleaseAddresses", releaseAddresses)
+	s.register("vpc", "AssociateAddress", associateAddress)
+	s.register("vpc", "DisassociateAddress", disassociateAddress)
+	s.register("vpc", "DescribeAddressQuota", describeAddressQuota)
+
+	s.register("vpc", "CreateNetworkInterface", createNetworkInterface)
+	s.register("vpc", "DescribeNetworkInterfaces", describeNetworkInterfaces)
+	s.register("vpc", "DeleteNetworkInterface", deleteNetworkInterface)
+	s.register("vpc", "AttachNetworkInterface", attachNetworkInterface)
+	s.register("vpc", "DetachNetworkInterface", detachNetworkInterface)
+
+	s.register("vpc", "CreateNatGateway", createNatGateway)
+	s.register("vpc", "DescribeNatGateways", describeNatGateways)
+	s.register("vpc", "DeleteNatGateway", deleteNatGateway)
+
+	//the peer of a peering connection is never asked, the peering connection turns active by itself
+	s.register("vpc", "CreateVpcPeeringConnection", createVpcPeeringConnection)
+	s.register("vpc", "DescribeVpcPeeringConnections", describeVpcPeeringConnections)
+	s.register("vpc", "DeleteVpcPeeringConnection", deleteVpcPeeringConnection)
+}
+
+//beijingTime is the timezone of the times qcloud reports like 2006-01-02 15:04:05
+var beijingTime = time.FixedZone("CST", 8*60*60)
+
+func now() string {
+	return time.Now().In(beijingTime).Format("2006-01-02 15:04:05")
+}
+
+//matchFilters checks the ids and filters of a describe request, fields maps filter names to data fields
+func matchFilters(ctx *requestContext, idsName string, idField string, fields map[string]string) func(data map[string]interface{}) bool {
+	ids := ctx.strs(idsName)
+	filters := ctx.filters()
+	return func(data map[string]interface{}) bool {
+		if len(ids) > 0 && !matchAny(data[idField], ids) {
+			return false
+		}
+		return matchAllFilters(data, filters, fields)
+	}
+}
+
+//matchAllFilters is true if the data matches one of the values of every filter
+func matchAllFilters(data map[string]interface{}, filters map[string][]string, fields map[string]string) bool {
+	for name, values := range filters {
+		field, found := fields[name]
+		if !found || !matchAny(data[field], values) {
+			return false
+		}
+	}
+	return true
+}
+
+func mustGet(s *Server, kind string, ctx *requestContext, paramName string, notFoundCode string) (*resource, error) {
+	if err := require(ctx, paramName); err != nil {
+		return nil, err
+	}
+	r, found := s.getResource(kind, ctx.region, ctx.str(paramName))
+	if !found {
+		return nil, newApiError(notFoundCode, "%s[%s] not found", kind, ctx.str(paramName))
+	}
+	return r, nil
+}
+
+func isCidrOverlap(cidr1 string, cidr2 string) bool {
+	_, net1, err1 := net.ParseCIDR(cidr1)
+	_, net2, err2 := net.ParseCIDR(cidr2)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return net1.Contains(net2.IP) || net2.Contains(net1.IP)
+}
+
+func isCidrContains(parent string, child string) bool {
+	_, parentNet, err1 := net.ParseCIDR(parent)
+	_, childNet, err2 := net.ParseCIDR(child)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	parentOnes, _ := parentNet.Mask.Size()
+	childOnes, _ := childNet.Mask.Size()
+	return parentNet.Contains(childNet.IP) && parentOnes <= childOnes
+}
+
+//a new vpc comes with its main route table, like qcloud does
+func createVpc(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "VpcName", "CidrBlock"); err != nil {
+		return nil, err
+	}
+	if _, _, err := net.ParseCIDR(ctx.str("CidrBlock")); err != nil {
+		return nil, newApiError("InvalidParameterValue", "invalid cidr block[%s]", ctx.str("CidrBlock"))
+	}
+
+	id := s.newId("vpc")
+	vpc := s.putResource(KIND_VPC, ctx.region, id, map[string]interface{}{
+		"VpcId":            id,
+		"VpcName":          ctx.str("VpcName"),
+		"CidrBlock":        ctx.str("CidrBlock"),
+		"IsDefault":        false,
+		"EnableMulticast":  false,
+		"DnsServerSet":     []interface{}{},
+		"DomainName":       "",
+		"AssistantCidrSet": []interface{}{},
+		"Ipv6CidrBlock":    "",
+		"CreatedTime":      now(),
+	})
+	routeTableId := s.newId("rtb")
+	s.putResource(KIND_ROUTE_TABLE, ctx.region, routeTableId, map[string]interface{}{
+		"RouteTableId":   routeTableId,
+		"RouteTableName": "default",
+		"VpcId":          id,
+		"Main":           true,
+		"RouteSet":       []interface{}{},
+		"CreatedTime":    now(),
+	})
+	return map[string]interface{}{"Vpc": vpc.data}, nil
+}
+
+func describeVpcs(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	items := s.listResources(KIND_VPC, ctx.region, matchFilters(ctx, "VpcIds", "VpcId", map[string]string{
+		"vpc-id":     "VpcId",
+		"vpc-name":   "VpcName",
+		"cidr-block": "CidrBlock",
+	}))
+	return map[string]interface{}{"TotalCount": len(items), "VpcSet": items}, nil
+}
+
+func deleteVpc(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	vpc, err := mustGet(s, KIND_VPC, ctx, "VpcId", "ResourceNotFound")
+	if err != nil {
+		return nil, err
+	}
+	vpcId := vpc.data["VpcId"]
+	subnets := s.findResources(KIND_SUBNET, ctx.region, func(data map[string]interface{}) bool { return data["VpcId"] == vpcId })
+	if len(subnets) > 0 {
+		return nil, newApiError("ResourceInUse", "vpc[%s] still has %d subnets", vpcId, len(subnets))
+	}
+	for _, routeTable := range s.findResources(KIND_ROUTE_TABLE, ctx.region, func(data map[string]interface{}) bool { return data["VpcId"] == vpcId }) {
+		s.removeResource(KIND_ROUTE_TABLE, ctx.region, routeTable["RouteTableId"].(string))
+	}
+	s.removeResource(KIND_VPC, ctx.region, vpcId.(string))
+	return map[string]interface{}{}, nil
+}
+
+func modifyVpcAttribute(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	vpc, err := mustGet(s, KIND_VPC, ctx, "VpcId", "ResourceNotFound")
+	if err != nil {
+		return nil, err
+	}
+	if name := ctx.str("VpcName"); name != "" {
+		vpc.data["VpcName"] = name
+	}
+	if dnsServers := ctx.strs("DnsServers"); len(dnsServers) > 0 {
+		if len(dnsServers) > 4 {
+			return nil, newApiError("InvalidParameterValue.Range", "vpc has at most 4 dns servers")
+		}
+		vpc.data["DnsServerSet"] = toInterfaces(dnsServers)
+	}
+	if domainName := ctx.str("DomainName"); domainName != "" {
+		vpc.data["DomainName"] = domainName
+	}
+	return map[string]interface{}{}, nil
+}
+
+//vpcCidrBlocks returns the primary cidr of the vpc followed by its secondary cidrs
+func vpcCidrBlocks(vpc map[string]interface{}) []string {
+	cidrBlocks := []string{vpc["CidrBlock"].(string)}
+	assistantCidrs, _ := vpc["AssistantCidrSet"].([]interface{})
+	for _, assistantCidr := range assistantCidrs {
+		cidrBlocks = append(cidrBlocks, assistantCidr.(map[string]interface{})["CidrBlock"].(string))
+	}
+	return cidrBlocks
+}
+
+func createAssistantCidr(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	vpc, err := mustGet(s, KIND_VPC, ctx, "VpcId", "ResourceNotFound")
+	if err != nil {
+		return nil, err
+	}
+	if err := require(ctx, "CidrBlocks"); err != nil {
+		return nil, err
+	}
+
+	added := []interface{}{}
+	for _, cidr := range ctx.strs("CidrBlocks") {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return nil, newApiError("InvalidParameterValue.Malformed", "invalid cidr block[%s]", cidr)
+		}
+		for _, existed := range vpcCidrBlocks(vpc.data) {
+			if isCidrOverlap(cidr, existed) {
+				return nil, newApiError("InvalidParameterValue.SubnetConflict", "cidr block[%s] overlaps with cidr block[%s] of vpc", cidr, existed)
+			}
+		}
+		assistantCidr := map[string]interface{}{"VpcId": vpc.data["VpcId"], "CidrBlock": cidr, "AssistantType": 0}
+		vpc.data["AssistantCidrSet"] = append(vpc.data["AssistantCidrSet"].([]interface{}), assistantCidr)
+		added = append(added, assistantCidr)
+	}
+	return map[string]interface{}{"AssistantCidrSet": added}, nil
+}
+
+func deleteAssistantCidr(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	vpc, err := mustGet(s, KIND_VPC, ctx, "VpcId", "ResourceNotFound")
+	if err != nil {
+		return nil, err
+	}
+	if err := require(ctx, "CidrBlocks"); err != nil {
+		return nil, err
+	}
+
+	vpcId := vpc.data["VpcId"]
+	subnets := s.findResources(KIND_SUBNET, ctx.region, func(data map[string]interface{}) bool { return data["VpcId"] == vpcId })
+	for _, cidr := range ctx.strs("CidrBlocks") {
+		for _, subnet := range subnets {
+			if isCidrContains(cidr, subnet["CidrBlock"].(string)) {
+				return nil, newApiError("ResourceInUse", "cidr block[%s] still has subnet[%s]", cidr, subnet["SubnetId"])
+			}
+		}
+	}
+
+	remained := []interface{}{}
+	for _, assistantCidr := range vpc.data["AssistantCidrSet"].([]interface{}) {
+		if !matchAny(assistantCidr.(map[string]interface{})["CidrBlock"], ctx.strs("CidrBlocks")) {
+			remained = append(remained, assistantCidr)
+		}
+	}
+	if len(remained) == len(vpc.data["AssistantCidrSet"].([]interface{})) {
+		return nil, newApiError("ResourceNotFound", "cidr blocks%v not found in vpc[%s]", ctx.strs("CidrBlocks"), vpcId)
+	}
+	vpc.data["AssistantCidrSet"] = remained
+	return map[string]interface{}{}, nil
+}
+
+//every vpc gets its own /56 of a fake ipv6 range
+func assignIpv6CidrBlock(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	vpc, err := mustGet(s, KIND_VPC, ctx, "VpcId", "ResourceNotFound")
+	if err != nil {
+		return nil, err
+	}
+	if vpc.data["Ipv6CidrBlock"] != "" && vpc.data["Ipv6CidrBlock"] != nil {
+		return nil, newApiError("InvalidParameterValue.Duplicate", "vpc[%s] already has ipv6 cidr block[%s]", vpc.data["VpcId"], vpc.data["Ipv6CidrBlock"])
+	}
+	s.sequence++
+	ipv6CidrBlock := fmt.Sprintf("2402:4e00:1000:%x00::/56", s.sequence%256)
+	vpc.data["Ipv6CidrBlock"] = ipv6CidrBlock
+	return map[string]interface{}{"Ipv6CidrBlock": ipv6CidrBlock}, nil
+}
+
+func createSubnet(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "VpcId", "SubnetName", "CidrBlock", "Zone"); err != nil {
+		return nil, err
+	}
+	vpc, err := mustGet(s, KIND_VPC, ctx, "VpcId", "ResourceNotFound")
+	if err != nil {
+		return nil, err
+	}
+	vpcId := vpc.data["VpcId"]
+	cidr := ctx.str("CidrBlock")
+	inVpc := false
+	for _, vpcCidr := range vpcCidrBlocks(vpc.data) {
+		inVpc = inVpc || isCidrContains(vpcCidr, cidr)
+	}
+	if !inVpc {
+		return nil, newApiError("InvalidParameterValue.SubnetRange", "subnet cidr[%s] is not in vpc cidrs%v", cidr, vpcCidrBlocks(vpc.data))
+	}
+	for _, subnet := range s.findResources(KIND_SUBNET, ctx.region, func(data map[string]interface{}) bool { return data["VpcId"] == vpcId }) {
+		if isCidrOverlap(cidr, subnet["CidrBlock"].(string)) {
+			return nil, newApiError("InvalidParameterValue.SubnetConflict", "subnet cidr[%s] overlaps with subnet[%s]", cidr, subnet["SubnetId"])
+		}
+	}
+
+	mainRouteTableId := ""
+	for _, routeTable := range s.findResources(KIND_ROUTE_TABLE, ctx.region, func(data map[string]interface{}) bool {
+		return data["VpcId"] == vpcId && data["Main"] == true
+	}) {
+		mainRouteTableId = routeTable["RouteTableId"].(string)
+	}
+
+	id := s.newId("subnet")
+	subnet := s.putResource(KIND_SUBNET, ctx.region, id, map[string]interface{}{
+		"SubnetId":      id,
+		"SubnetName":    ctx.str("SubnetName"),
+		"VpcId":         vpcId,
+		"CidrBlock":     cidr,
+		"Zone":          ctx.str("Zone"),
+		"RouteTableId":  mainRouteTableId,
+		"IsDefault":     false,
+		"Ipv6CidrBlock": "",
+		"CreatedTime":   now(),
+	})
+	return map[string]interface{}{"Subnet": subnet.data}, nil
+}
+
+func describeSubnets(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	items := s.listResources(KIND_SUBNET, ctx.region, matchFilters(ctx, "SubnetIds", "SubnetId", map[string]string{
+		"vpc-id":         "VpcId",
+		"subnet-id":      "SubnetId",
+		"subnet-name":    "SubnetName",
+		"zone":           "Zone",
+		"cidr-block":     "CidrBlock",
+		"route-table-id": "RouteTableId",
+	}))
+	return map[string]interface{}{"TotalCount": len(items), "SubnetSet": items}, nil
+}
+
+func assignIpv6SubnetCidrBlock(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	vpc, err := mustGet(s, KIND_VPC, ctx, "VpcId", "ResourceNotFound")
+	if err != nil {
+		return nil, err
+	}
+	if err := require(ctx, "Ipv6SubnetCidrBlocks"); err != nil {
+		return nil, err
+	}
+	vpcIpv6CidrBlock, _ := vpc.data["Ipv6CidrBlock"].(string)
+
+	assigned := []interface{}{}
+	for _, item := range ctx.objects("Ipv6SubnetCidrBlocks") {
+		subnetId, _ := item["SubnetId"].(string)
+		ipv6CidrBlock, _ := item["Ipv6CidrBlock"].(string)
+		subnet, found := s.getResource(KIND_SUBNET, ctx.region, subnetId)
+		if !found || subnet.data["VpcId"] != vpc.data["VpcId"] {
+			return nil, newApiError("ResourceNotFound", "subnet[%s] not found in vpc[%s]", subnetId, vpc.data["VpcId"])
+		}
+		if existed, _ := subnet.data["Ipv6CidrBlock"].(string); existed != "" {
+			return nil, newApiError("InvalidParameterValue.Duplicate", "subnet[%s] already has ipv6 cidr block[%s]", subnetId, existed)
+		}
+		if !isCidrContains(vpcIpv6CidrBlock, ipv6CidrBlock) {
+			return nil, newApiError("InvalidParameterValue.SubnetRange", "ipv6 cidr[%s] is not in vpc ipv6 cidr[%s]", ipv6CidrBlock, vpcIpv6CidrBlock)
+		}
+		subnet.data["Ipv6CidrBlock"] = ipv6CidrBlock
+		assigned = append(assigned, map[string]interface{}{"SubnetId": subnetId, "Ipv6CidrBlock": ipv6CidrBlock})
+	}
+	return map[string]interface{}{"Ipv6SubnetCidrBlockSet": assigned}, nil
+}
+
+func deleteSubnet(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	subnet, err := mustGet(s, KIND_SUBNET, ctx, "SubnetId", "ResourceNotFound")
+	if err != nil {
+		return nil, err
+	}
+	subnetId := subnet.data["SubnetId"].(string)
+	instances := s.findResources(KIND_CVM_INSTANCE, ctx.region, func(data map[string]interface{}) bool {
+		return data["VirtualPrivateCloud"].(map[string]interface{})["SubnetId"] == subnetId
+	})
+	nics := s.findResources(KIND_NETWORK_INTERFACE, ctx.region, func(data map[string]interface{}) bool { return data["SubnetId"] == subnetId })
+	if len(instances) > 0 || len(nics) > 0 {
+		return nil, newApiError("ResourceInUse", "subnet[%s] is used by %d instances and %d network interfaces", subnetId, len(instances), len(nics))
+	}
+	s.removeResource(KIND_SUBNET, ctx.region, subnetId)
+	return map[string]interface{}{}, nil
+}
+
+func createRouteTable(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "VpcId", "RouteTableName"); err != nil {
+		return nil, err
+	}
+	vpc, err := mustGet(s, KIND_VPC, ctx, "VpcId", "ResourceNotFound")
+	if err != nil {
+		return nil, err
+	}
+	id := s.newId("rtb")
+	routeTable := s.putResource(KIND_ROUTE_TABLE, ctx.region, id, map[string]interface{}{
+		"RouteTableId":   id,
+		"RouteTableName": ctx.str("RouteTableName"),
+		"VpcId":          vpc.data["VpcId"],
+		"Main":           false,
+		"RouteSet":       []interface{}{},
+		"CreatedTime":    now(),
+	})
+	return map[string]interface{}{"RouteTable": routeTable.data}, nil
+}
+
+//the association set of a route table is computed from the subnets
+func (s *Server) routeTableView(region string, data map[string]interface{}) map[string]interface{} {
+	view := make(map[string]interface{})
+	for key, value := range data {
+		view[key] = value
+	}
+	associations := []interface{}{}
+	for _, subnet := range s.findResources(KIND_SUBNET, region, func(subnet map[string]interface{}) bool {
+		return subnet["RouteTableId"] == data["RouteTableId"]
+	}) {
+		associations = append(associations, map[string]interface{}{"SubnetId": subnet["SubnetId"], "RouteTableId": data["RouteTableId"]})
+	}
+	view["AssociationSet"] = associations
+	return view
+}
+
+func describeRouteTables(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	match := matchFilters(ctx, "RouteTableIds", "RouteTableId", map[string]string{
+		"route-table-id":   "RouteTableId",
+		"route-table-name": "RouteTableName",
+		"vpc-id":           "VpcId",
+		"association.main": "Main",
+	})
+	items := []map[string]interface{}{}
+	for _, item := range s.listResources(KIND_ROUTE_TABLE, ctx.region, match) {
+		items = append(items, s.routeTableView(ctx.region, item))
+	}
+	return map[string]interface{}{"TotalCount": len(items), "RouteTableSet": items}, nil
+}
+
+func deleteRouteTable(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	routeTable, err := mustGet(s, KIND_ROUTE_TABLE, ctx, "RouteTableId", "ResourceNotFound")
+	if err != nil {
+		return nil, err
+	}
+	id := routeTable.data["RouteTableId"].(string)
+	if routeTable.data["Main"] == true {
+		return nil, newApiError("UnsupportedOperation", "main route table[%s] can't be deleted", id)
+	}
+	if associations := s.routeTableView(ctx.region, routeTable.data)["AssociationSet"].([]interface{}); len(associations) > 0 {
+		return nil, newApiError("ResourceInUse", "route table[%s] is associated with %d subnets", id, len(associations))
+	}
+	s.removeResource(KIND_ROUTE_TABLE, ctx.region, id)
+	return map[string]interface{}{}, nil
+}
+
+func replaceRouteTableAssociation(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	subnet, err := mustGet(s, KIND_SUBNET, ctx, "SubnetId", "ResourceNotFound")
+	if err != nil {
+		return nil, err
+	}
+	routeTable, err := mustGet(s, KIND_ROUTE_TABLE, ctx, "RouteTableId", "ResourceNotFound")
+	if err != nil {
+		return nil, err
+	}
+	if subnet.data["VpcId"] != routeTable.data["VpcId"] {
+		return nil, newApiError("UnsupportedOperation", "subnet[%s] and route table[%s] are not in the same vpc", subnet.data["SubnetId"], routeTable.data["RouteTableId"])
+	}
+	subnet.data["RouteTableId"] = routeTable.data["RouteTableId"]
+	return map[string]interface{}{}, nil
+}
+
+//the route table in the response only lists the routes just created
+func createRoutes(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	routeTable, err := mustGet(s, KIND_ROUTE_TABLE, ctx, "RouteTableId", "ResourceNotFound")
+	if err != nil {
+		return nil, err
+	}
+	if err = require(ctx, "Routes"); err != nil {
+		return nil, err
+	}
+	routes, _ := routeTable.data["RouteSet"].([]interface{})
+	created := []interface{}{}
+	for _, route := range ctx.objects("Routes") {
+		for _, existed := range routes {
+			if existed.(map[string]interface{})["DestinationCidrBlock"] == route["DestinationCidrBlock"] {
+				return nil, newApiError("InvalidParameterValue.Duplicate", "route to[%s] already exists", route["DestinationCidrBlock"])
+			}
+		}
+		s.sequence++
+		newRoute := map[string]interface{}{
+			"DestinationCidrBlock": route["DestinationCidrBlock"],
+			"GatewayType":          route["GatewayType"],
+			"GatewayId":            route["GatewayId"],
+			"RouteDescription":     route["RouteDescription"],
+			"RouteId":              s.sequence,
+			"RouteType":            "USER",
+			"Enabled":              true,
+		}
+		routes = append(routes, newRoute)
+		created = append(created, newRoute)
+	}
+	routeTable.data["RouteSet"] = routes
+
+	view := s.routeTableView(ctx.region, routeTable.data)
+	view["RouteSet"] = created
+	return map[string]interface{}{"TotalCount": len(created), "RouteTableSet": []interface{}{view}}, nil
+}
+
+func deleteRoutes(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	routeTable, err := mustGet(s, KIND_ROUTE_TABLE, ctx, "RouteTableId", "ResourceNotFound")
+	if err != nil {
+		return nil, err
+	}
+	routes, _ := routeTable.data["RouteSet"].([]interface{})
+	for _, route := range ctx.objects("Routes") {
+		left := []interface{}{}
+		found := false
+		for _, existed := range routes {
+			data := existed.(map[string]interface{})
+			if (route["RouteId"] != nil && fmt.Sprint(route["RouteId"]) == fmt.Sprint(data["RouteId"])) ||
+				(route["RouteId"] == nil && route["DestinationCidrBlock"] == data["DestinationCidrBlock"]) {
+				found = true
+				continue
+			}
+			left = append(left, existed)
+		}
+		if !found {
+			return nil, newApiError("ResourceNotFound", "route[%v] not found", route)
+		}
+		routes = left
+	}
+	routeTable.data["RouteSet"] = routes
+	return map[string]interface{}{}, nil
+}
+
+func modifyRouteTableAttribute(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	routeTable, err := mustGet(s, KIND_ROUTE_TABLE, ctx, "RouteTableId", "ResourceNotFound")
+	if err != nil {
+		return nil, err
+	}
+	if name := ctx.str("RouteTableName"); name != "" {
+		routeTable.data["RouteTableName"] = name
+	}
+	return map[string]interface{}{}, nil
+}
+
+//setRoutesEnabled fails without changing any route if one of the route ids is not in the route table
+func setRoutesEnabled(s *Server, ctx *requestContext, enabled bool) (map[string]interface{}, error) {
+	routeTable, err := mustGet(s, KIND_ROUTE_TABLE, ctx, "RouteTableId", "ResourceNotFound")
+	if err != nil {
+		return nil, err
+	}
+	if err = require(ctx, "RouteIds"); err != nil {
+		return nil, err
+	}
+	routes, _ := routeTable.data["RouteSet"].([]interface{})
+	matched := []map[string]interface{}{}
+	routeIds, _ := ctx.params["RouteIds"].([]interface{})
+	for _, routeId := range routeIds {
+		found := false
+		for _, route := range routes {
+			data := route.(map[string]interface{})
+			if fmt.Sprint(routeId) == fmt.Sprint(data["RouteId"]) {
+				matched = append(matched, data)
+				found = true
+			}
+		}
+		if !found {
+			return nil, newApiError("ResourceNotFound", "route[%v] not found", routeId)
+		}
+	}
+	for _, route := range matched {
+		route["Enabled"] = enabled
+	}
+	return map[string]interface{}{}, nil
+}
+
+func enableRoutes(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	return setRoutesEnabled(s, ctx, true)
+}
+
+func disableRoutes(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	return setRoutesEnabled(s, ctx, false)
+}
+
+//the replaced routes keep their ids, a route can't be replaced by one to the destination of another route
+func replaceRoutes(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	routeTable, err := mustGet(s, KIND_ROUTE_TABLE, ctx, "RouteTableId", "ResourceNotFound")
+	if err != nil {
+		return nil, err
+	}
+	if err = require(ctx, "Routes"); err != nil {
+		return nil, err
+	}
+	routes, _ := routeTable.data["RouteSet"].([]interface{})
+	for _, route := range ctx.objects("Routes") {
+		var replaced map[string]interface{}
+		for _, existed := range routes {
+			data := existed.(map[string]interface{})
+			if fmt.Sprint(route["RouteId"]) == fmt.Sprint(data["RouteId"]) {
+				replaced = data
+			} else if data["DestinationCidrBlock"] == route["DestinationCidrBlock"] {
+				return nil, newApiError("InvalidParameterValue.Duplicate", "route to[%s] already exists", route["DestinationCidrBlock"])
+			}
+		}
+		if replaced == nil {
+			return nil, newApiError("ResourceNotFound", "route[%v] not found", route["RouteId"])
+		}
+		for _, key := range []string{"DestinationCidrBlock", "GatewayType", "GatewayId", "RouteDescription"} {
+			replaced[key] = route[key]
+		}
+	}
+	return map[string]interface{}{}, nil
+}
+
+//routes conflict when their destination is the same
+func describeRouteConflicts(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	routeTable, err := mustGet(s, KIND_ROUTE_TABLE, ctx, "RouteTableId", "ResourceNotFound")
+	if err != nil {
+		return nil, err
+	}
+	routes, _ := routeTable.data["RouteSet"].([]interface{})
+	conflicts := []interface{}{}
+	for _, cidr := range ctx.strs("DestinationCidrBlocks") {
+		conflictSet := []interface{}{}
+		for _, route := range routes {
+			if route.(map[string]interface{})["DestinationCidrBlock"] == cidr {
+				conflictSet = append(conflictSet, route)
+			}
+		}
+		conflicts = append(conflicts, map[string]interface{}{
+			"RouteTableId":         routeTable.data["RouteTableId"],
+			"DestinationCidrBlock": cidr,
+			"ConflictSet":          conflictSet,
+		})
+	}
+	return map[string]interface{}{"RouteConflictSet": conflicts}, nil
+}
+
+func createSecurityGroup(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "GroupName", "GroupDescription"); err != nil {
+		return nil, err
+	}
+	id := s.newId("sg")
+	securityGroup := s.putResource(KIND_SECURITY_GROUP, ctx.region, id, map[string]interface{}{
+		"SecurityGroupId":   id,
+		"SecurityGroupName": ctx.str("GroupName"),
+		"SecurityGroupDesc": ctx.str("GroupDescription"),
+		"ProjectId":         ctx.str("ProjectId"),
+		"IsDefault":         false,
+		"CreatedTime":       now(),
+	})
+	s.putResource(KIND_SECURITY_GROUP_POLICIES, ctx.region, id, map[string]interface{}{
+		"Version": "0",
+		"Egress":  []interface{}{},
+		"Ingress": []interface{}{},
+	})
+	return map[string]interface{}{"SecurityGroup": securityGroup.data}, nil
+}
+
+func describeSecurityGroups(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	items := s.listResources(KIND_SECURITY_GROUP, ctx.region, matchFilters(ctx, "SecurityGroupIds", "SecurityGroupId", map[string]string{
+		"security-group-id":   "SecurityGroupId",
+		"security-group-name": "SecurityGroupName",
+		"project-id":          "ProjectId",
+	}))
+	return map[string]interface{}{"TotalCount": len(items), "SecurityGroupSet": items}, nil
+}
+
+func deleteSecurityGroup(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	securityGroup, err := mustGet(s, KIND_SECURITY_GROUP, ctx, "SecurityGroupId", "ResourceNotFound")
+	if err != nil {
+		return nil, err
+	}
+	id := securityGroup.data["SecurityGroupId"].(string)
+	instances := s.findResources(KIND_CVM_INSTANCE, ctx.region, func(data map[string]interface{}) bool {
+		groups, _ := data["SecurityGroupIds"].([]interface{})
+		for _, group := range groups {
+			if group == id {
+				return true
+			}
+		}
+		return false
+	})
+	if len(instances) > 0 {
+		return nil, newApiError("ResourceInUse", "security group[%s] is used by %d instances", id, len(instances))
+	}
+	s.removeResource(KIND_SECURITY_GROUP, ctx.region, id)
+	s.removeResource(KIND_SECURITY_GROUP_POLICIES, ctx.region, id)
+	return map[string]interface{}{}, nil
+}
+
+func getSecurityGroupPolicies(s *Server, ctx *requestContext) (*resource, error) {
+	if _, err := mustGet(s, KIND_SECURITY_GROUP, ctx, "SecurityGroupId", "ResourceNotFound"); err != nil {
+		return nil, err
+	}
+	policies, _ := s.getResource(KIND_SECURITY_GROUP_POLICIES, ctx.region, ctx.str("SecurityGroupId"))
+	return policies, nil
+}
+
+func renumberPolicies(policies []interface{}) {
+	for i, policy := range policies {
+		policy.(map[string]interface{})["PolicyIndex"] = i
+	}
+}
+
+func isSamePolicy(a map[string]interface{}, b map[string]interface{}) bool {
+	for _, field := range []string{"Protocol", "Port", "CidrBlock", "Action", "SecurityGroupId"} {
+		if !strings.EqualFold(fmt.Sprint(a[field]), fmt.Sprint(b[field])) {
+			return false
+		}
+	}
+	return true
+}
+
+func createSecurityGroupPolicies(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	policies, err := getSecurityGroupPolicies(s, ctx)
+	if err != nil {
+		return nil, err
+	}
+	policySet := ctx.object("SecurityGroupPolicySet")
+	for _, direction := range []string{"Egress", "Ingress"} {
+		items, _ := policySet[direction].([]interface{})
+		existed, _ := policies.data[direction].([]interface{})
+		for _, item := range items {
+			policy, _ := item.(map[string]interface{})
+			if policy == nil {
+				continue
+			}
+			policy["ModifyTime"] = now()
+			existed = append(existed, policy)
+		}
+		renumberPolicies(existed)
+		policies.data[direction] = existed
+	}
+	policies.data["Version"] = fmt.Sprint(s.sequence)
+	return map[string]interface{}{}, nil
+}
+
+func deleteSecurityGroupPolicies(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	policies, err := getSecurityGroupPolicies(s, ctx)
+	if err != nil {
+		return nil, err
+	}
+	policySet := ctx.object("SecurityGroupPolicySet")
+	for _, direction := range []string{"Egress", "Ingress"} {
+		items, _ := policySet[direction].([]interface{})
+		existed, _ := policies.data[direction].([]interface{})
+		for _, item := range items {
+			policy, _ := item.(map[string]interface{})
+			left := []interface{}{}
+			for _, e := range existed {
+				data := e.(map[string]interface{})
+				if policy["PolicyIndex"] != nil && fmt.Sprint(policy["PolicyIndex"]) == fmt.Sprint(data["PolicyIndex"]) {
+					continue
+				}
+				if policy["PolicyIndex"] == nil && isSamePolicy(policy, data) {
+					continue
+				}
+				left = append(left, e)
+			}
+			if len(left) == len(existed) {
+				return nil, newApiError("ResourceNotFound", "%s policy[%v] not found", direction, policy)
+			}
+			existed = left
+		}
+		renumberPolicies(existed)
+		policies.data[direction] = existed
+	}
+	return map[string]interface{}{}, nil
+}
+
+func describeSecurityGroupPolicies(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	policies, err := getSecurityGroupPolicies(s, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"SecurityGroupPolicySet": policies.data}, nil
+}
+
+func allocateAddresses(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	count := ctx.int("AddressCount", 1)
+	if count+int64(len(s.findResources(KIND_ADDRESS, ctx.region, nil))) > EIP_QUOTA {
+		return nil, newApiError("AddressQuotaLimitExceeded", "eip quota %d exceeded", EIP_QUOTA)
+	}
+	ids := []string{}
+	for i := int64(0); i < count; i++ {
+		id := s.newId("eip")
+		s.putResource(KIND_ADDRESS, ctx.region, id, map[string]interface{}{
+			"AddressId":     id,
+			"AddressName":   "",
+			"AddressIp":     fmt.Sprintf("100.64.%d.%d", s.sequence/250, s.sequence%250+1),
+			"AddressStatus": "CREATING",
+			"AddressType":   "EIP",
+			"InstanceId":    "",
+			"CreatedTime":   now(),
+		})
+		s.schedule(KIND_ADDRESS, ctx.region, id, "AddressStatus", "UNBIND")
+		ids = append(ids, id)
+	}
+	return map[string]interface{}{"AddressSet": ids}, nil
+}
+
+func describeAddresses(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	items := s.listResources(KIND_ADDRESS, ctx.region, matchFilters(ctx, "AddressIds", "AddressId", map[string]string{
+		"address-id":     "AddressId",
+		"address-name":   "AddressName",
+		"address-ip":     "AddressIp",
+		"address-status": "AddressStatus",
+		"instance-id":    "InstanceId",
+	}))
+	return map[string]interface{}{"TotalCount": len(items), "AddressSet": items}, nil
+}
+
+func getAddresses(s *Server, ctx *requestContext) ([]*resource, error) {
+	if err := require(ctx, "AddressIds"); err != nil {
+		return nil, err
+	}
+	addresses := []*resource{}
+	for _, id := range ctx.strs("AddressIds") {
+		r, found := s.getResource(KIND_ADDRESS, ctx.region, id)
+		if !found {
+			return nil, newApiError("InvalidAddressId.NotFound", "address[%s] not found", id)
+		}
+		addresses = append(addresses, r)
+	}
+	return addresses, nil
+}
+
+func releaseAddresses(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	addresses, err := getAddresses(s, ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, address := range addresses {
+		if address.data["AddressStatus"] != "UNBIND" {
+			return nil, newApiError("InvalidAddressIdStatus.NotPermit", "address[%s] is %s", address.data["AddressId"], address.data["AddressStatus"])
+		}
+	}
+	for _, address := range addresses {
+		s.removeResource(KIND_ADDRESS, ctx.region, address.data["AddressId"].(string))
+	}
+	return map[string]interface{}{}, nil
+}
+
+func associateAddress(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	address, err := mustGet(s, KIND_ADDRESS, ctx, "AddressId", "InvalidAddressId.NotFound")
+	if err != nil {
+		return nil, err
+	}
+	if address.data["AddressStatus"] != "UNBIND" {
+		return nil, newApiError("InvalidAddressIdStatus.NotPermit", "address[%s] is %s", address.data["AddressId"], address.data["AddressStatus"])
+	}
+	instanceId := ctx.str("InstanceId")
+	if instanceId == "" {
+		instanceId = ctx.str("NetworkInterfaceId")
+	}
+	address.data["InstanceId"] = instanceId
+	address.data["PrivateAddressIp"] = ctx.str("PrivateIpAddress")
+	address.data["AddressStatus"] = "BINDING"
+	s.schedule(KIND_ADDRESS, ctx.region, address.data["AddressId"].(string), "AddressStatus", "BIND")
+	return map[string]interface{}{"TaskId": s.newId("task")}, nil
+}
+
+func disassociateAddress(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	address, err := mustGet(s, KIND_ADDRESS, ctx, "AddressId", "InvalidAddressId.NotFound")
+	if err != nil {
+		return nil, err
+	}
+	if address.data["AddressStatus"] != "BIND" {
+		return nil, newApiError("InvalidAddressIdStatus.NotPermit", "address[%s] is %s", address.data["AddressId"], address.data["AddressStatus"])
+	}
+	address.data["InstanceId"] = ""
+	address.data["PrivateAddressIp"] = ""
+	address.data["AddressStatus"] = "UNBINDING"
+	s.schedule(KIND_ADDRESS, ctx.region, address.data["AddressId"].(string), "AddressStatus", "UNBIND")
+	return map[string]interface{}{"TaskId": s.newId("task")}, nil
+}
+
+func describeAddressQuota(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	current := len(s.findResources(KIND_ADDRESS, ctx.region, nil))
+	return map[string]interface{}{"QuotaSet": []interface{}{
+		map[string]interface{}{"QuotaId": "TOTAL_EIP_QUOTA", "QuotaCurrent": current, "QuotaLimit": EIP_QUOTA},
+		map[string]interface{}{"QuotaId": "DAILY_EIP_APPLY", "QuotaCurrent": current, "QuotaLimit": 2 * EIP_QUOTA},
+	}}, nil
+}
+
+func createNetworkInterface(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "VpcId", "SubnetId", "NetworkInterfaceName"); err != nil {
+		return nil, err
+	}
+	subnet, err := mustGet(s, KIND_SUBNET, ctx, "SubnetId", "ResourceNotFound")
+	if err != nil {
+		return nil, err
+	}
+	if subnet.data["VpcId"] != ctx.str("VpcId") {
+		return nil, newApiError("InvalidParameterValue", "subnet[%s] is not in vpc[%s]", ctx.str("SubnetId"), ctx.str("VpcId"))
+	}
+
+	id := s.newId("eni")
+	privateIps := []interface{}{}
+	for i, privateIp := range ctx.objects("PrivateIpAddresses") {
+		privateIp["Primary"] = i == 0
+		privateIps = append(privateIps, privateIp)
+	}
+	if len(privateIps) == 0 {
+		privateIps = append(privateIps, map[string]interface{}{
+			"PrivateIpAddress": fmt.Sprintf("10.1.%d.%d", s.sequence/250, s.sequence%250+2),
+			"Primary":          true,
+		})
+	}
+	nic := s.putResource(KIND_NETWORK_INTERFACE, ctx.region, id, map[string]interface{}{
+		"NetworkInterfaceId":          id,
+		"NetworkInterfaceName":        ctx.str("NetworkInterfaceName"),
+		"NetworkInterfaceDescription": ctx.str("NetworkInterfaceDescription"),
+		"VpcId":                       ctx.str("VpcId"),
+		"SubnetId":                    ctx.str("SubnetId"),
+		"GroupSet":                    toInterfaces(ctx.strs("SecurityGroupIds")),
+		"PrivateIpAddressSet":         privateIps,
+		"Primary":                     false,
+		"State":                       "PENDING",
+		"CreatedTime":                 now(),
+	})
+	s.schedule(KIND_NETWORK_INTERFACE, ctx.region, id, "State", "AVAILABLE")
+	return map[string]interface{}{"NetworkInterface": nic.data}, nil
+}
+
+func describeNetworkInterfaces(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	match := matchFilters(ctx, "NetworkInterfaceIds", "NetworkInterfaceId", map[string]string{
+		"vpc-id":                 "VpcId",
+		"subnet-id":              "SubnetId",
+		"network-interface-id":   "NetworkInterfaceId",
+		"network-interface-name": "NetworkInterfaceName",
+	})
+	items := s.listResources(KIND_NETWORK_INTERFACE, ctx.region, match)
+	return map[string]interface{}{"TotalCount": len(items), "NetworkInterfaceSet": items}, nil
+}
+
+func deleteNetworkInterface(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	nic, err := mustGet(s, KIND_NETWORK_INTERFACE, ctx, "NetworkInterfaceId", "ResourceNotFound")
+	if err != nil {
+		return nil, err
+	}
+	if nic.data["Attachment"] != nil {
+		return nil, newApiError("ResourceInUse", "network interface[%s] is attached", nic.data["NetworkInterfaceId"])
+	}
+	s.removeResource(KIND_NETWORK_INTERFACE, ctx.region, nic.data["NetworkInterfaceId"].(string))
+	return map[string]interface{}{}, nil
+}
+
+func attachNetworkInterface(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	nic, err := mustGet(s, KIND_NETWORK_INTERFACE, ctx, "NetworkInterfaceId", "ResourceNotFound")
+	if err != nil {
+		return nil, err
+	}
+	if _, err = mustGet(s, KIND_CVM_INSTANCE, ctx, "InstanceId", "InvalidInstanceId.NotFound"); err != nil {
+		return nil, err
+	}
+	if nic.data["State"] != "AVAILABLE" || nic.data["Attachment"] != nil {
+		return nil, newApiError("UnsupportedOperation", "network interface[%s] is %s", nic.data["NetworkInterfaceId"], nic.data["State"])
+	}
+	nic.data["Attachment"] = map[string]interface{}{"InstanceId": ctx.str("InstanceId"), "AttachTime": now()}
+	nic.data["State"] = "ATTACHING"
+	s.schedule(KIND_NETWORK_INTERFACE, ctx.region, nic.data["NetworkInterfaceId"].(string), "State", "AVAILABLE")
+	return map[string]interface{}{}, nil
+}
+
+func detachNetworkInterface(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	nic, err := mustGet(s, KIND_NETWORK_INTERFACE, ctx, "NetworkInterfaceId", "ResourceNotFound")
+	if err != nil {
+		return nil, err
+	}
+	attachment, _ := nic.data["Attachment"].(map[string]interface{})
+	if attachment == nil || attachment["InstanceId"] != ctx.str("InstanceId") {
+		return nil, newApiError("UnsupportedOperation", "network interface[%s] is not attached to instance[%s]", nic.data["NetworkInterfaceId"], ctx.str("InstanceId"))
+	}
+	delete(nic.data, "Attachment")
+	nic.data["State"] = "DETACHING"
+	s.schedule(KIND_NETWORK_INTERFACE, ctx.region, nic.data["NetworkInterfaceId"].(string), "State", "AVAILABLE")
+	return map[string]interface{}{}, nil
+}
+
+//the eips of PublicIpAddresses are not checked, AddressCount eips are made up without allocating addresses
+func createNatGateway(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "VpcId", "NatGatewayName"); err != nil {
+		return nil, err
+	}
+	if _, err := mustGet(s, KIND_VPC, ctx, "VpcId", "ResourceNotFound"); err != nil {
+		return nil, err
+	}
+
+	addresses := []interface{}{}
+	for _, ip := range ctx.strs("PublicIpAddresses") {
+		addresses = append(addresses, map[string]interface{}{"AddressId": s.newId("eip"), "PublicIpAddress": ip})
+	}
+	for i := int64(0); i < ctx.int("AddressCount", 0); i++ {
+		addresses = append(addresses, map[string]interface{}{
+			"AddressId":       s.newId("eip"),
+			"PublicIpAddress": fmt.Sprintf("100.64.%d.%d", s.sequence/250, s.sequence%250+1),
+		})
+	}
+	id := s.newId("nat")
+	natGateway := s.putResource(KIND_NAT_GATEWAY, ctx.region, id, map[string]interface{}{
+		"NatGatewayId":            id,
+		"NatGatewayName":          ctx.str("NatGatewayName"),
+		"VpcId":                   ctx.str("VpcId"),
+		"InternetMaxBandwidthOut": ctx.int("InternetMaxBandwidthOut", 100),
+		"MaxConcurrentConnection": ctx.int("MaxConcurrentConnection", 1000000),
+		"PublicIpAddressSet":      addresses,
+		"State":                   "PENDING",
+		"CreatedTime":             now(),
+	})
+	s.schedule(KIND_NAT_GATEWAY, ctx.region, id, "State", "AVAILABLE")
+	return map[string]interface{}{"TotalCount": 1, "NatGatewaySet": []interface{}{natGateway.data}}, nil
+}
+
+func describeNatGateways(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	items := s.listResources(KIND_NAT_GATEWAY, ctx.region, matchFilters(ctx, "NatGatewayIds", "NatGatewayId", map[string]string{
+		"nat-gateway-id":   "NatGatewayId",
+		"nat-gateway-name": "NatGatewayName",
+		"vpc-id":           "VpcId",
+	}))
+	return map[string]interface{}{"TotalCount": len(items), "NatGatewaySet": page(ctx, items)}, nil
+}
+
+func deleteNatGateway(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	natGateway, err := mustGet(s, KIND_NAT_GATEWAY, ctx, "NatGatewayId", "ResourceNotFound")
+	if err != nil {
+		return nil, err
+	}
+	id := natGateway.data["NatGatewayId"].(string)
+	natGateway.data["State"] = "DELETING"
+	s.scheduleRemove(KIND_NAT_GATEWAY, ctx.region, id)
+	return map[string]interface{}{}, nil
+}
+
+func createVpcPeeringConnection(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	if err := require(ctx, "SourceVpcId", "DestinationVpcId", "PeeringConnectionName"); err != nil {
+		return nil, err
+	}
+	if _, err := mustGet(s, KIND_VPC, ctx, "SourceVpcId", "ResourceNotFound"); err != nil {
+		return nil, err
+	}
+
+	destinationRegion := ctx.str("DestinationRegion")
+	if destinationRegion == "" {
+		destinationRegion = ctx.region
+	}
+	id := s.newId("pcx")
+	s.putResource(KIND_PEERING_CONNECTION, ctx.region, id, map[string]interface{}{
+		"PeeringConnectionId":   id,
+		"PeeringConnectionName": ctx.str("PeeringConnectionName"),
+		"SourceVpcId":           ctx.str("SourceVpcId"),
+		"PeerVpcId":             ctx.str("DestinationVpcId"),
+		"SourceRegion":          ctx.region,
+		"DestinationRegion":     destinationRegion,
+		"Bandwidth":             ctx.int("Bandwidth", 0),
+		"State":                 "PENDING",
+		"CreateTime":            now(),
+	})
+	s.schedule(KIND_PEERING_CONNECTION, ctx.region, id, "State", "ACTIVE")
+	return map[string]interface{}{"PeeringConnectionId": id}, nil
+}
+
+func deleteVpcPeeringConnection(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	peeringConnection, err := mustGet(s, KIND_PEERING_CONNECTION, ctx, "PeeringConnectionId", "ResourceNotFound")
+	if err != nil {
+		return nil, err
+	}
+	peeringConnection.data["State"] = "DELETING"
+	s.scheduleRemove(KIND_PEERING_CONNECTION, ctx.region, peeringConnection.data["PeeringConnectionId"].(string))
+	return map[string]interface{}{}, nil
+}
+
+//the vpc-id filter matches the peering connections on either side of the vpc
+func describeVpcPeeringConnections(s *Server, ctx *requestContext) (map[string]interface{}, error) {
+	filters := ctx.filters()
+	vpcIds := filters["vpc-id"]
+	delete(filters, "vpc-id")
+	items := s.listResources(KIND_PEERING_CONNECTION, ctx.region, func(data map[string]interface{}) bool {
+		if len(vpcIds) > 0 && !matchAny(data["SourceVpcId"], vpcIds) && !matchAny(data["PeerVpcId"], vpcIds) {
+			return false
+		}
+		return matchAllFilters(data, filters, map[string]string{
+			"peering-connection-id":   "PeeringConnectionId",
+			"peering-connection-name": "PeeringConnectionName",
+			"state":                   "State",
+		})
+	})
+	return map[string]interface{}{"TotalCount": len(items), "PeerConnectionSet": page(ctx, items)}, nil
+}